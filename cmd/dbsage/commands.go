@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/output"
+	"dbsage/internal/report"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/internal/ui/renderers"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// commandOptions are the flags shared by exec and analyze
+type commandOptions struct {
+	conn   string
+	format output.Format
+	query  string
+}
+
+// parseCommandFlags parses --conn and --output plus the SQL argument of a subcommand
+func parseCommandFlags(name string, args []string, extra func(fs *flag.FlagSet)) (*commandOptions, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	conn := fs.String("conn", "", "Connection name (defaults to the last used connection)")
+	format := fs.String("output", string(output.FormatTable), "Output format: table, json or markdown")
+	if extra != nil {
+		extra(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dbsage %s [--conn name] [--output table|json|markdown] \"<sql>\"\n", name)
+		if name == "exec" {
+			fmt.Fprintln(fs.Output(), "       dbsage exec [--conn name] [--yes] -    (read a script from stdin)")
+		}
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	opts := &commandOptions{conn: *conn, query: strings.TrimSpace(strings.Join(fs.Args(), " "))}
+	f, err := output.ParseFormat(*format)
+	if err != nil {
+		return nil, err
+	}
+	opts.format = f
+	if opts.query == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return opts, nil
+}
+
+// openConnection opens a dedicated connection by name, or the last used one
+func openConnection(name string) (dbinterfaces.DatabaseInterface, string, error) {
+	manager := database.NewConnectionManager()
+	if name == "" {
+		name = manager.GetLastUsedConnection()
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("no connection configured; add one in the TUI with /add or pass --conn")
+	}
+
+	config, exists := manager.ListConnections()[name]
+	if !exists {
+		return nil, name, fmt.Errorf("connection '%s' not found", name)
+	}
+	db, err := database.NewProviderManager().OpenConnection(config)
+	if err != nil {
+		return nil, name, fmt.Errorf("failed to connect to '%s': %w", name, err)
+	}
+	return db, name, nil
+}
+
+// runExec executes a read-only statement (or any statement with --yes) and
+// prints the result. With "-" a script is read from stdin and every statement
+// is checked before any of them runs.
+func runExec(args []string) error {
+	var yes bool
+	opts, err := parseCommandFlags("exec", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&yes, "yes", false, "Allow statements that modify data or schema, or have high severity lint findings")
+	})
+	if err != nil {
+		return err
+	}
+
+	statements := []string{opts.query}
+	script := opts.query == "-"
+	if script {
+		script, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return reportError(opts, "", fmt.Errorf("failed to read stdin: %w", err))
+		}
+		opts.query = string(script)
+		statements = sqlanalysis.SplitStatements(opts.query)
+		if len(statements) == 0 {
+			return reportError(opts, "", fmt.Errorf("no statements on stdin"))
+		}
+	}
+
+	db, conn, err := openConnection(opts.conn)
+	if err != nil {
+		return reportError(opts, conn, err)
+	}
+	defer db.Close()
+	if script {
+		// MySQL and ClickHouse strings escape quotes with backslashes
+		statements = sqlanalysis.SplitStatementsFor(dbinterfaces.GetDatabaseType(db), opts.query)
+	}
+
+	if err := checkStatements(statements, dbinterfaces.GetDatabaseType(db), yes); err != nil {
+		return reportError(opts, conn, err)
+	}
+	defer cancelOnInterrupt(db)()
+
+	if len(statements) == 1 {
+		result, err := db.ExecuteSQL(statements[0])
+		if err != nil {
+			return reportError(opts, conn, err)
+		}
+		if opts.format == output.FormatJSON {
+			return output.NewResultDocument(conn, statements[0], result).WriteJSON(os.Stdout)
+		}
+		fmt.Println(renderers.FormatQueryResult(result, opts.format))
+		return nil
+	}
+	return execScript(db, conn, statements, opts.format)
+}
+
+// cancelOnInterrupt cancels the statements running on db on the server when
+// the command is interrupted, so they don't keep running after it exits; the
+// cancelled statement then fails as usual. With nothing to cancel, or on a
+// second interrupt, the command exits right away. Call the returned function
+// once the statements finished.
+func cancelOnInterrupt(db dbinterfaces.DatabaseInterface) func() {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupts:
+			signal.Stop(interrupts)
+			cancelled, err := dbinterfaces.CancelRunning(db)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to cancel the running statement on the server: %v\n", err)
+			}
+			if cancelled == 0 {
+				os.Exit(130)
+			}
+			fmt.Fprintln(os.Stderr, "cancelled the running statement on the server")
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(interrupts)
+		close(done)
+	}
+}
+
+// checkStatements lints every statement, printing the findings to stderr, and
+// refuses the lot unless all are read-only and free of high severity findings
+// or yes is set
+func checkStatements(statements []string, dialect string, yes bool) error {
+	var blocked []string
+	for i, stmt := range statements {
+		label := "statement"
+		if len(statements) > 1 {
+			label = fmt.Sprintf("statement %d", i+1)
+		}
+		high := false
+		for _, f := range sqlanalysis.LintSQL(stmt, dialect) {
+			fmt.Fprintf(os.Stderr, "%s: [%s] %s: %s\n", label, f.Severity, f.Rule, f.Message)
+			high = high || f.Severity == sqlanalysis.SeverityHigh
+		}
+		switch {
+		case !sqlanalysis.IsReadOnly(stmt):
+			blocked = append(blocked, label+" is not read-only")
+		case high:
+			blocked = append(blocked, label+" has high severity findings")
+		}
+	}
+	if len(blocked) == 0 || yes {
+		return nil
+	}
+	if len(statements) > 1 {
+		return fmt.Errorf("%s; nothing was executed, pass --yes to run the script", strings.Join(blocked, ", "))
+	}
+	return fmt.Errorf("%s; pass --yes to run it", blocked[0])
+}
+
+// execScript runs the statements of a script in order and stops at the first
+// failure. Statements are not wrapped in a transaction, so the ones before the
+// failure stay applied.
+func execScript(db dbinterfaces.DatabaseInterface, conn string, statements []string, format output.Format) error {
+	var results []output.StatementResult
+	var failure error
+	for i, stmt := range statements {
+		result, err := db.ExecuteSQL(stmt)
+		if err != nil {
+			results = append(results, output.StatementResult{Query: stmt, Error: err.Error()})
+			failure = fmt.Errorf("statement %d of %d failed: %w", i+1, len(statements), err)
+			if i > 0 {
+				failure = fmt.Errorf("%w (statements 1-%d were applied)", failure, i)
+			}
+			break
+		}
+		results = append(results, output.StatementResult{Query: stmt, Result: result})
+	}
+
+	if format == output.FormatJSON {
+		output.NewScriptDocument(conn, results).WriteJSON(os.Stdout)
+		if failure != nil {
+			os.Exit(1)
+		}
+		return nil
+	}
+	for i, r := range results {
+		if r.Result == nil {
+			continue
+		}
+		fmt.Printf("-- [%d/%d] %s\n", i+1, len(statements), firstLine(r.Query))
+		fmt.Println(renderers.FormatQueryResult(r.Result, format))
+	}
+	return failure
+}
+
+// firstLine returns the first line of a statement, marking when more follows
+func firstLine(stmt string) string {
+	if i := strings.IndexByte(stmt, '\n'); i >= 0 {
+		return strings.TrimSpace(stmt[:i]) + " ..."
+	}
+	return stmt
+}
+
+// runAnalyze lints a statement and analyzes its estimated plan without executing it
+func runAnalyze(args []string) error {
+	opts, err := parseCommandFlags("analyze", args, nil)
+	if err != nil {
+		return err
+	}
+
+	db, conn, err := openConnection(opts.conn)
+	if err != nil {
+		return reportError(opts, conn, err)
+	}
+	defer db.Close()
+
+	analysis := tools.AnalyzeQuery(db, opts.query)
+	if opts.format == output.FormatJSON {
+		return output.NewAnalysisDocument(conn, opts.query, analysis).WriteJSON(os.Stdout)
+	}
+	fmt.Print(report.FormatAnalysis(analysis, opts.format))
+	return nil
+}
+
+// reportError prints a JSON error document in json mode, otherwise returns the error
+func reportError(opts *commandOptions, conn string, err error) error {
+	if opts.format == output.FormatJSON {
+		output.NewErrorDocument(conn, opts.query, err).WriteJSON(os.Stdout)
+		os.Exit(1)
+	}
+	return err
+}
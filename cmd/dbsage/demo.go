@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dbsage/internal/demo"
+	"dbsage/internal/ui"
+	"dbsage/internal/version"
+	"dbsage/pkg/database"
+)
+
+// runDemo opens the TUI on the sample database with a guided tour. The demo
+// keeps its own connections file, so the user's connections are untouched.
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	reset := fs.Bool("reset", false, "Recreate the sample database, discarding changes made to it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dbsage demo [--reset]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	path := filepath.Join(demo.Dir(), "demo.db")
+	if _, err := os.Stat(path); *reset || os.IsNotExist(err) {
+		if err := demo.Create(path); err != nil {
+			return err
+		}
+	}
+
+	connService := database.NewConnectionServiceAt(filepath.Join(demo.Dir(), "connections.json"))
+	defer connService.Close()
+	connections, _, _ := connService.GetConnectionInfo()
+	if _, exists := connections[demo.ConnectionName]; !exists {
+		if err := connService.AddConnection(demo.Config(path)); err != nil {
+			return err
+		}
+	}
+	if err := connService.SwitchConnection(demo.ConnectionName); err != nil {
+		return err
+	}
+
+	openaiClient := newAIClient(connService)
+	firstQuestion := ""
+	if openaiClient != nil {
+		firstQuestion = demo.FirstQuestion
+	}
+
+	version.InitVersionService()
+	defer version.StopVersionService()
+	return ui.RunDemo(openaiClient, connService, demo.Tour(openaiClient != nil), firstQuestion)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"dbsage/internal/ai"
+	"dbsage/internal/ai/providers"
+	"dbsage/internal/session"
 	"dbsage/internal/ui"
 	"dbsage/internal/version"
 	"dbsage/pkg/database"
@@ -22,6 +26,67 @@ func showVersion() {
 	fmt.Println()
 }
 
+// runReplay plays back a session recorded with /record
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := fs.Float64("speed", 1, "Playback speed multiplier")
+	maxIdle := fs.Duration("max-idle", 2*time.Second, "Longest pause between events (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dbsage replay [-speed N] [-max-idle D] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	events, err := session.LoadRecording(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return session.Replay(events, os.Stdout, session.ReplayOptions{Speed: *speed, MaxIdle: *maxIdle})
+}
+
+// newAIClient creates the AI client working on the connections of
+// connService on the provider selected by DBSAGE_PROVIDER, or returns nil
+// when the provider has no API key configured
+func newAIClient(connService dbinterfaces.ConnectionServiceInterface) *ai.Client {
+	config := providers.ConfigFromEnv("")
+	provider, err := providers.New(config)
+	if errors.Is(err, providers.ErrMissingAPIKey) {
+		return nil
+	}
+	if err != nil {
+		log.Fatalf("AI provider error: %v", err)
+	}
+
+	// Initialize the AI client with dynamic database tools
+	openaiClient := ai.NewClientWithProvider(provider, providers.ModelFromEnv(config.Provider), func() dbinterfaces.DatabaseInterface {
+		return connService.GetCurrentTools()
+	})
+	openaiClient.EnableQueryHistory()
+	caps, err := ai.LoadCapabilities()
+	if err != nil {
+		log.Fatalf("Capabilities error: %v", err)
+	}
+	openaiClient.SetCapabilities(caps)
+	openaiClient.SetConnectionOpener(func(name string) (dbinterfaces.DatabaseInterface, error) {
+		connections, _, _ := connService.GetConnectionInfo()
+		config, exists := connections[name]
+		if !exists {
+			return nil, fmt.Errorf("connection '%s' not found", name)
+		}
+		db, err := database.NewProviderManager().OpenConnection(config)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	})
+	return openaiClient
+}
+
 // Build information - these will be set via ldflags
 var (
 	Version   = "dev"
@@ -44,11 +109,32 @@ func main() {
 		return
 	}
 
-	// Get environment variables
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	baseURL := os.Getenv("OPENAI_BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://api.openai.com/v1"
+	switch flag.Arg(0) {
+	case "replay":
+		if err := runReplay(flag.Args()[1:]); err != nil {
+			log.Fatalf("Replay error: %v", err)
+		}
+		return
+	case "exec":
+		if err := runExec(flag.Args()[1:]); err != nil {
+			log.Fatalf("Exec error: %v", err)
+		}
+		return
+	case "analyze":
+		if err := runAnalyze(flag.Args()[1:]); err != nil {
+			log.Fatalf("Analyze error: %v", err)
+		}
+		return
+	case "report":
+		if err := runReport(flag.Args()[1:]); err != nil {
+			log.Fatalf("Report error: %v", err)
+		}
+		return
+	case "demo":
+		if err := runDemo(flag.Args()[1:]); err != nil {
+			log.Fatalf("Demo error: %v", err)
+		}
+		return
 	}
 
 	// Initialize connection service (will automatically load connections from config file)
@@ -58,13 +144,7 @@ func main() {
 	// Get current database tools
 	dbTools := connService.GetCurrentTools()
 
-	var openaiClient *ai.Client
-	if apiKey != "" {
-		// Initialize OpenAI client with dynamic database tools
-		openaiClient = ai.NewClient(apiKey, baseURL, func() dbinterfaces.DatabaseInterface {
-			return connService.GetCurrentTools()
-		})
-	}
+	openaiClient := newAIClient(connService)
 
 	// Initialize version checking service
 	version.InitVersionService()
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbsage/internal/report"
+	"dbsage/internal/templatevars"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// runReport runs a report template and writes, emails or prints the result.
+// With a schedule the report only runs when the current minute matches it, so
+// the same command can be installed in cron as printed by --crontab.
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	templatePath := fs.String("template", "", "Report template (YAML)")
+	conn := fs.String("conn", "", "Connection name (overrides the template)")
+	schedule := fs.String("schedule", "", "Cron expression, e.g. \"0 8 * * 1\" (overrides the template)")
+	format := fs.String("format", "", "Report format: markdown or html (overrides the template)")
+	out := fs.String("out", "", "Output file, may contain {date}, {time} and {name} (overrides the template)")
+	email := fs.String("email", "", "Comma-separated recipients (overrides the template)")
+	force := fs.Bool("force", false, "Run now even if the schedule is not due")
+	crontab := fs.Bool("crontab", false, "Print the crontab entry for the schedule and exit")
+	var vars varFlags
+	fs.Var(&vars, "var", "Template variable as name=value (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dbsage report --template weekly.yaml [--schedule \"0 8 * * 1\"] [--conn name] [--format markdown|html] [--out file] [--email addr,...] [--var name=value ...] [--force] [--crontab]")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nDate helpers: {{%s}}, with offsets like {{today-7d}}\n", strings.Join(templatevars.BuiltinNames(), "}}, {{"))
+	}
+	fs.Parse(args)
+
+	if *templatePath == "" && fs.NArg() > 0 {
+		*templatePath = fs.Arg(0)
+	}
+	if *templatePath == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	tmpl, err := report.LoadTemplate(*templatePath)
+	if err != nil {
+		return err
+	}
+	if *conn != "" {
+		tmpl.Connection = *conn
+	}
+	if *schedule != "" {
+		tmpl.Schedule = *schedule
+	}
+	if *format != "" {
+		tmpl.Format = *format
+	}
+	if *out != "" {
+		tmpl.Output = *out
+	}
+	if *email != "" {
+		tmpl.Email = &report.Email{}
+		for _, addr := range strings.Split(*email, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				tmpl.Email.To = append(tmpl.Email.To, addr)
+			}
+		}
+	}
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	given, err := templatevars.ParseAssignments(vars)
+	if err != nil {
+		return err
+	}
+	if tmpl.Schedule != "" {
+		sched, err := report.ParseSchedule(tmpl.Schedule)
+		if err != nil {
+			return err
+		}
+		if *crontab {
+			return printCrontab(sched, *templatePath, args)
+		}
+		if !*force && !sched.Matches(now) {
+			return nil
+		}
+	} else if *crontab {
+		return fmt.Errorf("no schedule given; pass --schedule or set schedule in the template")
+	}
+
+	// Scheduled runs have nobody to answer, so only prompt on a terminal
+	var prompt templatevars.Prompter
+	if isTerminal(os.Stdin) {
+		prompt = promptVariable
+	}
+	values, err := templatevars.Resolve(tmpl.Texts(), tmpl.Variables, given, prompt, now)
+	if err != nil {
+		return err
+	}
+
+	db, connName, err := openConnection(tmpl.Connection)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := tmpl.ApplyVariables(values, dbinterfaces.GetDatabaseType(db)); err != nil {
+		return err
+	}
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+
+	r := report.Run(db, tmpl, connName, now)
+	content, err := r.Render(tmpl.Format)
+	if err != nil {
+		return err
+	}
+
+	delivered := false
+	if tmpl.Output != "" {
+		path := report.ExpandPlaceholders(tmpl.Output, tmpl.Name(), now)
+		if err := report.WriteFile(path, content); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Report written to %s\n", path)
+		delivered = true
+	}
+	if tmpl.Email != nil {
+		smtpConfig, err := report.SMTPConfigFromEnv()
+		if err != nil {
+			return err
+		}
+		if err := report.SendEmail(smtpConfig, tmpl.Email.To, tmpl.Subject(now), content, tmpl.Format); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Report emailed to %s\n", strings.Join(tmpl.Email.To, ", "))
+		delivered = true
+	}
+	if !delivered {
+		fmt.Print(content)
+	}
+
+	if failed := r.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d report sections failed", failed, len(r.Sections))
+	}
+	return nil
+}
+
+// printCrontab prints a crontab line that runs this report on its schedule
+func printCrontab(sched *report.Schedule, templatePath string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(templatePath)
+	if err != nil {
+		return err
+	}
+
+	// Keep the other flags, pointing at the template by absolute path
+	line := []string{sched.String(), exe, "report", "--template", shellQuote(abs)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(strings.SplitN(arg, "=", 2)[0], "-")
+		switch name {
+		case "crontab", "force":
+			continue
+		case "template", "schedule":
+			if !strings.Contains(arg, "=") {
+				i++
+			}
+			continue
+		}
+		if arg == templatePath {
+			continue
+		}
+		line = append(line, shellQuote(arg))
+	}
+	fmt.Println(strings.Join(line, " "))
+	return nil
+}
+
+// shellQuote quotes an argument for a crontab command line when needed. Cron
+// turns unescaped % into newlines, so those are escaped as well.
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t'\"$*?[]{}()&;|<>\\`%#~") {
+		return arg
+	}
+	quoted := "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	return strings.ReplaceAll(quoted, "%", `\%`)
+}
+
+// varFlags collects repeated --var name=value flags
+type varFlags []string
+
+func (v *varFlags) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *varFlags) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stdinReader is shared by prompts so buffered input is not lost between them
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptVariable asks for a template variable on the terminal
+func promptVariable(name, prompt, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", prompt, defaultValue)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	}
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no value for %s: %w", name, err)
+	}
+	return strings.TrimSpace(line), nil
+}
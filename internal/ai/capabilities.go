@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Capabilities restricts what the AI may do, for deployments that must ship
+// with a reduced tool surface. Restricted tools are never offered to the
+// model, and the executor refuses them should the model call them anyway.
+type Capabilities struct {
+	DisabledTools []string `json:"disabled_tools,omitempty"` // Tools removed by name, e.g. execute_sql
+	ReadOnly      bool     `json:"read_only,omitempty"`      // Remove tools that change data or schema; execute_sql only runs reads
+	NoSchema      bool     `json:"no_schema,omitempty"`      // Never send table names or structure to the model
+
+	Quotas tools.Quotas `json:"quotas,omitempty"` // Per-session limits on statements, rows and AI calls
+}
+
+// writeTools change data or schema
+var writeTools = []string{"insert_row", "update_rows", "generate_test_data", "copy_table", "watch_table"}
+
+// schemaTools send table names or structure to the model
+var schemaTools = []string{"get_all_tables", "get_table_schema", "get_table_indexes", "get_table_stats", "get_rls_policies", "get_collations", "setup_fts", "copy_table", "trace_column", "get_query_history", "advise_indexes", "preview_table", "generate_erd"}
+
+// capabilitiesFile returns the path of the capabilities file, which
+// DBSAGE_CAPABILITIES_FILE overrides so a deployment can ship a system-wide one
+func capabilitiesFile() string {
+	if path := os.Getenv("DBSAGE_CAPABILITIES_FILE"); path != "" {
+		return path
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "capabilities.json")
+}
+
+// LoadCapabilities reads the capability switches. A missing file enables
+// everything; an unreadable or invalid one is an error rather than a silent
+// fallback to full access.
+func LoadCapabilities() (Capabilities, error) {
+	var caps Capabilities
+	path := capabilitiesFile()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && os.Getenv("DBSAGE_CAPABILITIES_FILE") == "" {
+		return caps, nil
+	}
+	if err != nil {
+		return caps, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&caps); err != nil {
+		return caps, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	known := make(map[string]bool)
+	for _, tool := range GetTools() {
+		known[tool.Function.Name] = true
+	}
+	for _, name := range caps.DisabledTools {
+		if !known[name] {
+			return caps, fmt.Errorf("%s: unknown tool %q in disabled_tools", path, name)
+		}
+	}
+	if err := caps.Quotas.Validate(); err != nil {
+		return caps, fmt.Errorf("%s: %w", path, err)
+	}
+	return caps, nil
+}
+
+// Restricted reports whether any capability is switched off
+func (c Capabilities) Restricted() bool {
+	return len(c.DisabledTools) > 0 || c.ReadOnly || c.NoSchema || c.Quotas.Set()
+}
+
+// Disabled returns the names of the tools the switches remove, sorted
+func (c Capabilities) Disabled() []string {
+	disabled := make(map[string]bool)
+	for _, name := range c.DisabledTools {
+		disabled[name] = true
+	}
+	if c.ReadOnly {
+		for _, name := range writeTools {
+			disabled[name] = true
+		}
+	}
+	if c.NoSchema {
+		for _, name := range schemaTools {
+			disabled[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(disabled))
+	for name := range disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FilterTools returns the tool definitions the capabilities allow
+func (c Capabilities) FilterTools(all []openai.Tool) []openai.Tool {
+	disabled := make(map[string]bool)
+	for _, name := range c.Disabled() {
+		disabled[name] = true
+	}
+	var allowed []openai.Tool
+	for _, tool := range all {
+		if !disabled[tool.Function.Name] {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+// Restrictions returns what the tool executor enforces for the capabilities
+func (c Capabilities) Restrictions() tools.Restrictions {
+	r := tools.Restrictions{Disabled: make(map[string]bool), ReadOnlySQL: c.ReadOnly, NoCatalogSQL: c.NoSchema}
+	for _, name := range c.Disabled() {
+		r.Disabled[name] = true
+	}
+	return r
+}
+
+// promptSection tells the model which capabilities it lacks, so it does not
+// plan around them
+func (c Capabilities) promptSection() string {
+	if !c.Restricted() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nRESTRICTED DEPLOYMENT:\n")
+	if disabled := c.Disabled(); len(disabled) > 0 {
+		b.WriteString(fmt.Sprintf("These tools are disabled and not available, ignore any rule above that refers to them: %s.\n", strings.Join(disabled, ", ")))
+	}
+	if c.ReadOnly {
+		b.WriteString("execute_sql only runs read-only statements. For changes, give the user the SQL to review and run themselves.\n")
+	}
+	if c.NoSchema {
+		b.WriteString("Table names and structure must not be inspected: execute_sql refuses queries on information_schema and the system catalogs. Ask the user for the relevant columns instead.\n")
+	}
+	if c.Quotas.Set() {
+		b.WriteString(fmt.Sprintf("This session is limited to %s. Make every call count: combine questions into one query and select only the rows needed.\n", c.Quotas.Describe()))
+	}
+	return b.String()
+}
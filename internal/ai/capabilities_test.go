@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbsage/internal/ai/tools"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolNames(c Capabilities) map[string]bool {
+	names := make(map[string]bool)
+	for _, tool := range c.FilterTools(GetTools()) {
+		names[tool.Function.Name] = true
+	}
+	return names
+}
+
+func TestLoadCapabilities(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DBSAGE_CAPABILITIES_FILE", "")
+	caps, err := LoadCapabilities()
+	require.NoError(t, err)
+	assert.False(t, caps.Restricted())
+
+	path := filepath.Join(t.TempDir(), "capabilities.json")
+	t.Setenv("DBSAGE_CAPABILITIES_FILE", path)
+	_, err = LoadCapabilities()
+	assert.Error(t, err, "a configured file that is missing must not enable everything")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"disabled_tools": ["execute_sql"], "no_schema": true}`), 0o600))
+	caps, err = LoadCapabilities()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"execute_sql"}, caps.DisabledTools)
+	assert.True(t, caps.NoSchema)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"disabled_tools": ["execute_sq1"]}`), 0o600))
+	_, err = LoadCapabilities()
+	assert.ErrorContains(t, err, `unknown tool "execute_sq1"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"quotas": {"statements_per_minute": 30, "ai_calls_per_session": 100}}`), 0o600))
+	caps, err = LoadCapabilities()
+	require.NoError(t, err)
+	assert.Equal(t, 30, caps.Quotas.StatementsPerMinute)
+	assert.True(t, caps.Restricted())
+	assert.Contains(t, caps.promptSection(), "limited to 30 statements per minute, 100 AI calls per session")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"quotas": {"rows_per_hour": -1}}`), 0o600))
+	_, err = LoadCapabilities()
+	assert.ErrorContains(t, err, "quotas must not be negative")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"readonly": true}`), 0o600))
+	_, err = LoadCapabilities()
+	assert.ErrorContains(t, err, "readonly")
+}
+
+func TestCapabilities_FilterTools(t *testing.T) {
+	all := toolNames(Capabilities{})
+	assert.Len(t, all, len(GetTools()))
+
+	names := toolNames(Capabilities{DisabledTools: []string{"execute_sql"}})
+	assert.False(t, names["execute_sql"])
+	assert.True(t, names["get_table_schema"])
+
+	names = toolNames(Capabilities{ReadOnly: true})
+	assert.True(t, names["execute_sql"], "execute_sql stays, limited to reads")
+	assert.False(t, names["insert_row"])
+	assert.False(t, names["update_rows"])
+	assert.False(t, names["copy_table"])
+
+	names = toolNames(Capabilities{NoSchema: true})
+	assert.False(t, names["get_all_tables"])
+	assert.False(t, names["get_table_schema"])
+	assert.True(t, names["explain_query"])
+}
+
+func TestClient_SetCapabilities(t *testing.T) {
+	client := NewClient("test-key", "", nil)
+	assert.NotContains(t, client.systemPrompt(), "RESTRICTED DEPLOYMENT")
+
+	client.SetCapabilities(Capabilities{ReadOnly: true, NoSchema: true})
+	prompt := client.systemPrompt()
+	assert.Contains(t, prompt, "RESTRICTED DEPLOYMENT")
+	assert.Contains(t, prompt, "get_table_schema")
+	assert.Contains(t, prompt, "only runs read-only statements")
+	assert.True(t, client.Capabilities().Restricted())
+}
+
+func TestClient_AICallQuota(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	c.SetCapabilities(Capabilities{Quotas: tools.Quotas{AICallsPerSession: 2}})
+
+	require.NoError(t, c.countAICall())
+	require.NoError(t, c.countAICall())
+	err := c.countAICall()
+	assert.ErrorIs(t, err, tools.ErrQuotaExceeded)
+	assert.EqualError(t, err, "quota exceeded: this session may make 2 AI calls and has used them all; start a new session to continue")
+}
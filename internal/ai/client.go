@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
-	"dbsage/internal/ai/streaming"
+	"dbsage/internal/ai/providers"
 	"dbsage/internal/ai/tools"
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
 	"dbsage/pkg/dbinterfaces"
 
 	"github.com/sashabaranov/go-openai"
@@ -15,29 +20,60 @@ import (
 // StreamingCallback is called for each chunk of streaming response
 type StreamingCallback func(chunk string) error
 
+// DefaultModel is the OpenAI chat model used when none is configured
+var DefaultModel = providers.DefaultModel(providers.OpenAI)
+
 type Client struct {
-	client              *openai.Client
 	toolExecutor        *tools.Executor
-	streamingHandler    *streaming.StreamingHandler
 	toolConfirmCallback func(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, callback StreamingCallback) (bool, error)
 	toolConfirmConfig   *ToolConfirmationConfig
+	capabilities        Capabilities // Capabilities switched off for this deployment
+
+	mu             sync.Mutex         // Guards the model and the per-turn state below
+	provider       providers.Provider // Backend the model runs on
+	model          string
+	currentTurn    *TurnTiming // Turn being timed, nil between turns
+	lastTurn       *TurnTiming // Last finished turn, shown by /timing last
+	sqlCorrections int         // Failed statements handed back to the AI this turn
+	turns          int         // Turns begun this session, the {turn} of query labels
+	notices        []string    // Notices not yet shown to the user
+	aiCalls        int         // Requests to the model this session, limited by the AI call quota
+
+	lastSchema *sqlanalysis.SchemaContext // Schema summary sent with the last request, for /context show
+	schemaSent bool                       // A request was sent, so lastSchema is what it carried
+	semantic   semanticSearch             // Ranks tables by similarity to the question for the schema summary
+
+	stepMode    bool              // Pause before each tool call of new turns
+	stepping    bool              // The current turn pauses before each tool call
+	toolResults map[string]string // Results of the tool calls run so far in the message being run, by call ID
 }
 
-// NewClient creates a new client with dynamic database tools getter
+// NewClient creates a new client on the OpenAI API with dynamic database tools getter
 func NewClient(apiKey, baseURL string, getDbTools func() dbinterfaces.DatabaseInterface) *Client {
-	config := openai.DefaultConfig(apiKey)
-	if baseURL != "" {
-		config.BaseURL = baseURL
-	}
+	return NewClientWithProvider(providers.NewOpenAI(apiKey, baseURL), DefaultModel, getDbTools)
+}
 
-	client := openai.NewClientWithConfig(config)
+// NewClientWithProvider creates a client running model on an LLM provider
+func NewClientWithProvider(provider providers.Provider, model string, getDbTools func() dbinterfaces.DatabaseInterface) *Client {
 	return &Client{
-		client:           client,
-		toolExecutor:     tools.NewExecutorWithDynamicTools(getDbTools),
-		streamingHandler: streaming.NewStreamingHandler(),
+		provider:     provider,
+		model:        model,
+		toolExecutor: tools.NewExecutorWithDynamicTools(getDbTools),
 	}
 }
 
+// SetCapabilities restricts the tools offered to the model and executed
+func (c *Client) SetCapabilities(caps Capabilities) {
+	c.capabilities = caps
+	c.toolExecutor.SetRestrictions(caps.Restrictions())
+	c.toolExecutor.SetQuotas(caps.Quotas)
+}
+
+// Capabilities returns the capability restrictions in effect
+func (c *Client) Capabilities() Capabilities {
+	return c.capabilities
+}
+
 // executeToolWithConfirmation executes a tool with confirmation check
 func (c *Client) executeToolWithConfirmation(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, callback StreamingCallback) (string, error) {
 	var args map[string]interface{}
@@ -45,8 +81,8 @@ func (c *Client) executeToolWithConfirmation(ctx context.Context, messages []ope
 		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
 
-	// Check if tool requires confirmation based on config
-	if c.toolConfirmConfig != nil && c.toolConfirmConfig.RequiresConfirmation[toolCall.Function.Name] {
+	// Check if tool requires confirmation based on config or its arguments, or the turn is stepped through
+	if c.Stepping() || tools.RunsStatement(toolCall.Function.Name, args) || (c.toolConfirmConfig != nil && c.toolConfirmConfig.RequiresConfirmation[toolCall.Function.Name]) {
 		if c.toolConfirmCallback != nil {
 			confirmed, err := c.toolConfirmCallback(ctx, messages, completeMessage, toolCall, callback)
 			if err != nil {
@@ -60,83 +96,281 @@ func (c *Client) executeToolWithConfirmation(ctx context.Context, messages []ope
 	}
 
 	// Execute the tool normally
-	return c.toolExecutor.Execute(toolCall)
+	return c.executeTool(ctx, toolCall)
+}
+
+// executeTool runs a tool and records how long it took in the current turn
+func (c *Client) executeTool(ctx context.Context, toolCall openai.ToolCall) (string, error) {
+	start := time.Now()
+	result, err := c.toolExecutor.ExecuteContext(ctx, toolCall)
+	c.RecordPhase(PhaseTool, toolCall.Function.Name, toolCallDetail(toolCall), time.Since(start))
+
+	if toolCall.Function.Name == "execute_sql" {
+		if err != nil {
+			var args map[string]interface{}
+			_ = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+			sql, _ := args["sql"].(string)
+			return c.sqlCorrectionResult(sql, err)
+		}
+		c.resetSQLCorrections()
+	}
+	return result, err
+}
+
+// toolCallDetail returns the SQL of a tool call, shortened for the timing report
+func toolCallDetail(toolCall openai.ToolCall) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return ""
+	}
+	detail, _ := args["sql"].(string)
+	if detail == "" {
+		detail, _ = args["tableName"].(string)
+	}
+	detail = strings.Join(strings.Fields(detail), " ")
+	if len([]rune(detail)) > 60 {
+		detail = string([]rune(detail)[:57]) + "..."
+	}
+	return detail
 }
 
 // QueryWithToolsStreaming performs a streaming query with tools support
 func (c *Client) QueryWithToolsStreaming(ctx context.Context, messages []openai.ChatCompletionMessage, callback StreamingCallback) error {
 	systemMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleSystem,
-		Content: GetSystemPrompt(),
+		Content: c.systemPrompt() + c.schemaSection(ctx, messages),
 	}
 
 	allMessages := append([]openai.ChatCompletionMessage{systemMessage}, messages...)
 
-	// Create streaming request with tools
-	stream, err := c.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-		Model:    "gpt-4o-mini",
-		Messages: allMessages,
-		Tools:    GetTools(),
-		Stream:   true,
-	})
-	if err != nil {
-		return fmt.Errorf("OpenAI streaming API error: %w", err)
+	if err := c.countAICall(); err != nil {
+		return err
 	}
 
-	// Process the stream
-	completeMessage, err := c.streamingHandler.ProcessStream(ctx, stream, streaming.StreamingCallback(callback))
+	// Stream the answer, with tools, from the provider
+	c.mu.Lock()
+	provider, model := c.provider, c.model
+	c.mu.Unlock()
+	start := time.Now()
+	completeMessage, err := provider.StreamChat(ctx, providers.Request{
+		Model:    model,
+		Messages: allMessages,
+		Tools:    c.capabilities.FilterTools(GetTools()),
+	}, providers.ChunkCallback(callback))
 	if err != nil {
 		return err
 	}
+	c.recordCompletion(completeMessage, time.Since(start))
 
 	// If tools are needed, execute them
 	if len(completeMessage.ToolCalls) > 0 {
-		// For streaming with multiple tool calls, we need to handle them sequentially
-		// because confirmation might be needed for some tools
-		toolCall := completeMessage.ToolCalls[0]
+		return c.runToolCalls(ctx, messages, completeMessage, callback)
+	}
 
-		// Execute the first tool with confirmation check
-		result, err := c.executeToolWithConfirmation(ctx, messages, completeMessage, toolCall, callback)
-		if err != nil {
-			return fmt.Errorf("tool execution error: %w", err)
-		}
+	// No tools needed, streaming is already complete
+	return nil
+}
 
-		// Check if confirmation is pending
-		if result == "CONFIRMATION_PENDING" {
-			// Tool confirmation is pending - don't continue execution
-			// The UI will handle the confirmation and call ContinueWithConfirmedTool when ready
-			return nil
+// countAICall counts a request to the model, or refuses it when the session
+// has used its AI call quota
+func (c *Client) countAICall() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if limit := c.capabilities.Quotas.AICallsPerSession; limit > 0 && c.aiCalls >= limit {
+		return fmt.Errorf("%w: this session may make %d AI calls and has used them all; start a new session to continue", tools.ErrQuotaExceeded, limit)
+	}
+	c.aiCalls++
+	return nil
+}
+
+// recordCompletion records one model round trip of the current turn
+func (c *Client) recordCompletion(message openai.ChatCompletionMessage, duration time.Duration) {
+	detail := "answer"
+	if len(message.ToolCalls) > 0 {
+		names := make([]string, 0, len(message.ToolCalls))
+		for _, tc := range message.ToolCalls {
+			names = append(names, tc.Function.Name)
 		}
+		detail = "requested " + strings.Join(names, ", ")
+	}
+	c.RecordPhase(PhaseLLM, fmt.Sprintf("request %d", c.countPhases(PhaseLLM)+1), detail, duration)
+}
 
-		// Process all tool calls
-		toolMessages := []openai.ChatCompletionMessage{completeMessage}
-
-		for _, tc := range completeMessage.ToolCalls {
-			var toolResult string
-			if tc.ID == toolCall.ID {
-				// Use the already executed result for the first tool
-				toolResult = result
-			} else {
-				// Execute other tools (they don't need confirmation in this context)
-				toolResult, err = c.toolExecutor.Execute(tc)
-				if err != nil {
-					return fmt.Errorf("tool execution error: %w", err)
-				}
-			}
+// PreviewStatement returns the SQL a statement-building tool call would run,
+// for showing it in the confirmation prompt
+func (c *Client) PreviewStatement(toolCall openai.ToolCall) (string, error) {
+	return c.toolExecutor.PreviewStatement(toolCall)
+}
+
+// InlineVariables returns the statement execute_sql runs for sql, with the
+// result variables it refers to inlined, for showing it in the confirmation
+func (c *Client) InlineVariables(sql string) string {
+	return c.toolExecutor.InlineVariables(sql)
+}
+
+// DDLImpact lists the objects that use what the ALTER and DROP statements of
+// sql change
+func (c *Client) DDLImpact(sql string) ([]string, error) {
+	return c.toolExecutor.DDLImpact(sql)
+}
+
+// Model returns the chat model used by the client
+func (c *Client) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
 
-			toolMessages = append(toolMessages, openai.ChatCompletionMessage{
-				Role:       openai.ChatMessageRoleTool,
-				Content:    toolResult,
-				ToolCallID: tc.ID,
-			})
+// Provider returns the name of the provider the model runs on
+func (c *Client) Provider() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.provider.Name()
+}
+
+// SetModel switches the chat model, and the provider when spec names one:
+// "gpt-4.1" changes the model, "ollama" switches to Ollama's default model
+// and "anthropic:claude-sonnet-4-5" to a model of Anthropic. A new provider
+// is configured from its environment variables.
+func (c *Client) SetModel(spec string) error {
+	name, model := providers.ParseModel(spec)
+	if name == "" && model == "" {
+		return fmt.Errorf("no model given")
+	}
+
+	var provider providers.Provider
+	if name != "" && name != c.Provider() {
+		p, err := providers.New(providers.ConfigFromEnv(name))
+		if err != nil {
+			return err
 		}
+		provider = p
+	}
+	if model == "" {
+		model = providers.DefaultModel(name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if provider != nil {
+		c.provider = provider
+	}
+	c.model = model
+	return nil
+}
+
+// SetTenant scopes the statements the AI runs to a tenant; "" turns scoping off
+func (c *Client) SetTenant(tenant string) {
+	c.toolExecutor.SetTenant(tenant)
+}
+
+// EnableQueryHistory records the questions asked and the statements run by
+// the AI in ~/.dbsage/history.jsonl
+func (c *Client) EnableQueryHistory() {
+	c.toolExecutor.EnableHistory()
+}
+
+// SetConnectionOpener lets tools open other configured connections by name,
+// for copy_table
+func (c *Client) SetConnectionOpener(open tools.ConnectionOpener) {
+	c.toolExecutor.SetConnectionOpener(open)
+}
+
+// RecordQuestion adds a question to the query history when it is enabled
+func (c *Client) RecordQuestion(question string) {
+	c.toolExecutor.RecordQuestion(question)
+}
+
+// TakeExecutedSQL returns the statements the AI executed since the last call
+func (c *Client) TakeExecutedSQL() []string {
+	return c.toolExecutor.TakeExecutedSQL()
+}
+
+// LastResult returns the full result of the last query the AI ran, or nil
+func (c *Client) LastResult() *models.QueryResult {
+	return c.toolExecutor.LastResult()
+}
 
-		updatedMessages := append(messages, toolMessages...)
-		// Recursively call with updated messages
-		return c.QueryWithToolsStreaming(ctx, updatedMessages, callback)
+// LastResultQuery returns the statement of LastResult and the connection it ran on
+func (c *Client) LastResultQuery() (string, string) {
+	return c.toolExecutor.LastResultQuery()
+}
+
+// SaveResult saves the last query result the AI ran under a name for $name references
+func (c *Client) SaveResult(name string) (*tools.ResultVariable, error) {
+	return c.toolExecutor.SaveResult(name)
+}
+
+// Variables returns the results saved with SaveResult
+func (c *Client) Variables() []*tools.ResultVariable {
+	return c.toolExecutor.Variables()
+}
+
+// ExpandPrompt adds the saved results a prompt refers to as $name
+func (c *Client) ExpandPrompt(prompt string) string {
+	return c.toolExecutor.ExpandPrompt(prompt)
+}
+
+// Tenant returns the active tenant, or "" when statements are not scoped
+func (c *Client) Tenant() string {
+	return c.toolExecutor.Tenant()
+}
+
+// systemPrompt returns the system prompt, with the tenant rules when a tenant is active
+func (c *Client) systemPrompt() string {
+	prompt := GetSystemPrompt() + c.capabilities.promptSection()
+	if c.Stepping() {
+		prompt += stepPromptSection
+	}
+	tenant := c.toolExecutor.Tenant()
+	if tenant == "" {
+		return prompt
 	}
+	policy, _ := sqlanalysis.LoadTenantPolicy()
+	return prompt + fmt.Sprintf(`
 
-	// No tools needed, streaming is already complete
+TENANT CONTEXT:
+Every statement must stay within tenant %s. Filter each tenant table you read, update or delete from by its tenant condition (for example orders.%s = %s, qualified by the table's alias in joins), and set the tenant column on inserts. insert_row and update_rows add the tenant automatically; execute_sql rejects statements that miss the condition.
+%s`, tenant, policy.Column, sqlanalysis.TenantLiteral(tenant), policy.Describe())
+}
+
+// LastQueryDuration returns the duration reported for the last SQL query run by a tool
+func (c *Client) LastQueryDuration() string {
+	return c.toolExecutor.GetLastDuration()
+}
+
+// baseContextChars is the size of the system prompt and tool definitions,
+// which every request carries
+var baseContextChars = sync.OnceValue(func() int {
+	chars := len(GetSystemPrompt())
+	if tools, err := json.Marshal(GetTools()); err == nil {
+		chars += len(tools)
+	}
+	return chars
+})
+
+// EstimateContextTokens roughly estimates the tokens sent with the next request,
+// including the system prompt and tool definitions (about 4 characters per token)
+func EstimateContextTokens(messages []openai.ChatCompletionMessage) int {
+	chars := baseContextChars()
+	for _, msg := range messages {
+		chars += len(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			chars += len(tc.Function.Name) + len(tc.Function.Arguments)
+		}
+	}
+	return chars / 4
+}
+
+// emitToolNotices streams notices produced during tool execution (such as result
+// truncation) to the user so they are visible alongside the AI's answer
+func (c *Client) emitToolNotices(callback StreamingCallback) error {
+	notices := append(c.takeNotices(), c.toolExecutor.TakeNotices()...)
+	for _, notice := range notices {
+		if err := callback("\n> ⚠ " + notice + "\n\n"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -153,35 +387,58 @@ func (c *Client) SetToolConfirmationConfig(config *ToolConfirmationConfig) {
 // ContinueWithConfirmedTool continues AI processing after tool confirmation
 func (c *Client) ContinueWithConfirmedTool(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, callback StreamingCallback) error {
 	// Execute the confirmed tool
-	result, err := c.toolExecutor.Execute(toolCall)
+	result, err := c.executeTool(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("tool execution error: %w", err)
 	}
+	c.setToolResult(toolCall.ID, result)
 
-	// Process all tool calls
-	toolMessages := []openai.ChatCompletionMessage{completeMessage}
+	return c.runToolCalls(ctx, messages, completeMessage, callback)
+}
 
+// runToolCalls runs the tool calls of a model message in order and continues
+// the conversation with their results. It stops at a call waiting for
+// confirmation; ContinueWithConfirmedTool picks up from there, reusing the
+// results of the calls already run.
+func (c *Client) runToolCalls(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, callback StreamingCallback) error {
 	for _, tc := range completeMessage.ToolCalls {
-		var toolResult string
-		if tc.ID == toolCall.ID {
-			// Use the confirmed tool result
-			toolResult = result
-		} else {
-			// Execute other tools
-			toolResult, err = c.toolExecutor.Execute(tc)
-			if err != nil {
-				return fmt.Errorf("tool execution error: %w", err)
-			}
+		if _, done := c.toolResult(tc.ID); done {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			// The turn was aborted: run none of its remaining tools
+			return err
+		}
+
+		result, err := c.executeToolWithConfirmation(ctx, messages, completeMessage, tc, callback)
+		if err != nil {
+			return fmt.Errorf("tool execution error: %w", err)
 		}
 
+		// Check if confirmation is pending
+		if result == "CONFIRMATION_PENDING" {
+			// Tool confirmation is pending - don't continue execution
+			// The UI will handle the confirmation and call ContinueWithConfirmedTool when ready
+			return nil
+		}
+		c.setToolResult(tc.ID, result)
+	}
+
+	results := c.takeToolResults()
+	toolMessages := []openai.ChatCompletionMessage{completeMessage}
+	for _, tc := range completeMessage.ToolCalls {
 		toolMessages = append(toolMessages, openai.ChatCompletionMessage{
 			Role:       openai.ChatMessageRoleTool,
-			Content:    toolResult,
+			Content:    c.toolExecutor.Guard(tc.Function.Name, results[tc.ID]),
 			ToolCallID: tc.ID,
 		})
 	}
 
+	if err := c.emitToolNotices(callback); err != nil {
+		return err
+	}
+
 	updatedMessages := append(messages, toolMessages...)
-	// Continue with streaming
+	// Recursively call with updated messages
 	return c.QueryWithToolsStreaming(ctx, updatedMessages, callback)
 }
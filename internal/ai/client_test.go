@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SetModel(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	assert.Equal(t, DefaultModel, c.Model())
+	assert.Equal(t, "openai", c.Provider())
+
+	require.NoError(t, c.SetModel("gpt-4.1"))
+	assert.Equal(t, "gpt-4.1", c.Model())
+	assert.Equal(t, "openai", c.Provider())
+
+	require.NoError(t, c.SetModel("ollama:qwen2.5:7b"))
+	assert.Equal(t, "qwen2.5:7b", c.Model())
+	assert.Equal(t, "ollama", c.Provider())
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	assert.ErrorContains(t, c.SetModel("anthropic"), "ANTHROPIC_API_KEY")
+	assert.Equal(t, "ollama", c.Provider(), "a failed switch keeps the provider")
+	assert.Error(t, c.SetModel(" "))
+}
+
+func TestClient_AdviseCTEsAnalyzeNeedsConfirmation(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	var asked []string
+	c.SetToolConfirmationCallback(func(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, callback StreamingCallback) (bool, error) {
+		asked = append(asked, toolCall.Function.Arguments)
+		return false, nil
+	})
+	c.SetToolConfirmationConfig(NewToolConfirmationConfig(map[string]bool{"advise_ctes": false}, nil, nil))
+
+	analyze := openai.ToolCall{ID: "call-1", Function: openai.FunctionCall{Name: "advise_ctes",
+		Arguments: `{"sql": "SELECT pg_terminate_backend(pid) FROM pg_stat_activity", "analyze": true}`}}
+	result, err := c.executeToolWithConfirmation(context.Background(), nil, openai.ChatCompletionMessage{}, analyze, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "CONFIRMATION_PENDING", result, "EXPLAIN ANALYZE runs the statement")
+	assert.Len(t, asked, 1)
+}
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"dbsage/internal/embeddings"
+	"dbsage/internal/sqlanalysis"
+)
+
+// DefaultEmbeddingModel embeds the schema for semantic table search
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// similarTables is how many tables semantic search adds to a schema summary
+// that does not fit whole
+const similarTables = 10
+
+// embeddingTimeout bounds the embedding requests of one schema summary
+const embeddingTimeout = 20 * time.Second
+
+// EmbeddingModel returns the model that embeds tables for semantic search,
+// set with DBSAGE_EMBEDDING_MODEL ("off" disables semantic search)
+func EmbeddingModel() string {
+	model := strings.TrimSpace(os.Getenv("DBSAGE_EMBEDDING_MODEL"))
+	switch {
+	case model == "":
+		return DefaultEmbeddingModel
+	case strings.EqualFold(model, "off"):
+		return ""
+	}
+	return model
+}
+
+// semanticSearch ranks tables by their similarity to the question. Table
+// embeddings are kept in the local cache; the question's is kept until the
+// question changes, as a turn builds the summary once per model request.
+type semanticSearch struct {
+	mu       sync.Mutex
+	cache    *embeddings.Cache
+	dir      string // Directory of the cache files, embeddings.Dir() when empty
+	question string
+	vector   []float32
+	failed   bool // The embedder failed; semantic search is off for the session
+}
+
+// similarTablesFor returns the ranker of SchemaContext for a question, or nil
+// when the provider cannot embed, semantic search is off or has failed
+func (c *Client) similarTablesFor(ctx context.Context, question string) func([]sqlanalysis.SchemaTable) []string {
+	c.mu.Lock()
+	embedder, ok := c.provider.(embeddings.Embedder)
+	c.mu.Unlock()
+	model := EmbeddingModel()
+	if !ok || model == "" || strings.TrimSpace(question) == "" {
+		return nil
+	}
+
+	s := &c.semantic
+	s.mu.Lock()
+	failed := s.failed
+	s.mu.Unlock()
+	if failed {
+		return nil
+	}
+
+	return func(tables []sqlanalysis.SchemaTable) []string {
+		ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+		defer cancel()
+		names, err := s.rank(ctx, embedder, model, question, tables)
+		if err != nil {
+			s.mu.Lock()
+			s.failed = true
+			s.mu.Unlock()
+			c.addNotice(fmt.Sprintf("Semantic schema search is off for this session: %v", err))
+			return nil
+		}
+		return names
+	}
+}
+
+// rank embeds the tables that changed since they were last embedded and the
+// question, and returns the names of the tables closest to the question
+func (s *semanticSearch) rank(ctx context.Context, embedder embeddings.Embedder, model, question string, tables []sqlanalysis.SchemaTable) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cache == nil || s.cache.Model() != model {
+		dir := s.dir
+		if dir == "" {
+			dir = embeddings.Dir()
+		}
+		s.cache = embeddings.Open(dir, model)
+	}
+	texts := make([]string, len(tables))
+	for i, t := range tables {
+		texts[i] = t.String()
+	}
+	vectors, embedded, err := s.cache.Embed(ctx, embedder, texts)
+	if embedded > 0 {
+		if err := s.cache.Save(); err != nil {
+			log.Printf("Warning: failed to save embeddings: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if question != s.question || s.vector == nil {
+		query, err := embedder.Embed(ctx, model, []string{question})
+		if err != nil {
+			return nil, err
+		}
+		if len(query) != 1 {
+			return nil, fmt.Errorf("the embedding model returned %d vectors for the question", len(query))
+		}
+		s.question, s.vector = question, query[0]
+	}
+
+	var names []string
+	for _, i := range embeddings.Nearest(s.vector, vectors, similarTables) {
+		names = append(names, tables[i].Name)
+	}
+	return names, nil
+}
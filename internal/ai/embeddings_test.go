@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicEmbedder embeds texts by whether they are about orders or users
+type topicEmbedder struct {
+	calls int
+}
+
+func (e *topicEmbedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vectors[i] = []float32{float32(strings.Count(text, "order") + strings.Count(text, "purchase")), float32(strings.Count(text, "user"))}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingModel(t *testing.T) {
+	t.Setenv("DBSAGE_EMBEDDING_MODEL", "")
+	assert.Equal(t, DefaultEmbeddingModel, EmbeddingModel())
+	t.Setenv("DBSAGE_EMBEDDING_MODEL", "off")
+	assert.Empty(t, EmbeddingModel())
+}
+
+func TestSemanticSearch_Rank(t *testing.T) {
+	search := &semanticSearch{dir: t.TempDir()}
+	embedder := &topicEmbedder{}
+	tables := []sqlanalysis.SchemaTable{
+		{Name: "accounts", Columns: []string{"user_id integer"}},
+		{Name: "line_items", Columns: []string{"order_id integer"}},
+	}
+
+	names, err := search.rank(context.Background(), embedder, "model", "Which purchases were refunded?", tables)
+	require.NoError(t, err)
+	assert.Equal(t, "line_items", names[0])
+	assert.Equal(t, 2, embedder.calls)
+
+	// The tables and the question are embedded once
+	_, err = search.rank(context.Background(), embedder, "model", "Which purchases were refunded?", tables)
+	require.NoError(t, err)
+	assert.Equal(t, 2, embedder.calls)
+}
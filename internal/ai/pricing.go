@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultTokenPreviewThreshold is the context size above which a cost preview is shown
+const DefaultTokenPreviewThreshold = 8000
+
+// inputPricePerMillion is the USD price per million input tokens for known models
+var inputPricePerMillion = map[string]float64{
+	"gpt-4o-mini":  0.15,
+	"gpt-4o":       2.50,
+	"gpt-4.1":      2.00,
+	"gpt-4.1-mini": 0.40,
+	"gpt-4.1-nano": 0.10,
+	"o3-mini":      1.10,
+	"o4-mini":      1.10,
+
+	"claude-sonnet-4-5": 3.00,
+	"claude-haiku-4-5":  1.00,
+	"claude-opus-4-1":   15.00,
+}
+
+// EstimateInputCost returns the estimated USD cost of sending tokens to model,
+// and false if the model's price is unknown
+func EstimateInputCost(model string, tokens int) (float64, bool) {
+	price, ok := inputPricePerMillion[strings.ToLower(model)]
+	if !ok {
+		return 0, false
+	}
+	return float64(tokens) * price / 1_000_000, true
+}
+
+// TokenPreviewThreshold returns the context size that triggers a cost preview,
+// set with DBSAGE_TOKEN_PREVIEW (0 disables the preview)
+func TokenPreviewThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_TOKEN_PREVIEW")); err == nil && n >= 0 {
+		return n
+	}
+	return DefaultTokenPreviewThreshold
+}
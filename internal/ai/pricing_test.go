@@ -0,0 +1,24 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateInputCost(t *testing.T) {
+	cost, ok := EstimateInputCost("gpt-4o-mini", 1_000_000)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.15, cost, 1e-9)
+
+	_, ok = EstimateInputCost("some-local-model", 1000)
+	assert.False(t, ok)
+}
+
+func TestTokenPreviewThreshold(t *testing.T) {
+	t.Setenv("DBSAGE_TOKEN_PREVIEW", "")
+	assert.Equal(t, DefaultTokenPreviewThreshold, TokenPreviewThreshold())
+
+	t.Setenv("DBSAGE_TOKEN_PREVIEW", "0")
+	assert.Equal(t, 0, TokenPreviewThreshold())
+}
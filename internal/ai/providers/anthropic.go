@@ -0,0 +1,255 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+}
+
+// NewAnthropic creates a provider for the Anthropic Messages API; an empty
+// baseURL uses the default endpoint
+func NewAnthropic(apiKey, baseURL string) Provider {
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+	return &anthropicProvider{apiKey: apiKey, baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+}
+
+// Name returns the provider name
+func (p *anthropicProvider) Name() string {
+	return Anthropic
+}
+
+// anthropicBlock is a content block of a message
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   string          `json:"content,omitempty"`     // tool_result
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream"`
+}
+
+// anthropicRequestFrom translates a chat request. System messages become the
+// system prompt, tool calls become tool_use blocks and tool results become
+// tool_result blocks of a user message; consecutive messages of one role are
+// merged, since the API expects user and assistant turns to alternate.
+func anthropicRequestFrom(req Request) anthropicRequest {
+	out := anthropicRequest{Model: req.Model, MaxTokens: anthropicMaxTokens, Stream: true}
+	var system []string
+	for _, msg := range req.Messages {
+		role := "user"
+		var blocks []anthropicBlock
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem:
+			system = append(system, msg.Content)
+			continue
+		case openai.ChatMessageRoleAssistant:
+			role = "assistant"
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+			}
+		case openai.ChatMessageRoleTool:
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		default:
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+		} else {
+			out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		out.Tools = append(out.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+	return out
+}
+
+// anthropicEvent is a server-sent event of a streamed message
+type anthropicEvent struct {
+	Type         string          `json:"type"`
+	Index        int             `json:"index"`
+	ContentBlock *anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// StreamChat streams a message, translating tool_use blocks back to tool calls
+func (p *anthropicProvider) StreamChat(ctx context.Context, req Request, onChunk ChunkCallback) (openai.ChatCompletionMessage, error) {
+	complete := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	body, err := json.Marshal(anthropicRequestFrom(req))
+	if err != nil {
+		return complete, fmt.Errorf("failed to encode Anthropic request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return complete, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.http.Do(httpReq)
+	if err != nil {
+		return complete, fmt.Errorf("Anthropic streaming API error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return complete, fmt.Errorf("Anthropic streaming API error: %s", httpError(resp))
+	}
+
+	var content strings.Builder
+	toolIndex := make(map[int]int) // Content block index to tool call index
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				toolIndex[event.Index] = len(complete.ToolCalls)
+				complete.ToolCalls = append(complete.ToolCalls, openai.ToolCall{
+					ID:       event.ContentBlock.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: event.ContentBlock.Name},
+				})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				content.WriteString(event.Delta.Text)
+				if err := onChunk(event.Delta.Text); err != nil {
+					return complete, err
+				}
+			case "input_json_delta":
+				if i, ok := toolIndex[event.Index]; ok {
+					complete.ToolCalls[i].Function.Arguments += event.Delta.PartialJSON
+				}
+			}
+		case "error":
+			if event.Error != nil {
+				return complete, fmt.Errorf("stream error: %s", event.Error.Message)
+			}
+		case "message_stop":
+			complete.Content = content.String()
+			finishToolCalls(complete.ToolCalls)
+			return complete, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return complete, fmt.Errorf("stream error: %w", err)
+	}
+	return complete, fmt.Errorf("stream error: the response ended before the message was complete")
+}
+
+// finishToolCalls gives tool calls without arguments an empty object, which
+// the tools expect
+func finishToolCalls(calls []openai.ToolCall) {
+	for i := range calls {
+		if strings.TrimSpace(calls[i].Function.Arguments) == "" {
+			calls[i].Function.Arguments = "{}"
+		}
+	}
+}
+
+// httpError describes a failed API response, preferring the message in its
+// JSON error body
+func httpError(resp *http.Response) string {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && len(body.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &detail) == nil && detail.Message != "" {
+			return fmt.Sprintf("%s: %s", resp.Status, detail.Message)
+		}
+		var text string
+		if json.Unmarshal(body.Error, &text) == nil && text != "" {
+			return fmt.Sprintf("%s: %s", resp.Status, text)
+		}
+	}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, text)
+	}
+	return resp.Status
+}
@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const ollamaBaseURL = "http://localhost:11434"
+
+// ollamaCalls numbers tool calls, which Ollama does not identify
+var ollamaCalls atomic.Int64
+
+// ollamaProvider talks to the native chat API of an Ollama server
+type ollamaProvider struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewOllama creates a provider for an Ollama server; an empty baseURL uses
+// the local default
+func NewOllama(baseURL string) Provider {
+	if baseURL == "" {
+		baseURL = ollamaBaseURL
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL // OLLAMA_HOST is often host:port
+	}
+	return &ollamaProvider{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+}
+
+// Name returns the provider name
+func (p *ollamaProvider) Name() string {
+	return Ollama
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openai.Tool   `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error"`
+}
+
+// ollamaRequestFrom translates a chat request. Ollama takes tool definitions
+// in the OpenAI format, but tool call arguments as JSON objects rather than
+// strings, and tool results by tool name rather than call ID.
+func ollamaRequestFrom(req Request) ollamaRequest {
+	out := ollamaRequest{Model: req.Model, Tools: req.Tools, Stream: true}
+	toolNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		m := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, tc := range msg.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			m.ToolCalls = append(m.ToolCalls, call)
+		}
+		if msg.Role == openai.ChatMessageRoleTool {
+			m.ToolName = toolNames[msg.ToolCallID]
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out
+}
+
+// StreamChat streams a chat response, reading one JSON object per line
+func (p *ollamaProvider) StreamChat(ctx context.Context, req Request, onChunk ChunkCallback) (openai.ChatCompletionMessage, error) {
+	complete := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	body, err := json.Marshal(ollamaRequestFrom(req))
+	if err != nil {
+		return complete, fmt.Errorf("failed to encode Ollama request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return complete, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Do(httpReq)
+	if err != nil {
+		return complete, fmt.Errorf("Ollama streaming API error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return complete, fmt.Errorf("Ollama streaming API error: %s", httpError(resp))
+	}
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return complete, fmt.Errorf("stream error: %w", err)
+		}
+		if chunk.Error != "" {
+			return complete, fmt.Errorf("stream error: %s", chunk.Error)
+		}
+		if text := chunk.Message.Content; text != "" {
+			content.WriteString(text)
+			if err := onChunk(text); err != nil {
+				return complete, err
+			}
+		}
+		for _, call := range chunk.Message.ToolCalls {
+			arguments := string(call.Function.Arguments)
+			var encoded string
+			if json.Unmarshal(call.Function.Arguments, &encoded) == nil {
+				arguments = encoded // Some models return the arguments as a JSON string
+			}
+			complete.ToolCalls = append(complete.ToolCalls, openai.ToolCall{
+				ID:       fmt.Sprintf("call_%d", ollamaCalls.Add(1)),
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: call.Function.Name, Arguments: arguments},
+			})
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return complete, fmt.Errorf("stream error: %w", err)
+	}
+	complete.Content = content.String()
+	finishToolCalls(complete.ToolCalls)
+	return complete, nil
+}
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"dbsage/internal/ai/streaming"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openAIProvider talks to the OpenAI chat completions API, or a server that
+// implements it
+type openAIProvider struct {
+	name      string
+	client    *openai.Client
+	streaming *streaming.StreamingHandler
+}
+
+// NewOpenAI creates a provider for the OpenAI API; an empty baseURL uses the
+// default endpoint
+func NewOpenAI(apiKey, baseURL string) Provider {
+	return newOpenAICompatible(OpenAI, apiKey, baseURL)
+}
+
+func newOpenAICompatible(name, apiKey, baseURL string) *openAIProvider {
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	return &openAIProvider{
+		name:      name,
+		client:    openai.NewClientWithConfig(config),
+		streaming: streaming.NewStreamingHandler(),
+	}
+}
+
+// Name returns the provider name
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+// StreamChat streams a chat completion; the API takes the request as is
+func (p *openAIProvider) StreamChat(ctx context.Context, req Request, onChunk ChunkCallback) (openai.ChatCompletionMessage, error) {
+	stream, err := p.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Tools:    req.Tools,
+		Stream:   true,
+	})
+	if err != nil {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("OpenAI streaming API error: %w", err)
+	}
+	return p.streaming.ProcessStream(ctx, stream, streaming.StreamingCallback(onChunk))
+}
+
+// Embed returns the embeddings of texts from the embeddings endpoint
+func (p *openAIProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings API error: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index >= 0 && data.Index < len(vectors) {
+			vectors[data.Index] = data.Embedding
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,155 @@
+// Package providers streams chat completions with tool calls from the LLM
+// backends dbsage can talk to. Conversations and tool definitions use the
+// OpenAI chat types throughout dbsage; each provider translates them to its
+// own API and the answer back.
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Provider names, as used in DBSAGE_PROVIDER and /model
+const (
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+	Ollama    = "ollama"
+	LlamaCpp  = "llamacpp"
+)
+
+// ErrMissingAPIKey is returned by New for a hosted provider without an API key
+var ErrMissingAPIKey = errors.New("no API key configured")
+
+// Request is one chat completion request
+type Request struct {
+	Model    string
+	Messages []openai.ChatCompletionMessage
+	Tools    []openai.Tool
+}
+
+// ChunkCallback receives the answer text as it streams in
+type ChunkCallback func(chunk string) error
+
+// Provider streams a chat completion, calling onChunk with the text as it
+// arrives, and returns the complete assistant message with its tool calls
+type Provider interface {
+	Name() string
+	StreamChat(ctx context.Context, req Request, onChunk ChunkCallback) (openai.ChatCompletionMessage, error)
+}
+
+// Config selects and configures a provider
+type Config struct {
+	Provider string
+	APIKey   string
+	BaseURL  string // Empty for the provider's default endpoint
+}
+
+// backend describes how a provider is configured from the environment
+type backend struct {
+	keyEnv, urlEnv string
+	defaultModel   string
+	needsKey       bool
+}
+
+var backends = map[string]backend{
+	OpenAI:    {keyEnv: "OPENAI_API_KEY", urlEnv: "OPENAI_BASE_URL", defaultModel: "gpt-4o-mini", needsKey: true},
+	Anthropic: {keyEnv: "ANTHROPIC_API_KEY", urlEnv: "ANTHROPIC_BASE_URL", defaultModel: "claude-sonnet-4-5", needsKey: true},
+	Ollama:    {urlEnv: "OLLAMA_HOST", defaultModel: "llama3.1"},
+	LlamaCpp:  {keyEnv: "LLAMACPP_API_KEY", urlEnv: "LLAMACPP_BASE_URL", defaultModel: "default"},
+}
+
+// Names returns the supported provider names, sorted
+func Names() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsProvider reports whether name is a supported provider
+func IsProvider(name string) bool {
+	_, ok := backends[strings.ToLower(name)]
+	return ok
+}
+
+// DefaultModel returns the model used with a provider when none is given
+func DefaultModel(provider string) string {
+	return backends[strings.ToLower(provider)].defaultModel
+}
+
+// ConfigFromEnv reads the key and endpoint of a provider from its environment
+// variables. An empty provider means DBSAGE_PROVIDER, which defaults to openai.
+func ConfigFromEnv(provider string) Config {
+	if provider == "" {
+		provider = os.Getenv("DBSAGE_PROVIDER")
+	}
+	if provider == "" {
+		provider = OpenAI
+	}
+	config := Config{Provider: strings.ToLower(provider)}
+	if b, ok := backends[config.Provider]; ok {
+		if b.keyEnv != "" {
+			config.APIKey = os.Getenv(b.keyEnv)
+		}
+		config.BaseURL = os.Getenv(b.urlEnv)
+	}
+	return config
+}
+
+// ModelFromEnv returns DBSAGE_MODEL, or the provider's default model
+func ModelFromEnv(provider string) string {
+	if model := os.Getenv("DBSAGE_MODEL"); model != "" {
+		return model
+	}
+	return DefaultModel(provider)
+}
+
+// New creates the provider a config selects
+func New(config Config) (Provider, error) {
+	b, ok := backends[config.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider %q (use %s)", config.Provider, strings.Join(Names(), ", "))
+	}
+	if b.needsKey && config.APIKey == "" {
+		return nil, fmt.Errorf("%w for %s: set %s", ErrMissingAPIKey, config.Provider, b.keyEnv)
+	}
+
+	switch config.Provider {
+	case Anthropic:
+		return NewAnthropic(config.APIKey, config.BaseURL), nil
+	case Ollama:
+		return NewOllama(config.BaseURL), nil
+	case LlamaCpp:
+		// llama.cpp's server speaks the OpenAI chat API
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = "http://localhost:8080/v1"
+		}
+		return newOpenAICompatible(LlamaCpp, config.APIKey, baseURL), nil
+	default:
+		return NewOpenAI(config.APIKey, config.BaseURL), nil
+	}
+}
+
+// ParseModel splits a /model argument into a provider and a model. Either
+// may be empty: "anthropic:claude-sonnet-4-5" names both, "ollama" only the
+// provider and "gpt-4.1" only the model. Model names may contain colons, as
+// in ollama:llama3.1:8b.
+func ParseModel(spec string) (provider, model string) {
+	spec = strings.TrimSpace(spec)
+	if IsProvider(spec) {
+		return strings.ToLower(spec), ""
+	}
+	if prefix, rest, ok := strings.Cut(spec, ":"); ok && IsProvider(prefix) {
+		return strings.ToLower(prefix), rest
+	}
+	return "", spec
+}
@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conversation is a turn that called a tool and got its result
+var conversation = []openai.ChatCompletionMessage{
+	{Role: openai.ChatMessageRoleSystem, Content: "You are a database assistant."},
+	{Role: openai.ChatMessageRoleUser, Content: "How many users?"},
+	{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+		{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql":"SELECT count(*) FROM users"}`}},
+		{ID: "call_2", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_all_tables", Arguments: ""}},
+	}},
+	{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: `{"rows":[[3]]}`},
+	{Role: openai.ChatMessageRoleTool, ToolCallID: "call_2", Content: `["users"]`},
+}
+
+var sqlTool = openai.Tool{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+	Name:        "execute_sql",
+	Description: "Run SQL",
+	Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"sql": map[string]interface{}{"type": "string"}}},
+}}
+
+func TestParseModel(t *testing.T) {
+	for spec, want := range map[string][2]string{
+		"gpt-4.1":                     {"", "gpt-4.1"},
+		"Ollama":                      {"ollama", ""},
+		"anthropic:claude-sonnet-4-5": {"anthropic", "claude-sonnet-4-5"},
+		"ollama:llama3.1:8b":          {"ollama", "llama3.1:8b"},
+		"llama3.1:8b":                 {"", "llama3.1:8b"},
+	} {
+		provider, model := ParseModel(spec)
+		assert.Equal(t, want, [2]string{provider, model}, spec)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("DBSAGE_PROVIDER", "anthropic")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	config := ConfigFromEnv("")
+	assert.Equal(t, Anthropic, config.Provider)
+	_, err := New(config)
+	assert.ErrorIs(t, err, ErrMissingAPIKey)
+
+	_, err = New(Config{Provider: "bard"})
+	assert.ErrorContains(t, err, "unknown AI provider")
+
+	p, err := New(ConfigFromEnv("ollama"))
+	require.NoError(t, err)
+	assert.Equal(t, Ollama, p.Name())
+	p, err = New(ConfigFromEnv("llamacpp"))
+	require.NoError(t, err)
+	assert.Equal(t, LlamaCpp, p.Name())
+
+	assert.Equal(t, "claude-sonnet-4-5", ModelFromEnv(Anthropic))
+	t.Setenv("DBSAGE_MODEL", "claude-haiku-4-5")
+	assert.Equal(t, "claude-haiku-4-5", ModelFromEnv(Anthropic))
+}
+
+func TestAnthropicRequestFrom(t *testing.T) {
+	req := anthropicRequestFrom(Request{Model: "claude", Messages: conversation, Tools: []openai.Tool{sqlTool}})
+	assert.Equal(t, "You are a database assistant.", req.System)
+	require.Len(t, req.Messages, 3)
+	assert.Equal(t, "assistant", req.Messages[1].Role)
+	assert.Equal(t, "tool_use", req.Messages[1].Content[0].Type)
+	assert.JSONEq(t, `{"sql":"SELECT count(*) FROM users"}`, string(req.Messages[1].Content[0].Input))
+	assert.JSONEq(t, `{}`, string(req.Messages[1].Content[1].Input))
+
+	// Both tool results go back in one user message
+	assert.Equal(t, "user", req.Messages[2].Role)
+	require.Len(t, req.Messages[2].Content, 2)
+	assert.Equal(t, "call_2", req.Messages[2].Content[1].ToolUseID)
+	require.Len(t, req.Tools, 1)
+	assert.Equal(t, sqlTool.Function.Parameters, req.Tools[0].InputSchema)
+}
+
+func TestAnthropicStreamChat(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"execute_sql","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"sql\": \"SELECT"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" 1\"}"}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "key", r.Header.Get("x-api-key"))
+		var body anthropicRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, body.Stream)
+		for _, event := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	var streamed strings.Builder
+	msg, err := NewAnthropic("key", server.URL).StreamChat(context.Background(), Request{Model: "claude", Messages: conversation[:2]}, func(chunk string) error {
+		streamed.WriteString(chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check.", streamed.String())
+	assert.Equal(t, "Let me check.", msg.Content)
+	require.Len(t, msg.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", msg.ToolCalls[0].ID)
+	assert.Equal(t, "execute_sql", msg.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"sql":"SELECT 1"}`, msg.ToolCalls[0].Function.Arguments)
+}
+
+func TestAnthropicStreamChat_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	}))
+	defer server.Close()
+
+	_, err := NewAnthropic("bad", server.URL).StreamChat(context.Background(), Request{}, func(string) error { return nil })
+	assert.ErrorContains(t, err, "invalid x-api-key")
+}
+
+func TestOllamaStreamChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		var body ollamaRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Messages, 5)
+		assert.JSONEq(t, `{"sql":"SELECT count(*) FROM users"}`, string(body.Messages[2].ToolCalls[0].Function.Arguments))
+		assert.Equal(t, "get_all_tables", body.Messages[4].ToolName, "tool results are matched by name")
+		assert.Equal(t, "execute_sql", body.Tools[0].Function.Name)
+
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"There are "}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"3 users."}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_table_schema","arguments":{"tableName":"users"}}}]}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	var streamed strings.Builder
+	msg, err := NewOllama(strings.TrimPrefix(server.URL, "http://")).StreamChat(context.Background(),
+		Request{Model: "llama3.1", Messages: conversation, Tools: []openai.Tool{sqlTool}},
+		func(chunk string) error {
+			streamed.WriteString(chunk)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "There are 3 users.", streamed.String())
+	require.Len(t, msg.ToolCalls, 1)
+	assert.NotEmpty(t, msg.ToolCalls[0].ID)
+	assert.Equal(t, "get_table_schema", msg.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"tableName":"users"}`, msg.ToolCalls[0].Function.Arguments)
+}
+
+func TestOllamaStreamChat_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama9\" not found, try pulling it first"}`)
+	}))
+	defer server.Close()
+
+	_, err := NewOllama(server.URL).StreamChat(context.Background(), Request{Model: "llama9"}, func(string) error { return nil })
+	assert.ErrorContains(t, err, "try pulling it first")
+}
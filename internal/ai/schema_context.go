@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultSchemaBudget is the size in tokens of the schema summary sent with
+// each request
+const DefaultSchemaBudget = 4000
+
+// SchemaBudget returns the token budget of the schema summary, set with
+// DBSAGE_SCHEMA_BUDGET (0 sends no summary)
+func SchemaBudget() int {
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_SCHEMA_BUDGET")); err == nil && n >= 0 {
+		return n
+	}
+	return DefaultSchemaBudget
+}
+
+// schemaSection returns the schema summary to send with a conversation and
+// remembers it for /context show. No summary is sent when the deployment
+// keeps the schema from the model or no connection is active.
+func (c *Client) schemaSection(ctx context.Context, messages []openai.ChatCompletionMessage) string {
+	budget := SchemaBudget()
+	if budget == 0 || c.capabilities.NoSchema {
+		c.setLastSchema(nil)
+		return ""
+	}
+
+	// Only what the user and the AI said counts as a mention; tool results
+	// such as get_all_tables name every table
+	var conversation strings.Builder
+	question := ""
+	for _, msg := range messages {
+		if msg.Role == openai.ChatMessageRoleUser || msg.Role == openai.ChatMessageRoleAssistant {
+			conversation.WriteString(msg.Content + "\n")
+		}
+		if msg.Role == openai.ChatMessageRoleUser {
+			question = msg.Content
+		}
+	}
+	selected, err := c.toolExecutor.SchemaContext(conversation.String(), budget, c.similarTablesFor(ctx, question))
+	if err != nil {
+		c.setLastSchema(nil)
+		return ""
+	}
+	c.setLastSchema(selected)
+	return selected.Prompt()
+}
+
+func (c *Client) setLastSchema(schema *sqlanalysis.SchemaContext) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastSchema = schema
+	c.schemaSent = true
+}
+
+// LastSchemaContext returns the schema summary sent with the last request, nil
+// when none was sent, and whether a request was sent at all
+func (c *Client) LastSchemaContext() (*sqlanalysis.SchemaContext, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastSchema, c.schemaSent
+}
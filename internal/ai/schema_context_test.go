@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaBudget(t *testing.T) {
+	t.Setenv("DBSAGE_SCHEMA_BUDGET", "")
+	assert.Equal(t, DefaultSchemaBudget, SchemaBudget())
+	t.Setenv("DBSAGE_SCHEMA_BUDGET", "0")
+	assert.Equal(t, 0, SchemaBudget())
+	t.Setenv("DBSAGE_SCHEMA_BUDGET", "-5")
+	assert.Equal(t, DefaultSchemaBudget, SchemaBudget())
+}
+
+func TestClient_SchemaSection(t *testing.T) {
+	client := NewClient("test-key", "", nil)
+	_, sent := client.LastSchemaContext()
+	assert.False(t, sent)
+
+	// Without a connection nothing is sent, and /context show can tell
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "orders"}}
+	assert.Empty(t, client.schemaSection(context.Background(), messages))
+	schema, sent := client.LastSchemaContext()
+	assert.Nil(t, schema)
+	assert.True(t, sent)
+
+	client.SetCapabilities(Capabilities{NoSchema: true})
+	assert.Empty(t, client.schemaSection(context.Background(), messages))
+}
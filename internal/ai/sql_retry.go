@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/sqlerrors"
+)
+
+// DefaultSQLCorrections is how many times a failed statement is handed back to
+// the AI for correction within one turn
+const DefaultSQLCorrections = 2
+
+// MaxSQLCorrections returns the number of automatic corrections per turn, set
+// with DBSAGE_SQL_RETRIES (0 disables them)
+func MaxSQLCorrections() int {
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_SQL_RETRIES")); err == nil && n >= 0 {
+		return n
+	}
+	return DefaultSQLCorrections
+}
+
+// sqlCorrectionResult turns a failed execute_sql call into a tool result that
+// asks the AI to fix the statement. The error is returned unchanged when it is
+// not something a rewrite can fix or the corrections for this turn are used up.
+func (c *Client) sqlCorrectionResult(sql string, execErr error) (string, error) {
+	if !tools.IsCorrectableSQLError(execErr) {
+		return "", execErr
+	}
+
+	limit := MaxSQLCorrections()
+	c.mu.Lock()
+	c.sqlCorrections++
+	attempt := c.sqlCorrections
+	c.mu.Unlock()
+
+	if attempt > limit {
+		if limit == 0 {
+			return "", execErr
+		}
+		return "", fmt.Errorf("SQL still failing after %d automatic corrections: %w", limit, execErr)
+	}
+
+	c.addNotice(fmt.Sprintf("SQL failed (correction %d/%d): %v. Asking the AI to fix the statement.", attempt, limit, execErr))
+
+	correction := map[string]interface{}{
+		"error":              execErr.Error(),
+		"failed_sql":         sql,
+		"correction_attempt": attempt,
+		"max_corrections":    limit,
+		"instruction": "The statement failed. Fix it based on the error and call execute_sql again. " +
+			"If a table or column is unknown, check the schema with get_table_schema first.",
+	}
+	if explanation := sqlerrors.Explain(execErr); explanation != nil {
+		c.addNotice(explanation.Summary())
+		correction["explanation"] = explanation.String()
+	}
+	payload, err := json.Marshal(correction)
+	if err != nil {
+		return "", execErr
+	}
+	return string(payload), nil
+}
+
+// resetSQLCorrections starts a new budget of automatic corrections
+func (c *Client) resetSQLCorrections() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sqlCorrections = 0
+}
+
+// addNotice queues a notice shown to the user after the current tool round
+func (c *Client) addNotice(notice string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notices = append(c.notices, notice)
+}
+
+// takeNotices returns the queued client notices and clears them
+func (c *Client) takeNotices() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	notices := c.notices
+	c.notices = nil
+	return notices
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLCorrectionResult(t *testing.T) {
+	t.Setenv("DBSAGE_SQL_RETRIES", "2")
+	c := NewClient("test-key", "", nil)
+	execErr := errors.New(`pq: column "nmae" does not exist`)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		result, err := c.sqlCorrectionResult("SELECT nmae FROM users", execErr)
+		require.NoError(t, err)
+
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result), &payload))
+		assert.Equal(t, float64(attempt), payload["correction_attempt"])
+		assert.Equal(t, "SELECT nmae FROM users", payload["failed_sql"])
+	}
+
+	notices := c.takeNotices()
+	require.Len(t, notices, 2)
+	assert.Contains(t, notices[1], "correction 2/2")
+
+	// The budget is used up, so the error ends the turn
+	_, err := c.sqlCorrectionResult("SELECT nmae FROM users", execErr)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, execErr)
+	assert.Contains(t, err.Error(), "after 2 automatic corrections")
+
+	// A new turn starts with a fresh budget
+	c.BeginTurn()
+	_, err = c.sqlCorrectionResult("SELECT nmae FROM users", execErr)
+	assert.NoError(t, err)
+}
+
+func TestSQLCorrectionResult_NotCorrectable(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	execErr := errors.New("dial tcp 127.0.0.1:5432: connection refused")
+
+	_, err := c.sqlCorrectionResult("SELECT 1", execErr)
+	assert.Equal(t, execErr, err)
+	assert.Empty(t, c.takeNotices())
+}
+
+func TestSQLCorrectionResult_Disabled(t *testing.T) {
+	t.Setenv("DBSAGE_SQL_RETRIES", "0")
+	c := NewClient("test-key", "", nil)
+	execErr := errors.New("near \"SELEC\": syntax error")
+
+	_, err := c.sqlCorrectionResult("SELEC 1", execErr)
+	assert.Equal(t, execErr, err)
+}
+
+func TestSQLCorrectionResult_Explained(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	execErr := errors.New("no such column: nmae")
+
+	result, err := c.sqlCorrectionResult("SELECT nmae FROM users", execErr)
+	require.NoError(t, err)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result), &payload))
+	assert.Contains(t, payload["explanation"], "Undefined column")
+
+	notices := c.takeNotices()
+	require.Len(t, notices, 2)
+	assert.Contains(t, notices[1], "Undefined column: A column in the statement does not exist")
+}
@@ -0,0 +1,70 @@
+package ai
+
+// stepPromptSection asks the model to explain its tool calls while the user
+// reviews them one at a time
+const stepPromptSection = `
+
+STEP MODE:
+The user reviews every tool call before it runs. Before calling tools, say in one short sentence what the next call is for.`
+
+// SetStepMode switches step mode on or off. In step mode every tool call of a
+// turn waits for the user, starting with the next turn.
+func (c *Client) SetStepMode(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stepMode = on
+}
+
+// StepMode reports whether step mode is on
+func (c *Client) StepMode() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stepMode
+}
+
+// Stepping reports whether the current turn pauses before each tool call
+func (c *Client) Stepping() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stepping
+}
+
+// StopStepping lets the rest of the current turn run without pausing. Tools
+// that always need confirmation still ask for it.
+func (c *Client) StopStepping() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stepping = false
+}
+
+// NextStep returns the number of the next tool call in the current turn
+func (c *Client) NextStep() int {
+	return c.countPhases(PhaseTool) + 1
+}
+
+// toolResult returns the result of a tool call of the message being run
+func (c *Client) toolResult(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.toolResults[id]
+	return result, ok
+}
+
+// setToolResult keeps the result of a tool call until the whole message has run
+func (c *Client) setToolResult(id, result string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.toolResults == nil {
+		c.toolResults = make(map[string]string)
+	}
+	c.toolResults[id] = result
+}
+
+// takeToolResults returns and forgets the kept tool call results
+func (c *Client) takeToolResults() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := c.toolResults
+	c.toolResults = nil
+	return results
+}
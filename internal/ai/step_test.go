@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepMode_PausesEveryToolCallOfTheTurn(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	var asked []string
+	c.SetToolConfirmationCallback(func(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, callback StreamingCallback) (bool, error) {
+		asked = append(asked, toolCall.Function.Name)
+		return false, nil
+	})
+	toolCall := openai.ToolCall{ID: "call-1", Function: openai.FunctionCall{Name: "list_tables", Arguments: "{}"}}
+
+	// Step mode takes effect with the next turn
+	c.SetStepMode(true)
+	assert.True(t, c.StepMode())
+	assert.False(t, c.Stepping())
+	c.BeginTurn()
+	require.True(t, c.Stepping())
+	assert.Contains(t, c.systemPrompt(), "STEP MODE")
+	assert.Equal(t, 1, c.NextStep())
+
+	result, err := c.executeToolWithConfirmation(context.Background(), nil, openai.ChatCompletionMessage{}, toolCall, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "CONFIRMATION_PENDING", result)
+	assert.Equal(t, []string{"list_tables"}, asked)
+
+	// Continue runs the rest of the turn without pausing
+	c.StopStepping()
+	assert.False(t, c.Stepping())
+	assert.True(t, c.StepMode())
+	assert.NotContains(t, c.systemPrompt(), "STEP MODE")
+
+	c.SetStepMode(false)
+	c.BeginTurn()
+	assert.False(t, c.Stepping())
+}
+
+func TestStepMode_ToolResultsKeptUntilTaken(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	c.setToolResult("call-1", "3 rows")
+
+	result, ok := c.toolResult("call-1")
+	assert.True(t, ok)
+	assert.Equal(t, "3 rows", result)
+
+	assert.Equal(t, map[string]string{"call-1": "3 rows"}, c.takeToolResults())
+	_, ok = c.toolResult("call-1")
+	assert.False(t, ok)
+
+	// A new turn forgets results of an aborted one
+	c.setToolResult("call-2", "done")
+	c.BeginTurn()
+	_, ok = c.toolResult("call-2")
+	assert.False(t, ok)
+}
@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/pkg/database"
+)
+
+// Phase kinds recorded for a turn
+const (
+	PhaseLLM          = "llm"
+	PhaseTool         = "tool"
+	PhaseRender       = "render"
+	PhaseConfirmation = "confirmation"
+)
+
+// TimingPhase is one timed step of a turn
+type TimingPhase struct {
+	Kind     string
+	Name     string
+	Detail   string
+	Duration time.Duration
+}
+
+// TurnTiming records where the time of one conversation turn was spent, from
+// sending the prompt until the answer has been rendered
+type TurnTiming struct {
+	Start  time.Time
+	End    time.Time
+	Phases []TimingPhase
+}
+
+// Total returns the wall-clock time of the turn
+func (t *TurnTiming) Total() time.Duration {
+	if t.End.IsZero() {
+		return time.Since(t.Start)
+	}
+	return t.End.Sub(t.Start)
+}
+
+// TotalByKind sums the durations of all phases of a kind
+func (t *TurnTiming) TotalByKind(kind string) time.Duration {
+	var total time.Duration
+	for _, phase := range t.Phases {
+		if phase.Kind == kind {
+			total += phase.Duration
+		}
+	}
+	return total
+}
+
+// Summary returns a one-line breakdown, e.g. "llm 1.2s · tools 35ms · render 4ms"
+func (t *TurnTiming) Summary() string {
+	parts := []string{fmt.Sprintf("llm %s", formatDuration(t.TotalByKind(PhaseLLM)))}
+	if tools := t.TotalByKind(PhaseTool); tools > 0 {
+		parts = append(parts, fmt.Sprintf("tools %s", formatDuration(tools)))
+	}
+	if render := t.TotalByKind(PhaseRender); render > 0 {
+		parts = append(parts, fmt.Sprintf("render %s", formatDuration(render)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Report returns the full per-phase breakdown of the turn
+func (t *TurnTiming) Report() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Last turn: %s total\n\n", formatDuration(t.Total())))
+
+	for i, phase := range t.Phases {
+		line := fmt.Sprintf("%d. %-12s %-10s %s", i+1, phase.Kind, formatDuration(phase.Duration), phase.Name)
+		if phase.Detail != "" {
+			line += " (" + phase.Detail + ")"
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	llm := t.TotalByKind(PhaseLLM)
+	tools := t.TotalByKind(PhaseTool)
+	b.WriteString(fmt.Sprintf("\nModel: %s · Tools/database: %s · Rendering: %s",
+		formatDuration(llm), formatDuration(tools), formatDuration(t.TotalByKind(PhaseRender))))
+	if wait := t.TotalByKind(PhaseConfirmation); wait > 0 {
+		b.WriteString(fmt.Sprintf(" · Waiting for confirmation: %s", formatDuration(wait)))
+	}
+
+	switch {
+	case llm == 0 && tools == 0:
+	case tools > llm:
+		b.WriteString("\nMost of the time was spent running tools against the database.")
+	default:
+		b.WriteString("\nMost of the time was spent waiting for the model.")
+	}
+	return b.String()
+}
+
+// copy returns a deep copy that is safe to read while the original is updated
+func (t *TurnTiming) copy() *TurnTiming {
+	c := *t
+	c.Phases = append([]TimingPhase(nil), t.Phases...)
+	return &c
+}
+
+// formatDuration rounds a duration for display
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// BeginTurn starts a new turn, timing it, resetting the SQL correction budget
+// and numbering the statements' query labels
+func (c *Client) BeginTurn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.currentTurn = &TurnTiming{Start: time.Now()}
+	c.sqlCorrections = 0
+	c.turns++
+	c.stepping = c.stepMode
+	c.toolResults = nil
+	database.SetQueryLabelValue("turn", strconv.Itoa(c.turns))
+}
+
+// RecordPhase adds a timed phase to the current turn. It is a no-op when no
+// turn is being timed.
+func (c *Client) RecordPhase(kind, name, detail string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.currentTurn == nil {
+		return
+	}
+	c.currentTurn.Phases = append(c.currentTurn.Phases, TimingPhase{
+		Kind:     kind,
+		Name:     name,
+		Detail:   detail,
+		Duration: duration,
+	})
+}
+
+// FinishTurn ends the current turn, making it available from LastTurnTiming
+func (c *Client) FinishTurn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.currentTurn == nil {
+		return
+	}
+	c.currentTurn.End = time.Now()
+	c.lastTurn = c.currentTurn
+	c.currentTurn = nil
+}
+
+// LastTurnTiming returns the timing of the last finished turn, or nil if there is none
+func (c *Client) LastTurnTiming() *TurnTiming {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastTurn == nil {
+		return nil
+	}
+	return c.lastTurn.copy()
+}
+
+// countPhases returns how many phases of a kind the current turn has
+func (c *Client) countPhases(kind string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.currentTurn == nil {
+		return 0
+	}
+	count := 0
+	for _, phase := range c.currentTurn.Phases {
+		if phase.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnTiming(t *testing.T) {
+	c := NewClient("test-key", "", nil)
+	assert.Nil(t, c.LastTurnTiming())
+
+	// Phases outside a turn are ignored
+	c.RecordPhase(PhaseLLM, "request 1", "", time.Second)
+
+	c.BeginTurn()
+	c.recordCompletion(openai.ChatCompletionMessage{
+		ToolCalls: []openai.ToolCall{{Function: openai.FunctionCall{Name: "execute_sql"}}},
+	}, 800*time.Millisecond)
+	c.RecordPhase(PhaseTool, "execute_sql", "SELECT 1", 2*time.Second)
+	c.recordCompletion(openai.ChatCompletionMessage{}, 400*time.Millisecond)
+	c.RecordPhase(PhaseRender, "view", "12 frames", 5*time.Millisecond)
+	c.FinishTurn()
+
+	timing := c.LastTurnTiming()
+	require.NotNil(t, timing)
+	require.Len(t, timing.Phases, 4)
+	assert.Equal(t, "request 1", timing.Phases[0].Name)
+	assert.Equal(t, "requested execute_sql", timing.Phases[0].Detail)
+	assert.Equal(t, "request 2", timing.Phases[2].Name)
+	assert.Equal(t, 1200*time.Millisecond, timing.TotalByKind(PhaseLLM))
+
+	assert.Equal(t, "llm 1.2s · tools 2s · render 5ms", timing.Summary())
+	report := timing.Report()
+	assert.Contains(t, report, "execute_sql (SELECT 1)")
+	assert.Contains(t, report, "Most of the time was spent running tools against the database.")
+}
+
+func TestToolCallDetail(t *testing.T) {
+	call := openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "execute_sql",
+		Arguments: `{"sql": "SELECT id,\n  name FROM users"}`,
+	}}
+	assert.Equal(t, "SELECT id, name FROM users", toolCallDetail(call))
+
+	call.Function.Arguments = `{"tableName": "orders"}`
+	assert.Equal(t, "orders", toolCallDetail(call))
+}
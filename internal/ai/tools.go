@@ -9,13 +9,17 @@ func GetTools() []openai.Tool {
 			Type: openai.ToolTypeFunction,
 			Function: &openai.FunctionDefinition{
 				Name:        "execute_sql",
-				Description: "Execute a SQL query",
+				Description: "Execute a SQL query, or a script of statements separated by semicolons. Scripts run statement by statement and return each statement's result, duration and error, stopping at the first failure",
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
 						"sql": map[string]interface{}{
 							"type":        "string",
-							"description": "The SQL query to execute",
+							"description": "The SQL query or script to execute",
+						},
+						"continueOnError": map[string]interface{}{
+							"type":        "boolean",
+							"description": "For scripts, run the remaining statements after one fails instead of stopping (default false)",
 						},
 					},
 					"required": []string{"sql"},
@@ -68,6 +72,23 @@ func GetTools() []openai.Tool {
 				},
 			},
 		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "analyze_query",
+				Description: "Run the built-in SQL optimizer on a query without executing it: lint and optimizer findings plus warnings from the estimated plan (full scans on large tables, nested loop blowups, disk sorts)",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"sql": map[string]interface{}{
+							"type":        "string",
+							"description": "The SQL query to analyze",
+						},
+					},
+					"required": []string{"sql"},
+				},
+			},
+		},
 		{
 			Type: openai.ToolTypeFunction,
 			Function: &openai.FunctionDefinition{
@@ -85,6 +106,427 @@ func GetTools() []openai.Tool {
 				},
 			},
 		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_table_stats",
+				Description: "Get the estimated row count, table and index size and vacuum/analyze state of a table. For TimescaleDB hypertables it also reports chunk counts, compression status and retention policies with suggestions",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The name of the table",
+						},
+					},
+					"required": []string{"tableName"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_rls_policies",
+				Description: "PostgreSQL only: list row-level security policies (roles, USING/WITH CHECK expressions, enabled/forced state) and explain which apply to the connected role. Use it when rows are unexpectedly missing or writes fail with a policy violation",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The table to inspect; omit to list every table that uses row-level security",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "setup_fts",
+				Description: "Generate, without executing, the statements that add full-text search to text columns: a tsvector column with a GIN index (PostgreSQL), a FULLTEXT index (MySQL) or an FTS5 table with sync triggers (SQLite), plus a sample search query",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The name of the table",
+						},
+						"columns": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "string",
+							},
+							"description": "Text columns to search, most important first (PostgreSQL ranks earlier columns higher)",
+						},
+						"language": map[string]interface{}{
+							"type":        "string",
+							"description": "PostgreSQL text search configuration, e.g. english (default), simple, german",
+						},
+						"useTrigger": map[string]interface{}{
+							"type":        "boolean",
+							"description": "PostgreSQL only: maintain the tsvector with a trigger instead of a generated column, for servers before 12",
+						},
+					},
+					"required": []string{"tableName", "columns"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "list_extensions",
+				Description: "List installed extensions (PostgreSQL), plugins (MySQL) or compiled-in modules (SQLite), with install instructions for known extensions that are missing",
+				Parameters: map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "list_features",
+				Description: "List server version and settings that matter for analysis (preloaded libraries, performance_schema, timing settings), with instructions for missing capabilities",
+				Parameters: map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "profile_waits",
+				Description: "Sample the wait events of the other active sessions (pg_stat_activity or performance_schema) for a few seconds while a slow operation runs and report the top wait types (locks, I/O, CPU, ...) with example queries",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"seconds": map[string]interface{}{
+							"type":        "number",
+							"description": "How long to sample, default 10, at most 60",
+						},
+						"intervalMs": map[string]interface{}{
+							"type":        "integer",
+							"description": "Milliseconds between samples, default 200, at least 50",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "advise_config",
+				Description: "Compare key server settings (shared_buffers, effective_cache_size, work_mem, innodb_buffer_pool_size, max_connections, ...) with the RAM and CPUs of the database host and return a tuned configuration diff with the rationale for each change (does not apply it)",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"memoryGB": map[string]interface{}{
+							"type":        "number",
+							"description": "RAM of the database host in GB; probed from the server when omitted (PostgreSQL on Linux with sufficient privileges)",
+						},
+						"cpus": map[string]interface{}{
+							"type":        "integer",
+							"description": "CPU count of the database host; probed like memoryGB when omitted",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_temp_usage",
+				Description: "PostgreSQL only: report temporary files written by sorts and hashes that exceeded work_mem (pg_stat_database temp_files/temp_bytes), the statements that spilled most (pg_stat_statements), and work_mem or rewrite recommendations",
+				Parameters: map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_collations",
+				Description: "Report the database encoding and the character sets and collations of text columns, flag utf8mb3 columns, foreign keys joining different collations and mixed ICU/libc collations, with conversion DDL and size/lock warnings (does not execute it)",
+				Parameters: map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "copy_table",
+				Description: "Copy a table (schema and data) from the current or a named connection to another configured connection, in batches. The target table is created with types mapped to the target engine (primary key and NOT NULL only). A failed copy can be resumed where it stopped. The copy is shown to the user for confirmation",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The table to copy",
+						},
+						"target": map[string]interface{}{
+							"type":        "string",
+							"description": "Name of the connection to copy to",
+						},
+						"source": map[string]interface{}{
+							"type":        "string",
+							"description": "Name of the connection to copy from; defaults to the current connection",
+						},
+						"targetTable": map[string]interface{}{
+							"type":        "string",
+							"description": "Name of the table on the target; defaults to tableName",
+						},
+						"batchSize": map[string]interface{}{
+							"type":        "integer",
+							"description": "Rows per batch, default 1000, at most 10000",
+						},
+						"resume": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Continue a previous copy of the same table that stopped part way",
+						},
+					},
+					"required": []string{"tableName", "target"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "watch_table",
+				Description: "Watch the inserts, updates and deletes on a table for a few seconds while the user reproduces a problem. Temporary helper triggers log the changed rows and are always removed afterwards. Requires permission to create triggers; the watch is shown to the user for confirmation",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The table to watch",
+						},
+						"seconds": map[string]interface{}{
+							"type":        "integer",
+							"description": "How long to watch, default 15, at most 120",
+						},
+						"intervalMs": map[string]interface{}{
+							"type":        "integer",
+							"description": "Milliseconds between reads of the captured changes, default 500, at least 200",
+						},
+						"maxEvents": map[string]interface{}{
+							"type":        "integer",
+							"description": "Most changes to return, default 100, at most 500; further changes are only counted",
+						},
+					},
+					"required": []string{"tableName"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "export_results",
+				Description: "Save the full result of the last execute_sql query, or of a given read-only query, to a CSV, JSON or Excel file. Exports the complete result rather than the rows shown to you, up to the export row limit",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "The file to write, e.g. orders.csv; the format follows the extension",
+						},
+						"format": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"csv", "json", "md", "xlsx"},
+							"description": "The file format, when the extension does not name it",
+						},
+						"sql": map[string]interface{}{
+							"type":        "string",
+							"description": "A read-only query to run and export instead of the last result",
+						},
+					},
+					"required": []string{"path"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "sample_results",
+				Description: "Sample the full result of the last execute_sql query, or of a given read-only query, for analysis. Sampling is stratified by a chosen column so every value keeps its share of the rows, and the result's sampling field describes the sample so conclusions can be caveated",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"column": map[string]interface{}{
+							"type":        "string",
+							"description": "The column to stratify by, e.g. a status or region; omit for an evenly spaced sample",
+						},
+						"size": map[string]interface{}{
+							"type":        "integer",
+							"description": "The most rows in the sample (default and maximum 200)",
+						},
+						"sql": map[string]interface{}{
+							"type":        "string",
+							"description": "A read-only query to run and sample instead of the last result",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "preview_table",
+				Description: "Look at a sample of a table's rows without writing a query: the first rows by primary key, a random sample, or the newest rows by a timestamp column. Long text and JSON values are shortened, and the result's sampling field says how the rows were picked",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The table to preview",
+						},
+						"mode": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"first", "random", "newest"},
+							"description": "How to pick the rows (default first)",
+						},
+						"rows": map[string]interface{}{
+							"type":        "integer",
+							"description": "The number of rows (default 10, maximum 100)",
+						},
+						"orderColumn": map[string]interface{}{
+							"type":        "string",
+							"description": "The column ordering the newest rows; omit to use the table's creation or update timestamp",
+						},
+					},
+					"required": []string{"tableName"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "generate_erd",
+				Description: "Draw an entity-relationship diagram of the tables of a schema, with their columns, keys and the foreign keys between them, as Mermaid or Graphviz DOT source",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type":        "string",
+							"description": "The schema to draw, e.g. public; omit for every schema",
+						},
+						"tables": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": "Only draw these tables, e.g. the ones a question is about",
+						},
+						"format": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"mermaid", "dot"},
+							"description": "The diagram language (default mermaid)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "trace_column",
+				Description: "Trace a view column through the view definitions, CTEs and subqueries it is built from down to the base table columns that feed it. Use for impact analysis before altering or dropping a base table column",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"column": map[string]interface{}{
+							"type":        "string",
+							"description": "The view column to trace, as view.column",
+						},
+					},
+					"required": []string{"column"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_query_history",
+				Description: "Read statements executed in earlier sessions from the query history, newest first, with their time, connection, duration, row count and error. Use when the user refers to a query they ran before",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"search": map[string]interface{}{
+							"type":        "string",
+							"description": "Words every returned statement must contain, e.g. a table name. Omit for the most recent statements",
+						},
+						"connection": map[string]interface{}{
+							"type":        "string",
+							"description": "Only return statements run on this connection",
+						},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": "Most statements to return (default 20, at most 100)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "advise_ctes",
+				Description: "PostgreSQL only: find CTEs evaluated inefficiently (optimization fences before version 12, MATERIALIZED / NOT MATERIALIZED hints working against the reference count), subqueries repeated in one statement and correlated subqueries that run per row, and compare the EXPLAIN plan of each suggested rewrite with the original",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"sql": map[string]interface{}{
+							"type":        "string",
+							"description": "The SQL query to restructure",
+						},
+						"analyze": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Compare actual execution times with EXPLAIN ANALYZE, which runs the query and each rewrite, so the user is asked to confirm it (read-only statements only; default false compares estimated costs)",
+						},
+					},
+					"required": []string{"sql"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "advise_indexes",
+				Description: "PostgreSQL and MySQL: propose indexes for the predicates (equality, range, join and sort columns) of the heaviest statements in pg_stat_statements or the performance_schema digests, skipping those existing indexes already serve; on PostgreSQL with HypoPG, plan each top suggestion as a hypothetical index and report whether the planner uses it and the estimated cost change (builds nothing)",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": "Heaviest statements to analyze (default 50, at most 200)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "generate_test_data",
+				Description: "Insert rows of synthetic test data you generate into a table. Each row is checked like insert_row, the INSERTs are shown to the user for confirmation, and the primary key of every inserted row is recorded so /cleanup generated can delete exactly those rows later, even in another session. The table needs a primary key",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The name of the table",
+						},
+						"rows": map[string]interface{}{
+							"type":        "array",
+							"description": "Rows to insert (at most 500), each an object of column names mapped to plain values (never SQL expressions)",
+							"items":       map[string]interface{}{"type": "object"},
+						},
+					},
+					"required": []string{"tableName", "rows"},
+				},
+			},
+		},
 		{
 			Type: openai.ToolTypeFunction,
 			Function: &openai.FunctionDefinition{
@@ -109,5 +551,81 @@ func GetTools() []openai.Tool {
 				},
 			},
 		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "generate_code",
+				Description: "Wrap the last executed SQL (or the given SQL) into a ready-to-paste code snippet with parameter binding and error handling",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"language": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"go", "python", "node"},
+							"description": "Target language; the driver follows the connected database: go (database/sql), python (psycopg, pymysql, sqlite3), node (pg, mysql2, better-sqlite3)",
+						},
+						"sql": map[string]interface{}{
+							"type":        "string",
+							"description": "Optional SQL statement; defaults to the last executed SQL",
+						},
+					},
+					"required": []string{"language"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "insert_row",
+				Description: "Insert one row from field values. Values are checked against the column types, NOT NULL and enum constraints, omitted columns use their defaults, and a parameterized INSERT is built and shown to the user for confirmation",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The name of the table",
+						},
+						"values": map[string]interface{}{
+							"type":        "object",
+							"description": "Column names mapped to plain values (strings, numbers, booleans, null or JSON objects); never SQL expressions",
+						},
+					},
+					"required": []string{"tableName", "values"},
+				},
+			},
+		},
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "update_rows",
+				Description: "Update rows targeted either by primary-key values, or by a WHERE predicate plus the exact number of rows it should change. The update runs in a transaction and is rolled back if a different number of rows is affected. The statement is shown to the user for confirmation",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tableName": map[string]interface{}{
+							"type":        "string",
+							"description": "The name of the table",
+						},
+						"values": map[string]interface{}{
+							"type":        "object",
+							"description": "Columns to set, mapped to plain values (never SQL expressions)",
+						},
+						"keys": map[string]interface{}{
+							"type":        "object",
+							"description": "Primary-key column names mapped to the values of the single row to update",
+						},
+						"where": map[string]interface{}{
+							"type":        "string",
+							"description": "SQL predicate selecting the rows when keys are not used, e.g. status = 'pending' AND created_at < '2024-01-01'",
+						},
+						"expectedRows": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of rows the where predicate is expected to change; count them with a SELECT first",
+						},
+					},
+					"required": []string{"tableName", "values"},
+				},
+			},
+		},
 	}
 }
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+func (e *Executor) adviseConfig(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	query, err := sqlanalysis.BuildConfigQuery(dialect)
+	if err != nil {
+		return "", err
+	}
+
+	host, ok := hostResources(dbTools, dialect, args)
+	if !ok {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":       "the RAM and CPU count of the database host are unknown and could not be probed",
+			"instruction": "Ask the user how much RAM (memoryGB) and how many CPUs (cpus) the database server has, then call advise_config again with them.",
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal error: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		if missing := e.missingPrivilegeResult(dbTools, err); missing != "" {
+			return missing, nil
+		}
+		return "", fmt.Errorf("failed to read server settings: %w", err)
+	}
+	advice := sqlanalysis.AdviseConfig(dialect, sqlanalysis.ConfigSettingsFromResult(dialect, result), host)
+
+	resultJSON, err := json.Marshal(advice)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal configuration advice: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// hostResources takes the host RAM and CPUs from the arguments, or probes them
+// through a PostgreSQL server running on Linux. Partial arguments are
+// completed by the probe.
+func hostResources(dbTools dbinterfaces.DatabaseInterface, dialect string, args map[string]interface{}) (sqlanalysis.HostResources, bool) {
+	memoryGB, _ := args["memoryGB"].(float64)
+	cpus, _ := args["cpus"].(float64)
+	memory := int64(memoryGB * (1 << 30))
+	source := "provided"
+
+	if (memory <= 0 || cpus <= 0) && dialect == "postgresql" {
+		source = "probed"
+		if memory <= 0 {
+			if result, err := dbTools.ExecuteSQL(sqlanalysis.MemInfoProbeQuery); err == nil && result != nil && len(result.Rows) > 0 {
+				memory, _ = sqlanalysis.ParseMemInfo(fmt.Sprintf("%s", result.Rows[0][0]))
+			}
+		}
+		if cpus <= 0 {
+			if result, err := dbTools.ExecuteSQL(sqlanalysis.CPUInfoProbeQuery); err == nil && result != nil && len(result.Rows) > 0 {
+				count, _ := sqlanalysis.ParseCPUInfo(fmt.Sprintf("%s", result.Rows[0][0]))
+				cpus = float64(count)
+			}
+		}
+	}
+	if memory <= 0 || cpus <= 0 {
+		return sqlanalysis.HostResources{}, false
+	}
+	return sqlanalysis.NewHostResources(memory, int(cpus), source), true
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_AdviseConfig_AsksForHostWhenProbeFails(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("ExecuteSQL", sqlanalysis.MemInfoProbeQuery).
+		Return((*models.QueryResult)(nil), errors.New("pq: permission denied for function pg_read_file"))
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_config", Arguments: `{"cpus": 4}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "memoryGB")
+	mockDB.AssertNotCalled(t, "ExecuteSQL", sqlanalysis.CPUInfoProbeQuery)
+}
+
+func TestExecutor_AdviseConfig_ProvidedHost(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	query, err := sqlanalysis.BuildConfigQuery("postgresql")
+	require.NoError(t, err)
+	mockDB.On("ExecuteSQL", query).Return(&models.QueryResult{Rows: [][]interface{}{{"shared_buffers", "16384", "8kB"}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_config", Arguments: `{"memoryGB": 8, "cpus": 2}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"source":"provided"`)
+	assert.Contains(t, output, `"recommended":"2GB"`)
+}
@@ -0,0 +1,445 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Supported code generation targets
+const (
+	LanguageGo     = "go"
+	LanguagePython = "python"
+	LanguageNode   = "node"
+)
+
+// CodeSnippet represents a generated code snippet for a SQL statement
+type CodeSnippet struct {
+	Language         string   `json:"language"`
+	Driver           string   `json:"driver"`
+	SQL              string   `json:"sql"`
+	ParameterizedSQL string   `json:"parameterized_sql"`
+	Parameters       []string `json:"parameters"`
+	Code             string   `json:"code"`
+}
+
+// sqlLiteral represents a literal value extracted from a SQL statement
+type sqlLiteral struct {
+	text     string // Literal value without quotes
+	isString bool
+}
+
+// GetSupportedLanguages returns the languages supported by the code generator
+func GetSupportedLanguages() []string {
+	return []string{LanguageGo, LanguagePython, LanguageNode}
+}
+
+// GenerateCodeSnippet wraps a SQL statement into a ready-to-paste code snippet
+// for the driver of the dialect, PostgreSQL when it is empty
+func GenerateCodeSnippet(language, query, dialect string) (*CodeSnippet, error) {
+	query = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if query == "" {
+		return nil, fmt.Errorf("no SQL statement to generate code for")
+	}
+
+	switch dialect {
+	case "", "postgres", "postgresql":
+		dialect = "postgresql"
+	case "mysql", "sqlite":
+	default:
+		return nil, fmt.Errorf("code generation is not supported for %s databases (supported: postgresql, mysql, sqlite)", dialect)
+	}
+
+	switch strings.ToLower(language) {
+	case LanguageGo, "golang":
+		language = LanguageGo
+	case LanguagePython, "py", "psycopg":
+		language = LanguagePython
+	case LanguageNode, "nodejs", "javascript", "js", "pg":
+		language = LanguageNode
+	default:
+		return nil, fmt.Errorf("unsupported language: %s (supported: %s)", language, strings.Join(GetSupportedLanguages(), ", "))
+	}
+
+	// The Python drivers for PostgreSQL and MySQL use %s for every parameter
+	// and so need literal percent signs doubled; sqlite3 uses ?
+	marker := func(index int) string { return placeholder(dialect, index) }
+	pythonFormat := language == LanguagePython && dialect != "sqlite"
+	if pythonFormat {
+		marker = func(index int) string { return "%s" }
+	}
+
+	parameterized, literals := parameterizeSQL(query, marker, pythonFormat)
+	returnsRows := statementReturnsRows(query)
+
+	snippet := &CodeSnippet{
+		Language:         language,
+		SQL:              query,
+		ParameterizedSQL: parameterized,
+	}
+	for _, lit := range literals {
+		snippet.Parameters = append(snippet.Parameters, lit.text)
+	}
+
+	switch language {
+	case LanguageGo:
+		snippet.Driver, snippet.Code = generateGoCode(parameterized, literals, returnsRows, dialect)
+	case LanguagePython:
+		snippet.Driver, snippet.Code = generatePythonCode(parameterized, literals, returnsRows, dialect)
+	case LanguageNode:
+		snippet.Driver, snippet.Code = generateNodeCode(parameterized, literals, returnsRows, dialect)
+	}
+
+	return snippet, nil
+}
+
+// parameterizeSQL replaces string and numeric literals with placeholders.
+// ORDER BY and GROUP BY ordinals and the literal of an INTERVAL are kept, as
+// a parameter there would sort by a constant or not parse.
+func parameterizeSQL(query string, marker func(index int) string, escapePercent bool) (string, []sqlLiteral) {
+	var result strings.Builder
+	var literals []sqlLiteral
+
+	// prev is the last token outside whitespace: a keyword in upper case or
+	// a symbol. byDepth is the parenthesis depth of the ORDER BY or GROUP BY
+	// list being read, -1 outside one.
+	prev := ""
+	depth, byDepth := 0, -1
+	keepLiteral := func() bool {
+		return prev == "INTERVAL" || (depth == byDepth && (prev == "BY" || prev == ","))
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+
+		switch {
+		case ch == '\'':
+			// String literal, '' is an escaped quote
+			var value strings.Builder
+			j := i + 1
+			for j < len(runes) {
+				if runes[j] == '\'' {
+					if j+1 < len(runes) && runes[j+1] == '\'' {
+						value.WriteRune('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				value.WriteRune(runes[j])
+				j++
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			if keepLiteral() {
+				result.WriteString(string(runes[i : j+1]))
+			} else {
+				literals = append(literals, sqlLiteral{text: value.String(), isString: true})
+				result.WriteString(marker(len(literals)))
+			}
+			prev = "'"
+			i = j
+
+		case ch == '"' || ch == '`':
+			// Quoted identifier, copy verbatim
+			j := i + 1
+			for j < len(runes) && runes[j] != ch {
+				j++
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			result.WriteString(string(runes[i : j+1]))
+			prev = string(ch)
+			i = j
+
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment, copy verbatim
+			j := i
+			for j < len(runes) && runes[j] != '\n' {
+				j++
+			}
+			result.WriteString(string(runes[i:j]))
+			i = j - 1
+
+		case isDigit(ch):
+			j := i
+			for j < len(runes) && (isDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			if (j < len(runes) && isIdentifierRune(runes[j])) || keepLiteral() {
+				// Part of an identifier like 1col, or an ordinal, copy verbatim
+				result.WriteString(string(runes[i:j]))
+			} else {
+				literals = append(literals, sqlLiteral{text: string(runes[i:j])})
+				result.WriteString(marker(len(literals)))
+			}
+			prev = "0"
+			i = j - 1
+
+		case isIdentifierRune(ch):
+			// Keyword or identifier, digits in it like t1.col2 are kept
+			j := i
+			for j < len(runes) && isIdentifierRune(runes[j]) {
+				j++
+			}
+			word := strings.ToUpper(string(runes[i:j]))
+			switch {
+			case word == "BY" && (prev == "ORDER" || prev == "GROUP"):
+				byDepth = depth
+			case byListEnds[word] && depth == byDepth:
+				byDepth = -1
+			}
+			result.WriteString(string(runes[i:j]))
+			prev = word
+			i = j - 1
+
+		case ch == '%' && escapePercent:
+			result.WriteString("%%")
+			prev = "%"
+
+		default:
+			switch ch {
+			case '(':
+				depth++
+			case ')':
+				if depth == byDepth {
+					byDepth = -1
+				}
+				depth--
+			case ';':
+				byDepth = -1
+			}
+			if !unicode.IsSpace(ch) {
+				prev = string(ch)
+			}
+			result.WriteRune(ch)
+		}
+	}
+
+	return result.String(), literals
+}
+
+// byListEnds are the keywords that end an ORDER BY or GROUP BY list
+var byListEnds = map[string]bool{
+	"HAVING": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "WINDOW": true, "FOR": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "ORDER": true, "QUALIFY": true,
+}
+
+// statementReturnsRows reports whether a statement produces a result set
+func statementReturnsRows(query string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	for _, prefix := range []string{"SELECT", "WITH", "SHOW", "EXPLAIN", "VALUES", "TABLE", "PRAGMA", "DESCRIBE"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(upper, " RETURNING ")
+}
+
+// goDrivers are the database/sql drivers by dialect
+var goDrivers = map[string]string{
+	"postgresql": "github.com/lib/pq",
+	"mysql":      "github.com/go-sql-driver/mysql",
+	"sqlite":     "github.com/mattn/go-sqlite3",
+}
+
+func generateGoCode(query string, literals []sqlLiteral, returnsRows bool, dialect string) (string, string) {
+	var args []string
+	for _, lit := range literals {
+		if lit.isString {
+			args = append(args, strconv.Quote(lit.text))
+		} else {
+			args = append(args, lit.text)
+		}
+	}
+	argList := ""
+	if len(args) > 0 {
+		argList = ", " + strings.Join(args, ", ")
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("import (\n\t\"context\"\n\t\"database/sql\"\n\t\"fmt\"\n\n\t_ %q\n)\n\n", goDrivers[dialect]))
+	b.WriteString(fmt.Sprintf("const query = %s\n\n", goRawString(query)))
+	if returnsRows {
+		b.WriteString("func runQuery(ctx context.Context, db *sql.DB) error {\n")
+		b.WriteString(fmt.Sprintf("\trows, err := db.QueryContext(ctx, query%s)\n", argList))
+		b.WriteString("\tif err != nil {\n\t\treturn fmt.Errorf(\"query failed: %w\", err)\n\t}\n")
+		b.WriteString("\tdefer rows.Close()\n\n")
+		b.WriteString("\tcolumns, err := rows.Columns()\n")
+		b.WriteString("\tif err != nil {\n\t\treturn fmt.Errorf(\"failed to get columns: %w\", err)\n\t}\n\n")
+		b.WriteString("\tvalues := make([]interface{}, len(columns))\n")
+		b.WriteString("\tvaluePtrs := make([]interface{}, len(columns))\n")
+		b.WriteString("\tfor i := range values {\n\t\tvaluePtrs[i] = &values[i]\n\t}\n\n")
+		b.WriteString("\tfor rows.Next() {\n")
+		b.WriteString("\t\tif err := rows.Scan(valuePtrs...); err != nil {\n\t\t\treturn fmt.Errorf(\"failed to scan row: %w\", err)\n\t\t}\n")
+		b.WriteString("\t\tfmt.Println(values...)\n\t}\n\n")
+		b.WriteString("\tif err := rows.Err(); err != nil {\n\t\treturn fmt.Errorf(\"error iterating rows: %w\", err)\n\t}\n")
+		b.WriteString("\treturn nil\n}\n")
+	} else {
+		b.WriteString("func runStatement(ctx context.Context, db *sql.DB) error {\n")
+		b.WriteString(fmt.Sprintf("\tresult, err := db.ExecContext(ctx, query%s)\n", argList))
+		b.WriteString("\tif err != nil {\n\t\treturn fmt.Errorf(\"statement failed: %w\", err)\n\t}\n\n")
+		b.WriteString("\taffected, err := result.RowsAffected()\n")
+		b.WriteString("\tif err != nil {\n\t\treturn fmt.Errorf(\"failed to get affected rows: %w\", err)\n\t}\n")
+		b.WriteString("\tfmt.Printf(\"%d rows affected\\n\", affected)\n")
+		b.WriteString("\treturn nil\n}\n")
+	}
+	return fmt.Sprintf("database/sql (%s)", goDrivers[dialect]), b.String()
+}
+
+func generatePythonCode(query string, literals []sqlLiteral, returnsRows bool, dialect string) (string, string) {
+	var args []string
+	for _, lit := range literals {
+		if lit.isString {
+			args = append(args, pythonString(lit.text))
+		} else {
+			args = append(args, lit.text)
+		}
+	}
+	params := "()"
+	if len(args) == 1 {
+		params = "(" + args[0] + ",)"
+	} else if len(args) > 1 {
+		params = "(" + strings.Join(args, ", ") + ")"
+	}
+
+	module, connect := "psycopg", "psycopg.connect(conninfo)"
+	switch dialect {
+	case "mysql":
+		module, connect = "pymysql", "pymysql.connect(**conninfo)"
+	case "sqlite":
+		module, connect = "sqlite3", "sqlite3.connect(conninfo)"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("import %s\n\n", module))
+	b.WriteString(fmt.Sprintf("QUERY = \"\"\"%s\"\"\"\n\n", strings.ReplaceAll(query, `"""`, `\"\"\"`)))
+	b.WriteString("def run(conninfo):\n")
+	b.WriteString("    try:\n")
+	b.WriteString(fmt.Sprintf("        with %s as conn:\n", connect))
+	if dialect == "sqlite" {
+		// sqlite3 cursors are not context managers
+		b.WriteString(fmt.Sprintf("            cur = conn.execute(QUERY, %s)\n", params))
+		if returnsRows {
+			b.WriteString("            for row in cur.fetchall():\n")
+			b.WriteString("                print(row)\n")
+		} else {
+			b.WriteString("            print(f\"{cur.rowcount} rows affected\")\n")
+		}
+	} else {
+		b.WriteString("            with conn.cursor() as cur:\n")
+		b.WriteString(fmt.Sprintf("                cur.execute(QUERY, %s)\n", params))
+		if returnsRows {
+			b.WriteString("                for row in cur.fetchall():\n")
+			b.WriteString("                    print(row)\n")
+		} else {
+			b.WriteString("                print(f\"{cur.rowcount} rows affected\")\n")
+			b.WriteString("            conn.commit()\n")
+		}
+	}
+	b.WriteString(fmt.Sprintf("    except %s.Error as e:\n", module))
+	b.WriteString("        print(f\"Database error: {e}\")\n")
+	b.WriteString("        raise\n")
+	return module, b.String()
+}
+
+func generateNodeCode(query string, literals []sqlLiteral, returnsRows bool, dialect string) (string, string) {
+	var args []string
+	for _, lit := range literals {
+		if lit.isString {
+			args = append(args, strconv.Quote(lit.text))
+		} else {
+			args = append(args, lit.text)
+		}
+	}
+
+	var b strings.Builder
+	switch dialect {
+	case "mysql":
+		b.WriteString("const mysql = require('mysql2/promise');\n\n")
+		b.WriteString("const pool = mysql.createPool(process.env.DATABASE_URL);\n\n")
+	case "sqlite":
+		b.WriteString("const Database = require('better-sqlite3');\n\n")
+		b.WriteString("const db = new Database(process.env.SQLITE_PATH);\n\n")
+	default:
+		b.WriteString("const { Pool } = require('pg');\n\n")
+		b.WriteString("const pool = new Pool(); // Uses PG* environment variables\n\n")
+	}
+	b.WriteString(fmt.Sprintf("const query = `%s`;\n", strings.ReplaceAll(strings.ReplaceAll(query, "\\", "\\\\"), "`", "\\`")))
+	b.WriteString(fmt.Sprintf("const params = [%s];\n\n", strings.Join(args, ", ")))
+
+	if dialect == "sqlite" {
+		// better-sqlite3 is synchronous
+		b.WriteString("function run() {\n")
+		b.WriteString("  try {\n")
+		if returnsRows {
+			b.WriteString("    console.table(db.prepare(query).all(...params));\n")
+		} else {
+			b.WriteString("    const info = db.prepare(query).run(...params);\n")
+			b.WriteString("    console.log(`${info.changes} rows affected`);\n")
+		}
+		b.WriteString("  } catch (err) {\n")
+		b.WriteString("    console.error('Database error:', err.message);\n")
+		b.WriteString("    throw err;\n")
+		b.WriteString("  } finally {\n")
+		b.WriteString("    db.close();\n")
+		b.WriteString("  }\n")
+		b.WriteString("}\n")
+		return "better-sqlite3", b.String()
+	}
+
+	b.WriteString("async function run() {\n")
+	b.WriteString("  try {\n")
+	switch {
+	case dialect == "mysql" && returnsRows:
+		b.WriteString("    const [rows] = await pool.execute(query, params);\n")
+		b.WriteString("    console.table(rows);\n")
+	case dialect == "mysql":
+		b.WriteString("    const [result] = await pool.execute(query, params);\n")
+		b.WriteString("    console.log(`${result.affectedRows} rows affected`);\n")
+	case returnsRows:
+		b.WriteString("    const result = await pool.query(query, params);\n")
+		b.WriteString("    console.table(result.rows);\n")
+	default:
+		b.WriteString("    const result = await pool.query(query, params);\n")
+		b.WriteString("    console.log(`${result.rowCount} rows affected`);\n")
+	}
+	b.WriteString("  } catch (err) {\n")
+	b.WriteString("    console.error('Database error:', err.message);\n")
+	b.WriteString("    throw err;\n")
+	b.WriteString("  } finally {\n")
+	b.WriteString("    await pool.end();\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	if dialect == "mysql" {
+		return "mysql2", b.String()
+	}
+	return "pg", b.String()
+}
+
+// goRawString formats a string as a Go raw string literal when possible
+func goRawString(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// pythonString formats a string as a Python string literal
+func pythonString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r == '$' || r == '.' || isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+func (e *Executor) getCollations(dbTools dbinterfaces.DatabaseInterface) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	defaultsQuery, columnsQuery, keysQuery, err := sqlanalysis.BuildCollationQueries(dialect)
+	if err != nil {
+		return "", err
+	}
+
+	defaults, err := dbTools.ExecuteSQL(defaultsQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to read database encoding: %w", err)
+	}
+	var encoding, collation string
+	if defaults != nil && len(defaults.Rows) > 0 && len(defaults.Rows[0]) >= 2 {
+		encoding = fmt.Sprintf("%v", defaults.Rows[0][0])
+		collation = fmt.Sprintf("%v", defaults.Rows[0][1])
+	}
+
+	result, err := dbTools.ExecuteSQL(columnsQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to read column collations: %w", err)
+	}
+	columns := sqlanalysis.ColumnCollationsFromResult(result)
+
+	// Foreign keys only add join mismatches; the report is still useful without them
+	var keys []sqlanalysis.ForeignKeyColumn
+	if result, err := dbTools.ExecuteSQL(keysQuery); err == nil {
+		keys = sqlanalysis.ForeignKeysFromResult(result)
+	}
+
+	report := sqlanalysis.AnalyzeCollations(dialect, encoding, collation, columns, keys)
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal collation report: %w", err)
+	}
+	return string(resultJSON), nil
+}
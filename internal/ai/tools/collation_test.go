@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_GetCollations_JoinMismatch(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	defaults, columns, keys, err := sqlanalysis.BuildCollationQueries("postgresql")
+	require.NoError(t, err)
+	mockDB.On("ExecuteSQL", defaults).Return(&models.QueryResult{Rows: [][]interface{}{{"UTF8", "en_US.UTF-8"}}}, nil)
+	mockDB.On("ExecuteSQL", columns).Return(&models.QueryResult{Rows: [][]interface{}{
+		{"customers", "code", "text", "", "default", "default", int64(8192)},
+		{"invoices", "customer_code", "text", "", "C", "libc", int64(8192)},
+	}}, nil)
+	mockDB.On("ExecuteSQL", keys).Return(&models.QueryResult{Rows: [][]interface{}{{"invoices", "customer_code", "customers", "code"}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "get_collations", Arguments: `{}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"kind":"join_mismatch"`)
+	assert.Contains(t, output, `ALTER TABLE \"invoices\" ALTER COLUMN \"customer_code\" TYPE text COLLATE \"default\";`)
+	assert.Contains(t, output, "ACCESS EXCLUSIVE")
+}
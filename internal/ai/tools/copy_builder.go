@@ -0,0 +1,299 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// maxCopyParams caps the bound parameters of one batch INSERT, below the
+// limits of PostgreSQL (65535) and SQLite (32766)
+const maxCopyParams = 30000
+
+// MapColumnType translates a column's type from the source to the target
+// dialect. The warning is set when the mapping loses information or constraints.
+func MapColumnType(source, target string, col models.ColumnInfo) (string, string) {
+	dataType := strings.ToLower(strings.TrimSpace(col.DataType))
+	base := dataType
+	if i := strings.Index(base, "("); i >= 0 {
+		base = strings.TrimSpace(base[:i])
+	}
+	length := 0
+	if col.CharMaxLength != nil {
+		length = *col.CharMaxLength
+	}
+
+	switch {
+	case len(col.EnumValues) > 0:
+		if target == "mysql" {
+			quoted := make([]string, len(col.EnumValues))
+			for i, value := range col.EnumValues {
+				quoted[i] = formatLiteral(value)
+			}
+			return fmt.Sprintf("ENUM(%s)", strings.Join(quoted, ", ")), ""
+		}
+		return textType(target), fmt.Sprintf("column %s: enum values are not enforced on %s", col.ColumnName, target)
+
+	case base == "boolean" || base == "bool" || dataType == "tinyint(1)":
+		switch target {
+		case "mysql":
+			return "TINYINT(1)", ""
+		case "sqlite":
+			return "INTEGER", ""
+		}
+		return "BOOLEAN", ""
+
+	case integerTypes[base]:
+		switch {
+		case target == "sqlite":
+			return "INTEGER", ""
+		case base == "bigint" || base == "int8" || base == "bigserial" || source == "sqlite":
+			// SQLite integers are 64 bits wide whatever their declared type
+			return "BIGINT", ""
+		case base == "smallint" || base == "int2" || base == "tinyint" || base == "smallserial":
+			return "SMALLINT", ""
+		case target == "mysql":
+			return "INT", ""
+		}
+		return "INTEGER", ""
+
+	case base == "numeric" || base == "decimal" || base == "money":
+		if target == "sqlite" {
+			return "NUMERIC", ""
+		}
+		name := "NUMERIC"
+		if target == "mysql" {
+			name = "DECIMAL"
+		}
+		if col.NumPrecision != nil && *col.NumPrecision > 0 {
+			scale := 0
+			if col.NumScale != nil {
+				scale = *col.NumScale
+			}
+			return fmt.Sprintf("%s(%d,%d)", name, *col.NumPrecision, scale), ""
+		}
+		if target == "mysql" {
+			return "DECIMAL(65,30)", fmt.Sprintf("column %s: unconstrained numeric mapped to DECIMAL(65,30)", col.ColumnName)
+		}
+		return name, ""
+
+	case base == "real" || base == "float" || base == "float4":
+		if target == "sqlite" {
+			return "REAL", ""
+		}
+		if target == "mysql" {
+			return "FLOAT", ""
+		}
+		return "REAL", ""
+
+	case strings.HasPrefix(base, "double"), base == "float8":
+		if target == "sqlite" {
+			return "REAL", ""
+		}
+		if target == "mysql" {
+			return "DOUBLE", ""
+		}
+		return "DOUBLE PRECISION", ""
+
+	case base == "uuid":
+		switch target {
+		case "postgresql":
+			return "UUID", ""
+		case "mysql":
+			return "CHAR(36)", ""
+		}
+		return "TEXT", ""
+
+	case strings.Contains(base, "json"):
+		switch target {
+		case "postgresql":
+			return "JSONB", ""
+		case "mysql":
+			return "JSON", ""
+		}
+		return "TEXT", ""
+
+	case base == "date":
+		if target == "sqlite" {
+			return "TEXT", ""
+		}
+		return "DATE", ""
+
+	case strings.HasPrefix(base, "timestamp") || base == "datetime":
+		withZone := strings.Contains(dataType, "with time zone") || base == "timestamptz"
+		switch target {
+		case "postgresql":
+			if withZone {
+				return "TIMESTAMPTZ", ""
+			}
+			return "TIMESTAMP", ""
+		case "mysql":
+			if withZone {
+				return "DATETIME(6)", fmt.Sprintf("column %s: time zone offsets are dropped, values are stored as sent by the driver", col.ColumnName)
+			}
+			return "DATETIME(6)", ""
+		}
+		return "TEXT", ""
+
+	case strings.HasPrefix(base, "time"):
+		if target == "sqlite" {
+			return "TEXT", ""
+		}
+		return "TIME", ""
+
+	case base == "character varying" || base == "varchar" || base == "nvarchar":
+		if length > 0 && (target != "mysql" || length <= 16383) {
+			if target == "sqlite" {
+				return "TEXT", ""
+			}
+			return fmt.Sprintf("VARCHAR(%d)", length), ""
+		}
+		return textType(target), ""
+
+	case base == "character" || base == "char" || base == "nchar":
+		if target == "sqlite" {
+			return "TEXT", ""
+		}
+		if length > 0 && length <= 255 {
+			return fmt.Sprintf("CHAR(%d)", length), ""
+		}
+		return textType(target), ""
+
+	case strings.Contains(base, "text") || base == "clob" || base == "string":
+		return textType(target), ""
+
+	case base == "bytea" || strings.Contains(base, "blob") || strings.Contains(base, "binary"):
+		switch target {
+		case "postgresql":
+			return "BYTEA", fmt.Sprintf("column %s: binary values are copied as text and may not round-trip", col.ColumnName)
+		case "mysql":
+			return "LONGBLOB", ""
+		}
+		return "BLOB", ""
+	}
+
+	return textType(target), fmt.Sprintf("column %s: type %s has no equivalent on %s, copied as text", col.ColumnName, col.DataType, target)
+}
+
+// integerTypes are the integer type names of the supported dialects
+var integerTypes = map[string]bool{
+	"smallint": true, "integer": true, "int": true, "bigint": true, "tinyint": true, "mediumint": true,
+	"int2": true, "int4": true, "int8": true, "smallserial": true, "serial": true, "bigserial": true,
+}
+
+func textType(dialect string) string {
+	if dialect == "mysql" {
+		return "LONGTEXT"
+	}
+	return "TEXT"
+}
+
+// BuildCreateTable builds the CREATE TABLE for a copy of a table on the target
+// dialect, with NOT NULL and the primary key. Defaults, indexes and other
+// constraints are not copied.
+func BuildCreateTable(source, target, table string, columns []models.ColumnInfo) (string, []string) {
+	var defs, keys, warnings []string
+	for _, col := range columns {
+		dataType, warning := MapColumnType(source, target, col)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		def := quoteIdentifier(target, col.ColumnName) + " " + dataType
+		if strings.EqualFold(col.IsNullable, "NO") {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+		if col.IsPrimaryKey {
+			keys = append(keys, quoteIdentifier(target, col.ColumnName))
+		}
+	}
+	if len(keys) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(keys, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", quoteIdentifier(target, table), strings.Join(defs, ",\n  ")), warnings
+}
+
+// BuildCopySelect builds the query reading the next batch of a table. With key
+// columns it pages by key (resumable, after holds the last key copied);
+// without them it pages by offset. A scope condition, such as the tenant
+// predicate, limits the rows read.
+func BuildCopySelect(dialect, table string, columns, keys []string, after []interface{}, offset int64, limit int, scope string) (string, []interface{}) {
+	quoted := make([]string, len(columns))
+	for i, name := range columns {
+		quoted[i] = quoteIdentifier(dialect, name)
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), quoteIdentifier(dialect, table))
+	var conditions []string
+	if scope != "" {
+		conditions = append(conditions, "("+scope+")")
+	}
+
+	if len(keys) == 0 {
+		if len(conditions) > 0 {
+			sql += " WHERE " + conditions[0]
+		}
+		return fmt.Sprintf("%s LIMIT %d OFFSET %d", sql, limit, offset), nil
+	}
+
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = quoteIdentifier(dialect, key)
+	}
+	var params []interface{}
+	if len(after) == len(keys) {
+		markers := make([]string, len(after))
+		for i := range after {
+			markers[i] = placeholder(dialect, i+1)
+		}
+		params = after
+		if len(keys) == 1 {
+			conditions = append(conditions, fmt.Sprintf("%s > %s", quotedKeys[0], markers[0]))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s) > (%s)", strings.Join(quotedKeys, ", "), strings.Join(markers, ", ")))
+		}
+	}
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return fmt.Sprintf("%s ORDER BY %s LIMIT %d", sql, strings.Join(quotedKeys, ", "), limit), params
+}
+
+// BuildBatchInsert builds one parameterized multi-row INSERT
+func BuildBatchInsert(dialect, table string, columns []string, rows [][]interface{}) (string, []interface{}) {
+	quoted := make([]string, len(columns))
+	for i, name := range columns {
+		quoted[i] = quoteIdentifier(dialect, name)
+	}
+
+	params := make([]interface{}, 0, len(rows)*len(columns))
+	tuples := make([]string, len(rows))
+	for r, row := range rows {
+		markers := make([]string, len(columns))
+		for c := range columns {
+			params = append(params, row[c])
+			markers[c] = placeholder(dialect, len(params))
+		}
+		tuples[r] = "(" + strings.Join(markers, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		quoteIdentifier(dialect, table), strings.Join(quoted, ", "), strings.Join(tuples, ", ")), params
+}
+
+// copyBatchSize returns the rows per batch, capped so one INSERT stays under
+// the bound parameter limit
+func copyBatchSize(requested, columns int) int {
+	if requested <= 0 {
+		requested = defaultCopyBatchSize
+	}
+	if requested > maxCopyBatchSize {
+		requested = maxCopyBatchSize
+	}
+	if columns > 0 && requested*columns > maxCopyParams {
+		requested = maxCopyParams / columns
+	}
+	if requested < 1 {
+		requested = 1
+	}
+	return requested
+}
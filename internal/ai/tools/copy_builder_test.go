@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapColumnType(t *testing.T) {
+	dataTypes := map[string][3]string{
+		// source type: postgresql, mysql, sqlite
+		"integer":                     {"INTEGER", "INT", "INTEGER"},
+		"bigint":                      {"BIGINT", "BIGINT", "INTEGER"},
+		"tinyint":                     {"SMALLINT", "SMALLINT", "INTEGER"},
+		"boolean":                     {"BOOLEAN", "TINYINT(1)", "INTEGER"},
+		"double precision":            {"DOUBLE PRECISION", "DOUBLE", "REAL"},
+		"uuid":                        {"UUID", "CHAR(36)", "TEXT"},
+		"jsonb":                       {"JSONB", "JSON", "TEXT"},
+		"timestamp without time zone": {"TIMESTAMP", "DATETIME(6)", "TEXT"},
+		"text":                        {"TEXT", "LONGTEXT", "TEXT"},
+		"bytea":                       {"BYTEA", "LONGBLOB", "BLOB"},
+	}
+	for dataType, want := range dataTypes {
+		for i, target := range []string{"postgresql", "mysql", "sqlite"} {
+			got, _ := MapColumnType("postgresql", target, ordersColumn(dataType))
+			assert.Equal(t, want[i], got, "%s on %s", dataType, target)
+		}
+	}
+
+	got, warning := MapColumnType("sqlite", "postgresql", ordersColumn("INTEGER"))
+	assert.Equal(t, "BIGINT", got)
+	assert.Empty(t, warning)
+
+	got, warning = MapColumnType("postgresql", "mysql", ordersColumns[3])
+	assert.Equal(t, "DECIMAL(65,30)", got)
+	assert.Contains(t, warning, "unconstrained numeric")
+
+	got, _ = MapColumnType("postgresql", "mysql", ordersColumns[1])
+	assert.Equal(t, "VARCHAR(10)", got)
+	got, _ = MapColumnType("postgresql", "mysql", ordersColumns[2])
+	assert.Equal(t, "ENUM('new', 'paid', 'shipped')", got)
+	got, warning = MapColumnType("postgresql", "sqlite", ordersColumns[2])
+	assert.Equal(t, "TEXT", got)
+	assert.Contains(t, warning, "enum values are not enforced")
+
+	got, warning = MapColumnType("postgresql", "mysql", ordersColumn("geometry"))
+	assert.Equal(t, "LONGTEXT", got)
+	assert.Contains(t, warning, "no equivalent")
+}
+
+func ordersColumn(dataType string) models.ColumnInfo {
+	return models.ColumnInfo{ColumnName: "c", DataType: dataType, IsNullable: "YES"}
+}
+
+func TestBuildCreateTable(t *testing.T) {
+	ddl, warnings := BuildCreateTable("postgresql", "mysql", "orders", ordersColumns)
+
+	assert.Equal(t, "CREATE TABLE `orders` (\n"+
+		"  `id` INT NOT NULL,\n"+
+		"  `customer` VARCHAR(10) NOT NULL,\n"+
+		"  `status` ENUM('new', 'paid', 'shipped') NOT NULL,\n"+
+		"  `total` DECIMAL(65,30) NOT NULL,\n"+
+		"  `paid` TINYINT(1),\n"+
+		"  `placed_on` DATE,\n"+
+		"  `meta` JSON,\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		")", ddl)
+	assert.Len(t, warnings, 1)
+}
+
+func TestBuildCopySelect(t *testing.T) {
+	sql, params := BuildCopySelect("postgresql", "public.orders", []string{"id", "total"}, []string{"id"}, nil, 0, 500, "")
+	assert.Equal(t, `SELECT "id", "total" FROM "public"."orders" ORDER BY "id" LIMIT 500`, sql)
+	assert.Empty(t, params)
+
+	sql, params = BuildCopySelect("postgresql", "orders", []string{"a", "b", "n"}, []string{"a", "b"}, []interface{}{1, "x"}, 0, 500, "")
+	assert.Equal(t, `SELECT "a", "b", "n" FROM "orders" WHERE ("a", "b") > ($1, $2) ORDER BY "a", "b" LIMIT 500`, sql)
+	assert.Equal(t, []interface{}{1, "x"}, params)
+
+	sql, _ = BuildCopySelect("mysql", "log", []string{"msg"}, nil, nil, 1000, 500, "")
+	assert.Equal(t, "SELECT `msg` FROM `log` LIMIT 500 OFFSET 1000", sql)
+
+	sql, _ = BuildCopySelect("postgresql", "orders", []string{"id"}, []string{"id"}, []interface{}{7}, 0, 500, `"tenant_id" = 42`)
+	assert.Equal(t, `SELECT "id" FROM "orders" WHERE ("tenant_id" = 42) AND "id" > $1 ORDER BY "id" LIMIT 500`, sql)
+	sql, _ = BuildCopySelect("mysql", "log", []string{"msg"}, nil, nil, 0, 500, "`tenant_id` = 42")
+	assert.Equal(t, "SELECT `msg` FROM `log` WHERE (`tenant_id` = 42) LIMIT 500 OFFSET 0", sql)
+}
+
+func TestBuildBatchInsert(t *testing.T) {
+	sql, params := BuildBatchInsert("postgresql", "orders", []string{"id", "total"}, [][]interface{}{{1, "9.50"}, {2, nil}})
+	assert.Equal(t, `INSERT INTO "orders" ("id", "total") VALUES ($1, $2), ($3, $4)`, sql)
+	assert.Equal(t, []interface{}{1, "9.50", 2, nil}, params)
+
+	sql, _ = BuildBatchInsert("sqlite", "orders", []string{"id"}, [][]interface{}{{1}, {2}})
+	assert.Equal(t, `INSERT INTO "orders" ("id") VALUES (?), (?)`, sql)
+}
+
+func TestCopyBatchSize(t *testing.T) {
+	assert.Equal(t, defaultCopyBatchSize, copyBatchSize(0, 5))
+	assert.Equal(t, maxCopyBatchSize, copyBatchSize(50000, 2))
+	assert.Equal(t, maxCopyParams/100, copyBatchSize(5000, 100))
+}
@@ -0,0 +1,333 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Copy batch limits
+const (
+	defaultCopyBatchSize = 1000
+	maxCopyBatchSize     = 10000
+)
+
+// ConnectionOpener opens a dedicated connection to a configured connection by name
+type ConnectionOpener func(name string) (dbinterfaces.DatabaseInterface, error)
+
+// SetConnectionOpener lets tools such as copy_table reach other configured connections
+func (e *Executor) SetConnectionOpener(open ConnectionOpener) {
+	e.openConnection = open
+}
+
+// CopyCheckpoint records how far a copy got, so an interrupted copy can resume
+type CopyCheckpoint struct {
+	LastKey   []interface{} `json:"last_key,omitempty"` // Key of the last copied row, when paging by key
+	Rows      int64         `json:"rows"`               // Rows copied so far
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// copyCheckpointsPath returns the path of the copy checkpoint file
+func copyCheckpointsPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "copy_checkpoints.json")
+}
+
+// loadCopyCheckpoints reads the checkpoints of unfinished copies. Numbers are
+// kept as text so large integer keys are not rounded.
+func loadCopyCheckpoints() (map[string]CopyCheckpoint, error) {
+	checkpoints := make(map[string]CopyCheckpoint)
+	data, err := os.ReadFile(copyCheckpointsPath())
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read copy checkpoints: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse copy checkpoints: %w", err)
+	}
+	for key, checkpoint := range checkpoints {
+		for i, value := range checkpoint.LastKey {
+			if number, ok := value.(json.Number); ok {
+				checkpoint.LastKey[i] = number.String()
+			}
+		}
+		checkpoints[key] = checkpoint
+	}
+	return checkpoints, nil
+}
+
+// saveCopyCheckpoint stores the checkpoint of a copy, or removes it when nil
+func saveCopyCheckpoint(key string, checkpoint *CopyCheckpoint) error {
+	checkpoints, err := loadCopyCheckpoints()
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		delete(checkpoints, key)
+	} else {
+		checkpoints[key] = *checkpoint
+	}
+
+	path := copyCheckpointsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal copy checkpoints: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// CopyReport is the result of copy_table
+type CopyReport struct {
+	Source       string   `json:"source"`
+	Target       string   `json:"target"`
+	Table        string   `json:"table"`
+	TargetTable  string   `json:"target_table"`
+	CreatedTable bool     `json:"created_table"`
+	DDL          string   `json:"ddl,omitempty"`
+	ResumedAt    int64    `json:"resumed_at_row,omitempty"`
+	RowsCopied   int64    `json:"rows_copied"`
+	Batches      int      `json:"batches"`
+	BatchSize    int      `json:"batch_size"`
+	Complete     bool     `json:"complete"`
+	DurationMs   int64    `json:"duration_ms"`
+	Warnings     []string `json:"warnings,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+func (e *Executor) copyTable(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return "", fmt.Errorf("tableName argument is required and must be a string")
+	}
+	targetName, ok := args["target"].(string)
+	if !ok || targetName == "" {
+		return "", fmt.Errorf("target argument is required and must be a connection name")
+	}
+	sourceName, _ := args["source"].(string)
+	targetTable, _ := args["targetTable"].(string)
+	if targetTable == "" {
+		targetTable = tableName
+	}
+	resume, _ := args["resume"].(bool)
+	requestedBatch, _ := args["batchSize"].(float64)
+
+	if e.openConnection == nil {
+		return `{"error": "copy_table is not available: other connections cannot be opened from here"}`, nil
+	}
+
+	source := dbTools
+	if sourceName != "" {
+		db, err := e.openConnection(sourceName)
+		if err != nil {
+			return "", fmt.Errorf("failed to connect to source '%s': %w", sourceName, err)
+		}
+		defer db.Close()
+		source = db
+	} else {
+		sourceName = "current connection"
+	}
+	target, err := e.openConnection(targetName)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to target '%s': %w", targetName, err)
+	}
+	defer target.Close()
+
+	report, err := copyTableRows(source, target, CopyRequest{
+		SourceName:  sourceName,
+		TargetName:  targetName,
+		Table:       tableName,
+		TargetTable: targetTable,
+		BatchSize:   int(requestedBatch),
+		Resume:      resume,
+		Tenant:      e.Tenant(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if report.RowsCopied > 0 {
+		e.notices = append(e.notices, fmt.Sprintf("Copied %d rows of %s to %s.%s in %d batches", report.RowsCopied, tableName, targetName, targetTable, report.Batches))
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal copy report: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// CopyRequest describes one table copy between two connections
+type CopyRequest struct {
+	SourceName  string
+	TargetName  string
+	Table       string
+	TargetTable string
+	BatchSize   int
+	Resume      bool
+	Tenant      string // Only the tenant's rows of a scoped table are copied
+}
+
+// copyTableRows creates the target table when missing and copies the rows in
+// batches, each inserted atomically. After every batch a checkpoint is saved,
+// so a failed or interrupted copy continues where it stopped with resume.
+// Failures while copying are reported in the report rather than as errors.
+func copyTableRows(source, target dbinterfaces.DatabaseInterface, req CopyRequest) (*CopyReport, error) {
+	start := time.Now()
+	sourceDialect := dbinterfaces.GetDatabaseType(source)
+	targetDialect := dbinterfaces.GetDatabaseType(target)
+	report := &CopyReport{Source: req.SourceName, Target: req.TargetName, Table: req.Table, TargetTable: req.TargetTable}
+	finish := func() (*CopyReport, error) {
+		report.DurationMs = time.Since(start).Milliseconds()
+		return report, nil
+	}
+
+	columns, err := source.GetTableSchema(unqualifiedName(req.Table))
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		report.Error = fmt.Sprintf("table %s not found on %s", req.Table, req.SourceName)
+		return finish()
+	}
+	names := make([]string, len(columns))
+	var keys []string
+	for i, col := range columns {
+		names[i] = col.ColumnName
+		if col.IsPrimaryKey {
+			keys = append(keys, col.ColumnName)
+		}
+	}
+	if len(keys) == 0 {
+		report.Warnings = append(report.Warnings, "the table has no primary key: rows are read by offset, so concurrent changes can be missed or copied twice")
+	}
+
+	scope, err := tenantScopeCondition(sourceDialect, req.Tenant, req.Table, columns)
+	if err != nil {
+		return nil, err
+	}
+	parts := []string{req.SourceName, req.Table, req.TargetName, req.TargetTable}
+	if scope != "" {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("only the rows of tenant %s are copied", req.Tenant))
+		parts = append(parts, req.Tenant)
+	}
+	checkpointKey := strings.Join(parts, "|")
+	checkpoints, err := loadCopyCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, hasCheckpoint := checkpoints[checkpointKey]
+
+	existing, err := target.GetTableSchema(unqualifiedName(req.TargetTable))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		ddl, warnings := BuildCreateTable(sourceDialect, targetDialect, req.TargetTable, columns)
+		report.DDL = ddl
+		report.Warnings = append(report.Warnings, warnings...)
+		if _, err := target.ExecuteSQL(ddl); err != nil {
+			report.Error = fmt.Sprintf("failed to create %s: %v", req.TargetTable, err)
+			return finish()
+		}
+		report.CreatedTable = true
+		hasCheckpoint = false
+	} else if !req.Resume || !hasCheckpoint {
+		count, err := target.ExecuteSQL("SELECT COUNT(*) FROM " + quoteIdentifier(targetDialect, req.TargetTable))
+		if err != nil {
+			return nil, err
+		}
+		if count.RowCount > 0 && fmt.Sprint(count.Rows[0][0]) != "0" {
+			report.Error = fmt.Sprintf("target table %s already has rows", req.TargetTable)
+			if hasCheckpoint {
+				report.Error += fmt.Sprintf("; a previous copy stopped after %d rows, call copy_table again with resume true to continue it", checkpoint.Rows)
+			} else {
+				report.Error += "; choose another targetTable or empty it first"
+			}
+			return finish()
+		}
+		hasCheckpoint = false
+	}
+	if !hasCheckpoint {
+		checkpoint = CopyCheckpoint{}
+	}
+	report.ResumedAt = checkpoint.Rows
+	report.RowsCopied = checkpoint.Rows
+
+	batchSize := copyBatchSize(req.BatchSize, len(names))
+	report.BatchSize = batchSize
+	for {
+		query, params := BuildCopySelect(sourceDialect, req.Table, names, keys, checkpoint.LastKey, checkpoint.Rows, batchSize, scope)
+		var batch *models.QueryResult
+		if len(params) > 0 {
+			batch, err = dbinterfaces.ExecuteSQLWithArgs(source, query, params...)
+		} else {
+			batch, err = source.ExecuteSQL(query)
+		}
+		if err != nil {
+			report.Error = fmt.Sprintf("failed to read rows after row %d: %v", checkpoint.Rows, err)
+			break
+		}
+		if len(batch.Rows) == 0 {
+			report.Complete = true
+			break
+		}
+
+		insert, values := BuildBatchInsert(targetDialect, req.TargetTable, names, batch.Rows)
+		if _, err := dbinterfaces.ExecuteExpectingRows(target, insert, int64(len(batch.Rows)), values...); err != nil {
+			report.Error = fmt.Sprintf("failed to write rows %d-%d: %v", checkpoint.Rows+1, checkpoint.Rows+int64(len(batch.Rows)), err)
+			break
+		}
+
+		checkpoint.Rows += int64(len(batch.Rows))
+		if len(keys) > 0 {
+			last := batch.Rows[len(batch.Rows)-1]
+			checkpoint.LastKey = make([]interface{}, len(keys))
+			for i, key := range keys {
+				checkpoint.LastKey[i] = last[columnIndex(names, key)]
+			}
+		}
+		checkpoint.UpdatedAt = time.Now()
+		if err := saveCopyCheckpoint(checkpointKey, &checkpoint); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("could not save the copy checkpoint, resume will not be possible: %v", err))
+		}
+		report.RowsCopied = checkpoint.Rows
+		report.Batches++
+
+		if len(batch.Rows) < batchSize {
+			report.Complete = true
+			break
+		}
+	}
+
+	if report.Complete {
+		if err := saveCopyCheckpoint(checkpointKey, nil); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("could not clear the copy checkpoint: %v", err))
+		}
+	} else if report.RowsCopied > 0 {
+		report.Error += "; call copy_table again with resume true to continue"
+	}
+	return finish()
+}
+
+func columnIndex(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/database/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingTarget fails the n-th batch insert
+type failingTarget struct {
+	*sqlite.SQLiteDatabase
+	failOn int
+	calls  int
+}
+
+func (f *failingTarget) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	f.calls++
+	if f.calls == f.failOn {
+		return 0, fmt.Errorf("connection reset")
+	}
+	return f.SQLiteDatabase.ExecuteExpectingRows(query, expected, args...)
+}
+
+func openSQLite(t *testing.T, name string) *sqlite.SQLiteDatabase {
+	t.Helper()
+	db, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), name))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func seedOrders(t *testing.T, db *sqlite.SQLiteDatabase, rows int) {
+	t.Helper()
+	_, err := db.ExecuteSQL("CREATE TABLE orders (id INTEGER PRIMARY KEY, customer VARCHAR(20) NOT NULL, total NUMERIC)")
+	require.NoError(t, err)
+	for i := 1; i <= rows; i++ {
+		_, err := db.ExecuteSQLWithArgs("INSERT INTO orders (id, customer, total) VALUES (?, ?, ?)", i, fmt.Sprintf("c%d", i), float64(i)*1.5)
+		require.NoError(t, err)
+	}
+}
+
+func countRows(t *testing.T, db *sqlite.SQLiteDatabase, table string) string {
+	t.Helper()
+	result, err := db.ExecuteSQL("SELECT COUNT(*) FROM " + table)
+	require.NoError(t, err)
+	return fmt.Sprint(result.Rows[0][0])
+}
+
+func TestCopyTableRows(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	source := openSQLite(t, "source.db")
+	target := openSQLite(t, "target.db")
+	seedOrders(t, source, 25)
+
+	report, err := copyTableRows(source, target, CopyRequest{SourceName: "dev", TargetName: "scratch", Table: "orders", TargetTable: "orders_copy", BatchSize: 10})
+	require.NoError(t, err)
+
+	assert.Empty(t, report.Error)
+	assert.True(t, report.CreatedTable)
+	assert.Contains(t, report.DDL, `PRIMARY KEY ("id")`)
+	assert.True(t, report.Complete)
+	assert.Equal(t, int64(25), report.RowsCopied)
+	assert.Equal(t, 3, report.Batches)
+	assert.Equal(t, "25", countRows(t, target, "orders_copy"))
+
+	// A second copy into the filled table is refused
+	report, err = copyTableRows(source, target, CopyRequest{SourceName: "dev", TargetName: "scratch", Table: "orders", TargetTable: "orders_copy"})
+	require.NoError(t, err)
+	assert.Contains(t, report.Error, "already has rows")
+	assert.Equal(t, "25", countRows(t, target, "orders_copy"))
+}
+
+func TestCopyTableRows_Resume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	source := openSQLite(t, "source.db")
+	target := openSQLite(t, "target.db")
+	seedOrders(t, source, 25)
+	req := CopyRequest{SourceName: "dev", TargetName: "scratch", Table: "orders", TargetTable: "orders", BatchSize: 10}
+
+	report, err := copyTableRows(source, &failingTarget{SQLiteDatabase: target, failOn: 2}, req)
+	require.NoError(t, err)
+	assert.False(t, report.Complete)
+	assert.Equal(t, int64(10), report.RowsCopied)
+	assert.Contains(t, report.Error, "failed to write rows 11-20: connection reset")
+	assert.Contains(t, report.Error, "resume true")
+	assert.Equal(t, "10", countRows(t, target, "orders"))
+
+	report, err = copyTableRows(source, target, req)
+	require.NoError(t, err)
+	assert.Contains(t, report.Error, "previous copy stopped after 10 rows")
+
+	req.Resume = true
+	report, err = copyTableRows(source, target, req)
+	require.NoError(t, err)
+	assert.Empty(t, report.Error)
+	assert.True(t, report.Complete)
+	assert.Equal(t, int64(10), report.ResumedAt)
+	assert.Equal(t, int64(25), report.RowsCopied)
+	assert.Equal(t, "25", countRows(t, target, "orders"))
+
+	checkpoints, err := loadCopyCheckpoints()
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+}
+
+func TestCopyTableRows_LimitedToTenant(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	source := openSQLite(t, "source.db")
+	target := openSQLite(t, "target.db")
+	_, err := source.ExecuteSQL("CREATE TABLE orders (id INTEGER PRIMARY KEY, tenant_id INTEGER NOT NULL, total NUMERIC)")
+	require.NoError(t, err)
+	for i := 1; i <= 12; i++ {
+		_, err := source.ExecuteSQLWithArgs("INSERT INTO orders (id, tenant_id, total) VALUES (?, ?, ?)", i, 40+i%3, i)
+		require.NoError(t, err)
+	}
+
+	report, err := copyTableRows(source, target, CopyRequest{SourceName: "dev", TargetName: "scratch", Table: "orders", TargetTable: "orders", BatchSize: 2, Tenant: "42"})
+	require.NoError(t, err)
+	assert.Empty(t, report.Error)
+	assert.Equal(t, int64(4), report.RowsCopied)
+	assert.Contains(t, report.Warnings, "only the rows of tenant 42 are copied")
+	assert.Equal(t, "0", countRows(t, target, "orders WHERE tenant_id <> 42"))
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// CTEReport is the result of advise_ctes: restructuring suggestions for a
+// statement, each with the plan of its rewrite next to the original plan
+type CTEReport struct {
+	ServerVersion int                      `json:"server_version,omitempty"` // Major version, omitted when unknown
+	Analyzed      bool                     `json:"analyzed"`                 // Plans come from EXPLAIN ANALYZE rather than estimates
+	Plan          *sqlanalysis.PlanSummary `json:"plan,omitempty"`
+	PlanError     string                   `json:"plan_error,omitempty"`
+	Advice        []CTESuggestion          `json:"advice"`
+	Notes         []string                 `json:"notes,omitempty"`
+}
+
+// CTESuggestion is a piece of advice with the plan of its rewrite
+type CTESuggestion struct {
+	sqlanalysis.CTEAdvice
+	Plan      *sqlanalysis.PlanSummary `json:"rewrite_plan,omitempty"`
+	PlanError string                   `json:"rewrite_plan_error,omitempty"`
+	Change    string                   `json:"change,omitempty"` // Execution time change when analyzed, otherwise estimated cost change
+}
+
+func (e *Executor) adviseCTEs(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	sql, ok := args["sql"].(string)
+	if !ok {
+		return "", fmt.Errorf("sql argument is required and must be a string")
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	if dialect != "postgresql" {
+		return "", fmt.Errorf("CTE advice is not supported for %s databases", dialect)
+	}
+	// The statement and its rewrites are explained without arguments, which
+	// would run a second statement after the EXPLAIN
+	if err := sqlanalysis.CheckExplainable(sql); err != nil {
+		return "", err
+	}
+
+	report := &CTEReport{Advice: []CTESuggestion{}}
+	// EXPLAIN ANALYZE runs the statement and every rewrite, which is only
+	// done for statements that do not change data. The text cannot show what
+	// the functions it calls do, so the call was confirmed, see RunsStatement.
+	analyze, _ := args["analyze"].(bool)
+	if analyze && sqlanalysis.FirstWrite(sql) != "" {
+		analyze = false
+		report.Notes = append(report.Notes, "The statement changes data, so plans are estimated instead of analyzed")
+	}
+	report.Analyzed = analyze
+
+	// Without the version, 12 or later is assumed
+	if result, err := dbTools.ExecuteSQL(sqlanalysis.ServerVersionQuery); err == nil {
+		report.ServerVersion = sqlanalysis.MajorVersionFromResult(result)
+	}
+
+	thresholds, _ := sqlanalysis.LoadThresholds()
+	explain := func(query string) (*sqlanalysis.PlanSummary, error) {
+		if err := sqlanalysis.CheckExplainable(query); err != nil {
+			return nil, err
+		}
+		statement := sqlanalysis.BuildExplainStatement(dialect, query)
+		if analyze {
+			statement = sqlanalysis.BuildExplainAnalyzeStatement(dialect, query)
+		}
+		result, err := dbTools.ExecuteSQL(statement)
+		if err != nil {
+			return nil, err
+		}
+		return sqlanalysis.AnalyzePlanWithThresholds(dialect, result, thresholds)
+	}
+
+	var err error
+	if report.Plan, err = explain(sql); err != nil {
+		report.PlanError = err.Error()
+	}
+	for _, advice := range sqlanalysis.AdviseCTEs(sql, report.ServerVersion) {
+		suggestion := CTESuggestion{CTEAdvice: advice}
+		if advice.Rewrite != "" {
+			if suggestion.Plan, err = explain(advice.Rewrite); err != nil {
+				suggestion.PlanError = err.Error()
+			} else {
+				suggestion.Change = planChange(report.Plan, suggestion.Plan)
+			}
+		}
+		report.Advice = append(report.Advice, suggestion)
+	}
+	if len(report.Advice) == 0 {
+		report.Notes = append(report.Notes, "No CTE or subquery restructuring found")
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal CTE advice: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// planChange describes how a rewrite's plan compares to the original's: the
+// execution time when both were analyzed, otherwise the estimated cost
+func planChange(before, after *sqlanalysis.PlanSummary) string {
+	if before == nil || after == nil {
+		return ""
+	}
+	if before.ExecutionTime > 0 && after.ExecutionTime > 0 {
+		return fmt.Sprintf("execution time %.1f ms -> %.1f ms (%+.0f%%)", before.ExecutionTime, after.ExecutionTime,
+			(after.ExecutionTime-before.ExecutionTime)/before.ExecutionTime*100)
+	}
+	if before.TotalCost > 0 {
+		return fmt.Sprintf("estimated cost %.0f -> %.0f (%+.0f%%)", before.TotalCost, after.TotalCost,
+			(after.TotalCost-before.TotalCost)/before.TotalCost*100)
+	}
+	return ""
+}
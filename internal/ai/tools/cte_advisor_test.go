@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func explainResult(plan string) *models.QueryResult {
+	return &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+}
+
+func TestExecutor_AdviseCTEs_ComparesPlans(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	sql := "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent WHERE id = 1"
+	rewrite := "SELECT * FROM (SELECT * FROM orders) AS recent WHERE id = 1"
+	mockDB.On("ExecuteSQL", sqlanalysis.ServerVersionQuery).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"110021"}}}, nil)
+	mockDB.On("ExecuteSQL", "EXPLAIN (FORMAT JSON) "+sql).
+		Return(explainResult(`[{"Plan": {"Node Type": "CTE Scan", "Total Cost": 2000, "Plan Rows": 1}}]`), nil)
+	mockDB.On("ExecuteSQL", "EXPLAIN (FORMAT JSON) "+rewrite).
+		Return(explainResult(`[{"Plan": {"Node Type": "Index Scan", "Total Cost": 8.3, "Plan Rows": 1}}]`), nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes", Arguments: `{"sql": "` + sql + `"}`}})
+	require.NoError(t, err)
+
+	var report CTEReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, 11, report.ServerVersion)
+	assert.False(t, report.Analyzed)
+	require.Len(t, report.Advice, 1)
+	assert.Equal(t, sqlanalysis.AdviceCTEFence, report.Advice[0].Kind)
+	assert.Equal(t, rewrite, report.Advice[0].Rewrite)
+	assert.Equal(t, "estimated cost 2000 -> 8 (-100%)", report.Advice[0].Change)
+}
+
+func TestExecutor_AdviseCTEs_Analyze(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	sql := "WITH t AS MATERIALIZED (SELECT * FROM big) SELECT * FROM t WHERE id = 1"
+	rewrite := "WITH t AS (SELECT * FROM big) SELECT * FROM t WHERE id = 1"
+	mockDB.On("ExecuteSQL", sqlanalysis.ServerVersionQuery).
+		Return((*models.QueryResult)(nil), errors.New("pq: permission denied"))
+	mockDB.On("ExecuteSQL", "EXPLAIN (ANALYZE, FORMAT JSON) "+sql).
+		Return(explainResult(`[{"Plan": {"Total Cost": 500, "Plan Rows": 1}, "Execution Time": 40}]`), nil)
+	mockDB.On("ExecuteSQL", "EXPLAIN (ANALYZE, FORMAT JSON) "+rewrite).
+		Return(explainResult(`[{"Plan": {"Total Cost": 9, "Plan Rows": 1}, "Execution Time": 0.5}]`), nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes", Arguments: `{"sql": "` + sql + `", "analyze": true}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"analyzed":true`)
+	assert.Contains(t, output, `"kind":"materialized-hint"`)
+	assert.Contains(t, output, "execution time 40.0 ms -\\u003e 0.5 ms (-99%)")
+
+	// Statements that change data are only planned
+	mockDB.On("ExecuteSQL", "EXPLAIN (FORMAT JSON) DELETE FROM big WHERE id = 1").
+		Return(explainResult(`[{"Plan": {"Total Cost": 8, "Plan Rows": 1}}]`), nil)
+	output, err = executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes", Arguments: `{"sql": "DELETE FROM big WHERE id = 1", "analyze": true}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"analyzed":false`)
+	assert.Contains(t, output, "plans are estimated instead of analyzed")
+}
+
+func TestExecutor_AdviseCTEs_Unsupported(t *testing.T) {
+	executor := NewExecutor(&MockDatabaseInterface{})
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes", Arguments: `{"sql": "SELECT 1"}`}})
+	assert.Error(t, err)
+}
+
+func TestExecutor_AdviseCTEs_RefusesSecondStatement(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes",
+		Arguments: `{"sql": "WITH t AS (SELECT 1) SELECT * FROM t; DELETE FROM big"}`}})
+	assert.EqualError(t, err, "only one statement can be explained at a time, got 2")
+	_, err = executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_ctes",
+		Arguments: `{"sql": "SELECT E'x\\'' ; DROP TABLE t; SELECT ''"}`}})
+	assert.EqualError(t, err, "only one statement can be explained at a time, got 3")
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+}
+
+func TestRunsStatement(t *testing.T) {
+	assert.True(t, RunsStatement("advise_ctes", map[string]interface{}{"sql": "SELECT pg_terminate_backend(pid) FROM pg_stat_activity", "analyze": true}))
+	assert.False(t, RunsStatement("advise_ctes", map[string]interface{}{"sql": "SELECT 1"}))
+	assert.False(t, RunsStatement("analyze_query", map[string]interface{}{"analyze": true}))
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// DDLImpact lists the views, foreign keys, triggers and functions that use
+// what the ALTER and DROP statements of sql change, for the confirmation
+// shown before they run. It returns nil for statements that change no table
+// or column.
+func (e *Executor) DDLImpact(sql string) ([]string, error) {
+	targets := sqlanalysis.DDLTargets(sql)
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	dbTools := e.currentTools()
+	if dbTools == nil {
+		return nil, fmt.Errorf("no database connection available")
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+
+	var impact []string
+	for _, target := range targets {
+		query, err := sqlanalysis.BuildDependentsQuery(dialect, target.Table)
+		if err != nil {
+			return nil, err
+		}
+		result, err := dbTools.ExecuteSQL(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the objects that depend on %s: %w", target.Table, err)
+		}
+		dependents := sqlanalysis.DependentsFromResult(result, target)
+		if len(dependents) == 0 {
+			impact = append(impact, fmt.Sprintf("%s %s: no views, foreign keys, triggers or functions depend on it", target.Action, target))
+			continue
+		}
+		for _, dependent := range dependents {
+			impact = append(impact, fmt.Sprintf("%s %s affects %s", target.Action, target, dependent))
+		}
+	}
+	return impact, nil
+}
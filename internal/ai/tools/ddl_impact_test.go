@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_DDLImpact(t *testing.T) {
+	db := openSQLite(t, "impact.db")
+	seedOrders(t, db, 1)
+	for _, stmt := range []string{
+		"CREATE TABLE refunds (id INTEGER PRIMARY KEY, order_id INTEGER REFERENCES orders (id), amount NUMERIC)",
+		"CREATE VIEW order_totals AS SELECT customer, SUM(total) AS spent FROM orders GROUP BY customer",
+		"CREATE VIEW refund_list AS SELECT order_id, amount FROM refunds",
+		"CREATE TRIGGER orders_audit AFTER UPDATE OF customer ON orders BEGIN SELECT 1; END",
+	} {
+		_, err := db.ExecuteSQL(stmt)
+		require.NoError(t, err)
+	}
+	e := NewExecutor(db)
+
+	impact, err := e.DDLImpact("ALTER TABLE orders DROP COLUMN total")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DROP COLUMN orders.total affects view order_totals (uses total)"}, impact)
+
+	impact, err = e.DDLImpact("DROP TABLE orders")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"DROP TABLE orders affects view order_totals",
+		"DROP TABLE orders affects trigger orders_audit",
+		"DROP TABLE orders affects foreign key refunds.order_id (uses id)",
+	}, impact)
+
+	impact, err = e.DDLImpact("ALTER TABLE refunds RENAME COLUMN id TO refund_id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"RENAME COLUMN refunds.id: no views, foreign keys, triggers or functions depend on it"}, impact)
+
+	impact, err = e.DDLImpact("SELECT * FROM orders")
+	require.NoError(t, err)
+	assert.Nil(t, impact)
+}
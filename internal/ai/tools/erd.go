@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// MaxDiagramTables is the most tables read into an ER diagram
+const MaxDiagramTables = 100
+
+// ERDReport is the result of generate_erd
+type ERDReport struct {
+	Format        string   `json:"format"`
+	Tables        int      `json:"tables"`
+	Relationships int      `json:"relationships"`
+	Diagram       string   `json:"diagram"`
+	Notes         []string `json:"notes,omitempty"`
+}
+
+// BuildERDiagram reads the tables of a schema, or of every schema when none
+// is given, with their columns and the foreign keys between them. tables
+// limits the diagram to the named tables.
+func BuildERDiagram(dbTools dbinterfaces.DatabaseInterface, schema string, tables []string) (*sqlanalysis.ERDiagram, error) {
+	all, err := dbTools.GetAllTables()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[strings.ToLower(unqualifiedName(table))] = true
+	}
+
+	diagram := &sqlanalysis.ERDiagram{Schema: schema}
+	schemas := map[string]bool{}
+	var names []string
+	for _, t := range all {
+		schemas[t.Schema] = true
+		if strings.Contains(strings.ToUpper(t.TableType), "VIEW") {
+			continue
+		}
+		if schema != "" && !strings.EqualFold(t.Schema, schema) {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[strings.ToLower(t.TableName)] {
+			continue
+		}
+		names = append(names, t.TableName)
+	}
+	if len(names) == 0 {
+		if schema != "" && !schemas[schema] {
+			known := make([]string, 0, len(schemas))
+			for name := range schemas {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("no tables in schema %s; schemas: %s", schema, strings.Join(known, ", "))
+		}
+		return nil, fmt.Errorf("no tables to draw")
+	}
+	if len(names) > MaxDiagramTables {
+		diagram.Omitted = len(names) - MaxDiagramTables
+		names = names[:MaxDiagramTables]
+		diagram.Notes = append(diagram.Notes, fmt.Sprintf("Only the first %d tables are drawn, %d more were left out; name a schema or tables to narrow it down", MaxDiagramTables, diagram.Omitted))
+	}
+
+	for _, name := range names {
+		columns, err := dbTools.GetTableSchema(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %w", name, err)
+		}
+		entity := sqlanalysis.Entity{Name: name}
+		for _, col := range columns {
+			entity.Columns = append(entity.Columns, sqlanalysis.EntityColumn{
+				Name:       col.ColumnName,
+				Type:       col.DataType,
+				PrimaryKey: col.IsPrimaryKey,
+				Nullable:   strings.EqualFold(col.IsNullable, "YES"),
+			})
+		}
+		diagram.Entities = append(diagram.Entities, entity)
+	}
+
+	relations, err := diagramRelations(dbTools)
+	if err != nil {
+		diagram.Notes = append(diagram.Notes, fmt.Sprintf("Foreign keys could not be read, so no relationships are drawn: %v", err))
+		return diagram, nil
+	}
+	for _, r := range relations {
+		child, parent := entityIndex(diagram, r.Table), entityIndex(diagram, r.RefTable)
+		if child < 0 || parent < 0 {
+			continue
+		}
+		// SQLite leaves out the columns of implicit primary key references
+		if len(r.RefColumns) == 0 {
+			for _, col := range diagram.Entities[parent].Columns {
+				if col.PrimaryKey {
+					r.RefColumns = append(r.RefColumns, col.Name)
+				}
+			}
+		}
+		for i, col := range diagram.Entities[child].Columns {
+			for _, fk := range r.Columns {
+				if strings.EqualFold(col.Name, fk) {
+					diagram.Entities[child].Columns[i].ForeignKey = true
+				}
+			}
+		}
+		diagram.Relations = append(diagram.Relations, r)
+	}
+	return diagram, nil
+}
+
+// diagramRelations reads every foreign key of the connection
+func diagramRelations(dbTools dbinterfaces.DatabaseInterface) ([]sqlanalysis.Relation, error) {
+	query, err := sqlanalysis.BuildRelationsQuery(dbinterfaces.GetDatabaseType(dbTools))
+	if err != nil {
+		return nil, err
+	}
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	return sqlanalysis.RelationsFromResult(result), nil
+}
+
+// entityIndex returns the position of a table in the diagram, or -1
+func entityIndex(diagram *sqlanalysis.ERDiagram, name string) int {
+	for i, e := range diagram.Entities {
+		if strings.EqualFold(e.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *Executor) generateERD(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	format := sqlanalysis.DiagramMermaid
+	if name, _ := args["format"].(string); name != "" {
+		parsed, err := sqlanalysis.ParseDiagramFormat(name)
+		if err != nil {
+			return "", err
+		}
+		format = parsed
+	}
+	schema, _ := args["schema"].(string)
+	var tables []string
+	if list, ok := args["tables"].([]interface{}); ok {
+		for _, table := range list {
+			if name, ok := table.(string); ok && name != "" {
+				tables = append(tables, name)
+			}
+		}
+	}
+
+	diagram, err := BuildERDiagram(dbTools, strings.TrimSpace(schema), tables)
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(resultJSON), nil
+	}
+	text, err := diagram.Render(format, 0)
+	if err != nil {
+		return "", err
+	}
+	resultJSON, err := json.Marshal(ERDReport{
+		Format:        format,
+		Tables:        len(diagram.Entities),
+		Relationships: len(diagram.Relations),
+		Diagram:       text,
+		Notes:         diagram.Notes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diagram: %w", err)
+	}
+	return string(resultJSON), nil
+}
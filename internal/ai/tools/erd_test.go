@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_GenerateERD(t *testing.T) {
+	db := openSQLite(t, "erd.db")
+	for _, statement := range []string{
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id), note TEXT)",
+		"CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER REFERENCES orders, sku TEXT)",
+		"CREATE VIEW big_orders AS SELECT * FROM orders",
+	} {
+		_, err := db.ExecuteSQL(statement)
+		require.NoError(t, err)
+	}
+	e := NewExecutor(db)
+
+	result, err := e.Execute(toolCall("generate_erd", `{}`))
+	require.NoError(t, err)
+	var report ERDReport
+	require.NoError(t, json.Unmarshal([]byte(result), &report))
+	assert.Equal(t, "mermaid", report.Format)
+	assert.Equal(t, 3, report.Tables, "views are left out")
+	assert.Equal(t, 2, report.Relationships)
+	assert.Contains(t, report.Diagram, "INTEGER customer_id FK")
+	assert.Contains(t, report.Diagram, `customers ||--o{ orders : "customer_id"`)
+	assert.Contains(t, report.Diagram, `orders |o--o{ order_items : "order_id"`)
+
+	result, err = e.Execute(toolCall("generate_erd", `{"format": "dot", "tables": ["orders", "order_items"]}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &report))
+	assert.Equal(t, 2, report.Tables)
+	assert.Equal(t, 1, report.Relationships, "only keys between the drawn tables are kept")
+	assert.Contains(t, report.Diagram, `"order_items":"order_id" -> "orders":"id";`, "implicit references point at the primary key")
+
+	result, err = e.Execute(toolCall("generate_erd", `{"schema": "sales"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "no tables in schema sales")
+}
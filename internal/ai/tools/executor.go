@@ -1,18 +1,52 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
 	"dbsage/pkg/dbinterfaces"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// Limits applied to query results before they are sent to the AI
+const (
+	MaxToolResultRows  = 200
+	MaxToolResultBytes = 64 * 1024
+)
+
 // Executor handles tool execution
 type Executor struct {
 	dbTools    dbinterfaces.DatabaseInterface
 	getDbTools func() dbinterfaces.DatabaseInterface
+	lastSQL    string   // Last successfully executed SQL statement
+	executed   []string // Statements executed since the last TakeExecutedSQL
+	notices    []string // Truncation notices not yet shown to the user
+
+	mu           sync.Mutex
+	lastDuration string                                      // Duration of the last SQL query, read by the UI
+	lastResult   *models.QueryResult                         // Full result of the last execute_sql query, for exports
+	tenant       string                                      // Tenant statements are scoped to, empty when not scoped
+	unavailable  map[string]map[string]sqlanalysis.Privilege // Sources the user may not read, by connection and source
+
+	lastResultSQL        string                     // Statement of lastResult, for /as
+	lastResultConnection string                     // Connection lastResult came from
+	variables            map[string]*ResultVariable // Results saved with /as, by lower-case name
+
+	schemas map[string]*schemaSnapshot // Schemas read for the AI's schema summary, by connection
+	queried map[string][]string        // Tables used by execute_sql, most recent first, by connection
+
+	recordHistory bool // Append executed statements to the query history
+
+	openConnection ConnectionOpener // Opens other configured connections, nil when unavailable
+	restrictions   Restrictions     // Capabilities switched off for this deployment
+	quota          *quotaTracker    // Statement and row quotas of the session, nil when unlimited
 }
 
 func NewExecutor(dbTools dbinterfaces.DatabaseInterface) *Executor {
@@ -25,13 +59,13 @@ func NewExecutorWithDynamicTools(getDbTools func() dbinterfaces.DatabaseInterfac
 
 // Execute executes a tool call
 func (e *Executor) Execute(toolCall openai.ToolCall) (string, error) {
-	// Get current database tools (either static or dynamic)
-	var dbTools dbinterfaces.DatabaseInterface
-	if e.getDbTools != nil {
-		dbTools = e.getDbTools()
-	} else {
-		dbTools = e.dbTools
-	}
+	return e.ExecuteContext(context.Background(), toolCall)
+}
+
+// ExecuteContext executes a tool call of a turn. Tools that wait, such as
+// watch_table, stop early when ctx is cancelled.
+func (e *Executor) ExecuteContext(ctx context.Context, toolCall openai.ToolCall) (string, error) {
+	dbTools := e.currentTools()
 
 	// Check if database tools are available
 	if dbTools == nil {
@@ -43,7 +77,18 @@ func (e *Executor) Execute(toolCall openai.ToolCall) (string, error) {
 		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
 
+	if refusal := e.restrictions.refusal(toolCall.Function.Name, args); refusal != "" {
+		return refusal, nil
+	}
+	if toolCall.Function.Name != "generate_code" && toolCall.Function.Name != "get_query_history" {
+		if refusal := e.quotaRefusal(e.quota.statement()); refusal != "" {
+			return refusal, nil
+		}
+	}
+
 	switch toolCall.Function.Name {
+	case "generate_code":
+		return e.generateCode(dbTools, args)
 	case "execute_sql":
 		return e.executeSQL(dbTools, args)
 	case "get_all_tables":
@@ -52,31 +97,301 @@ func (e *Executor) Execute(toolCall openai.ToolCall) (string, error) {
 		return e.getTableSchema(dbTools, args)
 	case "explain_query":
 		return e.explainQuery(dbTools, args)
+	case "analyze_query":
+		return e.analyzeQuery(dbTools, args)
+	case "advise_ctes":
+		return e.adviseCTEs(dbTools, args)
+	case "advise_indexes":
+		return e.adviseIndexes(dbTools, args)
 	case "get_table_indexes":
 		return e.getTableIndexes(dbTools, args)
+	case "get_table_stats":
+		return e.getTableStats(dbTools, args)
 	case "find_duplicate_data":
 		return e.findDuplicateData(dbTools, args)
+	case "insert_row":
+		return e.insertRow(dbTools, args)
+	case "update_rows":
+		return e.updateRows(dbTools, args)
+	case "generate_test_data":
+		return e.generateTestData(dbTools, args)
+	case "get_rls_policies":
+		return e.getRLSPolicies(dbTools, args)
+	case "setup_fts":
+		return e.setupFTS(dbTools, args)
+	case "list_extensions":
+		return e.listExtensions(dbTools)
+	case "list_features":
+		return e.listFeatures(dbTools)
+	case "get_collations":
+		return e.getCollations(dbTools)
+	case "get_temp_usage":
+		return e.getTempUsage(dbTools)
+	case "advise_config":
+		return e.adviseConfig(dbTools, args)
+	case "profile_waits":
+		return e.profileWaits(dbTools, args)
+	case "copy_table":
+		return e.copyTable(dbTools, args)
+	case "watch_table":
+		return e.watchTable(ctx, dbTools, args)
+	case "export_results":
+		return e.exportResults(dbTools, args)
+	case "sample_results":
+		return e.sampleResults(dbTools, args)
+	case "preview_table":
+		return e.previewTable(dbTools, args)
+	case "generate_erd":
+		return e.generateERD(dbTools, args)
+	case "trace_column":
+		return e.traceColumn(dbTools, args)
+	case "get_query_history":
+		return e.getQueryHistory(args)
 	default:
 		return "", fmt.Errorf("unknown tool: %s", toolCall.Function.Name)
 	}
 }
 
+// currentTools returns the current database tools (either static or dynamic)
+func (e *Executor) currentTools() dbinterfaces.DatabaseInterface {
+	if e.getDbTools != nil {
+		return e.getDbTools()
+	}
+	return e.dbTools
+}
+
+// statementBuilders are the tools that build a statement from structured
+// arguments instead of taking SQL
+var statementBuilders = map[string]bool{
+	"insert_row":         true,
+	"update_rows":        true,
+	"generate_test_data": true,
+}
+
+// IsStatementBuilder reports whether a tool builds its statement from structured arguments
+func IsStatementBuilder(toolName string) bool {
+	return statementBuilders[toolName]
+}
+
+// RunsStatement reports whether the arguments of a tool call make a tool that
+// otherwise only plans or reads run the statement it is given, so the call
+// needs the user's approval like execute_sql. advise_ctes with analyze runs
+// the statement and every rewrite with EXPLAIN ANALYZE, and functions such as
+// pg_terminate_backend or setval act even in a SELECT.
+func RunsStatement(toolName string, args map[string]interface{}) bool {
+	analyze, _ := args["analyze"].(bool)
+	return toolName == "advise_ctes" && analyze
+}
+
+// PreviewStatement returns the SQL a statement-building tool such as insert_row
+// would run, with parameters inlined for display. An error means the arguments
+// do not produce a valid statement; running the tool then writes nothing and
+// reports the problems instead.
+func (e *Executor) PreviewStatement(toolCall openai.ToolCall) (string, error) {
+	dbTools := e.currentTools()
+	if dbTools == nil {
+		return "", fmt.Errorf("no database connection available")
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
+	}
+
+	switch toolCall.Function.Name {
+	case "get_query_history":
+		return e.getQueryHistory(args)
+	case "insert_row":
+		stmt, problems, err := buildInsertFromArgs(dbTools, args, e.Tenant())
+		if err != nil {
+			return "", err
+		}
+		if len(problems) > 0 {
+			return "", fmt.Errorf("invalid values: %s", strings.Join(problems, "; "))
+		}
+		return stmt.Preview, nil
+	case "update_rows":
+		stmt, problems, err := buildUpdateFromArgs(dbTools, args, e.Tenant())
+		if err != nil {
+			return "", err
+		}
+		if len(problems) > 0 {
+			return "", fmt.Errorf("invalid update: %s", strings.Join(problems, "; "))
+		}
+		return stmt.Preview, nil
+	case "generate_test_data":
+		batch, problems, err := buildTestDataFromArgs(dbTools, args, e.Tenant())
+		if err != nil {
+			return "", err
+		}
+		if len(problems) > 0 {
+			return "", fmt.Errorf("invalid rows: %s", strings.Join(problems, "; "))
+		}
+		return strings.Join(batch.preview(), "\n"), nil
+	}
+	return "", fmt.Errorf("%s does not build a statement", toolCall.Function.Name)
+}
+
 func (e *Executor) executeSQL(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
 	sql, ok := args["sql"].(string)
 	if !ok {
 		return "", fmt.Errorf("sql argument is required and must be a string")
 	}
+	sql, refusal := e.inlineVariables(dbTools, sql)
+	if refusal != "" {
+		return refusal, nil
+	}
+	if violation, err := e.tenantViolation(dbTools, sql); err != nil || violation != "" {
+		return violation, err
+	}
+	if violation, err := e.maskingViolation(dbTools, sql); err != nil || violation != "" {
+		return violation, err
+	}
+	if statements := sqlanalysis.SplitStatementsFor(dbinterfaces.GetDatabaseType(dbTools), sql); len(statements) > 1 {
+		continueOnError, _ := args["continueOnError"].(bool)
+		return e.executeScript(dbTools, statements, continueOnError)
+	}
 	result, err := dbTools.ExecuteSQL(sql)
+	e.addToHistory(dbTools, sql, result, err)
 	if err != nil {
+		if missing := e.missingCapabilityResult(dbTools, err); missing != "" {
+			return missing, nil
+		}
+		if missing := e.missingPrivilegeResult(dbTools, err); missing != "" {
+			return missing, nil
+		}
 		return "", err
 	}
-	resultJSON, err := json.Marshal(result)
+	if refusal := e.quotaRefusal(e.quota.fetched(len(result.Rows))); refusal != "" {
+		return refusal, nil
+	}
+	e.lastSQL = sql
+	e.setLastDuration(result.Duration)
+	e.recordQueried(dbinterfaces.ConnectionName(dbTools), sql)
+	e.mu.Lock()
+	e.executed = append(e.executed, sql)
+	if len(result.Columns) > 0 {
+		e.lastResult = result
+		e.lastResultSQL = sql
+		e.lastResultConnection = dbinterfaces.ConnectionName(dbTools)
+	}
+	e.mu.Unlock()
+	if result.StalenessWarning != "" {
+		e.notices = append(e.notices, "Result "+result.StalenessWarning)
+	}
+	resultJSON, err := e.marshalTruncated(dbinterfaces.ConnectionName(dbTools), result)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal SQL result: %w", err)
 	}
 	return string(resultJSON), nil
 }
 
+// GetLastDuration returns the duration of the last SQL query executed by a tool
+func (e *Executor) GetLastDuration() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastDuration
+}
+
+func (e *Executor) setLastDuration(duration string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastDuration = duration
+}
+
+// TakeExecutedSQL returns the statements executed by execute_sql since the last call and clears them
+func (e *Executor) TakeExecutedSQL() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	executed := e.executed
+	e.executed = nil
+	return executed
+}
+
+// TakeNotices returns the truncation notices produced since the last call and clears them
+func (e *Executor) TakeNotices() []string {
+	notices := e.notices
+	e.notices = nil
+	return notices
+}
+
+// marshalTruncated marshals a query result, masking sensitive values by the
+// settings of the connection it came from and dropping rows beyond the row and
+// size limits. Truncation is recorded in the result itself so the AI knows the
+// data is partial, and a notice is queued for the user.
+func (e *Executor) marshalTruncated(connection string, result *models.QueryResult) ([]byte, error) {
+	return e.marshalFitted(e.maskResult(connection, result))
+}
+
+// marshalFitted marshals a result already masked for the AI, dropping rows
+// beyond the row and size limits
+func (e *Executor) marshalFitted(result *models.QueryResult) ([]byte, error) {
+	total := len(result.Rows)
+	reason := ""
+	if total > MaxToolResultRows {
+		reason = fmt.Sprintf("row limit of %d rows", MaxToolResultRows)
+		result = truncatedCopy(result, MaxToolResultRows, total, reason)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Halve the rows until the payload fits the size limit
+	for len(data) > MaxToolResultBytes && len(result.Rows) > 1 {
+		reason = fmt.Sprintf("size limit of %d KB", MaxToolResultBytes/1024)
+		result = truncatedCopy(result, len(result.Rows)/2, total, reason)
+		if data, err = json.Marshal(result); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Truncated {
+		e.notices = append(e.notices, fmt.Sprintf("Result truncated: the AI received %d of %d rows (%s)",
+			len(result.Rows), total, reason))
+	}
+	return data, nil
+}
+
+// truncatedCopy returns a copy of the result with only the first rows kept
+func truncatedCopy(result *models.QueryResult, rows, total int, reason string) *models.QueryResult {
+	truncated := *result
+	truncated.Rows = result.Rows[:rows]
+	truncated.RowCount = rows
+	truncated.Truncated = true
+	truncated.TotalRows = total
+	truncated.TruncationReason = reason
+	return &truncated
+}
+
+// GetLastSQL returns the last successfully executed SQL statement
+func (e *Executor) GetLastSQL() string {
+	return e.lastSQL
+}
+
+func (e *Executor) generateCode(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	language, ok := args["language"].(string)
+	if !ok {
+		return "", fmt.Errorf("language argument is required and must be a string")
+	}
+	sql, _ := args["sql"].(string)
+	if sql == "" {
+		sql = e.lastSQL
+	}
+	if sql == "" {
+		return `{"error": "No SQL has been executed yet. Execute a query first or pass the sql argument."}`, nil
+	}
+	snippet, err := GenerateCodeSnippet(language, sql, dbinterfaces.GetDatabaseType(dbTools))
+	if err != nil {
+		return "", err
+	}
+	resultJSON, err := json.Marshal(snippet)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal code snippet: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
 func (e *Executor) getAllTables(dbTools dbinterfaces.DatabaseInterface) (string, error) {
 	tables, err := dbTools.GetAllTables()
 	if err != nil {
@@ -98,6 +413,11 @@ func (e *Executor) getTableSchema(dbTools dbinterfaces.DatabaseInterface, args m
 	if err != nil {
 		return "", err
 	}
+	for _, col := range schema {
+		if col.Spatial != nil && !col.Spatial.Indexed {
+			col.Spatial.Suggestion = sqlanalysis.SpatialIndexSuggestion(tableName, col.ColumnName)
+		}
+	}
 	resultJSON, err := json.Marshal(schema)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal schema: %w", err)
@@ -121,6 +441,56 @@ func (e *Executor) explainQuery(dbTools dbinterfaces.DatabaseInterface, args map
 	return string(resultJSON), nil
 }
 
+// QueryAnalysis is the result of running the built-in SQL optimizer on a statement
+type QueryAnalysis struct {
+	Dialect   string                   `json:"dialect,omitempty"`
+	Profile   string                   `json:"profile"`
+	Findings  []sqlanalysis.Finding    `json:"findings"`
+	Plan      *sqlanalysis.PlanSummary `json:"plan,omitempty"`
+	PlanError string                   `json:"plan_error,omitempty"`
+}
+
+func (e *Executor) analyzeQuery(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	sql, ok := args["sql"].(string)
+	if !ok {
+		return "", fmt.Errorf("sql argument is required and must be a string")
+	}
+
+	resultJSON, err := json.Marshal(AnalyzeQuery(dbTools, sql))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal query analysis: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// AnalyzeQuery lints a statement and analyzes its estimated plan using the
+// configured thresholds. Only a plain EXPLAIN of a single statement is run,
+// so the statement itself is not executed.
+func AnalyzeQuery(dbTools dbinterfaces.DatabaseInterface, sql string) *QueryAnalysis {
+	thresholds, _ := sqlanalysis.LoadThresholds()
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	analysis := &QueryAnalysis{
+		Dialect:  dialect,
+		Profile:  thresholds.Profile,
+		Findings: sqlanalysis.LintSQL(sql, dialect),
+	}
+
+	// Only the estimated plan is requested. A second statement would run
+	// after the EXPLAIN, so those are refused.
+	if err := sqlanalysis.CheckExplainable(sql); err != nil {
+		analysis.PlanError = err.Error()
+		return analysis
+	}
+	result, err := dbTools.ExecuteSQL(sqlanalysis.BuildExplainStatement(dialect, sql))
+	if err == nil {
+		analysis.Plan, err = sqlanalysis.AnalyzePlanWithThresholds(dialect, result, thresholds)
+	}
+	if err != nil {
+		analysis.PlanError = err.Error()
+	}
+	return analysis
+}
+
 func (e *Executor) getTableIndexes(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
 	tableName, ok := args["tableName"].(string)
 	if !ok {
@@ -152,13 +522,232 @@ func (e *Executor) findDuplicateData(dbTools dbinterfaces.DatabaseInterface, arg
 			columns = append(columns, colStr)
 		}
 	}
-	result, err := dbTools.FindDuplicateData(tableName, columns)
+	result, err := findDuplicates(dbTools, tableName, columns, e.Tenant())
 	if err != nil {
 		return "", err
 	}
-	resultJSON, err := json.Marshal(result)
+	if refusal := e.quotaRefusal(e.quota.fetched(len(result.Rows))); refusal != "" {
+		return refusal, nil
+	}
+	resultJSON, err := e.marshalTruncated(dbinterfaces.ConnectionName(dbTools), result)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal duplicate result: %w", err)
 	}
 	return string(resultJSON), nil
 }
+
+// findDuplicates groups a table by columns and returns the values found more
+// than once. In a tenant context only the tenant's rows of a scoped table are
+// grouped.
+func findDuplicates(dbTools dbinterfaces.DatabaseInterface, tableName string, columns []string, tenant string) (*models.QueryResult, error) {
+	if tenant == "" {
+		return dbTools.FindDuplicateData(tableName, columns)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column must be specified")
+	}
+	schema, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return nil, err
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	scope, err := tenantScopeCondition(dialect, tenant, tableName, schema)
+	if err != nil {
+		return nil, err
+	}
+	if scope == "" {
+		return dbTools.FindDuplicateData(tableName, columns)
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(dialect, col)
+	}
+	columnList := strings.Join(quoted, ", ")
+	return dbTools.ExecuteSQL(fmt.Sprintf("SELECT %s, COUNT(*) AS duplicate_count FROM %s WHERE %s GROUP BY %s HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT 100",
+		columnList, quoteIdentifier(dialect, tableName), scope, columnList))
+}
+
+// buildInsertFromArgs reads the insert_row arguments and builds the statement
+// from the table's current schema, setting the tenant column when scoped
+func buildInsertFromArgs(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}, tenant string) (*InsertStatement, []string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return nil, nil, fmt.Errorf("tableName argument is required and must be a string")
+	}
+	values, ok := args["values"].(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("values argument is required and must be an object")
+	}
+	columns, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return nil, nil, err
+	}
+	tenantProblems, err := scopeInsertValues(tenant, tableName, columns, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt, problems := BuildInsert(dbinterfaces.GetDatabaseType(dbTools), tableName, columns, values)
+	if len(tenantProblems) > 0 {
+		return nil, append(tenantProblems, problems...), nil
+	}
+	return stmt, problems, nil
+}
+
+func (e *Executor) insertRow(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	stmt, problems, err := buildInsertFromArgs(dbTools, args, e.Tenant())
+	if err != nil {
+		return "", err
+	}
+	if len(problems) > 0 {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":    "validation failed, nothing was inserted",
+			"problems": problems,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal validation problems: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+
+	result, err := dbinterfaces.ExecuteSQLWithArgs(dbTools, stmt.SQL, stmt.Params...)
+	if err != nil {
+		return "", err
+	}
+	e.setLastDuration(result.Duration)
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"statement": stmt,
+		"inserted":  e.maskResult(dbinterfaces.ConnectionName(dbTools), result),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal insert result: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// buildUpdateFromArgs reads the update_rows arguments and builds the statement
+// from the table's current schema, limited to the tenant when scoped
+func buildUpdateFromArgs(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}, tenant string) (*UpdateStatement, []string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return nil, nil, fmt.Errorf("tableName argument is required and must be a string")
+	}
+	values, ok := args["values"].(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("values argument is required and must be an object")
+	}
+	keys, _ := args["keys"].(map[string]interface{})
+	where, _ := args["where"].(string)
+	expected, err := expectedRowsArg(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return nil, nil, err
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	scope, err := tenantScopeCondition(dialect, tenant, tableName, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt, problems := BuildUpdate(dialect, columns, UpdateRequest{
+		Table:        tableName,
+		Values:       values,
+		Keys:         keys,
+		Where:        where,
+		ExpectedRows: expected,
+		Scope:        scope,
+	})
+	return stmt, problems, nil
+}
+
+func (e *Executor) updateRows(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	stmt, problems, err := buildUpdateFromArgs(dbTools, args, e.Tenant())
+	if err != nil {
+		return "", err
+	}
+	if len(problems) > 0 {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":    "validation failed, nothing was updated",
+			"problems": problems,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal validation problems: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+
+	affected, err := dbinterfaces.ExecuteExpectingRows(dbTools, stmt.SQL, stmt.ExpectedRows, stmt.Params...)
+	var mismatch *dbinterfaces.RowCountMismatchError
+	if errors.As(err, &mismatch) {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":         "the update affected a different number of rows than expected and was rolled back",
+			"expected_rows": mismatch.Expected,
+			"actual_rows":   mismatch.Actual,
+			"statement":     stmt,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal update result: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"statement":     stmt,
+		"affected_rows": affected,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal update result: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+func (e *Executor) setupFTS(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return "", fmt.Errorf("tableName argument is required and must be a string")
+	}
+	columnsInterface, ok := args["columns"].([]interface{})
+	if !ok {
+		return "", fmt.Errorf("columns argument is required and must be an array")
+	}
+	req := FTSRequest{Table: tableName}
+	for _, col := range columnsInterface {
+		if colStr, ok := col.(string); ok {
+			req.Columns = append(req.Columns, colStr)
+		}
+	}
+	req.Language, _ = args["language"].(string)
+	req.Trigger, _ = args["useTrigger"].(bool)
+
+	columns, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return "", err
+	}
+	plan, problems := BuildFTSPlan(dbinterfaces.GetDatabaseType(dbTools), columns, req)
+	if len(problems) > 0 {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":    "cannot set up full-text search",
+			"problems": problems,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal problems: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"plan":        plan,
+		"instruction": "Nothing was executed. Show the statements and notes to the user; run them one by one with execute_sql only if they agree.",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal full-text search plan: %w", err)
+	}
+	return string(resultJSON), nil
+}
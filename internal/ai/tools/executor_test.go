@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"dbsage/internal/models"
@@ -64,6 +65,27 @@ func (m *MockDatabaseInterface) FindDuplicateData(tableName string, columns []st
 	return args.Get(0).(*models.QueryResult), args.Error(1)
 }
 
+func (m *MockDatabaseInterface) ExecuteSQLWithArgs(query string, params ...interface{}) (*models.QueryResult, error) {
+	args := m.Called(query, params)
+	return args.Get(0).(*models.QueryResult), args.Error(1)
+}
+
+// mockPostgres is a mock database that reports itself as PostgreSQL
+type mockPostgres struct {
+	*MockDatabaseInterface
+}
+
+func (m mockPostgres) DatabaseType() string { return "postgresql" }
+
+func (m mockPostgres) ExecuteExpectingRows(query string, expected int64, params ...interface{}) (int64, error) {
+	args := m.Called(query, expected, params)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m mockPostgres) ExecuteSQLWithArgs(query string, params ...interface{}) (*models.QueryResult, error) {
+	return m.MockDatabaseInterface.ExecuteSQLWithArgs(query, params...)
+}
+
 func TestNewExecutor(t *testing.T) {
 	mockDB := &MockDatabaseInterface{}
 	executor := NewExecutor(mockDB)
@@ -350,6 +372,132 @@ func TestExecutor_FindDuplicateData_MissingColumns(t *testing.T) {
 	assert.Empty(t, result)
 }
 
+func TestExecutor_GenerateCode_UsesLastSQL(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	query := "SELECT id, name FROM users WHERE email = 'a@b.com' AND age > 21"
+	mockDB.On("ExecuteSQL", query).Return(&models.QueryResult{Columns: []string{"id", "name"}}, nil)
+
+	_, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "execute_sql",
+			Arguments: `{"sql": "SELECT id, name FROM users WHERE email = 'a@b.com' AND age > 21"}`,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, query, executor.GetLastSQL())
+
+	result, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "generate_code",
+			Arguments: `{"language": "python"}`,
+		},
+	})
+	require.NoError(t, err)
+
+	var snippet CodeSnippet
+	require.NoError(t, json.Unmarshal([]byte(result), &snippet))
+	assert.Equal(t, "python", snippet.Language)
+	assert.Equal(t, "SELECT id, name FROM users WHERE email = %s AND age > %s", snippet.ParameterizedSQL)
+	assert.Equal(t, []string{"a@b.com", "21"}, snippet.Parameters)
+	assert.Contains(t, snippet.Code, "cur.execute(QUERY, ('a@b.com', 21))")
+	assert.Contains(t, snippet.Code, "except psycopg.Error")
+}
+
+func TestExecutor_GenerateCode_NoSQL(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	result, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "generate_code",
+			Arguments: `{"language": "go"}`,
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "No SQL has been executed yet")
+}
+
+func TestGenerateCodeSnippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		dialect  string
+		query    string
+		contains []string
+	}{
+		{
+			name:     "go select",
+			language: "go",
+			query:    "SELECT * FROM orders WHERE status = 'paid' LIMIT 10;",
+			contains: []string{"WHERE status = $1 LIMIT $2", `db.QueryContext(ctx, query, "paid", 10)`, "rows.Err()"},
+		},
+		{
+			name:     "node update",
+			language: "node",
+			query:    "UPDATE users SET name = 'O''Brien' WHERE id = 7",
+			contains: []string{"SET name = $1 WHERE id = $2", `const params = ["O'Brien", 7];`, "result.rowCount"},
+		},
+		{
+			name:     "identifiers with digits are kept",
+			language: "go",
+			query:    `SELECT t1.col2 FROM "table3" t1`,
+			contains: []string{"SELECT t1.col2 FROM \"table3\" t1", "db.QueryContext(ctx, query)"},
+		},
+		{
+			name:     "ordinals and intervals are kept",
+			language: "go",
+			dialect:  "postgresql",
+			query:    "SELECT status, count(*) FROM orders WHERE created_at > now() - INTERVAL '1 day' AND total > 5 GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT 3",
+			contains: []string{"now() - INTERVAL '1 day' AND total > $1 GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $2", "db.QueryContext(ctx, query, 5, 3)"},
+		},
+		{
+			name:     "numbers in ORDER BY expressions are parameters",
+			language: "go",
+			query:    "SELECT id FROM orders ORDER BY coalesce(total, 0), id",
+			contains: []string{"ORDER BY coalesce(total, $1), id", "db.QueryContext(ctx, query, 0)"},
+		},
+		{
+			name:     "go mysql",
+			language: "go",
+			dialect:  "mysql",
+			query:    "SELECT * FROM orders WHERE status = 'paid' LIMIT 10",
+			contains: []string{"WHERE status = ? LIMIT ?", `_ "github.com/go-sql-driver/mysql"`},
+		},
+		{
+			name:     "python sqlite",
+			language: "python",
+			dialect:  "sqlite",
+			query:    "DELETE FROM logs WHERE level = 'debug' AND message LIKE '%x%'",
+			contains: []string{"WHERE level = ? AND message LIKE ?", "import sqlite3", "conn.execute(QUERY, ('debug', '%x%'))"},
+		},
+		{
+			name:     "node mysql",
+			language: "node",
+			dialect:  "mysql",
+			query:    "UPDATE users SET name = 'Ann' WHERE id = 7",
+			contains: []string{"SET name = ? WHERE id = ?", "require('mysql2/promise')", "result.affectedRows"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, err := GenerateCodeSnippet(tt.language, tt.query, tt.dialect)
+			require.NoError(t, err)
+			for _, want := range tt.contains {
+				assert.Contains(t, snippet.Code, want)
+			}
+		})
+	}
+
+	_, err := GenerateCodeSnippet("cobol", "SELECT 1", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported language")
+	_, err = GenerateCodeSnippet("go", "SELECT 1", "clickhouse")
+	assert.Error(t, err)
+}
+
 // Benchmark tests
 func BenchmarkExecutor_ExecuteSQL(b *testing.B) {
 	mockDB := &MockDatabaseInterface{}
@@ -376,3 +524,169 @@ func BenchmarkExecutor_ExecuteSQL(b *testing.B) {
 		_, _ = executor.Execute(toolCall)
 	}
 }
+
+func TestExecutor_AnalyzeQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // Use the default analysis thresholds
+
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	plan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 1200.5, "Plan Rows": 80000}}]`
+	mockDB.On("ExecuteSQL", "EXPLAIN (FORMAT JSON) SELECT * FROM orders").
+		Return(&models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}, nil)
+
+	result, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "analyze_query",
+			Arguments: `{"sql": "SELECT * FROM orders"}`,
+		},
+	})
+	require.NoError(t, err)
+
+	var analysis QueryAnalysis
+	require.NoError(t, json.Unmarshal([]byte(result), &analysis))
+	assert.NotEmpty(t, analysis.Findings)
+	require.NotNil(t, analysis.Plan)
+	assert.Equal(t, 1200.5, analysis.Plan.TotalCost)
+	require.Len(t, analysis.Plan.Warnings, 1)
+	assert.Equal(t, "orders", analysis.Plan.Warnings[0].Relation)
+	mockDB.AssertExpectations(t)
+}
+
+func TestExecutor_AnalyzeQuery_NotExplainable(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	result, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "analyze_query",
+			Arguments: `{"sql": "DROP TABLE orders"}`,
+		},
+	})
+	require.NoError(t, err)
+
+	var analysis QueryAnalysis
+	require.NoError(t, json.Unmarshal([]byte(result), &analysis))
+	assert.Nil(t, analysis.Plan)
+	assert.NotEmpty(t, analysis.PlanError)
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+}
+
+func TestExecutor_AnalyzeQuery_RefusesSecondStatement(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	result, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "analyze_query",
+			Arguments: `{"sql": "SELECT 1; DROP TABLE orders"}`,
+		},
+	})
+	require.NoError(t, err)
+
+	var analysis QueryAnalysis
+	require.NoError(t, json.Unmarshal([]byte(result), &analysis))
+	assert.Nil(t, analysis.Plan)
+	assert.Equal(t, "only one statement can be explained at a time, got 2", analysis.PlanError)
+
+	result, err = executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{
+			Name:      "analyze_query",
+			Arguments: `{"sql": "EXPLAIN SELECT E'x\\'' ; DROP TABLE orders; SELECT ''"}`,
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &analysis))
+	assert.Equal(t, "only one statement can be explained at a time, got 3", analysis.PlanError, "an escaped quote does not hide the DROP")
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+}
+
+func TestExecutor_ExecuteSQL_TruncatesLargeResults(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	result := &models.QueryResult{Columns: []string{"id"}}
+	for i := 0; i < MaxToolResultRows+50; i++ {
+		result.Rows = append(result.Rows, []interface{}{i})
+	}
+	result.RowCount = len(result.Rows)
+	mockDB.On("ExecuteSQL", "SELECT id FROM events").Return(result, nil)
+
+	output, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT id FROM events"}`},
+	})
+	require.NoError(t, err)
+
+	var sent models.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(output), &sent))
+	assert.True(t, sent.Truncated)
+	assert.Equal(t, MaxToolResultRows, sent.RowCount)
+	assert.Len(t, sent.Rows, MaxToolResultRows)
+	assert.Equal(t, MaxToolResultRows+50, sent.TotalRows)
+	assert.Contains(t, sent.TruncationReason, "row limit")
+
+	// The original result must not be modified
+	assert.Len(t, result.Rows, MaxToolResultRows+50)
+
+	notices := executor.TakeNotices()
+	require.Len(t, notices, 1)
+	assert.Contains(t, notices[0], "200 of 250 rows")
+	assert.Empty(t, executor.TakeNotices())
+}
+
+func TestExecutor_ExecuteSQL_TruncatesBySize(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	wide := strings.Repeat("x", 2048)
+	result := &models.QueryResult{Columns: []string{"payload"}}
+	for i := 0; i < 100; i++ {
+		result.Rows = append(result.Rows, []interface{}{wide})
+	}
+	mockDB.On("ExecuteSQL", "SELECT payload FROM blobs").Return(result, nil)
+
+	output, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT payload FROM blobs"}`},
+	})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(output), MaxToolResultBytes)
+
+	var sent models.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(output), &sent))
+	assert.True(t, sent.Truncated)
+	assert.Equal(t, 100, sent.TotalRows)
+	assert.Contains(t, sent.TruncationReason, "size limit")
+}
+
+func TestExecutor_ExecuteSQL_SmallResultNotTruncated(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+
+	mockDB.On("ExecuteSQL", "SELECT 1").Return(&models.QueryResult{Columns: []string{"?column?"}, Rows: [][]interface{}{{1}}, RowCount: 1}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{
+		Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT 1"}`},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, output, "truncated")
+	assert.Empty(t, executor.TakeNotices())
+}
+
+func TestExecutor_GetTableSchema_SuggestsSpatialIndex(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockDB)
+	mockDB.On("GetTableSchema", "parcels").Return([]models.ColumnInfo{
+		{ColumnName: "id", DataType: "integer"},
+		{ColumnName: "geom", DataType: "geometry", Spatial: &models.Spatial{Type: "geometry", GeometryType: "POLYGON", SRID: 4326}},
+		{ColumnName: "centroid", DataType: "geometry", Spatial: &models.Spatial{Type: "geometry", GeometryType: "POINT", SRID: 4326, Indexed: true}},
+	}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "get_table_schema",
+		Arguments: `{"tableName": "parcels"}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `CREATE INDEX ON parcels USING GIST (geom)`)
+	assert.NotContains(t, output, `(centroid)`)
+	assert.Contains(t, output, `"srid":4326`)
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"dbsage/internal/export"
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// ExportReport is the result of export_results
+type ExportReport struct {
+	Path      string `json:"path"`
+	Format    string `json:"format"`
+	Rows      int    `json:"rows"`
+	TotalRows int    `json:"total_rows"`
+	Columns   int    `json:"columns"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Message   string `json:"message"`
+}
+
+// LastResult returns the full result of the last query run by execute_sql,
+// or nil when none returned rows
+func (e *Executor) LastResult() *models.QueryResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastResult
+}
+
+// LastResultQuery returns the statement of LastResult and the connection it
+// ran on, empty when there is none
+func (e *Executor) LastResultQuery() (string, string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastResult == nil {
+		return "", ""
+	}
+	return e.lastResultSQL, e.lastResultConnection
+}
+
+// exportResults saves the last query result, or the result of a given
+// read-only query, to a file
+func (e *Executor) exportResults(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	path, _ := args["path"].(string)
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("path argument is required and must be a string")
+	}
+
+	format, ok := export.FormatForPath(path)
+	if name, _ := args["format"].(string); name != "" {
+		parsed, err := export.ParseFormat(name)
+		if err != nil {
+			return "", err
+		}
+		format, ok = parsed, true
+	}
+	if !ok {
+		return "", fmt.Errorf("cannot tell the format from %s: pass format csv, json, md or xlsx", path)
+	}
+
+	result := e.LastResult()
+	query, connection := e.LastResultQuery()
+	if sql, _ := args["sql"].(string); strings.TrimSpace(sql) != "" {
+		if !sqlanalysis.IsReadOnly(sql) {
+			return `{"error": "export_results only runs read-only queries; run changes with execute_sql"}`, nil
+		}
+		if violation, err := e.tenantViolation(dbTools, sql); err != nil || violation != "" {
+			return violation, err
+		}
+		var err error
+		if result, err = dbTools.ExecuteSQL(sql); err != nil {
+			return "", err
+		}
+		query, connection = sql, dbinterfaces.ConnectionName(dbTools)
+		if refusal := e.quotaRefusal(e.quota.fetched(len(result.Rows))); refusal != "" {
+			return refusal, nil
+		}
+	}
+	if result == nil {
+		return `{"error": "No query result to export yet. Run a query with execute_sql first or pass the sql argument."}`, nil
+	}
+
+	summary, err := export.Write(path, format, result, export.NewMetadata(connection, query, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	if summary.Truncated() {
+		e.notices = append(e.notices, summary.String())
+	}
+	report, err := json.Marshal(ExportReport{
+		Path:      summary.Path,
+		Format:    string(summary.Format),
+		Rows:      summary.Rows,
+		TotalRows: summary.Total,
+		Columns:   summary.Columns,
+		Truncated: summary.Truncated(),
+		Message:   summary.String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal export report: %w", err)
+	}
+	return string(report), nil
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_ExportResults(t *testing.T) {
+	db := openSQLite(t, "export.db")
+	seedOrders(t, db, 3)
+	e := NewExecutor(db)
+	dir := t.TempDir()
+
+	result, err := e.Execute(toolCall("export_results", `{"path": "`+filepath.Join(dir, "none.csv")+`"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "No query result to export yet")
+
+	_, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT id, customer FROM orders ORDER BY id"}`))
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "orders.csv")
+	result, err = e.Execute(toolCall("export_results", `{"path": "`+path+`"}`))
+	require.NoError(t, err)
+	var report ExportReport
+	require.NoError(t, json.Unmarshal([]byte(result), &report))
+	assert.Equal(t, "csv", report.Format)
+	assert.Equal(t, 3, report.Rows)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "id,customer\n1,c1\n2,c2\n3,c3\n", string(data))
+
+	// A query of its own, in the format given
+	path = filepath.Join(dir, "totals.out")
+	result, err = e.Execute(toolCall("export_results", `{"path": "`+path+`", "format": "json", "sql": "SELECT COUNT(*) AS n FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, `"rows":1`)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[\n  {\"n\": 3}\n]\n", string(data))
+
+	result, err = e.Execute(toolCall("export_results", `{"path": "`+path+`", "format": "json", "sql": "DELETE FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "only runs read-only queries")
+	assert.Equal(t, "3", countRows(t, db, "orders"))
+
+	_, err = e.Execute(toolCall("export_results", `{"path": "`+filepath.Join(dir, "orders")+`"}`))
+	assert.ErrorContains(t, err, "pass format csv, json, md or xlsx")
+}
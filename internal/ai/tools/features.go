@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// inventory queries the extensions and server settings of the current database.
+// Settings are optional: a failure to read them leaves features nil.
+func inventory(dbTools dbinterfaces.DatabaseInterface) ([]sqlanalysis.Extension, []sqlanalysis.Feature, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	query, err := sqlanalysis.BuildExtensionsQuery(dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list extensions: %w", err)
+	}
+	extensions := sqlanalysis.ExtensionsFromResult(dialect, result)
+	if extensions == nil {
+		extensions = []sqlanalysis.Extension{}
+	}
+
+	var features []sqlanalysis.Feature
+	if query, err := sqlanalysis.BuildFeaturesQuery(dialect); err == nil {
+		if result, err := dbTools.ExecuteSQL(query); err == nil {
+			features = sqlanalysis.FeaturesFromResult(result)
+		}
+	}
+	return extensions, features, nil
+}
+
+func (e *Executor) listExtensions(dbTools dbinterfaces.DatabaseInterface) (string, error) {
+	extensions, features, err := inventory(dbTools)
+	if err != nil {
+		return "", err
+	}
+
+	var installed, available []sqlanalysis.Extension
+	for _, ext := range extensions {
+		if ext.Installed {
+			installed = append(installed, ext)
+		} else {
+			available = append(available, ext)
+		}
+	}
+	names := make([]string, len(available))
+	for i, ext := range available {
+		names[i] = ext.Name
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"installed":               installed,
+		"available_not_installed": names,
+		"missing_capabilities":    sqlanalysis.MissingCapabilities(dbinterfaces.GetDatabaseType(dbTools), extensions, features),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal extensions: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+func (e *Executor) listFeatures(dbTools dbinterfaces.DatabaseInterface) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	if _, err := sqlanalysis.BuildFeaturesQuery(dialect); err != nil {
+		return "", err
+	}
+	extensions, features, err := inventory(dbTools)
+	if err != nil {
+		return "", err
+	}
+	if features == nil {
+		return "", fmt.Errorf("failed to read server settings")
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"settings":             features,
+		"missing_capabilities": sqlanalysis.MissingCapabilities(dialect, extensions, features),
+		"missing_privileges":   e.missingPrivileges(dbTools),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal features: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// missingCapabilityResult returns the tool result for a statement that failed
+// because an extension or setting is missing, or "" for other errors
+func (e *Executor) missingCapabilityResult(dbTools dbinterfaces.DatabaseInterface, execErr error) string {
+	capability, ok := sqlanalysis.MissingCapability(dbinterfaces.GetDatabaseType(dbTools), execErr)
+	if !ok {
+		return ""
+	}
+
+	e.notices = append(e.notices, fmt.Sprintf("%s is not installed; to enable %s: %s", capability.Name, capability.Purpose, capability.Install))
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"error":             execErr.Error(),
+		"missing_extension": capability,
+		"instruction":       "The statement needs an extension or setting that is not enabled. Explain how to install it (missing_extension.install) and do not retry the statement.",
+	})
+	if err != nil {
+		return ""
+	}
+	return string(resultJSON)
+}
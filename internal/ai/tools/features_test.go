@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_ExecuteSQL_MissingExtension(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("ExecuteSQL", "SELECT * FROM pg_stat_statements").
+		Return((*models.QueryResult)(nil), errors.New(`pq: relation "pg_stat_statements" does not exist`))
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "execute_sql",
+		Arguments: `{"sql": "SELECT * FROM pg_stat_statements"}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"missing_extension"`)
+	assert.Contains(t, output, "shared_preload_libraries")
+	assert.Len(t, executor.TakeNotices(), 1)
+}
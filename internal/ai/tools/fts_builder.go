@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// FTSVectorColumn is the tsvector column added to PostgreSQL tables
+const FTSVectorColumn = "search_vector"
+
+// FTSPlan is the change set that adds full-text search to a table. It is
+// generated for review and never executed by setup_fts itself.
+type FTSPlan struct {
+	Dialect     string   `json:"dialect"`
+	Statements  []string `json:"statements"` // Run in order
+	SampleQuery string   `json:"sample_query"`
+	Notes       []string `json:"notes,omitempty"`
+}
+
+// FTSRequest selects the columns to index
+type FTSRequest struct {
+	Table    string
+	Columns  []string // In order of importance; PostgreSQL weights the first ones higher
+	Language string   // PostgreSQL text search configuration, defaults to english
+	Trigger  bool     // PostgreSQL: maintain the column with a trigger instead of a generated column (before 12)
+}
+
+// BuildFTSPlan validates the columns against the table schema and builds the
+// full-text search change set for the dialect: a tsvector column with a GIN
+// index on PostgreSQL, a FULLTEXT index on MySQL and an FTS5 table on SQLite
+func BuildFTSPlan(dialect string, columns []models.ColumnInfo, req FTSRequest) (*FTSPlan, []string) {
+	var problems []string
+	if len(req.Columns) == 0 {
+		problems = append(problems, "at least one text column is required")
+	}
+
+	byName := columnsByName(columns)
+	var selected []models.ColumnInfo
+	for _, name := range req.Columns {
+		col, ok := byName[strings.ToLower(name)]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown column %s", name))
+		case !isTextType(col.DataType):
+			problems = append(problems, fmt.Sprintf("column %s is %s, not a text column", col.ColumnName, col.DataType))
+		default:
+			selected = append(selected, col)
+		}
+	}
+	if dialect == "postgresql" {
+		if _, exists := byName[FTSVectorColumn]; exists {
+			problems = append(problems, fmt.Sprintf("%s already has a %s column; full-text search may already be set up", req.Table, FTSVectorColumn))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	switch dialect {
+	case "mysql":
+		return buildMySQLFTS(req.Table, selected), nil
+	case "sqlite":
+		return buildSQLiteFTS(req.Table, columns, selected), nil
+	default:
+		return buildPostgresFTS(req, selected), nil
+	}
+}
+
+// isTextType reports whether a column holds text that can be indexed for search
+func isTextType(dataType string) bool {
+	dataType = strings.ToLower(dataType)
+	return strings.Contains(dataType, "char") || strings.Contains(dataType, "text") || dataType == "citext"
+}
+
+// ftsObjectName derives the name of an index, trigger or table from the table name
+func ftsObjectName(table, suffix string) string {
+	return unqualifiedName(table) + "_" + suffix
+}
+
+func buildPostgresFTS(req FTSRequest, columns []models.ColumnInfo) *FTSPlan {
+	const dialect = "postgresql"
+	language := req.Language
+	if language == "" {
+		language = "english"
+	}
+	table := quoteIdentifier(dialect, req.Table)
+	vector := quoteIdentifier(dialect, FTSVectorColumn)
+
+	// vectorExpression weights the columns A to D in the order given
+	vectorExpression := func(prefix string) string {
+		parts := make([]string, len(columns))
+		for i, col := range columns {
+			weight := string(rune('A' + min(i, 3)))
+			parts[i] = fmt.Sprintf("setweight(to_tsvector('%s', coalesce(%s%s, '')), '%s')",
+				language, prefix, quoteIdentifier(dialect, col.ColumnName), weight)
+		}
+		return strings.Join(parts, " || ")
+	}
+
+	plan := &FTSPlan{Dialect: dialect}
+	if req.Trigger {
+		function := quoteIdentifier(dialect, ftsObjectName(req.Table, "search_vector_update"))
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = quoteIdentifier(dialect, col.ColumnName)
+		}
+		plan.Statements = []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s tsvector", table, vector),
+			fmt.Sprintf("CREATE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$\nBEGIN\n  NEW.%s := %s;\n  RETURN NEW;\nEND\n$$", function, vector, vectorExpression("NEW.")),
+			fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE OF %s ON %s FOR EACH ROW EXECUTE PROCEDURE %s()",
+				quoteIdentifier(dialect, ftsObjectName(req.Table, "search_vector_trigger")), strings.Join(names, ", "), table, function),
+			fmt.Sprintf("UPDATE %s SET %s = %s", table, vector, vectorExpression("")),
+		}
+		plan.Notes = append(plan.Notes, "The backfill UPDATE rewrites every row; run it in batches on large tables.")
+	} else {
+		plan.Statements = []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s tsvector GENERATED ALWAYS AS (%s) STORED", table, vector, vectorExpression("")),
+		}
+		plan.Notes = append(plan.Notes, "Adding a generated column rewrites the table under an ACCESS EXCLUSIVE lock (PostgreSQL 12+); on older servers or busy tables use the trigger variant.")
+	}
+	plan.Statements = append(plan.Statements, fmt.Sprintf("CREATE INDEX %s ON %s USING GIN (%s)",
+		quoteIdentifier(dialect, ftsObjectName(req.Table, "search_idx")), table, vector))
+	plan.Notes = append(plan.Notes, "Use CREATE INDEX CONCURRENTLY outside a transaction to avoid blocking writes while the index builds.")
+
+	plan.SampleQuery = fmt.Sprintf("SELECT *, ts_rank(%[2]s, query) AS rank\nFROM %[1]s, websearch_to_tsquery('%[3]s', 'search terms') AS query\nWHERE %[2]s @@ query\nORDER BY rank DESC\nLIMIT 20",
+		table, vector, language)
+	return plan
+}
+
+func buildMySQLFTS(tableName string, columns []models.ColumnInfo) *FTSPlan {
+	const dialect = "mysql"
+	table := quoteIdentifier(dialect, tableName)
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = quoteIdentifier(dialect, col.ColumnName)
+	}
+	match := fmt.Sprintf("MATCH(%s) AGAINST ('search terms' IN NATURAL LANGUAGE MODE)", strings.Join(names, ", "))
+
+	return &FTSPlan{
+		Dialect: dialect,
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (%s)", table, quoteIdentifier(dialect, ftsObjectName(tableName, "search_idx")), strings.Join(names, ", ")),
+		},
+		SampleQuery: fmt.Sprintf("SELECT *, %[2]s AS score\nFROM %[1]s\nWHERE %[2]s\nORDER BY score DESC\nLIMIT 20", table, match),
+		Notes: []string{
+			"FULLTEXT indexes need InnoDB or MyISAM tables.",
+			"MATCH() must list exactly the indexed columns. Words shorter than innodb_ft_min_token_size (default 3) and stopwords are not indexed.",
+		},
+	}
+}
+
+func buildSQLiteFTS(tableName string, allColumns, columns []models.ColumnInfo) *FTSPlan {
+	const dialect = "sqlite"
+	table := quoteIdentifier(dialect, tableName)
+	ftsName := ftsObjectName(tableName, "fts")
+	fts := quoteIdentifier(dialect, ftsName)
+
+	// An INTEGER PRIMARY KEY is an alias of the rowid; otherwise use the rowid itself
+	rowid := "rowid"
+	for _, col := range allColumns {
+		if col.IsPrimaryKey && strings.EqualFold(col.DataType, "integer") {
+			rowid = col.ColumnName
+		}
+	}
+	quotedRowid := quoteIdentifier(dialect, rowid)
+
+	names := make([]string, len(columns))
+	newValues := make([]string, len(columns))
+	oldValues := make([]string, len(columns))
+	for i, col := range columns {
+		name := quoteIdentifier(dialect, col.ColumnName)
+		names[i] = name
+		newValues[i] = "new." + name
+		oldValues[i] = "old." + name
+	}
+	columnList := strings.Join(names, ", ")
+	insertNew := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.%s, %s);", fts, columnList, quotedRowid, strings.Join(newValues, ", "))
+	deleteOld := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s);", fts, fts, columnList, quotedRowid, strings.Join(oldValues, ", "))
+
+	return &FTSPlan{
+		Dialect: dialect,
+		Statements: []string{
+			fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s', content_rowid='%s')", fts, columnList, unqualifiedName(tableName), rowid),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s BEGIN\n  %s\nEND", quoteIdentifier(dialect, ftsName+"_ai"), table, insertNew),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s BEGIN\n  %s\nEND", quoteIdentifier(dialect, ftsName+"_ad"), table, deleteOld),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s BEGIN\n  %s\n  %s\nEND", quoteIdentifier(dialect, ftsName+"_au"), table, deleteOld, insertNew),
+			fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+		},
+		SampleQuery: fmt.Sprintf("SELECT %[1]s.*\nFROM %[2]s\nJOIN %[1]s ON %[1]s.%[3]s = %[2]s.rowid\nWHERE %[2]s MATCH 'search terms'\nORDER BY rank\nLIMIT 20",
+			table, fts, quotedRowid),
+		Notes: []string{
+			"The FTS5 table stores only the index (external content); the triggers keep it in sync with the table.",
+			"FTS5 must be compiled into SQLite; check with list_extensions.",
+		},
+	}
+}
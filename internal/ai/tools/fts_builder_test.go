@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var articleColumns = []models.ColumnInfo{
+	{ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+	{ColumnName: "title", DataType: "character varying"},
+	{ColumnName: "body", DataType: "text"},
+	{ColumnName: "views", DataType: "integer"},
+}
+
+func TestBuildFTSPlan_PostgreSQL(t *testing.T) {
+	plan, problems := BuildFTSPlan("postgresql", articleColumns, FTSRequest{Table: "articles", Columns: []string{"title", "body"}})
+	require.Empty(t, problems)
+	require.Len(t, plan.Statements, 2)
+	assert.Equal(t, `ALTER TABLE "articles" ADD COLUMN "search_vector" tsvector GENERATED ALWAYS AS (`+
+		`setweight(to_tsvector('english', coalesce("title", '')), 'A') || setweight(to_tsvector('english', coalesce("body", '')), 'B')) STORED`,
+		plan.Statements[0])
+	assert.Equal(t, `CREATE INDEX "articles_search_idx" ON "articles" USING GIN ("search_vector")`, plan.Statements[1])
+	assert.Contains(t, plan.SampleQuery, "websearch_to_tsquery('english'")
+
+	plan, problems = BuildFTSPlan("postgresql", articleColumns, FTSRequest{Table: "articles", Columns: []string{"body"}, Language: "simple", Trigger: true})
+	require.Empty(t, problems)
+	require.Len(t, plan.Statements, 5)
+	assert.Contains(t, plan.Statements[1], `NEW."search_vector" := setweight(to_tsvector('simple', coalesce(NEW."body", '')), 'A')`)
+	assert.Contains(t, plan.Statements[2], `BEFORE INSERT OR UPDATE OF "body" ON "articles"`)
+}
+
+func TestBuildFTSPlan_MySQLAndSQLite(t *testing.T) {
+	plan, problems := BuildFTSPlan("mysql", articleColumns, FTSRequest{Table: "articles", Columns: []string{"title", "body"}})
+	require.Empty(t, problems)
+	assert.Equal(t, "ALTER TABLE `articles` ADD FULLTEXT INDEX `articles_search_idx` (`title`, `body`)", plan.Statements[0])
+	assert.Contains(t, plan.SampleQuery, "MATCH(`title`, `body`) AGAINST")
+
+	plan, problems = BuildFTSPlan("sqlite", articleColumns, FTSRequest{Table: "articles", Columns: []string{"title"}})
+	require.Empty(t, problems)
+	assert.Equal(t, `CREATE VIRTUAL TABLE "articles_fts" USING fts5("title", content='articles', content_rowid='id')`, plan.Statements[0])
+	assert.Contains(t, plan.Statements[2], `VALUES ('delete', old."id", old."title")`)
+	assert.Contains(t, plan.SampleQuery, `WHERE "articles_fts" MATCH 'search terms'`)
+}
+
+func TestBuildFTSPlan_Problems(t *testing.T) {
+	_, problems := BuildFTSPlan("postgresql", articleColumns, FTSRequest{Table: "articles", Columns: []string{"views", "summary"}})
+	assert.Equal(t, []string{"column views is integer, not a text column", "unknown column summary"}, problems)
+
+	withVector := append(articleColumns, models.ColumnInfo{ColumnName: "search_vector", DataType: "tsvector"})
+	_, problems = BuildFTSPlan("postgresql", withVector, FTSRequest{Table: "articles", Columns: []string{"title"}})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "already has a search_vector column")
+}
+
+func TestExecutor_SetupFTS_DoesNotExecute(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("GetTableSchema", "articles").Return(articleColumns, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "setup_fts",
+		Arguments: `{"tableName": "articles", "columns": ["title", "body"]}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "USING GIN")
+	assert.Contains(t, output, "Nothing was executed")
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"dbsage/internal/history"
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// EnableHistory records the statements run by execute_sql in the query history
+func (e *Executor) EnableHistory() {
+	e.recordHistory = true
+}
+
+// addToHistory records an executed statement. The history is best effort: a
+// failure to write it never fails the statement.
+func (e *Executor) addToHistory(dbTools dbinterfaces.DatabaseInterface, sql string, result *models.QueryResult, execErr error) {
+	if !e.recordHistory {
+		return
+	}
+	entry := history.Entry{Time: time.Now(), SQL: sql, Connection: dbinterfaces.ConnectionName(dbTools)}
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	} else if result != nil {
+		entry.DurationMs = history.ParseDurationMs(result.Duration)
+		entry.Rows = result.RowCount
+	}
+	_ = history.Append(entry)
+}
+
+// RecordQuestion adds a question asked to the AI to the query history
+func (e *Executor) RecordQuestion(question string) {
+	if e.recordHistory {
+		_ = history.Append(history.Entry{Time: time.Now(), Question: question})
+	}
+}
+
+// Statements returned by get_query_history when no limit is given, and the most
+const (
+	defaultHistoryStatements = 20
+	maxHistoryStatements     = 100
+)
+
+// getQueryHistory returns past statements from the query history, newest
+// first, optionally filtered by search words and connection
+func (e *Executor) getQueryHistory(args map[string]interface{}) (string, error) {
+	search, _ := args["search"].(string)
+	connection, _ := args["connection"].(string)
+	limit := defaultHistoryStatements
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), maxHistoryStatements)
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to read query history: %w", err)
+	}
+	statements := history.Statements(entries, strings.TrimSpace(search), strings.TrimSpace(connection), limit)
+
+	response := map[string]interface{}{
+		"statements": statements,
+		"count":      len(statements),
+	}
+	if len(statements) == 0 {
+		response["statements"] = []history.Entry{}
+		response["note"] = "No matching statements in the query history"
+	}
+	resultJSON, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal query history: %w", err)
+	}
+	return string(resultJSON), nil
+}
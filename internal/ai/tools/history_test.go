@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/history"
+	"dbsage/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_QueryHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openSQLite(t, "history.db")
+	seedOrders(t, db, 3)
+	e := NewExecutor(database.NewLimitedDatabase(db, "shop", 2))
+	e.EnableHistory()
+
+	_, err := e.Execute(toolCall("execute_sql", `{"sql": "SELECT * FROM orders"}`))
+	require.NoError(t, err)
+	_, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT count(*) FROM orders"}`))
+	require.NoError(t, err)
+
+	result, err := e.Execute(toolCall("get_query_history", `{"search": "orders", "limit": 1}`))
+	require.NoError(t, err)
+	var response struct {
+		Statements []history.Entry `json:"statements"`
+		Count      int             `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "SELECT count(*) FROM orders", response.Statements[0].SQL)
+	assert.Equal(t, "shop", response.Statements[0].Connection)
+	assert.Equal(t, 1, response.Statements[0].Rows)
+
+	result, err = e.Execute(toolCall("get_query_history", `{"connection": "other"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, `"count":0`)
+}
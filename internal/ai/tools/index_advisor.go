@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+const (
+	defaultAdvisedStatements = 50 // Heaviest statements analyzed by default
+	maxAdvisedStatements     = 200
+	maxEvaluatedIndexes      = 5 // Suggestions planned as hypothetical indexes
+)
+
+// adviseIndexes proposes indexes for the predicates of the heaviest statements
+// recorded by pg_stat_statements or the performance_schema digests, and on
+// PostgreSQL with HypoPG checks whether the planner would use each of them
+func (e *Executor) adviseIndexes(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	limit := defaultAdvisedStatements
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), maxAdvisedStatements)
+	}
+	query, err := sqlanalysis.BuildDigestQuery(dialect, limit)
+	if err != nil {
+		return "", err
+	}
+
+	var privilege *sqlanalysis.Privilege
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		if capability, ok := sqlanalysis.MissingCapability(dialect, err); ok {
+			return "", fmt.Errorf("%s is not installed, so there is no workload to analyze; to enable %s: %s", capability.Name, capability.Purpose, capability.Install)
+		}
+		if result := e.missingPrivilegeResult(dbTools, err); result != "" {
+			return result, nil
+		}
+		return "", fmt.Errorf("failed to read the workload: %w", err)
+	}
+	hidden := sqlanalysis.HiddenRows(result, 0)
+	if hidden > 0 {
+		if p, ok := sqlanalysis.StatsPrivilege(dialect); ok {
+			p = grantFor(dbTools, p)
+			e.markUnavailable(dbTools, p)
+			privilege = &p
+		}
+	}
+
+	snapshot, err := e.schemaSnapshot(dbTools, dbinterfaces.ConnectionName(dbTools))
+	if err != nil {
+		return "", err
+	}
+	query, err = sqlanalysis.BuildIndexColumnsQuery(dialect)
+	if err != nil {
+		return "", err
+	}
+	indexes, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the existing indexes: %w", err)
+	}
+
+	advice := sqlanalysis.AdviseIndexes(dialect, sqlanalysis.WorkloadFromResult(result), snapshot.tables, sqlanalysis.IndexesFromResult(indexes))
+	advice.MissingPrivilege, advice.HiddenQueries = privilege, hidden
+	if dialect == "postgresql" && len(advice.Candidates) > 0 {
+		evaluateIndexes(dbTools, &advice)
+	}
+
+	resultJSON, err := json.Marshal(advice)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal index advice: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// evaluateIndexes plans the heaviest statement of the top suggestions without
+// and with the suggested index created by HypoPG, which only exists for the
+// planner of one session and is never built
+func evaluateIndexes(dbTools dbinterfaces.DatabaseInterface, advice *sqlanalysis.IndexAdvice) {
+	installed, err := dbTools.ExecuteSQL(sqlanalysis.HypoPGInstalledQuery)
+	if err != nil || len(installed.Rows) == 0 {
+		note := "hypopg is not installed, so the planner did not evaluate the suggestions"
+		if capability, ok := sqlanalysis.LookupCapability("hypopg"); ok {
+			note += "; to evaluate them: " + capability.Install
+		}
+		advice.Notes = append(advice.Notes, note)
+		return
+	}
+
+	// Without the version, a server that can plan normalized statements is assumed
+	version := 0
+	if result, err := dbTools.ExecuteSQL(sqlanalysis.ServerVersionQuery); err == nil {
+		version = sqlanalysis.MajorVersionFromResult(result)
+	}
+	err = dbinterfaces.RunInSession(dbTools, func(query dbinterfaces.SessionQuery) error {
+		for i := range advice.Candidates[:min(len(advice.Candidates), maxEvaluatedIndexes)] {
+			advice.Candidates[i].Evaluation = evaluateIndex(query, advice.Candidates[i], version)
+		}
+		return nil
+	})
+	if err != nil {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("The suggestions could not be evaluated: %v", err))
+	}
+}
+
+// evaluateIndex compares the plans of a candidate's heaviest statement
+// without and with it as a hypothetical index
+func evaluateIndex(query dbinterfaces.SessionQuery, candidate sqlanalysis.IndexCandidate, version int) *sqlanalysis.IndexEvaluation {
+	statement := candidate.Examples[0]
+	failed := func(format string, args ...interface{}) *sqlanalysis.IndexEvaluation {
+		return &sqlanalysis.IndexEvaluation{Statement: statement, Error: fmt.Sprintf(format, args...)}
+	}
+	explain, err := sqlanalysis.BuildHypotheticalExplain(statement, version)
+	if err != nil {
+		return failed("%v", err)
+	}
+	plan := func() (*sqlanalysis.PlanNode, error) {
+		result, err := query(explain)
+		if err != nil {
+			return nil, err
+		}
+		return sqlanalysis.ParsePlan(result)
+	}
+
+	before, err := plan()
+	if err != nil {
+		return failed("failed to plan the statement: %v", err)
+	}
+	created, err := query("SELECT indexname FROM hypopg_create_index($1)", candidate.HypotheticalDDL())
+	if err != nil {
+		return failed("failed to create the hypothetical index: %v", err)
+	}
+	defer func() { _, _ = query("SELECT hypopg_reset()") }()
+	after, err := plan()
+	if err != nil {
+		return failed("failed to plan the statement with the hypothetical index: %v", err)
+	}
+	return sqlanalysis.NewIndexEvaluation(statement, before, after, sqlanalysis.HypotheticalIndexFromResult(created))
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionPostgres is a PostgreSQL mock whose sessions run on the mock
+type sessionPostgres struct {
+	mockPostgres
+}
+
+func (m sessionPostgres) RunInSession(fn func(query dbinterfaces.SessionQuery) error) error {
+	return fn(m.ExecuteSQLWithArgs)
+}
+
+func mockIndexAdvisorWorkload(mockDB *MockDatabaseInterface) {
+	digestQuery, _ := sqlanalysis.BuildDigestQuery("postgresql", 50)
+	schemaQuery, _ := sqlanalysis.BuildSchemaColumnsQuery("postgresql")
+	relationsQuery, _ := sqlanalysis.BuildRelationsQuery("postgresql")
+	indexesQuery, _ := sqlanalysis.BuildIndexColumnsQuery("postgresql")
+	mockDB.On("ExecuteSQL", digestQuery).Return(&models.QueryResult{Rows: [][]interface{}{
+		{"SELECT * FROM orders WHERE status = $1", int64(500), 12.0},
+		{"SELECT * FROM orders WHERE id = $1", int64(9000), 0.1},
+	}}, nil)
+	mockDB.On("ExecuteSQL", schemaQuery).Return(&models.QueryResult{Rows: [][]interface{}{
+		{"orders", "id", "integer"},
+		{"orders", "status", "text"},
+	}}, nil)
+	mockDB.On("ExecuteSQL", relationsQuery).Return(&models.QueryResult{}, nil)
+	mockDB.On("ExecuteSQL", indexesQuery).Return(&models.QueryResult{Rows: [][]interface{}{
+		{"orders", "orders_pkey", "id"},
+	}}, nil)
+}
+
+func TestExecutor_AdviseIndexes_EvaluatesWithHypoPG(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(sessionPostgres{mockPostgres{mockDB}})
+	mockIndexAdvisorWorkload(mockDB)
+
+	explain := "EXPLAIN (FORMAT JSON, GENERIC_PLAN) SELECT * FROM orders WHERE status = $1"
+	mockDB.On("ExecuteSQL", sqlanalysis.HypoPGInstalledQuery).Return(&models.QueryResult{Rows: [][]interface{}{{"1.4.0"}}}, nil)
+	mockDB.On("ExecuteSQL", sqlanalysis.ServerVersionQuery).Return(&models.QueryResult{Rows: [][]interface{}{{"160002"}}}, nil)
+	mockDB.On("ExecuteSQLWithArgs", explain, []interface{}(nil)).
+		Return(explainResult(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 1800}}]`), nil).Once()
+	mockDB.On("ExecuteSQLWithArgs", "SELECT indexname FROM hypopg_create_index($1)", []interface{}{"CREATE INDEX orders_status_idx ON orders (status)"}).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"<13542>btree_orders_status"}}}, nil)
+	mockDB.On("ExecuteSQLWithArgs", explain, []interface{}(nil)).
+		Return(explainResult(`[{"Plan": {"Node Type": "Index Scan", "Index Name": "<13542>btree_orders_status", "Total Cost": 40}}]`), nil).Once()
+	mockDB.On("ExecuteSQLWithArgs", "SELECT hypopg_reset()", []interface{}(nil)).Return(&models.QueryResult{}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_indexes", Arguments: `{}`}})
+	require.NoError(t, err)
+
+	var advice sqlanalysis.IndexAdvice
+	require.NoError(t, json.Unmarshal([]byte(output), &advice))
+	assert.Equal(t, []string{"orders(id) by orders_pkey"}, advice.AlreadyIndexed)
+	require.Len(t, advice.Candidates, 1)
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY orders_status_idx ON orders (status)", advice.Candidates[0].DDL)
+	require.NotNil(t, advice.Candidates[0].Evaluation)
+	assert.True(t, advice.Candidates[0].Evaluation.Used)
+	assert.InDelta(t, 40, advice.Candidates[0].Evaluation.CostAfter, 0.001)
+	mockDB.AssertCalled(t, "ExecuteSQLWithArgs", "SELECT hypopg_reset()", []interface{}(nil))
+}
+
+func TestExecutor_AdviseIndexes_WithoutHypoPG(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockIndexAdvisorWorkload(mockDB)
+	mockDB.On("ExecuteSQL", sqlanalysis.HypoPGInstalledQuery).Return(&models.QueryResult{}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_indexes", Arguments: `{}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"columns":["status"]`)
+	assert.NotContains(t, output, `"evaluation"`)
+	assert.Contains(t, output, "CREATE EXTENSION hypopg")
+}
+
+func TestExecutor_AdviseIndexes_WithoutPgStatStatements(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	digestQuery, _ := sqlanalysis.BuildDigestQuery("postgresql", 50)
+	mockDB.On("ExecuteSQL", digestQuery).
+		Return((*models.QueryResult)(nil), errors.New(`pq: relation "pg_stat_statements" does not exist`))
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "advise_indexes", Arguments: `{}`}})
+	assert.ErrorContains(t, err, "shared_preload_libraries")
+}
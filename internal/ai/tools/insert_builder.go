@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// InsertStatement is a parameterized INSERT built from validated field values
+type InsertStatement struct {
+	Table    string            `json:"table"`
+	SQL      string            `json:"sql"`
+	Params   []interface{}     `json:"params"`
+	Preview  string            `json:"preview"`            // SQL with the parameters inlined, for display only
+	Defaults map[string]string `json:"defaults,omitempty"` // Omitted columns filled by the database, with their default
+}
+
+// BuildInsert validates field values against the table's columns and builds a
+// parameterized INSERT. Every problem found is returned, so they can all be
+// fixed at once; the statement is nil when there are problems.
+func BuildInsert(dialect, table string, columns []models.ColumnInfo, values map[string]interface{}) (*InsertStatement, []string) {
+	if len(columns) == 0 {
+		return nil, []string{fmt.Sprintf("table %s not found or has no columns", table)}
+	}
+	if len(values) == 0 {
+		return nil, []string{"no values given"}
+	}
+
+	byName := columnsByName(columns)
+	var problems []string
+	var names []string
+	var params []interface{}
+	given := make(map[string]bool, len(values))
+
+	for _, key := range sortedKeys(values) {
+		col, ok := byName[strings.ToLower(key)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown column %s", key))
+			continue
+		}
+		given[strings.ToLower(col.ColumnName)] = true
+
+		value, err := coerceValue(col, values[key])
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		names = append(names, col.ColumnName)
+		params = append(params, value)
+	}
+
+	defaults := make(map[string]string)
+	for _, col := range columns {
+		if given[strings.ToLower(col.ColumnName)] {
+			continue
+		}
+		switch {
+		case col.DefaultValue != nil:
+			defaults[col.ColumnName] = *col.DefaultValue
+		case isAutoGenerated(col):
+			defaults[col.ColumnName] = "generated"
+		case strings.EqualFold(col.IsNullable, "NO"):
+			problems = append(problems, fmt.Sprintf("column %s is NOT NULL and has no default, a value is required", col.ColumnName))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	quoted := make([]string, len(names))
+	markers := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(dialect, name)
+		markers[i] = placeholder(dialect, i+1)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(dialect, table), strings.Join(quoted, ", "), strings.Join(markers, ", "))
+
+	// Return the inserted row, including defaults, where the dialect supports it
+	if dialect == "postgresql" || dialect == "sqlite" {
+		sql += " RETURNING *"
+	}
+
+	stmt := &InsertStatement{
+		Table:   table,
+		SQL:     sql,
+		Params:  params,
+		Preview: inlineParameters(dialect, sql, params),
+	}
+	if len(defaults) > 0 {
+		stmt.Defaults = defaults
+	}
+	return stmt, nil
+}
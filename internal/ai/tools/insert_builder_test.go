@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string { return &s }
+func intPtr(n int) *int       { return &n }
+
+var ordersColumns = []models.ColumnInfo{
+	{ColumnName: "id", DataType: "integer", IsNullable: "NO", DefaultValue: strPtr("nextval('orders_id_seq'::regclass)"), IsPrimaryKey: true},
+	{ColumnName: "customer", DataType: "character varying", IsNullable: "NO", CharMaxLength: intPtr(10)},
+	{ColumnName: "status", DataType: "USER-DEFINED", IsNullable: "NO", DefaultValue: strPtr("'new'::order_status"), EnumValues: []string{"new", "paid", "shipped"}},
+	{ColumnName: "total", DataType: "numeric", IsNullable: "NO"},
+	{ColumnName: "paid", DataType: "boolean", IsNullable: "YES"},
+	{ColumnName: "placed_on", DataType: "date", IsNullable: "YES"},
+	{ColumnName: "meta", DataType: "jsonb", IsNullable: "YES"},
+}
+
+func TestBuildInsert(t *testing.T) {
+	stmt, problems := BuildInsert("postgresql", "orders", ordersColumns, map[string]interface{}{
+		"customer":  "o'brien",
+		"total":     "19.90",
+		"paid":      true,
+		"placed_on": "2024-05-01",
+		"meta":      map[string]interface{}{"gift": true},
+	})
+	require.Empty(t, problems)
+	require.NotNil(t, stmt)
+
+	assert.Equal(t, `INSERT INTO "orders" ("customer", "meta", "paid", "placed_on", "total") VALUES ($1, $2, $3, $4, $5) RETURNING *`, stmt.SQL)
+	assert.Equal(t, []interface{}{"o'brien", `{"gift":true}`, true, "2024-05-01", "19.90"}, stmt.Params)
+	assert.Contains(t, stmt.Preview, `VALUES ('o''brien', '{"gift":true}', TRUE, '2024-05-01', '19.90')`)
+	assert.Equal(t, "'new'::order_status", stmt.Defaults["status"])
+	assert.Contains(t, stmt.Defaults, "id")
+}
+
+func TestBuildInsert_ReportsAllProblems(t *testing.T) {
+	stmt, problems := BuildInsert("mysql", "orders", ordersColumns, map[string]interface{}{
+		"customer":  "a much too long name",
+		"status":    "lost",
+		"paid":      "maybe",
+		"placed_on": "yesterday",
+		"colour":    "red",
+	})
+	assert.Nil(t, stmt)
+	assert.Len(t, problems, 6)
+	assert.Contains(t, problems, "unknown column colour")
+	assert.Contains(t, problems, "column customer allows at most 10 characters, got 20")
+	assert.Contains(t, problems, "column status must be one of new, paid, shipped, got \"lost\"")
+	assert.Contains(t, problems, "column total is NOT NULL and has no default, a value is required")
+}
+
+func TestBuildInsert_MySQLPlaceholders(t *testing.T) {
+	stmt, problems := BuildInsert("mysql", "orders", ordersColumns, map[string]interface{}{
+		"customer": "bob",
+		"total":    12,
+	})
+	require.Empty(t, problems)
+	assert.Equal(t, "INSERT INTO `orders` (`customer`, `total`) VALUES (?, ?)", stmt.SQL)
+	assert.Equal(t, "INSERT INTO `orders` (`customer`, `total`) VALUES ('bob', 12)", stmt.Preview)
+}
+
+func TestExecutor_InsertRow(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+	mockDB.On("ExecuteSQLWithArgs",
+		`INSERT INTO "orders" ("customer", "total") VALUES ($1, $2) RETURNING *`,
+		[]interface{}{"bob", "5"},
+	).Return(&models.QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}, RowCount: 1}, nil)
+
+	call := openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "insert_row",
+		Arguments: `{"tableName": "orders", "values": {"customer": "bob", "total": "5"}}`,
+	}}
+
+	preview, err := executor.PreviewStatement(call)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" ("customer", "total") VALUES ('bob', '5') RETURNING *`, preview)
+
+	output, err := executor.Execute(call)
+	require.NoError(t, err)
+	assert.Contains(t, output, `"inserted"`)
+	mockDB.AssertExpectations(t)
+}
+
+func TestExecutor_InsertRow_InvalidValuesNotExecuted(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+
+	call := openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "insert_row",
+		Arguments: `{"tableName": "orders", "values": {"customer": "bob", "total": "lots"}}`,
+	}}
+
+	_, err := executor.PreviewStatement(call)
+	assert.Error(t, err)
+
+	output, err := executor.Execute(call)
+	require.NoError(t, err)
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Equal(t, "validation failed, nothing was inserted", result["error"])
+	mockDB.AssertNotCalled(t, "ExecuteSQLWithArgs", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dbsage/internal/masking"
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// maskResult returns the result with its sensitive values redacted by the
+// masking settings of the connection it came from, for handing to the AI.
+// The result itself keeps its values for the user and exports.
+func (e *Executor) maskResult(connection string, result *models.QueryResult) *models.QueryResult {
+	masked, findings := e.maskingSettings(connection).Mask(result)
+	if len(findings) > 0 {
+		masked.Masked = masking.Describe(findings)
+		e.noteMasked(findings)
+	}
+	return masked
+}
+
+// maskingSettings returns the masking settings of a connection, masking with
+// every rule when they cannot be read
+func (e *Executor) maskingSettings(connection string) masking.Settings {
+	settings, err := masking.Load(connection)
+	if err != nil {
+		e.notices = append(e.notices, fmt.Sprintf("Masking with every rule, the masking settings could not be read: %v", err))
+	}
+	return settings
+}
+
+// noteMasked tells the user which values the AI did not see
+func (e *Executor) noteMasked(findings []masking.Finding) {
+	e.notices = append(e.notices, fmt.Sprintf("Masked before sending to the AI: %s. /masking lists and overrides the rules.", masking.Describe(findings)))
+}
+
+// maskingViolation checks a statement for ways around the masking of results
+// and returns the tool result reporting them, or "" when the statement may run
+func (e *Executor) maskingViolation(dbTools dbinterfaces.DatabaseInterface, sql string) (string, error) {
+	settings := e.maskingSettings(dbinterfaces.ConnectionName(dbTools))
+	if settings.Disabled {
+		return "", nil
+	}
+
+	schema := sqlanalysis.MaskedSchema{
+		Column: func(name string) bool { return settings.ColumnRule(name) != "" },
+		// Looked up only for a table whose whole row the statement uses
+		Table: func(table string) bool {
+			columns, err := dbTools.GetTableSchema(table)
+			if err != nil {
+				return true
+			}
+			for _, col := range columns {
+				if settings.ColumnRule(col.ColumnName) != "" {
+					return true
+				}
+			}
+			return false
+		},
+	}
+
+	problems := sqlanalysis.CheckMaskedAccess(sql, schema)
+	if len(problems) == 0 {
+		return "", nil
+	}
+	e.notices = append(e.notices, "Blocked a statement that would reveal masked values: "+strings.Join(problems, "; "))
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"error":       "the statement would reveal values that masking hides from you, nothing was executed",
+		"problems":    problems,
+		"instruction": "Select masked columns as they are, or leave them out. The user can unmask a column with /masking column <column> unmask.",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal masking problems: %w", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/masking"
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_ExecuteSQL_MasksSensitiveValues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(namedMock{mockDB, "crm"})
+
+	mockDB.On("ExecuteSQL", "SELECT name, email FROM customers").Return(&models.QueryResult{
+		Columns: []string{"name", "email"},
+		Rows:    [][]interface{}{{"Ann", "ann@example.com"}, {"Bob", "bob@example.com"}},
+	}, nil)
+	call := openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT name, email FROM customers"}`}}
+
+	output, err := executor.Execute(call)
+	require.NoError(t, err)
+	var result models.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Equal(t, [][]interface{}{{"Ann", "[masked email]"}, {"Bob", "[masked email]"}}, result.Rows)
+	assert.Equal(t, "email as email (2 values)", result.Masked)
+	assert.Contains(t, executor.TakeNotices()[0], "Masked before sending to the AI")
+	assert.Equal(t, "ann@example.com", executor.LastResult().Rows[0][1], "the user's copy keeps its values")
+
+	_, err = executor.SaveResult("contacts")
+	require.NoError(t, err)
+	prompt := executor.ExpandPrompt("who is in $contacts")
+	assert.Contains(t, prompt, `[["Ann","[masked email]"],["Bob","[masked email]"]]`)
+	assert.NotContains(t, prompt, "ann@example.com")
+
+	// Overrides of the connection apply to the next result
+	var settings masking.Settings
+	require.NoError(t, settings.SetColumn("email", masking.ModeUnmask))
+	require.NoError(t, masking.Save("crm", settings))
+	output, err = executor.Execute(call)
+	require.NoError(t, err)
+	assert.Contains(t, output, "ann@example.com")
+}
+
+func TestExecutor_ExecuteSQL_BlocksWaysAroundMasking(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mockDB := &MockDatabaseInterface{}
+	mockDB.On("GetTableSchema", "customers").Return([]models.ColumnInfo{{ColumnName: "id"}, {ColumnName: "email"}}, nil)
+	executor := NewExecutor(namedMock{mockDB, "crm"})
+
+	for _, sql := range []string{
+		"SELECT upper(email) FROM customers",
+		"SELECT row_to_json(c) FROM customers c",
+		"SELECT pg_read_file('/etc/passwd')",
+	} {
+		args, err := json.Marshal(map[string]string{"sql": sql})
+		require.NoError(t, err)
+		output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: string(args)}})
+		require.NoError(t, err)
+		assert.Contains(t, output, "the statement would reveal values that masking hides from you", sql)
+	}
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+	assert.Contains(t, executor.TakeNotices()[0], "Blocked a statement that would reveal masked values")
+
+	// Without masking nothing is blocked
+	require.NoError(t, masking.Save("crm", masking.Settings{Disabled: true}))
+	mockDB.On("ExecuteSQL", "SELECT upper(email) FROM customers").Return(&models.QueryResult{Columns: []string{"upper"}}, nil)
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT upper(email) FROM customers"}`}})
+	require.NoError(t, err)
+	mockDB.AssertCalled(t, "ExecuteSQL", "SELECT upper(email) FROM customers")
+}
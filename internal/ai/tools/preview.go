@@ -0,0 +1,420 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Ways a table preview picks its rows
+const (
+	PreviewFirst  = "first"
+	PreviewRandom = "random"
+	PreviewNewest = "newest"
+)
+
+const (
+	// DefaultPreviewRows is the size of a preview when none is given
+	DefaultPreviewRows = 10
+	// MaxPreviewRows is the largest preview
+	MaxPreviewRows = 100
+	// PreviewValueLength is the longest text value of a preview, in characters
+	PreviewValueLength = 200
+	// jsonScalarLength is the longest string shown inside a JSON summary
+	jsonScalarLength = 40
+)
+
+// newestColumns are the columns preferred for ordering by age, in order
+var newestColumns = []string{"created_at", "inserted_at", "updated_at", "modified_at", "timestamp", "ts", "time", "date"}
+
+// PreviewOptions describe which rows of a table to preview
+type PreviewOptions struct {
+	Table       string
+	Rows        int    // Number of rows, DefaultPreviewRows when zero
+	Mode        string // PreviewFirst, PreviewRandom or PreviewNewest
+	OrderColumn string // Column ordering a newest preview, found from the column types when empty
+}
+
+// Preview is a sample of the rows of a table
+type Preview struct {
+	SQL     string
+	Result  *models.QueryResult // Values as stored; Sampling says how the rows were picked
+	Columns []models.ColumnInfo
+}
+
+// ParsePreviewMode reads a preview mode, accepting a few common synonyms
+func ParsePreviewMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", PreviewFirst, "head", "top":
+		return PreviewFirst, nil
+	case PreviewRandom, "sample", "rand":
+		return PreviewRandom, nil
+	case PreviewNewest, "latest", "recent", "last":
+		return PreviewNewest, nil
+	}
+	return "", fmt.Errorf("unknown preview mode %q: use first, random or newest", mode)
+}
+
+// PreviewTable reads a sample of a table's rows, limited to the tenant when
+// the table is scoped
+func PreviewTable(dbTools dbinterfaces.DatabaseInterface, options PreviewOptions, tenant string) (*Preview, error) {
+	columns, err := dbTools.GetTableSchema(unqualifiedName(options.Table))
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", options.Table)
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	scope, err := tenantScopeCondition(dialect, tenant, options.Table, columns)
+	if err != nil {
+		return nil, err
+	}
+	query, description, err := BuildPreviewQuery(dialect, columns, options, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", options.Table, err)
+	}
+	result.Sampling = description
+	return &Preview{SQL: query, Result: result, Columns: columns}, nil
+}
+
+// BuildPreviewQuery builds the query reading a preview of a table and
+// describes how it picks the rows. scope is an extra condition, such as the
+// tenant's, or empty.
+func BuildPreviewQuery(dialect string, columns []models.ColumnInfo, options PreviewOptions, scope string) (string, string, error) {
+	rows := options.Rows
+	if rows <= 0 {
+		rows = DefaultPreviewRows
+	}
+	if rows > MaxPreviewRows {
+		rows = MaxPreviewRows
+	}
+	mode, err := ParsePreviewMode(options.Mode)
+	if err != nil {
+		return "", "", err
+	}
+
+	var order, description string
+	switch mode {
+	case PreviewFirst:
+		description = fmt.Sprintf("First %d rows", rows)
+		var keys []string
+		for _, col := range columns {
+			if col.IsPrimaryKey {
+				keys = append(keys, col.ColumnName)
+			}
+		}
+		if len(keys) > 0 {
+			quoted := make([]string, len(keys))
+			for i, key := range keys {
+				quoted[i] = quoteIdentifier(dialect, key)
+			}
+			order = strings.Join(quoted, ", ")
+			description += " by " + strings.Join(keys, ", ")
+		} else {
+			description += " in storage order"
+		}
+	case PreviewRandom:
+		order = randomFunction(dialect)
+		description = fmt.Sprintf("%d random rows; the table is read in full to pick them", rows)
+	case PreviewNewest:
+		column, err := newestColumn(columns, options.OrderColumn)
+		if err != nil {
+			return "", "", fmt.Errorf("%w in %s", err, options.Table)
+		}
+		order = quoteIdentifier(dialect, column) + " DESC"
+		if dialect == "postgresql" {
+			order += " NULLS LAST"
+		}
+		description = fmt.Sprintf("Newest %d rows by %s", rows, column)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT * FROM " + quoteIdentifier(dialect, options.Table))
+	if scope != "" {
+		b.WriteString(" WHERE " + scope)
+		description += ", limited to the current tenant"
+	}
+	if order != "" {
+		b.WriteString(" ORDER BY " + order)
+	}
+	fmt.Fprintf(&b, " LIMIT %d", rows)
+	return b.String(), description, nil
+}
+
+// randomFunction returns the dialect's function returning a random number
+func randomFunction(dialect string) string {
+	switch dialect {
+	case "mysql":
+		return "RAND()"
+	case "sqlite":
+		return "RANDOM()"
+	case "clickhouse":
+		return "rand()"
+	default:
+		return "random()"
+	}
+}
+
+// newestColumn returns the column ordering rows by age: the given one, or a
+// date or timestamp column, preferring the usual names for creation times
+func newestColumn(columns []models.ColumnInfo, given string) (string, error) {
+	if given != "" {
+		for _, col := range columns {
+			if strings.EqualFold(col.ColumnName, given) {
+				return col.ColumnName, nil
+			}
+		}
+		return "", fmt.Errorf("no column %s", given)
+	}
+
+	var temporal []string
+	for _, col := range columns {
+		if isTemporalType(col.DataType) {
+			temporal = append(temporal, col.ColumnName)
+		}
+	}
+	for _, name := range newestColumns {
+		for _, column := range temporal {
+			if strings.EqualFold(column, name) {
+				return column, nil
+			}
+		}
+	}
+	if len(temporal) > 0 {
+		return temporal[0], nil
+	}
+	return "", fmt.Errorf("no date or timestamp column to order by; name the column")
+}
+
+// isTemporalType reports whether a column type holds dates or timestamps
+func isTemporalType(dataType string) bool {
+	t := strings.ToLower(baseColumnType(dataType))
+	return strings.Contains(t, "timestamp") || strings.Contains(t, "datetime") || strings.HasPrefix(t, "date")
+}
+
+// isJSONType reports whether a column type holds JSON documents
+func isJSONType(dataType string) bool {
+	t := strings.ToLower(baseColumnType(dataType))
+	return t == "json" || t == "jsonb" || strings.HasPrefix(t, "object(")
+}
+
+// isBinaryType reports whether a column type holds bytes rather than text
+func isBinaryType(dataType string) bool {
+	t := strings.ToLower(baseColumnType(dataType))
+	return t == "bytea" || strings.Contains(t, "blob") || strings.Contains(t, "binary")
+}
+
+// baseColumnType strips the Nullable and LowCardinality wrappers ClickHouse
+// puts around column types
+func baseColumnType(dataType string) string {
+	for strings.HasSuffix(dataType, ")") {
+		if inner, ok := strings.CutPrefix(dataType, "Nullable("); ok {
+			dataType = inner[:len(inner)-1]
+		} else if inner, ok := strings.CutPrefix(dataType, "LowCardinality("); ok {
+			dataType = inner[:len(inner)-1]
+		} else {
+			break
+		}
+	}
+	return dataType
+}
+
+// ShortenValues returns a copy of a result with its long values shortened for
+// reading: text is cut to limit characters, JSON documents are summarized by
+// their structure and binary values replaced by their size. It also returns
+// the columns that had values shortened.
+func ShortenValues(result *models.QueryResult, columns []models.ColumnInfo, limit int) (*models.QueryResult, []string) {
+	types := make(map[string]string, len(columns))
+	for _, col := range columns {
+		types[strings.ToLower(col.ColumnName)] = col.DataType
+	}
+
+	shortened := *result
+	shortened.Rows = make([][]interface{}, len(result.Rows))
+	changed := make([]bool, len(result.Columns))
+	for r, row := range result.Rows {
+		shortened.Rows[r] = make([]interface{}, len(row))
+		for c, value := range row {
+			dataType := ""
+			if c < len(result.Columns) {
+				dataType = types[strings.ToLower(result.Columns[c])]
+			}
+			short, cut := shortenValue(value, dataType, limit)
+			shortened.Rows[r][c] = short
+			if cut && c < len(changed) {
+				changed[c] = true
+			}
+		}
+	}
+
+	var names []string
+	for c, cut := range changed {
+		if cut {
+			names = append(names, result.Columns[c])
+		}
+	}
+	return &shortened, names
+}
+
+// shortenValue shortens one value by its column type, reporting whether it
+// was changed
+func shortenValue(value interface{}, dataType string, limit int) (interface{}, bool) {
+	var text string
+	switch v := value.(type) {
+	case []byte:
+		if isBinaryType(dataType) || !utf8.Valid(v) {
+			return fmt.Sprintf("<%d bytes>", len(v)), true
+		}
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return value, false
+	}
+
+	length := utf8.RuneCountInString(text)
+	if length <= limit {
+		return text, false
+	}
+	if isJSONType(dataType) || looksLikeJSON(text) {
+		if summary, ok := summarizeJSON(text); ok {
+			return cutText(summary, limit) + fmt.Sprintf(" (JSON, %d chars)", length), true
+		}
+	}
+	return cutText(text, limit) + fmt.Sprintf(" (%d chars)", length), true
+}
+
+// looksLikeJSON reports whether text stored in a text column is a JSON object
+// or array
+func looksLikeJSON(text string) bool {
+	trimmed := strings.TrimSpace(text)
+	return (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed))
+}
+
+// summarizeJSON describes a JSON document by its structure: the keys of an
+// object with their values, nested objects and arrays reduced to their size
+func summarizeJSON(text string) (string, bool) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(text), &document); err != nil {
+		return "", false
+	}
+	switch v := document.(type) {
+	case map[string]interface{}:
+		return summarizeObject(v), true
+	case []interface{}:
+		if len(v) == 0 {
+			return "[]", true
+		}
+		return fmt.Sprintf("[%s, …%d items]", summarizeNested(v[0]), len(v)), true
+	}
+	return "", false
+}
+
+// summarizeObject lists the keys of an object with their summarized values
+func summarizeObject(object map[string]interface{}) string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		name, _ := json.Marshal(key)
+		parts[i] = string(name) + ": " + summarizeNested(object[key])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// summarizeNested summarizes a value inside a JSON document
+func summarizeNested(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			return "{…1 key}"
+		}
+		return fmt.Sprintf("{…%d keys}", len(v))
+	case []interface{}:
+		if len(v) == 1 {
+			return "[…1 item]"
+		}
+		return fmt.Sprintf("[…%d items]", len(v))
+	case string:
+		encoded, _ := json.Marshal(cutText(v, jsonScalarLength))
+		return string(encoded)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// cutText cuts text to at most limit characters, marking the cut
+func cutText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "…"
+}
+
+// describeShortened extends a preview's description with the columns that had
+// values shortened
+func describeShortened(description string, columns []string) string {
+	if len(columns) == 0 {
+		return description
+	}
+	return fmt.Sprintf("%s. Long values of %s were shortened to %d characters; select the column of one row to read it in full",
+		description, strings.Join(columns, ", "), PreviewValueLength)
+}
+
+// previewOptionsFromArgs reads the preview_table arguments
+func previewOptionsFromArgs(args map[string]interface{}) (PreviewOptions, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return PreviewOptions{}, fmt.Errorf("tableName argument is required and must be a string")
+	}
+	options := PreviewOptions{Table: tableName}
+	if n, ok := args["rows"].(float64); ok {
+		options.Rows = int(n)
+	}
+	mode, _ := args["mode"].(string)
+	var err error
+	if options.Mode, err = ParsePreviewMode(mode); err != nil {
+		return PreviewOptions{}, err
+	}
+	options.OrderColumn, _ = args["orderColumn"].(string)
+	return options, nil
+}
+
+func (e *Executor) previewTable(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	options, err := previewOptionsFromArgs(args)
+	if err != nil {
+		return "", err
+	}
+	preview, err := PreviewTable(dbTools, options, e.Tenant())
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(resultJSON), nil
+	}
+	if refusal := e.quotaRefusal(e.quota.fetched(len(preview.Result.Rows))); refusal != "" {
+		return refusal, nil
+	}
+
+	// Masked before shortening, so the masking rules see whole values
+	result := e.maskResult(dbinterfaces.ConnectionName(dbTools), preview.Result)
+	result, shortened := ShortenValues(result, preview.Columns, PreviewValueLength)
+	result.Sampling = describeShortened(result.Sampling, shortened)
+	data, err := e.marshalFitted(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal preview: %w", err)
+	}
+	return string(data), nil
+}
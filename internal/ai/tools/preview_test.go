@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPreviewQuery(t *testing.T) {
+	columns := []models.ColumnInfo{
+		{ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+		{ColumnName: "body", DataType: "text"},
+		{ColumnName: "updated_at", DataType: "timestamp with time zone"},
+		{ColumnName: "created_at", DataType: "timestamp without time zone"},
+	}
+
+	query, description, err := BuildPreviewQuery("postgresql", columns, PreviewOptions{Table: "public.events"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "public"."events" ORDER BY "id" LIMIT 10`, query)
+	assert.Equal(t, "First 10 rows by id", description)
+
+	query, _, err = BuildPreviewQuery("mysql", columns, PreviewOptions{Table: "events", Mode: "random", Rows: 500}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM `events` ORDER BY RAND() LIMIT 100", query, "previews are capped")
+
+	query, description, err = BuildPreviewQuery("postgresql", columns, PreviewOptions{Table: "events", Mode: "newest", Rows: 3}, `"tenant_id" = 'acme'`)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "events" WHERE "tenant_id" = 'acme' ORDER BY "created_at" DESC NULLS LAST LIMIT 3`, query, "created_at is preferred")
+	assert.Equal(t, "Newest 3 rows by created_at, limited to the current tenant", description)
+
+	query, _, err = BuildPreviewQuery("sqlite", columns, PreviewOptions{Table: "events", Mode: "latest", OrderColumn: "UPDATED_AT"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "events" ORDER BY "updated_at" DESC LIMIT 10`, query)
+
+	_, _, err = BuildPreviewQuery("sqlite", columns[:2], PreviewOptions{Table: "notes", Mode: "newest"}, "")
+	assert.EqualError(t, err, "no date or timestamp column to order by; name the column in notes")
+
+	_, _, err = BuildPreviewQuery("sqlite", columns, PreviewOptions{Table: "events", Mode: "oldest"}, "")
+	assert.Error(t, err)
+
+	_, description, err = BuildPreviewQuery("clickhouse", []models.ColumnInfo{{ColumnName: "at", DataType: "Nullable(DateTime64(3))"}}, PreviewOptions{Table: "hits", Mode: "newest"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Newest 10 rows by at", description)
+}
+
+func TestShortenValues(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+	document := fmt.Sprintf(`{"name": "ann", "tags": ["a", "b", "c"], "address": {"city": "Oslo", "zip": "0150"}, "bio": %q}`, long)
+	result := &models.QueryResult{
+		Columns: []string{"id", "note", "payload", "raw", "doc"},
+		Rows: [][]interface{}{
+			{int64(1), long, document, []byte{0xff, 0x00, 0x01}, `[{"a": 1}, {"a": 2}]`},
+			{int64(2), "short", nil, []byte("text"), "[]"},
+		},
+	}
+	columns := []models.ColumnInfo{
+		{ColumnName: "note", DataType: "text"},
+		{ColumnName: "payload", DataType: "jsonb"},
+		{ColumnName: "raw", DataType: "bytea"},
+		{ColumnName: "doc", DataType: "json"},
+	}
+
+	shortened, names := ShortenValues(result, columns, 120)
+	assert.Equal(t, []string{"note", "payload", "raw"}, names)
+	assert.Equal(t, strings.Repeat("word ", 24)+"… (300 chars)", shortened.Rows[0][1])
+	assert.Equal(t, `{"address": {…2 keys}, "bio": "word word word word word word word word …", "name": "ann", "tags": […3 items]} (JSON, 395 chars)`,
+		shortened.Rows[0][2])
+	assert.Equal(t, "<3 bytes>", shortened.Rows[0][3])
+	assert.Equal(t, "<4 bytes>", shortened.Rows[1][3], "binary columns never show their bytes")
+	assert.Equal(t, `[{"a": 1}, {"a": 2}]`, shortened.Rows[0][4], "short documents are kept")
+	assert.Equal(t, "short", shortened.Rows[1][1])
+	assert.Equal(t, long, result.Rows[0][1], "the result itself is unchanged")
+
+	summary, ok := summarizeJSON(`[{"id": 1, "items": [1, 2]}, {"id": 2}]`)
+	require.True(t, ok)
+	assert.Equal(t, "[{…2 keys}, …2 items]", summary)
+}
+
+func TestExecutor_PreviewTable(t *testing.T) {
+	db := openSQLite(t, "preview.db")
+	_, err := db.ExecuteSQL("CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT, payload TEXT, created_at TIMESTAMP)")
+	require.NoError(t, err)
+	payload := fmt.Sprintf(`{"user": {"id": 7, "name": "ann"}, "items": [1, 2, 3], "note": %q}`, strings.Repeat("x", 300))
+	for i := 1; i <= 30; i++ {
+		_, err := db.ExecuteSQLWithArgs("INSERT INTO events (id, kind, payload, created_at) VALUES (?, ?, ?, ?)",
+			i, fmt.Sprintf("k%d", i%3), payload, fmt.Sprintf("2026-01-%02d 10:00:00", i))
+		require.NoError(t, err)
+	}
+	e := NewExecutor(db)
+
+	result, err := e.Execute(toolCall("preview_table", `{"tableName": "events", "mode": "newest", "rows": 3}`))
+	require.NoError(t, err)
+	var preview models.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(result), &preview))
+	require.Equal(t, 3, preview.RowCount)
+	assert.Equal(t, float64(30), preview.Rows[0][0])
+	assert.Equal(t, float64(28), preview.Rows[2][0])
+	assert.Contains(t, preview.Sampling, "Newest 3 rows by created_at")
+	assert.Contains(t, preview.Sampling, "Long values of payload were shortened")
+	assert.Contains(t, preview.Rows[0][2], `"items": […3 items], "note": "xxx`)
+
+	result, err = e.Execute(toolCall("preview_table", `{"tableName": "events"}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &preview))
+	assert.Equal(t, 10, preview.RowCount)
+	assert.Equal(t, float64(1), preview.Rows[0][0])
+
+	result, err = e.Execute(toolCall("preview_table", `{"tableName": "events", "mode": "random", "rows": 30}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &preview))
+	assert.Equal(t, 30, preview.RowCount)
+
+	result, err = e.Execute(toolCall("preview_table", `{"tableName": "missing"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "table missing not found")
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// grantFor fills in the connected user of a missing privilege, asking the
+// server who it is only once a privilege is found missing
+func grantFor(dbTools dbinterfaces.DatabaseInterface, privilege sqlanalysis.Privilege) sqlanalysis.Privilege {
+	user := ""
+	if result, err := dbTools.ExecuteSQL(sqlanalysis.CurrentUserQuery(dbinterfaces.GetDatabaseType(dbTools))); err == nil {
+		user = sqlanalysis.CurrentUserFromResult(result)
+	}
+	return privilege.For(user)
+}
+
+// markUnavailable records a source the connected user cannot read, for
+// list_features, and tells the user which grant it needs once per connection
+func (e *Executor) markUnavailable(dbTools dbinterfaces.DatabaseInterface, privilege sqlanalysis.Privilege) {
+	name := dbinterfaces.ConnectionName(dbTools)
+	e.mu.Lock()
+	if e.unavailable == nil {
+		e.unavailable = make(map[string]map[string]sqlanalysis.Privilege)
+	}
+	if e.unavailable[name] == nil {
+		e.unavailable[name] = make(map[string]sqlanalysis.Privilege)
+	}
+	_, known := e.unavailable[name][privilege.Source]
+	e.unavailable[name][privilege.Source] = privilege
+	e.mu.Unlock()
+
+	if !known {
+		e.notices = append(e.notices, privilege.Explain())
+	}
+}
+
+// missingPrivileges returns the sources found unreadable on a connection
+func (e *Executor) missingPrivileges(dbTools dbinterfaces.DatabaseInterface) []sqlanalysis.Privilege {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	missing := []sqlanalysis.Privilege{}
+	for _, privilege := range e.unavailable[dbinterfaces.ConnectionName(dbTools)] {
+		missing = append(missing, privilege)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Source < missing[j].Source })
+	return missing
+}
+
+// missingPrivilege returns the grant whose absence caused a statement to
+// fail, filled in for the connected user and recorded as unavailable
+func (e *Executor) missingPrivilege(dbTools dbinterfaces.DatabaseInterface, execErr error) (sqlanalysis.Privilege, bool) {
+	privilege, ok := sqlanalysis.MissingPrivilege(dbinterfaces.GetDatabaseType(dbTools), execErr)
+	if !ok {
+		return sqlanalysis.Privilege{}, false
+	}
+	privilege = grantFor(dbTools, privilege)
+	e.markUnavailable(dbTools, privilege)
+	return privilege, true
+}
+
+// missingPrivilegeResult returns the tool result for a statement that failed
+// because the user may not read an introspection source, or "" for other errors
+func (e *Executor) missingPrivilegeResult(dbTools dbinterfaces.DatabaseInterface, execErr error) string {
+	privilege, ok := e.missingPrivilege(dbTools, execErr)
+	if !ok {
+		return ""
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"error":             execErr.Error(),
+		"missing_privilege": privilege,
+		"instruction":       fmt.Sprintf("The connected user may not read %s. Tell the user the analysis is unavailable and that an administrator must run missing_privilege.grant; do not retry the statement or present partial results as complete.", privilege.Source),
+	})
+	if err != nil {
+		return ""
+	}
+	return string(resultJSON)
+}
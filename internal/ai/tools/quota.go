@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is wrapped by the errors of calls refused by a quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quotas limit what one session may do, so a shared deployment cannot be
+// abused. A zero limit is off.
+type Quotas struct {
+	StatementsPerMinute int `json:"statements_per_minute,omitempty"` // Database tool calls per minute
+	RowsPerHour         int `json:"rows_per_hour,omitempty"`         // Result rows fetched per hour
+	AICallsPerSession   int `json:"ai_calls_per_session,omitempty"`  // Requests to the model per session
+}
+
+// Set reports whether any quota is configured
+func (q Quotas) Set() bool {
+	return q.StatementsPerMinute > 0 || q.RowsPerHour > 0 || q.AICallsPerSession > 0
+}
+
+// Validate rejects negative limits
+func (q Quotas) Validate() error {
+	if q.StatementsPerMinute < 0 || q.RowsPerHour < 0 || q.AICallsPerSession < 0 {
+		return fmt.Errorf("quotas must not be negative")
+	}
+	return nil
+}
+
+// Describe lists the configured quotas, e.g. for the system prompt
+func (q Quotas) Describe() string {
+	var limits []string
+	if q.StatementsPerMinute > 0 {
+		limits = append(limits, fmt.Sprintf("%d statements per minute", q.StatementsPerMinute))
+	}
+	if q.RowsPerHour > 0 {
+		limits = append(limits, fmt.Sprintf("%d result rows per hour", q.RowsPerHour))
+	}
+	if q.AICallsPerSession > 0 {
+		limits = append(limits, fmt.Sprintf("%d AI calls per session", q.AICallsPerSession))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// usageWindow counts usage over a sliding window of time
+type usageWindow struct {
+	span    time.Duration
+	entries []usageEntry
+}
+
+type usageEntry struct {
+	at time.Time
+	n  int
+}
+
+// used returns the usage within the window, forgetting older entries
+func (w *usageWindow) used(now time.Time) int {
+	keep := w.entries[:0]
+	total := 0
+	for _, entry := range w.entries {
+		if now.Sub(entry.at) < w.span {
+			keep = append(keep, entry)
+			total += entry.n
+		}
+	}
+	w.entries = keep
+	return total
+}
+
+// add records usage
+func (w *usageWindow) add(now time.Time, n int) {
+	w.entries = append(w.entries, usageEntry{at: now, n: n})
+}
+
+// retryIn returns how long until the oldest usage leaves the window
+func (w *usageWindow) retryIn(now time.Time) time.Duration {
+	if len(w.entries) == 0 {
+		return 0
+	}
+	return (w.entries[0].at.Add(w.span).Sub(now)).Round(time.Second)
+}
+
+// quotaTracker enforces the statement and row quotas of the executor
+type quotaTracker struct {
+	mu         sync.Mutex
+	quotas     Quotas
+	statements usageWindow
+	rows       usageWindow
+	now        func() time.Time
+}
+
+func newQuotaTracker(quotas Quotas) *quotaTracker {
+	return &quotaTracker{
+		quotas:     quotas,
+		statements: usageWindow{span: time.Minute},
+		rows:       usageWindow{span: time.Hour},
+		now:        time.Now,
+	}
+}
+
+// statement counts a statement, or returns why a quota refuses it: too many
+// statements this minute, or no rows left to fetch this hour
+func (t *quotaTracker) statement() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if limit := t.quotas.RowsPerHour; limit > 0 && t.rows.used(now) >= limit {
+		return fmt.Errorf("%w: this session may fetch %d rows per hour; try again in %s",
+			ErrQuotaExceeded, limit, t.rows.retryIn(now))
+	}
+	if limit := t.quotas.StatementsPerMinute; limit > 0 {
+		if t.statements.used(now) >= limit {
+			return fmt.Errorf("%w: this session may run %d statements per minute; try again in %s",
+				ErrQuotaExceeded, limit, t.statements.retryIn(now))
+		}
+		t.statements.add(now, 1)
+	}
+	return nil
+}
+
+// fetched counts the rows of a result, or returns why the quota withholds
+// them. Rows count once they are fetched, whether or not they are returned.
+func (t *quotaTracker) fetched(rows int) error {
+	if t == nil || t.quotas.RowsPerHour == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	used := t.rows.used(now)
+	t.rows.add(now, rows)
+	if used+rows > t.quotas.RowsPerHour {
+		return fmt.Errorf("%w: the %d rows of this result go over the %d rows per hour this session may fetch (%d fetched before); narrow the query or try again in %s",
+			ErrQuotaExceeded, rows, t.quotas.RowsPerHour, used, t.rows.retryIn(now))
+	}
+	return nil
+}
+
+// SetQuotas sets the statement and row quotas of the session, starting their
+// counts over
+func (e *Executor) SetQuotas(quotas Quotas) {
+	e.quota = newQuotaTracker(quotas)
+}
+
+// quotaRefusal returns the tool result refusing a call over a quota, and
+// queues a notice so the user sees why, or "" when the call is within quota
+func (e *Executor) quotaRefusal(err error) string {
+	if err == nil {
+		return ""
+	}
+	message := err.Error()
+	e.notices = append(e.notices, strings.ToUpper(message[:1])+message[1:])
+	result, _ := json.Marshal(map[string]string{"error": message})
+	return string(result)
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_StatementQuota(t *testing.T) {
+	db := openSQLite(t, "quota.db")
+	seedOrders(t, db, 3)
+	e := NewExecutor(db)
+	e.SetQuotas(Quotas{StatementsPerMinute: 2})
+	now := time.Now()
+	e.quota.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		result, err := e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders"}`))
+		require.NoError(t, err)
+		assert.NotContains(t, result, "quota exceeded")
+	}
+
+	result, err := e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "quota exceeded: this session may run 2 statements per minute; try again in 1m0s")
+	assert.Equal(t, []string{"Quota exceeded: this session may run 2 statements per minute; try again in 1m0s"}, e.TakeNotices())
+
+	// Generating code runs no statement
+	_, err = e.Execute(toolCall("generate_code", `{"language": "go", "sql": "SELECT 1"}`))
+	require.NoError(t, err)
+	assert.Empty(t, e.TakeNotices())
+
+	// The window slides
+	now = now.Add(time.Minute)
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, result, "quota exceeded")
+}
+
+func TestExecutor_RowQuota(t *testing.T) {
+	db := openSQLite(t, "rows.db")
+	seedOrders(t, db, 3)
+	e := NewExecutor(db)
+	e.SetQuotas(Quotas{RowsPerHour: 4})
+	now := time.Now()
+	e.quota.now = func() time.Time { return now }
+
+	result, err := e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, result, "quota exceeded")
+
+	// The second result is fetched but withheld
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "the 3 rows of this result go over the 4 rows per hour this session may fetch (3 fetched before)")
+
+	// With the budget used up, statements are refused before running
+	now = now.Add(30 * time.Minute)
+	result, err = e.Execute(toolCall("get_all_tables", `{}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "quota exceeded: this session may fetch 4 rows per hour; try again in 30m0s")
+
+	now = now.Add(30 * time.Minute)
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT id FROM orders WHERE id = 1"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, result, "quota exceeded")
+}
+
+func TestQuotas_Validate(t *testing.T) {
+	assert.NoError(t, Quotas{}.Validate())
+	assert.False(t, Quotas{}.Set())
+	assert.Error(t, Quotas{RowsPerHour: -1}.Validate())
+	assert.Equal(t, "30 statements per minute, 5 AI calls per session", Quotas{StatementsPerMinute: 30, AICallsPerSession: 5}.Describe())
+}
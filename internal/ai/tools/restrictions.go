@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"dbsage/internal/sqlanalysis"
+)
+
+// Restrictions are the capabilities the executor refuses, so a tool removed
+// from the model's tool list stays unavailable even when called by name
+type Restrictions struct {
+	Disabled     map[string]bool // Tools refused outright
+	ReadOnlySQL  bool            // execute_sql only runs read-only statements
+	NoCatalogSQL bool            // execute_sql refuses statements that inspect the schema
+}
+
+// catalogPattern matches statements reading table names or structure
+var catalogPattern = regexp.MustCompile(`(?i)\b(information_schema|pg_catalog|pg_class|pg_attribute|pg_tables|pg_views|pg_indexes|sqlite_master|sqlite_schema|pragma_table_info)\b|^\s*(SHOW|DESCRIBE|DESC|PRAGMA)\b`)
+
+// SetRestrictions sets the capabilities the executor refuses
+func (e *Executor) SetRestrictions(r Restrictions) {
+	e.restrictions = r
+}
+
+// refusal returns the tool result refusing a call, or "" when it is allowed
+func (r Restrictions) refusal(name string, args map[string]interface{}) string {
+	reason := ""
+	sql, _ := args["sql"].(string)
+	switch {
+	case r.Disabled[name]:
+		reason = fmt.Sprintf("the %s tool is disabled in this deployment", name)
+	case name != "execute_sql" && name != "export_results" && name != "sample_results":
+	case r.ReadOnlySQL && !sqlanalysis.IsReadOnly(sql):
+		reason = "only read-only statements may be executed in this deployment; give the user the SQL to run themselves"
+	case r.NoCatalogSQL && catalogPattern.MatchString(sql):
+		reason = "inspecting the schema is disabled in this deployment; ask the user for the relevant tables and columns"
+	}
+	if reason == "" {
+		return ""
+	}
+	result, _ := json.Marshal(map[string]string{"error": reason})
+	return string(result)
+}
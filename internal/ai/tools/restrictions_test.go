@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolCall(name, arguments string) openai.ToolCall {
+	return openai.ToolCall{Function: openai.FunctionCall{Name: name, Arguments: arguments}}
+}
+
+func TestExecutor_Restrictions(t *testing.T) {
+	db := openSQLite(t, "restricted.db")
+	seedOrders(t, db, 1)
+	e := NewExecutor(db)
+	e.SetRestrictions(Restrictions{Disabled: map[string]bool{"get_all_tables": true}, ReadOnlySQL: true, NoCatalogSQL: true})
+
+	result, err := e.Execute(toolCall("get_all_tables", `{}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "the get_all_tables tool is disabled")
+
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "DELETE FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "only read-only statements")
+	assert.Equal(t, "1", countRows(t, db, "orders"))
+
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT name FROM sqlite_master"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "inspecting the schema is disabled")
+
+	result, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT customer FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "c1")
+}
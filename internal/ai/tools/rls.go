@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// rlsCommands are the commands a policy can be limited to
+var rlsCommands = []string{"SELECT", "INSERT", "UPDATE", "DELETE"}
+
+func (e *Executor) getRLSPolicies(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	tableName, _ := args["tableName"].(string)
+	report, err := dbinterfaces.GetRLSPolicies(dbTools, strings.TrimSpace(tableName))
+	if err != nil {
+		return "", err
+	}
+	for i := range report.Tables {
+		report.Tables[i].Effect = explainRLS(report, report.Tables[i])
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policies: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// explainRLS describes how a table's policies apply to the current role,
+// following PostgreSQL's rules: superusers, BYPASSRLS roles and owners of
+// tables without FORCE ROW LEVEL SECURITY skip the policies; otherwise a row
+// must pass any applicable permissive policy and every applicable restrictive
+// one, and a command without a permissive policy is denied.
+func explainRLS(report *models.RLSReport, table models.RLSTable) string {
+	role := report.CurrentRole
+	switch {
+	case !table.Enabled:
+		if len(table.Policies) > 0 {
+			return "Row-level security is disabled, so the policies are ignored and only grants apply; enable it with ALTER TABLE ... ENABLE ROW LEVEL SECURITY."
+		}
+		return "Row-level security is disabled; only grants apply."
+	case report.Superuser:
+		return fmt.Sprintf("%s is a superuser and bypasses every policy; other roles will see fewer rows.", role)
+	case report.BypassRLS:
+		return fmt.Sprintf("%s has BYPASSRLS and bypasses every policy; other roles will see fewer rows.", role)
+	case table.OwnedByCurrentRole && !table.Forced:
+		return fmt.Sprintf("%s owns the table and bypasses its policies because FORCE ROW LEVEL SECURITY is off; other roles will see fewer rows.", role)
+	}
+
+	var permissive, restrictive []string
+	var denied []string
+	for _, command := range rlsCommands {
+		allowed := false
+		for _, p := range table.Policies {
+			if p.AppliesToCurrentRole && p.Permissive && (p.Command == "ALL" || p.Command == command) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			denied = append(denied, command)
+		}
+	}
+	for _, p := range table.Policies {
+		if !p.AppliesToCurrentRole {
+			continue
+		}
+		name := fmt.Sprintf("%s (%s)", p.Name, p.Command)
+		if p.Permissive {
+			permissive = append(permissive, name)
+		} else {
+			restrictive = append(restrictive, name)
+		}
+	}
+
+	if len(permissive) == 0 {
+		return fmt.Sprintf("Row-level security is enforced and no permissive policy applies to %s, so every row is hidden and writes fail (default deny).", role)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Row-level security is enforced for %s: rows must pass any of %s", role, strings.Join(permissive, ", ")))
+	if len(restrictive) > 0 {
+		b.WriteString(fmt.Sprintf(" and all of %s", strings.Join(restrictive, ", ")))
+	}
+	b.WriteString(".")
+	if len(denied) > 0 {
+		b.WriteString(fmt.Sprintf(" No permissive policy covers %s, so those commands see or change no rows.", strings.Join(denied, ", ")))
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainRLS(t *testing.T) {
+	report := &models.RLSReport{CurrentRole: "app"}
+	documents := models.RLSTable{
+		Table:   "documents",
+		Enabled: true,
+		Policies: []models.RLSPolicy{
+			{Name: "own_rows", Command: "ALL", Permissive: true, Roles: []string{"app"}, AppliesToCurrentRole: true},
+			{Name: "not_archived", Command: "SELECT", Roles: []string{"public"}, AppliesToCurrentRole: true},
+			{Name: "admin_rows", Command: "ALL", Permissive: true, Roles: []string{"admin"}},
+		},
+	}
+
+	effect := explainRLS(report, documents)
+	assert.Contains(t, effect, "any of own_rows (ALL) and all of not_archived (SELECT)")
+	assert.NotContains(t, effect, "admin_rows")
+	assert.NotContains(t, effect, "No permissive policy")
+
+	readOnly := documents
+	readOnly.Policies = []models.RLSPolicy{{Name: "read", Command: "SELECT", Permissive: true, AppliesToCurrentRole: true}}
+	assert.Contains(t, explainRLS(report, readOnly), "No permissive policy covers INSERT, UPDATE, DELETE")
+
+	denied := documents
+	denied.Policies = documents.Policies[2:]
+	assert.Contains(t, explainRLS(report, denied), "default deny")
+
+	owned := documents
+	owned.OwnedByCurrentRole = true
+	assert.Contains(t, explainRLS(report, owned), "FORCE ROW LEVEL SECURITY is off")
+	owned.Forced = true
+	assert.Contains(t, explainRLS(report, owned), "enforced")
+
+	assert.Contains(t, explainRLS(&models.RLSReport{CurrentRole: "postgres", Superuser: true}, documents), "superuser")
+
+	disabled := documents
+	disabled.Enabled = false
+	assert.Contains(t, explainRLS(report, disabled), "policies are ignored")
+}
+
+func TestExecutor_GetRLSPolicies_Unsupported(t *testing.T) {
+	executor := NewExecutor(&MockDatabaseInterface{})
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "get_rls_policies",
+		Arguments: `{"tableName": "documents"}`,
+	}})
+	assert.ErrorContains(t, err, "only available for PostgreSQL")
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// maxListedStrata is how many strata the sampling description lists by name
+const maxListedStrata = 10
+
+// stratum is the rows of a result sharing one value of the sampled column
+type stratum struct {
+	value string
+	rows  []int // Row indexes in result order
+	take  int   // Rows the sample takes
+}
+
+// SampleResult picks at most size rows of a result to hand to the model,
+// stratified by column when one is given: every value keeps its share of the
+// rows, and each gets at least one row while there is room. Rows are picked
+// at even intervals, so the same result always gives the same sample. The
+// sample's Sampling field says how it was drawn.
+func SampleResult(result *models.QueryResult, column string, size int) (*models.QueryResult, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("sample size must be positive")
+	}
+	total := len(result.Rows)
+	sample := *result
+
+	if column == "" {
+		if total <= size {
+			sample.Sampling = fmt.Sprintf("All %d rows, no sampling needed", total)
+			return &sample, nil
+		}
+		sample.Rows = pickEvenly(result.Rows, indexes(total), size)
+		sample.RowCount = len(sample.Rows)
+		sample.Sampling = fmt.Sprintf("Systematic sample: %d of %d rows at even intervals through the result. "+
+			"Counts and sums describe the sample only; scale them by %d/%d or compute them with SQL for the whole result.",
+			len(sample.Rows), total, total, len(sample.Rows))
+		return &sample, nil
+	}
+
+	col := -1
+	for i, name := range result.Columns {
+		if strings.EqualFold(name, column) {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("column %s is not in the result; columns: %s", column, strings.Join(result.Columns, ", "))
+	}
+	column = result.Columns[col]
+	if total <= size {
+		sample.Sampling = fmt.Sprintf("All %d rows, no sampling needed", total)
+		return &sample, nil
+	}
+
+	strata := groupStrata(result.Rows, col)
+	allocate(strata, size, total)
+
+	var picked []int
+	for _, s := range strata {
+		picked = append(picked, pickEvenlyIndexes(s.rows, s.take)...)
+	}
+	sort.Ints(picked)
+	sample.Rows = make([][]interface{}, len(picked))
+	for i, index := range picked {
+		sample.Rows[i] = result.Rows[index]
+	}
+	sample.RowCount = len(sample.Rows)
+	sample.Sampling = describeStrata(column, strata, len(sample.Rows), total)
+	return &sample, nil
+}
+
+// groupStrata groups row indexes by the value of a column, largest group first
+func groupStrata(rows [][]interface{}, col int) []*stratum {
+	byValue := make(map[string]*stratum)
+	var strata []*stratum
+	for i, row := range rows {
+		value := "NULL"
+		if col < len(row) && row[col] != nil {
+			value = fmt.Sprint(row[col])
+			if b, ok := row[col].([]byte); ok {
+				value = string(b)
+			}
+		}
+		s, ok := byValue[value]
+		if !ok {
+			s = &stratum{value: value}
+			byValue[value] = s
+			strata = append(strata, s)
+		}
+		s.rows = append(s.rows, i)
+	}
+	sort.SliceStable(strata, func(i, j int) bool {
+		return len(strata[i].rows) > len(strata[j].rows)
+	})
+	return strata
+}
+
+// allocate shares size rows among the strata in proportion to their size,
+// giving each at least one row while there is room, with the rows left by
+// rounding down going to the largest remainders
+func allocate(strata []*stratum, size, total int) {
+	if len(strata) >= size {
+		// One row each for the largest values; the rest are left out
+		for i, s := range strata {
+			if i < size {
+				s.take = 1
+			}
+		}
+		return
+	}
+
+	remaining := size
+	for _, s := range strata {
+		s.take = 1
+		remaining--
+	}
+	type remainder struct {
+		s    *stratum
+		frac float64
+	}
+	var remainders []remainder
+	spare := size - len(strata)
+	for _, s := range strata {
+		share := float64(spare) * float64(len(s.rows)-1) / float64(total-len(strata))
+		extra := int(share)
+		if extra > len(s.rows)-1 {
+			extra = len(s.rows) - 1
+		}
+		s.take += extra
+		remaining -= extra
+		remainders = append(remainders, remainder{s, share - float64(extra)})
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].frac > remainders[j].frac
+	})
+	for _, r := range remainders {
+		if remaining == 0 {
+			break
+		}
+		if r.s.take < len(r.s.rows) {
+			r.s.take++
+			remaining--
+		}
+	}
+}
+
+// describeStrata explains a stratified sample, listing the largest values
+func describeStrata(column string, strata []*stratum, sampled, total int) string {
+	var parts []string
+	omitted := 0
+	for i, s := range strata {
+		if s.take == 0 {
+			omitted++
+			continue
+		}
+		if i < maxListedStrata {
+			parts = append(parts, fmt.Sprintf("%s %d of %d", s.value, s.take, len(s.rows)))
+		}
+	}
+	if listed := len(strata) - omitted; listed > maxListedStrata {
+		parts = append(parts, fmt.Sprintf("%d more values", listed-maxListedStrata))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Stratified sample by %s: %d of %d rows across %d values (%s). ", column, sampled, total, len(strata), strings.Join(parts, "; "))
+	b.WriteString("Each value keeps roughly its share of the rows, but values with few rows are over-represented. ")
+	if omitted > 0 {
+		fmt.Fprintf(&b, "%d rare values are not in the sample at all. ", omitted)
+	}
+	b.WriteString("Scale counts and sums by each value's total, or compute them with SQL, before drawing conclusions about the whole result.")
+	return b.String()
+}
+
+// indexes returns 0..n-1
+func indexes(n int) []int {
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// pickEvenlyIndexes returns k of the given indexes at even intervals
+func pickEvenlyIndexes(from []int, k int) []int {
+	if k >= len(from) {
+		return from
+	}
+	picked := make([]int, k)
+	for i := range picked {
+		picked[i] = from[(2*i+1)*len(from)/(2*k)]
+	}
+	return picked
+}
+
+// pickEvenly returns k rows at even intervals
+func pickEvenly(rows [][]interface{}, from []int, k int) [][]interface{} {
+	picked := pickEvenlyIndexes(from, k)
+	sample := make([][]interface{}, len(picked))
+	for i, index := range picked {
+		sample[i] = rows[index]
+	}
+	return sample
+}
+
+// sampleResults hands the model a sample of the last query result, or of a
+// given read-only query, for analysis
+func (e *Executor) sampleResults(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	size := MaxToolResultRows
+	if n, ok := args["size"].(float64); ok && n > 0 && int(n) < size {
+		size = int(n)
+	}
+	column, _ := args["column"].(string)
+
+	result := e.LastResult()
+	_, connection := e.LastResultQuery()
+	if sql, _ := args["sql"].(string); strings.TrimSpace(sql) != "" {
+		if !sqlanalysis.IsReadOnly(sql) {
+			return `{"error": "sample_results only runs read-only queries"}`, nil
+		}
+		if violation, err := e.tenantViolation(dbTools, sql); err != nil || violation != "" {
+			return violation, err
+		}
+		if violation, err := e.maskingViolation(dbTools, sql); err != nil || violation != "" {
+			return violation, err
+		}
+		var err error
+		if result, err = dbTools.ExecuteSQL(sql); err != nil {
+			return "", err
+		}
+		connection = dbinterfaces.ConnectionName(dbTools)
+		if refusal := e.quotaRefusal(e.quota.fetched(len(result.Rows))); refusal != "" {
+			return refusal, nil
+		}
+	}
+	if result == nil {
+		return `{"error": "No query result to sample yet. Run a query with execute_sql first or pass the sql argument."}`, nil
+	}
+
+	sample, err := SampleResult(result, strings.TrimSpace(column), size)
+	if err != nil {
+		return "", err
+	}
+	data, err := e.marshalTruncated(connection, sample)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sample: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusResult returns a result with count rows of each status, in order
+func statusResult(counts ...interface{}) *models.QueryResult {
+	result := &models.QueryResult{Columns: []string{"id", "status"}}
+	for i := 0; i < len(counts); i += 2 {
+		for n := 0; n < counts[i+1].(int); n++ {
+			result.Rows = append(result.Rows, []interface{}{len(result.Rows) + 1, counts[i]})
+		}
+	}
+	result.RowCount = len(result.Rows)
+	return result
+}
+
+func countBy(result *models.QueryResult, col int) map[interface{}]int {
+	counts := make(map[interface{}]int)
+	for _, row := range result.Rows {
+		counts[row[col]]++
+	}
+	return counts
+}
+
+func TestSampleResult_Stratified(t *testing.T) {
+	result := statusResult("paid", 90, "refunded", 9, nil, 1)
+
+	sample, err := SampleResult(result, "STATUS", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 10, sample.RowCount)
+	assert.Equal(t, map[interface{}]int{"paid": 7, "refunded": 2, nil: 1}, countBy(sample, 1))
+	assert.Equal(t, 100, len(result.Rows), "the result is left alone")
+
+	// Rows keep the result's order
+	for i := 1; i < len(sample.Rows); i++ {
+		assert.Less(t, sample.Rows[i-1][0].(int), sample.Rows[i][0].(int))
+	}
+	assert.Contains(t, sample.Sampling, "Stratified sample by status: 10 of 100 rows across 3 values (paid 7 of 90; refunded 2 of 9; NULL 1 of 1)")
+	assert.Contains(t, sample.Sampling, "over-represented")
+
+	again, err := SampleResult(result, "status", 10)
+	require.NoError(t, err)
+	assert.Equal(t, sample.Rows, again.Rows, "sampling is deterministic")
+}
+
+func TestSampleResult_MoreValuesThanRows(t *testing.T) {
+	result := statusResult("a", 4, "b", 3, "c", 2, "d", 2, "e", 1)
+
+	sample, err := SampleResult(result, "status", 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[interface{}]int{"a": 1, "b": 1, "c": 1}, countBy(sample, 1))
+	assert.Contains(t, sample.Sampling, "2 rare values are not in the sample at all")
+}
+
+func TestSampleResult_Systematic(t *testing.T) {
+	result := statusResult("paid", 100)
+
+	sample, err := SampleResult(result, "", 10)
+	require.NoError(t, err)
+	var ids []int
+	for _, row := range sample.Rows {
+		ids = append(ids, row[0].(int))
+	}
+	assert.Equal(t, []int{6, 16, 26, 36, 46, 56, 66, 76, 86, 96}, ids)
+	assert.Contains(t, sample.Sampling, "Systematic sample: 10 of 100 rows")
+}
+
+func TestSampleResult_Errors(t *testing.T) {
+	result := statusResult("paid", 3)
+
+	sample, err := SampleResult(result, "status", 10)
+	require.NoError(t, err)
+	assert.Len(t, sample.Rows, 3)
+	assert.Equal(t, "All 3 rows, no sampling needed", sample.Sampling)
+
+	_, err = SampleResult(result, "region", 10)
+	assert.EqualError(t, err, "column region is not in the result; columns: id, status")
+
+	_, err = SampleResult(result, "", 0)
+	assert.Error(t, err)
+}
+
+func TestExecutor_SampleResults(t *testing.T) {
+	db := openSQLite(t, "sample.db")
+	seedOrders(t, db, 20)
+	e := NewExecutor(db)
+
+	result, err := e.Execute(toolCall("sample_results", `{"column": "customer"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "No query result to sample yet")
+
+	_, err = e.Execute(toolCall("execute_sql", `{"sql": "SELECT id, total FROM orders ORDER BY id"}`))
+	require.NoError(t, err)
+	result, err = e.Execute(toolCall("sample_results", `{"size": 5}`))
+	require.NoError(t, err)
+	var sample models.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(result), &sample))
+	assert.Equal(t, 5, sample.RowCount)
+	assert.Contains(t, sample.Sampling, "Systematic sample: 5 of 20 rows")
+
+	result, err = e.Execute(toolCall("sample_results", `{"column": "n", "sql": "SELECT id, id % 2 AS n FROM orders"}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &sample))
+	assert.Equal(t, 20, sample.RowCount)
+
+	result, err = e.Execute(toolCall("sample_results", `{"sql": "DELETE FROM orders"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "only runs read-only queries")
+	assert.Equal(t, "20", countRows(t, db, "orders"))
+}
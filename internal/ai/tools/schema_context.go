@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// maxQueriedTables is how many recently queried tables are remembered per
+// connection for the schema summary
+const maxQueriedTables = 20
+
+// schemaSnapshot is the schema of a connection, read once for the schema
+// summary sent to the AI
+type schemaSnapshot struct {
+	tables    []sqlanalysis.SchemaTable
+	relations []sqlanalysis.Relation
+}
+
+// SchemaContext selects the schema summary sent to the AI within budget
+// tokens, favoring the tables named in conversation, those queried recently,
+// those similar ranks as closest to the question and their foreign key
+// neighbors when the whole schema does not fit. similar is only called then,
+// and may be nil.
+func (e *Executor) SchemaContext(conversation string, budget int, similar func([]sqlanalysis.SchemaTable) []string) (*sqlanalysis.SchemaContext, error) {
+	dbTools := e.currentTools()
+	if dbTools == nil {
+		return nil, fmt.Errorf("no database connection")
+	}
+	connection := dbinterfaces.ConnectionName(dbTools)
+	snapshot, err := e.schemaSnapshot(dbTools, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	queried := append([]string(nil), e.queried[connection]...)
+	e.mu.Unlock()
+	mentioned := sqlanalysis.MentionedTables(conversation, snapshot.tables)
+	var nearest []string
+	if similar != nil && !sqlanalysis.SchemaFits(snapshot.tables, budget) {
+		nearest = similar(snapshot.tables)
+	}
+	selected := sqlanalysis.SelectSchema(snapshot.tables, snapshot.relations, mentioned, queried, nearest, budget)
+	return &selected, nil
+}
+
+// schemaSnapshot returns the schema of a connection, reading it on first use.
+// Foreign keys are optional: without them no neighbors are chosen.
+func (e *Executor) schemaSnapshot(dbTools dbinterfaces.DatabaseInterface, connection string) (*schemaSnapshot, error) {
+	e.mu.Lock()
+	snapshot := e.schemas[connection]
+	e.mu.Unlock()
+	if snapshot != nil {
+		return snapshot, nil
+	}
+
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	query, err := sqlanalysis.BuildSchemaColumnsQuery(dialect)
+	if err != nil {
+		return nil, err
+	}
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schema: %w", err)
+	}
+	snapshot = &schemaSnapshot{tables: sqlanalysis.SchemaFromResult(result)}
+	if query, err := sqlanalysis.BuildRelationsQuery(dialect); err == nil {
+		if result, err := dbTools.ExecuteSQL(query); err == nil {
+			snapshot.relations = sqlanalysis.RelationsFromResult(result)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.schemas == nil {
+		e.schemas = make(map[string]*schemaSnapshot)
+	}
+	e.schemas[connection] = snapshot
+	return snapshot, nil
+}
+
+// recordQueried remembers the tables a statement used, most recent first, and
+// forgets the schema of the connection when the statement may have changed it
+func (e *Executor) recordQueried(connection, sql string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch sqlanalysis.FirstWrite(sql) {
+	case "", "INSERT", "UPDATE", "DELETE", "MERGE":
+	default:
+		delete(e.schemas, connection)
+	}
+
+	if e.queried == nil {
+		e.queried = make(map[string][]string)
+	}
+	for _, ref := range sqlanalysis.TableReferences(sql) {
+		tables := []string{ref.Table}
+		for _, table := range e.queried[connection] {
+			if table != ref.Table {
+				tables = append(tables, table)
+			}
+		}
+		if len(tables) > maxQueriedTables {
+			tables = tables[:maxQueriedTables]
+		}
+		e.queried[connection] = tables
+	}
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_SchemaContext(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	columnsQuery, _ := sqlanalysis.BuildSchemaColumnsQuery("postgresql")
+	relationsQuery, _ := sqlanalysis.BuildRelationsQuery("postgresql")
+	columns := &models.QueryResult{Rows: [][]interface{}{
+		{"customers", "id", "integer"},
+		{"orders", "id", "integer"},
+		{"orders", "customer_id", "integer"},
+	}}
+	for i := 0; i < 30; i++ {
+		columns.Rows = append(columns.Rows, []interface{}{"events", fmt.Sprintf("payload_%d", i), "jsonb"})
+	}
+	mockDB.On("ExecuteSQL", columnsQuery).Return(columns, nil).Once()
+	mockDB.On("ExecuteSQL", relationsQuery).Return(&models.QueryResult{Rows: [][]interface{}{
+		{"orders_customer_fk", "orders", "customer_id", "customers", "id"},
+	}}, nil).Once()
+	mockDB.On("ExecuteSQL", "SELECT * FROM orders").Return(&models.QueryResult{Columns: []string{"id"}}, nil)
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT * FROM orders"}`}})
+	require.NoError(t, err)
+
+	// A budget too small for the whole schema keeps the queried table and its neighbor
+	ctx, err := executor.SchemaContext("hello", 80, nil)
+	require.NoError(t, err)
+	assert.False(t, ctx.Complete)
+	require.Len(t, ctx.Tables, 2)
+	assert.Equal(t, "orders", ctx.Tables[0].Name)
+	assert.Equal(t, sqlanalysis.ReasonQueried, ctx.Tables[0].Reason)
+	assert.Equal(t, "referenced by orders", ctx.Tables[1].Reason)
+	assert.Equal(t, []string{"events"}, ctx.Listed)
+
+	// The schema is read once per connection
+	ctx, err = executor.SchemaContext("hello", 2000, nil)
+	require.NoError(t, err)
+	assert.True(t, ctx.Complete)
+	mockDB.AssertNumberOfCalls(t, "ExecuteSQL", 3)
+
+	// DDL run by the AI makes the next summary read the schema again
+	mockDB.On("ExecuteSQL", "ALTER TABLE orders ADD note text").Return(&models.QueryResult{}, nil)
+	mockDB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	_, err = executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "ALTER TABLE orders ADD note text"}`}})
+	require.NoError(t, err)
+	_, err = executor.SchemaContext("hello", 1000, nil)
+	require.NoError(t, err)
+	mockDB.AssertNumberOfCalls(t, "ExecuteSQL", 6)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// ScriptResult is what execute_sql returns for a script of several statements
+type ScriptResult struct {
+	Statements []StatementOutcome `json:"statements"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	Skipped    int                `json:"skipped,omitempty"` // Statements left unexecuted after a failure
+	Duration   string             `json:"duration"`          // Time taken by the whole script
+	Note       string             `json:"note,omitempty"`    // Why the script stopped early
+}
+
+// StatementOutcome is the outcome of one executed statement of a script
+type StatementOutcome struct {
+	SQL      string          `json:"sql"`
+	Result   json.RawMessage `json:"result,omitempty"` // Masked and truncated like the result of a single statement
+	Duration string          `json:"duration,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// executeScript runs the statements of a script in order. It stops at the
+// first failure unless continueOnError is set; statements are not wrapped in
+// a transaction, so the ones before a failure stay applied. Each statement
+// after the first counts against the statement quota.
+func (e *Executor) executeScript(dbTools dbinterfaces.DatabaseInterface, statements []string, continueOnError bool) (string, error) {
+	connection := dbinterfaces.ConnectionName(dbTools)
+	script := ScriptResult{Statements: []StatementOutcome{}}
+	start := time.Now()
+
+	for i, stmt := range statements {
+		if i > 0 {
+			if err := e.quota.statement(); err != nil {
+				e.quotaRefusal(err)
+				script.Skipped = len(statements) - i
+				script.Note = err.Error()
+				break
+			}
+		}
+
+		began := time.Now()
+		result, err := dbTools.ExecuteSQL(stmt)
+		e.addToHistory(dbTools, stmt, result, err)
+		outcome := StatementOutcome{SQL: stmt, Duration: time.Since(began).String()}
+		if err == nil {
+			if quotaErr := e.quota.fetched(len(result.Rows)); quotaErr != nil {
+				e.quotaRefusal(quotaErr)
+				err = quotaErr
+			}
+		}
+		if err != nil {
+			outcome.Error = err.Error()
+			script.Statements = append(script.Statements, outcome)
+			script.Failed++
+			if !continueOnError {
+				script.Skipped = len(statements) - i - 1
+				if script.Skipped > 0 {
+					script.Note = fmt.Sprintf("stopped at statement %d of %d; statements before it stay applied, pass continueOnError to run the rest anyway", i+1, len(statements))
+				}
+				break
+			}
+			continue
+		}
+
+		if result.Duration != "" {
+			outcome.Duration = result.Duration
+		}
+		if outcome.Result, err = e.marshalTruncated(connection, result); err != nil {
+			return "", fmt.Errorf("failed to marshal SQL result: %w", err)
+		}
+		if result.StalenessWarning != "" {
+			e.notices = append(e.notices, "Result "+result.StalenessWarning)
+		}
+		script.Statements = append(script.Statements, outcome)
+		script.Succeeded++
+
+		e.lastSQL = stmt
+		e.recordQueried(connection, stmt)
+		e.mu.Lock()
+		e.executed = append(e.executed, stmt)
+		if len(result.Columns) > 0 {
+			e.lastResult = result
+			e.lastResultSQL = stmt
+			e.lastResultConnection = connection
+		}
+		e.mu.Unlock()
+	}
+
+	script.Duration = time.Since(start).String()
+	e.setLastDuration(script.Duration)
+	if script.Failed > 0 {
+		e.notices = append(e.notices, fmt.Sprintf("Script: %d of %d statements failed", script.Failed, len(statements)))
+	}
+
+	resultJSON, err := json.Marshal(script)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal script result: %w", err)
+	}
+	return string(resultJSON), nil
+}
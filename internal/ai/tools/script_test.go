@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_ExecuteSQL_Script(t *testing.T) {
+	db := openSQLite(t, "script.db")
+	seedOrders(t, db, 2)
+	e := NewExecutor(db)
+
+	script := `UPDATE orders SET customer = 'a;b' WHERE id = 1; SELECT customer FROM orders WHERE id = 1;
+INSERT INTO missing VALUES (1); DELETE FROM orders WHERE id = 2`
+	output, err := e.Execute(toolCall("execute_sql", `{"sql": `+quoteJSON(script)+`}`))
+	require.NoError(t, err)
+
+	var result ScriptResult
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	require.Len(t, result.Statements, 3, "the statement after the failure is not run")
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Contains(t, result.Note, "stopped at statement 3 of 4")
+	assert.Equal(t, "INSERT INTO missing VALUES (1)", result.Statements[2].SQL)
+	assert.Contains(t, result.Statements[2].Error, "missing")
+	assert.NotEmpty(t, result.Statements[0].Duration)
+
+	var selected models.QueryResult
+	require.NoError(t, json.Unmarshal(result.Statements[1].Result, &selected))
+	assert.Equal(t, []interface{}{"a;b"}, selected.Rows[0])
+	sql, _ := e.LastResultQuery()
+	assert.Equal(t, "SELECT customer FROM orders WHERE id = 1", sql)
+	assert.Equal(t, []string{"UPDATE orders SET customer = 'a;b' WHERE id = 1", "SELECT customer FROM orders WHERE id = 1"}, e.TakeExecutedSQL())
+	assert.Equal(t, "2", countRows(t, db, "orders"))
+	assert.Contains(t, e.TakeNotices(), "Script: 1 of 4 statements failed")
+
+	// With continueOnError the statements after the failure run too
+	output, err = e.Execute(toolCall("execute_sql", `{"sql": `+quoteJSON(script)+`, "continueOnError": true}`))
+	require.NoError(t, err)
+	result = ScriptResult{}
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Len(t, result.Statements, 4)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Zero(t, result.Skipped)
+	assert.Equal(t, "1", countRows(t, db, "orders"))
+}
+
+func TestExecutor_ExecuteSQL_ScriptQuota(t *testing.T) {
+	db := openSQLite(t, "script_quota.db")
+	seedOrders(t, db, 1)
+	e := NewExecutor(db)
+	e.SetQuotas(Quotas{StatementsPerMinute: 2})
+
+	output, err := e.Execute(toolCall("execute_sql", `{"sql": "SELECT 1; SELECT 2; SELECT 3"}`))
+	require.NoError(t, err)
+	var result ScriptResult
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Len(t, result.Statements, 2, "each statement counts against the quota")
+	assert.Equal(t, 1, result.Skipped)
+	assert.Contains(t, result.Note, "quota")
+}
+
+// quoteJSON returns text as a JSON string
+func quoteJSON(text string) string {
+	data, _ := json.Marshal(text)
+	return string(data)
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"strings"
+)
+
+// correctableSQLErrors are error fragments of mistakes the AI can fix by rewriting
+// the statement: syntax errors and unknown tables, columns or functions
+var correctableSQLErrors = []string{
+	// PostgreSQL
+	"syntax error",
+	"does not exist",
+	"is ambiguous",
+	"must appear in the group by clause",
+	"operator does not exist",
+	"invalid input syntax",
+	// MySQL
+	"error in your sql syntax",
+	"unknown column",
+	"unknown table",
+	"doesn't exist",
+	"in field list is ambiguous",
+	// SQLite
+	"no such column",
+	"no such table",
+	"no such function",
+	"ambiguous column name",
+	"incomplete input",
+}
+
+// IsCorrectableSQLError reports whether a failed statement could be fixed by
+// rewriting it, as opposed to errors such as lost connections or permissions
+func IsCorrectableSQLError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "permission denied") || strings.Contains(message, "access denied") {
+		return false
+	}
+	for _, fragment := range correctableSQLErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
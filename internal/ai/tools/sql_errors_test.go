@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCorrectableSQLError(t *testing.T) {
+	correctable := []string{
+		`pq: syntax error at or near "FORM"`,
+		`pq: column "nmae" does not exist`,
+		`Error 1054 (42S22): Unknown column 'nmae' in 'field list'`,
+		`Error 1064 (42000): You have an error in your SQL syntax`,
+		`no such table: userz`,
+	}
+	for _, message := range correctable {
+		assert.True(t, IsCorrectableSQLError(errors.New(message)), message)
+	}
+
+	notCorrectable := []string{
+		"dial tcp 127.0.0.1:5432: connect: connection refused",
+		`pq: permission denied for table users`,
+		"context deadline exceeded",
+	}
+	for _, message := range notCorrectable {
+		assert.False(t, IsCorrectableSQLError(errors.New(message)), message)
+	}
+	assert.False(t, IsCorrectableSQLError(nil))
+}
@@ -0,0 +1,305 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+)
+
+var (
+	uuidPattern         = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numberedPlaceholder = regexp.MustCompile(`\$\d+`)
+)
+
+// Layouts accepted for date and timestamp values
+var (
+	dateLayouts      = []string{"2006-01-02"}
+	timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"}
+	timeLayouts      = []string{"15:04:05.999999999", "15:04"}
+)
+
+// quoteIdentifier quotes a possibly schema-qualified identifier for the dialect
+func quoteIdentifier(dialect, name string) string {
+	quote := `"`
+	if dialect == "mysql" {
+		quote = "`"
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// placeholder returns the bind parameter marker for the n-th (1-based) parameter
+func placeholder(dialect string, n int) string {
+	if dialect == "postgresql" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// unqualifiedName returns the table name without its schema, as expected by GetTableSchema
+func unqualifiedName(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+// columnsByName indexes table columns by lower-cased name
+func columnsByName(columns []models.ColumnInfo) map[string]models.ColumnInfo {
+	byName := make(map[string]models.ColumnInfo, len(columns))
+	for _, col := range columns {
+		byName[strings.ToLower(col.ColumnName)] = col
+	}
+	return byName
+}
+
+// sortedKeys returns the keys of a value map in a stable order
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isAutoGenerated reports whether the database fills a column by itself, such as
+// serial, identity and auto-increment primary keys
+func isAutoGenerated(col models.ColumnInfo) bool {
+	if col.DefaultValue != nil {
+		def := strings.ToLower(*col.DefaultValue)
+		if strings.Contains(def, "nextval(") || strings.Contains(def, "auto_increment") {
+			return true
+		}
+	}
+	return col.IsPrimaryKey && isIntegerType(strings.ToLower(col.DataType))
+}
+
+func isIntegerType(dataType string) bool {
+	return strings.Contains(dataType, "int") || strings.Contains(dataType, "serial")
+}
+
+// coerceValue validates a value against a column's type and constraints and
+// converts it to a value that can be bound as a statement parameter
+func coerceValue(col models.ColumnInfo, value interface{}) (interface{}, error) {
+	if value == nil {
+		if strings.EqualFold(col.IsNullable, "NO") {
+			return nil, fmt.Errorf("column %s is NOT NULL", col.ColumnName)
+		}
+		return nil, nil
+	}
+
+	if len(col.EnumValues) > 0 {
+		text := fmt.Sprint(value)
+		for _, allowed := range col.EnumValues {
+			if text == allowed {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("column %s must be one of %s, got %q", col.ColumnName, strings.Join(col.EnumValues, ", "), text)
+	}
+
+	dataType := strings.ToLower(col.DataType)
+	switch {
+	case dataType == "boolean" || dataType == "bool":
+		return coerceBool(col, value)
+
+	case isIntegerType(dataType):
+		return coerceInteger(col, value)
+
+	case strings.Contains(dataType, "numeric") || strings.Contains(dataType, "decimal") ||
+		strings.Contains(dataType, "real") || strings.Contains(dataType, "double") ||
+		strings.Contains(dataType, "float") || dataType == "money":
+		return coerceNumber(col, value)
+
+	case strings.Contains(dataType, "json"):
+		if text, ok := value.(string); ok {
+			if !json.Valid([]byte(text)) {
+				return nil, fmt.Errorf("column %s expects JSON, got %q", col.ColumnName, text)
+			}
+			return text, nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.ColumnName, err)
+		}
+		return string(data), nil
+
+	case dataType == "uuid":
+		text, ok := value.(string)
+		if !ok || !uuidPattern.MatchString(text) {
+			return nil, fmt.Errorf("column %s expects a UUID, got %v", col.ColumnName, value)
+		}
+		return text, nil
+
+	case dataType == "date":
+		return coerceTime(col, value, dateLayouts)
+
+	case strings.HasPrefix(dataType, "timestamp") || dataType == "datetime":
+		return coerceTime(col, value, timestampLayouts)
+
+	case strings.HasPrefix(dataType, "time"):
+		return coerceTime(col, value, timeLayouts)
+
+	case strings.Contains(dataType, "char") || strings.Contains(dataType, "text") || dataType == "string":
+		text := stringValue(value)
+		if col.CharMaxLength != nil && *col.CharMaxLength > 0 && len([]rune(text)) > *col.CharMaxLength {
+			return nil, fmt.Errorf("column %s allows at most %d characters, got %d", col.ColumnName, *col.CharMaxLength, len([]rune(text)))
+		}
+		return text, nil
+	}
+
+	// Other types are passed through and checked by the database
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.ColumnName, err)
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+func coerceBool(col models.ColumnInfo, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		if b, err := strconv.ParseBool(strings.ToLower(v)); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("column %s expects a boolean, got %v", col.ColumnName, value)
+}
+
+func coerceInteger(col models.ColumnInfo, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), nil
+		}
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case bool:
+		// MySQL stores booleans as tinyint(1)
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("column %s expects an integer, got %v", col.ColumnName, value)
+}
+
+func coerceNumber(col models.ColumnInfo, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64, int, int64:
+		return v, nil
+	case string:
+		// Keep the text so decimals are not rounded through float64
+		if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return nil, fmt.Errorf("column %s expects a number, got %v", col.ColumnName, value)
+}
+
+func coerceTime(col models.ColumnInfo, value interface{}, layouts []string) (interface{}, error) {
+	text, ok := value.(string)
+	if ok {
+		for _, layout := range layouts {
+			if _, err := time.Parse(layout, text); err == nil {
+				return text, nil
+			}
+		}
+		// Expressions such as now() cannot be bound as parameters
+		if strings.HasSuffix(strings.TrimSpace(text), ")") {
+			return nil, fmt.Errorf("column %s: SQL expressions are not allowed as values, omit the column to use its default", col.ColumnName)
+		}
+	}
+	return nil, fmt.Errorf("column %s expects a %s value like %s, got %v", col.ColumnName, strings.ToLower(col.DataType), layouts[0], value)
+}
+
+// stringValue converts a JSON value to text for character columns
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatLiteral renders a bound parameter as a SQL literal. It is used only to
+// show statements to the user; execution always binds the parameters.
+func formatLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+// inlineParameters renders a parameterized statement with its parameters as literals, for display
+func inlineParameters(dialect, sql string, params []interface{}) string {
+	if dialect == "postgresql" {
+		return numberedPlaceholder.ReplaceAllStringFunc(sql, func(marker string) string {
+			n, _ := strconv.Atoi(marker[1:])
+			if n < 1 || n > len(params) {
+				return marker
+			}
+			return formatLiteral(params[n-1])
+		})
+	}
+
+	var b strings.Builder
+	next := 0
+	for _, ch := range sql {
+		if ch == '?' && next < len(params) {
+			b.WriteString(formatLiteral(params[next]))
+			next++
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+func (e *Executor) getTableStats(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok {
+		return "", fmt.Errorf("tableName argument is required and must be a string")
+	}
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	query, err := sqlanalysis.BuildTableStatsQuery(dialect, tableName)
+	if err != nil {
+		return "", err
+	}
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	stats, err := sqlanalysis.TableStatsFromResult(tableName, result)
+	if err != nil {
+		return "", err
+	}
+
+	if dialect == "postgresql" {
+		stats.Hypertable = hypertable(dbTools, tableName)
+		if stats.Hypertable != nil {
+			// The parent of a hypertable is empty; its data lives in the chunks
+			stats.TotalSize = stats.Hypertable.TotalSize
+			stats.Suggestions = stats.Hypertable.Suggestions(tableName)
+		}
+	}
+
+	resultJSON, err := json.Marshal(stats)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal table statistics: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// hypertable returns the TimescaleDB state of a table, or nil when TimescaleDB
+// is not installed or the table is not a hypertable
+func hypertable(dbTools dbinterfaces.DatabaseInterface, tableName string) *sqlanalysis.Hypertable {
+	installed, err := dbTools.ExecuteSQL(sqlanalysis.TimescaleInstalledQuery)
+	if err != nil || installed == nil || len(installed.Rows) == 0 || fmt.Sprintf("%v", installed.Rows[0][0]) == "0" {
+		return nil
+	}
+	result, err := dbTools.ExecuteSQL(sqlanalysis.BuildHypertableQuery(tableName))
+	if err != nil {
+		return nil
+	}
+	return sqlanalysis.HypertableFromResult(result)
+}
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_GetTableStats_Hypertable(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	mockDB.On("ExecuteSQL", sqlanalysis.TimescaleInstalledQuery).
+		Return(&models.QueryResult{Rows: [][]interface{}{{int64(1)}}}, nil)
+	mockDB.On("ExecuteSQL", sqlanalysis.BuildHypertableQuery("metrics")).
+		Return(&models.QueryResult{Rows: [][]interface{}{{int64(40), true, int64(30), "7 days", "", "time every 1 day", int64(4096)}}}, nil)
+	mockDB.On("ExecuteSQL", mock.MatchedBy(func(q string) bool { return q != sqlanalysis.TimescaleInstalledQuery })).
+		Return(&models.QueryResult{Rows: [][]interface{}{{int64(0), int64(8192), int64(0), int64(8192), int64(0), "", ""}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "get_table_stats",
+		Arguments: `{"tableName": "metrics"}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"chunks":40`)
+	assert.Contains(t, output, `"total_size":"4.0 kB"`)
+	assert.Contains(t, output, "add_retention_policy")
+	assert.NotContains(t, output, "add_compression_policy")
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+func (e *Executor) getTempUsage(dbTools dbinterfaces.DatabaseInterface) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	if dialect != "postgresql" {
+		return "", fmt.Errorf("temporary file analysis is not supported for %s databases", dialect)
+	}
+
+	result, err := dbTools.ExecuteSQL(sqlanalysis.TempUsageQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file statistics: %w", err)
+	}
+	usage, err := sqlanalysis.TempUsageFromResult(result)
+	if err != nil {
+		return "", err
+	}
+
+	// The spilling statements come from pg_stat_statements; without it, or
+	// without the privilege to read it, only the database-wide counters are
+	// reported and the suggestions say why
+	queries, err := dbTools.ExecuteSQL(sqlanalysis.BuildTempQueriesQuery(10))
+	if err != nil {
+		if capability, ok := sqlanalysis.MissingCapability(dialect, err); ok {
+			e.notices = append(e.notices, fmt.Sprintf("%s is not installed; to enable %s: %s", capability.Name, capability.Purpose, capability.Install))
+		} else if privilege, ok := e.missingPrivilege(dbTools, err); ok {
+			usage.MissingPrivilege = &privilege
+		}
+		queries = nil
+	} else if sqlanalysis.HiddenRows(queries, 0) > 0 {
+		if privilege, ok := sqlanalysis.StatsPrivilege(dialect); ok {
+			privilege = grantFor(dbTools, privilege)
+			e.markUnavailable(dbTools, privilege)
+			usage.MissingPrivilege = &privilege
+		}
+	}
+	usage.AddQueries(queries)
+
+	resultJSON, err := json.Marshal(usage)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal temporary file usage: %w", err)
+	}
+	return string(resultJSON), nil
+}
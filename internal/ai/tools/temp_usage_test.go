@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_GetTempUsage_WithoutPgStatStatements(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	mockDB.On("ExecuteSQL", sqlanalysis.TempUsageQuery).
+		Return(&models.QueryResult{Rows: [][]interface{}{{int64(3), int64(1 << 20), "", "4MB", "0"}}}, nil)
+	mockDB.On("ExecuteSQL", sqlanalysis.BuildTempQueriesQuery(10)).
+		Return((*models.QueryResult)(nil), errors.New(`pq: relation "pg_stat_statements" does not exist`))
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "get_temp_usage", Arguments: `{}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"temp_files":3`)
+	assert.Contains(t, output, `"spilling_queries":[]`)
+	require.Len(t, executor.TakeNotices(), 1)
+}
+
+func TestExecutor_GetTempUsage_Unsupported(t *testing.T) {
+	executor := NewExecutor(&MockDatabaseInterface{})
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "get_temp_usage", Arguments: `{}`}})
+	assert.Error(t, err)
+}
+
+func TestExecutor_GetTempUsage_PermissionDenied(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	mockDB.On("ExecuteSQL", sqlanalysis.TempUsageQuery).
+		Return(&models.QueryResult{Rows: [][]interface{}{{int64(3), int64(1 << 20), "", "4MB", "0"}}}, nil)
+	mockDB.On("ExecuteSQL", sqlanalysis.BuildTempQueriesQuery(10)).
+		Return((*models.QueryResult)(nil), errors.New("pq: permission denied for view pg_stat_statements"))
+	mockDB.On("ExecuteSQL", sqlanalysis.CurrentUserQuery("postgresql")).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"app"}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "get_temp_usage", Arguments: `{}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"missing_privilege":{"source":"pg_stat_statements"`)
+	assert.Contains(t, output, "GRANT SELECT ON pg_stat_statements TO app;")
+	assert.Len(t, executor.TakeNotices(), 1)
+
+	// list_features reports the source as unavailable
+	assert.Equal(t, "pg_stat_statements", executor.missingPrivileges(mockPostgres{mockDB})[0].Source)
+}
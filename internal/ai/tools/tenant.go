@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// SetTenant scopes every statement the tools run to a tenant, see
+// sqlanalysis.TenantPolicy. An empty tenant turns scoping off.
+func (e *Executor) SetTenant(tenant string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tenant = strings.TrimSpace(tenant)
+}
+
+// Tenant returns the active tenant, or "" when statements are not scoped
+func (e *Executor) Tenant() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.tenant
+}
+
+// tenantColumnChecker reports whether a table has a column, looking each table
+// up at most once
+func tenantColumnChecker(dbTools dbinterfaces.DatabaseInterface) func(table, column string) bool {
+	schemas := make(map[string][]models.ColumnInfo)
+	return func(table, column string) bool {
+		name := unqualifiedName(table)
+		columns, ok := schemas[name]
+		if !ok {
+			columns, _ = dbTools.GetTableSchema(name)
+			schemas[name] = columns
+		}
+		for _, col := range columns {
+			if strings.EqualFold(col.ColumnName, column) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// columnsChecker reports whether a column is one of columns
+func columnsChecker(columns []models.ColumnInfo) func(table, column string) bool {
+	byName := columnsByName(columns)
+	return func(_, column string) bool {
+		_, ok := byName[strings.ToLower(column)]
+		return ok
+	}
+}
+
+// tenantViolation checks a statement against the tenant policy and returns the
+// tool result reporting the problems, or "" when the statement may run
+func (e *Executor) tenantViolation(dbTools dbinterfaces.DatabaseInterface, sql string) (string, error) {
+	tenant := e.Tenant()
+	if tenant == "" {
+		return "", nil
+	}
+	policy, err := sqlanalysis.LoadTenantPolicy()
+	if err != nil {
+		return "", fmt.Errorf("tenant %s is active but the tenant policy cannot be read: %w", tenant, err)
+	}
+
+	problems := sqlanalysis.CheckTenantScope(sql, tenant, policy, tenantColumnChecker(dbTools))
+	if len(problems) == 0 {
+		return "", nil
+	}
+
+	e.notices = append(e.notices, fmt.Sprintf("Blocked a statement outside tenant %s: %s", tenant, strings.Join(problems, "; ")))
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"error":       fmt.Sprintf("the statement is not scoped to tenant %s, nothing was executed", tenant),
+		"tenant":      tenant,
+		"problems":    problems,
+		"instruction": "Add the tenant conditions listed in problems and call execute_sql again.",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tenant problems: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// scopeInsertValues sets the tenant column of an insert into a tenant table,
+// returning a problem when the values name another tenant
+func scopeInsertValues(tenant, table string, columns []models.ColumnInfo, values map[string]interface{}) ([]string, error) {
+	if tenant == "" {
+		return nil, nil
+	}
+	policy, err := sqlanalysis.LoadTenantPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s is active but the tenant policy cannot be read: %w", tenant, err)
+	}
+	rule, scoped := policy.Rule(table, columnsChecker(columns))
+	if !scoped || rule.Column == "" {
+		return nil, nil
+	}
+
+	value, given := lookupKey(values, rule.Column)
+	if !given {
+		values[rule.Column] = tenant
+		return nil, nil
+	}
+	if stringValue(value) != tenant {
+		return []string{fmt.Sprintf("%s must be %s in the current tenant context, got %v", rule.Column, tenant, value)}, nil
+	}
+	return nil, nil
+}
+
+// tenantScopeCondition returns the condition that limits an update of a table
+// to the tenant, or "" when the table is not scoped
+func tenantScopeCondition(dialect, tenant, table string, columns []models.ColumnInfo) (string, error) {
+	if tenant == "" {
+		return "", nil
+	}
+	policy, err := sqlanalysis.LoadTenantPolicy()
+	if err != nil {
+		return "", fmt.Errorf("tenant %s is active but the tenant policy cannot be read: %w", tenant, err)
+	}
+	rule, scoped := policy.Rule(table, columnsChecker(columns))
+	switch {
+	case !scoped:
+		return "", nil
+	case rule.Column != "":
+		return quoteIdentifier(dialect, rule.Column) + " = " + sqlanalysis.TenantLiteral(tenant), nil
+	default:
+		return rule.Condition("", tenant), nil
+	}
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var tenantOrdersColumns = append([]models.ColumnInfo{
+	{ColumnName: "tenant_id", DataType: "integer", IsNullable: "NO"},
+}, ordersColumns...)
+
+func newTenantExecutor(t *testing.T, mockDB *MockDatabaseInterface) *Executor {
+	t.Setenv("HOME", t.TempDir())
+	executor := NewExecutor(mockPostgres{mockDB})
+	executor.SetTenant("42")
+	return executor
+}
+
+func TestExecutor_ExecuteSQL_BlocksUnscopedQuery(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := newTenantExecutor(t, mockDB)
+	mockDB.On("GetTableSchema", "orders").Return(tenantOrdersColumns, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "execute_sql",
+		Arguments: `{"sql": "SELECT * FROM orders WHERE status = 'new'"}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "not scoped to tenant 42")
+	assert.Contains(t, output, "orders.tenant_id = 42")
+	assert.Len(t, executor.TakeNotices(), 1)
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+}
+
+func TestExecutor_ExecuteSQL_RunsScopedQuery(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := newTenantExecutor(t, mockDB)
+	query := "SELECT * FROM orders o WHERE o.tenant_id = 42"
+	mockDB.On("GetTableSchema", "orders").Return(tenantOrdersColumns, nil)
+	mockDB.On("ExecuteSQL", query).Return(&models.QueryResult{Columns: []string{"id"}}, nil)
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "execute_sql",
+		Arguments: `{"sql": "` + query + `"}`,
+	}})
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestExecutor_InsertRow_SetsTenant(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := newTenantExecutor(t, mockDB)
+	mockDB.On("GetTableSchema", "orders").Return(tenantOrdersColumns, nil)
+
+	preview, err := executor.PreviewStatement(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "insert_row",
+		Arguments: `{"tableName": "orders", "values": {"customer": "bob", "total": "5"}}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, preview, `("customer", "tenant_id", "total")`)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "insert_row",
+		Arguments: `{"tableName": "orders", "values": {"customer": "bob", "total": "5", "tenant_id": 7}}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "tenant_id must be 42")
+	mockDB.AssertNotCalled(t, "ExecuteSQLWithArgs", mock.Anything, mock.Anything)
+}
+
+func TestExecutor_UpdateRows_LimitedToTenant(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := newTenantExecutor(t, mockDB)
+	mockDB.On("GetTableSchema", "orders").Return(tenantOrdersColumns, nil)
+	mockDB.On("ExecuteExpectingRows", `UPDATE "orders" SET "status" = $1 WHERE "id" = $2 AND ("tenant_id" = 42)`, int64(1), []interface{}{"paid", int64(3)}).
+		Return(int64(1), nil)
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "update_rows",
+		Arguments: `{"tableName": "orders", "values": {"status": "paid"}, "keys": {"id": 3}}`,
+	}})
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestExecutor_FindDuplicateData_LimitedToTenant(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := newTenantExecutor(t, mockDB)
+	mockDB.On("GetTableSchema", "orders").Return(tenantOrdersColumns, nil)
+	mockDB.On("ExecuteSQL", `SELECT "customer", COUNT(*) AS duplicate_count FROM "orders" WHERE "tenant_id" = 42 GROUP BY "customer" HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT 100`).
+		Return(&models.QueryResult{Columns: []string{"customer", "duplicate_count"}, Rows: [][]interface{}{{"bob", 2}}, RowCount: 1}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "find_duplicate_data",
+		Arguments: `{"tableName": "orders", "columns": ["customer"]}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "bob")
+	mockDB.AssertNotCalled(t, "FindDuplicateData", mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dbsage/internal/generated"
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+const (
+	maxTestRows        = 500 // Rows one generate_test_data call inserts
+	testDataPreviewed  = 3   // INSERTs shown for confirmation
+	testDataCleanupTip = "Remove them with /cleanup generated, in this or a later session."
+)
+
+// TestDataReport is the outcome of inserting generated test rows
+type TestDataReport struct {
+	Table      string   `json:"table"`
+	Inserted   int      `json:"inserted"`
+	Tracked    int      `json:"tracked"`              // Rows recorded for /cleanup generated
+	Untracked  int      `json:"untracked,omitempty"`  // Rows whose generated key could not be read back
+	Scratchpad bool     `json:"scratchpad,omitempty"` // The rows went into a scratchpad and go with its rollback
+	Preview    []string `json:"preview"`
+	Error      string   `json:"error,omitempty"`
+	Note       string   `json:"note"`
+}
+
+// testDataBatch is the INSERT of each generated row
+type testDataBatch struct {
+	table      string
+	keys       []string // Primary-key columns
+	rows       []map[string]interface{}
+	statements []*InsertStatement
+}
+
+// preview returns the first INSERTs of the batch and how many follow
+func (b *testDataBatch) preview() []string {
+	var previews []string
+	for _, stmt := range b.statements[:min(len(b.statements), testDataPreviewed)] {
+		previews = append(previews, stmt.Preview)
+	}
+	if more := len(b.statements) - testDataPreviewed; more > 0 {
+		previews = append(previews, fmt.Sprintf("-- and %d more rows", more))
+	}
+	return previews
+}
+
+// buildTestDataFromArgs reads the generate_test_data arguments and builds an
+// INSERT per row from the table's current schema, limited to the tenant when
+// scoped. Problems name the row they were found in.
+func buildTestDataFromArgs(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}, tenant string) (*testDataBatch, []string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return nil, nil, fmt.Errorf("tableName argument is required and must be a string")
+	}
+	rows, ok := args["rows"].([]interface{})
+	if !ok || len(rows) == 0 {
+		return nil, nil, fmt.Errorf("rows argument is required and must be a non-empty array of objects")
+	}
+	if len(rows) > maxTestRows {
+		return nil, nil, fmt.Errorf("at most %d rows can be generated at once, got %d", maxTestRows, len(rows))
+	}
+
+	columns, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return nil, nil, err
+	}
+	batch := &testDataBatch{table: tableName}
+	for _, col := range columns {
+		if col.IsPrimaryKey {
+			batch.keys = append(batch.keys, col.ColumnName)
+		}
+	}
+	if len(columns) > 0 && len(batch.keys) == 0 {
+		return nil, nil, fmt.Errorf("%s has no primary key, so generated rows could not be found again to remove them", tableName)
+	}
+
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	var problems []string
+	for i, row := range rows {
+		values, ok := row.(map[string]interface{})
+		if !ok {
+			problems = append(problems, fmt.Sprintf("row %d: not an object of column values", i+1))
+			continue
+		}
+		rowProblems, err := scopeInsertValues(tenant, tableName, columns, values)
+		if err != nil {
+			return nil, nil, err
+		}
+		stmt, insertProblems := BuildInsert(dialect, tableName, columns, values)
+		for _, problem := range append(rowProblems, insertProblems...) {
+			problems = append(problems, fmt.Sprintf("row %d: %s", i+1, problem))
+		}
+		batch.rows = append(batch.rows, values)
+		batch.statements = append(batch.statements, stmt)
+	}
+	return batch, problems, nil
+}
+
+// generateTestData inserts rows of synthetic test data and records the primary
+// key of each, so /cleanup generated can delete them later. Rows inserted
+// before a failure stay inserted and recorded.
+func (e *Executor) generateTestData(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	batch, problems, err := buildTestDataFromArgs(dbTools, args, e.Tenant())
+	if err != nil {
+		return "", err
+	}
+	if len(problems) > 0 {
+		resultJSON, err := json.Marshal(map[string]interface{}{
+			"error":    "validation failed, nothing was inserted",
+			"problems": problems,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal validation problems: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+
+	connection := dbinterfaces.ConnectionName(dbTools)
+	report := TestDataReport{Table: batch.table, Preview: batch.preview()}
+	var recorded []generated.Row
+	for i, stmt := range batch.statements {
+		result, err := dbinterfaces.ExecuteSQLWithArgs(dbTools, stmt.SQL, stmt.Params...)
+		if err != nil {
+			report.Error = fmt.Sprintf("row %d: %v; the rows before it were inserted", i+1, err)
+			break
+		}
+		report.Inserted++
+		if result.Scratchpad {
+			report.Scratchpad = true
+			continue
+		}
+		key, ok := insertedKey(batch.keys, batch.rows[i], result)
+		if !ok {
+			report.Untracked++
+			continue
+		}
+		recorded = append(recorded, generated.Row{Connection: connection, Table: batch.table, Key: key})
+	}
+	if err := generated.Record(recorded); err != nil {
+		report.Untracked += len(recorded)
+		recorded = nil
+		e.notices = append(e.notices, fmt.Sprintf("Generated rows could not be recorded for /cleanup generated: %v", err))
+	}
+	report.Tracked = len(recorded)
+
+	switch {
+	case report.Scratchpad:
+		report.Note = "The rows were inserted in the open scratchpad and are removed when it is rolled back."
+	case report.Untracked > 0:
+		report.Note = fmt.Sprintf("%d rows could not be recorded because their generated primary key was not returned; give primary-key values to have rows recorded. %s", report.Untracked, testDataCleanupTip)
+	default:
+		report.Note = testDataCleanupTip
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal test data report: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// insertedKey returns the primary-key values of an inserted row, from the row
+// the INSERT returned or else from the values given
+func insertedKey(keys []string, values map[string]interface{}, result *models.QueryResult) (map[string]interface{}, bool) {
+	key := make(map[string]interface{}, len(keys))
+	for _, column := range keys {
+		if result != nil && len(result.Rows) == 1 {
+			for i, name := range result.Columns {
+				if strings.EqualFold(name, column) && i < len(result.Rows[0]) && result.Rows[0][i] != nil {
+					key[column] = result.Rows[0][i]
+				}
+			}
+		}
+		if _, ok := key[column]; ok {
+			continue
+		}
+		for name, value := range values {
+			if strings.EqualFold(name, column) && value != nil {
+				key[column] = value
+			}
+		}
+		if _, ok := key[column]; !ok {
+			return nil, false
+		}
+	}
+	return key, true
+}
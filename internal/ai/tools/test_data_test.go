@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dbsage/internal/generated"
+	"dbsage/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_GenerateTestData_RecordsKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	insert := `INSERT INTO "orders" ("customer", "total") VALUES ($1, $2) RETURNING *`
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+	mockDB.On("ExecuteSQLWithArgs", insert, []interface{}{"ann", "5"}).
+		Return(&models.QueryResult{Columns: []string{"id", "customer"}, Rows: [][]interface{}{{int64(41), "ann"}}}, nil)
+	mockDB.On("ExecuteSQLWithArgs", insert, []interface{}{"bob", "7"}).
+		Return(&models.QueryResult{Columns: []string{"id", "customer"}, Rows: [][]interface{}{{int64(42), "bob"}}}, nil)
+	mockDB.On("ExecuteSQLWithArgs", insert, []interface{}{"cy", "9"}).
+		Return((*models.QueryResult)(nil), errors.New("duplicate key value"))
+
+	call := openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "generate_test_data",
+		Arguments: `{"tableName": "orders", "rows": [{"customer": "ann", "total": "5"}, {"customer": "bob", "total": "7"}, {"customer": "cy", "total": "9"}, {"customer": "dee", "total": "1"}]}`,
+	}}
+	preview, err := executor.PreviewStatement(call)
+	require.NoError(t, err)
+	assert.Contains(t, preview, `VALUES ('ann', '5') RETURNING *`)
+	assert.Contains(t, preview, "-- and 1 more rows")
+
+	output, err := executor.Execute(call)
+	require.NoError(t, err)
+	var report TestDataReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, 2, report.Inserted)
+	assert.Equal(t, 2, report.Tracked)
+	assert.Contains(t, report.Error, "row 3: duplicate key value")
+	assert.Contains(t, report.Note, "/cleanup generated")
+
+	rows, err := generated.Load("")
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "orders", rows[0].Table)
+	assert.Equal(t, int64(41), rows[0].Key["id"])
+	assert.Equal(t, int64(42), rows[1].Key["id"])
+}
+
+func TestExecutor_GenerateTestData_InvalidRowsNotExecuted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "generate_test_data",
+		Arguments: `{"tableName": "orders", "rows": [{"customer": "ann", "total": "5"}, {"customer": "bob", "total": "lots"}]}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "validation failed, nothing was inserted")
+	assert.Contains(t, output, "row 2: ")
+	mockDB.AssertNotCalled(t, "ExecuteSQLWithArgs", mock.Anything, mock.Anything)
+}
+
+func TestExecutor_GenerateTestData_RequiresPrimaryKey(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	mockDB.On("GetTableSchema", "events").Return([]models.ColumnInfo{{ColumnName: "name", DataType: "text", IsNullable: "YES"}}, nil)
+
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "generate_test_data",
+		Arguments: `{"tableName": "events", "rows": [{"name": "x"}]}`,
+	}})
+	assert.ErrorContains(t, err, "no primary key")
+}
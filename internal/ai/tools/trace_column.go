@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// traceColumn follows a view column through the view definitions down to the
+// base table columns it is computed from
+func (e *Executor) traceColumn(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	target, _ := args["column"].(string)
+	target = strings.TrimSpace(target)
+	dot := strings.LastIndex(target, ".")
+	if dot <= 0 || dot == len(target)-1 {
+		return "", fmt.Errorf("column argument must name a view column as view.column")
+	}
+	view, column := target[:dot], strings.Trim(target[dot+1:], "\"`")
+
+	query, err := sqlanalysis.BuildViewDefinitionsQuery(dbinterfaces.GetDatabaseType(dbTools))
+	if err != nil {
+		return "", err
+	}
+	result, err := dbTools.ExecuteSQL(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read view definitions: %w", err)
+	}
+
+	columns := make(map[string][]string)
+	tableColumns := func(table string) []string {
+		if names, ok := columns[table]; ok {
+			return names
+		}
+		var names []string
+		if schema, err := dbTools.GetTableSchema(table); err == nil {
+			for _, col := range schema {
+				names = append(names, col.ColumnName)
+			}
+		}
+		columns[table] = names
+		return names
+	}
+
+	lineage, err := sqlanalysis.TraceColumn(sqlanalysis.ViewDefinitionsFromResult(result), view, column, tableColumns)
+	if err != nil {
+		return "", err
+	}
+	resultJSON, err := json.Marshal(lineage)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal column lineage: %w", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_TraceColumn(t *testing.T) {
+	db := openSQLite(t, "lineage.db")
+	seedOrders(t, db, 1)
+	_, err := db.ExecuteSQL("CREATE TABLE refunds (order_id INTEGER, amount NUMERIC)")
+	require.NoError(t, err)
+	_, err = db.ExecuteSQL(`CREATE VIEW net_orders AS
+		SELECT id, customer, total - COALESCE(amount, 0) AS net FROM orders LEFT JOIN refunds ON order_id = id`)
+	require.NoError(t, err)
+	e := NewExecutor(db)
+
+	result, err := e.Execute(toolCall("trace_column", `{"column": "net_orders.net"}`))
+	require.NoError(t, err)
+	var lineage sqlanalysis.ColumnLineage
+	require.NoError(t, json.Unmarshal([]byte(result), &lineage))
+	assert.Equal(t, []string{"orders.total", "refunds.amount"}, lineage.BaseColumns, "table columns attribute unqualified names")
+	assert.Empty(t, lineage.Warnings)
+
+	_, err = e.Execute(toolCall("trace_column", `{"column": "orders.total"}`))
+	assert.EqualError(t, err, "orders is not a view; views: net_orders")
+	_, err = e.Execute(toolCall("trace_column", `{"column": "net_orders"}`))
+	assert.ErrorContains(t, err, "view.column")
+}
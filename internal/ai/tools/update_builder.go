@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// trivialPredicate matches WHERE predicates that select every row
+var trivialPredicate = regexp.MustCompile(`^(?i)\(?\s*(true|1\s*=\s*1|'1'\s*=\s*'1'|1)\s*\)?$`)
+
+// UpdateStatement is a parameterized UPDATE that must affect an exact number of rows
+type UpdateStatement struct {
+	Table        string        `json:"table"`
+	SQL          string        `json:"sql"`
+	Params       []interface{} `json:"params"`
+	Preview      string        `json:"preview"` // SQL with the parameters inlined, for display only
+	ExpectedRows int64         `json:"expected_rows"`
+}
+
+// UpdateRequest describes the rows to update and how they are targeted: either
+// by primary-key values, or by an explicit WHERE predicate together with the
+// number of rows it is expected to affect
+type UpdateRequest struct {
+	Table        string
+	Values       map[string]interface{}
+	Keys         map[string]interface{}
+	Where        string
+	ExpectedRows int64  // Required with Where, must be 1 (or 0 for unset) with Keys
+	Scope        string // Condition always added to the target, such as the tenant predicate
+}
+
+// BuildUpdate validates an update request against the table's columns and
+// builds a parameterized UPDATE. Every problem found is returned; the
+// statement is nil when there are problems.
+func BuildUpdate(dialect string, columns []models.ColumnInfo, req UpdateRequest) (*UpdateStatement, []string) {
+	if len(columns) == 0 {
+		return nil, []string{fmt.Sprintf("table %s not found or has no columns", req.Table)}
+	}
+
+	byName := columnsByName(columns)
+	var problems []string
+	var assignments []string
+	var params []interface{}
+
+	if len(req.Values) == 0 {
+		problems = append(problems, "no values to set")
+	}
+	for _, key := range sortedKeys(req.Values) {
+		col, ok := byName[strings.ToLower(key)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown column %s", key))
+			continue
+		}
+		value, err := coerceValue(col, req.Values[key])
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		params = append(params, value)
+		assignments = append(assignments, fmt.Sprintf("%s = %s", quoteIdentifier(dialect, col.ColumnName), placeholder(dialect, len(params))))
+	}
+
+	where, expected, targetProblems := buildUpdateTarget(dialect, columns, byName, req, &params)
+	problems = append(problems, targetProblems...)
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	if req.Scope != "" {
+		where += " AND (" + req.Scope + ")"
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdentifier(dialect, req.Table), strings.Join(assignments, ", "), where)
+	return &UpdateStatement{
+		Table:        req.Table,
+		SQL:          sql,
+		Params:       params,
+		Preview:      fmt.Sprintf("-- expects %d row(s), rolled back otherwise\n%s", expected, inlineParameters(dialect, sql, params)),
+		ExpectedRows: expected,
+	}, nil
+}
+
+// buildUpdateTarget builds the WHERE clause of an update and the number of rows
+// it must affect, appending key values to params
+func buildUpdateTarget(dialect string, columns []models.ColumnInfo, byName map[string]models.ColumnInfo, req UpdateRequest, params *[]interface{}) (string, int64, []string) {
+	where := strings.TrimSpace(req.Where)
+
+	switch {
+	case len(req.Keys) > 0 && where != "":
+		return "", 0, []string{"give either keys or a where predicate, not both"}
+
+	case len(req.Keys) > 0:
+		var primaryKey []models.ColumnInfo
+		for _, col := range columns {
+			if col.IsPrimaryKey {
+				primaryKey = append(primaryKey, col)
+			}
+		}
+		if len(primaryKey) == 0 {
+			return "", 0, []string{fmt.Sprintf("table %s has no primary key, use a where predicate with expectedRows", req.Table)}
+		}
+		if req.ExpectedRows != 0 && req.ExpectedRows != 1 {
+			return "", 0, []string{"a primary key matches at most one row, expectedRows must be 1"}
+		}
+
+		var problems []string
+		for _, key := range sortedKeys(req.Keys) {
+			if col, ok := byName[strings.ToLower(key)]; !ok || !col.IsPrimaryKey {
+				problems = append(problems, fmt.Sprintf("%s is not a primary-key column", key))
+			}
+		}
+		var conditions []string
+		for _, col := range primaryKey {
+			value, ok := lookupKey(req.Keys, col.ColumnName)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("missing value for primary-key column %s", col.ColumnName))
+				continue
+			}
+			coerced, err := coerceValue(col, value)
+			if err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+			if coerced == nil {
+				problems = append(problems, fmt.Sprintf("primary-key column %s cannot be null", col.ColumnName))
+				continue
+			}
+			*params = append(*params, coerced)
+			conditions = append(conditions, fmt.Sprintf("%s = %s", quoteIdentifier(dialect, col.ColumnName), placeholder(dialect, len(*params))))
+		}
+		return strings.Join(conditions, " AND "), 1, problems
+
+	case where != "":
+		var problems []string
+		if strings.Contains(where, ";") {
+			problems = append(problems, "the where predicate must be a single condition without ';'")
+		}
+		if trivialPredicate.MatchString(where) {
+			problems = append(problems, "the where predicate matches every row")
+		}
+		if req.ExpectedRows < 1 {
+			problems = append(problems, "expectedRows is required with a where predicate and must be at least 1; count the rows with a SELECT first")
+		}
+		return "(" + where + ")", req.ExpectedRows, problems
+	}
+
+	return "", 0, []string{"give keys with the primary-key values, or a where predicate together with expectedRows"}
+}
+
+// lookupKey finds a key value by case-insensitive column name
+func lookupKey(keys map[string]interface{}, column string) (interface{}, bool) {
+	for key, value := range keys {
+		if strings.EqualFold(key, column) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// expectedRowsArg reads the expectedRows argument, which JSON decodes as a float
+func expectedRowsArg(args map[string]interface{}) (int64, error) {
+	raw, ok := args["expectedRows"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	n, ok := raw.(float64)
+	if !ok || n != math.Trunc(n) || n < 0 {
+		return 0, fmt.Errorf("expectedRows must be a non-negative integer")
+	}
+	return int64(n), nil
+}
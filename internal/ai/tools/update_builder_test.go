@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildUpdate_ByPrimaryKey(t *testing.T) {
+	stmt, problems := BuildUpdate("postgresql", ordersColumns, UpdateRequest{
+		Table:  "orders",
+		Values: map[string]interface{}{"status": "paid", "paid": true},
+		Keys:   map[string]interface{}{"id": float64(42)},
+	})
+	require.Empty(t, problems)
+
+	assert.Equal(t, `UPDATE "orders" SET "paid" = $1, "status" = $2 WHERE "id" = $3`, stmt.SQL)
+	assert.Equal(t, []interface{}{true, "paid", int64(42)}, stmt.Params)
+	assert.Equal(t, int64(1), stmt.ExpectedRows)
+	assert.Contains(t, stmt.Preview, `WHERE "id" = 42`)
+}
+
+func TestBuildUpdate_ByPredicate(t *testing.T) {
+	stmt, problems := BuildUpdate("mysql", ordersColumns, UpdateRequest{
+		Table:        "orders",
+		Values:       map[string]interface{}{"status": "shipped"},
+		Where:        "status = 'paid' AND placed_on < '2024-01-01'",
+		ExpectedRows: 3,
+	})
+	require.Empty(t, problems)
+	assert.Equal(t, "UPDATE `orders` SET `status` = ? WHERE (status = 'paid' AND placed_on < '2024-01-01')", stmt.SQL)
+	assert.Equal(t, int64(3), stmt.ExpectedRows)
+}
+
+func TestBuildUpdate_RejectsUntargetedUpdates(t *testing.T) {
+	values := map[string]interface{}{"status": "paid"}
+	tests := []struct {
+		name    string
+		req     UpdateRequest
+		problem string
+	}{
+		{"no target", UpdateRequest{Values: values}, "give keys with the primary-key values"},
+		{"no expected count", UpdateRequest{Values: values, Where: "total > 10"}, "expectedRows is required"},
+		{"every row", UpdateRequest{Values: values, Where: "1=1", ExpectedRows: 5}, "matches every row"},
+		{"stacked statement", UpdateRequest{Values: values, Where: "id = 1; DELETE FROM orders", ExpectedRows: 1}, "without ';'"},
+		{"non-key column", UpdateRequest{Values: values, Keys: map[string]interface{}{"customer": "bob"}}, "customer is not a primary-key column"},
+		{"both targets", UpdateRequest{Values: values, Keys: map[string]interface{}{"id": 1}, Where: "id = 1"}, "not both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Table = "orders"
+			stmt, problems := BuildUpdate("postgresql", ordersColumns, tt.req)
+			assert.Nil(t, stmt)
+			require.NotEmpty(t, problems)
+			assert.Contains(t, problems[len(problems)-1]+problems[0], tt.problem)
+		})
+	}
+}
+
+func TestExecutor_UpdateRows_RolledBackOnMismatch(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	db := mockPostgres{mockDB}
+	executor := NewExecutor(db)
+
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+	mockDB.On("ExecuteExpectingRows", `UPDATE "orders" SET "status" = $1 WHERE (status = 'new')`, int64(2), []interface{}{"paid"}).
+		Return(int64(7), &dbinterfaces.RowCountMismatchError{Expected: 2, Actual: 7})
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "update_rows",
+		Arguments: `{"tableName": "orders", "values": {"status": "paid"}, "where": "status = 'new'", "expectedRows": 2}`,
+	}})
+	require.NoError(t, err)
+
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Contains(t, result["error"], "rolled back")
+	assert.Equal(t, float64(7), result["actual_rows"])
+	mockDB.AssertExpectations(t)
+}
+
+func TestExecutor_UpdateRows_ByKey(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+
+	mockDB.On("GetTableSchema", "orders").Return(ordersColumns, nil)
+	mockDB.On("ExecuteExpectingRows", mock.Anything, int64(1), []interface{}{"paid", int64(3)}).Return(int64(1), nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "update_rows",
+		Arguments: `{"tableName": "orders", "values": {"status": "paid"}, "keys": {"id": 3}}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"affected_rows":1`)
+	mockDB.AssertExpectations(t)
+}
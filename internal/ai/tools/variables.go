@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Limits of the cached rows of a result variable sent with a prompt
+const (
+	MaxVariablePromptRows  = 50
+	MaxVariablePromptBytes = 8 * 1024
+)
+
+// ResultVariable is a query result saved under a name with /as, so later
+// prompts can refer to it as $name
+type ResultVariable struct {
+	Name       string
+	SQL        string // Statement the result came from
+	Connection string // Connection the statement ran on
+	Result     *models.QueryResult
+}
+
+// SaveResult saves the last query result run by execute_sql under a name,
+// replacing any variable of that name. Results of statements that write, such
+// as DELETE ... RETURNING, are refused: $name runs the saved SQL again.
+func (e *Executor) SaveResult(name string) (*ResultVariable, error) {
+	if !sqlanalysis.IsVariableName(name) {
+		return nil, fmt.Errorf("invalid name %q: use letters, digits and underscores, starting with a letter", name)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastResult == nil {
+		return nil, fmt.Errorf("nothing to save yet: ask a question that runs a query first")
+	}
+	if verb := sqlanalysis.FirstWrite(e.lastResultSQL); verb != "" {
+		return nil, fmt.Errorf("the last result came from a %s statement, which $%s would run again; save the result of a query that only reads", verb, strings.ToLower(name))
+	}
+	variable := &ResultVariable{
+		Name:       strings.ToLower(name),
+		SQL:        e.lastResultSQL,
+		Connection: e.lastResultConnection,
+		Result:     e.lastResult,
+	}
+	if e.variables == nil {
+		e.variables = make(map[string]*ResultVariable)
+	}
+	e.variables[variable.Name] = variable
+	return variable, nil
+}
+
+// Variables returns the saved result variables by name
+func (e *Executor) Variables() []*ResultVariable {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	variables := make([]*ResultVariable, 0, len(e.variables))
+	for _, variable := range e.variables {
+		variables = append(variables, variable)
+	}
+	sort.Slice(variables, func(i, j int) bool { return variables[i].Name < variables[j].Name })
+	return variables
+}
+
+// variable returns a saved result variable, or nil
+func (e *Executor) variable(name string) *ResultVariable {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.variables[strings.ToLower(name)]
+}
+
+// ExpandPrompt appends the result variables a prompt refers to as $name: their
+// cached rows, for answers the model can work out from them, and their SQL,
+// which execute_sql substitutes as a CTE for new queries
+func (e *Executor) ExpandPrompt(prompt string) string {
+	var sections []string
+	for _, name := range sqlanalysis.PromptVariables(prompt) {
+		if variable := e.variable(name); variable != nil {
+			sections = append(sections, variable.describe(e.maskResult(variable.Connection, variable.Result)))
+		}
+	}
+	if len(sections) == 0 {
+		return prompt
+	}
+	return prompt + "\n\nResult variables referenced above (saved with /as):\n" + strings.Join(sections, "\n") +
+		"\nAnswer from the cached rows when they hold everything needed. To query further, write $name where a table would go in execute_sql: dbsage runs the saved SQL in its place as a CTE."
+}
+
+// describe summarizes a variable for a prompt, with as many of the cached
+// rows as fit the prompt limits. cached is the variable's result as the AI
+// may see it, with sensitive values masked.
+func (v *ResultVariable) describe(cached *models.QueryResult) string {
+	rows := cached.Rows
+	if len(rows) > MaxVariablePromptRows {
+		rows = rows[:MaxVariablePromptRows]
+	}
+	data, _ := json.Marshal(rows)
+	for len(data) > MaxVariablePromptBytes && len(rows) > 1 {
+		rows = rows[:len(rows)/2]
+		data, _ = json.Marshal(rows)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "- $%s: %d rows of (%s) from connection '%s', saved from:\n  %s\n",
+		v.Name, len(v.Result.Rows), strings.Join(v.Result.Columns, ", "), v.Connection, strings.Join(strings.Fields(v.SQL), " "))
+	if len(rows) < len(v.Result.Rows) {
+		fmt.Fprintf(&b, "  First %d cached rows: %s", len(rows), data)
+	} else {
+		fmt.Fprintf(&b, "  Cached rows: %s", data)
+	}
+	if cached.Masked != "" {
+		fmt.Fprintf(&b, "\n  Masked values: %s", cached.Masked)
+	}
+	return b.String()
+}
+
+// InlineVariables returns a statement the way execute_sql runs it on the
+// current connection, with $name references replaced by the saved SQL, so a
+// confirmation shows what will actually run
+func (e *Executor) InlineVariables(sql string) string {
+	dbTools := e.currentTools()
+	if dbTools == nil {
+		return sql
+	}
+	if inlined, refusal := e.inlineVariables(dbTools, sql); refusal == "" {
+		return inlined
+	}
+	return sql
+}
+
+// inlineVariables replaces the $name references of a statement with CTEs of
+// the saved SQL. It returns a refusal instead when a variable was saved on
+// another connection, where its SQL would read different data.
+func (e *Executor) inlineVariables(dbTools dbinterfaces.DatabaseInterface, sql string) (string, string) {
+	e.mu.Lock()
+	variables := make(map[string]string, len(e.variables))
+	connections := make(map[string]string, len(e.variables))
+	for name, variable := range e.variables {
+		variables[name] = variable.SQL
+		connections[name] = variable.Connection
+	}
+	e.mu.Unlock()
+
+	inlined, used := sqlanalysis.InlineVariables(sql, variables)
+	current := dbinterfaces.ConnectionName(dbTools)
+	for _, name := range used {
+		if connections[name] != current {
+			refusal, _ := json.Marshal(map[string]string{
+				"error":       fmt.Sprintf("$%s was saved on connection '%s', but the current connection is '%s'", name, connections[name], current),
+				"instruction": fmt.Sprintf("Use the cached rows of $%s from the prompt, or ask the user to switch back to '%s' to query it.", name, connections[name]),
+			})
+			return "", string(refusal)
+		}
+	}
+	return inlined, ""
+}
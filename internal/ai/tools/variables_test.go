@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// namedMock is a mock database with a connection name
+type namedMock struct {
+	*MockDatabaseInterface
+	name string
+}
+
+func (m namedMock) ConnectionName() string { return m.name }
+
+func TestExecutor_ResultVariables(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	current := namedMock{mockDB, "main"}
+	executor := NewExecutorWithDynamicTools(func() dbinterfaces.DatabaseInterface { return current })
+
+	mockDB.On("ExecuteSQL", "SELECT month, total FROM revenue").
+		Return(&models.QueryResult{Columns: []string{"month", "total"}, Rows: [][]interface{}{{"2024-01", 120}}}, nil).Once()
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT month, total FROM revenue"}`}})
+	require.NoError(t, err)
+
+	_, err = executor.SaveResult("1st")
+	assert.Error(t, err, "names start with a letter")
+	variable, err := executor.SaveResult("Monthly_Revenue")
+	require.NoError(t, err)
+	assert.Equal(t, "monthly_revenue", variable.Name)
+	assert.Equal(t, "main", variable.Connection)
+
+	prompt := executor.ExpandPrompt("compare $monthly_revenue with last year")
+	assert.Contains(t, prompt, `Cached rows: [["2024-01",120]]`)
+	assert.Contains(t, prompt, "SELECT month, total FROM revenue")
+	assert.Equal(t, "no variables", executor.ExpandPrompt("no variables"))
+
+	// $name in SQL runs the saved statement as a CTE
+	mockDB.On("ExecuteSQL", "WITH monthly_revenue AS (SELECT month, total FROM revenue)\nSELECT sum(total) FROM monthly_revenue").
+		Return(&models.QueryResult{Columns: []string{"sum"}, Rows: [][]interface{}{{120}}}, nil).Once()
+	_, err = executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT sum(total) FROM $monthly_revenue"}`}})
+	require.NoError(t, err)
+
+	// but not on another connection
+	current = namedMock{mockDB, "staging"}
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT * FROM $monthly_revenue"}`}})
+	require.NoError(t, err)
+	assert.Contains(t, output, "was saved on connection 'main'")
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.MatchedBy(func(sql string) bool { return sql == "SELECT * FROM $monthly_revenue" }))
+}
+
+func TestExecutor_ResultVariablesRefuseWrites(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutorWithDynamicTools(func() dbinterfaces.DatabaseInterface { return namedMock{mockDB, "main"} })
+
+	mockDB.On("ExecuteSQL", "SELECT id FROM users").
+		Return(&models.QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}}, nil).Once()
+	_, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "SELECT id FROM users"}`}})
+	require.NoError(t, err)
+	_, err = executor.SaveResult("users")
+	require.NoError(t, err)
+	assert.Equal(t, "WITH users AS (SELECT id FROM users)\nSELECT * FROM users", executor.InlineVariables("SELECT * FROM $users"), "the confirmation shows the saved SQL")
+
+	mockDB.On("ExecuteSQL", "DELETE FROM sessions RETURNING id").
+		Return(&models.QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{7}}}, nil).Once()
+	_, err = executor.Execute(openai.ToolCall{Function: openai.FunctionCall{Name: "execute_sql", Arguments: `{"sql": "DELETE FROM sessions RETURNING id"}`}})
+	require.NoError(t, err)
+	_, err = executor.SaveResult("deleted")
+	assert.EqualError(t, err, "the last result came from a DELETE statement, which $deleted would run again; save the result of a query that only reads")
+	assert.Equal(t, "SELECT * FROM $deleted", executor.InlineVariables("SELECT * FROM $deleted"))
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Wait profiling limits
+const (
+	DefaultWaitSeconds    = 10
+	MaxWaitSeconds        = 60
+	DefaultWaitIntervalMs = 200
+	MinWaitIntervalMs     = 50
+)
+
+func (e *Executor) profileWaits(dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	query, err := sqlanalysis.BuildWaitSampleQuery(dialect)
+	if err != nil {
+		return "", err
+	}
+
+	seconds, ok := args["seconds"].(float64)
+	if !ok || seconds <= 0 {
+		seconds = DefaultWaitSeconds
+	}
+	seconds = math.Min(seconds, MaxWaitSeconds)
+	intervalMs, ok := args["intervalMs"].(float64)
+	if !ok || intervalMs < MinWaitIntervalMs {
+		intervalMs = DefaultWaitIntervalMs
+	}
+	interval := time.Duration(intervalMs) * time.Millisecond
+	samples := int(math.Ceil(seconds * 1000 / intervalMs))
+
+	profile := sqlanalysis.NewWaitProfile()
+	for i := 0; i < samples; i++ {
+		result, err := dbTools.ExecuteSQL(query)
+		if err != nil {
+			if missing := e.missingCapabilityResult(dbTools, err); missing != "" {
+				return missing, nil
+			}
+			if missing := e.missingPrivilegeResult(dbTools, err); missing != "" {
+				return missing, nil
+			}
+			return "", fmt.Errorf("failed to sample wait events: %w", err)
+		}
+		profile.AddSample(result)
+		if i < samples-1 {
+			time.Sleep(interval)
+		}
+	}
+	profile.Finish(10)
+
+	output := map[string]interface{}{"profile": profile}
+	switch {
+	case profile.HiddenSamples > 0:
+		// Sessions of other users were active, but their waits are hidden
+		if privilege, ok := sqlanalysis.StatsPrivilege(dialect); ok {
+			privilege = grantFor(dbTools, privilege)
+			e.markUnavailable(dbTools, privilege)
+			output["missing_privilege"] = privilege
+			output["note"] = fmt.Sprintf("%d session samples of other users were hidden and are missing from top_waits, which is incomplete. Say so, and that an administrator must run missing_privilege.grant for a complete profile.", profile.HiddenSamples)
+		}
+	case profile.ActiveSamples == 0:
+		output["note"] = "No other session was active while sampling. Run the slow operation while profile_waits is sampling."
+		if privilege, ok := sqlanalysis.StatsPrivilege(dialect); ok && dialect == "mysql" {
+			// Without PROCESS, performance_schema.threads lists only the user's own sessions
+			output["note"] = fmt.Sprintf("%s Without the PROCESS privilege only the sessions of the connected user are visible: %s", output["note"], grantFor(dbTools, privilege).Grant)
+		}
+	}
+	resultJSON, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal wait profile: %w", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_ProfileWaits(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	query, err := sqlanalysis.BuildWaitSampleQuery("postgresql")
+	require.NoError(t, err)
+	mockDB.On("ExecuteSQL", query).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"IO", "DataFileRead", "SELECT * FROM orders"}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "profile_waits",
+		Arguments: `{"seconds": 0.1, "intervalMs": 50}`,
+	}})
+	require.NoError(t, err)
+	mockDB.AssertNumberOfCalls(t, "ExecuteSQL", 2)
+	assert.Contains(t, output, `"event":"DataFileRead"`)
+	assert.Contains(t, output, `"percent":100`)
+	assert.NotContains(t, output, `"note"`)
+}
+
+func TestExecutor_ProfileWaits_HiddenSessions(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	executor := NewExecutor(mockPostgres{mockDB})
+	query, err := sqlanalysis.BuildWaitSampleQuery("postgresql")
+	require.NoError(t, err)
+	mockDB.On("ExecuteSQL", query).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"CPU", "CPU", sqlanalysis.InsufficientPrivilege}}}, nil)
+	mockDB.On("ExecuteSQL", sqlanalysis.CurrentUserQuery("postgresql")).
+		Return(&models.QueryResult{Rows: [][]interface{}{{"app"}}}, nil)
+
+	output, err := executor.Execute(openai.ToolCall{Function: openai.FunctionCall{
+		Name:      "profile_waits",
+		Arguments: `{"seconds": 0.05, "intervalMs": 50}`,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, output, `"hidden_session_samples":1`)
+	assert.Contains(t, output, "GRANT pg_read_all_stats TO app;")
+	assert.Contains(t, output, `"note"`)
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WatchPrefix starts the names of every object watch_table creates, so
+// leftovers of an interrupted watch are easy to find
+const WatchPrefix = "dbsage_watch_"
+
+// WatchPlan holds the statements that capture the changes of a table into a
+// log table with helper triggers, the query reading the log and the
+// statements removing everything again
+type WatchPlan struct {
+	LogTable string   `json:"log_table"`
+	Setup    []string `json:"setup"`
+	Poll     string   `json:"poll"`     // Parameters: last seen sequence number; reads seq, op, at, row_data
+	Count    string   `json:"count"`    // Parameters: last seen sequence number; counts the remaining changes
+	Teardown []string `json:"teardown"` // Safe to run more than once
+}
+
+// BuildWatchPlan builds the change capture statements for a table. The id
+// makes the object names unique; columns are needed on MySQL and SQLite,
+// where triggers cannot serialize a whole row. Each poll reads at most limit
+// changes.
+func BuildWatchPlan(dialect, table, id string, columns []string, limit int) (*WatchPlan, error) {
+	logTable := WatchPrefix + id
+	plan := &WatchPlan{LogTable: logTable}
+	quotedTable := quoteIdentifier(dialect, table)
+	quotedLog := quoteIdentifier(dialect, logTable)
+	plan.Poll = fmt.Sprintf("SELECT seq, op, at, row_data FROM %s WHERE seq > %s ORDER BY seq LIMIT %d", quotedLog, placeholder(dialect, 1), limit)
+	plan.Count = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE seq > %s", quotedLog, placeholder(dialect, 1))
+
+	switch dialect {
+	case "postgresql":
+		function := quoteIdentifier(dialect, logTable+"_fn")
+		trigger := quoteIdentifier(dialect, logTable)
+		plan.Setup = []string{
+			fmt.Sprintf("CREATE UNLOGGED TABLE %s (seq bigserial PRIMARY KEY, op text NOT NULL, at timestamptz NOT NULL DEFAULT clock_timestamp(), row_data jsonb)", quotedLog),
+			fmt.Sprintf(`CREATE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO %s (op, row_data) VALUES (TG_OP, to_jsonb(OLD));
+    RETURN OLD;
+  END IF;
+  INSERT INTO %s (op, row_data) VALUES (TG_OP, to_jsonb(NEW));
+  RETURN NEW;
+END
+$$`, function, quotedLog, quotedLog),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE PROCEDURE %s()", trigger, quotedTable, function),
+		}
+		plan.Teardown = []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger, quotedTable),
+			fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", function),
+			fmt.Sprintf("DROP TABLE IF EXISTS %s", quotedLog),
+		}
+		return plan, nil
+
+	case "mysql", "sqlite":
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("table %s not found or has no columns", table)
+		}
+		jsonObject := "JSON_OBJECT"
+		logDDL := fmt.Sprintf("CREATE TABLE %s (seq BIGINT AUTO_INCREMENT PRIMARY KEY, op VARCHAR(6) NOT NULL, at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6), row_data JSON)", quotedLog)
+		if dialect == "sqlite" {
+			jsonObject = "json_object"
+			logDDL = fmt.Sprintf("CREATE TABLE %s (seq INTEGER PRIMARY KEY AUTOINCREMENT, op TEXT NOT NULL, at TEXT NOT NULL DEFAULT (strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now')), row_data TEXT)", quotedLog)
+		}
+		plan.Setup = []string{logDDL}
+
+		for _, op := range []string{"INSERT", "UPDATE", "DELETE"} {
+			row := "NEW"
+			if op == "DELETE" {
+				row = "OLD"
+			}
+			pairs := make([]string, len(columns))
+			for i, col := range columns {
+				pairs[i] = fmt.Sprintf("%s, %s.%s", formatLiteral(col), row, quoteIdentifier(dialect, col))
+			}
+			insert := fmt.Sprintf("INSERT INTO %s (op, row_data) VALUES ('%s', %s(%s))", quotedLog, op, jsonObject, strings.Join(pairs, ", "))
+			trigger := quoteIdentifier(dialect, fmt.Sprintf("%s_%s", logTable, strings.ToLower(op)))
+
+			if dialect == "sqlite" {
+				plan.Setup = append(plan.Setup, fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s BEGIN\n  %s;\nEND", trigger, op, quotedTable, insert))
+			} else {
+				plan.Setup = append(plan.Setup, fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW %s", trigger, op, quotedTable, insert))
+			}
+			plan.Teardown = append(plan.Teardown, fmt.Sprintf("DROP TRIGGER IF EXISTS %s", trigger))
+		}
+		plan.Teardown = append(plan.Teardown, fmt.Sprintf("DROP TABLE IF EXISTS %s", quotedLog))
+		return plan, nil
+
+	default:
+		return nil, fmt.Errorf("watch_table is not supported for %s databases", dialect)
+	}
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWatchPlan(t *testing.T) {
+	columns := []string{"id", "status"}
+
+	plan, err := BuildWatchPlan("postgresql", "public.orders", "abc", columns, 50)
+	require.NoError(t, err)
+	assert.Equal(t, "dbsage_watch_abc", plan.LogTable)
+	require.Len(t, plan.Setup, 3)
+	assert.Contains(t, plan.Setup[0], "CREATE UNLOGGED TABLE")
+	assert.Contains(t, plan.Setup[1], "to_jsonb(OLD)")
+	assert.Contains(t, plan.Setup[2], `AFTER INSERT OR UPDATE OR DELETE ON "public"."orders"`)
+	assert.Equal(t, `SELECT seq, op, at, row_data FROM "dbsage_watch_abc" WHERE seq > $1 ORDER BY seq LIMIT 50`, plan.Poll)
+	assert.Equal(t, `DROP TRIGGER IF EXISTS "dbsage_watch_abc" ON "public"."orders"`, plan.Teardown[0])
+
+	plan, err = BuildWatchPlan("mysql", "orders", "abc", columns, 50)
+	require.NoError(t, err)
+	require.Len(t, plan.Setup, 4)
+	assert.Equal(t, "CREATE TRIGGER `dbsage_watch_abc_delete` AFTER DELETE ON `orders` FOR EACH ROW "+
+		"INSERT INTO `dbsage_watch_abc` (op, row_data) VALUES ('DELETE', JSON_OBJECT('id', OLD.`id`, 'status', OLD.`status`))", plan.Setup[3])
+	assert.Contains(t, plan.Poll, "WHERE seq > ?")
+	assert.Len(t, plan.Teardown, 4)
+	for _, stmt := range plan.Teardown {
+		assert.True(t, strings.Contains(stmt, "IF EXISTS"), stmt)
+	}
+
+	plan, err = BuildWatchPlan("sqlite", "orders", "abc", columns, 50)
+	require.NoError(t, err)
+	assert.Contains(t, plan.Setup[1], "BEGIN\n  INSERT INTO")
+	assert.Contains(t, plan.Setup[1], "json_object('id', NEW.")
+
+	_, err = BuildWatchPlan("sqlite", "missing", "abc", nil, 50)
+	assert.Error(t, err)
+	_, err = BuildWatchPlan("oracle", "orders", "abc", columns, 50)
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"dbsage/internal/masking"
+	"dbsage/internal/watches"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Table watching limits
+const (
+	defaultWatchSeconds    = 15
+	maxWatchSeconds        = 120
+	defaultWatchIntervalMs = 500
+	minWatchIntervalMs     = 200
+	defaultWatchEvents     = 100
+	maxWatchEvents         = 500
+
+	// watchRecordSlack is how long after its time a watch may still be
+	// tearing down before its record counts as abandoned
+	watchRecordSlack = time.Minute
+)
+
+// WatchEvent is one change captured by watch_table
+type WatchEvent struct {
+	Seq interface{}            `json:"seq"`
+	Op  string                 `json:"op"`
+	At  string                 `json:"at"`
+	Row map[string]interface{} `json:"row"`
+}
+
+// WatchReport is the result of watch_table
+type WatchReport struct {
+	Table    string         `json:"table"`
+	Seconds  float64        `json:"seconds"`
+	Stopped  string         `json:"stopped,omitempty"` // Why the watch ended before its time
+	Events   []WatchEvent   `json:"events"`
+	Counts   map[string]int `json:"counts"`              // Shown events by operation
+	NotShown int            `json:"not_shown,omitempty"` // Changes beyond maxEvents, counted but not listed
+	Masked   string         `json:"masked,omitempty"`    // Columns whose sensitive values were redacted
+	Teardown string         `json:"teardown"`
+	// Statements to run by hand when the helper objects could not be removed
+	TeardownStatements []string `json:"teardown_statements,omitempty"`
+}
+
+// watchTable captures the changes of a table until the time is up or ctx is
+// cancelled. The helper objects are recorded before they are created, so a
+// watch cut short by a crash is cleaned up when the connection is next opened.
+func (e *Executor) watchTable(ctx context.Context, dbTools dbinterfaces.DatabaseInterface, args map[string]interface{}) (string, error) {
+	tableName, ok := args["tableName"].(string)
+	if !ok || tableName == "" {
+		return "", fmt.Errorf("tableName argument is required and must be a string")
+	}
+	seconds, ok := args["seconds"].(float64)
+	if !ok || seconds <= 0 {
+		seconds = defaultWatchSeconds
+	}
+	seconds = math.Min(seconds, maxWatchSeconds)
+	intervalMs, ok := args["intervalMs"].(float64)
+	if !ok || intervalMs < minWatchIntervalMs {
+		intervalMs = defaultWatchIntervalMs
+	}
+	maxEvents := defaultWatchEvents
+	if n, ok := args["maxEvents"].(float64); ok && n >= 1 {
+		maxEvents = int(math.Min(n, maxWatchEvents))
+	}
+
+	columns, err := dbTools.GetTableSchema(unqualifiedName(tableName))
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return fmt.Sprintf(`{"error": "table %s not found"}`, tableName), nil
+	}
+	// The helper trigger captures the changes of every tenant
+	dialect := dbinterfaces.GetDatabaseType(dbTools)
+	if tenant := e.Tenant(); tenant != "" {
+		scope, err := tenantScopeCondition(dialect, tenant, tableName, columns)
+		if err != nil {
+			return "", err
+		}
+		if scope != "" {
+			resultJSON, _ := json.Marshal(map[string]interface{}{
+				"error":       fmt.Sprintf("watch_table cannot be limited to tenant %s: it would capture the changes of every tenant", tenant),
+				"tenant":      tenant,
+				"instruction": "Query the tenant's rows with execute_sql instead, or ask the user to clear the tenant context with /tenant clear",
+			})
+			return string(resultJSON), nil
+		}
+	}
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.ColumnName
+	}
+
+	id := strconv.FormatInt(time.Now().UnixNano(), 36)
+	plan, err := BuildWatchPlan(dialect, tableName, id, names, maxEvents)
+	if err != nil {
+		return "", err
+	}
+
+	report := &WatchReport{Table: tableName, Seconds: seconds, Events: []WatchEvent{}, Counts: map[string]int{}}
+	connection := dbinterfaces.ConnectionName(dbTools)
+	record := watches.Watch{
+		Connection: connection,
+		Table:      tableName,
+		LogTable:   plan.LogTable,
+		Teardown:   plan.Teardown,
+		Until:      time.Now().Add(time.Duration(seconds*float64(time.Second)) + watchRecordSlack),
+	}
+	if err := watches.Record(record); err != nil {
+		return "", fmt.Errorf("failed to record the watch for cleanup: %w", err)
+	}
+	for _, stmt := range plan.Setup {
+		if _, err := dbTools.ExecuteSQL(stmt); err != nil {
+			if teardownWatch(dbTools, plan, report) {
+				_ = watches.Forget(connection, plan.LogTable)
+			}
+			resultJSON, _ := json.Marshal(map[string]interface{}{
+				"error":       fmt.Sprintf("could not create the helper trigger: %v", err),
+				"instruction": "The connected role needs permission to create tables and triggers on this table. Ask the user to grant it or watch the table from another connection; nothing was left behind",
+				"teardown":    report.Teardown,
+			})
+			return string(resultJSON), nil
+		}
+	}
+
+	// The helper objects are removed whatever happens while watching
+	var last interface{} = 0
+	pollErr := func() error {
+		defer func() {
+			if teardownWatch(dbTools, plan, report) {
+				_ = watches.Forget(connection, plan.LogTable)
+			}
+		}()
+
+		// Changes are written on the primary, which a replica may not have yet
+		interval := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer interval.Stop()
+		start := time.Now()
+		deadline := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+		defer deadline.Stop()
+		for done := false; !done; {
+			select {
+			case <-ctx.Done():
+				done = true
+				report.Stopped = "the turn was cancelled"
+				report.Seconds = math.Round(time.Since(start).Seconds()*10) / 10
+			case <-deadline.C:
+				done = true
+			case <-interval.C:
+			}
+			if len(report.Events) < maxEvents {
+				result, err := dbinterfaces.ExecuteSQLOnPrimary(dbTools, plan.Poll, last)
+				if err != nil {
+					return fmt.Errorf("failed to read captured changes: %w", err)
+				}
+				for _, row := range result.Rows {
+					if len(row) < 4 || len(report.Events) == maxEvents {
+						break
+					}
+					event := WatchEvent{Seq: row[0], Op: fmt.Sprint(row[1]), At: fmt.Sprint(row[2])}
+					if text, ok := row[3].(string); ok {
+						_ = json.Unmarshal([]byte(text), &event.Row)
+					}
+					report.Events = append(report.Events, event)
+					report.Counts[event.Op]++
+					last = row[0]
+				}
+			}
+		}
+
+		result, err := dbinterfaces.ExecuteSQLOnPrimary(dbTools, plan.Count, last)
+		if err == nil && len(result.Rows) > 0 && len(result.Rows[0]) > 0 {
+			report.NotShown, _ = strconv.Atoi(fmt.Sprint(result.Rows[0][0]))
+		}
+		return nil
+	}()
+	if pollErr != nil {
+		return "", pollErr
+	}
+
+	settings := e.maskingSettings(dbinterfaces.ConnectionName(dbTools))
+	var findings []masking.Finding
+	for _, event := range report.Events {
+		findings = append(findings, settings.MaskRow(event.Row)...)
+	}
+	if findings = masking.Merge(findings); len(findings) > 0 {
+		report.Masked = masking.Describe(findings)
+		e.noteMasked(findings)
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal watch report: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// teardownWatch removes the helper trigger and log table, recording any
+// statement that failed so the user can run it by hand. It reports whether
+// everything was removed.
+func teardownWatch(dbTools dbinterfaces.DatabaseInterface, plan *WatchPlan, report *WatchReport) bool {
+	var failed []string
+	var firstErr error
+	for _, stmt := range plan.Teardown {
+		if _, err := dbTools.ExecuteSQL(stmt); err != nil {
+			failed = append(failed, stmt)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		report.Teardown = fmt.Sprintf("could not remove the helper objects: %v", firstErr)
+		report.TeardownStatements = failed
+		return false
+	}
+	report.Teardown = "helper trigger and log table removed"
+	return true
+}
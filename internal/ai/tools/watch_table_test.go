@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"dbsage/internal/watches"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchTable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openSQLite(t, "watch.db")
+	seedOrders(t, db, 2)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		db.ExecuteSQL("INSERT INTO orders (id, customer, total) VALUES (3, 'c3', 4.5)")
+		db.ExecuteSQL("UPDATE orders SET total = 9 WHERE id = 1")
+		db.ExecuteSQL("DELETE FROM orders WHERE id = 2")
+		for i := 10; i < 15; i++ {
+			db.ExecuteSQL(fmt.Sprintf("INSERT INTO orders (id, customer) VALUES (%d, 'bulk')", i))
+		}
+	}()
+
+	e := NewExecutor(db)
+	result, err := e.watchTable(context.Background(), db, map[string]interface{}{"tableName": "orders", "seconds": 1.0, "intervalMs": 200.0, "maxEvents": 5.0})
+	require.NoError(t, err)
+
+	var report WatchReport
+	require.NoError(t, json.Unmarshal([]byte(result), &report))
+	require.Len(t, report.Events, 5)
+	assert.Equal(t, "INSERT", report.Events[0].Op)
+	assert.Equal(t, "c3", report.Events[0].Row["customer"])
+	assert.Equal(t, "UPDATE", report.Events[1].Op)
+	assert.EqualValues(t, 9, report.Events[1].Row["total"])
+	assert.Equal(t, "DELETE", report.Events[2].Op)
+	assert.Equal(t, "c2", report.Events[2].Row["customer"])
+	assert.Equal(t, map[string]int{"INSERT": 3, "UPDATE": 1, "DELETE": 1}, report.Counts)
+	assert.Equal(t, 3, report.NotShown)
+	assert.Equal(t, "helper trigger and log table removed", report.Teardown)
+
+	leftovers, err := db.ExecuteSQL("SELECT name FROM sqlite_master WHERE name LIKE 'dbsage_watch_%'")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers.Rows)
+	abandoned, err := watches.Abandoned("", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, abandoned, "the cleanup record is removed with the objects")
+}
+
+func TestWatchTableStopsWhenCancelled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openSQLite(t, "watch.db")
+	seedOrders(t, db, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		db.ExecuteSQL("DELETE FROM orders WHERE id = 1")
+		cancel()
+	}()
+
+	start := time.Now()
+	result, err := NewExecutor(db).watchTable(ctx, db, map[string]interface{}{"tableName": "orders", "seconds": 60.0})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	var report WatchReport
+	require.NoError(t, json.Unmarshal([]byte(result), &report))
+	assert.Equal(t, "the turn was cancelled", report.Stopped)
+	assert.Equal(t, map[string]int{"DELETE": 1}, report.Counts, "changes up to the cancel are read")
+	assert.Equal(t, "helper trigger and log table removed", report.Teardown)
+
+	leftovers, err := db.ExecuteSQL("SELECT name FROM sqlite_master WHERE name LIKE 'dbsage_watch_%'")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers.Rows)
+}
+
+func TestWatchTableMissingTable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openSQLite(t, "watch.db")
+
+	result, err := NewExecutor(db).watchTable(context.Background(), db, map[string]interface{}{"tableName": "missing"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "not found")
+}
+
+func TestWatchTableRefusedForTenant(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openSQLite(t, "watch.db")
+	_, err := db.ExecuteSQL("CREATE TABLE orders (id INTEGER PRIMARY KEY, tenant_id INTEGER NOT NULL)")
+	require.NoError(t, err)
+	e := NewExecutor(db)
+	e.SetTenant("42")
+
+	result, err := e.watchTable(context.Background(), db, map[string]interface{}{"tableName": "orders", "seconds": 1.0})
+	require.NoError(t, err)
+	assert.Contains(t, result, "cannot be limited to tenant 42")
+
+	leftovers, err := db.ExecuteSQL("SELECT name FROM sqlite_master WHERE name LIKE 'dbsage_watch_%'")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers.Rows, "no trigger was created")
+}
@@ -0,0 +1,157 @@
+// Package bookmarks keeps AI answers and results the user wants to come back
+// to, with the question, SQL and connection needed to re-run them.
+package bookmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bookmark is a saved answer or result
+type Bookmark struct {
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Note       string    `json:"note"`
+	Connection string    `json:"connection,omitempty"` // Connection the SQL ran on
+	Question   string    `json:"question,omitempty"`
+	SQL        []string  `json:"sql,omitempty"` // Statements executed for the answer, in order
+	Answer     string    `json:"answer,omitempty"`
+}
+
+var mu sync.Mutex
+
+// bookmarksFile returns the path of the bookmarks file
+func bookmarksFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "bookmarks.json")
+}
+
+// Load reads the bookmarks, oldest first. A missing file means no bookmarks.
+func Load() ([]Bookmark, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+func load() ([]Bookmark, error) {
+	data, err := os.ReadFile(bookmarksFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+	var bookmarks []Bookmark
+	if err := json.Unmarshal(data, &bookmarks); err != nil {
+		return nil, fmt.Errorf("failed to parse bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+func save(bookmarks []Bookmark) error {
+	path := bookmarksFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Add saves a bookmark, assigning its ID and time
+func Add(b Bookmark) (Bookmark, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bookmarks, err := load()
+	if err != nil {
+		return b, err
+	}
+	b.ID = 1
+	for _, existing := range bookmarks {
+		if existing.ID >= b.ID {
+			b.ID = existing.ID + 1
+		}
+	}
+	if b.Time.IsZero() {
+		b.Time = time.Now()
+	}
+	return b, save(append(bookmarks, b))
+}
+
+// Get returns the bookmark with an ID
+func Get(id int) (Bookmark, error) {
+	bookmarks, err := Load()
+	if err != nil {
+		return Bookmark{}, err
+	}
+	for _, b := range bookmarks {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return Bookmark{}, fmt.Errorf("no bookmark %d", id)
+}
+
+// Remove deletes the bookmark with an ID
+func Remove(id int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bookmarks, err := load()
+	if err != nil {
+		return err
+	}
+	for i, b := range bookmarks {
+		if b.ID == id {
+			return save(append(bookmarks[:i], bookmarks[i+1:]...))
+		}
+	}
+	return fmt.Errorf("no bookmark %d", id)
+}
+
+// Markdown renders bookmarks as a markdown document for sharing the findings
+// of an investigation
+func Markdown(bookmarks []Bookmark) string {
+	var b strings.Builder
+	b.WriteString("# Bookmarks\n")
+	for _, bm := range bookmarks {
+		b.WriteString(fmt.Sprintf("\n## %d. %s\n\n", bm.ID, bm.Note))
+		b.WriteString(fmt.Sprintf("_%s", bm.Time.Format("2006-01-02 15:04")))
+		if bm.Connection != "" {
+			b.WriteString(" on " + bm.Connection)
+		}
+		b.WriteString("_\n")
+		if bm.Question != "" {
+			b.WriteString(fmt.Sprintf("\n**Question:** %s\n", bm.Question))
+		}
+		if len(bm.SQL) > 0 {
+			b.WriteString("\n```sql\n" + strings.Join(bm.SQL, ";\n\n") + ";\n```\n")
+		}
+		if bm.Answer != "" {
+			b.WriteString("\n" + bm.Answer + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Export writes bookmarks to a file: JSON for a .json extension, markdown otherwise
+func Export(path string, bookmarks []Bookmark) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		if data, err = json.MarshalIndent(bookmarks, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		data = []byte(Markdown(bookmarks))
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,61 @@
+package bookmarks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddGetRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	list, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	first, err := Add(Bookmark{Note: "slow checkout", Connection: "prod", SQL: []string{"SELECT 1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	assert.False(t, first.Time.IsZero())
+	second, err := Add(Bookmark{Note: "orphans"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+
+	got, err := Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", got.Connection)
+	assert.Equal(t, []string{"SELECT 1"}, got.SQL)
+
+	require.NoError(t, Remove(1))
+	assert.Error(t, Remove(1))
+	_, err = Get(1)
+	assert.Error(t, err)
+
+	third, err := Add(Bookmark{Note: "after removal"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, third.ID, "ids are not reused while later ones exist")
+}
+
+func TestExport(t *testing.T) {
+	list := []Bookmark{{ID: 4, Note: "dupes", Connection: "staging", Question: "Which emails are duplicated?",
+		SQL: []string{"SELECT email FROM users GROUP BY email HAVING COUNT(*) > 1"}, Answer: "Three emails are duplicated."}}
+	dir := t.TempDir()
+
+	require.NoError(t, Export(filepath.Join(dir, "b.md"), list))
+	data, err := os.ReadFile(filepath.Join(dir, "b.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "## 4. dupes")
+	assert.Contains(t, string(data), " on staging_")
+	assert.Contains(t, string(data), "```sql\nSELECT email FROM users GROUP BY email HAVING COUNT(*) > 1;\n```")
+
+	require.NoError(t, Export(filepath.Join(dir, "b.json"), list))
+	data, err = os.ReadFile(filepath.Join(dir, "b.json"))
+	require.NoError(t, err)
+	var decoded []Bookmark
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, list[0].SQL, decoded[0].SQL)
+}
@@ -0,0 +1,241 @@
+// Package catalog indexes the tables of a database in the background, one
+// schema at a time, so that features listing tables do not block on databases
+// with thousands of them.
+package catalog
+
+import (
+	"fmt"
+	"sync"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Progress describes how far indexing has got
+type Progress struct {
+	Loaded  int    // Schemas whose tables are loaded
+	Total   int    // Schemas found, 0 until they are listed
+	Loading string // Schema being loaded, empty when idle
+	Tables  int    // Tables indexed so far
+	Done    bool
+	Failed  int    // Schemas whose tables could not be loaded
+	Err     string // First load error
+}
+
+// String returns a short placeholder-style description of the progress
+func (p Progress) String() string {
+	switch {
+	case p.Done && p.Failed > 0:
+		return fmt.Sprintf("%d tables, %d of %d schemas failed to load: %s", p.Tables, p.Failed, p.Total, p.Err)
+	case p.Done && p.Total > 1:
+		return fmt.Sprintf("%d tables in %d schemas", p.Tables, p.Total)
+	case p.Done:
+		return fmt.Sprintf("%d tables", p.Tables)
+	case p.Total <= 1:
+		return "loading tables..."
+	case p.Loading != "":
+		return fmt.Sprintf("indexing %d of %d schemas (loading %s)...", p.Loaded, p.Total, p.Loading)
+	default:
+		return fmt.Sprintf("indexing %d of %d schemas...", p.Loaded, p.Total)
+	}
+}
+
+// Catalog holds the tables of one connection, filled in by a background indexer
+type Catalog struct {
+	db dbinterfaces.DatabaseInterface
+
+	mu       sync.Mutex
+	schemas  []string // Indexing order
+	tables   map[string][]models.TableInfo
+	loaded   map[string]bool
+	failed   map[string]error
+	loading  string
+	priority chan string
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New returns a catalog for db; call Start to begin indexing
+func New(db dbinterfaces.DatabaseInterface) *Catalog {
+	return &Catalog{
+		db:       db,
+		tables:   make(map[string][]models.TableInfo),
+		loaded:   make(map[string]bool),
+		failed:   make(map[string]error),
+		priority: make(chan string, 16),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start indexes the catalog in a background goroutine
+func (c *Catalog) Start() {
+	go c.run()
+}
+
+// Stop abandons indexing after the schema being loaded
+func (c *Catalog) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+}
+
+// Done is closed when every schema has been indexed or indexing was stopped
+func (c *Catalog) Done() <-chan struct{} {
+	return c.done
+}
+
+// Request moves a schema to the front of the queue, for when the user refers
+// to a schema that has not been indexed yet
+func (c *Catalog) Request(schema string) {
+	select {
+	case c.priority <- schema:
+	default:
+	}
+}
+
+// Tables returns the tables indexed so far, in schema order, and the progress
+func (c *Catalog) Tables() ([]models.TableInfo, Progress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var tables []models.TableInfo
+	for _, schema := range c.schemas {
+		tables = append(tables, c.tables[schema]...)
+	}
+	return tables, c.progressLocked()
+}
+
+// Progress returns how far indexing has got
+func (c *Catalog) Progress() Progress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progressLocked()
+}
+
+func (c *Catalog) progressLocked() Progress {
+	progress := Progress{Loaded: len(c.loaded), Total: len(c.schemas), Loading: c.loading}
+	for _, schema := range c.schemas {
+		progress.Tables += len(c.tables[schema])
+		if err, ok := c.failed[schema]; ok {
+			if progress.Failed == 0 {
+				progress.Err = err.Error()
+			}
+			progress.Failed++
+		}
+	}
+	select {
+	case <-c.done:
+		progress.Done = true
+	default:
+	}
+	return progress
+}
+
+func (c *Catalog) run() {
+	defer close(c.done)
+
+	// On remote connections one query for every table beats a round trip per schema
+	provider, ok := c.db.(dbinterfaces.SchemaCatalogProvider)
+	if ok && dbinterfaces.IsRemote(c.db) {
+		ok = false
+	}
+	var schemas []string
+	var err error
+	if ok {
+		schemas, err = provider.ListSchemas()
+	}
+	if !ok || err != nil || len(schemas) == 0 {
+		// Databases without schemas (or that cannot list them) are indexed in one go
+		c.loadAll()
+		return
+	}
+
+	c.mu.Lock()
+	c.schemas = schemas
+	c.mu.Unlock()
+
+	for {
+		schema, ok := c.next()
+		if !ok {
+			return
+		}
+		tables, err := provider.GetTablesInSchema(schema)
+		c.finish(schema, tables, err)
+	}
+}
+
+// loadAll indexes every table with a single GetAllTables call
+func (c *Catalog) loadAll() {
+	c.mu.Lock()
+	c.schemas = []string{""}
+	c.mu.Unlock()
+
+	tables, err := c.db.GetAllTables()
+	c.finish("", tables, err)
+}
+
+// next picks the schema to load next: a requested one, otherwise the first
+// pending one in order. It reports false when nothing is left or indexing was
+// stopped.
+func (c *Catalog) next() (string, bool) {
+	for {
+		select {
+		case <-c.stop:
+			return "", false
+		case schema := <-c.priority:
+			c.mu.Lock()
+			pending := c.isPendingLocked(schema)
+			if pending {
+				c.loading = schema
+			}
+			c.mu.Unlock()
+			if pending {
+				return schema, true
+			}
+			continue
+		default:
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, schema := range c.schemas {
+			if c.isPendingLocked(schema) {
+				c.loading = schema
+				return schema, true
+			}
+		}
+		return "", false
+	}
+}
+
+func (c *Catalog) isPendingLocked(schema string) bool {
+	if c.loaded[schema] {
+		return false
+	}
+	if _, failed := c.failed[schema]; failed {
+		return false
+	}
+	for _, known := range c.schemas {
+		if known == schema {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Catalog) finish(schema string, tables []models.TableInfo, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading = ""
+	if err != nil {
+		c.failed[schema] = err
+		return
+	}
+	c.tables[schema] = tables
+	c.loaded[schema] = true
+}
@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB lists schemas and records the order they were loaded in. Loading
+// blocks on gate when it is set.
+type fakeDB struct {
+	schemas map[string][]string
+	order   []string
+	gate    chan struct{}
+	mu      sync.Mutex
+	loaded  []string
+	failing string
+}
+
+func (f *fakeDB) Close() error                                       { return nil }
+func (f *fakeDB) IsConnectionHealthy() bool                          { return true }
+func (f *fakeDB) CheckConnection() error                             { return nil }
+func (f *fakeDB) ExecuteSQL(string) (*models.QueryResult, error)     { return nil, nil }
+func (f *fakeDB) ExplainQuery(string) (*models.QueryResult, error)   { return nil, nil }
+func (f *fakeDB) GetTableSchema(string) ([]models.ColumnInfo, error) { return nil, nil }
+func (f *fakeDB) GetTableIndexes(string) ([]models.IndexInfo, error) { return nil, nil }
+func (f *fakeDB) FindDuplicateData(string, []string) (*models.QueryResult, error) {
+	return nil, nil
+}
+
+func (f *fakeDB) GetAllTables() ([]models.TableInfo, error) {
+	var tables []models.TableInfo
+	for _, schema := range f.order {
+		t, _ := f.GetTablesInSchema(schema)
+		tables = append(tables, t...)
+	}
+	return tables, nil
+}
+
+func (f *fakeDB) ListSchemas() ([]string, error) { return f.order, nil }
+
+func (f *fakeDB) GetTablesInSchema(schema string) ([]models.TableInfo, error) {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	f.loaded = append(f.loaded, schema)
+	f.mu.Unlock()
+	if schema == f.failing {
+		return nil, fmt.Errorf("permission denied for schema %s", schema)
+	}
+	var tables []models.TableInfo
+	for _, name := range f.schemas[schema] {
+		tables = append(tables, models.TableInfo{TableName: name, Schema: schema})
+	}
+	return tables, nil
+}
+
+// tablesOnly hides ListSchemas, like SQLite
+type tablesOnly struct{ *fakeDB }
+
+func (t tablesOnly) ListSchemas() {}
+
+// remoteDB reports itself as a high-latency connection
+type remoteDB struct{ *fakeDB }
+
+func (remoteDB) IsRemote() bool { return true }
+
+func waitDone(t *testing.T, c *Catalog) {
+	t.Helper()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("indexing did not finish")
+	}
+}
+
+func TestCatalog_IndexesSchemasInOrder(t *testing.T) {
+	db := &fakeDB{
+		order:   []string{"public", "sales", "audit"},
+		schemas: map[string][]string{"public": {"users"}, "sales": {"orders", "invoices"}, "audit": {"log"}},
+		failing: "audit",
+	}
+	c := New(db)
+	c.Start()
+	waitDone(t, c)
+
+	tables, progress := c.Tables()
+	require.Len(t, tables, 3)
+	assert.Equal(t, "users", tables[0].TableName)
+	assert.Equal(t, "sales", tables[1].Schema)
+	assert.Equal(t, []string{"public", "sales", "audit"}, db.loaded)
+	assert.Equal(t, Progress{Loaded: 2, Total: 3, Tables: 3, Done: true, Failed: 1, Err: "permission denied for schema audit"}, progress)
+	assert.Equal(t, "3 tables, 1 of 3 schemas failed to load: permission denied for schema audit", progress.String())
+}
+
+func TestCatalog_RequestedSchemaLoadsFirst(t *testing.T) {
+	db := &fakeDB{
+		order:   []string{"a", "b", "c", "d"},
+		schemas: map[string][]string{"a": {"t1"}, "b": {"t2"}, "c": {"t3"}, "d": {"t4"}},
+		gate:    make(chan struct{}),
+	}
+	c := New(db)
+	c.Start()
+
+	// Schema a is in flight; ask for d before anything else finishes
+	require.Eventually(t, func() bool { return c.Progress().Loading == "a" }, time.Second, time.Millisecond)
+	c.Request("d")
+	c.Request("unknown")
+	close(db.gate)
+	waitDone(t, c)
+
+	assert.Equal(t, []string{"a", "d", "b", "c"}, db.loaded)
+}
+
+func TestCatalog_PlaceholderWhileLoading(t *testing.T) {
+	db := &fakeDB{
+		order:   []string{"public", "sales"},
+		schemas: map[string][]string{"public": {"users"}, "sales": {"orders"}},
+		gate:    make(chan struct{}),
+	}
+	c := New(db)
+	c.Start()
+
+	db.gate <- struct{}{}
+	require.Eventually(t, func() bool { return c.Progress().Loading == "sales" }, time.Second, time.Millisecond)
+
+	tables, progress := c.Tables()
+	assert.Len(t, tables, 1)
+	assert.False(t, progress.Done)
+	assert.Equal(t, "indexing 1 of 2 schemas (loading sales)...", progress.String())
+
+	c.Stop()
+	close(db.gate)
+	waitDone(t, c)
+}
+
+func TestCatalog_FallsBackToAllTables(t *testing.T) {
+	db := &fakeDB{order: []string{"main"}, schemas: map[string][]string{"main": {"users", "orders"}}}
+	c := New(tablesOnly{db})
+	c.Start()
+	waitDone(t, c)
+
+	tables, progress := c.Tables()
+	assert.Len(t, tables, 2)
+	assert.Equal(t, "2 tables", progress.String())
+}
+
+func TestCatalog_RemoteLoadsAllTablesAtOnce(t *testing.T) {
+	db := &fakeDB{order: []string{"public", "sales"}, schemas: map[string][]string{"public": {"users"}, "sales": {"orders"}}}
+	c := New(remoteDB{db})
+	c.Start()
+	waitDone(t, c)
+
+	tables, progress := c.Tables()
+	assert.Len(t, tables, 2)
+	assert.Equal(t, "2 tables", progress.String())
+	assert.Equal(t, Progress{Loaded: 1, Total: 1, Tables: 2, Done: true}, progress)
+}
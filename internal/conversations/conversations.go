@@ -0,0 +1,194 @@
+// Package conversations saves chat conversations under ~/.dbsage/sessions,
+// so one can be resumed later with its messages restored into the AI history.
+package conversations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"dbsage/internal/notebook"
+)
+
+// maxTitleLength is the longest automatic title, in characters
+const maxTitleLength = 60
+
+// Message is a message of the conversation sent to the AI
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Conversation is a saved chat conversation
+type Conversation struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Connection string          `json:"connection,omitempty"` // Connection current when it started
+	Created    time.Time       `json:"created"`
+	Updated    time.Time       `json:"updated"`
+	Messages   []Message       `json:"messages"`
+	Turns      []notebook.Turn `json:"turns,omitempty"` // Questions, SQL and answers, for /export and /share after a resume
+}
+
+// Summary describes a saved conversation without its messages
+type Summary struct {
+	ID         string
+	Title      string
+	Connection string
+	Updated    time.Time
+	Messages   int
+}
+
+// Enabled reports whether conversations are saved. DBSAGE_SAVE_SESSIONS=off
+// keeps them in memory only.
+func Enabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("DBSAGE_SAVE_SESSIONS"))) {
+	case "off", "0", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// dir returns the directory conversations are saved in
+func dir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "sessions")
+}
+
+// New starts a conversation on a connection. Its ID is the time it started,
+// made unique among the saved conversations.
+func New(connection string, now time.Time) *Conversation {
+	id := now.Format("20060102-150405")
+	for n := 2; exists(id); n++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), n)
+	}
+	return &Conversation{ID: id, Connection: connection, Created: now, Updated: now}
+}
+
+func exists(id string) bool {
+	_, err := os.Stat(filepath.Join(dir(), id+".json"))
+	return err == nil
+}
+
+// Add appends a message, titling the conversation after its first question
+func (c *Conversation) Add(role, content string, now time.Time) {
+	c.Messages = append(c.Messages, Message{Role: role, Content: content})
+	c.Updated = now
+	if c.Title == "" && role == "user" {
+		c.Title = Title(content)
+	}
+}
+
+// Title returns the automatic title of a conversation starting with a
+// question: its first line, shortened at a word boundary
+func Title(question string) string {
+	title := strings.Join(strings.Fields(strings.SplitN(strings.TrimSpace(question), "\n", 2)[0]), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	runes := []rune(title)[:maxTitleLength]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > maxTitleLength/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// Save writes a conversation, replacing its previous version
+func Save(c *Conversation) error {
+	if err := os.MkdirAll(dir(), 0700); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	// Written next to the file and renamed, so a crash never leaves half a conversation
+	path := filepath.Join(dir(), c.ID+".json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// Load reads the conversation with an ID, or with the only ID starting with
+// it. "last" is the most recently updated one.
+func Load(id string) (*Conversation, error) {
+	summaries, err := List()
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("no saved conversations")
+	}
+
+	var matches []string
+	for _, s := range summaries {
+		if s.ID == id {
+			matches = []string{s.ID}
+			break
+		}
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s.ID)
+		}
+	}
+	if id == "last" {
+		matches = []string{summaries[0].ID}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no saved conversation %s", id)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%d conversations start with %s: %s", len(matches), id, strings.Join(matches, ", "))
+	}
+	return read(filepath.Join(dir(), matches[0]+".json"))
+}
+
+func read(path string) (*Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	var c Conversation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation %s: %w", filepath.Base(path), err)
+	}
+	return &c, nil
+}
+
+// List returns the saved conversations, most recently updated first. Files
+// that cannot be read are skipped.
+func List() ([]Summary, error) {
+	paths, err := filepath.Glob(filepath.Join(dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []Summary
+	for _, path := range paths {
+		c, err := read(path)
+		if err != nil || c.ID == "" {
+			continue
+		}
+		summaries = append(summaries, Summary{
+			ID:         c.ID,
+			Title:      c.Title,
+			Connection: c.Connection,
+			Updated:    c.Updated,
+			Messages:   len(c.Messages),
+		})
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Updated.After(summaries[j].Updated)
+	})
+	return summaries, nil
+}
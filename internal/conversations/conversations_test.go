@@ -0,0 +1,64 @@
+package conversations
+
+import (
+	"testing"
+	"time"
+
+	"dbsage/internal/notebook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTitle(t *testing.T) {
+	assert.Equal(t, "Why is the orders query slow?", Title("  Why is the orders   query slow?\nSELECT * FROM orders"))
+	long := Title("Compare the monthly revenue of every region with last year's numbers, broken down by product line")
+	assert.Equal(t, "Compare the monthly revenue of every region with last…", long)
+}
+
+func TestSaveLoadAndList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	start := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+
+	first := New("prod", start)
+	assert.Equal(t, "20261017-093000", first.ID)
+	first.Add("user", "Why is the orders query slow?", start)
+	first.Add("assistant", "It scans the table.", start.Add(time.Minute))
+	first.Turns = []notebook.Turn{{Question: "Why is the orders query slow?", SQL: []string{"EXPLAIN SELECT 1"}}}
+	require.NoError(t, Save(first))
+
+	second := New("", start)
+	assert.Equal(t, "20261017-093000-2", second.ID, "IDs stay unique within a second")
+	second.Add("assistant", "Hello", start.Add(time.Hour))
+	second.Add("user", "List the tables", start.Add(time.Hour))
+	require.NoError(t, Save(second))
+
+	summaries, err := List()
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, second.ID, summaries[0].ID, "most recently updated first")
+	assert.Equal(t, "List the tables", summaries[0].Title)
+	assert.Equal(t, "prod", summaries[1].Connection)
+	assert.Equal(t, 2, summaries[1].Messages)
+
+	loaded, err := Load(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Messages, loaded.Messages)
+	assert.Equal(t, []string{"EXPLAIN SELECT 1"}, loaded.Turns[0].SQL)
+
+	loaded, err = Load("last")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, loaded.ID)
+
+	_, err = Load("20261017")
+	assert.ErrorContains(t, err, "2 conversations start with 20261017")
+	_, err = Load("1999")
+	assert.ErrorContains(t, err, "no saved conversation 1999")
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("DBSAGE_SAVE_SESSIONS", "")
+	assert.True(t, Enabled())
+	t.Setenv("DBSAGE_SAVE_SESSIONS", "off")
+	assert.False(t, Enabled())
+}
@@ -0,0 +1,232 @@
+// Package demo builds the sample e-commerce database behind `dbsage demo`
+// and the guided tour that introduces it
+package demo
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ConnectionName is the name of the demo connection
+const ConnectionName = "demo"
+
+// Sample data sizes
+const (
+	customerCount = 60
+	orderCount    = 400
+	reviewCount   = 150
+)
+
+// Dir returns the directory holding the demo database and its connections
+// file, kept apart from the user's own connections
+func Dir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "demo")
+}
+
+// Config returns the connection settings of the demo database at path
+func Config(path string) *dbinterfaces.ConnectionConfig {
+	return &dbinterfaces.ConnectionConfig{
+		Name:        ConnectionName,
+		Type:        "sqlite",
+		Database:    path,
+		Description: "Sample e-commerce database for the dbsage tour",
+	}
+}
+
+// schema is the demo schema. order_items.product_id is deliberately not
+// indexed, so the tour has a slow join to optimize.
+var schema = []string{
+	`CREATE TABLE customers (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE,
+	country TEXT NOT NULL,
+	created_at TEXT NOT NULL
+)`,
+	`CREATE TABLE products (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	category TEXT NOT NULL,
+	price NUMERIC(10,2) NOT NULL
+)`,
+	`CREATE TABLE orders (
+	id INTEGER PRIMARY KEY,
+	customer_id INTEGER NOT NULL REFERENCES customers(id),
+	status TEXT NOT NULL CHECK (status IN ('pending', 'paid', 'shipped', 'cancelled', 'refunded')),
+	ordered_at TEXT NOT NULL,
+	total NUMERIC(10,2) NOT NULL
+)`,
+	`CREATE INDEX orders_customer_id_idx ON orders (customer_id)`,
+	`CREATE TABLE order_items (
+	id INTEGER PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id),
+	product_id INTEGER NOT NULL REFERENCES products(id),
+	quantity INTEGER NOT NULL,
+	unit_price NUMERIC(10,2) NOT NULL
+)`,
+	`CREATE INDEX order_items_order_id_idx ON order_items (order_id)`,
+	`CREATE TABLE reviews (
+	id INTEGER PRIMARY KEY,
+	product_id INTEGER NOT NULL REFERENCES products(id),
+	customer_id INTEGER NOT NULL REFERENCES customers(id),
+	rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+	created_at TEXT NOT NULL
+)`,
+}
+
+var (
+	firstNames = []string{"Ava", "Ben", "Chloe", "Diego", "Emma", "Farah", "Gus", "Hana", "Ivan", "Jade", "Kofi", "Lena", "Mateo", "Nora", "Omar", "Priya", "Quinn", "Rosa", "Sven", "Tara"}
+	lastNames  = []string{"Silva", "Chen", "Müller", "Okafor", "Rossi", "Tanaka", "Novak", "Haddad", "Kim", "Larsen"}
+	countries  = []string{"US", "US", "US", "DE", "DE", "GB", "FR", "BR", "JP", "IN"}
+	products   = []struct {
+		name, category string
+		price          float64
+	}{
+		{"Espresso Beans 1kg", "coffee", 24.90}, {"Decaf Beans 500g", "coffee", 13.50}, {"Cold Brew Kit", "coffee", 39.00},
+		{"Pour-over Dripper", "equipment", 29.00}, {"Burr Grinder", "equipment", 149.00}, {"Gooseneck Kettle", "equipment", 69.00},
+		{"Milk Frother", "equipment", 45.00}, {"Travel Mug", "accessories", 22.00}, {"Paper Filters (100)", "accessories", 6.50},
+		{"Ceramic Cup Set", "accessories", 34.00}, {"Matcha Tin", "tea", 18.00}, {"Earl Grey 250g", "tea", 11.00},
+		{"Chai Blend", "tea", 12.50}, {"Gift Card", "gifts", 50.00}, {"Barista Course", "gifts", 120.00},
+	}
+	// statuses weighs paid and shipped orders above the rest
+	statuses = []string{"pending", "paid", "paid", "shipped", "shipped", "shipped", "shipped", "cancelled", "refunded"}
+)
+
+// baseTime anchors the sample dates so every demo database is the same
+var baseTime = time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// Create writes the demo database to path, replacing any existing file. The
+// data is generated from a fixed seed, so every demo looks the same.
+func Create(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create demo directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace demo database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		return fmt.Errorf("failed to create demo database: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := populate(tx, rand.New(rand.NewSource(42))); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to fill demo database: %w", err)
+	}
+	return tx.Commit()
+}
+
+// populate creates the schema and the sample rows
+func populate(tx *sql.Tx, r *rand.Rand) error {
+	for _, statement := range schema {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	timestamp := func(t time.Time) string { return t.Format("2006-01-02 15:04:05") }
+
+	for id := 1; id <= customerCount; id++ {
+		first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+		joined := baseTime.Add(time.Duration(r.Intn(200*24)) * time.Hour)
+		if _, err := tx.Exec("INSERT INTO customers (id, name, email, country, created_at) VALUES (?, ?, ?, ?, ?)",
+			id, first+" "+last, fmt.Sprintf("customer%d@example.com", id), countries[r.Intn(len(countries))], timestamp(joined)); err != nil {
+			return err
+		}
+	}
+	for i, p := range products {
+		if _, err := tx.Exec("INSERT INTO products (id, name, category, price) VALUES (?, ?, ?, ?)", i+1, p.name, p.category, p.price); err != nil {
+			return err
+		}
+	}
+
+	itemID := 0
+	for id := 1; id <= orderCount; id++ {
+		// A few customers order much more often than the rest
+		customer := 1 + r.Intn(customerCount)
+		if r.Intn(4) == 0 {
+			customer = 1 + r.Intn(8)
+		}
+		ordered := baseTime.Add(time.Duration(24*200+r.Intn(24*150)) * time.Hour)
+
+		type item struct{ product, quantity int }
+		items := make([]item, 1+r.Intn(4))
+		total := 0.0
+		for i := range items {
+			items[i] = item{product: r.Intn(len(products)), quantity: 1 + r.Intn(3)}
+			total += float64(items[i].quantity) * products[items[i].product].price
+		}
+		if _, err := tx.Exec("INSERT INTO orders (id, customer_id, status, ordered_at, total) VALUES (?, ?, ?, ?, ?)",
+			id, customer, statuses[r.Intn(len(statuses))], timestamp(ordered), fmt.Sprintf("%.2f", total)); err != nil {
+			return err
+		}
+		for _, it := range items {
+			itemID++
+			if _, err := tx.Exec("INSERT INTO order_items (id, order_id, product_id, quantity, unit_price) VALUES (?, ?, ?, ?, ?)",
+				itemID, id, it.product+1, it.quantity, products[it.product].price); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id := 1; id <= reviewCount; id++ {
+		rating := 5 - r.Intn(3)
+		if r.Intn(6) == 0 {
+			rating = 1 + r.Intn(2)
+		}
+		if _, err := tx.Exec("INSERT INTO reviews (id, product_id, customer_id, rating, created_at) VALUES (?, ?, ?, ?, ?)",
+			id, 1+r.Intn(len(products)), 1+r.Intn(customerCount), rating,
+			timestamp(baseTime.Add(time.Duration(24*220+r.Intn(24*140))*time.Hour))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tour is the guidance shown when the demo starts. Without an API key it
+// points at the commands that work offline.
+func Tour(hasAPIKey bool) *models.GuidanceInfo {
+	tour := &models.GuidanceInfo{
+		Type:    "demo_tour",
+		Title:   "🧭 DBSage Demo Tour",
+		Message: "You are connected to a sample coffee shop database (customers, products, orders, order_items, reviews). Nothing here touches your own connections; 'dbsage demo --reset' restores the original data.",
+	}
+	if hasAPIKey {
+		tour.Instructions = []string{
+			"1. The first question is already in the input: press Enter to see the tables",
+			"2. Ask \"Who are the top 5 customers by revenue?\"",
+			"3. Ask \"Which products have the worst average rating?\"",
+			"4. Ask \"Why is a join between order_items and products slow, and how do I fix it?\"",
+			"5. Try '/related' orders, '/build' orders and '/history' to see what ran",
+		}
+	} else {
+		tour.Instructions = []string{
+			"Set OPENAI_API_KEY to ask questions in plain language. Until then, try:",
+			"1. '/related' orders to walk the foreign keys between tables",
+			"2. '/build' orders to build a query in a form",
+			"3. '/list' and '/status' to see the demo connection",
+		}
+	}
+	tour.Actions = []string{
+		"Press 'q' to dismiss this message",
+	}
+	return tour
+}
+
+// FirstQuestion is placed in the input when the tour starts
+const FirstQuestion = "What tables are in this database, and how are they related?"
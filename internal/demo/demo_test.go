@@ -0,0 +1,54 @@
+package demo
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo", "demo.db")
+	require.NoError(t, Create(path))
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	counts := map[string]int{}
+	for _, table := range []string{"customers", "products", "orders", "order_items", "reviews"} {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		counts[table] = n
+	}
+	assert.Equal(t, customerCount, counts["customers"])
+	assert.Equal(t, len(products), counts["products"])
+	assert.Equal(t, orderCount, counts["orders"])
+	assert.Equal(t, reviewCount, counts["reviews"])
+	assert.GreaterOrEqual(t, counts["order_items"], orderCount)
+
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	require.NoError(t, err)
+	assert.False(t, rows.Next(), "every reference points at an existing row")
+	rows.Close()
+
+	// Order totals match their items
+	var mismatched int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM orders o
+WHERE ABS(o.total - (SELECT SUM(quantity * unit_price) FROM order_items WHERE order_id = o.id)) > 0.005`).Scan(&mismatched))
+	assert.Zero(t, mismatched)
+
+	// Creating again replaces the database with the same data
+	var first string
+	require.NoError(t, db.QueryRow("SELECT name FROM customers WHERE id = 1").Scan(&first))
+	db.Close()
+	require.NoError(t, Create(path))
+	db, err = sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	var again string
+	require.NoError(t, db.QueryRow("SELECT name FROM customers WHERE id = 1").Scan(&again))
+	assert.Equal(t, first, again)
+}
@@ -0,0 +1,216 @@
+// Package embeddings keeps the embeddings of schema objects in a local file,
+// so that a large database is embedded once and afterwards only the objects
+// whose definition changed are sent to the embedding model again.
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Embedder turns texts into embedding vectors, one per text in order
+type Embedder interface {
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// batchSize is how many texts are sent to the embedder at once
+const batchSize = 256
+
+// maxUnused is how long an embedding is kept without being used, so objects
+// that were dropped or changed do not grow the file forever
+const maxUnused = 90 * 24 * time.Hour
+
+// entry is a cached embedding
+type entry struct {
+	Vector []float32
+	Used   time.Time
+}
+
+// Cache holds the embeddings of one model, keyed by the hash of the embedded
+// text: an object whose definition is unchanged keeps its key and is not
+// embedded again
+type Cache struct {
+	path  string
+	model string
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	dirty   bool
+}
+
+// unsafeFileChars are replaced in model names used as file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Dir returns the directory of the embedding caches, ~/.dbsage/embeddings
+func Dir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "embeddings")
+}
+
+// Open loads the cache of a model from dir, starting empty when there is none
+// or it cannot be read
+func Open(dir, model string) *Cache {
+	c := &Cache{
+		path:    filepath.Join(dir, unsafeFileChars.ReplaceAllString(model, "_")+".gob"),
+		model:   model,
+		entries: make(map[string]*entry),
+	}
+	file, err := os.Open(c.path)
+	if err != nil {
+		return c
+	}
+	defer file.Close()
+	var entries map[string]*entry
+	if err := gob.NewDecoder(file).Decode(&entries); err == nil && entries != nil {
+		c.entries = entries
+	}
+	return c
+}
+
+// Model returns the embedding model of the cache
+func (c *Cache) Model() string {
+	return c.model
+}
+
+// Key returns the cache key of a text: the hash of the text and the model
+func (c *Cache) Key(text string) string {
+	sum := sha256.Sum256([]byte(c.model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Len returns the number of cached embeddings
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Embed returns the embeddings of texts, sending only the texts that are not
+// cached yet to the embedder, and reports how many were embedded
+func (c *Cache) Embed(ctx context.Context, embedder Embedder, texts []string) ([][]float32, int, error) {
+	now := time.Now()
+	vectors := make([][]float32, len(texts))
+	var missing []int
+	c.mu.Lock()
+	for i, text := range texts {
+		if e, ok := c.entries[c.Key(text)]; ok {
+			// Use times only matter for pruning, so they are saved at most daily
+			if now.Sub(e.Used) > 24*time.Hour {
+				c.dirty = true
+			}
+			e.Used = now
+			vectors[i] = e.Vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	c.mu.Unlock()
+
+	for start := 0; start < len(missing); start += batchSize {
+		batch := missing[start:min(start+batchSize, len(missing))]
+		input := make([]string, len(batch))
+		for j, i := range batch {
+			input[j] = texts[i]
+		}
+		embedded, err := embedder.Embed(ctx, c.model, input)
+		if err != nil {
+			return nil, start, err
+		}
+		if len(embedded) != len(input) {
+			return nil, start, fmt.Errorf("the embedding model returned %d vectors for %d texts", len(embedded), len(input))
+		}
+
+		c.mu.Lock()
+		for j, i := range batch {
+			vectors[i] = embedded[j]
+			c.entries[c.Key(texts[i])] = &entry{Vector: embedded[j], Used: now}
+		}
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return vectors, len(missing), nil
+}
+
+// Save writes the cache to its file, dropping the embeddings that were not
+// used for maxUnused
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-maxUnused)
+	for key, e := range c.entries {
+		if e.Used.Before(cutoff) {
+			delete(c.entries, key)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// Write a temporary file and rename it, so a crash leaves the old cache
+	tmp := c.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save embeddings: %w", err)
+	}
+	if err := gob.NewEncoder(file).Encode(c.entries); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save embeddings: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 when either is empty
+// or their lengths differ
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Nearest returns the indexes of the n vectors most similar to query, most
+// similar first
+func Nearest(query []float32, vectors [][]float32, n int) []int {
+	scores := make([]float64, len(vectors))
+	indexes := make([]int, len(vectors))
+	for i, v := range vectors {
+		scores[i] = Cosine(query, v)
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool { return scores[indexes[a]] > scores[indexes[b]] })
+	if len(indexes) > n {
+		indexes = indexes[:n]
+	}
+	return indexes
+}
@@ -0,0 +1,68 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder embeds a text as its length and first letter, and records
+// the texts it was asked for
+type countingEmbedder struct {
+	embedded []string
+	err      error
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.embedded = append(e.embedded, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), float32(text[0])}
+	}
+	return vectors, nil
+}
+
+func TestCache_EmbedsOnlyChangedObjects(t *testing.T) {
+	dir := t.TempDir()
+	embedder := &countingEmbedder{}
+
+	cache := Open(dir, "text-embedding-3-small")
+	vectors, embedded, err := cache.Embed(context.Background(), embedder, []string{"orders(id int)", "users(id int)"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, embedded)
+	assert.Equal(t, []float32{14, 'o'}, vectors[0])
+	require.NoError(t, cache.Save())
+
+	// After a restart only the changed table is embedded again
+	embedder.embedded = nil
+	cache = Open(dir, "text-embedding-3-small")
+	assert.Equal(t, 2, cache.Len())
+	vectors, embedded, err = cache.Embed(context.Background(), embedder, []string{"orders(id int)", "users(id int, name text)"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, embedded)
+	assert.Equal(t, []string{"users(id int, name text)"}, embedder.embedded)
+	assert.Equal(t, []float32{14, 'o'}, vectors[0])
+
+	// Another model has its own cache
+	assert.Equal(t, 0, Open(dir, "nomic/embed-text").Len())
+}
+
+func TestCache_EmbedError(t *testing.T) {
+	cache := Open(t.TempDir(), "model")
+	_, _, err := cache.Embed(context.Background(), &countingEmbedder{err: errors.New("401 unauthorized")}, []string{"orders"})
+	assert.EqualError(t, err, "401 unauthorized")
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestNearest(t *testing.T) {
+	vectors := [][]float32{{1, 0}, {0, 1}, {1, 1}}
+	assert.Equal(t, []int{1, 2}, Nearest([]float32{0, 1}, vectors, 2))
+	assert.InDelta(t, 1, Cosine([]float32{2, 0}, []float32{1, 0}), 1e-9)
+	assert.Zero(t, Cosine([]float32{1}, []float32{1, 0}))
+}
@@ -0,0 +1,295 @@
+// Package export saves query results to files in CSV, JSON, Markdown or Excel
+// format.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+)
+
+// Format is a file format results can be exported to
+type Format string
+
+const (
+	FormatCSV      Format = "csv"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "md"
+	FormatXLSX     Format = "xlsx"
+)
+
+// DefaultMaxRows is the most rows written when DBSAGE_EXPORT_MAX_ROWS is not set
+const DefaultMaxRows = 1000000
+
+// ParseFormat parses a format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "."))) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	case FormatXLSX, "excel":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (use csv, json, md or xlsx)", name)
+	}
+}
+
+// FormatForPath returns the format named by a file's extension
+func FormatForPath(path string) (Format, bool) {
+	format, err := ParseFormat(filepath.Ext(path))
+	return format, err == nil
+}
+
+// MaxRows returns the most rows written to one file, set with
+// DBSAGE_EXPORT_MAX_ROWS
+func MaxRows() int {
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_EXPORT_MAX_ROWS")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultMaxRows
+}
+
+// Summary describes a written export
+type Summary struct {
+	Path    string
+	Format  Format
+	Rows    int // Rows written
+	Total   int // Rows in the result
+	Columns int
+}
+
+// Truncated reports whether the size guard left rows out
+func (s Summary) Truncated() bool {
+	return s.Rows < s.Total
+}
+
+// String describes the export for the user
+func (s Summary) String() string {
+	message := fmt.Sprintf("Exported %d rows and %d columns to %s (%s)", s.Rows, s.Columns, s.Path, s.Format)
+	if s.Truncated() {
+		message += fmt.Sprintf(". The result has %d rows: only the first %d were written (limit DBSAGE_EXPORT_MAX_ROWS); narrow the query or raise the limit for the rest", s.Total, s.Rows)
+	}
+	return message
+}
+
+// Write saves a result to path, writing at most MaxRows rows. Parent
+// directories are created as needed. Metadata, when not nil, is embedded in
+// the file: as comment lines in CSV and Markdown, next to the rows in JSON and
+// on a sheet of its own in Excel.
+func Write(path string, format Format, result *models.QueryResult, meta *Metadata) (Summary, error) {
+	if result == nil || len(result.Columns) == 0 {
+		return Summary{}, fmt.Errorf("the result has no columns to export")
+	}
+	rows := result.Rows
+	limit := MaxRows()
+	if format == FormatXLSX && limit > xlsxMaxRows {
+		limit = xlsxMaxRows
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	summary := Summary{Path: path, Format: format, Rows: len(rows), Total: len(result.Rows), Columns: len(result.Columns)}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return summary, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return summary, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	switch format {
+	case FormatCSV:
+		err = writeCSV(w, result.Columns, rows, meta)
+	case FormatJSON:
+		err = writeJSON(w, result.Columns, rows, meta)
+	case FormatMarkdown:
+		err = writeMarkdown(w, result.Columns, rows, meta)
+	case FormatXLSX:
+		err = writeXLSX(w, result.Columns, rows, meta)
+	default:
+		err = fmt.Errorf("unknown export format %q", format)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		return summary, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return summary, nil
+}
+
+// cellText returns the text of a value for CSV cells
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeCSV writes the rows below a header, after the metadata as # comment
+// lines
+func writeCSV(w *bufio.Writer, columns []string, rows [][]interface{}, meta *Metadata) error {
+	if meta != nil {
+		for _, field := range meta.Fields() {
+			fmt.Fprintf(w, "# %s: %s\n", field[0], field[1])
+		}
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = cellText(row[i])
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeJSON writes the rows as an array of objects, keeping the column order.
+// With metadata, the file is an object holding the metadata and the rows.
+func writeJSON(w *bufio.Writer, columns []string, rows [][]interface{}, meta *Metadata) error {
+	indent := "\n  "
+	if meta != nil {
+		data, err := marshalJSON(meta)
+		if err != nil {
+			return err
+		}
+		w.WriteString("{\n  \"metadata\": ")
+		w.Write(data)
+		w.WriteString(",\n  \"rows\": ")
+		indent = "\n    "
+	}
+
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := marshalJSON(column)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	w.WriteString("[")
+	for r, row := range rows {
+		if r > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(indent + "{")
+		for i, key := range keys {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			var value interface{}
+			if i < len(row) {
+				value = row[i]
+				if b, ok := value.([]byte); ok {
+					value = string(b)
+				}
+			}
+			data, err := marshalJSON(value)
+			if err != nil {
+				return err
+			}
+			w.Write(key)
+			w.WriteString(": ")
+			w.Write(data)
+		}
+		w.WriteString("}")
+	}
+	if len(rows) > 0 {
+		w.WriteString(indent[:len(indent)-2])
+	}
+	if meta != nil {
+		_, err := w.WriteString("]\n}\n")
+		return err
+	}
+	_, err := w.WriteString("]\n")
+	return err
+}
+
+// writeMarkdown writes the rows as a table, after the metadata in an HTML
+// comment, which renderers do not show
+func writeMarkdown(w *bufio.Writer, columns []string, rows [][]interface{}, meta *Metadata) error {
+	if meta != nil {
+		w.WriteString("<!--\n")
+		for _, field := range meta.Fields() {
+			fmt.Fprintf(w, "%s: %s\n", field[0], strings.ReplaceAll(field[1], "--", "- -"))
+		}
+		w.WriteString("-->\n\n")
+	}
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		cells[i] = markdownCell(column)
+	}
+	w.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	for i := range cells {
+		cells[i] = "---"
+	}
+	w.WriteString("|" + strings.Join(cells, "|") + "|\n")
+	for _, row := range rows {
+		for i := range cells {
+			cells[i] = ""
+			if i < len(row) {
+				cells[i] = markdownCell(cellText(row[i]))
+			}
+		}
+		if _, err := w.WriteString("| " + strings.Join(cells, " | ") + " |\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownCell escapes text for a table cell, which holds a single line
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+// marshalJSON encodes a value without escaping HTML characters, which exported
+// files have no reason to avoid
+func marshalJSON(value interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
@@ -0,0 +1,231 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleResult() *models.QueryResult {
+	return &models.QueryResult{
+		Columns: []string{"id", "name", "created_at", "note"},
+		Rows: [][]interface{}{
+			{int64(1), "Ada", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), nil},
+			{int64(2), "Bob, Jr.", time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC), []byte("<vip> & \"friends\"")},
+			{2.5, "Cy", nil, "line\nbreak"},
+		},
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	for path, want := range map[string]Format{"out.csv": FormatCSV, "dir/a.JSON": FormatJSON, "report.xlsx": FormatXLSX, "notes.markdown": FormatMarkdown} {
+		format, ok := FormatForPath(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, want, format, path)
+	}
+	_, ok := FormatForPath("session.ipynb")
+	assert.False(t, ok)
+	_, err := ParseFormat("parquet")
+	assert.ErrorContains(t, err, "use csv, json, md or xlsx")
+}
+
+func TestWrite_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "out.csv")
+	summary, err := Write(path, FormatCSV, sampleResult(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Rows)
+	assert.False(t, summary.Truncated())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "id,name,created_at,note\n"+
+		"1,Ada,2024-05-01T12:00:00Z,\n"+
+		"2,\"Bob, Jr.\",2024-05-02T08:30:00Z,\"<vip> & \"\"friends\"\"\"\n"+
+		"2.5,Cy,,\"line\nbreak\"\n", string(data))
+}
+
+func TestWrite_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	_, err := Write(path, FormatJSON, sampleResult(), nil)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `[
+  {"id": 1, "name": "Ada", "created_at": "2024-05-01T12:00:00Z", "note": null},
+  {"id": 2, "name": "Bob, Jr.", "created_at": "2024-05-02T08:30:00Z", "note": "<vip> & \"friends\""},
+  {"id": 2.5, "name": "Cy", "created_at": null, "note": "line\nbreak"}
+]
+`, string(data))
+
+	empty := filepath.Join(t.TempDir(), "empty.json")
+	_, err = Write(empty, FormatJSON, &models.QueryResult{Columns: []string{"id"}}, nil)
+	require.NoError(t, err)
+	data, err = os.ReadFile(empty)
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", string(data))
+}
+
+func TestWrite_XLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.xlsx")
+	_, err := Write(path, FormatXLSX, sampleResult(), nil)
+	require.NoError(t, err)
+
+	archive, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+		parts[file.Name] = string(data)
+	}
+	require.Contains(t, parts, "[Content_Types].xml")
+	require.Contains(t, parts, "xl/workbook.xml")
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="D3" t="inlineStr"><is><t xml:space="preserve">&lt;vip&gt; &amp; &quot;friends&quot;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A4"><v>2.5</v></c>`)
+	assert.NotContains(t, sheet, `r="D2"`, "NULL cells are left empty")
+}
+
+func TestWrite_XLSXNonFiniteNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.xlsx")
+	result := &models.QueryResult{
+		Columns: []string{"ratio", "low", "high"},
+		Rows:    [][]interface{}{{math.NaN(), float32(math.Inf(-1)), math.Inf(1)}},
+	}
+	_, err := Write(path, FormatXLSX, result, nil)
+	require.NoError(t, err)
+
+	archive, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer archive.Close()
+	reader, err := archive.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	reader.Close()
+	sheet := string(data)
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">NaN</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">-Inf</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="inlineStr"><is><t xml:space="preserve">+Inf</t></is></c>`)
+	assert.NotContains(t, sheet, `<v>NaN</v>`, "Excel refuses a workbook with non-finite numbers")
+}
+
+func TestWrite_Markdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.md")
+	_, err := Write(path, FormatMarkdown, sampleResult(), nil)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "| id | name | created_at | note |\n"+
+		"|---|---|---|---|\n"+
+		"| 1 | Ada | 2024-05-01T12:00:00Z |  |\n"+
+		"| 2 | Bob, Jr. | 2024-05-02T08:30:00Z | <vip> & \"friends\" |\n"+
+		"| 2.5 | Cy |  | line<br>break |\n", string(data))
+}
+
+func TestWrite_Metadata(t *testing.T) {
+	meta := &Metadata{
+		Connection: "main",
+		Query:      "SELECT *\n  FROM customers",
+		ExportedAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		User:       "ann",
+		Version:    "1.4.0",
+	}
+	dir := t.TempDir()
+	read := func(name string, format Format) string {
+		path := filepath.Join(dir, name)
+		_, err := Write(path, format, sampleResult(), meta)
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.True(t, strings.HasPrefix(read("out.csv", FormatCSV), "# Exported by: dbsage 1.4.0\n"+
+		"# User: ann\n"+
+		"# Exported at: 2026-10-18T09:30:00Z\n"+
+		"# Connection: main\n"+
+		"# Query: SELECT * FROM customers\n"+
+		"id,name,created_at,note\n"))
+	assert.True(t, strings.HasPrefix(read("out.md", FormatMarkdown), "<!--\nExported by: dbsage 1.4.0\n"))
+
+	var doc struct {
+		Metadata Metadata                 `json:"metadata"`
+		Rows     []map[string]interface{} `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(read("out.json", FormatJSON)), &doc))
+	assert.Equal(t, "main", doc.Metadata.Connection)
+	assert.Equal(t, "1.4.0", doc.Metadata.Version)
+	require.Len(t, doc.Rows, 3)
+	assert.Equal(t, "Ada", doc.Rows[0]["name"])
+
+	read("out.xlsx", FormatXLSX)
+	archive, err := zip.OpenReader(filepath.Join(dir, "out.xlsx"))
+	require.NoError(t, err)
+	defer archive.Close()
+	var about string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet2.xml" {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			reader.Close()
+			about = string(data)
+		}
+	}
+	assert.Contains(t, about, `<t xml:space="preserve">Connection</t></is></c><c r="B4" t="inlineStr"><is><t xml:space="preserve">main</t>`)
+}
+
+func TestNewMetadata(t *testing.T) {
+	t.Setenv("DBSAGE_EXPORT_METADATA", "")
+	assert.Nil(t, NewMetadata("main", "SELECT 1", time.Now()))
+
+	t.Setenv("DBSAGE_EXPORT_METADATA", "on")
+	meta := NewMetadata("main", " SELECT 1 ", time.Now())
+	require.NotNil(t, meta)
+	assert.Equal(t, "SELECT 1", meta.Query)
+	assert.Contains(t, meta.Byline(), " with dbsage ")
+}
+
+func TestWrite_SizeGuard(t *testing.T) {
+	t.Setenv("DBSAGE_EXPORT_MAX_ROWS", "2")
+	path := filepath.Join(t.TempDir(), "out.csv")
+	summary, err := Write(path, FormatCSV, sampleResult(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Rows)
+	assert.Equal(t, 3, summary.Total)
+	assert.True(t, summary.Truncated())
+	assert.Contains(t, summary.String(), "The result has 3 rows: only the first 2 were written")
+
+	_, err = Write(path, FormatCSV, &models.QueryResult{}, nil)
+	assert.ErrorContains(t, err, "no columns")
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AZ", columnName(51))
+	assert.Equal(t, "BA", columnName(52))
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"dbsage/internal/version"
+)
+
+// Metadata says where an exported result came from, so a shared file can be
+// traced back to its source query
+type Metadata struct {
+	Connection string    `json:"connection,omitempty"`
+	Query      string    `json:"query,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+	User       string    `json:"user,omitempty"`
+	Version    string    `json:"dbsage_version"`
+}
+
+// MetadataEnabled reports whether exports and reports carry their metadata,
+// set with DBSAGE_EXPORT_METADATA=on
+func MetadataEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("DBSAGE_EXPORT_METADATA"))) {
+	case "on", "true", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// NewMetadata returns the metadata of a result exported now by the current
+// user, or nil when DBSAGE_EXPORT_METADATA is not on
+func NewMetadata(connection, query string, now time.Time) *Metadata {
+	if !MetadataEnabled() {
+		return nil
+	}
+	return &Metadata{
+		Connection: connection,
+		Query:      strings.TrimSpace(query),
+		ExportedAt: now,
+		User:       currentUser(),
+		Version:    version.Version,
+	}
+}
+
+// currentUser returns the name of the user running dbsage
+func currentUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Fields returns the metadata as label and value pairs, leaving out the
+// unknown ones. Queries are kept on one line.
+func (m *Metadata) Fields() [][2]string {
+	fields := [][2]string{{"Exported by", "dbsage " + m.Version}}
+	if m.User != "" {
+		fields = append(fields, [2]string{"User", m.User})
+	}
+	fields = append(fields, [2]string{"Exported at", m.ExportedAt.Format(time.RFC3339)})
+	if m.Connection != "" {
+		fields = append(fields, [2]string{"Connection", m.Connection})
+	}
+	if m.Query != "" {
+		fields = append(fields, [2]string{"Query", strings.Join(strings.Fields(m.Query), " ")})
+	}
+	return fields
+}
+
+// Byline continues a "Generated ..." line with who produced the file, e.g.
+// " by ann with dbsage 1.4.0"
+func (m *Metadata) Byline() string {
+	if m.User == "" {
+		return " with dbsage " + m.Version
+	}
+	return " by " + m.User + " with dbsage " + m.Version
+}
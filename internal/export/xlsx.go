@@ -0,0 +1,168 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// xlsxMaxRows is the most data rows an Excel sheet holds below the header
+const xlsxMaxRows = 1048575
+
+// xlsxParts returns the fixed parts of a workbook with the Result sheet and,
+// when about is set, an About sheet
+func xlsxParts(about bool) []struct{ name, content string } {
+	contentTypes := `<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+	sheets := `<sheet name="Result" sheetId="1" r:id="rId1"/>`
+	relationships := `<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>`
+	if about {
+		contentTypes += `<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+		sheets += `<sheet name="About" sheetId="2" r:id="rId2"/>`
+		relationships += `<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>`
+	}
+	return []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + contentTypes + `</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + relationships + `</Relationships>`},
+	}
+}
+
+// writeXLSX writes a workbook with the result on one sheet, and the metadata
+// on an About sheet when given. Numbers are stored as numbers and everything
+// else as inline text, so no shared string table is needed.
+func writeXLSX(w io.Writer, columns []string, rows [][]interface{}, meta *Metadata) error {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts(meta != nil) {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	writeXLSXRow(&b, 1, header)
+	for r, row := range rows {
+		writeXLSXRow(&b, r+2, row)
+		// Keep memory flat for large results
+		if b.Len() > 64*1024 {
+			if _, err := io.WriteString(sheet, b.String()); err != nil {
+				return err
+			}
+			b.Reset()
+		}
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	if _, err := io.WriteString(sheet, b.String()); err != nil {
+		return err
+	}
+
+	if meta != nil {
+		about, err := archive.Create("xl/worksheets/sheet2.xml")
+		if err != nil {
+			return err
+		}
+		b.Reset()
+		b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+		b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+		for i, field := range meta.Fields() {
+			writeXLSXRow(&b, i+1, []interface{}{field[0], field[1]})
+		}
+		b.WriteString(`</sheetData></worksheet>`)
+		if _, err := io.WriteString(about, b.String()); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeXLSXRow(b *strings.Builder, number int, values []interface{}) {
+	fmt.Fprintf(b, `<row r="%d">`, number)
+	for i, value := range values {
+		ref := columnName(i) + strconv.Itoa(number)
+		if n, ok := xlsxNumber(value); ok {
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, n)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(cellText(value)))
+	}
+	b.WriteString(`</row>`)
+}
+
+// xlsxNumber returns the text of numeric values. NaN and infinities have no
+// spreadsheet number, so they are left to be written as text
+func xlsxNumber(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), true
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// columnName returns the spreadsheet name of a zero-based column: A, B, ..., Z, AA
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xmlEscape escapes text for XML, dropping the control characters XML cannot hold
+func xmlEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"':
+			b.WriteString("&quot;")
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
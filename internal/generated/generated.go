@@ -0,0 +1,153 @@
+// Package generated records the rows dbsage inserted as synthetic test data,
+// by connection, table and primary key, so /cleanup generated can delete them
+// in this or a later session.
+package generated
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Row is an inserted test row
+type Row struct {
+	Connection string                 `json:"connection"`
+	Table      string                 `json:"table"`
+	Key        map[string]interface{} `json:"key"` // Primary-key column values identifying the row
+	Time       time.Time              `json:"time"`
+}
+
+// id identifies the row across loads
+func (r Row) id() string {
+	key, _ := json.Marshal(r.Key)
+	return r.Connection + "\x00" + strings.ToLower(r.Table) + "\x00" + string(key)
+}
+
+var mu sync.Mutex
+
+// generatedFile returns the path of the file recording generated rows
+func generatedFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "generated.json")
+}
+
+// Load reads the recorded rows of a connection, oldest first, or of every
+// connection when connection is empty. A missing file means no rows.
+func Load(connection string) ([]Row, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	rows, err := load()
+	if err != nil || connection == "" {
+		return rows, err
+	}
+	var matching []Row
+	for _, row := range rows {
+		if row.Connection == connection {
+			matching = append(matching, row)
+		}
+	}
+	return matching, nil
+}
+
+func load() ([]Row, error) {
+	data, err := os.ReadFile(generatedFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated rows: %w", err)
+	}
+	// Numbers are decoded as integers where they are, so keys bind as the
+	// integers they were inserted as
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var rows []Row
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to parse generated rows: %w", err)
+	}
+	for _, row := range rows {
+		for column, value := range row.Key {
+			if number, ok := value.(json.Number); ok {
+				if n, err := number.Int64(); err == nil {
+					row.Key[column] = n
+				} else if f, err := number.Float64(); err == nil {
+					row.Key[column] = f
+				}
+			}
+		}
+	}
+	return rows, nil
+}
+
+func save(rows []Row) error {
+	path := generatedFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Record adds inserted rows, stamping those without a time
+func Record(rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	existing, err := load()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, row := range rows {
+		if row.Time.IsZero() {
+			row.Time = now
+		}
+		existing = append(existing, row)
+	}
+	return save(existing)
+}
+
+// Forget removes rows that no longer exist from the record
+func Forget(rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	existing, err := load()
+	if err != nil {
+		return err
+	}
+	forget := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		forget[row.id()] = true
+	}
+	kept := existing[:0]
+	for _, row := range existing {
+		if !forget[row.id()] {
+			kept = append(kept, row)
+		}
+	}
+	return save(kept)
+}
+
+// CountByTable returns how many rows are recorded per table
+func CountByTable(rows []Row) map[string]int {
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.Table]++
+	}
+	return counts
+}
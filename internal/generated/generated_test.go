@@ -0,0 +1,39 @@
+package generated
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoadForget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	rows, err := Load("")
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	require.NoError(t, Record([]Row{
+		{Connection: "dev", Table: "customers", Key: map[string]interface{}{"id": float64(7)}},
+		{Connection: "dev", Table: "orders", Key: map[string]interface{}{"id": "a1b2", "region": "eu"}},
+		{Connection: "staging", Table: "customers", Key: map[string]interface{}{"id": 7}},
+	}))
+
+	dev, err := Load("dev")
+	require.NoError(t, err)
+	require.Len(t, dev, 2)
+	assert.Equal(t, int64(7), dev[0].Key["id"], "integer keys load as integers")
+	assert.False(t, dev[0].Time.IsZero())
+	assert.Equal(t, map[string]int{"customers": 1, "orders": 1}, CountByTable(dev))
+
+	require.NoError(t, Forget(dev[:1]))
+	dev, err = Load("dev")
+	require.NoError(t, err)
+	require.Len(t, dev, 1)
+	assert.Equal(t, "orders", dev[0].Table)
+
+	staging, err := Load("staging")
+	require.NoError(t, err)
+	assert.Len(t, staging, 1, "forgetting a row of one connection keeps the same key on another")
+}
@@ -0,0 +1,149 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dbsage/internal/sqlanalysis"
+)
+
+// Entry is one statement executed through dbsage, or a question asked to the
+// AI when Question is set
+type Entry struct {
+	Time       time.Time `json:"time"`
+	SQL        string    `json:"sql,omitempty"`
+	Connection string    `json:"connection,omitempty"` // Connection the statement ran on
+	Question   string    `json:"question,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	Rows       int       `json:"rows"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Group aggregates the history entries sharing a fingerprint
+type Group struct {
+	Fingerprint string
+	Example     string // Most recent statement with this fingerprint
+	Runs        int
+	Errors      int
+	TotalMs     float64
+	MaxMs       float64
+	LastRun     time.Time
+}
+
+// MeanMs returns the average duration of the successful runs
+func (g Group) MeanMs() float64 {
+	if g.Runs == g.Errors {
+		return 0
+	}
+	return g.TotalMs / float64(g.Runs-g.Errors)
+}
+
+var mu sync.Mutex
+
+// historyFile returns the path of the query history file
+func historyFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "history.jsonl")
+}
+
+// Append adds an entry to ~/.dbsage/history.jsonl
+func Append(entry Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := historyFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open query history: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads the query history, oldest first. A missing file is an empty
+// history and malformed lines are skipped.
+func Load() ([]Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	file, err := os.Open(historyFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open query history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && (entry.SQL != "" || entry.Question != "") {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// GroupByFingerprint groups the entries run at or after since by their
+// fingerprint, most total time first
+func GroupByFingerprint(entries []Entry, since time.Time) []Group {
+	index := make(map[string]int)
+	var groups []Group
+	for _, entry := range entries {
+		if entry.SQL == "" || entry.Time.Before(since) {
+			continue
+		}
+		fp := sqlanalysis.Fingerprint(entry.SQL)
+		i, exists := index[fp]
+		if !exists {
+			i = len(groups)
+			index[fp] = i
+			groups = append(groups, Group{Fingerprint: fp})
+		}
+		g := &groups[i]
+		g.Runs++
+		if entry.Error != "" {
+			g.Errors++
+		} else {
+			g.TotalMs += entry.DurationMs
+			g.MaxMs = max(g.MaxMs, entry.DurationMs)
+		}
+		if !entry.Time.Before(g.LastRun) {
+			g.LastRun = entry.Time
+			g.Example = strings.TrimSpace(entry.SQL)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].TotalMs > groups[j].TotalMs
+	})
+	return groups
+}
+
+// ParseDurationMs converts a query duration such as "12.5ms" to milliseconds
+func ParseDurationMs(duration string) float64 {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0
+	}
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	entries, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, Append(Entry{Time: now, SQL: "SELECT 1", DurationMs: 1.5, Rows: 1}))
+	require.NoError(t, Append(Entry{Time: now, SQL: "SELECT nope", Error: "syntax error"}))
+
+	f, err := os.OpenFile(filepath.Join(home, ".dbsage", "history.jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = Load()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 1.5, entries[0].DurationMs)
+	assert.True(t, entries[0].Time.Equal(now))
+	assert.Equal(t, "syntax error", entries[1].Error)
+}
+
+func TestGroupByFingerprint(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: day.Add(-time.Hour), SQL: "SELECT * FROM orders WHERE id = 1", DurationMs: 500},
+		{Time: day.Add(time.Hour), SQL: "SELECT * FROM orders WHERE id = 2", DurationMs: 10},
+		{Time: day.Add(3 * time.Hour), SQL: "SELECT * FROM orders WHERE id = 3", DurationMs: 30},
+		{Time: day.Add(2 * time.Hour), SQL: "SELECT * FROM orders WHERE id = 'x'", Error: "invalid input"},
+		{Time: day.Add(time.Hour), SQL: "SELECT count(*) FROM users", DurationMs: 5},
+	}
+
+	groups := GroupByFingerprint(entries, day)
+	require.Len(t, groups, 2)
+	g := groups[0]
+	assert.Equal(t, "select * from orders where id = ?", g.Fingerprint)
+	assert.Equal(t, 3, g.Runs)
+	assert.Equal(t, 1, g.Errors)
+	assert.Equal(t, 40.0, g.TotalMs)
+	assert.Equal(t, 20.0, g.MeanMs())
+	assert.Equal(t, 30.0, g.MaxMs)
+	assert.Equal(t, "SELECT * FROM orders WHERE id = 3", g.Example)
+	assert.Equal(t, day.Add(3*time.Hour), g.LastRun)
+
+	assert.Len(t, GroupByFingerprint(entries, time.Time{}), 2)
+	assert.Equal(t, 4, GroupByFingerprint(entries, time.Time{})[0].Runs)
+}
+
+func TestParseDurationMs(t *testing.T) {
+	assert.Equal(t, 12.5, ParseDurationMs("12.5ms"))
+	assert.Equal(t, 1500.0, ParseDurationMs("1.5s"))
+	assert.Equal(t, 0.0, ParseDurationMs(""))
+}
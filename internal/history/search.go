@@ -0,0 +1,143 @@
+package history
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+)
+
+// Search match kinds
+const (
+	MatchQuestion = "question"
+	MatchSQL      = "sql"
+	MatchTable    = "table"
+)
+
+// Match is a search hit together with the input that jumps to it
+type Match struct {
+	Kind  string
+	Text  string
+	Time  time.Time // Last time it was asked or run, zero for schema objects
+	Runs  int       // Times the question was asked or the SQL fingerprint was run
+	Score float64
+	Input string // Placed in the input box by /jump
+}
+
+// Search finds the past questions, executed statements and table names
+// containing every word of term. Matches on whole words and names score
+// higher, and recent history ranks above older history.
+func Search(term string, entries []Entry, tables []models.TableInfo, now time.Time) []Match {
+	words := strings.Fields(strings.ToLower(term))
+	if len(words) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int)
+	var matches []Match
+	add := func(key string, match Match) {
+		if i, exists := index[key]; exists {
+			matches[i].Runs++
+			if match.Time.After(matches[i].Time) {
+				runs := matches[i].Runs
+				matches[i] = match
+				matches[i].Runs = runs
+			}
+			return
+		}
+		index[key] = len(matches)
+		match.Runs = 1
+		matches = append(matches, match)
+	}
+
+	for _, entry := range entries {
+		if entry.Question != "" {
+			if score := matchScore(entry.Question, words); score > 0 {
+				add(MatchQuestion+":"+strings.ToLower(entry.Question), Match{Kind: MatchQuestion, Text: entry.Question, Time: entry.Time, Score: score, Input: entry.Question})
+			}
+		}
+		if entry.SQL != "" {
+			if score := matchScore(entry.SQL, words); score > 0 {
+				sql := strings.TrimSpace(entry.SQL)
+				add(MatchSQL+":"+sqlanalysis.Fingerprint(sql), Match{Kind: MatchSQL, Text: sql, Time: entry.Time, Score: score, Input: sql})
+			}
+		}
+	}
+	for i := range matches {
+		// Up to twice the score for today, fading over the following days
+		days := now.Sub(matches[i].Time).Hours() / 24
+		matches[i].Score *= 1 + 1/(1+max(days, 0))
+	}
+
+	for _, table := range tables {
+		name := table.TableName
+		if table.Schema != "" && table.Schema != "public" && table.Schema != "main" {
+			name = table.Schema + "." + table.TableName
+		}
+		if score := matchScore(name, words); score > 0 {
+			matches = append(matches, Match{Kind: MatchTable, Text: name, Score: score * 2, Input: "Describe the table " + name})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Time.After(matches[j].Time)
+	})
+	return matches
+}
+
+// Statements returns the executed statements containing every word of term,
+// or all of them when term is empty, newest first. When connection is set
+// only the statements run on it are returned. At most limit entries are
+// returned, all of them when limit is 0.
+func Statements(entries []Entry, term, connection string, limit int) []Entry {
+	words := strings.Fields(strings.ToLower(term))
+	var found []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.SQL == "" || (connection != "" && !strings.EqualFold(entry.Connection, connection)) {
+			continue
+		}
+		if len(words) > 0 && matchScore(entry.SQL, words) == 0 {
+			continue
+		}
+		found = append(found, entry)
+		if len(found) == limit {
+			break
+		}
+	}
+	return found
+}
+
+var wordPattern = regexp.MustCompile(`[a-z0-9_]+`)
+
+// matchScore scores text containing every word, or returns 0 when a word is missing
+func matchScore(text string, words []string) float64 {
+	lower := strings.ToLower(text)
+	tokens := make(map[string]bool)
+	for _, token := range wordPattern.FindAllString(lower, -1) {
+		tokens[token] = true
+	}
+
+	score := 0.0
+	for _, word := range words {
+		switch {
+		case lower == word:
+			score += 100
+		case tokens[word]:
+			score += 30
+		case strings.HasPrefix(lower, word):
+			score += 20
+		case strings.Contains(lower, word):
+			score += 10
+		default:
+			return 0
+		}
+	}
+	return score
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: now.AddDate(0, 0, -7), Question: "Which orders shipped late last month?"},
+		{Time: now.AddDate(0, 0, -7), SQL: "SELECT * FROM orders WHERE shipped_at > due_at AND id = 1"},
+		{Time: now.AddDate(0, 0, -1), SQL: "SELECT * FROM orders WHERE shipped_at > due_at AND id = 2"},
+		{Time: now.Add(-time.Hour), SQL: "SELECT count(*) FROM users"},
+	}
+	tables := []models.TableInfo{{TableName: "orders", Schema: "public"}, {TableName: "order_items", Schema: "sales"}}
+
+	matches := Search("orders", entries, tables, now)
+	require.Len(t, matches, 3)
+	assert.Equal(t, Match{Kind: MatchTable, Text: "orders", Score: 200, Input: "Describe the table orders"}, matches[0])
+
+	var sql *Match
+	for i := range matches {
+		if matches[i].Kind == MatchSQL {
+			sql = &matches[i]
+		}
+	}
+	require.NotNil(t, sql, "SQL variants are merged into one match")
+	assert.Equal(t, 2, sql.Runs)
+	assert.Contains(t, sql.Input, "id = 2", "the most recent variant is kept")
+
+	matches = Search("orders late", entries, tables, now)
+	require.Len(t, matches, 1)
+	assert.Equal(t, MatchQuestion, matches[0].Kind)
+
+	assert.Equal(t, "sales.order_items", Search("items", nil, tables, now)[0].Text)
+	assert.Empty(t, Search("  ", entries, tables, now))
+}
+
+func TestStatements(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: now.Add(-3 * time.Hour), SQL: "SELECT * FROM orders", Connection: "prod"},
+		{Time: now.Add(-2 * time.Hour), Question: "How many orders?"},
+		{Time: now.Add(-time.Hour), SQL: "SELECT count(*) FROM orders", Connection: "staging"},
+		{Time: now, SQL: "SELECT * FROM users", Connection: "prod"},
+	}
+
+	all := Statements(entries, "", "", 0)
+	require.Len(t, all, 3)
+	assert.Equal(t, "SELECT * FROM users", all[0].SQL, "newest first")
+
+	orders := Statements(entries, "orders", "", 0)
+	require.Len(t, orders, 2)
+	assert.Equal(t, "staging", orders[0].Connection)
+
+	assert.Len(t, Statements(entries, "orders", "PROD", 0), 1)
+	assert.Len(t, Statements(entries, "", "", 2), 2)
+	assert.Empty(t, Statements(entries, "invoices", "", 0))
+}
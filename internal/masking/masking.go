@@ -0,0 +1,397 @@
+// Package masking redacts personal data from query results before they are
+// sent to the AI. Columns are masked by name, such as email or phone, and
+// values are masked where they look like an email address, phone number,
+// social security or credit card number. Each connection can switch rules off
+// and force columns masked or unmasked.
+package masking
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"dbsage/internal/models"
+)
+
+// Rule detects one kind of personal data
+type Rule struct {
+	Name        string
+	Description string
+	column      *regexp.Regexp    // Matches normalized column names
+	value       *regexp.Regexp    // Matches whole values
+	check       func(string) bool // Further check of a matching value, nil when none
+}
+
+// Rules are the built-in rules, in the order values are tested against them
+var Rules = []Rule{
+	{
+		Name:        "email",
+		Description: "email addresses",
+		column:      regexp.MustCompile(`e_?mail`),
+		value:       regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`),
+	},
+	{
+		Name:        "ssn",
+		Description: "social security numbers",
+		column:      regexp.MustCompile(`(^|_)ssn($|_)|social_?security`),
+		value:       regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`),
+	},
+	{
+		Name:        "credit_card",
+		Description: "credit card numbers (Luhn-checked)",
+		column:      regexp.MustCompile(`credit_?card|card_?(number|num|no)($|_)|(^|_)cc_?(number|num|no)($|_)|(^|_)pan($|_)`),
+		value:       regexp.MustCompile(`^(\d[ -]?){12,18}\d$`),
+		check:       luhn,
+	},
+	{
+		Name:        "phone",
+		Description: "phone numbers",
+		column:      regexp.MustCompile(`phone|mobile|(^|_)(tel|fax)($|_)`),
+		value:       regexp.MustCompile(`^\+?(\d{1,3}[ .-]?)?\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}$`),
+		check:       hasSeparator,
+	},
+}
+
+// Column modes of Settings.Columns
+const (
+	ModeMask   = "mask"   // Always mask the column
+	ModeUnmask = "unmask" // Never mask the column
+)
+
+// Settings are the masking overrides of a connection. The zero value masks
+// with every built-in rule.
+type Settings struct {
+	Disabled bool              `json:"disabled,omitempty"`
+	RulesOff []string          `json:"rules_off,omitempty"` // Built-in rules switched off
+	Columns  map[string]string `json:"columns,omitempty"`   // Mode by lower-case column name
+}
+
+// Finding is a column whose values were masked
+type Finding struct {
+	Column string
+	Rule   string // Rule that matched, or "column" when the column is forced masked
+	Values int
+}
+
+// RuleEnabled reports whether a built-in rule masks on the connection
+func (s Settings) RuleEnabled(name string) bool {
+	for _, off := range s.RulesOff {
+		if strings.EqualFold(off, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetRule switches a built-in rule on or off
+func (s *Settings) SetRule(name string, on bool) error {
+	if LookupRule(name) == nil {
+		return fmt.Errorf("unknown masking rule %q", name)
+	}
+	var rulesOff []string
+	for _, off := range s.RulesOff {
+		if !strings.EqualFold(off, name) {
+			rulesOff = append(rulesOff, off)
+		}
+	}
+	if !on {
+		rulesOff = append(rulesOff, strings.ToLower(name))
+	}
+	s.RulesOff = rulesOff
+	return nil
+}
+
+// SetColumn forces a column masked or unmasked, or with mode "auto" leaves it
+// to the rules again
+func (s *Settings) SetColumn(column, mode string) error {
+	column = strings.ToLower(column)
+	switch mode {
+	case ModeMask, ModeUnmask:
+		if s.Columns == nil {
+			s.Columns = make(map[string]string)
+		}
+		s.Columns[column] = mode
+	case "auto":
+		delete(s.Columns, column)
+	default:
+		return fmt.Errorf("unknown column mode %q, use mask, unmask or auto", mode)
+	}
+	return nil
+}
+
+// LookupRule returns the built-in rule with a name, or nil
+func LookupRule(name string) *Rule {
+	for i := range Rules {
+		if strings.EqualFold(Rules[i].Name, name) {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+// ColumnRule returns the rule that masks a whole column by its name: the
+// name of a built-in rule, "column" when forced masked, or "" when only
+// values that look sensitive are masked
+func (s Settings) ColumnRule(column string) string {
+	switch s.Columns[strings.ToLower(column)] {
+	case ModeMask:
+		return "column"
+	case ModeUnmask:
+		return ""
+	}
+	name := normalize(column)
+	for _, rule := range Rules {
+		if s.RuleEnabled(rule.Name) && rule.column.MatchString(name) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// valueRule returns the rule a value looks sensitive to, or ""
+func (s Settings) valueRule(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = strings.TrimSpace(v)
+	case []byte:
+		text = strings.TrimSpace(string(v))
+	default:
+		return ""
+	}
+	for _, rule := range Rules {
+		if s.RuleEnabled(rule.Name) && rule.value.MatchString(text) && (rule.check == nil || rule.check(text)) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// Mask returns a copy of the result with sensitive values redacted, and the
+// columns that had values masked. The result itself is left unchanged.
+func (s Settings) Mask(result *models.QueryResult) (*models.QueryResult, []Finding) {
+	if s.Disabled || result == nil || len(result.Rows) == 0 {
+		return result, nil
+	}
+	counts := make([]map[string]int, len(result.Columns))
+	columnRules := make([]string, len(result.Columns))
+	for i, column := range result.Columns {
+		columnRules[i] = s.ColumnRule(column)
+	}
+	var masked [][]interface{}
+	for r, row := range result.Rows {
+		var copied []interface{}
+		for i, value := range row {
+			if i >= len(result.Columns) {
+				break
+			}
+			rule := s.cellRule(result.Columns[i], columnRules[i], value)
+			if rule == "" {
+				continue
+			}
+			if copied == nil {
+				copied = append([]interface{}(nil), row...)
+			}
+			copied[i] = Redacted(rule)
+			if counts[i] == nil {
+				counts[i] = make(map[string]int)
+			}
+			counts[i][rule]++
+		}
+		if copied != nil {
+			if masked == nil {
+				masked = append([][]interface{}(nil), result.Rows...)
+			}
+			masked[r] = copied
+		}
+	}
+	if masked == nil {
+		return result, nil
+	}
+
+	var findings []Finding
+	for i, byRule := range counts {
+		for rule, n := range byRule {
+			findings = append(findings, Finding{Column: result.Columns[i], Rule: rule, Values: n})
+		}
+	}
+	sortFindings(findings)
+	copied := *result
+	copied.Rows = masked
+	return &copied, findings
+}
+
+// MaskRow redacts the sensitive values of a row keyed by column name in
+// place, and returns the columns that had values masked
+func (s Settings) MaskRow(row map[string]interface{}) []Finding {
+	if s.Disabled {
+		return nil
+	}
+	var findings []Finding
+	for column, value := range row {
+		if rule := s.cellRule(column, s.ColumnRule(column), value); rule != "" {
+			row[column] = Redacted(rule)
+			findings = append(findings, Finding{Column: column, Rule: rule, Values: 1})
+		}
+	}
+	sortFindings(findings)
+	return findings
+}
+
+// cellRule returns the rule that masks a value of a column, given the rule
+// masking the column by name, or ""
+func (s Settings) cellRule(column, columnRule string, value interface{}) string {
+	if value == nil || s.Columns[strings.ToLower(column)] == ModeUnmask {
+		return ""
+	}
+	if columnRule != "" {
+		return columnRule
+	}
+	return s.valueRule(value)
+}
+
+// Redacted is the value a masked value is replaced with
+func Redacted(rule string) string {
+	return "[masked " + rule + "]"
+}
+
+// Describe summarizes findings, e.g. "contact as email (12 values), mobile as phone (3 values)"
+func Describe(findings []Finding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%s as %s (%d values)", f.Column, f.Rule, f.Values)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Merge adds the values of findings for the same column and rule together
+func Merge(findings []Finding) []Finding {
+	index := make(map[Finding]int)
+	var merged []Finding
+	for _, f := range findings {
+		key := Finding{Column: f.Column, Rule: f.Rule}
+		if i, ok := index[key]; ok {
+			merged[i].Values += f.Values
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, f)
+	}
+	sortFindings(merged)
+	return merged
+}
+
+func sortFindings(findings []Finding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Column != findings[j].Column {
+			return findings[i].Column < findings[j].Column
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+}
+
+// normalize lower-cases a column name and turns other separators into "_"
+func normalize(column string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(column) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// luhn reports whether the digits of a value pass the Luhn checksum
+func luhn(value string) bool {
+	sum, double := 0, false
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// hasSeparator reports whether a phone-like value is written as one, with a
+// country code or separators, rather than as a bare run of digits
+func hasSeparator(value string) bool {
+	return strings.ContainsAny(value, "+()-. ")
+}
+
+var mu sync.Mutex
+
+// settingsFile returns the path of the masking settings file
+func settingsFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "masking.json")
+}
+
+// Load reads the masking settings of a connection. A connection without
+// settings masks with every built-in rule.
+func Load(connection string) (Settings, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	all, err := load()
+	if err != nil {
+		return Settings{}, err
+	}
+	return all[connection], nil
+}
+
+// Save writes the masking settings of a connection
+func Save(connection string, settings Settings) error {
+	mu.Lock()
+	defer mu.Unlock()
+	all, err := load()
+	if err != nil {
+		return err
+	}
+	if all == nil {
+		all = make(map[string]Settings)
+	}
+	all[connection] = settings
+	return save(all)
+}
+
+func load() (map[string]Settings, error) {
+	data, err := os.ReadFile(settingsFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read masking settings: %w", err)
+	}
+	var all map[string]Settings
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse masking settings: %w", err)
+	}
+	return all, nil
+}
+
+func save(all map[string]Settings) error {
+	path := settingsFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,92 @@
+package masking
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettings_Mask(t *testing.T) {
+	result := &models.QueryResult{
+		Columns: []string{"id", "contactEmail", "note", "created", "card"},
+		Rows: [][]interface{}{
+			{int64(1), "ann@example.com", "call +1 555-123-4567", "2024-01-02", "4111 1111 1111 1111"},
+			{int64(2), nil, "123-45-6789", "2024-01-03", "4111 1111 1111 1112"},
+			{int64(3), "bob", "(555) 123-4567", "2024-01-04", "5555555555"},
+		},
+		RowCount: 3,
+	}
+
+	masked, findings := Settings{}.Mask(result)
+	assert.Equal(t, []interface{}{int64(1), "[masked email]", "call +1 555-123-4567", "2024-01-02", "[masked credit_card]"}, masked.Rows[0])
+	assert.Equal(t, []interface{}{int64(2), nil, "[masked ssn]", "2024-01-03", "4111 1111 1111 1112"}, masked.Rows[1],
+		"a card number failing the Luhn check is kept")
+	assert.Equal(t, []interface{}{int64(3), "[masked email]", "[masked phone]", "2024-01-04", "5555555555"}, masked.Rows[2],
+		"a column named like email is masked whatever its values")
+	assert.Equal(t, []Finding{
+		{Column: "card", Rule: "credit_card", Values: 1},
+		{Column: "contactEmail", Rule: "email", Values: 2},
+		{Column: "note", Rule: "phone", Values: 1},
+		{Column: "note", Rule: "ssn", Values: 1},
+	}, findings)
+	assert.Equal(t, "ann@example.com", result.Rows[0][1], "the result itself is unchanged")
+}
+
+func TestSettings_Overrides(t *testing.T) {
+	result := &models.QueryResult{
+		Columns: []string{"email", "phone", "notes"},
+		Rows:    [][]interface{}{{"ann@example.com", "555-123-4567", "vip"}},
+	}
+
+	var settings Settings
+	require.NoError(t, settings.SetColumn("EMAIL", ModeUnmask))
+	require.NoError(t, settings.SetColumn("notes", ModeMask))
+	require.NoError(t, settings.SetRule("phone", false))
+	masked, _ := settings.Mask(result)
+	assert.Equal(t, []interface{}{"ann@example.com", "555-123-4567", "[masked column]"}, masked.Rows[0])
+
+	require.NoError(t, settings.SetRule("phone", true))
+	require.NoError(t, settings.SetColumn("notes", "auto"))
+	masked, _ = settings.Mask(result)
+	assert.Equal(t, []interface{}{"ann@example.com", "[masked phone]", "vip"}, masked.Rows[0])
+
+	settings.Disabled = true
+	masked, findings := settings.Mask(result)
+	assert.Same(t, result, masked)
+	assert.Empty(t, findings)
+
+	assert.Error(t, settings.SetRule("iban", false))
+	assert.Error(t, settings.SetColumn("notes", "hide"))
+}
+
+func TestSettings_MaskRow(t *testing.T) {
+	row := map[string]interface{}{"id": float64(7), "mobile_no": "0612345678", "comment": "mail ann@example.com"}
+	findings := Settings{}.MaskRow(row)
+	assert.Equal(t, map[string]interface{}{"id": float64(7), "mobile_no": "[masked phone]", "comment": "mail ann@example.com"}, row)
+	assert.Equal(t, []Finding{{Column: "mobile_no", Rule: "phone", Values: 1}}, findings)
+
+	assert.Equal(t, []Finding{{Column: "mobile_no", Rule: "phone", Values: 2}}, Merge(append(findings, findings...)))
+}
+
+func TestLoadSave(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	settings, err := Load("shop")
+	require.NoError(t, err)
+	assert.Equal(t, Settings{}, settings)
+
+	require.NoError(t, settings.SetRule("email", false))
+	require.NoError(t, Save("shop", settings))
+	require.NoError(t, Save("crm", Settings{Disabled: true}))
+
+	loaded, err := Load("shop")
+	require.NoError(t, err)
+	assert.False(t, loaded.RuleEnabled("email"))
+	assert.True(t, loaded.RuleEnabled("phone"))
+	loaded, err = Load("crm")
+	require.NoError(t, err)
+	assert.True(t, loaded.Disabled)
+}
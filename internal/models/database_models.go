@@ -17,6 +17,25 @@ type QueryResult struct {
 	Rows     [][]interface{} `json:"rows"`
 	RowCount int             `json:"row_count"`
 	Duration string          `json:"duration"`
+
+	// Set when rows were dropped before the result was handed to the AI
+	Truncated        bool   `json:"truncated,omitempty"`
+	TotalRows        int    `json:"total_rows,omitempty"`
+	TruncationReason string `json:"truncation_reason,omitempty"`
+
+	// Set when sensitive values were redacted before the result was handed to the AI, naming the columns
+	Masked string `json:"masked,omitempty"`
+
+	// Set on connections with read replicas
+	Endpoint         string `json:"endpoint,omitempty"`          // Endpoint that ran the query, e.g. "replica db-2:5432"
+	ReplicaLag       string `json:"replica_lag,omitempty"`       // Measured replication lag of the replica that answered, e.g. "1.2s"
+	StalenessWarning string `json:"staleness_warning,omitempty"` // Set when the replica that answered lags over the threshold or its lag is unknown
+
+	// Set when the rows are a sample of a larger result, saying how it was drawn
+	Sampling string `json:"sampling,omitempty"`
+
+	// Set when the statement ran in a scratchpad transaction, which is rolled back
+	Scratchpad bool `json:"scratchpad,omitempty"`
 }
 
 // TableInfo represents basic table information
@@ -29,16 +48,27 @@ type TableInfo struct {
 
 // ColumnInfo represents column information
 type ColumnInfo struct {
-	ColumnName    string  `json:"column_name"`
-	DataType      string  `json:"data_type"`
-	IsNullable    string  `json:"is_nullable"`
-	DefaultValue  *string `json:"default_value,omitempty"`
-	CharMaxLength *int    `json:"character_maximum_length,omitempty"`
-	NumPrecision  *int    `json:"numeric_precision,omitempty"`
-	NumScale      *int    `json:"numeric_scale,omitempty"`
-	IsPrimaryKey  bool    `json:"is_primary_key"`
-	IsForeignKey  bool    `json:"is_foreign_key"`
-	Description   string  `json:"description"`
+	ColumnName    string   `json:"column_name"`
+	DataType      string   `json:"data_type"`
+	IsNullable    string   `json:"is_nullable"`
+	DefaultValue  *string  `json:"default_value,omitempty"`
+	CharMaxLength *int     `json:"character_maximum_length,omitempty"`
+	NumPrecision  *int     `json:"numeric_precision,omitempty"`
+	NumScale      *int     `json:"numeric_scale,omitempty"`
+	IsPrimaryKey  bool     `json:"is_primary_key"`
+	IsForeignKey  bool     `json:"is_foreign_key"`
+	Description   string   `json:"description"`
+	EnumValues    []string `json:"enum_values,omitempty"` // Allowed values of enum columns
+	Spatial       *Spatial `json:"spatial,omitempty"`     // Set for PostGIS geometry and geography columns
+}
+
+// Spatial describes a PostGIS geometry or geography column
+type Spatial struct {
+	Type         string `json:"type"`          // geometry or geography
+	GeometryType string `json:"geometry_type"` // POINT, POLYGON, ... or GEOMETRY when unconstrained
+	SRID         int    `json:"srid"`          // 0 when unconstrained
+	Indexed      bool   `json:"indexed"`       // Covered by a GiST, SP-GiST or BRIN index
+	Suggestion   string `json:"suggestion,omitempty"`
 }
 
 // IndexInfo represents index information
@@ -51,3 +81,35 @@ type IndexInfo struct {
 	TableSpace  string   `json:"tablespace"`
 	Description string   `json:"description"`
 }
+
+// RLSReport lists the row-level security policies of tables, seen from the
+// connected role
+type RLSReport struct {
+	CurrentRole string     `json:"current_role"`
+	Superuser   bool       `json:"superuser"`
+	BypassRLS   bool       `json:"bypass_rls"`
+	Tables      []RLSTable `json:"tables"`
+}
+
+// RLSTable is the row-level security state of one table
+type RLSTable struct {
+	Schema             string      `json:"schema"`
+	Table              string      `json:"table"`
+	Owner              string      `json:"owner"`
+	Enabled            bool        `json:"rls_enabled"`
+	Forced             bool        `json:"rls_forced"`
+	OwnedByCurrentRole bool        `json:"owned_by_current_role"`
+	Policies           []RLSPolicy `json:"policies"`
+	Effect             string      `json:"effect,omitempty"` // How the policies apply to the current role
+}
+
+// RLSPolicy is a row-level security policy
+type RLSPolicy struct {
+	Name                 string   `json:"name"`
+	Command              string   `json:"command"` // ALL, SELECT, INSERT, UPDATE or DELETE
+	Permissive           bool     `json:"permissive"`
+	Roles                []string `json:"roles"`
+	Using                string   `json:"using,omitempty"`
+	Check                string   `json:"with_check,omitempty"`
+	AppliesToCurrentRole bool     `json:"applies_to_current_role"`
+}
@@ -27,6 +27,12 @@ type ToolConfirmationInfo struct {
 	Description string                 `json:"description"`
 	RiskLevel   string                 `json:"risk_level"` // "low", "medium", "high"
 	Options     []ConfirmationOption   `json:"options"`
+	Impact      []string               `json:"impact,omitempty"` // Objects that use what a DDL statement changes
+
+	// Set when the turn is stepped through
+	Step      int      `json:"step,omitempty"`      // Number of the tool call in the turn
+	Reason    string   `json:"reason,omitempty"`    // What the AI said about its tool calls
+	Remaining []string `json:"remaining,omitempty"` // Tool calls of the same message that follow
 }
 
 // ConfirmationOption represents an option in the confirmation dialog
@@ -34,7 +40,7 @@ type ConfirmationOption struct {
 	Key         string `json:"key"`
 	Label       string `json:"label"`
 	Description string `json:"description"`
-	Action      string `json:"action"` // "execute", "cancel", "edit", "custom"
+	Action      string `json:"action"` // "execute", "cancel", "edit", "continue", "custom"
 }
 
 // ToolConfirmationConfig defines which tools need confirmation
@@ -52,6 +58,28 @@ type PendingAIContext struct {
 	StreamingCallback func(chunk string) error       `json:"-"` // Not serializable
 }
 
+// Connection environments shown in the status bar
+const (
+	EnvProduction  = "production"
+	EnvStaging     = "staging"
+	EnvDevelopment = "development"
+)
+
+// StatusBarInfo holds the values shown in the bottom status bar
+type StatusBarInfo struct {
+	Connection    string
+	Environment   string // One of the Env* constants, empty if unknown
+	Tenant        string // Tenant statements are scoped to, empty when not scoped
+	Latency       string // Round trip of a remote connection, empty for nearby ones
+	Health        string // Problem with the current connection, e.g. "down, retry in 8s", empty when healthy
+	DownCount     int    // Other open connections the health monitor found down
+	Scratchpad    string // Time left in the open scratchpad, empty when none is open
+	Model         string
+	QueryDuration string
+	ContextTokens int
+	TurnTiming    string // Summary of the last turn's timing, empty when hidden
+}
+
 // CommandInfo contains information about a command
 type CommandInfo struct {
 	Name        string `json:"name"`
@@ -89,6 +117,40 @@ type ToolConfirmationResponseMsg struct {
 	Action    string // "execute", "cancel", "edit"
 }
 
+// TurnAbortedMsg reports the statements cancelled on the server when the user
+// aborted an AI turn
+type TurnAbortedMsg struct {
+	Cancelled int
+	Err       error
+}
+
+// WorkloadProgressMsg reports how far a running /capture or /replay got
+type WorkloadProgressMsg struct {
+	Text string
+}
+
+// WorkloadDoneMsg carries the response of a finished /capture or /replay
+type WorkloadDoneMsg struct {
+	Response string
+}
+
+// ResultPageMsg carries a page of query results read for the result pager
+type ResultPageMsg struct {
+	Page    int
+	Columns []string
+	Rows    [][]interface{}
+	Last    bool // The page is the last one of the result
+	Err     error
+}
+
+// GridWriteMsg carries the outcome of writing an edited cell of the data grid
+type GridWriteMsg struct {
+	Row    int
+	Column int
+	Value  *string // nil for NULL
+	Err    error
+}
+
 // GuidanceInfo contains information about user guidance
 type GuidanceInfo struct {
 	Type         string   `json:"type"` // "first_time", "api_key_missing", "no_database"
@@ -112,6 +174,10 @@ type VersionUpdateInfo struct {
 	ReleaseNotes   string `json:"release_notes"`
 }
 
+// HealthChangedMsg is sent by the connection health monitor when the health
+// of a connection changes, to redraw the status bar
+type HealthChangedMsg struct{}
+
 // VersionUpdateMsg is sent when a version update is available
 type VersionUpdateMsg struct {
 	UpdateInfo *VersionUpdateInfo
@@ -0,0 +1,166 @@
+package notebook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Turn is one question of a session with the SQL executed to answer it, in
+// execution order, and the answer
+type Turn struct {
+	Question string
+	SQL      []string
+	Answer   string
+	Result   *models.QueryResult // Last result of the turn's SQL, nil when none returned rows
+}
+
+// cell is a notebook cell: markdown narrative or a SQL statement
+type cell struct {
+	markdown bool
+	source   string
+}
+
+// cells lays out the turns as question, statements, answer
+func cells(turns []Turn) []cell {
+	var out []cell
+	for _, turn := range turns {
+		if turn.Question != "" {
+			out = append(out, cell{markdown: true, source: "**Question:** " + turn.Question})
+		}
+		for _, sql := range turn.SQL {
+			out = append(out, cell{source: strings.TrimSuffix(strings.TrimSpace(sql), ";")})
+		}
+		if answer := strings.TrimSpace(turn.Answer); answer != "" {
+			out = append(out, cell{markdown: true, source: answer})
+		}
+	}
+	return out
+}
+
+// ConnectionURL returns the SQLAlchemy URL of a connection for jupysql,
+// without the password, or "" for connections it cannot reach
+func ConnectionURL(config *dbinterfaces.ConnectionConfig) string {
+	if config == nil {
+		return ""
+	}
+	var scheme string
+	switch strings.ToLower(config.Type) {
+	case "postgresql", "postgres", "":
+		scheme = "postgresql"
+	case "mysql":
+		scheme = "mysql+pymysql"
+	case "sqlite":
+		return "sqlite:///" + config.Database
+	default:
+		return ""
+	}
+
+	u := url.URL{Scheme: scheme, Host: config.Host, Path: "/" + config.Database}
+	if config.Port > 0 {
+		u.Host = fmt.Sprintf("%s:%d", config.Host, config.Port)
+	}
+	if config.Username != "" {
+		u.User = url.User(config.Username)
+	}
+	return u.String()
+}
+
+// Export renders the turns as a notebook, picking the format from the file
+// extension: Jupyter (.ipynb, SQL cells run with jupysql) or a SQL file in the
+// jupytext percent format. It returns the content and the number of SQL cells.
+func Export(path string, turns []Turn, connectionURL string) ([]byte, int, error) {
+	layout := cells(turns)
+	sqlCells := 0
+	for _, c := range layout {
+		if !c.markdown {
+			sqlCells++
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".ipynb") {
+		data, err := jupyter(layout, connectionURL)
+		return data, sqlCells, err
+	}
+	return []byte(percentSQL(layout, connectionURL)), sqlCells, nil
+}
+
+const setupNote = "Exported from dbsage. The password is not included: set it in the URL, or use PGPASSWORD, ~/.pgpass or ~/.my.cnf."
+
+// jupyter renders an nbformat 4 notebook for a Python kernel with jupysql
+func jupyter(layout []cell, connectionURL string) ([]byte, error) {
+	type jupyterCell struct {
+		CellType       string                 `json:"cell_type"`
+		ExecutionCount *int                   `json:"execution_count,omitempty"`
+		Metadata       map[string]interface{} `json:"metadata"`
+		Outputs        *[]interface{}         `json:"outputs,omitempty"`
+		Source         []string               `json:"source"`
+	}
+	markdown := func(source string) jupyterCell {
+		return jupyterCell{CellType: "markdown", Metadata: map[string]interface{}{}, Source: sourceLines(source)}
+	}
+	code := func(source string) jupyterCell {
+		return jupyterCell{CellType: "code", Metadata: map[string]interface{}{}, Outputs: &[]interface{}{}, Source: sourceLines(source)}
+	}
+
+	connect := "%load_ext sql"
+	if connectionURL != "" {
+		connect += "\n%sql " + connectionURL
+	}
+	notebookCells := []jupyterCell{
+		markdown("# dbsage session\n\n" + setupNote + " Requires `pip install jupysql` and the database driver."),
+		code(connect),
+	}
+	for _, c := range layout {
+		if c.markdown {
+			notebookCells = append(notebookCells, markdown(c.source))
+		} else {
+			notebookCells = append(notebookCells, code("%%sql\n"+c.source))
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"cells": notebookCells,
+		"metadata": map[string]interface{}{
+			"kernelspec":    map[string]string{"display_name": "Python 3", "language": "python", "name": "python3"},
+			"language_info": map[string]string{"name": "python"},
+		},
+		"nbformat":       4,
+		"nbformat_minor": 4,
+	}, "", " ")
+}
+
+// sourceLines splits cell source into lines that keep their newline, as nbformat stores them
+func sourceLines(source string) []string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// percentSQL renders a SQL file with jupytext percent cell markers; the
+// narrative is kept in comments so the file still runs as a script
+func percentSQL(layout []cell, connectionURL string) string {
+	var b strings.Builder
+	b.WriteString("-- %% [markdown]\n-- # dbsage session\n--\n-- " + setupNote + "\n")
+	if connectionURL != "" {
+		b.WriteString("-- Connection: " + connectionURL + "\n")
+	}
+	for _, c := range layout {
+		if c.markdown {
+			b.WriteString("\n-- %% [markdown]\n")
+			for _, line := range strings.Split(c.source, "\n") {
+				b.WriteString(strings.TrimRight("-- "+line, " ") + "\n")
+			}
+		} else {
+			b.WriteString("\n-- %%\n" + c.source + ";\n")
+		}
+	}
+	return b.String()
+}
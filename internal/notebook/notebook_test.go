@@ -0,0 +1,54 @@
+package notebook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var turns = []Turn{
+	{Question: "How many users signed up today?", SQL: []string{"SELECT count(*)\nFROM users\nWHERE created_at >= current_date;"}, Answer: "42 users signed up today."},
+	{Question: "Thanks!", Answer: "You're welcome."},
+}
+
+func TestExport_Jupyter(t *testing.T) {
+	data, sqlCells, err := Export("session.ipynb", turns, "postgresql://app@db:5432/shop")
+	require.NoError(t, err)
+	assert.Equal(t, 1, sqlCells)
+
+	var nb struct {
+		Cells []struct {
+			CellType string   `json:"cell_type"`
+			Source   []string `json:"source"`
+		} `json:"cells"`
+		NBFormat int `json:"nbformat"`
+	}
+	require.NoError(t, json.Unmarshal(data, &nb))
+	assert.Equal(t, 4, nb.NBFormat)
+	require.Len(t, nb.Cells, 7)
+	assert.Equal(t, []string{"%load_ext sql\n", "%sql postgresql://app@db:5432/shop"}, nb.Cells[1].Source)
+	assert.Equal(t, "markdown", nb.Cells[2].CellType)
+	assert.Equal(t, "code", nb.Cells[3].CellType)
+	assert.Equal(t, []string{"%%sql\n", "SELECT count(*)\n", "FROM users\n", "WHERE created_at >= current_date"}, nb.Cells[3].Source)
+	assert.Equal(t, []string{"42 users signed up today."}, nb.Cells[4].Source)
+}
+
+func TestExport_PercentSQL(t *testing.T) {
+	data, sqlCells, err := Export("session.sql", turns, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, sqlCells)
+	assert.Contains(t, string(data), "\n-- %% [markdown]\n-- **Question:** How many users signed up today?\n\n-- %%\nSELECT count(*)\nFROM users\nWHERE created_at >= current_date;\n")
+	assert.NotContains(t, string(data), "Connection:")
+}
+
+func TestConnectionURL(t *testing.T) {
+	assert.Equal(t, "mysql+pymysql://root@localhost:3306/shop",
+		ConnectionURL(&dbinterfaces.ConnectionConfig{Type: "mysql", Host: "localhost", Port: 3306, Database: "shop", Username: "root", Password: "secret"}))
+	assert.Equal(t, "sqlite:///data/app.db", ConnectionURL(&dbinterfaces.ConnectionConfig{Type: "sqlite", Database: "data/app.db"}))
+	assert.Empty(t, ConnectionURL(&dbinterfaces.ConnectionConfig{Type: "files"}))
+	assert.Empty(t, ConnectionURL(nil))
+}
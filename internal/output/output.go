@@ -0,0 +1,99 @@
+// Package output defines the output formats shared by the TUI and the command
+// line, and the stable JSON document written in json mode.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/models"
+)
+
+// Format selects how results are rendered
+type Format string
+
+const (
+	FormatTable    Format = "table"    // Aligned text table (default)
+	FormatJSON     Format = "json"     // Document JSON, see SchemaVersion
+	FormatMarkdown Format = "markdown" // GitHub-flavored markdown table with all rows
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatTable, FormatJSON, FormatMarkdown}
+
+// ParseFormat parses a format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case FormatTable, "":
+		return FormatTable, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (use table, json or markdown)", name)
+	}
+}
+
+// SchemaVersion is incremented whenever a field of Document or the types it
+// contains is removed or changes meaning. Added fields do not change it.
+const SchemaVersion = 1
+
+// Document kinds
+const (
+	KindQueryResult   = "query_result"
+	KindQueryAnalysis = "query_analysis"
+	KindScriptResult  = "script_result"
+	KindError         = "error"
+)
+
+// Document is the top-level object written in json mode. Exactly one of
+// Result, Analysis, Statements or Error is set, according to Kind.
+type Document struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Kind          string               `json:"kind"`
+	Connection    string               `json:"connection,omitempty"`
+	Query         string               `json:"query,omitempty"`
+	Result        *models.QueryResult  `json:"result,omitempty"`
+	Analysis      *tools.QueryAnalysis `json:"analysis,omitempty"`
+	Statements    []StatementResult    `json:"statements,omitempty"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// StatementResult is the outcome of one statement of a script. Statements
+// after a failed one are not executed and not listed.
+type StatementResult struct {
+	Query  string              `json:"query"`
+	Result *models.QueryResult `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// NewResultDocument wraps a query result
+func NewResultDocument(connection, query string, result *models.QueryResult) *Document {
+	return &Document{SchemaVersion: SchemaVersion, Kind: KindQueryResult, Connection: connection, Query: query, Result: result}
+}
+
+// NewAnalysisDocument wraps a query analysis
+func NewAnalysisDocument(connection, query string, analysis *tools.QueryAnalysis) *Document {
+	return &Document{SchemaVersion: SchemaVersion, Kind: KindQueryAnalysis, Connection: connection, Query: query, Analysis: analysis}
+}
+
+// NewScriptDocument wraps the results of a script's statements
+func NewScriptDocument(connection string, statements []StatementResult) *Document {
+	return &Document{SchemaVersion: SchemaVersion, Kind: KindScriptResult, Connection: connection, Statements: statements}
+}
+
+// NewErrorDocument reports a failure
+func NewErrorDocument(connection, query string, err error) *Document {
+	return &Document{SchemaVersion: SchemaVersion, Kind: KindError, Connection: connection, Query: query, Error: err.Error()}
+}
+
+// WriteJSON writes the document as indented JSON followed by a newline
+func (d *Document) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"": FormatTable, "table": FormatTable, "JSON": FormatJSON, "md": FormatMarkdown, "markdown": FormatMarkdown} {
+		got, err := ParseFormat(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := ParseFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestDocument_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	doc := NewResultDocument("main", "SELECT 1", &models.QueryResult{Columns: []string{"n"}, Rows: [][]interface{}{{1}}, RowCount: 1})
+	require.NoError(t, doc.WriteJSON(&buf))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, float64(SchemaVersion), decoded["schema_version"])
+	assert.Equal(t, KindQueryResult, decoded["kind"])
+	assert.Equal(t, "main", decoded["connection"])
+	assert.NotContains(t, decoded, "analysis")
+	assert.NotContains(t, decoded, "error")
+
+	buf.Reset()
+	require.NoError(t, NewErrorDocument("", "SELEC 1", errors.New("syntax error")).WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"kind": "error"`)
+	assert.Contains(t, buf.String(), `"error": "syntax error"`)
+}
+
+func TestScriptDocument_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	doc := NewScriptDocument("staging", []StatementResult{
+		{Query: "UPDATE users SET active = true WHERE id = 1", Result: &models.QueryResult{RowCount: 1}},
+		{Query: "DELETE FROM nope", Error: "no such table: nope"},
+	})
+	require.NoError(t, doc.WriteJSON(&buf))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, KindScriptResult, decoded["kind"])
+	assert.NotContains(t, decoded, "result")
+	statements := decoded["statements"].([]interface{})
+	require.Len(t, statements, 2)
+	assert.Equal(t, "no such table: nope", statements[1].(map[string]interface{})["error"])
+}
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExpandPlaceholders expands the {date}, {time} and {name} placeholders of an
+// output path or email subject
+func ExpandPlaceholders(pattern, name string, now time.Time) string {
+	return strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("1504"),
+		"{name}", name,
+	).Replace(pattern)
+}
+
+// WriteFile writes a rendered report, creating the directory if needed
+func WriteFile(path, content string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// SMTPConfig holds the mail server settings, read from DBSAGE_SMTP_HOST,
+// DBSAGE_SMTP_PORT (default 587), DBSAGE_SMTP_USERNAME, DBSAGE_SMTP_PASSWORD
+// and DBSAGE_SMTP_FROM (defaults to the username)
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPConfigFromEnv reads the mail server settings from the environment
+func SMTPConfigFromEnv() (*SMTPConfig, error) {
+	cfg := &SMTPConfig{
+		Host:     os.Getenv("DBSAGE_SMTP_HOST"),
+		Port:     os.Getenv("DBSAGE_SMTP_PORT"),
+		Username: os.Getenv("DBSAGE_SMTP_USERNAME"),
+		Password: os.Getenv("DBSAGE_SMTP_PASSWORD"),
+		From:     os.Getenv("DBSAGE_SMTP_FROM"),
+	}
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("DBSAGE_SMTP_HOST is required to email reports")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("DBSAGE_SMTP_FROM or DBSAGE_SMTP_USERNAME is required to email reports")
+	}
+	return cfg, nil
+}
+
+// SendEmail mails a rendered report. Markdown reports are sent as plain text.
+func SendEmail(cfg *SMTPConfig, to []string, subject, content, format string) error {
+	contentType := "text/plain; charset=utf-8"
+	if format == FormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + cfg.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + strings.NewReplacer("\r", " ", "\n", " ").Replace(subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: " + contentType + "\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(content, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	if err := smtp.SendMail(net.JoinHostPort(cfg.Host, cfg.Port), auth, cfg.From, to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to email report: %w", err)
+	}
+	return nil
+}
+
+// Subject returns the email subject of a report
+func (t *Template) Subject(now time.Time) string {
+	if t.Email != nil && t.Email.Subject != "" {
+		return ExpandPlaceholders(t.Email.Subject, t.name, now)
+	}
+	return fmt.Sprintf("%s (%s)", t.Title, now.Format("2006-01-02"))
+}
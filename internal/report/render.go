@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/output"
+	"dbsage/internal/ui/renderers"
+)
+
+// FormatAnalysis renders a query analysis as text or markdown
+func FormatAnalysis(analysis *tools.QueryAnalysis, format output.Format) string {
+	heading := func(title string) string {
+		if format == output.FormatMarkdown {
+			return "## " + title + "\n\n"
+		}
+		return title + ":\n"
+	}
+	return formatAnalysis(analysis, heading)
+}
+
+func formatAnalysis(analysis *tools.QueryAnalysis, heading func(string) string) string {
+	var b strings.Builder
+	b.WriteString(heading("Findings"))
+	if len(analysis.Findings) == 0 {
+		b.WriteString("- none\n")
+	}
+	for _, f := range analysis.Findings {
+		b.WriteString(fmt.Sprintf("- [%s] %s: %s\n", f.Severity, f.Rule, f.Message))
+	}
+
+	b.WriteString("\n" + heading(fmt.Sprintf("Plan (%s profile)", analysis.Profile)))
+	switch {
+	case analysis.PlanError != "":
+		b.WriteString("- unavailable: " + analysis.PlanError + "\n")
+	case analysis.Plan != nil:
+		b.WriteString(fmt.Sprintf("- estimated cost %.2f, ~%.0f rows\n", analysis.Plan.TotalCost, analysis.Plan.EstimatedRows))
+		for _, w := range analysis.Plan.Warnings {
+			b.WriteString("- " + w.Message + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Markdown renders the report as GitHub-flavored markdown
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# " + r.Title + "\n\n")
+	b.WriteString(fmt.Sprintf("_Generated %s", r.GeneratedAt.Format("2006-01-02 15:04 MST")))
+	if r.Connection != "" {
+		b.WriteString(fmt.Sprintf(" on connection `%s`", r.Connection))
+	}
+	if r.Metadata != nil {
+		b.WriteString(r.Metadata.Byline())
+	}
+	b.WriteString("_\n")
+
+	for _, s := range r.Sections {
+		b.WriteString("\n## " + s.Section.Title + "\n\n")
+		if s.Section.Description != "" {
+			b.WriteString(s.Section.Description + "\n\n")
+		}
+		switch {
+		case s.Skipped != "":
+			b.WriteString("> _Skipped: " + s.Skipped + "_\n")
+		case s.Error != "":
+			b.WriteString("> **Failed:** " + s.Error + "\n")
+		case s.Analysis != nil:
+			b.WriteString("```sql\n" + s.Section.Analyze + "\n```\n\n")
+			b.WriteString(formatAnalysis(s.Analysis, func(title string) string {
+				return "### " + title + "\n\n"
+			}))
+		case s.Result != nil:
+			if r.Metadata != nil {
+				b.WriteString("```sql\n" + s.Section.Query + "\n```\n\n")
+			}
+			b.WriteString(renderers.FormatQueryResult(s.Result, output.FormatMarkdown) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// htmlReport is the page written for html reports
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{"cell": renderers.FormatCell}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; }
+th { background: #f6f8fa; }
+pre { background: #f6f8fa; padding: 8px; }
+.meta, .note { color: #59636e; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}{{if .Connection}} on connection <code>{{.Connection}}</code>{{end}}{{with .Metadata}}{{.Byline}}{{end}}</p>
+{{range .Sections}}
+<h2>{{.Section.Title}}</h2>
+{{if .Section.Description}}<p>{{.Section.Description}}</p>{{end}}
+{{if .Skipped}}<p class="note"><em>Skipped: {{.Skipped}}</em></p>
+{{else if .Error}}<p class="error"><strong>Failed:</strong> {{.Error}}</p>
+{{else if .Analysis}}<pre>{{.Section.Analyze}}</pre>
+<h3>Findings</h3>
+<ul>{{range .Analysis.Findings}}<li>[{{.Severity}}] {{.Rule}}: {{.Message}}</li>{{else}}<li>none</li>{{end}}</ul>
+<h3>Plan ({{.Analysis.Profile}} profile)</h3>
+<ul>{{if .Analysis.PlanError}}<li>unavailable: {{.Analysis.PlanError}}</li>{{else if .Analysis.Plan}}<li>estimated cost {{printf "%.2f" .Analysis.Plan.TotalCost}}, ~{{printf "%.0f" .Analysis.Plan.EstimatedRows}} rows</li>{{range .Analysis.Plan.Warnings}}<li>{{.Message}}</li>{{end}}{{end}}</ul>
+{{else if .Result}}{{if $.Metadata}}<pre>{{.Section.Query}}</pre>
+{{end}}<table>
+<tr>{{range .Result.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Result.Rows}}<tr>{{range .}}<td>{{cell .}}</td>{{end}}</tr>
+{{end}}</table>
+<p class="note">{{if .Result.Truncated}}{{.Result.RowCount}} of {{.Result.TotalRows}} rows{{else}}{{len .Result.Rows}} rows{{end}}{{if .Result.Duration}}, {{.Result.Duration}}{{end}}</p>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// HTML renders the report as a standalone HTML page
+func (r *Report) HTML() (string, error) {
+	var b bytes.Buffer
+	if err := htmlReport.Execute(&b, r); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.String(), nil
+}
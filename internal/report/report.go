@@ -0,0 +1,107 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/export"
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Report is the outcome of running a template
+type Report struct {
+	Title       string
+	Connection  string
+	GeneratedAt time.Time
+	Sections    []SectionResult
+
+	// Set when DBSAGE_EXPORT_METADATA is on: the report then names the user and
+	// dbsage version that produced it and shows the query of each section
+	Metadata *export.Metadata
+}
+
+// SectionResult is the outcome of one section. A failing section does not stop
+// the report; its error is shown in place of the result.
+type SectionResult struct {
+	Section  Section
+	Result   *models.QueryResult
+	Analysis *tools.QueryAnalysis
+	Error    string
+	Skipped  string // Why the section did not run, set by runbooks for unmet conditions
+	Duration time.Duration
+}
+
+// Run executes every section of the template against db
+func Run(db dbinterfaces.DatabaseInterface, t *Template, connection string, now time.Time) *Report {
+	r := &Report{Title: t.Title, Connection: connection, GeneratedAt: now, Metadata: export.NewMetadata(connection, "", now)}
+	for _, section := range t.Sections {
+		r.Sections = append(r.Sections, RunSection(db, section))
+	}
+	return r
+}
+
+// RunSection runs the query or analysis of one section against db
+func RunSection(db dbinterfaces.DatabaseInterface, section Section) SectionResult {
+	sr := SectionResult{Section: section}
+	start := time.Now()
+
+	if section.Analyze != "" {
+		sr.Analysis = tools.AnalyzeQuery(db, section.Analyze)
+		sr.Duration = time.Since(start)
+		return sr
+	}
+
+	var result *models.QueryResult
+	var err error
+	if len(section.Args) > 0 {
+		result, err = dbinterfaces.ExecuteSQLWithArgs(db, section.Query, section.Args...)
+	} else {
+		result, err = db.ExecuteSQL(section.Query)
+	}
+	sr.Duration = time.Since(start)
+	if err != nil {
+		sr.Error = err.Error()
+		return sr
+	}
+
+	maxRows := section.MaxRows
+	if maxRows == 0 {
+		maxRows = DefaultMaxRows
+	}
+	if len(result.Rows) > maxRows {
+		limited := *result
+		limited.Rows = result.Rows[:maxRows]
+		limited.RowCount = maxRows
+		limited.Truncated = true
+		limited.TotalRows = len(result.Rows)
+		limited.TruncationReason = fmt.Sprintf("max_rows %d", maxRows)
+		result = &limited
+	}
+	sr.Result = result
+	return sr
+}
+
+// Failed returns the number of sections that failed
+func (r *Report) Failed() int {
+	failed := 0
+	for _, s := range r.Sections {
+		if s.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Render renders the report in the given format
+func (r *Report) Render(format string) (string, error) {
+	switch format {
+	case FormatMarkdown, "":
+		return r.Markdown(), nil
+	case FormatHTML:
+		return r.HTML()
+	default:
+		return "", fmt.Errorf("unknown format %q (use markdown or html)", format)
+	}
+}
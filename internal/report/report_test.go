@@ -0,0 +1,132 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbsage/internal/templatevars"
+	"dbsage/pkg/database/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "weekly.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadTemplate(t *testing.T) {
+	path := writeTemplate(t, `
+title: Weekly orders
+schedule: "0 8 * * 1"
+format: HTML
+output: reports/{name}-{date}.html
+sections:
+  - title: Orders
+    query: SELECT * FROM orders
+  - analyze: SELECT * FROM orders WHERE status = 'open'
+`)
+	tmpl, err := LoadTemplate(path)
+	require.NoError(t, err)
+	assert.Equal(t, "weekly", tmpl.Name())
+	assert.Equal(t, FormatHTML, tmpl.Format)
+	assert.Equal(t, "Section 2", tmpl.Sections[1].Title)
+	assert.Equal(t, "reports/weekly-2026-10-19.html",
+		ExpandPlaceholders(tmpl.Output, tmpl.Name(), time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)))
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tests := map[string]string{
+		"at least one section":         "title: x\n",
+		"exactly one of query":         "sections:\n  - query: SELECT 1\n    analyze: SELECT 1\n",
+		"must be read-only":            "sections:\n  - query: DELETE FROM orders\n",
+		"unknown format":               "format: pdf\nsections:\n  - query: SELECT 1\n",
+		"invalid schedule":             "schedule: every monday\nsections:\n  - query: SELECT 1\n",
+		"needs at least one recipient": "email:\n  subject: x\nsections:\n  - query: SELECT 1\n",
+	}
+	for want, content := range tests {
+		_, err := LoadTemplate(writeTemplate(t, content))
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestRunAndRender(t *testing.T) {
+	db, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "report.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.ExecuteSQL("CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT)")
+	require.NoError(t, err)
+	_, err = db.ExecuteSQL("INSERT INTO orders (customer) VALUES ('alice'), ('<bob>'), ('carol')")
+	require.NoError(t, err)
+
+	tmpl := &Template{
+		Title: "Weekly",
+		Sections: []Section{
+			{Title: "Orders", Query: "SELECT customer FROM orders ORDER BY id", MaxRows: 2},
+			{Title: "Broken", Query: "SELECT * FROM missing"},
+			{Title: "Plan", Analyze: "SELECT * FROM orders"},
+		},
+	}
+	require.NoError(t, tmpl.Validate())
+
+	r := Run(db, tmpl, "main", time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, 1, r.Failed())
+	require.NotNil(t, r.Sections[0].Result)
+	assert.True(t, r.Sections[0].Result.Truncated)
+	assert.NotNil(t, r.Sections[2].Analysis)
+
+	md := r.Markdown()
+	assert.Contains(t, md, "# Weekly")
+	assert.Contains(t, md, "on connection `main`")
+	assert.Contains(t, md, "| alice")
+	assert.Contains(t, md, "2 of 3 rows")
+	assert.Contains(t, md, "> **Failed:**")
+	assert.Contains(t, md, "### Findings")
+
+	html, err := r.HTML()
+	require.NoError(t, err)
+	assert.Contains(t, html, "<td>&lt;bob&gt;</td>")
+	assert.NotContains(t, html, "carol")
+	assert.Contains(t, html, "2 of 3 rows")
+
+	assert.NotContains(t, md, "```sql\nSELECT customer", "queries are shown only with metadata on")
+
+	t.Setenv("DBSAGE_EXPORT_METADATA", "on")
+	r = Run(db, tmpl, "main", time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	assert.Contains(t, r.Markdown(), " with dbsage ")
+	assert.Contains(t, r.Markdown(), "```sql\nSELECT customer FROM orders ORDER BY id\n```")
+	html, err = r.HTML()
+	require.NoError(t, err)
+	assert.Contains(t, html, "<pre>SELECT customer FROM orders ORDER BY id</pre>")
+}
+
+func TestApplyVariables(t *testing.T) {
+	path := writeTemplate(t, `
+title: Orders for {{tenant}}
+output: reports/{{tenant}}-{date}.md
+variables:
+  tenant:
+    prompt: Tenant name
+sections:
+  - title: Since {{last_monday}}
+    query: SELECT * FROM orders WHERE tenant = '{{tenant}}' AND created_at >= '{{last_monday}}'
+`)
+	tmpl, err := LoadTemplate(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Tenant name", tmpl.Variables["tenant"].Prompt)
+
+	values, err := templatevars.Resolve(tmpl.Texts(), tmpl.Variables, map[string]string{"tenant": "o'neil", "today": "2026-10-21"}, nil, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, tmpl.ApplyVariables(values, "postgresql"))
+
+	assert.Equal(t, "Orders for o'neil", tmpl.Title)
+	assert.Equal(t, "reports/o'neil-{date}.md", tmpl.Output)
+	assert.Equal(t, "Since 2026-10-12", tmpl.Sections[0].Title)
+	assert.Equal(t, "SELECT * FROM orders WHERE tenant = $1 AND created_at >= $2", tmpl.Sections[0].Query)
+	assert.Equal(t, []interface{}{"o'neil", "2026-10-12"}, tmpl.Sections[0].Args)
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	monday8 := time.Date(2026, 10, 19, 8, 0, 30, 0, time.Local)
+
+	tests := []struct {
+		expr  string
+		at    time.Time
+		match bool
+	}{
+		{"0 8 * * 1", monday8, true},
+		{"0 8 * * 1", monday8.Add(time.Minute), false},
+		{"0 8 * * 1", monday8.AddDate(0, 0, 1), false},
+		{"*/15 * * * *", monday8.Add(45 * time.Minute), true},
+		{"*/15 * * * *", monday8.Add(50 * time.Minute), false},
+		{"0 8-17 * * 1-5", monday8.Add(9 * time.Hour), true},
+		{"0 8 * * 0", monday8.AddDate(0, 0, 6), true},
+		{"0 8 * * 7", monday8.AddDate(0, 0, 6), true},
+		{"0 8 1,15 * *", time.Date(2026, 10, 15, 8, 0, 0, 0, time.Local), true},
+		// Day of month and day of week are OR-ed when both are restricted
+		{"0 8 1 * 1", monday8, true},
+		{"0 8 1 * 2", monday8, false},
+		{"@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), true},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.match, s.Matches(tt.at), "%s at %s", tt.expr, tt.at)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{"", "0 8 * *", "60 * * * *", "0 8 * * 8", "0 */0 * * *", "0 5-2 * * *", "a * * * *"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
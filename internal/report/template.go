@@ -0,0 +1,196 @@
+// Package report runs a templated set of queries and analyses against a
+// connection and renders the results as a markdown or HTML report, for
+// `dbsage report` and scheduled runs from cron.
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/internal/templatevars"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Template describes a report: where it runs, what it contains and where it is delivered
+type Template struct {
+	Title      string    `yaml:"title"`
+	Connection string    `yaml:"connection"` // Defaults to the last used connection
+	Schedule   string    `yaml:"schedule"`   // Cron expression, see ParseSchedule
+	Format     string    `yaml:"format"`     // markdown (default) or html
+	Output     string    `yaml:"output"`     // File path, may contain {date}, {time} and {name}
+	Email      *Email    `yaml:"email"`
+	Sections   []Section `yaml:"sections"`
+
+	// Variables declares prompts and defaults for the {{variables}} used in the
+	// template; undeclared variables are prompted for by name
+	Variables map[string]templatevars.Definition `yaml:"variables"`
+
+	name string // Template file name without extension
+}
+
+// Email addresses the report is sent to, using the DBSAGE_SMTP_* settings
+type Email struct {
+	To      []string `yaml:"to"`
+	Subject string   `yaml:"subject"` // Defaults to the title and date
+}
+
+// Section is one part of a report: the result of a query, or the lint findings
+// and estimated plan of a statement, which is never executed
+type Section struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Query       string `yaml:"query"`
+	Analyze     string `yaml:"analyze"`
+	MaxRows     int    `yaml:"max_rows"` // 0 for DefaultMaxRows
+
+	// Args holds the values of the bind parameters ApplyVariables put in Query
+	Args []interface{} `yaml:"-"`
+}
+
+// DefaultMaxRows limits the rows shown per query section
+const DefaultMaxRows = 100
+
+// LoadTemplate reads and validates a report template from a YAML file
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	t.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Name returns the template's file name without extension
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Validate checks the template and fills in defaults. Report queries must be
+// read-only, since scheduled runs have nobody to confirm a change.
+func (t *Template) Validate() error {
+	if t.Title == "" {
+		t.Title = "Database report"
+	}
+
+	switch strings.ToLower(t.Format) {
+	case "", FormatMarkdown, "md":
+		t.Format = FormatMarkdown
+	case FormatHTML:
+		t.Format = FormatHTML
+	default:
+		return fmt.Errorf("unknown format %q (use markdown or html)", t.Format)
+	}
+
+	if t.Schedule != "" {
+		if _, err := ParseSchedule(t.Schedule); err != nil {
+			return err
+		}
+	}
+	if t.Email != nil && len(t.Email.To) == 0 {
+		return fmt.Errorf("email needs at least one recipient in to")
+	}
+
+	if len(t.Sections) == 0 {
+		return fmt.Errorf("at least one section is required")
+	}
+	for i := range t.Sections {
+		s := &t.Sections[i]
+		s.Query = strings.TrimSpace(s.Query)
+		s.Analyze = strings.TrimSpace(s.Analyze)
+		if s.Title == "" {
+			s.Title = fmt.Sprintf("Section %d", i+1)
+		}
+		switch {
+		case (s.Query == "") == (s.Analyze == ""):
+			return fmt.Errorf("section %q needs exactly one of query or analyze", s.Title)
+		case s.Query != "" && !sqlanalysis.IsReadOnly(s.Query):
+			return fmt.Errorf("section %q: report queries must be read-only", s.Title)
+		case s.MaxRows < 0:
+			return fmt.Errorf("section %q: max_rows must not be negative", s.Title)
+		}
+	}
+	return nil
+}
+
+// textFields returns the plain-text fields that may contain {{variables}}
+func (t *Template) textFields() []*string {
+	fields := []*string{&t.Title, &t.Output}
+	if t.Email != nil {
+		fields = append(fields, &t.Email.Subject)
+	}
+	for i := range t.Sections {
+		fields = append(fields, &t.Sections[i].Title, &t.Sections[i].Description)
+	}
+	return fields
+}
+
+// sqlFields returns the statements, which may contain {{variables}}
+func (t *Template) sqlFields() []*string {
+	var fields []*string
+	for i := range t.Sections {
+		fields = append(fields, &t.Sections[i].Query, &t.Sections[i].Analyze)
+	}
+	return fields
+}
+
+// Texts returns the contents of every field that may contain {{variables}}
+func (t *Template) Texts() []string {
+	var texts []string
+	for _, field := range append(t.textFields(), t.sqlFields()...) {
+		texts = append(texts, *field)
+	}
+	return texts
+}
+
+// ApplyVariables expands the {{variables}} of the template with resolved
+// values, see templatevars.Resolve. Queries get them as bind parameters for
+// the dialect of the connection they run on.
+func (t *Template) ApplyVariables(values map[string]string, dialect string) error {
+	for _, field := range t.textFields() {
+		expanded, err := templatevars.Expand(*field, values)
+		if err != nil {
+			return err
+		}
+		*field = expanded
+	}
+	for i := range t.Sections {
+		if err := t.Sections[i].ApplyVariables(values, dialect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyVariables binds the {{variables}} of the section's query as parameters
+// and expands those of its analysis, which EXPLAIN runs without parameters
+func (s *Section) ApplyVariables(values map[string]string, dialect string) error {
+	query, args, err := templatevars.BindSQL(s.Query, dialect, values)
+	if err != nil {
+		return err
+	}
+	analyze, err := templatevars.ExpandSQL(s.Analyze, dialect, values)
+	if err != nil {
+		return err
+	}
+	s.Query, s.Args, s.Analyze = query, args, analyze
+	return nil
+}
@@ -0,0 +1,165 @@
+package runbook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/report"
+	"dbsage/internal/ui/renderers"
+)
+
+// condition is a when expression: comparisons joined with and, which binds
+// tighter, and or
+type condition struct {
+	any [][]comparison
+}
+
+// comparison tests a value of an earlier step, e.g. blocked.rows > 0. Without
+// an operator the value must be truthy.
+type comparison struct {
+	step, field string
+	op, value   string
+}
+
+var (
+	comparisonPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)\s*(?:(==|!=|>=|<=|>|<)\s*(.+))?$`)
+	orPattern         = regexp.MustCompile(`(?i)\s+or\s+`)
+	andPattern        = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// parseCondition parses a when expression. A step exposes rows, failed,
+// findings and cost (of an analysis), and the columns of its first row.
+func parseCondition(text string) (*condition, error) {
+	c := &condition{}
+	for _, alternative := range orPattern.Split(strings.TrimSpace(text), -1) {
+		var all []comparison
+		for _, part := range andPattern.Split(alternative, -1) {
+			m := comparisonPattern.FindStringSubmatch(strings.TrimSpace(part))
+			if m == nil {
+				return nil, fmt.Errorf("invalid condition %q, expected e.g. step.rows > 0 or step.column == 'value'", part)
+			}
+			value := strings.TrimSpace(m[4])
+			if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			all = append(all, comparison{step: m[1], field: m[2], op: m[3], value: value})
+		}
+		c.any = append(c.any, all)
+	}
+	return c, nil
+}
+
+// steps returns the ids of the steps the condition refers to
+func (c *condition) steps() []string {
+	var ids []string
+	for _, all := range c.any {
+		for _, cmp := range all {
+			ids = append(ids, cmp.step)
+		}
+	}
+	return ids
+}
+
+// eval evaluates the condition on the results of the earlier steps
+func (c *condition) eval(results map[string]report.SectionResult) (bool, error) {
+	for _, all := range c.any {
+		matched := true
+		for _, cmp := range all {
+			ok, err := cmp.eval(results[cmp.step])
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (cmp comparison) eval(result report.SectionResult) (bool, error) {
+	actual, err := fieldValue(result, cmp.field)
+	if err != nil {
+		return false, fmt.Errorf("%s.%s: %w", cmp.step, cmp.field, err)
+	}
+	if cmp.op == "" {
+		switch strings.ToLower(actual) {
+		case "", "0", "false", "f", "no", "null":
+			return false, nil
+		}
+		return true, nil
+	}
+
+	a, aErr := strconv.ParseFloat(actual, 64)
+	b, bErr := strconv.ParseFloat(cmp.value, 64)
+	if aErr != nil || bErr != nil {
+		switch cmp.op {
+		case "==":
+			return strings.EqualFold(actual, cmp.value), nil
+		case "!=":
+			return !strings.EqualFold(actual, cmp.value), nil
+		}
+		return false, fmt.Errorf("cannot compare %q with %s %s", actual, cmp.op, cmp.value)
+	}
+	switch cmp.op {
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	case "<":
+		return a < b, nil
+	default:
+		return a <= b, nil
+	}
+}
+
+// fieldValue returns a value of a step's result as text
+func fieldValue(result report.SectionResult, field string) (string, error) {
+	switch strings.ToLower(field) {
+	case "failed":
+		return strconv.FormatBool(result.Error != ""), nil
+	case "skipped":
+		return strconv.FormatBool(result.Skipped != ""), nil
+	case "rows":
+		if result.Result == nil {
+			return "0", nil
+		}
+		if result.Result.Truncated {
+			return strconv.Itoa(result.Result.TotalRows), nil
+		}
+		return strconv.Itoa(len(result.Result.Rows)), nil
+	case "findings":
+		if result.Analysis == nil {
+			return "0", nil
+		}
+		return strconv.Itoa(len(result.Analysis.Findings)), nil
+	case "cost":
+		if result.Analysis == nil || result.Analysis.Plan == nil {
+			return "0", nil
+		}
+		return strconv.FormatFloat(result.Analysis.Plan.TotalCost, 'f', -1, 64), nil
+	}
+
+	if result.Result == nil {
+		return "", nil
+	}
+	for i, column := range result.Result.Columns {
+		if strings.EqualFold(column, field) {
+			if len(result.Result.Rows) == 0 || i >= len(result.Result.Rows[0]) {
+				return "", nil
+			}
+			return renderers.FormatCell(result.Result.Rows[0][i]), nil
+		}
+	}
+	return "", fmt.Errorf("no column %s in the result", field)
+}
@@ -0,0 +1,53 @@
+package runbook
+
+import (
+	"fmt"
+	"time"
+
+	"dbsage/internal/export"
+	"dbsage/internal/report"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Run executes the steps of the runbook in order against db. Steps whose
+// condition is false are skipped, a stop step skips everything after it, and
+// a failing step does not stop the runbook; later conditions can test it
+// with step.failed.
+func Run(db dbinterfaces.DatabaseInterface, rb *Runbook, connection string, now time.Time) *report.Report {
+	r := &report.Report{Title: rb.Title, Connection: connection, GeneratedAt: now, Metadata: export.NewMetadata(connection, "", now)}
+	results := make(map[string]report.SectionResult)
+	stoppedAt := ""
+
+	for _, step := range rb.Steps {
+		sr := report.SectionResult{Section: step.Section}
+		run := true
+		switch {
+		case stoppedAt != "":
+			sr.Skipped = fmt.Sprintf("the runbook stopped at %q", stoppedAt)
+			run = false
+		case step.condition != nil:
+			ok, err := step.condition.eval(results)
+			if err != nil {
+				sr.Error = "when: " + err.Error()
+				run = false
+			} else if !ok {
+				sr.Skipped = "when " + step.When + " is false"
+				run = false
+			}
+		}
+
+		if run {
+			if step.Query != "" || step.Analyze != "" {
+				sr = report.RunSection(db, step.Section)
+			}
+			if step.Stop {
+				stoppedAt = step.Title
+			}
+		}
+		if step.ID != "" {
+			results[step.ID] = sr
+		}
+		r.Sections = append(r.Sections, sr)
+	}
+	return r
+}
@@ -0,0 +1,199 @@
+// Package runbook runs saved triage procedures: YAML files of diagnostic
+// queries and query analyses, where a step may depend on the results of the
+// steps before it. The outcome of every step is collected into a report.
+package runbook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dbsage/internal/report"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/internal/templatevars"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Runbook is an ordered list of steps run against the current connection
+type Runbook struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Output      string `yaml:"output"` // File the report is also written to, may contain {date}, {time} and {name}
+	Steps       []Step `yaml:"steps"`
+
+	// Variables declares defaults for the {{variables}} used in the steps
+	Variables map[string]templatevars.Definition `yaml:"variables"`
+
+	name string // File name without extension
+}
+
+// Step runs a read-only query or analyzes a statement like a report section.
+// A step without either only adds its description to the report, which with
+// stop ends a runbook with a conclusion.
+type Step struct {
+	report.Section `yaml:",inline"`
+
+	ID   string `yaml:"id"`   // Name later conditions refer to the step by
+	When string `yaml:"when"` // Condition on earlier steps, e.g. "blocked.rows > 0"; the step is skipped when false
+	Stop bool   `yaml:"stop"` // End the runbook once this step has run
+
+	condition *condition
+}
+
+// stepID matches the names steps are referred to by in conditions
+var stepID = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Dir returns the directory runbooks are looked up in by name
+func Dir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "runbooks")
+}
+
+// Find returns the file of a runbook given by name, e.g. incident-latency for
+// ~/.dbsage/runbooks/incident-latency.yaml, or by path
+func Find(name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+		return name, nil
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(Dir(), name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no runbook %q in %s", name, Dir())
+}
+
+// List returns the names of the runbooks in Dir, sorted
+func List() ([]string, error) {
+	entries, err := os.ReadDir(Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list runbooks: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads and validates a runbook from a YAML file
+func Load(path string) (*Runbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runbook: %w", err)
+	}
+
+	var rb Runbook
+	if err := yaml.Unmarshal(data, &rb); err != nil {
+		return nil, fmt.Errorf("failed to parse runbook %s: %w", path, err)
+	}
+	rb.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	if err := rb.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid runbook %s: %w", path, err)
+	}
+	return &rb, nil
+}
+
+// Name returns the runbook's file name without extension
+func (rb *Runbook) Name() string {
+	return rb.name
+}
+
+// Validate checks the runbook and fills in defaults. Steps run without
+// confirmation, so their queries must be read-only, and conditions may only
+// refer to earlier steps.
+func (rb *Runbook) Validate() error {
+	if rb.Title == "" {
+		rb.Title = "Runbook " + rb.name
+	}
+	if len(rb.Steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+
+	earlier := make(map[string]bool)
+	for i := range rb.Steps {
+		s := &rb.Steps[i]
+		s.Query = strings.TrimSpace(s.Query)
+		s.Analyze = strings.TrimSpace(s.Analyze)
+		if s.Title == "" {
+			s.Title = fmt.Sprintf("Step %d", i+1)
+		}
+		switch {
+		case s.Query != "" && s.Analyze != "":
+			return fmt.Errorf("step %q needs at most one of query or analyze", s.Title)
+		case s.Query == "" && s.Analyze == "" && s.Description == "":
+			return fmt.Errorf("step %q needs a query, analyze or description", s.Title)
+		case s.Query != "" && !sqlanalysis.IsReadOnly(s.Query):
+			return fmt.Errorf("step %q: runbook queries must be read-only", s.Title)
+		case s.MaxRows < 0:
+			return fmt.Errorf("step %q: max_rows must not be negative", s.Title)
+		}
+
+		if s.When != "" {
+			cond, err := parseCondition(s.When)
+			if err != nil {
+				return fmt.Errorf("step %q: %w", s.Title, err)
+			}
+			for _, id := range cond.steps() {
+				if !earlier[id] {
+					return fmt.Errorf("step %q: when refers to %q, which is not an earlier step", s.Title, id)
+				}
+			}
+			s.condition = cond
+		}
+		if s.ID != "" {
+			if !stepID.MatchString(s.ID) {
+				return fmt.Errorf("step %q: id %q must be a name like blocked_sessions", s.Title, s.ID)
+			}
+			if earlier[s.ID] {
+				return fmt.Errorf("step id %q is used twice", s.ID)
+			}
+			earlier[s.ID] = true
+		}
+	}
+	return nil
+}
+
+// Texts returns the contents of every field that may contain {{variables}}
+func (rb *Runbook) Texts() []string {
+	texts := []string{rb.Title, rb.Output}
+	for _, s := range rb.Steps {
+		texts = append(texts, s.Title, s.Description, s.Query, s.Analyze)
+	}
+	return texts
+}
+
+// ApplyVariables expands the {{variables}} of the runbook with resolved
+// values, see templatevars.Resolve. Queries get them as bind parameters for
+// the dialect of the connection they run on.
+func (rb *Runbook) ApplyVariables(values map[string]string, dialect string) error {
+	text := []*string{&rb.Title, &rb.Output}
+	for i := range rb.Steps {
+		s := &rb.Steps[i]
+		text = append(text, &s.Title, &s.Description)
+		if err := s.Section.ApplyVariables(values, dialect); err != nil {
+			return err
+		}
+	}
+	for _, field := range text {
+		expanded, err := templatevars.Expand(*field, values)
+		if err != nil {
+			return err
+		}
+		*field = expanded
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package runbook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/internal/report"
+	"dbsage/internal/templatevars"
+	"dbsage/pkg/database/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRunbook(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "incident.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	rb, err := Load(writeRunbook(t, `
+steps:
+  - id: blocked
+    query: SELECT 1
+  - when: blocked.rows > 0 and blocked.failed == false
+    description: Blocked
+    stop: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "incident", rb.Name())
+	assert.Equal(t, "Runbook incident", rb.Title)
+	assert.Equal(t, "Step 2", rb.Steps[1].Title)
+
+	for content, want := range map[string]string{
+		"steps: []":                        "at least one step",
+		"steps:\n  - query: DELETE FROM t": "read-only",
+		"steps:\n  - title: Empty":         "needs a query, analyze or description",
+		"steps:\n  - when: later.rows > 0\n    query: SELECT 1\n  - id: later\n    query: SELECT 2": "not an earlier step",
+		"steps:\n  - id: a\n    query: SELECT 1\n  - when: a.rows >\n    query: SELECT 2":           "invalid condition",
+		"steps:\n  - id: a\n    query: SELECT 1\n  - id: a\n    query: SELECT 2":                    "used twice",
+	} {
+		_, err := Load(writeRunbook(t, content))
+		assert.ErrorContains(t, err, want, content)
+	}
+}
+
+func TestFindAndList(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	names, err := List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, os.MkdirAll(Dir(), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(), "locks.yml"), []byte("steps: []"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(), "notes.txt"), nil, 0o644))
+	names, err = List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"locks"}, names)
+
+	path, err := Find("locks")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(Dir(), "locks.yml"), path)
+	path, err = Find("./other.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "./other.yaml", path)
+	_, err = Find("missing")
+	assert.ErrorContains(t, err, "no runbook")
+}
+
+func TestCondition(t *testing.T) {
+	results := map[string]report.SectionResult{
+		"sessions": {Result: &models.QueryResult{Columns: []string{"state", "waiting"}, Rows: [][]interface{}{{"active", int64(3)}}}},
+		"broken":   {Error: "syntax error"},
+	}
+	for text, want := range map[string]bool{
+		"sessions.rows == 1":                          true,
+		"sessions.rows > 1":                           false,
+		"sessions.state == 'ACTIVE'":                  true,
+		"sessions.waiting >= 3 and sessions.rows < 2": true,
+		"sessions.waiting > 5 or broken.failed":       true,
+		"broken.rows > 0":                             false,
+		"sessions.failed":                             false,
+	} {
+		cond, err := parseCondition(text)
+		require.NoError(t, err, text)
+		got, err := cond.eval(results)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, got, text)
+	}
+
+	cond, err := parseCondition("sessions.missing == 1")
+	require.NoError(t, err)
+	_, err = cond.eval(results)
+	assert.ErrorContains(t, err, "no column missing")
+}
+
+func TestRun(t *testing.T) {
+	db, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "runbook.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.ExecuteSQL("CREATE TABLE jobs (id INTEGER, state TEXT)")
+	require.NoError(t, err)
+	_, err = db.ExecuteSQL("INSERT INTO jobs VALUES (1, 'stuck'), (2, 'done')")
+	require.NoError(t, err)
+
+	rb, err := Load(writeRunbook(t, `
+title: Jobs triage
+steps:
+  - id: stuck
+    title: Stuck jobs
+    query: SELECT * FROM jobs WHERE state = '{{state|stuck}}'
+  - id: broken
+    title: Broken step
+    query: SELECT * FROM missing_table
+  - title: Nothing stuck
+    when: stuck.rows == 0
+    description: All jobs are moving.
+    stop: true
+  - title: Stuck and broken
+    when: stuck.rows > 0 and broken.failed
+    description: Stuck jobs found.
+    stop: true
+  - title: Oldest job
+    query: SELECT min(id) FROM jobs
+`))
+	require.NoError(t, err)
+	values, err := templatevars.Resolve(rb.Texts(), rb.Variables, nil, nil, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, rb.ApplyVariables(values, db.DatabaseType()))
+
+	r := Run(db, rb, "jobs", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	require.Len(t, r.Sections, 5)
+	assert.Equal(t, 1, len(r.Sections[0].Result.Rows))
+	assert.NotEmpty(t, r.Sections[1].Error)
+	assert.Equal(t, "when stuck.rows == 0 is false", r.Sections[2].Skipped)
+	assert.Empty(t, r.Sections[3].Skipped)
+	assert.Equal(t, `the runbook stopped at "Stuck and broken"`, r.Sections[4].Skipped)
+	assert.Equal(t, 1, r.Failed())
+
+	markdown := r.Markdown()
+	assert.Contains(t, markdown, "# Jobs triage")
+	assert.Contains(t, markdown, "Stuck jobs found.")
+	assert.Contains(t, markdown, "_Skipped: when stuck.rows == 0 is false_")
+}
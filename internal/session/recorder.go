@@ -0,0 +1,176 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording formats
+const (
+	FormatAsciicast = "asciicast" // asciinema v2 .cast file, playable with `asciinema play`
+	FormatDBSage    = "dbsage"    // JSON lines with separate input and output events
+)
+
+// Event kinds in the dbsage format
+const (
+	EventInput  = "input"
+	EventOutput = "output"
+)
+
+// Header is the first line of a dbsage recording
+type Header struct {
+	Version   int    `json:"version"`
+	Format    string `json:"format"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Event is a single recorded input or output
+type Event struct {
+	Time       float64 `json:"time"` // Seconds since the recording started
+	Kind       string  `json:"kind"`
+	Text       string  `json:"text"`
+	DurationMs int64   `json:"duration_ms,omitempty"` // Time since the preceding input, for outputs
+}
+
+// Recorder writes a session recording to a file
+type Recorder struct {
+	mu        sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	path      string
+	format    string
+	started   time.Time
+	lastInput time.Time
+	events    int
+}
+
+// FormatForPath picks the recording format from the file extension
+func FormatForPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".cast") {
+		return FormatAsciicast
+	}
+	return FormatDBSage
+}
+
+// StartRecording creates the file at path and writes the recording header
+func StartRecording(path string, width, height int) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	r := &Recorder{
+		file:    file,
+		writer:  bufio.NewWriter(file),
+		path:    path,
+		format:  FormatForPath(path),
+		started: time.Now(),
+	}
+
+	var header interface{}
+	if r.format == FormatAsciicast {
+		header = map[string]interface{}{
+			"version":   2,
+			"width":     width,
+			"height":    height,
+			"timestamp": r.started.Unix(),
+			"title":     "dbsage session",
+		}
+	} else {
+		header = Header{Version: 1, Format: FormatDBSage, Timestamp: r.started.Unix()}
+	}
+	if err := r.writeLine(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the file the session is recorded to
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Format returns the recording format
+func (r *Recorder) Format() string {
+	return r.format
+}
+
+// Events returns the number of events recorded so far
+func (r *Recorder) Events() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+// RecordInput records a line entered by the user
+func (r *Recorder) RecordInput(text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.lastInput = now
+	return r.writeEvent(now, Event{Kind: EventInput, Text: text})
+}
+
+// RecordOutput records a response shown to the user
+func (r *Recorder) RecordOutput(text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	event := Event{Kind: EventOutput, Text: text}
+	if !r.lastInput.IsZero() {
+		event.DurationMs = now.Sub(r.lastInput).Milliseconds()
+	}
+	return r.writeEvent(now, event)
+}
+
+// Close flushes and closes the recording file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func (r *Recorder) writeEvent(now time.Time, event Event) error {
+	event.Time = now.Sub(r.started).Seconds()
+	r.events++
+
+	if r.format == FormatAsciicast {
+		return r.writeLine([]interface{}{event.Time, "o", terminalText(event)})
+	}
+	return r.writeLine(event)
+}
+
+func (r *Recorder) writeLine(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := r.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	// Flush every line so the recording survives a crash
+	return r.writer.Flush()
+}
+
+// terminalText renders an event as it appears in the terminal
+func terminalText(event Event) string {
+	text := event.Text
+	if event.Kind == EventInput {
+		text = "> " + text
+	}
+	return strings.ReplaceAll(text, "\n", "\r\n") + "\r\n\r\n"
+}
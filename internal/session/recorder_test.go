@@ -0,0 +1,77 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_DBSageFormatRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	r, err := StartRecording(path, 80, 24)
+	require.NoError(t, err)
+	assert.Equal(t, FormatDBSage, r.Format())
+	require.NoError(t, r.RecordInput("show tables"))
+	require.NoError(t, r.RecordOutput("users\norders"))
+	require.NoError(t, r.Close())
+	assert.Equal(t, 2, r.Events())
+
+	events, err := LoadRecording(path)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventInput, events[0].Kind)
+	assert.Equal(t, "show tables", events[0].Text)
+	assert.Equal(t, EventOutput, events[1].Kind)
+	assert.Equal(t, "users\norders", events[1].Text)
+	assert.GreaterOrEqual(t, events[1].Time, events[0].Time)
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(events, &out, ReplayOptions{Speed: 100}))
+	assert.True(t, strings.HasPrefix(out.String(), "> show tables\n\nusers\norders\n"))
+}
+
+func TestRecorder_AsciicastFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+
+	r, err := StartRecording(path, 120, 40)
+	require.NoError(t, err)
+	assert.Equal(t, FormatAsciicast, r.Format())
+	require.NoError(t, r.RecordInput("/list"))
+	require.NoError(t, r.RecordOutput("line1\nline2"))
+	require.NoError(t, r.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	var header map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, float64(2), header["version"])
+	assert.Equal(t, float64(120), header["width"])
+
+	var event []interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, "o", event[1])
+	assert.Equal(t, "line1\r\nline2\r\n\r\n", event[2])
+
+	events, err := LoadRecording(path)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "> /list\r\n\r\n", events[0].Text)
+}
+
+func TestLoadRecording_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.cast")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	_, err := LoadRecording(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReplayOptions controls playback of a recording
+type ReplayOptions struct {
+	Speed   float64       // Playback speed multiplier, 1 is real time
+	MaxIdle time.Duration // Longest pause between events, 0 for no limit
+}
+
+// LoadRecording reads a recording in either format and returns its events
+func LoadRecording(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("recording is empty")
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("invalid recording header: %w", err)
+	}
+	asciicast := header["format"] != FormatDBSage
+
+	var events []Event
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		event, ok, err := parseEvent([]byte(raw), asciicast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event on line %d: %w", line+1, err)
+		}
+		if ok {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// parseEvent decodes one event line. Asciicast input events are skipped since
+// the typed text is already part of the output stream.
+func parseEvent(data []byte, asciicast bool) (Event, bool, error) {
+	if !asciicast {
+		var event Event
+		err := json.Unmarshal(data, &event)
+		return event, err == nil, err
+	}
+
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Event{}, false, err
+	}
+	if len(fields) < 3 {
+		return Event{}, false, fmt.Errorf("expected [time, type, data]")
+	}
+	t, _ := fields[0].(float64)
+	kind, _ := fields[1].(string)
+	text, _ := fields[2].(string)
+	if kind != "o" {
+		return Event{}, false, nil
+	}
+	return Event{Time: t, Kind: EventOutput, Text: text}, true, nil
+}
+
+// Replay writes events to out, sleeping between them to reproduce the original timing
+func Replay(events []Event, out io.Writer, opts ReplayOptions) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	previous := 0.0
+	for _, event := range events {
+		wait := time.Duration((event.Time - previous) / opts.Speed * float64(time.Second))
+		if opts.MaxIdle > 0 && wait > opts.MaxIdle {
+			wait = opts.MaxIdle
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		previous = event.Time
+
+		if _, err := io.WriteString(out, formatReplayEvent(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatReplayEvent renders a dbsage event for playback; asciicast output is written as is
+func formatReplayEvent(event Event) string {
+	if strings.HasSuffix(event.Text, "\r\n") {
+		return event.Text
+	}
+	if event.Kind == EventInput {
+		return "> " + event.Text + "\n\n"
+	}
+	text := event.Text + "\n"
+	if event.DurationMs > 0 {
+		text += fmt.Sprintf("(%s)\n", time.Duration(event.DurationMs)*time.Millisecond)
+	}
+	return text + "\n"
+}
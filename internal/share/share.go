@@ -0,0 +1,208 @@
+// Package share writes a session transcript to a portable JSON file with
+// secrets and sensitive values removed, for /share, and reads it back so
+// another dbsage can review the investigation read-only with /open.
+package share
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"dbsage/internal/masking"
+	"dbsage/internal/models"
+	"dbsage/internal/notebook"
+	"dbsage/internal/version"
+)
+
+// FormatName identifies transcript files
+const FormatName = "dbsage-transcript"
+
+// FormatVersion is incremented whenever a field of Transcript or the types it
+// contains is removed or changes meaning. Added fields do not change it.
+const FormatVersion = 1
+
+// MaxResultRows is the most rows of a result kept in a transcript
+const MaxResultRows = 100
+
+// Transcript is a shared session
+type Transcript struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SharedAt      time.Time `json:"shared_at"`
+	DbsageVersion string    `json:"dbsage_version"`
+	Connection    string    `json:"connection,omitempty"`    // Name of the connection, never its address or credentials
+	DatabaseType  string    `json:"database_type,omitempty"` // postgresql, mysql, sqlite...
+	Turns         []Turn    `json:"turns"`
+	Redactions    int       `json:"redactions"`       // Secrets replaced in questions, SQL, answers and results
+	Masked        int       `json:"masked,omitempty"` // Result values removed by the connection's masking rules
+}
+
+// Turn is one question with the SQL executed to answer it, the answer and the
+// last result it produced
+type Turn struct {
+	Question string              `json:"question,omitempty"`
+	SQL      []string            `json:"sql,omitempty"`
+	Answer   string              `json:"answer,omitempty"`
+	Result   *models.QueryResult `json:"result,omitempty"`
+}
+
+// redactedSecret replaces secrets found in text
+const redactedSecret = "[redacted]"
+
+// secretKeys matches the name of a setting holding a secret, with what
+// separates it from its value
+const secretKeys = `\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[=:]\s*`
+
+// secretPatterns match credentials in text, each with the replacement that
+// keeps what surrounds the secret
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/\s@]+:)[^@\s]+@`), "${1}" + redactedSecret + "@"},         // URL credentials
+	{regexp.MustCompile(`(?i)(` + secretKeys + `)"[^"]*"`), `${1}"` + redactedSecret + `"`},                       // key="value" settings
+	{regexp.MustCompile(`(?i)(` + secretKeys + `)'(?:[^']|'')*'`), "${1}'" + redactedSecret + "'"},                // key='value' settings
+	{regexp.MustCompile(`(?i)(` + secretKeys + `)[^\s"',;&]+`), "${1}" + redactedSecret},                          // key=value settings
+	{regexp.MustCompile(`(?i)(\b(?:IDENTIFIED\s+BY|PASSWORD)\s+)'(?:[^']|'')*'`), "${1}'" + redactedSecret + "'"}, // CREATE USER, ALTER ROLE
+	{regexp.MustCompile(`(?i)(\bBearer\s+)[A-Za-z0-9._~+/=-]{16,}`), "${1}" + redactedSecret},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`), redactedSecret},        // OpenAI and Anthropic keys
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), redactedSecret},           // AWS access keys
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}\b`), redactedSecret}, // GitHub tokens
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), redactedSecret}, // Slack tokens
+}
+
+// RedactSecrets replaces credentials in text, and any of the given secret
+// values, and returns the number replaced
+func RedactSecrets(text string, secrets []string) (string, int) {
+	count := 0
+	for _, secret := range secrets {
+		if len(secret) >= 4 && strings.Contains(text, secret) {
+			count += strings.Count(text, secret)
+			text = strings.ReplaceAll(text, secret, redactedSecret)
+		}
+	}
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			redacted := secret.pattern.ReplaceAllString(match, secret.replacement)
+			if redacted != match {
+				count++
+			}
+			return redacted
+		})
+	}
+	return text, count
+}
+
+// New builds a transcript of the turns of a session on a connection. Secrets
+// are redacted from every text, result values are masked by the connection's
+// masking settings and results are cut to MaxResultRows rows.
+func New(turns []notebook.Turn, connection, databaseType string, settings masking.Settings, secrets []string, now time.Time) *Transcript {
+	t := &Transcript{
+		Format:        FormatName,
+		Version:       FormatVersion,
+		SharedAt:      now,
+		DbsageVersion: version.Version,
+		Connection:    connection,
+		DatabaseType:  databaseType,
+		Turns:         []Turn{},
+	}
+	redact := func(text string) string {
+		text, n := RedactSecrets(text, secrets)
+		t.Redactions += n
+		return text
+	}
+
+	for _, turn := range turns {
+		shared := Turn{Question: redact(turn.Question), Answer: redact(turn.Answer)}
+		for _, sql := range turn.SQL {
+			shared.SQL = append(shared.SQL, redact(sql))
+		}
+		if turn.Result != nil {
+			shared.Result = t.sanitizeResult(turn.Result, settings, redact)
+		}
+		t.Turns = append(t.Turns, shared)
+	}
+	return t
+}
+
+// sanitizeResult returns a copy of a result cut to MaxResultRows rows, with
+// sensitive values masked and secrets redacted from its text
+func (t *Transcript) sanitizeResult(result *models.QueryResult, settings masking.Settings, redact func(string) string) *models.QueryResult {
+	total := len(result.Rows)
+	if total > MaxResultRows {
+		cut := *result
+		cut.Rows = result.Rows[:MaxResultRows]
+		cut.Truncated = true
+		cut.TotalRows = total
+		cut.TruncationReason = fmt.Sprintf("shared transcripts keep %d rows", MaxResultRows)
+		result = &cut
+	}
+	masked, findings := settings.Mask(result)
+	for _, f := range findings {
+		t.Masked += f.Values
+	}
+
+	shared := &models.QueryResult{
+		Columns:          masked.Columns,
+		Rows:             make([][]interface{}, len(masked.Rows)),
+		RowCount:         len(masked.Rows),
+		Duration:         masked.Duration,
+		Truncated:        masked.Truncated,
+		TotalRows:        masked.TotalRows,
+		TruncationReason: masked.TruncationReason,
+		Masked:           masking.Describe(findings),
+	}
+	for i, row := range masked.Rows {
+		shared.Rows[i] = make([]interface{}, len(row))
+		for j, value := range row {
+			switch v := value.(type) {
+			case string:
+				shared.Rows[i][j] = redact(v)
+			case []byte:
+				shared.Rows[i][j] = redact(string(v))
+			default:
+				shared.Rows[i][j] = v
+			}
+		}
+	}
+	return shared
+}
+
+// Write saves a transcript as indented JSON, creating parent directories as
+// needed
+func Write(path string, t *Transcript) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Read loads a transcript written by Write, refusing other files and newer
+// format versions
+func Read(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil || t.Format != FormatName {
+		return nil, fmt.Errorf("%s is not a dbsage transcript: create one with /share", path)
+	}
+	if t.Version > FormatVersion {
+		return nil, fmt.Errorf("%s was shared by a newer dbsage (transcript version %d, this one reads %d): upgrade to open it", path, t.Version, FormatVersion)
+	}
+	return &t, nil
+}
@@ -0,0 +1,323 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// Collation problem kinds
+const (
+	CollationUTF8MB3       = "utf8mb3"
+	CollationJoinMismatch  = "join_mismatch"
+	CollationMixed         = "mixed_collations"
+	CollationMixedProvider = "mixed_providers"
+)
+
+// LargeConversionBytes is the table size above which conversion DDL is flagged
+// as needing a maintenance window or an online schema change tool
+const LargeConversionBytes = 1 << 30
+
+// ColumnCollation is the character set and collation of a text column
+type ColumnCollation struct {
+	Table      string `json:"table"`
+	Column     string `json:"column"`
+	Type       string `json:"type"`
+	Charset    string `json:"charset,omitempty"`  // MySQL only
+	Collation  string `json:"collation"`          // "default" for the PostgreSQL database default
+	Provider   string `json:"provider,omitempty"` // PostgreSQL: libc, icu or default
+	TableBytes int64  `json:"-"`
+}
+
+// ForeignKeyColumn is one column pair of a foreign key
+type ForeignKeyColumn struct {
+	Table, Column       string
+	RefTable, RefColumn string
+}
+
+// CollationProblem is a collation or encoding issue with the DDL that fixes it
+type CollationProblem struct {
+	Kind     string   `json:"kind"`
+	Message  string   `json:"message"`
+	DDL      []string `json:"ddl,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CollationReport lists the encodings and collations of a database and the problems they cause
+type CollationReport struct {
+	Encoding  string             `json:"encoding"`
+	Collation string             `json:"collation"`
+	Usage     map[string]int     `json:"columns_per_collation"`
+	Problems  []CollationProblem `json:"problems"`
+}
+
+// BuildCollationQueries returns the queries reading the database defaults
+// (encoding, collation), the text columns (table, column, type, charset,
+// collation, provider, table bytes) and the foreign key column pairs
+func BuildCollationQueries(dialect string) (defaults, columns, foreignKeys string, err error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT pg_encoding_to_char(encoding), datcollate FROM pg_database WHERE datname = current_database()`,
+			`SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), '', co.collname,
+	CASE co.collprovider WHEN 'i' THEN 'icu' WHEN 'c' THEN 'libc' ELSE 'default' END, pg_total_relation_size(c.oid)
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_collation co ON co.oid = a.attcollation
+WHERE c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'
+ORDER BY c.relname, a.attnum`,
+			`SELECT kcu.table_name, kcu.column_name, ccu.table_name, ccu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+JOIN information_schema.constraint_column_usage ccu ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+WHERE tc.constraint_type = 'FOREIGN KEY'`, nil
+	case "mysql":
+		return `SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = DATABASE()`,
+			`SELECT c.TABLE_NAME, c.COLUMN_NAME, c.COLUMN_TYPE, c.CHARACTER_SET_NAME, c.COLLATION_NAME, '', t.DATA_LENGTH + t.INDEX_LENGTH
+FROM information_schema.COLUMNS c
+JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND c.COLLATION_NAME IS NOT NULL AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`,
+			`SELECT TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`, nil
+	default:
+		return "", "", "", fmt.Errorf("collation diagnostics are not supported for %s databases", dialect)
+	}
+}
+
+// ColumnCollationsFromResult converts a collation columns query result
+func ColumnCollationsFromResult(result *models.QueryResult) []ColumnCollation {
+	if result == nil {
+		return nil
+	}
+	var columns []ColumnCollation
+	for _, row := range result.Rows {
+		if len(row) < 7 {
+			continue
+		}
+		columns = append(columns, ColumnCollation{
+			Table:      cellText(row[0]),
+			Column:     cellText(row[1]),
+			Type:       cellText(row[2]),
+			Charset:    cellText(row[3]),
+			Collation:  cellText(row[4]),
+			Provider:   cellText(row[5]),
+			TableBytes: int64(toFloat(cellString(row[6]))),
+		})
+	}
+	return columns
+}
+
+// ForeignKeysFromResult converts a foreign key columns query result
+func ForeignKeysFromResult(result *models.QueryResult) []ForeignKeyColumn {
+	if result == nil {
+		return nil
+	}
+	var keys []ForeignKeyColumn
+	for _, row := range result.Rows {
+		if len(row) < 4 {
+			continue
+		}
+		keys = append(keys, ForeignKeyColumn{
+			Table: cellText(row[0]), Column: cellText(row[1]),
+			RefTable: cellText(row[2]), RefColumn: cellText(row[3]),
+		})
+	}
+	return keys
+}
+
+// cellText renders a result cell as text, with NULL as ""
+func cellText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", cellString(value))
+}
+
+// AnalyzeCollations finds the encoding and collation problems of a database:
+// utf8mb3 columns, foreign keys joining columns of different collations,
+// collations mixed across tables and ICU mixed with libc, each with the DDL
+// that aligns them and warnings about size and locking
+func AnalyzeCollations(dialect, encoding, collation string, columns []ColumnCollation, keys []ForeignKeyColumn) *CollationReport {
+	dialect = normalizeDialect(dialect)
+	report := &CollationReport{Encoding: encoding, Collation: collation, Usage: make(map[string]int), Problems: []CollationProblem{}}
+
+	byColumn := make(map[string]ColumnCollation)
+	for _, col := range columns {
+		report.Usage[col.Collation]++
+		byColumn[strings.ToLower(col.Table+"."+col.Column)] = col
+	}
+
+	if dialect == "mysql" {
+		target := collation
+		if !strings.HasPrefix(strings.ToLower(target), "utf8mb4") {
+			target = "utf8mb4_unicode_ci"
+		}
+		if p, ok := mysqlUTF8MB3Problem(columns, target); ok {
+			report.Problems = append(report.Problems, p)
+		}
+		if p, ok := mysqlMixedProblem(columns, target); ok {
+			report.Problems = append(report.Problems, p)
+		}
+	} else if p, ok := postgresProviderProblem(columns); ok {
+		report.Problems = append(report.Problems, p)
+	}
+
+	for _, key := range keys {
+		col, ok := byColumn[strings.ToLower(key.Table+"."+key.Column)]
+		ref, refOK := byColumn[strings.ToLower(key.RefTable+"."+key.RefColumn)]
+		if !ok || !refOK || (col.Collation == ref.Collation && col.Charset == ref.Charset) {
+			continue
+		}
+		report.Problems = append(report.Problems, CollationProblem{
+			Kind: CollationJoinMismatch,
+			Message: fmt.Sprintf("%s.%s (%s) references %s.%s (%s); joins on them convert one side, so its index cannot be used",
+				col.Table, col.Column, describeCollation(col), ref.Table, ref.Column, describeCollation(ref)),
+			DDL:      []string{alterColumnCollation(dialect, col, ref)},
+			Warnings: conversionWarnings(dialect, col.Table, col.TableBytes),
+		})
+	}
+	return report
+}
+
+func describeCollation(col ColumnCollation) string {
+	if col.Charset != "" {
+		return col.Charset + " / " + col.Collation
+	}
+	return col.Collation
+}
+
+// alterColumnCollation returns the DDL giving col the collation of ref
+func alterColumnCollation(dialect string, col, ref ColumnCollation) string {
+	if dialect == "mysql" {
+		return fmt.Sprintf("ALTER TABLE `%s` MODIFY `%s` %s CHARACTER SET %s COLLATE %s;", col.Table, col.Column, col.Type, ref.Charset, ref.Collation)
+	}
+	return fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s COLLATE "%s";`, col.Table, col.Column, col.Type, ref.Collation)
+}
+
+// conversionWarnings describes the cost of converting a table
+func conversionWarnings(dialect, table string, bytes int64) []string {
+	var warnings []string
+	if dialect == "mysql" {
+		warnings = append(warnings, fmt.Sprintf("Rebuilds %s (%s) with a table copy; writes are blocked unless the server can do it online, so use gh-ost or pt-online-schema-change for busy tables.", table, FormatBytes(bytes)))
+	} else {
+		warnings = append(warnings, fmt.Sprintf("Takes an ACCESS EXCLUSIVE lock on %s (%s) and rebuilds the indexes on the column.", table, FormatBytes(bytes)))
+	}
+	if bytes >= LargeConversionBytes {
+		warnings = append(warnings, fmt.Sprintf("%s is larger than %s; schedule a maintenance window.", table, FormatBytes(LargeConversionBytes)))
+	}
+	return warnings
+}
+
+// isUTF8MB3 reports whether a MySQL character set is the 3-byte utf8
+func isUTF8MB3(charset string) bool {
+	charset = strings.ToLower(charset)
+	return charset == "utf8" || charset == "utf8mb3"
+}
+
+// tableConversions returns the CONVERT TO statements for tables, with warnings
+func tableConversions(tables []string, sizes map[string]int64, target string) ([]string, []string) {
+	charset := strings.SplitN(target, "_", 2)[0]
+	var ddl, warnings []string
+	for _, table := range tables {
+		ddl = append(ddl, fmt.Sprintf("ALTER TABLE `%s` CONVERT TO CHARACTER SET %s COLLATE %s;", table, charset, target))
+		warnings = append(warnings, conversionWarnings("mysql", table, sizes[table])...)
+	}
+	return ddl, warnings
+}
+
+func mysqlUTF8MB3Problem(columns []ColumnCollation, target string) (CollationProblem, bool) {
+	sizes := make(map[string]int64)
+	var tables, names []string
+	for _, col := range columns {
+		if !isUTF8MB3(col.Charset) {
+			continue
+		}
+		if _, seen := sizes[col.Table]; !seen {
+			tables = append(tables, col.Table)
+		}
+		sizes[col.Table] = col.TableBytes
+		names = append(names, col.Table+"."+col.Column)
+	}
+	if len(names) == 0 {
+		return CollationProblem{}, false
+	}
+
+	ddl, warnings := tableConversions(tables, sizes, target)
+	warnings = append(warnings, "utf8mb4 needs 4 bytes per character: indexed VARCHAR columns longer than 191 characters can exceed the 767-byte key limit of COMPACT/REDUNDANT row formats.")
+	return CollationProblem{
+		Kind:     CollationUTF8MB3,
+		Message:  fmt.Sprintf("%d columns use utf8mb3, which cannot store 4-byte characters such as emoji, and comparing them with utf8mb4 columns bypasses indexes: %s", len(names), summarizeNames(names)),
+		DDL:      ddl,
+		Warnings: warnings,
+	}, true
+}
+
+func mysqlMixedProblem(columns []ColumnCollation, target string) (CollationProblem, bool) {
+	collations := make(map[string]bool)
+	sizes := make(map[string]int64)
+	var tables []string
+	for _, col := range columns {
+		collations[col.Collation] = true
+		if isUTF8MB3(col.Charset) || strings.EqualFold(col.Collation, target) {
+			continue // utf8mb3 tables are converted by the utf8mb3 problem
+		}
+		if _, seen := sizes[col.Table]; !seen {
+			tables = append(tables, col.Table)
+		}
+		sizes[col.Table] = col.TableBytes
+	}
+	if len(collations) < 2 || len(tables) == 0 {
+		return CollationProblem{}, false
+	}
+
+	ddl, warnings := tableConversions(tables, sizes, target)
+	return CollationProblem{
+		Kind: CollationMixed,
+		Message: fmt.Sprintf("Columns use %d different collations (%s); comparing columns of different collations fails with \"Illegal mix of collations\" or converts one side and skips its index",
+			len(collations), strings.Join(sortedSet(collations), ", ")),
+		DDL:      ddl,
+		Warnings: warnings,
+	}, true
+}
+
+func postgresProviderProblem(columns []ColumnCollation) (CollationProblem, bool) {
+	var icu, libc []string
+	for _, col := range columns {
+		switch col.Provider {
+		case "icu":
+			icu = append(icu, col.Table+"."+col.Column)
+		case "libc":
+			libc = append(libc, col.Table+"."+col.Column)
+		}
+	}
+	if len(icu) == 0 || len(libc) == 0 {
+		return CollationProblem{}, false
+	}
+	return CollationProblem{
+		Kind: CollationMixedProvider,
+		Message: fmt.Sprintf("ICU collations (%s) are mixed with explicit libc collations (%s); they sort differently, so comparisons between them need COLLATE and skip indexes, and libc sort order can change with OS upgrades",
+			summarizeNames(icu), summarizeNames(libc)),
+	}, true
+}
+
+// summarizeNames lists up to five names and counts the rest
+func summarizeNames(names []string) string {
+	if len(names) <= 5 {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:5], ", "), len(names)-5)
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,53 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeCollations_MySQL(t *testing.T) {
+	columns := ColumnCollationsFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{"users", "email", "varchar(255)", "utf8mb4", "utf8mb4_0900_ai_ci", "", int64(4096)},
+		{"orders", "user_email", "varchar(255)", "utf8mb4", "utf8mb4_unicode_ci", "", int64(2 << 30)},
+		{"legacy", "note", "text", "utf8mb3", "utf8mb3_general_ci", "", []byte("1024")},
+	}})
+	require.Len(t, columns, 3)
+	assert.Equal(t, int64(1024), columns[2].TableBytes)
+
+	keys := ForeignKeysFromResult(&models.QueryResult{Rows: [][]interface{}{{"orders", "user_email", "users", "email"}}})
+	report := AnalyzeCollations("mysql", "utf8mb4", "utf8mb4_0900_ai_ci", columns, keys)
+
+	kinds := make(map[string]CollationProblem)
+	for _, p := range report.Problems {
+		kinds[p.Kind] = p
+	}
+	require.Len(t, kinds, 3)
+	assert.Equal(t, []string{"ALTER TABLE `legacy` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;"}, kinds[CollationUTF8MB3].DDL)
+	assert.Equal(t, []string{"ALTER TABLE `orders` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;"}, kinds[CollationMixed].DDL)
+	assert.Contains(t, kinds[CollationMixed].Warnings[1], "maintenance window")
+	assert.Equal(t, []string{"ALTER TABLE `orders` MODIFY `user_email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;"}, kinds[CollationJoinMismatch].DDL)
+	assert.Equal(t, 1, report.Usage["utf8mb3_general_ci"])
+}
+
+func TestAnalyzeCollations_PostgresProviders(t *testing.T) {
+	columns := []ColumnCollation{
+		{Table: "users", Column: "name", Type: "text", Collation: "default", Provider: "default"},
+		{Table: "users", Column: "sort_name", Type: "text", Collation: "und-x-icu", Provider: "icu"},
+		{Table: "codes", Column: "code", Type: "character varying(20)", Collation: "C", Provider: "libc"},
+	}
+	report := AnalyzeCollations("postgres", "UTF8", "en_US.UTF-8", columns, nil)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, CollationMixedProvider, report.Problems[0].Kind)
+
+	report = AnalyzeCollations("postgresql", "UTF8", "en_US.UTF-8", columns[:1], nil)
+	assert.Empty(t, report.Problems)
+}
+
+func TestBuildCollationQueries_Unsupported(t *testing.T) {
+	_, _, _, err := BuildCollationQueries("sqlite")
+	assert.Error(t, err)
+}
@@ -0,0 +1,260 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// HostResources describes the machine a database server runs on
+type HostResources struct {
+	MemoryBytes int64  `json:"-"`
+	Memory      string `json:"memory"`
+	CPUs        int    `json:"cpus"`
+	Source      string `json:"source"` // "provided" by the user or "probed" from the server
+}
+
+// NewHostResources returns host resources from a memory size in bytes
+func NewHostResources(memoryBytes int64, cpus int, source string) HostResources {
+	return HostResources{MemoryBytes: memoryBytes, Memory: FormatBytes(memoryBytes), CPUs: cpus, Source: source}
+}
+
+// ConfigSetting is a server setting normalized to a number; sizes are in bytes
+type ConfigSetting struct {
+	Name  string
+	Value float64
+	Size  bool
+}
+
+// ConfigChange is a recommended setting change
+type ConfigChange struct {
+	Name        string `json:"name"`
+	Current     string `json:"current"`
+	Recommended string `json:"recommended"`
+	Rationale   string `json:"rationale"`
+}
+
+// ConfigAdvice is a tuned configuration for a host
+type ConfigAdvice struct {
+	Dialect string         `json:"dialect"`
+	Host    HostResources  `json:"host"`
+	Changes []ConfigChange `json:"changes"`
+	Notes   []string       `json:"notes,omitempty"`
+	Diff    string         `json:"diff"` // postgresql.conf or my.cnf diff with the rationale as comments
+}
+
+// configTolerance is the relative difference below which a setting is left alone
+const configTolerance = 0.1
+
+var postgresConfigSettings = []string{
+	"shared_buffers", "effective_cache_size", "work_mem", "maintenance_work_mem", "max_connections",
+	"max_worker_processes", "max_parallel_workers", "max_parallel_workers_per_gather",
+}
+
+var mysqlConfigSettings = []string{
+	"innodb_buffer_pool_size", "innodb_buffer_pool_instances", "max_connections", "tmp_table_size", "max_heap_table_size",
+}
+
+// BuildConfigQuery returns the query reading the settings advise_config tunes
+// as (name, value, unit) rows
+func BuildConfigQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return fmt.Sprintf("SELECT name, setting, COALESCE(unit, '') FROM pg_settings WHERE name IN (%s)", literalList(postgresConfigSettings)), nil
+	case "mysql":
+		return fmt.Sprintf("SELECT VARIABLE_NAME, VARIABLE_VALUE, '' FROM performance_schema.global_variables WHERE VARIABLE_NAME IN (%s)", literalList(mysqlConfigSettings)), nil
+	default:
+		return "", fmt.Errorf("configuration advice is not supported for %s databases", dialect)
+	}
+}
+
+func literalList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = sqlLiteral(value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// postgresUnits converts pg_settings units to bytes
+var postgresUnits = map[string]float64{"B": 1, "kB": 1 << 10, "8kB": 8 << 10, "MB": 1 << 20, "GB": 1 << 30}
+
+// ConfigSettingsFromResult converts a BuildConfigQuery result into settings by lowercase name
+func ConfigSettingsFromResult(dialect string, result *models.QueryResult) map[string]ConfigSetting {
+	settings := make(map[string]ConfigSetting)
+	if result == nil {
+		return settings
+	}
+	for _, row := range result.Rows {
+		if len(row) < 3 {
+			continue
+		}
+		name := strings.ToLower(cellText(row[0]))
+		setting := ConfigSetting{Name: name, Value: toFloat(cellText(row[1]))}
+		if factor, ok := postgresUnits[cellText(row[2])]; ok {
+			setting.Value *= factor
+			setting.Size = true
+		} else if normalizeDialect(dialect) == "mysql" && strings.HasSuffix(name, "_size") {
+			setting.Size = true
+		}
+		settings[name] = setting
+	}
+	return settings
+}
+
+// HostProbeQueries read /proc/meminfo and /proc/cpuinfo through the server.
+// They need superuser or pg_read_server_files and only work on Linux hosts.
+const (
+	MemInfoProbeQuery = "SELECT pg_read_file('/proc/meminfo')"
+	CPUInfoProbeQuery = "SELECT pg_read_file('/proc/cpuinfo')"
+)
+
+var (
+	memTotalPattern  = regexp.MustCompile(`(?m)^MemTotal:\s+(\d+)\s+kB`)
+	processorPattern = regexp.MustCompile(`(?m)^processor\s*:`)
+)
+
+// ParseMemInfo returns the total memory in bytes from /proc/meminfo content
+func ParseMemInfo(content string) (int64, bool) {
+	match := memTotalPattern.FindStringSubmatch(content)
+	if match == nil {
+		return 0, false
+	}
+	kb, err := strconv.ParseInt(match[1], 10, 64)
+	return kb * 1024, err == nil && kb > 0
+}
+
+// ParseCPUInfo returns the number of processors in /proc/cpuinfo content
+func ParseCPUInfo(content string) (int, bool) {
+	count := len(processorPattern.FindAllString(content, -1))
+	return count, count > 0
+}
+
+// AdviseConfig compares the current settings with the host resources and
+// returns the changes with their rationale, assuming a server dedicated to the
+// database
+func AdviseConfig(dialect string, settings map[string]ConfigSetting, host HostResources) *ConfigAdvice {
+	dialect = normalizeDialect(dialect)
+	advice := &ConfigAdvice{Dialect: dialect, Host: host, Changes: []ConfigChange{}}
+	if dialect == "mysql" {
+		adviseMySQL(advice, settings)
+	} else {
+		advisePostgres(advice, settings)
+	}
+	advice.Diff = configDiff(advice.Changes)
+	return advice
+}
+
+func advisePostgres(advice *ConfigAdvice, settings map[string]ConfigSetting) {
+	ram := float64(advice.Host.MemoryBytes)
+	cpus := float64(advice.Host.CPUs)
+	connections := settings["max_connections"].Value
+	if connections <= 0 {
+		connections = 100
+	}
+
+	sharedBuffers := ram / 4
+	advice.suggest("postgresql", settings, "shared_buffers", sharedBuffers, true,
+		"25% of RAM; the rest is left to the OS page cache, which PostgreSQL relies on as well. Needs a restart.")
+	advice.suggest("postgresql", settings, "effective_cache_size", ram*3/4, true,
+		"75% of RAM: shared_buffers plus the expected OS cache. Only a planner hint, so index scans are costed realistically.")
+	advice.suggest("postgresql", settings, "maintenance_work_mem", math.Min(ram/16, 2<<30), true,
+		"RAM/16 capped at 2GB speeds up VACUUM, CREATE INDEX and foreign key checks.")
+	advice.suggest("postgresql", settings, "work_mem", math.Max((ram-sharedBuffers)/(connections*3), 4<<20), true,
+		fmt.Sprintf("Memory left after shared_buffers spread over %.0f connections with ~3 sort/hash nodes each; raise it per statement for reports rather than globally.", connections))
+	advice.suggest("postgresql", settings, "max_worker_processes", cpus, false,
+		"One background worker per CPU. Needs a restart.")
+	advice.suggest("postgresql", settings, "max_parallel_workers", cpus, false,
+		"Allows parallel queries to use every CPU.")
+	advice.suggest("postgresql", settings, "max_parallel_workers_per_gather", math.Max(math.Min(cpus/2, 4), 1), false,
+		"Half the CPUs, at most 4, so one parallel query cannot take the whole server.")
+
+	if connections > math.Max(100, cpus*4) {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("max_connections is %.0f for %d CPUs. Each connection is a process; put a pooler such as PgBouncer in front and lower max_connections instead of raising memory settings.",
+			connections, advice.Host.CPUs))
+	}
+	advice.Notes = append(advice.Notes, "Apply with ALTER SYSTEM SET name = 'value'; then SELECT pg_reload_conf(); settings marked as needing a restart only take effect after one.")
+}
+
+func adviseMySQL(advice *ConfigAdvice, settings map[string]ConfigSetting) {
+	ram := float64(advice.Host.MemoryBytes)
+	pool := ram * 7 / 10
+	advice.suggest("mysql", settings, "innodb_buffer_pool_size", pool, true,
+		"70% of RAM on a dedicated server; InnoDB caches data and indexes here and bypasses the OS cache.")
+	if pool >= 1<<30 {
+		advice.suggest("mysql", settings, "innodb_buffer_pool_instances", math.Min(math.Floor(pool/(1<<30)), 8), false,
+			"One instance per GB of buffer pool, at most 8, reduces mutex contention. Needs a restart.")
+	}
+
+	// Implicit temporary tables are limited by the smaller of the two
+	tmp, heap := settings["tmp_table_size"].Value, settings["max_heap_table_size"].Value
+	if tmp > 0 && heap > 0 && tmp != heap {
+		size := math.Max(tmp, heap)
+		rationale := "tmp_table_size and max_heap_table_size should match: in-memory temporary tables are limited by the smaller one."
+		advice.suggest("mysql", settings, "tmp_table_size", size, true, rationale)
+		advice.suggest("mysql", settings, "max_heap_table_size", size, true, rationale)
+	}
+
+	if connections := settings["max_connections"].Value; connections > float64(advice.Host.CPUs)*50 {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("max_connections is %.0f for %d CPUs; every connection can allocate per-thread buffers, so check that the application pools connections.",
+			connections, advice.Host.CPUs))
+	}
+	advice.Notes = append(advice.Notes, "Apply with SET PERSIST name = value (MySQL 8) or in my.cnf under [mysqld]; settings marked as needing a restart only take effect after one.")
+}
+
+// suggest records a change when the recommended value differs from the
+// current one by more than configTolerance
+func (a *ConfigAdvice) suggest(dialect string, settings map[string]ConfigSetting, name string, recommended float64, size bool, rationale string) {
+	current, known := settings[name]
+	if !known {
+		return
+	}
+	if size {
+		recommended = math.Floor(recommended/(1<<20)) * (1 << 20)
+	} else {
+		recommended = math.Round(recommended)
+	}
+	if current.Value > 0 && math.Abs(recommended-current.Value)/current.Value <= configTolerance {
+		return
+	}
+	a.Changes = append(a.Changes, ConfigChange{
+		Name:        name,
+		Current:     formatConfigValue(dialect, current.Value, size),
+		Recommended: formatConfigValue(dialect, recommended, size),
+		Rationale:   rationale,
+	})
+}
+
+// formatConfigValue renders a value the way the configuration file expects it
+func formatConfigValue(dialect string, value float64, size bool) string {
+	if !size {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	units := []struct {
+		bytes float64
+		pg    string
+		mysql string
+	}{{1 << 30, "GB", "G"}, {1 << 20, "MB", "M"}, {1 << 10, "kB", "K"}}
+	for _, unit := range units {
+		if value >= unit.bytes && math.Mod(value, unit.bytes) == 0 {
+			if dialect == "mysql" {
+				return fmt.Sprintf("%.0f%s", value/unit.bytes, unit.mysql)
+			}
+			return fmt.Sprintf("%.0f%s", value/unit.bytes, unit.pg)
+		}
+	}
+	return strconv.FormatFloat(value, 'f', 0, 64)
+}
+
+// configDiff renders the changes as a configuration file diff
+func configDiff(changes []ConfigChange) string {
+	var b strings.Builder
+	for _, change := range changes {
+		b.WriteString(fmt.Sprintf("# %s\n- %s = %s\n+ %s = %s\n", change.Rationale, change.Name, change.Current, change.Name, change.Recommended))
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdviseConfig_PostgreSQL(t *testing.T) {
+	settings := ConfigSettingsFromResult("postgresql", &models.QueryResult{Rows: [][]interface{}{
+		{"shared_buffers", "16384", "8kB"},
+		{"effective_cache_size", "524288", "8kB"},
+		{"work_mem", "4096", "kB"},
+		{"maintenance_work_mem", "65536", "kB"},
+		{"max_connections", "500", ""},
+		{"max_worker_processes", "8", ""},
+	}})
+	require.Equal(t, float64(128<<20), settings["shared_buffers"].Value)
+
+	advice := AdviseConfig("postgresql", settings, NewHostResources(16<<30, 8, "provided"))
+	changes := make(map[string]ConfigChange)
+	for _, change := range advice.Changes {
+		changes[change.Name] = change
+	}
+	assert.Equal(t, ConfigChange{Name: "shared_buffers", Current: "128MB", Recommended: "4GB", Rationale: changes["shared_buffers"].Rationale}, changes["shared_buffers"])
+	assert.Equal(t, "12GB", changes["effective_cache_size"].Recommended)
+	assert.Equal(t, "1GB", changes["maintenance_work_mem"].Recommended)
+	assert.Equal(t, "8MB", changes["work_mem"].Recommended)
+	assert.NotContains(t, changes, "max_worker_processes", "already matches the CPU count")
+	assert.NotContains(t, changes, "max_parallel_workers", "not reported by the server")
+	assert.Contains(t, advice.Diff, "- shared_buffers = 128MB\n+ shared_buffers = 4GB\n")
+	assert.Contains(t, advice.Notes[0], "PgBouncer")
+}
+
+func TestAdviseConfig_MySQL(t *testing.T) {
+	settings := ConfigSettingsFromResult("mysql", &models.QueryResult{Rows: [][]interface{}{
+		{"innodb_buffer_pool_size", "134217728", ""},
+		{"innodb_buffer_pool_instances", "1", ""},
+		{"max_connections", "151", ""},
+		{"tmp_table_size", "16777216", ""},
+		{"max_heap_table_size", []byte("67108864"), ""},
+	}})
+
+	advice := AdviseConfig("mysql", settings, NewHostResources(32<<30, 4, "provided"))
+	var names []string
+	for _, change := range advice.Changes {
+		names = append(names, change.Name)
+	}
+	assert.Equal(t, []string{"innodb_buffer_pool_size", "innodb_buffer_pool_instances", "tmp_table_size"}, names)
+	assert.Equal(t, "22937M", advice.Changes[0].Recommended)
+	assert.Equal(t, "128M", advice.Changes[0].Current)
+	assert.Equal(t, "64M", advice.Changes[2].Recommended)
+}
+
+func TestParseHostProbes(t *testing.T) {
+	memory, ok := ParseMemInfo("MemTotal:       16316412 kB\nMemFree:         1000 kB\n")
+	require.True(t, ok)
+	assert.Equal(t, int64(16316412*1024), memory)
+
+	cpus, ok := ParseCPUInfo("processor\t: 0\nmodel name\t: x\n\nprocessor\t: 1\nmodel name\t: x\n")
+	require.True(t, ok)
+	assert.Equal(t, 2, cpus)
+
+	_, ok = ParseMemInfo("")
+	assert.False(t, ok)
+}
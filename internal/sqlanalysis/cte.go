@@ -0,0 +1,516 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// CTE advice kinds
+const (
+	AdviceCTEFence           = "cte-fence"
+	AdviceCTEMaterialized    = "cte-materialized"
+	AdviceMaterializedHint   = "materialized-hint"
+	AdviceNotMaterialized    = "not-materialized-hint"
+	AdviceRepeatedSubquery   = "repeated-subquery"
+	AdviceCorrelatedSubquery = "correlated-subquery"
+)
+
+// ServerVersionQuery reads the PostgreSQL server version as a number, e.g.
+// 160002 for 16.2
+const ServerVersionQuery = "SHOW server_version_num"
+
+// CTEAdvice is a suggestion to restructure the CTEs or subqueries of a
+// PostgreSQL statement
+type CTEAdvice struct {
+	Kind    string `json:"kind"`
+	Target  string `json:"target"` // CTE name or subquery
+	Message string `json:"message"`
+	Rewrite string `json:"rewrite,omitempty"` // Restructured statement to compare plans with
+}
+
+// cteDefinition is a CTE of a WITH clause, by token indexes
+type cteDefinition struct {
+	name        string
+	start       int    // Name
+	hint        string // "", "MATERIALIZED" or "NOT MATERIALIZED"
+	hintStart   int    // First token of the hint, or the opening parenthesis
+	open, close int    // Parentheses around the body
+	modifies    bool   // Body is INSERT, UPDATE, DELETE or MERGE
+	references  []int  // Tokens referring to the CTE as a relation
+}
+
+// MajorVersionFromResult converts a ServerVersionQuery result to a major
+// version, or 0 when it cannot be read
+func MajorVersionFromResult(result *models.QueryResult) int {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return 0
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(cellText(result.Rows[0][0])))
+	if err != nil {
+		return 0
+	}
+	return number / 10000
+}
+
+// AdviseCTEs finds CTEs PostgreSQL evaluates inefficiently and subqueries it
+// evaluates more often than needed. Up to version 11 every CTE is an
+// optimization fence, evaluated once in full before the query that uses it;
+// from 12 a side-effect-free CTE referenced once is inlined, one referenced
+// more often is still materialized, and MATERIALIZED or NOT MATERIALIZED
+// override the choice. version is the server's major version, 0 when unknown,
+// which assumes 12 or later. Rewrites are for comparing plans: they are
+// built from the statement without its comments.
+func AdviseCTEs(sql string, version int) []CTEAdvice {
+	source := []rune(StripComments(sql))
+	tokens := unwrap(tokenizeSQL(source))
+	if len(tokens) == 0 {
+		return nil
+	}
+	modern := version == 0 || version >= 12
+
+	var advice []CTEAdvice
+	definitions, body, recursive := parseCTEs(tokens)
+	for _, cte := range definitions {
+		if recursive || cte.modifies || len(cte.references) == 0 {
+			// Recursive and data-modifying CTEs are always evaluated once,
+			// and PostgreSQL skips unreferenced ones
+			continue
+		}
+		references := len(cte.references)
+		switch {
+		case cte.hint != "" && !modern:
+			advice = append(advice, CTEAdvice{
+				Kind:    AdviceMaterializedHint,
+				Target:  cte.name,
+				Message: fmt.Sprintf("%s is declared AS %s, which PostgreSQL %d rejects: the hints need version 12 or later", cte.name, cte.hint, version),
+				Rewrite: withoutHint(source, tokens, cte),
+			})
+		case !modern && references == 1:
+			advice = append(advice, CTEAdvice{
+				Kind:   AdviceCTEFence,
+				Target: cte.name,
+				Message: fmt.Sprintf("PostgreSQL %d evaluates %s in full before the query that uses it, so filters and joins on it cannot reach its tables' indexes; "+
+					"as it is referenced once, inline it as a subquery (from version 12 this happens automatically)", version, cte.name),
+				Rewrite: inlineCTE(source, tokens, definitions, cte),
+			})
+		case cte.hint == "MATERIALIZED" && references == 1:
+			advice = append(advice, CTEAdvice{
+				Kind:   AdviceMaterializedHint,
+				Target: cte.name,
+				Message: fmt.Sprintf("%s is referenced once but declared AS MATERIALIZED, which keeps filters on it from reaching its tables' indexes; "+
+					"drop the hint unless the fence is deliberate, e.g. to evaluate an expensive function once", cte.name),
+				Rewrite: withoutHint(source, tokens, cte),
+			})
+		case cte.hint == "NOT MATERIALIZED" && references > 1:
+			advice = append(advice, CTEAdvice{
+				Kind:   AdviceNotMaterialized,
+				Target: cte.name,
+				Message: fmt.Sprintf("%s is declared AS NOT MATERIALIZED and referenced %d times, so its query runs once per reference; "+
+					"drop the hint to evaluate it once, unless each reference filters it down to a few indexed rows", cte.name, references),
+				Rewrite: withoutHint(source, tokens, cte),
+			})
+		case cte.hint == "" && modern && references > 1 && hasFilter(tokens[body:]):
+			advice = append(advice, CTEAdvice{
+				Kind:   AdviceCTEMaterialized,
+				Target: cte.name,
+				Message: fmt.Sprintf("%s is referenced %d times, so PostgreSQL evaluates it once in full and filters on it cannot reach its tables' indexes; "+
+					"when the references only need a few rows, AS NOT MATERIALIZED lets each use the indexes, at the cost of running the query per reference", cte.name, references),
+				Rewrite: withHint(source, tokens, cte, "NOT MATERIALIZED"),
+			})
+		}
+	}
+
+	advice = append(advice, repeatedSubqueries(source, tokens, definitions)...)
+	return append(advice, correlatedSubqueries(source, tokens)...)
+}
+
+// parseCTEs reads the WITH clause at the start of a statement, returning its
+// CTEs, the index of the statement after it and whether it is RECURSIVE
+func parseCTEs(tokens []sqlToken) ([]*cteDefinition, int, bool) {
+	if len(tokens) < 2 || !tokens[0].isWord("with") {
+		return nil, 0, false
+	}
+	i, recursive := 1, false
+	if tokens[i].isWord("recursive") {
+		i, recursive = i+1, true
+	}
+
+	var definitions []*cteDefinition
+	for i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenQuoted) {
+		cte := &cteDefinition{name: tokens[i].text, start: i}
+		i++
+		if i < len(tokens) && tokens[i].is("(") {
+			i = matchParen(tokens, i) + 1
+		}
+		if i >= len(tokens) || !tokens[i].isWord("as") {
+			break
+		}
+		i++
+		cte.hintStart = i
+		switch {
+		case i+1 < len(tokens) && tokens[i].isWord("not") && tokens[i+1].isWord("materialized"):
+			cte.hint, i = "NOT MATERIALIZED", i+2
+		case i < len(tokens) && tokens[i].isWord("materialized"):
+			cte.hint, i = "MATERIALIZED", i+1
+		}
+		if i >= len(tokens) || !tokens[i].is("(") {
+			break
+		}
+		cte.open, cte.close = i, matchParen(tokens, i)
+		if cte.open+1 < cte.close {
+			cte.modifies = writeVerbs[strings.ToLower(tokens[cte.open+1].text)]
+		}
+		definitions = append(definitions, cte)
+		i = cte.close + 1
+		if i >= len(tokens) || !tokens[i].is(",") {
+			break
+		}
+		i++
+	}
+
+	for _, cte := range definitions {
+		for j := range tokens {
+			if j > cte.open && j < cte.close {
+				continue
+			}
+			if j != cte.start && tokens[j].isName() && strings.EqualFold(tokens[j].text, cte.name) && isRelation(tokens, j) {
+				cte.references = append(cte.references, j)
+			}
+		}
+	}
+	body := len(tokens)
+	if len(definitions) > 0 {
+		body = definitions[len(definitions)-1].close + 1
+	}
+	return definitions, body, recursive
+}
+
+// isRelation reports whether the name at i is used as a relation: it follows
+// FROM or JOIN, or a comma of a FROM list, and is neither qualified nor a
+// qualifier or function
+func isRelation(tokens []sqlToken, i int) bool {
+	if i+1 < len(tokens) && (tokens[i+1].is(".") || tokens[i+1].is("(")) {
+		return false
+	}
+	return startsRelation(tokens, i)
+}
+
+// startsRelation reports whether the token at i starts a possibly qualified
+// relation name of a FROM list or JOIN
+func startsRelation(tokens []sqlToken, i int) bool {
+	if i == 0 {
+		return false
+	}
+	previous := tokens[i-1]
+	if previous.isWord("from") || previous.isWord("join") {
+		return true
+	}
+	return previous.is(",") && clauseOf(tokens, i-1) == "from"
+}
+
+// clauseKeywords start the clauses clauseOf tells apart
+var clauseKeywords = map[string]bool{
+	"select": true, "from": true, "where": true, "group": true, "having": true, "order": true,
+	"on": true, "using": true, "set": true, "returning": true, "values": true, "join": true,
+	"limit": true, "window": true,
+}
+
+// clauseOf returns the lower-case keyword starting the clause the token at i
+// belongs to, looking back within its parentheses; a JOIN ends a FROM list
+func clauseOf(tokens []sqlToken, i int) string {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch {
+		case tokens[j].is(")"):
+			depth++
+		case tokens[j].is("("):
+			if depth == 0 {
+				return ""
+			}
+			depth--
+		case depth == 0 && tokens[j].kind == tokenWord && clauseKeywords[strings.ToLower(tokens[j].text)]:
+			return strings.ToLower(tokens[j].text)
+		}
+	}
+	return ""
+}
+
+// hasFilter reports whether a statement filters or joins what it reads
+func hasFilter(tokens []sqlToken) bool {
+	for _, tok := range tokens {
+		if tok.isWord("where") || tok.isWord("on") || tok.isWord("using") {
+			return true
+		}
+	}
+	return false
+}
+
+// textEdit replaces the runes [start, end) of a source
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits applies non-overlapping edits to a source
+func applyEdits(source []rune, edits []textEdit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	last := 0
+	for _, edit := range edits {
+		b.WriteString(string(source[last:edit.start]))
+		b.WriteString(edit.text)
+		last = edit.end
+	}
+	b.WriteString(string(source[last:]))
+	return strings.TrimSpace(b.String())
+}
+
+// withoutHint returns the statement with the MATERIALIZED hint of a CTE removed
+func withoutHint(source []rune, tokens []sqlToken, cte *cteDefinition) string {
+	return applyEdits(source, []textEdit{{start: tokens[cte.hintStart].start, end: tokens[cte.open].start}})
+}
+
+// withHint returns the statement with a CTE declared AS hint
+func withHint(source []rune, tokens []sqlToken, cte *cteDefinition, hint string) string {
+	return applyEdits(source, []textEdit{{start: tokens[cte.hintStart].start, end: tokens[cte.open].start, text: hint + " "}})
+}
+
+// inlineCTE returns the statement with a CTE referenced once replaced by a
+// subquery where it is referenced
+func inlineCTE(source []rune, tokens []sqlToken, definitions []*cteDefinition, cte *cteDefinition) string {
+	body := string(source[tokens[cte.open].start:tokens[cte.close].end])
+	reference := cte.references[0]
+	replacement := body + " AS " + string(source[tokens[reference].start:tokens[reference].end])
+	if next := reference + 1; next < len(tokens) && (tokens[next].isWord("as") || tokens[next].isName()) {
+		replacement = body // Keeps its own alias
+	}
+	edits := []textEdit{{start: tokens[reference].start, end: tokens[reference].end, text: replacement}}
+
+	// The definition goes with the comma separating it from its neighbour,
+	// and the WITH keyword when it was the only one
+	switch index := definitionIndex(definitions, cte); {
+	case len(definitions) == 1:
+		edits = append(edits, textEdit{start: tokens[0].start, end: tokens[cte.close+1].start})
+	case index == 0:
+		edits = append(edits, textEdit{start: tokens[cte.start].start, end: tokens[definitions[1].start].start})
+	default:
+		edits = append(edits, textEdit{start: tokens[definitions[index-1].close].end, end: tokens[cte.close].end})
+	}
+	return applyEdits(source, edits)
+}
+
+func definitionIndex(definitions []*cteDefinition, cte *cteDefinition) int {
+	for i, definition := range definitions {
+		if definition == cte {
+			return i
+		}
+	}
+	return -1
+}
+
+// subquery is a parenthesized SELECT, by token indexes
+type subquery struct {
+	open, close int
+}
+
+// subqueries returns the parenthesized SELECTs of a statement, outermost first
+func subqueries(tokens []sqlToken) []subquery {
+	var found []subquery
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].is("(") && tokens[i+1].isWord("select") {
+			found = append(found, subquery{open: i, close: matchParen(tokens, i)})
+		}
+	}
+	return found
+}
+
+// repeatedSubqueries advises computing a subquery that appears more than once
+// in a CTE. Correlated subqueries depend on the outer row, so
+// they are left to correlatedSubqueries.
+func repeatedSubqueries(source []rune, tokens []sqlToken, definitions []*cteDefinition) []CTEAdvice {
+	groups := make(map[string][]subquery)
+	var order []string
+	for _, sub := range subqueries(tokens) {
+		key := strings.ToLower(collapseSpace(source, tokens, sub.open, sub.close))
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+
+	names := make(map[string]bool)
+	for _, cte := range definitions {
+		names[strings.ToLower(cte.name)] = true
+	}
+	var advice []CTEAdvice
+	var reported []subquery
+	for _, key := range order {
+		occurrences := groups[key]
+		if len(occurrences) < 2 || insideAny(occurrences[0], reported) || len(outerReferences(tokens, occurrences[0])) > 0 {
+			continue
+		}
+		reported = append(reported, occurrences...)
+
+		name := "repeated_1"
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("repeated_%d", n)
+		}
+		names[name] = true
+		target := collapseSpace(source, tokens, occurrences[0].open, occurrences[0].close)
+		item := CTEAdvice{
+			Kind:   AdviceRepeatedSubquery,
+			Target: truncateText(target, 120),
+			Message: fmt.Sprintf("The same subquery appears %d times and is evaluated for each; "+
+				"compute it once in a CTE such as %s, which PostgreSQL materializes because it is referenced more than once", len(occurrences), name),
+		}
+		if rewrite, ok := extractSubquery(source, tokens, definitions, occurrences, name); ok {
+			item.Rewrite = rewrite
+		}
+		advice = append(advice, item)
+	}
+	return advice
+}
+
+// extractSubquery returns the statement with the occurrences of a subquery
+// replaced by reads of a CTE computing it
+func extractSubquery(source []rune, tokens []sqlToken, definitions []*cteDefinition, occurrences []subquery, name string) (string, bool) {
+	first := occurrences[0]
+	body := string(source[tokens[first.open+1].start:tokens[first.close-1].end])
+	cte := name + " AS (" + body + ")"
+
+	edits := make([]textEdit, 0, len(occurrences)+1)
+	for _, occurrence := range occurrences {
+		edits = append(edits, textEdit{start: tokens[occurrence.open].start, end: tokens[occurrence.close].end, text: "(SELECT * FROM " + name + ")"})
+	}
+	if len(definitions) == 0 {
+		edits = append(edits, textEdit{start: tokens[0].start, end: tokens[0].start, text: "WITH " + cte + "\n"})
+		return applyEdits(source, edits), true
+	}
+
+	// The CTE goes first in the WITH clause so every other CTE can read it,
+	// which only works when it reads none of them itself
+	for i := first.open; i < first.close; i++ {
+		for _, definition := range definitions {
+			if tokens[i].isName() && strings.EqualFold(tokens[i].text, definition.name) {
+				return "", false
+			}
+		}
+	}
+	at := tokens[definitions[0].start].start
+	edits = append(edits, textEdit{start: at, end: at, text: cte + ", "})
+	return applyEdits(source, edits), true
+}
+
+func insideAny(sub subquery, outer []subquery) bool {
+	for _, o := range outer {
+		if sub.open > o.open && sub.close < o.close {
+			return true
+		}
+	}
+	return false
+}
+
+// correlatedSubqueries advises turning scalar subqueries that refer to the
+// outer query into joins. EXISTS, IN, ANY and ALL subqueries are left
+// alone, as PostgreSQL already plans them as semi- or anti-joins, and so
+// are LATERAL ones, which are correlated by design.
+func correlatedSubqueries(source []rune, tokens []sqlToken) []CTEAdvice {
+	var advice []CTEAdvice
+	var reported []subquery
+	for _, sub := range subqueries(tokens) {
+		if insideAny(sub, reported) {
+			continue
+		}
+		if sub.open > 0 {
+			previous := tokens[sub.open-1]
+			if previous.isWord("exists") || previous.isWord("in") || previous.isWord("any") ||
+				previous.isWord("some") || previous.isWord("all") || previous.isWord("lateral") {
+				continue
+			}
+		}
+		outer := outerReferences(tokens, sub)
+		if len(outer) == 0 {
+			continue
+		}
+		reported = append(reported, sub)
+		advice = append(advice, CTEAdvice{
+			Kind:   AdviceCorrelatedSubquery,
+			Target: truncateText(collapseSpace(source, tokens, sub.open, sub.close), 120),
+			Message: fmt.Sprintf("This subquery refers to %s of the outer query, so it runs once per outer row; "+
+				"rewrite it as a LEFT JOIN to a subquery grouped by the correlated columns, or as LEFT JOIN LATERAL when it picks a row per outer row (e.g. with LIMIT 1)",
+				strings.Join(outer, ", ")),
+		})
+	}
+	return advice
+}
+
+// outerReferences returns the qualified columns a subquery reads from
+// relations it does not name itself, e.g. o.id of the outer query
+func outerReferences(tokens []sqlToken, sub subquery) []string {
+	inner := tokens[sub.open+1 : sub.close]
+	aliases := make(map[string]bool)
+	for i, tok := range inner {
+		j := i + 1
+		switch {
+		case tok.is(")"):
+			// Alias of a derived table or function
+		case tok.isName() && startsRelation(inner, i):
+			// The relation names itself by the last part of its name, e.g.
+			// orders for public.orders
+			var parts []string
+			parts, j = qualifiedName(inner, i)
+			aliases[strings.ToLower(parts[len(parts)-1])] = true
+		default:
+			continue
+		}
+		if j < len(inner) && inner[j].isWord("as") {
+			j++
+		}
+		if j < len(inner) && inner[j].isName() {
+			aliases[strings.ToLower(inner[j].text)] = true
+		}
+	}
+
+	var outer []string
+	seen := make(map[string]bool)
+	for i := 0; i+2 < len(inner); i++ {
+		qualifier := inner[i]
+		if !qualifier.isName() || !inner[i+1].is(".") || (inner[i+2].kind != tokenWord && inner[i+2].kind != tokenQuoted) {
+			continue
+		}
+		if (i > 0 && inner[i-1].is(".")) || startsRelation(inner, i) {
+			continue
+		}
+		if i+3 < len(inner) && (inner[i+3].is("(") || inner[i+3].is(".")) {
+			continue // Function or relation of another schema
+		}
+		if aliases[strings.ToLower(qualifier.text)] {
+			continue
+		}
+		column := qualifier.text + "." + inner[i+2].text
+		if !seen[column] {
+			seen[column] = true
+			outer = append(outer, column)
+		}
+	}
+	return outer
+}
+
+// collapseSpace returns the source of the tokens [first, last] with runs of
+// whitespace collapsed
+func collapseSpace(source []rune, tokens []sqlToken, first, last int) string {
+	return whitespacePattern.ReplaceAllString(string(source[tokens[first].start:tokens[last].end]), " ")
+}
+
+// truncateText shortens text to at most limit runes, marking the cut
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-3]) + "..."
+}
@@ -0,0 +1,116 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMajorVersionFromResult(t *testing.T) {
+	assert.Equal(t, 16, MajorVersionFromResult(&models.QueryResult{Rows: [][]interface{}{{"160002"}}}))
+	assert.Equal(t, 11, MajorVersionFromResult(&models.QueryResult{Rows: [][]interface{}{{[]byte("110022")}}}))
+	assert.Equal(t, 0, MajorVersionFromResult(&models.QueryResult{}))
+	assert.Equal(t, 0, MajorVersionFromResult(nil))
+}
+
+func TestAdviseCTEs_FenceBeforeVersion12(t *testing.T) {
+	sql := "WITH recent AS (SELECT * FROM orders WHERE created_at > now() - interval '1 day')\nSELECT * FROM recent r WHERE r.customer_id = 42"
+
+	advice := AdviseCTEs(sql, 11)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceCTEFence, advice[0].Kind)
+	assert.Equal(t, "recent", advice[0].Target)
+	assert.Equal(t, "SELECT * FROM (SELECT * FROM orders WHERE created_at > now() - interval '1 day') r WHERE r.customer_id = 42", advice[0].Rewrite)
+
+	// From version 12 the CTE is inlined by PostgreSQL itself
+	assert.Empty(t, AdviseCTEs(sql, 12))
+	assert.Empty(t, AdviseCTEs(sql, 0))
+}
+
+func TestAdviseCTEs_InlinesOneOfSeveral(t *testing.T) {
+	sql := "WITH a AS (SELECT id FROM t), b AS (SELECT id FROM a) SELECT * FROM b, a"
+	advice := AdviseCTEs(sql, 10)
+	require.Len(t, advice, 1)
+	assert.Equal(t, "b", advice[0].Target)
+	assert.Equal(t, "WITH a AS (SELECT id FROM t) SELECT * FROM (SELECT id FROM a) AS b, a", advice[0].Rewrite)
+}
+
+func TestAdviseCTEs_Hints(t *testing.T) {
+	advice := AdviseCTEs("WITH totals AS MATERIALIZED (SELECT customer_id, sum(total) FROM orders GROUP BY 1) SELECT * FROM totals WHERE customer_id = 1", 15)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceMaterializedHint, advice[0].Kind)
+	assert.Equal(t, "WITH totals AS (SELECT customer_id, sum(total) FROM orders GROUP BY 1) SELECT * FROM totals WHERE customer_id = 1", advice[0].Rewrite)
+
+	advice = AdviseCTEs("WITH t AS NOT MATERIALIZED (SELECT * FROM big) SELECT * FROM t a JOIN t b ON a.id = b.parent_id", 15)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceNotMaterialized, advice[0].Kind)
+	assert.Equal(t, "WITH t AS (SELECT * FROM big) SELECT * FROM t a JOIN t b ON a.id = b.parent_id", advice[0].Rewrite)
+
+	// Hints are a syntax error before version 12
+	advice = AdviseCTEs("WITH t AS NOT MATERIALIZED (SELECT * FROM big) SELECT * FROM t", 11)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceMaterializedHint, advice[0].Kind)
+	assert.Contains(t, advice[0].Message, "PostgreSQL 11 rejects")
+}
+
+func TestAdviseCTEs_MaterializedByReferences(t *testing.T) {
+	sql := "WITH t AS (SELECT * FROM big) SELECT * FROM t a JOIN t b ON a.id = b.parent_id WHERE a.id = 5"
+	advice := AdviseCTEs(sql, 14)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceCTEMaterialized, advice[0].Kind)
+	assert.Equal(t, "WITH t AS NOT MATERIALIZED (SELECT * FROM big) SELECT * FROM t a JOIN t b ON a.id = b.parent_id WHERE a.id = 5", advice[0].Rewrite)
+
+	// A column sharing the CTE's name is not a reference
+	assert.Empty(t, AdviseCTEs("WITH total AS (SELECT 1 AS n) SELECT n, total FROM total, orders WHERE id = 1", 14))
+
+	// Recursive, data-modifying and unreferenced CTEs are left alone
+	for _, query := range []string{
+		"WITH RECURSIVE tree AS (SELECT id FROM nodes UNION ALL SELECT n.id FROM nodes n JOIN tree ON n.parent = tree.id) SELECT * FROM tree a JOIN tree b ON a.id = b.id",
+		"WITH moved AS (DELETE FROM queue RETURNING *) INSERT INTO archive SELECT * FROM moved",
+		"WITH unused AS (SELECT 1) SELECT * FROM orders WHERE id = 1",
+	} {
+		assert.Empty(t, AdviseCTEs(query, 11), query)
+	}
+}
+
+func TestAdviseCTEs_RepeatedSubquery(t *testing.T) {
+	sql := "SELECT name FROM products WHERE price > (SELECT avg(price) FROM products) OR cost > (SELECT  AVG(price) FROM products)"
+	advice := AdviseCTEs(sql, 16)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceRepeatedSubquery, advice[0].Kind)
+	assert.Equal(t, "(SELECT avg(price) FROM products)", advice[0].Target)
+	assert.Equal(t, "WITH repeated_1 AS (SELECT avg(price) FROM products)\nSELECT name FROM products WHERE price > (SELECT * FROM repeated_1) OR cost > (SELECT * FROM repeated_1)", advice[0].Rewrite)
+
+	// An existing WITH clause gets the CTE first, so its CTEs can read it
+	advice = AdviseCTEs("WITH a AS (SELECT * FROM t WHERE x > (SELECT max(x) FROM s)) SELECT * FROM a WHERE y > (SELECT max(x) FROM s)", 16)
+	require.Len(t, advice, 1)
+	assert.Equal(t, "WITH repeated_1 AS (SELECT max(x) FROM s), a AS (SELECT * FROM t WHERE x > (SELECT * FROM repeated_1)) SELECT * FROM a WHERE y > (SELECT * FROM repeated_1)", advice[0].Rewrite)
+}
+
+func TestAdviseCTEs_CorrelatedSubquery(t *testing.T) {
+	sql := "SELECT c.name, (SELECT max(o.created_at) FROM public.orders o WHERE o.customer_id = c.id) FROM customers c"
+	advice := AdviseCTEs(sql, 16)
+	require.Len(t, advice, 1)
+	assert.Equal(t, AdviceCorrelatedSubquery, advice[0].Kind)
+	assert.Contains(t, advice[0].Message, "c.id")
+	assert.Contains(t, advice[0].Message, "LEFT JOIN")
+	assert.Empty(t, advice[0].Rewrite)
+
+	// Repeated correlated subqueries are not extracted into a CTE
+	advice = AdviseCTEs("SELECT (SELECT count(*) FROM orders WHERE orders.customer_id = c.id), (SELECT count(*) FROM orders WHERE orders.customer_id = c.id) FROM customers c", 16)
+	require.Len(t, advice, 2)
+	assert.Equal(t, AdviceCorrelatedSubquery, advice[0].Kind)
+
+	// Semi-joins, lateral joins and uncorrelated subqueries are fine
+	for _, query := range []string{
+		"SELECT * FROM customers c WHERE EXISTS (SELECT 1 FROM orders o WHERE o.customer_id = c.id)",
+		"SELECT * FROM customers c WHERE c.id IN (SELECT o.customer_id FROM orders o WHERE o.total > 100)",
+		"SELECT * FROM customers c LEFT JOIN LATERAL (SELECT o.total FROM orders o WHERE o.customer_id = c.id LIMIT 1) last ON true",
+		"SELECT * FROM (SELECT s.id FROM sales.orders AS s) x WHERE x.id > 1",
+	} {
+		assert.Empty(t, AdviseCTEs(query, 16), query)
+	}
+}
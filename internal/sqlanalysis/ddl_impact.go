@@ -0,0 +1,279 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// DDLTarget is a table or column an ALTER or DROP statement changes
+type DDLTarget struct {
+	Action string // e.g. DROP TABLE, DROP COLUMN, RENAME COLUMN, ALTER COLUMN
+	Table  string
+	Column string // Empty when the whole table is affected
+}
+
+// String names the target as table or table.column
+func (t DDLTarget) String() string {
+	if t.Column != "" {
+		return t.Table + "." + t.Column
+	}
+	return t.Table
+}
+
+// Dependent is an object that uses a table or column
+type Dependent struct {
+	Kind   string // view, foreign key, trigger, function or procedure
+	Name   string
+	Column string // Column of the target it uses, empty when unknown
+}
+
+// String describes the dependent, e.g. "view order_totals (uses total)"
+func (d Dependent) String() string {
+	if d.Column != "" {
+		return fmt.Sprintf("%s %s (uses %s)", d.Kind, d.Name, d.Column)
+	}
+	return fmt.Sprintf("%s %s", d.Kind, d.Name)
+}
+
+// DDLTargets returns the tables and columns that the ALTER, DROP and RENAME
+// statements of a script drop, rename or retype. Adding columns or indexes
+// breaks nothing and is not reported.
+func DDLTargets(sql string) []DDLTarget {
+	var targets []DDLTarget
+	for _, stmt := range SplitStatements(sql) {
+		tokens := tokenizeSQL([]rune(StripComments(stmt)))
+		switch {
+		case len(tokens) > 2 && tokens[0].isWord("DROP"):
+			targets = append(targets, dropTargets(tokens[1:])...)
+		case len(tokens) > 2 && tokens[0].isWord("ALTER") && tokens[1].isWord("TABLE"):
+			targets = append(targets, alterTargets(tokens[2:])...)
+		case len(tokens) > 2 && tokens[0].isWord("RENAME") && tokens[1].isWord("TABLE"):
+			// MySQL: RENAME TABLE a TO b, c TO d
+			for _, part := range splitTopLevel(tokens[2:], func(tok sqlToken) bool { return tok.is(",") }) {
+				if len(part) > 0 && part[0].isName() {
+					parts, _ := qualifiedName(part, 0)
+					targets = append(targets, DDLTarget{Action: "RENAME TABLE", Table: strings.Join(parts, ".")})
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// dropTargets reads DROP TABLE and DROP VIEW statements after the DROP
+func dropTargets(tokens []sqlToken) []DDLTarget {
+	i := 0
+	if tokens[i].isWord("MATERIALIZED") {
+		i++
+	}
+	if !tokens[i].isWord("TABLE") && !tokens[i].isWord("VIEW") {
+		return nil
+	}
+	action := "DROP " + strings.ToUpper(tokens[i].text)
+	i++
+	if i+1 < len(tokens) && tokens[i].isWord("IF") && tokens[i+1].isWord("EXISTS") {
+		i += 2
+	}
+
+	var targets []DDLTarget
+	for _, part := range splitTopLevel(tokens[i:], func(tok sqlToken) bool { return tok.is(",") }) {
+		if len(part) > 0 && part[0].isName() {
+			parts, _ := qualifiedName(part, 0)
+			targets = append(targets, DDLTarget{Action: action, Table: strings.Join(parts, ".")})
+		}
+	}
+	return targets
+}
+
+// alterTargets reads the actions of an ALTER TABLE after the TABLE
+func alterTargets(tokens []sqlToken) []DDLTarget {
+	i := 0
+	if i+1 < len(tokens) && tokens[i].isWord("IF") && tokens[i+1].isWord("EXISTS") {
+		i += 2
+	}
+	if i < len(tokens) && tokens[i].isWord("ONLY") {
+		i++
+	}
+	if i >= len(tokens) || !tokens[i].isName() {
+		return nil
+	}
+	parts, i := qualifiedName(tokens, i)
+	table := strings.Join(parts, ".")
+
+	var targets []DDLTarget
+	for _, action := range splitTopLevel(tokens[i:], func(tok sqlToken) bool { return tok.is(",") }) {
+		if len(action) == 0 {
+			continue
+		}
+		verb := strings.ToUpper(action[0].text)
+		rest := action[1:]
+		if len(rest) > 0 && rest[0].isWord("COLUMN") {
+			rest = rest[1:]
+		} else if verb == "DROP" || verb == "ALTER" {
+			// DROP CONSTRAINT, ALTER INDEX and the like, unless it names a column
+			if len(rest) == 0 || !rest[0].isName() || ddlObjectWords[strings.ToLower(rest[0].text)] {
+				continue
+			}
+		}
+		if len(rest) > 1 && rest[0].isWord("IF") && rest[1].isWord("EXISTS") {
+			rest = rest[2:]
+		}
+
+		switch verb {
+		case "RENAME":
+			if len(rest) > 0 && (rest[0].isWord("TO") || rest[0].isWord("AS")) {
+				targets = append(targets, DDLTarget{Action: "RENAME TABLE", Table: table})
+			} else if len(rest) > 0 && rest[0].isName() && !rest[0].isWord("CONSTRAINT") && !rest[0].isWord("INDEX") && !rest[0].isWord("KEY") {
+				targets = append(targets, DDLTarget{Action: "RENAME COLUMN", Table: table, Column: rest[0].text})
+			}
+		case "DROP", "ALTER", "MODIFY", "CHANGE":
+			if len(rest) == 0 || !rest[0].isName() {
+				continue
+			}
+			name := "DROP COLUMN"
+			if verb != "DROP" {
+				name = "ALTER COLUMN"
+				if verb == "ALTER" && !changesType(rest[1:]) {
+					continue // SET DEFAULT, SET NOT NULL and the like keep the column
+				}
+			}
+			targets = append(targets, DDLTarget{Action: name, Table: table, Column: rest[0].text})
+		}
+	}
+	return targets
+}
+
+// ddlObjectWords name the things ALTER TABLE drops or alters other than columns
+var ddlObjectWords = map[string]bool{
+	"constraint": true, "index": true, "key": true, "primary": true, "foreign": true,
+	"check": true, "partition": true, "trigger": true, "policy": true,
+}
+
+// changesType reports whether an ALTER COLUMN action changes the column's type
+func changesType(tokens []sqlToken) bool {
+	for _, tok := range tokens {
+		if tok.isWord("TYPE") {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildDependentsQuery returns the query reading the objects that may use a
+// table as (kind, name, column used, definition). The definition is set for
+// objects found by searching their text, and DependentsFromResult confirms
+// the match.
+func BuildDependentsQuery(dialect, table string) (string, error) {
+	_, name := splitTableName(table)
+	literal := sqlLiteral(name)
+	pattern := sqlLiteral("%" + name + "%")
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return fmt.Sprintf(`SELECT DISTINCT 'view', v.relname, COALESCE(a.attname, ''), ''
+FROM pg_depend d
+JOIN pg_rewrite r ON r.oid = d.objid
+JOIN pg_class v ON v.oid = r.ev_class
+JOIN pg_class t ON t.oid = d.refobjid
+LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+WHERE d.classid = 'pg_rewrite'::regclass AND t.relname = %[1]s AND v.oid <> t.oid
+UNION ALL
+SELECT 'foreign key', src.relname || '.' || sa.attname, a.attname, ''
+FROM pg_constraint c
+JOIN pg_class src ON src.oid = c.conrelid
+JOIN pg_class t ON t.oid = c.confrelid
+CROSS JOIN LATERAL unnest(c.conkey, c.confkey) AS k(col, refcol)
+JOIN pg_attribute sa ON sa.attrelid = c.conrelid AND sa.attnum = k.col
+JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.refcol
+WHERE c.contype = 'f' AND t.relname = %[1]s
+UNION ALL
+SELECT 'trigger', tg.tgname, '', pg_get_triggerdef(tg.oid) || ' ' || p.prosrc
+FROM pg_trigger tg
+JOIN pg_class t ON t.oid = tg.tgrelid
+JOIN pg_proc p ON p.oid = tg.tgfoid
+WHERE NOT tg.tgisinternal AND t.relname = %[1]s
+UNION ALL
+SELECT 'function', p.proname, '', p.prosrc
+FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND p.prosrc ILIKE %[2]s`, literal, pattern), nil
+	case "mysql":
+		return fmt.Sprintf(`SELECT 'view', TABLE_NAME, '', VIEW_DEFINITION
+FROM information_schema.VIEWS
+WHERE TABLE_SCHEMA = DATABASE() AND VIEW_DEFINITION LIKE %[2]s
+UNION ALL
+SELECT 'foreign key', CONCAT(TABLE_NAME, '.', COLUMN_NAME), REFERENCED_COLUMN_NAME, ''
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME = %[1]s
+UNION ALL
+SELECT 'trigger', TRIGGER_NAME, '', CONCAT('ON ', EVENT_OBJECT_TABLE, ' ', ACTION_STATEMENT)
+FROM information_schema.TRIGGERS
+WHERE TRIGGER_SCHEMA = DATABASE() AND (EVENT_OBJECT_TABLE = %[1]s OR ACTION_STATEMENT LIKE %[2]s)
+UNION ALL
+SELECT LOWER(ROUTINE_TYPE), ROUTINE_NAME, '', ROUTINE_DEFINITION
+FROM information_schema.ROUTINES
+WHERE ROUTINE_SCHEMA = DATABASE() AND ROUTINE_DEFINITION LIKE %[2]s`, literal, pattern), nil
+	case "sqlite":
+		return fmt.Sprintf(`SELECT type, name, '', sql
+FROM sqlite_master
+WHERE type IN ('view', 'trigger') AND sql LIKE %[2]s
+UNION ALL
+SELECT 'foreign key', m.name || '.' || p."from", COALESCE(p."to", ''), ''
+FROM sqlite_master m
+JOIN pragma_foreign_key_list(m.name) p
+WHERE m.type = 'table' AND p."table" = %[1]s COLLATE NOCASE`, literal, pattern), nil
+	default:
+		return "", fmt.Errorf("dependency checks are not supported for %s databases", dialect)
+	}
+}
+
+// DependentsFromResult converts a dependents query result to the objects
+// that use the target. Objects found by their text must name the table, and
+// for a column target the column, as a whole word.
+func DependentsFromResult(result *models.QueryResult, target DDLTarget) []Dependent {
+	if result == nil {
+		return nil
+	}
+	_, table := splitTableName(target.Table)
+	var dependents []Dependent
+	seen := make(map[string]bool)
+	for _, row := range result.Rows {
+		if len(row) < 4 {
+			continue
+		}
+		d := Dependent{Kind: cellText(row[0]), Name: cellText(row[1]), Column: cellText(row[2])}
+		definition := cellText(row[3])
+		if strings.EqualFold(d.Name, table) && d.Kind == "view" {
+			continue // A dropped view is not its own dependent
+		}
+		if definition != "" && !mentions(definition, table) {
+			continue
+		}
+		if target.Column != "" {
+			switch {
+			case d.Column != "":
+				if !strings.EqualFold(d.Column, target.Column) {
+					continue
+				}
+			case definition != "":
+				if !mentions(definition, target.Column) {
+					continue
+				}
+				d.Column = target.Column
+			}
+		}
+		if key := d.String(); !seen[key] {
+			seen[key] = true
+			dependents = append(dependents, d)
+		}
+	}
+	return dependents
+}
+
+// mentions reports whether text names an identifier as a whole word
+func mentions(text, name string) bool {
+	pattern := `(?i)(^|[^a-z0-9_$])` + regexp.QuoteMeta(name) + `($|[^a-z0-9_$])`
+	return regexp.MustCompile(pattern).MatchString(text)
+}
@@ -0,0 +1,69 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDDLTargets(t *testing.T) {
+	targets := DDLTargets(`
+		ALTER TABLE IF EXISTS public.orders DROP COLUMN total, ADD COLUMN note TEXT, ALTER COLUMN placed_at TYPE date,
+			ALTER COLUMN status SET DEFAULT 'new', DROP CONSTRAINT orders_pk;
+		ALTER TABLE customers RENAME COLUMN name TO full_name;
+		ALTER TABLE customers RENAME TO clients;
+		ALTER TABLE items MODIFY price DECIMAL(10, 2);
+		DROP VIEW IF EXISTS v1, v2 CASCADE;
+		RENAME TABLE a TO b;
+		SELECT * FROM orders;
+		DROP INDEX orders_idx`)
+	assert.Equal(t, []DDLTarget{
+		{Action: "DROP COLUMN", Table: "public.orders", Column: "total"},
+		{Action: "ALTER COLUMN", Table: "public.orders", Column: "placed_at"},
+		{Action: "RENAME COLUMN", Table: "customers", Column: "name"},
+		{Action: "RENAME TABLE", Table: "customers"},
+		{Action: "ALTER COLUMN", Table: "items", Column: "price"},
+		{Action: "DROP VIEW", Table: "v1"},
+		{Action: "DROP VIEW", Table: "v2"},
+		{Action: "RENAME TABLE", Table: "a"},
+	}, targets)
+	assert.Equal(t, "public.orders.total", targets[0].String())
+	assert.Empty(t, DDLTargets("CREATE INDEX ON orders (total)"))
+}
+
+func TestDependentsFromResult(t *testing.T) {
+	result := &models.QueryResult{Rows: [][]interface{}{
+		{"view", "order_totals", "total", ""},
+		{"view", "order_dates", "placed_at", ""},
+		{"foreign key", "refunds.order_id", "id", ""},
+		{"trigger", "audit_orders", "", "CREATE TRIGGER audit_orders AFTER UPDATE ON orders BEGIN INSERT INTO log VALUES (NEW.total); END"},
+		{"function", "archive", "", "INSERT INTO orders_archive SELECT * FROM orders_old"},
+		{"view", "orders", "", "SELECT * FROM orders"},
+	}}
+
+	dependents := DependentsFromResult(result, DDLTarget{Action: "DROP COLUMN", Table: "public.orders", Column: "TOTAL"})
+	require.Len(t, dependents, 2)
+	assert.Equal(t, "view order_totals (uses total)", dependents[0].String())
+	assert.Equal(t, "trigger audit_orders (uses TOTAL)", dependents[1].String())
+
+	// The function only mentions tables whose names contain orders
+	dependents = DependentsFromResult(result, DDLTarget{Action: "DROP TABLE", Table: "orders"})
+	var names []string
+	for _, d := range dependents {
+		names = append(names, d.Name)
+	}
+	assert.Equal(t, []string{"order_totals", "order_dates", "refunds.order_id", "audit_orders"}, names)
+}
+
+func TestBuildDependentsQuery(t *testing.T) {
+	query, err := BuildDependentsQuery("postgresql", "public.it's")
+	require.NoError(t, err)
+	assert.Contains(t, query, "t.relname = 'it''s'")
+	assert.Contains(t, query, "ILIKE '%it''s%'")
+
+	_, err = BuildDependentsQuery("oracle", "orders")
+	assert.Error(t, err)
+}
@@ -0,0 +1,322 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats an entity-relationship diagram is written in
+const (
+	DiagramMermaid = "mermaid"
+	DiagramDOT     = "dot"
+	DiagramASCII   = "ascii"
+)
+
+// MaxASCIIDiagramTables is the most tables drawn as text boxes; larger
+// schemas are only readable once rendered from Mermaid or DOT
+const MaxASCIIDiagramTables = 15
+
+// ERDiagram is the tables of a schema with the foreign keys between them
+type ERDiagram struct {
+	Schema    string     `json:"schema,omitempty"`
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+	Omitted   int        `json:"omitted,omitempty"` // Tables left out beyond the table limit
+	Notes     []string   `json:"notes,omitempty"`
+}
+
+// Entity is a table of a diagram
+type Entity struct {
+	Name    string         `json:"name"`
+	Columns []EntityColumn `json:"columns"`
+}
+
+// EntityColumn is a column of a diagram table
+type EntityColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	ForeignKey bool   `json:"foreign_key,omitempty"`
+	Nullable   bool   `json:"nullable,omitempty"`
+}
+
+// ParseDiagramFormat reads a diagram format name
+func ParseDiagramFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case DiagramMermaid, "mmd":
+		return DiagramMermaid, nil
+	case DiagramDOT, "graphviz", "gv":
+		return DiagramDOT, nil
+	case DiagramASCII, "text", "txt":
+		return DiagramASCII, nil
+	}
+	return "", fmt.Errorf("unknown diagram format %q: use mermaid, dot or ascii", name)
+}
+
+// DiagramFormatForPath returns the diagram format a file extension names
+func DiagramFormatForPath(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mmd", ".mermaid", ".md":
+		return DiagramMermaid, true
+	case ".dot", ".gv":
+		return DiagramDOT, true
+	case ".txt":
+		return DiagramASCII, true
+	}
+	return "", false
+}
+
+// Render writes the diagram in a format. ASCII boxes are packed side by side
+// up to width characters.
+func (d *ERDiagram) Render(format string, width int) (string, error) {
+	switch format {
+	case DiagramMermaid:
+		return d.Mermaid(), nil
+	case DiagramDOT:
+		return d.DOT(), nil
+	case DiagramASCII:
+		if len(d.Entities) > MaxASCIIDiagramTables {
+			return "", fmt.Errorf("%d tables are too many to draw as text (at most %d); use mermaid or dot", len(d.Entities), MaxASCIIDiagramTables)
+		}
+		return d.ASCII(width), nil
+	}
+	return "", fmt.Errorf("unknown diagram format %q", format)
+}
+
+// entity returns the diagram table with a name
+func (d *ERDiagram) entity(name string) *Entity {
+	for i := range d.Entities {
+		if strings.EqualFold(d.Entities[i].Name, name) {
+			return &d.Entities[i]
+		}
+	}
+	return nil
+}
+
+// cardinality returns the crow's foot notation of a relation, parent first:
+// a key that is the child's whole primary key makes one-to-one, and a
+// nullable key makes the parent optional
+func (d *ERDiagram) cardinality(r Relation) string {
+	parent, child := "||", "o{"
+	if e := d.entity(r.Table); e != nil {
+		var keys []string
+		for _, col := range e.Columns {
+			if col.PrimaryKey {
+				keys = append(keys, strings.ToLower(col.Name))
+			}
+			for _, fk := range r.Columns {
+				if col.Nullable && strings.EqualFold(col.Name, fk) {
+					parent = "|o"
+				}
+			}
+		}
+		if len(keys) > 0 && strings.EqualFold(strings.Join(keys, ","), strings.Join(r.Columns, ",")) {
+			child = "o|"
+		}
+	}
+	return parent + "--" + child
+}
+
+var (
+	mermaidName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]+`)
+)
+
+// mermaidEntity returns a table name as Mermaid accepts it, quoted unless it
+// is a plain identifier
+func mermaidEntity(name string) string {
+	if mermaidName.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, "'") + `"`
+}
+
+// mermaidWord returns a column name or type as the single word Mermaid
+// attributes take
+func mermaidWord(text, fallback string) string {
+	word := strings.Trim(mermaidUnsafe.ReplaceAllString(strings.TrimSpace(text), "_"), "_")
+	if word == "" {
+		return fallback
+	}
+	if c := word[0]; c >= '0' && c <= '9' || c == '-' || c == '(' || c == '[' {
+		word = "_" + word
+	}
+	return word
+}
+
+// Mermaid writes the diagram as a Mermaid erDiagram
+func (d *ERDiagram) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, e := range d.Entities {
+		fmt.Fprintf(&b, "    %s {\n", mermaidEntity(e.Name))
+		for _, col := range e.Columns {
+			fmt.Fprintf(&b, "        %s %s", mermaidWord(col.Type, "any"), mermaidWord(col.Name, "column"))
+			var keys []string
+			if col.PrimaryKey {
+				keys = append(keys, "PK")
+			}
+			if col.ForeignKey {
+				keys = append(keys, "FK")
+			}
+			if len(keys) > 0 {
+				b.WriteString(" " + strings.Join(keys, ", "))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, r := range d.Relations {
+		fmt.Fprintf(&b, "    %s %s %s : \"%s\"\n", mermaidEntity(r.RefTable), d.cardinality(r), mermaidEntity(r.Table),
+			strings.ReplaceAll(strings.Join(r.Columns, ", "), `"`, "'"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// dotID quotes a Graphviz identifier
+func dotID(id string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(id, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+// DOT writes the diagram as a Graphviz digraph with one HTML-like table per
+// entity and an edge from each foreign key column to the column it references
+func (d *ERDiagram) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph erd {\n")
+	b.WriteString("    graph [rankdir=LR];\n")
+	b.WriteString("    node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	for _, e := range d.Entities {
+		fmt.Fprintf(&b, "    %s [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">", dotID(e.Name))
+		fmt.Fprintf(&b, "<TR><TD BGCOLOR=\"lightgrey\"><B>%s</B></TD></TR>", html.EscapeString(e.Name))
+		for _, col := range e.Columns {
+			text := html.EscapeString(col.Name)
+			if col.PrimaryKey {
+				text = "<U>" + text + "</U>"
+			}
+			if col.Type != "" {
+				text += " : " + html.EscapeString(col.Type)
+			}
+			if col.ForeignKey {
+				text += " (FK)"
+			}
+			fmt.Fprintf(&b, "<TR><TD ALIGN=\"LEFT\" PORT=%s>%s</TD></TR>", dotID(col.Name), text)
+		}
+		b.WriteString("</TABLE>>];\n")
+	}
+	for _, r := range d.Relations {
+		parent, child := dotID(r.RefTable), dotID(r.Table)
+		if len(r.RefColumns) > 0 {
+			parent += ":" + dotID(r.RefColumns[0])
+		}
+		if len(r.Columns) > 0 {
+			child += ":" + dotID(r.Columns[0])
+		}
+		fmt.Fprintf(&b, "    %s -> %s;\n", child, parent)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// ASCII draws each table as a text box, packed side by side up to width
+// characters, followed by the foreign keys
+func (d *ERDiagram) ASCII(width int) string {
+	if width <= 0 {
+		width = 80
+	}
+	var boxes [][]string
+	for _, e := range d.Entities {
+		boxes = append(boxes, asciiBox(e))
+	}
+
+	var b strings.Builder
+	for start := 0; start < len(boxes); {
+		end, used := start, 0
+		for end < len(boxes) {
+			w := utf8.RuneCountInString(boxes[end][0])
+			if end > start {
+				w += 2
+			}
+			if end > start && used+w > width {
+				break
+			}
+			used += w
+			end++
+		}
+		writeBoxRow(&b, boxes[start:end])
+		start = end
+	}
+
+	if len(d.Relations) > 0 {
+		b.WriteString("\nRelationships:\n")
+		for _, r := range d.Relations {
+			fmt.Fprintf(&b, "  %s\n", r)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// asciiBox draws a table with its columns, keys marked
+func asciiBox(e Entity) []string {
+	rows := make([][3]string, len(e.Columns))
+	nameWidth, typeWidth := 0, 0
+	for i, col := range e.Columns {
+		key := ""
+		switch {
+		case col.PrimaryKey && col.ForeignKey:
+			key = "PK,FK"
+		case col.PrimaryKey:
+			key = "PK"
+		case col.ForeignKey:
+			key = "FK"
+		}
+		rows[i] = [3]string{col.Name, col.Type, key}
+		nameWidth = max(nameWidth, utf8.RuneCountInString(col.Name))
+		typeWidth = max(typeWidth, utf8.RuneCountInString(col.Type))
+	}
+
+	lines := make([]string, len(rows))
+	inner := utf8.RuneCountInString(e.Name)
+	for i, row := range rows {
+		lines[i] = strings.TrimRight(fmt.Sprintf("%-5s %s  %s", row[2], pad(row[0], nameWidth), pad(row[1], typeWidth)), " ")
+		inner = max(inner, utf8.RuneCountInString(lines[i]))
+	}
+
+	border := "+" + strings.Repeat("-", inner+2) + "+"
+	box := []string{border, "| " + pad(e.Name, inner) + " |", border}
+	for _, line := range lines {
+		box = append(box, "| "+pad(line, inner)+" |")
+	}
+	return append(box, border)
+}
+
+// writeBoxRow writes boxes next to each other, the shorter ones padded
+func writeBoxRow(b *strings.Builder, boxes [][]string) {
+	height := 0
+	for _, box := range boxes {
+		height = max(height, len(box))
+	}
+	for line := 0; line < height; line++ {
+		var parts []string
+		for _, box := range boxes {
+			text := ""
+			if line < len(box) {
+				text = box[line]
+			}
+			parts = append(parts, pad(text, utf8.RuneCountInString(box[0])))
+		}
+		b.WriteString(strings.TrimRight(strings.Join(parts, "  "), " ") + "\n")
+	}
+	b.WriteString("\n")
+}
+
+// pad fills text with spaces to a width in characters
+func pad(text string, width int) string {
+	if n := utf8.RuneCountInString(text); n < width {
+		return text + strings.Repeat(" ", width-n)
+	}
+	return text
+}
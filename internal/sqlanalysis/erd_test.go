@@ -0,0 +1,107 @@
+package sqlanalysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleDiagram() *ERDiagram {
+	return &ERDiagram{
+		Entities: []Entity{
+			{Name: "customers", Columns: []EntityColumn{
+				{Name: "id", Type: "integer", PrimaryKey: true},
+				{Name: "email", Type: "varchar(255)"},
+			}},
+			{Name: "orders", Columns: []EntityColumn{
+				{Name: "id", Type: "integer", PrimaryKey: true},
+				{Name: "customer_id", Type: "integer", ForeignKey: true, Nullable: true},
+			}},
+			{Name: "order details", Columns: []EntityColumn{
+				{Name: "order_id", Type: "integer", PrimaryKey: true, ForeignKey: true},
+				{Name: "note", Type: "text", Nullable: true},
+			}},
+		},
+		Relations: []Relation{
+			{Name: "orders_customer_fk", Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+			{Name: "details_order_fk", Table: "order details", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+		},
+	}
+}
+
+func TestERDiagram_Mermaid(t *testing.T) {
+	expected := `erDiagram
+    customers {
+        integer id PK
+        varchar(255) email
+    }
+    orders {
+        integer id PK
+        integer customer_id FK
+    }
+    "order details" {
+        integer order_id PK, FK
+        text note
+    }
+    customers |o--o{ orders : "customer_id"
+    orders ||--o| "order details" : "order_id"`
+	assert.Equal(t, expected, sampleDiagram().Mermaid())
+}
+
+func TestERDiagram_DOT(t *testing.T) {
+	dot := sampleDiagram().DOT()
+	assert.True(t, strings.HasPrefix(dot, "digraph erd {\n"))
+	assert.Contains(t, dot, `<TD ALIGN="LEFT" PORT="id"><U>id</U> : integer</TD>`)
+	assert.Contains(t, dot, `<TD ALIGN="LEFT" PORT="customer_id">customer_id : integer (FK)</TD>`)
+	assert.Contains(t, dot, `    "orders":"customer_id" -> "customers":"id";`)
+	assert.Contains(t, dot, `    "order details":"order_id" -> "orders":"id";`)
+	assert.True(t, strings.HasSuffix(dot, "}"))
+}
+
+func TestERDiagram_ASCII(t *testing.T) {
+	diagram := sampleDiagram()
+	text := diagram.ASCII(70)
+	lines := strings.Split(text, "\n")
+	assert.Equal(t, "+---------------------------+  +----------------------------+", lines[0], "two boxes fit in 70 columns")
+	assert.Equal(t, "| customers                 |  | orders                     |", lines[1])
+	assert.Equal(t, "| PK    id     integer      |  | PK    id           integer |", lines[3])
+	assert.Equal(t, "|       email  varchar(255) |  | FK    customer_id  integer |", lines[4])
+	assert.Contains(t, text, "| PK,FK order_id  integer |")
+	assert.True(t, strings.HasSuffix(text, "Relationships:\n  orders.customer_id → customers.id\n  order details.order_id → orders.id"))
+
+	narrow := diagram.ASCII(30)
+	assert.Equal(t, "| customers                 |", strings.Split(narrow, "\n")[1], "each box gets its own row when they do not fit")
+}
+
+func TestERDiagram_Render(t *testing.T) {
+	diagram := sampleDiagram()
+	text, err := diagram.Render(DiagramMermaid, 0)
+	require.NoError(t, err)
+	assert.Equal(t, diagram.Mermaid(), text)
+
+	for i := 0; i < MaxASCIIDiagramTables; i++ {
+		diagram.Entities = append(diagram.Entities, Entity{Name: "extra"})
+	}
+	_, err = diagram.Render(DiagramASCII, 80)
+	assert.EqualError(t, err, "18 tables are too many to draw as text (at most 15); use mermaid or dot")
+}
+
+func TestParseDiagramFormat(t *testing.T) {
+	for name, expected := range map[string]string{"Mermaid": DiagramMermaid, "graphviz": DiagramDOT, "gv": DiagramDOT, "text": DiagramASCII} {
+		format, err := ParseDiagramFormat(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, format, name)
+	}
+	_, err := ParseDiagramFormat("svg")
+	assert.Error(t, err)
+
+	format, ok := DiagramFormatForPath("docs/schema.MD")
+	assert.True(t, ok)
+	assert.Equal(t, DiagramMermaid, format)
+	format, _ = DiagramFormatForPath("schema.gv")
+	assert.Equal(t, DiagramDOT, format)
+	_, ok = DiagramFormatForPath("schema.svg")
+	assert.False(t, ok)
+}
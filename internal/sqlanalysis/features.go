@@ -0,0 +1,284 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// Capability is an optional extension, plugin or server setting that an
+// analysis or query feature depends on
+type Capability struct {
+	Name    string `json:"name"`
+	Purpose string `json:"purpose"` // What it enables
+	Install string `json:"install"` // How to enable it
+
+	dialect string
+	setting string   // Server setting that must be ON, empty for extensions
+	errors  []string // Lower-case error fragments raised when it is missing
+}
+
+// capabilities are the extensions and settings dbsage knows how to suggest
+var capabilities = []Capability{
+	{
+		Name:    "pg_stat_statements",
+		Purpose: "query statistics for /capture and slow-query analysis",
+		Install: "add pg_stat_statements to shared_preload_libraries in postgresql.conf, restart the server, then run CREATE EXTENSION pg_stat_statements;",
+		dialect: "postgresql",
+		errors:  []string{`relation "pg_stat_statements" does not exist`, "pg_stat_statements must be loaded via"},
+	},
+	{
+		Name:    "pgcrypto",
+		Purpose: "digest(), crypt() and gen_random_bytes() for hashing and random values",
+		Install: "run CREATE EXTENSION pgcrypto;",
+		dialect: "postgresql",
+		errors:  []string{"function digest(", "function crypt(", "function gen_salt(", "function gen_random_bytes("},
+	},
+	{
+		Name:    "pg_trgm",
+		Purpose: "trigram indexes that speed up LIKE '%text%' and similarity() searches",
+		Install: "run CREATE EXTENSION pg_trgm;",
+		dialect: "postgresql",
+		errors:  []string{`operator class "gin_trgm_ops" does not exist`, `operator class "gist_trgm_ops" does not exist`, "function similarity("},
+	},
+	{
+		Name:    "timescaledb",
+		Purpose: "hypertables with compression and retention policies for time-series tables",
+		Install: "install the TimescaleDB package, add timescaledb to shared_preload_libraries, restart the server, then run CREATE EXTENSION timescaledb;",
+		dialect: "postgresql",
+		errors:  []string{"function create_hypertable(", `schema "timescaledb_information" does not exist`},
+	},
+	{
+		Name:    "postgis",
+		Purpose: "geometry and geography types, spatial functions and indexes",
+		Install: "install the PostGIS package for your server, then run CREATE EXTENSION postgis;",
+		dialect: "postgresql",
+		errors:  []string{`type "geometry" does not exist`, `type "geography" does not exist`, "function st_"},
+	},
+	{
+		Name:    "hypopg",
+		Purpose: "hypothetical indexes for testing index suggestions without building them",
+		Install: "install the hypopg package for your server, then run CREATE EXTENSION hypopg;",
+		dialect: "postgresql",
+		errors:  []string{"function hypopg_create_index("},
+	},
+	{
+		Name:    "uuid-ossp",
+		Purpose: "uuid_generate_v4() and related UUID functions",
+		Install: `run CREATE EXTENSION "uuid-ossp"; (on PostgreSQL 13+ gen_random_uuid() needs no extension)`,
+		dialect: "postgresql",
+		errors:  []string{"function uuid_generate_v4("},
+	},
+	{
+		Name:    "performance_schema",
+		Purpose: "statement history for /capture and wait analysis",
+		Install: "set performance_schema=ON in my.cnf and restart the server, then run UPDATE performance_schema.setup_consumers SET ENABLED = 'YES' WHERE NAME = 'events_statements_history_long';",
+		dialect: "mysql",
+		setting: "performance_schema",
+	},
+	{
+		Name:    "fts5",
+		Purpose: "full-text search with MATCH",
+		Install: "use a SQLite build compiled with SQLITE_ENABLE_FTS5 (for dbsage itself, build with -tags sqlite_fts5)",
+		dialect: "sqlite",
+		errors:  []string{"no such module: fts5"},
+	},
+	{
+		Name:    "rtree",
+		Purpose: "R*Tree indexes for range and spatial lookups",
+		Install: "use a SQLite build compiled with SQLITE_ENABLE_RTREE",
+		dialect: "sqlite",
+		errors:  []string{"no such module: rtree"},
+	},
+}
+
+// normalizeDialect maps the names used for a dialect to postgresql, mysql or sqlite
+func normalizeDialect(dialect string) string {
+	switch strings.ToLower(dialect) {
+	case "postgresql", "postgres", "":
+		return "postgresql"
+	case "files":
+		return "sqlite"
+	default:
+		return strings.ToLower(dialect)
+	}
+}
+
+// MissingCapability reports the extension or setting whose absence caused an
+// error, so the user gets install instructions instead of a bare error
+func MissingCapability(dialect string, err error) (Capability, bool) {
+	if err == nil {
+		return Capability{}, false
+	}
+	message := strings.ToLower(err.Error())
+	dialect = normalizeDialect(dialect)
+	for _, c := range capabilities {
+		if c.dialect != dialect {
+			continue
+		}
+		for _, fragment := range c.errors {
+			if strings.Contains(message, fragment) {
+				return c, true
+			}
+		}
+	}
+	return Capability{}, false
+}
+
+// LookupCapability returns a capability dbsage knows by name
+func LookupCapability(name string) (Capability, bool) {
+	for _, c := range capabilities {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Capability{}, false
+}
+
+// Extension is an installed (or installable) extension, plugin or compiled-in module
+type Extension struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Installed   bool   `json:"installed"`
+	Description string `json:"description,omitempty"`
+}
+
+// Feature is a server setting relevant to analysis
+type Feature struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BuildExtensionsQuery returns the query listing the extensions of a dialect:
+// available extensions on PostgreSQL, plugins on MySQL and compile options on SQLite
+func BuildExtensionsQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT name, COALESCE(installed_version, default_version, ''), installed_version IS NOT NULL, COALESCE(comment, '')
+FROM pg_available_extensions
+ORDER BY installed_version IS NULL, name`, nil
+	case "mysql":
+		return `SELECT PLUGIN_NAME, PLUGIN_VERSION, PLUGIN_STATUS = 'ACTIVE', COALESCE(PLUGIN_DESCRIPTION, '')
+FROM INFORMATION_SCHEMA.PLUGINS
+ORDER BY PLUGIN_NAME`, nil
+	case "sqlite":
+		return `SELECT compile_options, '', 1, '' FROM pragma_compile_options WHERE compile_options LIKE 'ENABLE_%'`, nil
+	default:
+		return "", fmt.Errorf("listing extensions is not supported for %s databases", dialect)
+	}
+}
+
+// BuildFeaturesQuery returns the query listing the server settings of a dialect that matter for analysis
+func BuildFeaturesQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT name, setting || COALESCE(' ' || unit, '')
+FROM pg_settings
+WHERE name IN ('server_version', 'shared_preload_libraries', 'track_io_timing', 'track_activity_query_size',
+	'wal_level', 'jit', 'max_parallel_workers_per_gather', 'shared_buffers', 'work_mem')
+ORDER BY name`, nil
+	case "mysql":
+		return `SHOW GLOBAL VARIABLES WHERE Variable_name IN ('version', 'version_comment', 'performance_schema',
+	'slow_query_log', 'long_query_time', 'innodb_buffer_pool_size', 'have_ssl')`, nil
+	case "sqlite":
+		return `SELECT 'sqlite_version', sqlite_version()
+UNION ALL SELECT 'journal_mode', journal_mode FROM pragma_journal_mode
+UNION ALL SELECT 'foreign_keys', foreign_keys FROM pragma_foreign_keys`, nil
+	default:
+		return "", fmt.Errorf("listing features is not supported for %s databases", dialect)
+	}
+}
+
+// ExtensionsFromResult converts an extensions query result
+func ExtensionsFromResult(dialect string, result *models.QueryResult) []Extension {
+	if result == nil {
+		return nil
+	}
+	var extensions []Extension
+	for _, row := range result.Rows {
+		if len(row) < 4 {
+			continue
+		}
+		e := Extension{
+			Name:        fmt.Sprintf("%v", cellString(row[0])),
+			Version:     fmt.Sprintf("%v", cellString(row[1])),
+			Installed:   isTrue(cellString(row[2])),
+			Description: fmt.Sprintf("%v", cellString(row[3])),
+		}
+		if normalizeDialect(dialect) == "sqlite" {
+			e.Name = strings.ToLower(strings.TrimPrefix(e.Name, "ENABLE_"))
+		}
+		extensions = append(extensions, e)
+	}
+	return extensions
+}
+
+// FeaturesFromResult converts a features query result
+func FeaturesFromResult(result *models.QueryResult) []Feature {
+	if result == nil {
+		return nil
+	}
+	var features []Feature
+	for _, row := range result.Rows {
+		if len(row) < 2 {
+			continue
+		}
+		features = append(features, Feature{
+			Name:  strings.ToLower(fmt.Sprintf("%v", cellString(row[0]))),
+			Value: fmt.Sprintf("%v", cellString(row[1])),
+		})
+	}
+	return features
+}
+
+// MissingCapabilities returns the known capabilities of a dialect that are not
+// installed. Capabilities backed by settings are only checked when features
+// are given.
+func MissingCapabilities(dialect string, extensions []Extension, features []Feature) []Capability {
+	installed := make(map[string]bool)
+	for _, e := range extensions {
+		if e.Installed {
+			installed[strings.ToLower(e.Name)] = true
+		}
+	}
+	settings := make(map[string]string)
+	for _, f := range features {
+		settings[f.Name] = f.Value
+	}
+
+	dialect = normalizeDialect(dialect)
+	var missing []Capability
+	for _, c := range capabilities {
+		if c.dialect != dialect {
+			continue
+		}
+		if c.setting != "" {
+			if value, ok := settings[c.setting]; ok && !isTrue(value) {
+				missing = append(missing, c)
+			}
+			continue
+		}
+		if extensions != nil && !installed[c.Name] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// isTrue interprets boolean cells and setting values such as ON, 1 and t
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	switch strings.ToLower(fmt.Sprintf("%v", value)) {
+	case "1", "t", "true", "on", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package sqlanalysis
+
+import (
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingCapability(t *testing.T) {
+	c, ok := MissingCapability("postgresql", errors.New(`query execution failed: pq: relation "pg_stat_statements" does not exist`))
+	require.True(t, ok)
+	assert.Equal(t, "pg_stat_statements", c.Name)
+	assert.Contains(t, c.Install, "shared_preload_libraries")
+
+	c, ok = MissingCapability("sqlite", errors.New("no such module: fts5"))
+	require.True(t, ok)
+	assert.Equal(t, "fts5", c.Name)
+
+	_, ok = MissingCapability("postgresql", errors.New(`relation "orders" does not exist`))
+	assert.False(t, ok)
+	_, ok = MissingCapability("mysql", errors.New(`relation "pg_stat_statements" does not exist`))
+	assert.False(t, ok)
+}
+
+func TestMissingCapabilities(t *testing.T) {
+	extensions := ExtensionsFromResult("postgresql", &models.QueryResult{Rows: [][]interface{}{
+		{"pg_stat_statements", "1.10", true, "track statistics"},
+		{"pgcrypto", "1.3", false, "cryptographic functions"},
+	}})
+	require.Len(t, extensions, 2)
+	assert.True(t, extensions[0].Installed)
+	assert.False(t, extensions[1].Installed)
+
+	var names []string
+	for _, c := range MissingCapabilities("postgresql", extensions, nil) {
+		names = append(names, c.Name)
+	}
+	assert.Contains(t, names, "pgcrypto")
+	assert.NotContains(t, names, "pg_stat_statements")
+
+	features := FeaturesFromResult(&models.QueryResult{Rows: [][]interface{}{{"performance_schema", "OFF"}}})
+	missing := MissingCapabilities("mysql", nil, features)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "performance_schema", missing[0].Name)
+	assert.Empty(t, MissingCapabilities("mysql", nil, []Feature{{Name: "performance_schema", Value: "ON"}}))
+}
+
+func TestExtensionsFromResult_SQLite(t *testing.T) {
+	extensions := ExtensionsFromResult("sqlite", &models.QueryResult{Rows: [][]interface{}{
+		{"ENABLE_FTS5", "", int64(1), ""},
+	}})
+	require.Len(t, extensions, 1)
+	assert.Equal(t, "fts5", extensions[0].Name)
+
+	var names []string
+	for _, c := range MissingCapabilities("sqlite", extensions, nil) {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"rtree"}, names)
+}
@@ -0,0 +1,26 @@
+package sqlanalysis
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	numberLiteralPattern   = regexp.MustCompile(`\b\d+(?:\.\d+)?(?:[eE][-+]?\d+)?\b`)
+	placeholderListPattern = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	valuesListPattern      = regexp.MustCompile(`(?i)\bvalues\s*\(\?\)(?:\s*,\s*\(\?\))+`)
+)
+
+// Fingerprint normalizes a statement so that variants differing only in
+// literal values map to the same text: comments are removed, string and
+// number literals and bind parameters become ?, lists of them collapse to a
+// single (?), and the text is lowercased with whitespace collapsed
+func Fingerprint(query string) string {
+	fp := stringLiteralPattern.ReplaceAllString(StripComments(query), "?")
+	fp = placeholderPattern.ReplaceAllString(fp, "?")
+	fp = numberLiteralPattern.ReplaceAllString(fp, "?")
+	fp = strings.ToLower(strings.Join(strings.Fields(fp), " "))
+	fp = placeholderListPattern.ReplaceAllString(fp, "(?)")
+	fp = valuesListPattern.ReplaceAllString(fp, "values (?)")
+	return strings.TrimSuffix(strings.TrimSpace(fp), ";")
+}
@@ -0,0 +1,21 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, "select * from orders where id = ? and status = ?",
+		Fingerprint("SELECT *\n  FROM orders -- recent\n WHERE id = 42 AND status = 'it''s';"))
+	assert.Equal(t, Fingerprint("select * from orders where id = 7 and status = 'new'"),
+		Fingerprint("SELECT * FROM orders WHERE id = $1 AND status = ?"))
+	assert.Equal(t, "select name from users where id in (?)",
+		Fingerprint("select name from users where id in (1, 2, 3)"))
+	assert.Equal(t, "insert into t (a, b) values (?)",
+		Fingerprint("INSERT INTO t (a, b) VALUES (1, 'x')"))
+	assert.Equal(t, "insert into t values (?)",
+		Fingerprint("INSERT INTO t VALUES (1), (2), (3)"))
+	assert.Equal(t, "select * from table2 limit ?", Fingerprint("SELECT * FROM table2 LIMIT 10"))
+}
@@ -0,0 +1,674 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+const (
+	maxIndexColumns    = 4  // Columns of a suggested index; wider ones rarely pay off
+	maxIndexCandidates = 10 // Suggestions AdviseIndexes returns
+	maxIndexExamples   = 3  // Statements kept per suggestion, heaviest first
+)
+
+// Ways a statement uses a column that an index can serve
+const (
+	useEquality = "equality"
+	useJoin     = "join"
+	useRange    = "range"
+	useSort     = "sort"
+)
+
+// HypoPGInstalledQuery returns a row when HypoPG is installed in the current database
+const HypoPGInstalledQuery = `SELECT extversion FROM pg_extension WHERE extname = 'hypopg'`
+
+// ExistingIndex is an index of a table, with its key columns in order
+type ExistingIndex struct {
+	Table   string
+	Name    string
+	Columns []string
+}
+
+// IndexCandidate is an index the statements of the workload would use
+type IndexCandidate struct {
+	Table      string           `json:"table"`
+	Columns    []string         `json:"columns"`
+	Reason     string           `json:"reason"` // How the statements use the columns
+	DDL        string           `json:"ddl"`
+	Statements int              `json:"statements"`
+	Calls      int64            `json:"calls"`
+	TotalMs    float64          `json:"total_ms"` // Time the statements took, calls times mean
+	Examples   []string         `json:"examples"`
+	Evaluation *IndexEvaluation `json:"evaluation,omitempty"`
+
+	equality int // Leading columns compared for equality, in any order
+}
+
+// IndexEvaluation is what the planner makes of a candidate built as a
+// hypothetical index
+type IndexEvaluation struct {
+	Statement  string  `json:"statement"` // The statement planned with and without the index
+	CostBefore float64 `json:"cost_before,omitempty"`
+	CostAfter  float64 `json:"cost_after,omitempty"`
+	Used       bool    `json:"used"`
+	Verdict    string  `json:"verdict,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// IndexAdvice is the outcome of the workload-driven index analysis
+type IndexAdvice struct {
+	Statements     int              `json:"statements"` // Statements of the workload analyzed
+	Candidates     []IndexCandidate `json:"candidates"`
+	AlreadyIndexed []string         `json:"already_indexed,omitempty"` // Predicates an existing index serves
+	Notes          []string         `json:"notes,omitempty"`
+
+	// Set when the statistics could not be read, or hid the statements of
+	// other users
+	MissingPrivilege *Privilege `json:"missing_privilege,omitempty"`
+	HiddenQueries    int        `json:"hidden_queries,omitempty"`
+}
+
+// HypotheticalDDL returns the DDL of the candidate as HypoPG accepts it,
+// which does not know CONCURRENTLY
+func (c IndexCandidate) HypotheticalDDL() string {
+	return strings.Replace(c.DDL, "CREATE INDEX CONCURRENTLY ", "CREATE INDEX ", 1)
+}
+
+// BuildDigestQuery returns the query reading the heaviest normalized
+// statements of the current database as (sql, calls, mean_ms), which
+// WorkloadFromResult reads, or an error if the dialect is not supported
+func BuildDigestQuery(dialect string, limit int) (string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return BuildCaptureQuery("postgresql", limit)
+	case "mysql":
+		return fmt.Sprintf(`SELECT DIGEST_TEXT, COUNT_STAR, AVG_TIMER_WAIT / 1000000000 AS mean_ms
+FROM performance_schema.events_statements_summary_by_digest
+WHERE SCHEMA_NAME = DATABASE() AND DIGEST_TEXT IS NOT NULL
+ORDER BY SUM_TIMER_WAIT DESC
+LIMIT %d`, limit), nil
+	default:
+		return "", fmt.Errorf("workload index analysis is not supported for %s databases", dialect)
+	}
+}
+
+// BuildIndexColumnsQuery returns the query reading the indexes of the user's
+// tables as (table, index, comma-separated key columns). Partial indexes are
+// left out, as they only serve the statements matching their predicate.
+func BuildIndexColumnsQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT CASE WHEN n.nspname = 'public' THEN t.relname ELSE n.nspname || '.' || t.relname END,
+	i.relname,
+	string_agg(COALESCE(a.attname, '(expression)'), ',' ORDER BY k.ord)
+FROM pg_index x
+JOIN pg_class t ON t.oid = x.indrelid
+JOIN pg_class i ON i.oid = x.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum > 0
+WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND x.indpred IS NULL AND k.ord <= x.indnkeyatts
+GROUP BY n.nspname, t.relname, i.relname`, nil
+	case "mysql":
+		return `SELECT TABLE_NAME, INDEX_NAME, GROUP_CONCAT(COALESCE(COLUMN_NAME, '(expression)') ORDER BY SEQ_IN_INDEX SEPARATOR ',')
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE()
+GROUP BY TABLE_NAME, INDEX_NAME`, nil
+	default:
+		return "", fmt.Errorf("index analysis is not supported for %s databases", dialect)
+	}
+}
+
+// IndexesFromResult converts the rows of BuildIndexColumnsQuery to indexes
+func IndexesFromResult(result *models.QueryResult) []ExistingIndex {
+	if result == nil {
+		return nil
+	}
+	var indexes []ExistingIndex
+	for _, row := range result.Rows {
+		if len(row) < 3 {
+			continue
+		}
+		index := ExistingIndex{Table: cellText(row[0]), Name: cellText(row[1])}
+		for _, column := range strings.Split(cellText(row[2]), ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				index.Columns = append(index.Columns, column)
+			}
+		}
+		if index.Table != "" && len(index.Columns) > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// HypotheticalIndexFromResult returns the name of the index hypopg_create_index created
+func HypotheticalIndexFromResult(result *models.QueryResult) string {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return ""
+	}
+	return cellText(result.Rows[0][0])
+}
+
+// BuildHypotheticalExplain returns the EXPLAIN planning a workload statement.
+// Normalized statements have $n parameters in place of their values, which
+// PostgreSQL 16 and later can plan generically; version is the server's major
+// version, 0 when unknown.
+func BuildHypotheticalExplain(statement string, version int) (string, error) {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	if !HasPlaceholders(statement) {
+		return "EXPLAIN (FORMAT JSON) " + statement, nil
+	}
+	if version > 0 && version < 16 {
+		return "", fmt.Errorf("the statement is normalized with $n parameters, which PostgreSQL %d cannot plan without values (GENERIC_PLAN needs 16 or later)", version)
+	}
+	return "EXPLAIN (FORMAT JSON, GENERIC_PLAN) " + statement, nil
+}
+
+// NewIndexEvaluation compares the plans of a statement without and with a
+// hypothetical index
+func NewIndexEvaluation(statement string, before, after *PlanNode, index string) *IndexEvaluation {
+	evaluation := &IndexEvaluation{Statement: statement, CostBefore: before.TotalCost, CostAfter: after.TotalCost}
+	after.Walk(func(node *PlanNode) {
+		if node.Index != "" && node.Index == index {
+			evaluation.Used = true
+		}
+	})
+	switch {
+	case !evaluation.Used:
+		evaluation.Verdict = "the planner does not use the index for this statement; it is probably not worth building"
+	case before.TotalCost > 0:
+		change := (after.TotalCost - before.TotalCost) / before.TotalCost * 100
+		evaluation.Verdict = fmt.Sprintf("the planner uses the index: estimated cost %.1f -> %.1f (%+.0f%%)", before.TotalCost, after.TotalCost, change)
+	default:
+		evaluation.Verdict = "the planner uses the index"
+	}
+	return evaluation
+}
+
+// AdviseIndexes proposes indexes for the predicates of the heaviest statements
+// of a workload: columns compared for equality or joined on lead, then one
+// column compared by range, or else the columns the statement sorts by.
+// Predicates an existing index already serves are reported apart, and
+// candidates that are a prefix of another are folded into it. The analysis is
+// textual; it does not know whether the planner would prefer the index, which
+// an evaluation with hypothetical indexes tells.
+func AdviseIndexes(dialect string, workload []WorkloadEntry, schema []SchemaTable, indexes []ExistingIndex) IndexAdvice {
+	advice := IndexAdvice{Statements: len(workload), Candidates: []IndexCandidate{}}
+	tables := make(map[string]SchemaTable)
+	for _, t := range schema {
+		tables[strings.ToLower(t.Name)] = t
+	}
+
+	// Heaviest first, so each candidate's first example is its heaviest statement
+	entries := append([]WorkloadEntry(nil), workload...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return float64(entries[i].Calls)*entries[i].MeanMs > float64(entries[j].Calls)*entries[j].MeanMs
+	})
+
+	var candidates []*IndexCandidate
+	byKey := make(map[string]*IndexCandidate)
+	alreadyIndexed := make(map[string]bool)
+	for _, entry := range entries {
+		for _, proposal := range proposeIndexes(entry.SQL, tables) {
+			if name, ok := coveringIndex(indexes, proposal.table, proposal.columns, proposal.equality); ok {
+				note := fmt.Sprintf("%s(%s) by %s", proposal.table, strings.Join(proposal.columns, ", "), name)
+				if !alreadyIndexed[note] {
+					alreadyIndexed[note] = true
+					advice.AlreadyIndexed = append(advice.AlreadyIndexed, note)
+				}
+				continue
+			}
+			key := strings.ToLower(proposal.table + "(" + strings.Join(proposal.columns, ",") + ")")
+			candidate := byKey[key]
+			if candidate == nil {
+				candidate = &IndexCandidate{
+					Table:    proposal.table,
+					Columns:  proposal.columns,
+					Reason:   proposal.reason,
+					DDL:      indexDDL(dialect, proposal.table, proposal.columns),
+					equality: proposal.equality,
+				}
+				byKey[key] = candidate
+				candidates = append(candidates, candidate)
+			}
+			candidate.add(entry)
+		}
+	}
+
+	// Fold candidates into the longer ones that serve them too
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i].Columns) > len(candidates[j].Columns) })
+	var kept []*IndexCandidate
+	for _, candidate := range candidates {
+		folded := false
+		for _, longer := range kept {
+			if strings.EqualFold(longer.Table, candidate.Table) && covers(longer.Columns, candidate.Columns, candidate.equality) {
+				longer.merge(candidate)
+				folded = true
+				break
+			}
+		}
+		if !folded {
+			kept = append(kept, candidate)
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].TotalMs > kept[j].TotalMs })
+	for i, candidate := range kept {
+		if i == maxIndexCandidates {
+			break
+		}
+		candidate.TotalMs = math.Round(candidate.TotalMs*1000) / 1000
+		advice.Candidates = append(advice.Candidates, *candidate)
+	}
+	return advice
+}
+
+// add counts a statement the candidate serves
+func (c *IndexCandidate) add(entry WorkloadEntry) {
+	c.Statements++
+	c.Calls += entry.Calls
+	c.TotalMs += float64(entry.Calls) * entry.MeanMs
+	if len(c.Examples) < maxIndexExamples {
+		c.Examples = append(c.Examples, entry.SQL)
+	}
+}
+
+// merge counts the statements of a candidate this one also serves
+func (c *IndexCandidate) merge(other *IndexCandidate) {
+	c.Statements += other.Statements
+	c.Calls += other.Calls
+	c.TotalMs += other.TotalMs
+	for _, example := range other.Examples {
+		if len(c.Examples) < maxIndexExamples {
+			c.Examples = append(c.Examples, example)
+		}
+	}
+}
+
+// indexProposal is the index one statement would use on one table
+type indexProposal struct {
+	table    string
+	columns  []string
+	equality int
+	reason   string
+}
+
+// tableUses collects how a statement uses the columns of one table
+type tableUses struct {
+	table    string
+	equality []string
+	joins    []string
+	ranges   []string
+	sorts    []string
+}
+
+// proposeIndexes returns the index each table of a statement would use for
+// the statement's predicates
+func proposeIndexes(sql string, tables map[string]SchemaTable) []indexProposal {
+	refs := TableReferences(sql)
+	var order []*tableUses
+	byTable := make(map[string]*tableUses)
+	for _, use := range columnUses(sql) {
+		table, column, ok := resolveColumn(use, refs, tables)
+		if !ok {
+			continue
+		}
+		uses := byTable[strings.ToLower(table)]
+		if uses == nil {
+			uses = &tableUses{table: table}
+			byTable[strings.ToLower(table)] = uses
+			order = append(order, uses)
+		}
+		switch use.kind {
+		case useEquality:
+			uses.equality = appendUnique(uses.equality, column)
+		case useJoin:
+			uses.joins = appendUnique(uses.joins, column)
+		case useRange:
+			uses.ranges = appendUnique(uses.ranges, column)
+		case useSort:
+			uses.sorts = appendUnique(uses.sorts, column)
+		}
+	}
+
+	var proposals []indexProposal
+	for _, uses := range order {
+		// A table filtered on its own columns is read first and the join
+		// columns of the other side do the lookup; otherwise this side's
+		// join columns are the lookup
+		sort.Strings(uses.equality)
+		var joins []string
+		if len(uses.equality) == 0 && len(uses.ranges) == 0 {
+			joins = append(joins, uses.joins...)
+			sort.Strings(joins)
+		}
+		columns := append(append([]string(nil), uses.equality...), joins...)
+		equality := len(columns)
+
+		var reasons []string
+		if len(uses.equality) > 0 {
+			reasons = append(reasons, "equality on "+strings.Join(uses.equality, ", "))
+		}
+		if len(joins) > 0 {
+			reasons = append(reasons, "join on "+strings.Join(joins, ", "))
+		}
+		// One range column can follow the equality columns; the sort order
+		// only helps when there is none and the table is the only one read
+		rangeColumn := ""
+		for _, column := range uses.ranges {
+			if !containsFold(columns, column) {
+				rangeColumn = column
+				break
+			}
+		}
+		switch {
+		case rangeColumn != "":
+			columns = append(columns, rangeColumn)
+			reasons = append(reasons, "range on "+rangeColumn)
+		case len(uses.sorts) > 0 && len(order) == 1:
+			var sorts []string
+			for _, column := range uses.sorts {
+				if !containsFold(columns, column) {
+					sorts = append(sorts, column)
+				}
+			}
+			if len(sorts) > 0 {
+				columns = append(columns, sorts...)
+				reasons = append(reasons, "sort on "+strings.Join(sorts, ", "))
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		if len(columns) > maxIndexColumns {
+			columns = columns[:maxIndexColumns]
+		}
+		proposals = append(proposals, indexProposal{
+			table:    uses.table,
+			columns:  columns,
+			equality: min(equality, len(columns)),
+			reason:   strings.Join(reasons, "; "),
+		})
+	}
+	return proposals
+}
+
+// columnUse is a column a statement compares, joins on or sorts by
+type columnUse struct {
+	qualifier string // Table or alias, empty when unqualified
+	column    string
+	kind      string
+}
+
+// predicateClauseEnds end a WHERE or ON condition or an ORDER BY list
+var predicateClauseEnds = map[string]bool{
+	"select": true, "from": true, "group": true, "having": true, "limit": true, "offset": true,
+	"window": true, "fetch": true, "for": true, "union": true, "except": true, "intersect": true,
+	"join": true, "inner": true, "left": true, "right": true, "full": true, "cross": true,
+	"natural": true, "returning": true, "set": true, "values": true, "using": true, "qualify": true,
+}
+
+// columnUses returns the columns a statement compares in its WHERE and ON
+// conditions, and those its top-level ORDER BY sorts by. Columns wrapped in
+// functions or casts are left out, as a plain index cannot serve them.
+func columnUses(sql string) []columnUse {
+	runes := []rune(StripComments(sql))
+	tokens := tokenizeSQL(runes)
+	var uses []columnUse
+	clause := ""
+	var outer []string // Clause around each open parenthesis
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.is("("):
+			outer = append(outer, clause)
+			continue
+		case tok.is(")"):
+			if n := len(outer); n > 0 {
+				clause, outer = outer[n-1], outer[:n-1]
+			}
+			continue
+		case tok.isWord("where") || (tok.isWord("on") && !(i+1 < len(tokens) && (tokens[i+1].isWord("conflict") || tokens[i+1].isWord("duplicate")))):
+			clause = "filter"
+			continue
+		case tok.isWord("order") && i+1 < len(tokens) && tokens[i+1].isWord("by"):
+			clause = "order"
+			i++
+			continue
+		case tok.kind == tokenWord && predicateClauseEnds[strings.ToLower(tok.text)]:
+			clause = ""
+			continue
+		}
+
+		switch {
+		case clause == "filter":
+			i = comparisonUses(runes, tokens, i, &uses)
+		case clause == "order" && len(outer) == 0:
+			qualifier, column, next, ok := columnAt(tokens, i)
+			if !ok {
+				continue
+			}
+			if next == len(tokens) || tokens[next].is(",") || tokens[next].isWord("asc") || tokens[next].isWord("desc") ||
+				tokens[next].isWord("nulls") || (tokens[next].kind == tokenWord && predicateClauseEnds[strings.ToLower(tokens[next].text)]) {
+				uses = append(uses, columnUse{qualifier: qualifier, column: column, kind: useSort})
+			}
+			i = next - 1
+		}
+	}
+	return uses
+}
+
+// comparisonUses records the columns compared by the comparison starting at
+// tokens[i], a column compared with a value or another column or a value
+// compared with a column, and returns the index of its last token
+func comparisonUses(runes []rune, tokens []sqlToken, i int, uses *[]columnUse) int {
+	if qualifier, column, next, ok := columnAt(tokens, i); ok {
+		kind, after := comparisonAt(runes, tokens, next)
+		if kind == "" {
+			return next - 1
+		}
+		if kind == useEquality {
+			if otherQualifier, other, end, ok := columnAt(tokens, after); ok && (end == len(tokens) || !tokens[end].is("::")) {
+				*uses = append(*uses,
+					columnUse{qualifier: qualifier, column: column, kind: useJoin},
+					columnUse{qualifier: otherQualifier, column: other, kind: useJoin})
+				return end - 1
+			}
+		}
+		*uses = append(*uses, columnUse{qualifier: qualifier, column: column, kind: kind})
+		return after - 1
+	}
+
+	// A value compared with a column, e.g. 10 < amount
+	if tok := tokens[i]; tok.kind != tokenNumber && tok.kind != tokenString {
+		return i
+	}
+	if i+1 >= len(tokens) || !(tokens[i+1].is("=") || tokens[i+1].is("<") || tokens[i+1].is(">")) {
+		return i
+	}
+	kind, after := comparisonAt(runes, tokens, i+1)
+	if kind == "" {
+		return i
+	}
+	if qualifier, column, next, ok := columnAt(tokens, after); ok && (next == len(tokens) || !tokens[next].is("::")) {
+		*uses = append(*uses, columnUse{qualifier: qualifier, column: column, kind: kind})
+		return next - 1
+	}
+	return i
+}
+
+// comparisonAt reads the comparison operator at tokens[i] and returns the use
+// it makes of its column, empty when an index cannot serve it, and the index
+// of the token after it
+func comparisonAt(runes []rune, tokens []sqlToken, i int) (string, int) {
+	if i >= len(tokens) {
+		return "", i
+	}
+	symbolAt := func(j int, symbol string) bool { return j < len(tokens) && tokens[j].is(symbol) }
+	tok := tokens[i]
+	switch {
+	case tok.is("="):
+		return useEquality, i + 1
+	case tok.is("<") && symbolAt(i+1, ">"):
+		return "", i + 2
+	case (tok.is("<") || tok.is(">")) && symbolAt(i+1, "="):
+		return useRange, i + 2
+	case tok.is("<") || tok.is(">") || tok.isWord("between"):
+		return useRange, i + 1
+	case tok.isWord("in"):
+		return useEquality, i + 1
+	case tok.isWord("is") && i+1 < len(tokens) && tokens[i+1].isWord("null"):
+		return useEquality, i + 2
+	case tok.isWord("like") && i+1 < len(tokens) && tokens[i+1].kind == tokenString:
+		// Only a pattern with a fixed prefix is a range an index can scan
+		literal := tokens[i+1]
+		if literal.end-literal.start > 2 && runes[literal.start+1] != '%' && runes[literal.start+1] != '_' {
+			return useRange, i + 2
+		}
+	}
+	return "", i + 1
+}
+
+// columnAt reads the possibly qualified column at tokens[i] and returns its
+// qualifier, name and the index after it. Function names, cast types and
+// keywords are not columns.
+func columnAt(tokens []sqlToken, i int) (string, string, int, bool) {
+	if i >= len(tokens) || !tokens[i].isName() {
+		return "", "", i, false
+	}
+	if i > 0 && (tokens[i-1].is(".") || tokens[i-1].is("::")) {
+		return "", "", i + 1, false
+	}
+	parts, next := qualifiedName(tokens, i)
+	if next < len(tokens) && (tokens[next].is("(") || tokens[next].is("::")) {
+		return "", "", next, false
+	}
+	return strings.Join(parts[:len(parts)-1], "."), parts[len(parts)-1], next, true
+}
+
+// resolveColumn finds the table and schema name of a column a statement
+// uses. Qualified columns are looked up through the statement's aliases;
+// unqualified ones must belong to exactly one of its tables.
+func resolveColumn(use columnUse, refs []TableReference, tables map[string]SchemaTable) (string, string, bool) {
+	if use.qualifier != "" {
+		qualifier := strings.ToLower(use.qualifier)
+		for _, ref := range refs {
+			if strings.ToLower(ref.Alias) == qualifier || strings.ToLower(ref.Table) == qualifier ||
+				(ref.Alias == "" && strings.ToLower(unqualifiedTable(ref.Table)) == qualifier) {
+				return lookupColumn(tables, ref.Table, use.column)
+			}
+		}
+		return "", "", false
+	}
+
+	var table, column string
+	found := 0
+	for _, ref := range refs {
+		if t, c, ok := lookupColumn(tables, ref.Table, use.column); ok && !strings.EqualFold(t, table) {
+			table, column = t, c
+			found++
+		}
+	}
+	return table, column, found == 1
+}
+
+// lookupColumn returns the schema names of a table and one of its columns
+func lookupColumn(tables map[string]SchemaTable, table, column string) (string, string, bool) {
+	t, ok := tables[strings.ToLower(table)]
+	if !ok {
+		if t, ok = tables[strings.ToLower(unqualifiedTable(table))]; !ok {
+			return "", "", false
+		}
+	}
+	for _, definition := range t.Columns {
+		name, _, _ := strings.Cut(definition, " ")
+		if strings.EqualFold(name, column) {
+			return t.Name, name, true
+		}
+	}
+	return "", "", false
+}
+
+// coveringIndex returns the existing index of a table that serves the columns
+func coveringIndex(indexes []ExistingIndex, table string, columns []string, equality int) (string, bool) {
+	for _, index := range indexes {
+		if (strings.EqualFold(index.Table, table) || strings.EqualFold(unqualifiedTable(index.Table), unqualifiedTable(table))) &&
+			covers(index.Columns, columns, equality) {
+			return index.Name, true
+		}
+	}
+	return "", false
+}
+
+// covers reports whether an index on indexColumns serves a lookup on columns
+// whose first equality columns may come in any order
+func covers(indexColumns, columns []string, equality int) bool {
+	if len(indexColumns) < len(columns) {
+		return false
+	}
+	for i := 0; i < equality; i++ {
+		if !containsFold(indexColumns[:equality], columns[i]) {
+			return false
+		}
+	}
+	for i := equality; i < len(columns); i++ {
+		if !strings.EqualFold(indexColumns[i], columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexDDL returns the statement building an index on columns of table.
+// PostgreSQL builds it concurrently so writes to the table are not blocked.
+func indexDDL(dialect, table string, columns []string) string {
+	name := strings.ToLower(unqualifiedTable(table) + "_" + strings.Join(columns, "_") + "_idx")
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = identifier(dialect, part)
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = identifier(dialect, column)
+	}
+
+	create := "CREATE INDEX "
+	if normalizeDialect(dialect) == "postgresql" {
+		create = "CREATE INDEX CONCURRENTLY "
+	}
+	return fmt.Sprintf("%s%s ON %s (%s)", create, name, strings.Join(parts, "."), strings.Join(quoted, ", "))
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsFold(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var indexAdvisorSchema = []SchemaTable{
+	{Name: "orders", Columns: []string{"id integer", "customer_id integer", "status text", "created_at timestamp without time zone", "total numeric"}},
+	{Name: "customers", Columns: []string{"id integer", "email text", "country text"}},
+}
+
+func TestColumnUses(t *testing.T) {
+	uses := columnUses(`SELECT o.id FROM orders o JOIN customers c ON c.id = o.customer_id
+WHERE o.status = $1 AND $2 < o.created_at AND lower(c.email) = $3 AND c.country IN ($4, $5)
+  AND o.total::int > 10 AND o.id IS NOT NULL
+ORDER BY o.created_at DESC LIMIT 10`)
+	assert.Equal(t, []columnUse{
+		{qualifier: "c", column: "id", kind: useJoin},
+		{qualifier: "o", column: "customer_id", kind: useJoin},
+		{qualifier: "o", column: "status", kind: useEquality},
+		{qualifier: "o", column: "created_at", kind: useRange},
+		{qualifier: "c", column: "country", kind: useEquality},
+		{qualifier: "o", column: "created_at", kind: useSort},
+	}, uses)
+
+	uses = columnUses("SELECT * FROM customers WHERE email LIKE 'ann%' OR email LIKE '%bob' ORDER BY (SELECT 1)")
+	assert.Equal(t, []columnUse{{column: "email", kind: useRange}}, uses)
+
+	assert.Empty(t, columnUses("INSERT INTO orders (id, status) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET status = excluded.status"))
+}
+
+func TestAdviseIndexes(t *testing.T) {
+	workload := []WorkloadEntry{
+		{SQL: "SELECT * FROM orders WHERE status = $1", Calls: 10, MeanMs: 5},
+		{SQL: "SELECT * FROM orders WHERE status = $1 AND created_at >= $2 ORDER BY created_at", Calls: 1000, MeanMs: 40},
+		{SQL: "SELECT o.id FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.email = $1", Calls: 200, MeanMs: 3},
+		{SQL: "SELECT * FROM customers WHERE id = $1", Calls: 90000, MeanMs: 0.1},
+		{SQL: "SELECT now()", Calls: 5, MeanMs: 0.01},
+	}
+	existing := []ExistingIndex{{Table: "customers", Name: "customers_pkey", Columns: []string{"id"}}}
+
+	advice := AdviseIndexes("postgresql", workload, indexAdvisorSchema, existing)
+	assert.Equal(t, 5, advice.Statements)
+	require.Len(t, advice.Candidates, 3)
+
+	first := advice.Candidates[0]
+	assert.Equal(t, "orders", first.Table)
+	assert.Equal(t, []string{"status", "created_at"}, first.Columns)
+	assert.Equal(t, "equality on status; range on created_at", first.Reason)
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY orders_status_created_at_idx ON orders (status, created_at)", first.DDL)
+	assert.Equal(t, "CREATE INDEX orders_status_created_at_idx ON orders (status, created_at)", first.HypotheticalDDL())
+	assert.Equal(t, 2, first.Statements, "the status-only lookup is folded into the wider index")
+	assert.Equal(t, int64(1010), first.Calls)
+	assert.InDelta(t, 40050.0, first.TotalMs, 0.001)
+	assert.Equal(t, workload[1].SQL, first.Examples[0], "the heaviest statement comes first")
+
+	assert.Equal(t, "customers", advice.Candidates[1].Table)
+	assert.Equal(t, []string{"email"}, advice.Candidates[1].Columns)
+	assert.Equal(t, []string{"customer_id"}, advice.Candidates[2].Columns)
+	assert.Equal(t, "join on customer_id", advice.Candidates[2].Reason)
+	assert.Equal(t, []string{"customers(id) by customers_pkey"}, advice.AlreadyIndexed)
+}
+
+func TestAdviseIndexes_ExistingIndexInAnyEqualityOrder(t *testing.T) {
+	workload := []WorkloadEntry{{SQL: "SELECT * FROM `orders` WHERE `status` = ? AND `customer_id` = ?", Calls: 3, MeanMs: 1}}
+	existing := []ExistingIndex{{Table: "orders", Name: "by_status", Columns: []string{"status", "customer_id", "total"}}}
+
+	advice := AdviseIndexes("mysql", workload, indexAdvisorSchema, existing)
+	assert.Empty(t, advice.Candidates)
+	assert.Equal(t, []string{"orders(customer_id, status) by by_status"}, advice.AlreadyIndexed)
+
+	advice = AdviseIndexes("mysql", workload, indexAdvisorSchema, nil)
+	require.Len(t, advice.Candidates, 1)
+	assert.Equal(t, "CREATE INDEX orders_customer_id_status_idx ON orders (customer_id, status)", advice.Candidates[0].DDL)
+}
+
+func TestIndexesFromResult(t *testing.T) {
+	indexes := IndexesFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{"orders", "orders_pkey", "id"},
+		{"sales.invoices", "invoices_due_idx", []byte("customer_id,due_date")},
+		{"orders", "broken", nil},
+	}})
+	assert.Equal(t, []ExistingIndex{
+		{Table: "orders", Name: "orders_pkey", Columns: []string{"id"}},
+		{Table: "sales.invoices", Name: "invoices_due_idx", Columns: []string{"customer_id", "due_date"}},
+	}, indexes)
+}
+
+func TestBuildHypotheticalExplain(t *testing.T) {
+	explain, err := BuildHypotheticalExplain("SELECT * FROM orders WHERE status = 'new';", 14)
+	require.NoError(t, err)
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) SELECT * FROM orders WHERE status = 'new'", explain)
+
+	explain, err = BuildHypotheticalExplain("SELECT * FROM orders WHERE status = $1", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "EXPLAIN (FORMAT JSON, GENERIC_PLAN) SELECT * FROM orders WHERE status = $1", explain)
+
+	_, err = BuildHypotheticalExplain("SELECT * FROM orders WHERE status = $1", 15)
+	assert.ErrorContains(t, err, "GENERIC_PLAN")
+}
+
+func TestNewIndexEvaluation(t *testing.T) {
+	before := &PlanNode{Operation: "Seq Scan", TotalCost: 1000}
+	after := &PlanNode{Operation: "Limit", TotalCost: 12.5, Children: []*PlanNode{
+		{Operation: "Index Scan", Index: "<13542>btree_orders_status", TotalCost: 12.5},
+	}}
+
+	evaluation := NewIndexEvaluation("SELECT 1", before, after, "<13542>btree_orders_status")
+	assert.True(t, evaluation.Used)
+	assert.Equal(t, "the planner uses the index: estimated cost 1000.0 -> 12.5 (-99%)", evaluation.Verdict)
+
+	evaluation = NewIndexEvaluation("SELECT 1", before, before, "<13542>btree_orders_status")
+	assert.False(t, evaluation.Used)
+	assert.Contains(t, evaluation.Verdict, "not worth building")
+}
@@ -0,0 +1,974 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// maxLineageDepth stops following definitions that nest deeper than any real
+// schema would, e.g. a view chain that refers back to itself
+const maxLineageDepth = 32
+
+// LineageNode is a column of a view, subquery, CTE or table, with the
+// columns it is computed from
+type LineageNode struct {
+	Relation   string         `json:"relation"`
+	Kind       string         `json:"kind"` // view, cte, subquery, table or function
+	Column     string         `json:"column"`
+	Expression string         `json:"expression,omitempty"` // Set when the column is computed rather than selected as is
+	Sources    []*LineageNode `json:"sources,omitempty"`
+}
+
+// ColumnLineage is the lineage of a view column down to base table columns
+type ColumnLineage struct {
+	View        string       `json:"view"`
+	Column      string       `json:"column"`
+	BaseColumns []string     `json:"base_columns"` // table.column pairs the view column is computed from
+	Tree        string       `json:"tree"`
+	Lineage     *LineageNode `json:"lineage"`
+	Warnings    []string     `json:"warnings,omitempty"`
+}
+
+// BuildViewDefinitionsQuery returns the query reading every view as
+// (name, definition)
+func BuildViewDefinitionsQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT c.relname, pg_get_viewdef(c.oid, true)
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('v', 'm') AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY c.relname`, nil
+	case "mysql":
+		return `SELECT TABLE_NAME, VIEW_DEFINITION
+FROM information_schema.VIEWS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME`, nil
+	case "sqlite":
+		return `SELECT name, sql FROM sqlite_master WHERE type = 'view' ORDER BY name`, nil
+	default:
+		return "", fmt.Errorf("column lineage is not supported for %s databases", dialect)
+	}
+}
+
+// ViewDefinitionsFromResult converts a view definitions query result to a map
+// of lower-cased view name to definition
+func ViewDefinitionsFromResult(result *models.QueryResult) map[string]string {
+	views := make(map[string]string)
+	if result == nil {
+		return views
+	}
+	for _, row := range result.Rows {
+		if len(row) < 2 {
+			continue
+		}
+		views[strings.ToLower(cellText(row[0]))] = cellText(row[1])
+	}
+	return views
+}
+
+// TraceColumn follows a view column through the definitions of views, CTEs
+// and subqueries down to the base table columns it is computed from. Only the
+// select list is followed: columns used in filters and join conditions change
+// which rows a view returns, not its values, and are not reported.
+// tableColumns, when not nil, returns the columns of a table, so unqualified
+// columns of joins can be attributed to the right table.
+func TraceColumn(views map[string]string, view, column string, tableColumns func(table string) []string) (*ColumnLineage, error) {
+	t := &lineageTracer{
+		views:        views,
+		parsed:       make(map[string]*lineageQuery),
+		tableColumns: tableColumns,
+		tracing:      make(map[lineageKey]bool),
+	}
+	q, name, ok := t.view(view)
+	if !ok {
+		names := make([]string, 0, len(views))
+		for name := range views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s is not a view: the database has no views", view)
+		}
+		return nil, fmt.Errorf("%s is not a view; views: %s", view, strings.Join(names, ", "))
+	}
+
+	root := &LineageNode{Relation: name, Kind: "view", Column: column}
+	if !t.fill(root, q, nil) {
+		return nil, fmt.Errorf("view %s has no column %s", name, column)
+	}
+
+	lineage := &ColumnLineage{View: name, Column: column, Lineage: root, Warnings: t.warnings}
+	seen := make(map[string]bool)
+	root.walk(func(node *LineageNode) {
+		if node.Kind == "table" {
+			key := node.Relation + "." + node.Column
+			if !seen[key] {
+				seen[key] = true
+				lineage.BaseColumns = append(lineage.BaseColumns, key)
+			}
+		}
+	})
+	sort.Strings(lineage.BaseColumns)
+
+	var b strings.Builder
+	root.render(&b, 0)
+	lineage.Tree = strings.TrimRight(b.String(), "\n")
+	return lineage, nil
+}
+
+func (n *LineageNode) walk(visit func(*LineageNode)) {
+	visit(n)
+	for _, source := range n.Sources {
+		source.walk(visit)
+	}
+}
+
+// render writes the node and its sources as an indented tree
+func (n *LineageNode) render(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%s.%s (%s)", strings.Repeat("  ", depth), n.Relation, n.Column, n.Kind)
+	if n.Expression != "" {
+		fmt.Fprintf(b, " = %s", n.Expression)
+	}
+	b.WriteString("\n")
+	for _, source := range n.Sources {
+		source.render(b, depth+1)
+	}
+}
+
+// sqlTokenKind is the kind of a lexical token
+type sqlTokenKind int
+
+const (
+	tokenWord   sqlTokenKind = iota // Keyword or unquoted identifier
+	tokenQuoted                     // Quoted identifier
+	tokenString                     // String literal
+	tokenNumber                     // Number or parameter
+	tokenSymbol                     // Punctuation or operator
+)
+
+// sqlToken is a token of a statement, with its position in the source
+type sqlToken struct {
+	kind       sqlTokenKind
+	text       string // Unquoted for identifiers
+	start, end int    // Rune offsets in the source
+}
+
+func (t sqlToken) is(symbol string) bool {
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+func (t sqlToken) isWord(word string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+// isName reports whether the token can name a column, table or alias
+func (t sqlToken) isName() bool {
+	return t.kind == tokenQuoted || (t.kind == tokenWord && !lineageKeywords[strings.ToLower(t.text)])
+}
+
+// tokenizeSQL splits a statement without comments into tokens
+func tokenizeSQL(runes []rune) []sqlToken {
+	return tokenize(runes, false)
+}
+
+// tokenize splits a statement into tokens, with backslash escapes in every
+// string literal when backslash is set
+func tokenize(runes []rune, backslash bool) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		start := i
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+		case ch == '\'':
+			i = findClosingQuote(runes, i, ch, backslash)
+			tokens = append(tokens, sqlToken{kind: tokenString, start: start, end: i})
+		case ch == '"' || ch == '`':
+			i = findClosingQuote(runes, i, ch, backslash)
+			text := string(runes[start+1 : max(i-1, start+1)])
+			text = strings.ReplaceAll(text, string(ch)+string(ch), string(ch))
+			tokens = append(tokens, sqlToken{kind: tokenQuoted, text: text, start: start, end: i})
+		case ch == '$':
+			if tag, ok := dollarQuoteTag(runes, i); ok {
+				end := indexRunes(runes, i+len([]rune(tag)), []rune(tag))
+				if end < 0 {
+					i = len(runes)
+				} else {
+					i = end + len([]rune(tag))
+				}
+				tokens = append(tokens, sqlToken{kind: tokenString, start: start, end: i})
+				continue
+			}
+			for i++; i < len(runes) && isDigit(runes[i]); i++ {
+			}
+			tokens = append(tokens, sqlToken{kind: tokenNumber, text: string(runes[start:i]), start: start, end: i})
+		case isDigit(ch) || (ch == '.' && i+1 < len(runes) && isDigit(runes[i+1])):
+			for i++; i < len(runes) && (isDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E'); i++ {
+			}
+			tokens = append(tokens, sqlToken{kind: tokenNumber, text: string(runes[start:i]), start: start, end: i})
+		case isIdentifierRune(ch) || ch >= 0x80:
+			for i++; i < len(runes) && (isIdentifierRune(runes[i]) || runes[i] == '$' || runes[i] >= 0x80); i++ {
+			}
+			tokens = append(tokens, sqlToken{kind: tokenWord, text: string(runes[start:i]), start: start, end: i})
+		case ch == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			i += 2
+			tokens = append(tokens, sqlToken{kind: tokenSymbol, text: "::", start: start, end: i})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: tokenSymbol, text: string(ch), start: start, end: i})
+		}
+	}
+	return tokens
+}
+
+// lineageKeywords are words that never name a column or alias
+var lineageKeywords = map[string]bool{
+	"select": true, "from": true, "where": true, "group": true, "having": true, "order": true,
+	"limit": true, "offset": true, "window": true, "fetch": true, "for": true, "qualify": true,
+	"union": true, "except": true, "intersect": true, "minus": true, "join": true, "inner": true,
+	"left": true, "right": true, "full": true, "outer": true, "cross": true, "natural": true,
+	"on": true, "using": true, "as": true, "and": true, "or": true, "not": true, "null": true,
+	"is": true, "in": true, "case": true, "when": true, "then": true, "else": true, "end": true,
+	"true": true, "false": true, "like": true, "ilike": true, "between": true, "distinct": true,
+	"exists": true, "interval": true, "over": true, "partition": true, "by": true, "asc": true,
+	"desc": true, "nulls": true, "filter": true, "within": true, "collate": true, "escape": true,
+	"any": true, "all": true, "some": true, "similar": true, "at": true, "zone": true,
+	"current_date": true, "current_time": true, "current_timestamp": true, "current_user": true,
+	"localtime": true, "localtimestamp": true, "unbounded": true, "preceding": true, "following": true,
+	"lateral": true, "with": true, "div": true, "mod": true, "xor": true, "unknown": true,
+	"values": true, "into": true, "straight_join": true, "tablesample": true, "use": true,
+	"force": true, "ignore": true, "only": true,
+}
+
+// datePartWords name the field of EXTRACT(field FROM value)
+var datePartWords = map[string]bool{
+	"year": true, "quarter": true, "month": true, "week": true, "day": true, "hour": true,
+	"minute": true, "second": true, "epoch": true, "dow": true, "doy": true,
+}
+
+// clauseWords end the select list or the FROM clause
+var clauseWords = map[string]bool{
+	"from": true, "into": true, "where": true, "group": true, "having": true, "order": true,
+	"limit": true, "offset": true, "window": true, "fetch": true, "for": true, "qualify": true,
+}
+
+// joinWords separate the tables of a FROM clause
+var joinWords = map[string]bool{
+	"join": true, "inner": true, "left": true, "right": true, "full": true, "outer": true,
+	"cross": true, "natural": true, "straight_join": true,
+}
+
+// lineageQuery is a parsed query: a select, or selects combined with UNION,
+// EXCEPT or INTERSECT, with the CTEs it defines
+type lineageQuery struct {
+	source   []rune
+	parent   *lineageQuery // Query whose CTEs are visible to this one
+	ctes     map[string]*lineageQuery
+	columns  []string // Output names given by a column list, e.g. CREATE VIEW v (a, b)
+	branches []*lineageSelect
+}
+
+// lineageSelect is one SELECT of a query
+type lineageSelect struct {
+	items   []lineageItem
+	sources []lineageSource
+}
+
+// lineageItem is an expression of a select list
+type lineageItem struct {
+	name      string // Output name, empty when the database would invent one
+	star      bool   // SELECT * or SELECT q.*
+	qualifier string // The q of q.*
+	bare      bool   // The expression is a plain column reference
+	tokens    []sqlToken
+}
+
+// lineageSource is a table, view, CTE, subquery or function in a FROM clause
+type lineageSource struct {
+	name     string
+	alias    string
+	query    *lineageQuery // Set for subqueries
+	function bool
+}
+
+// matches reports whether a column qualifier refers to the source
+func (s lineageSource) matches(qualifier string) bool {
+	if s.alias != "" {
+		return strings.EqualFold(s.alias, qualifier)
+	}
+	return strings.EqualFold(s.name, qualifier) || strings.EqualFold(unqualifiedTable(s.name), qualifier)
+}
+
+// cte returns the CTE a name refers to in the query
+func (q *lineageQuery) cte(name string) *lineageQuery {
+	if strings.Contains(name, ".") {
+		return nil
+	}
+	for ; q != nil; q = q.parent {
+		if cte, ok := q.ctes[strings.ToLower(name)]; ok {
+			return cte
+		}
+	}
+	return nil
+}
+
+// text returns the source of tokens with whitespace collapsed
+func (q *lineageQuery) text(tokens []sqlToken) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return whitespacePattern.ReplaceAllString(string(q.source[tokens[0].start:tokens[len(tokens)-1].end]), " ")
+}
+
+// matchParen returns the index of the parenthesis closing the one at open
+func matchParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is("("):
+			depth++
+		case tokens[i].is(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// splitTopLevel splits tokens at the tokens outside parentheses for which
+// separator is true, dropping the separators
+func splitTopLevel(tokens []sqlToken, separator func(sqlToken) bool) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth == 0 && separator(tok):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// topLevelIndex returns the index of the first token outside parentheses for
+// which match is true, or len(tokens)
+func topLevelIndex(tokens []sqlToken, match func(sqlToken) bool) int {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth == 0 && match(tok):
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// unwrap removes parentheses around a whole query and trailing semicolons
+func unwrap(tokens []sqlToken) []sqlToken {
+	for {
+		for len(tokens) > 0 && tokens[len(tokens)-1].is(";") {
+			tokens = tokens[:len(tokens)-1]
+		}
+		if len(tokens) < 2 || !tokens[0].is("(") || matchParen(tokens, 0) != len(tokens)-1 {
+			return tokens
+		}
+		tokens = tokens[1 : len(tokens)-1]
+	}
+}
+
+// qualifiedName reads a possibly qualified name starting at i, returning its
+// parts and the index after it
+func qualifiedName(tokens []sqlToken, i int) ([]string, int) {
+	parts := []string{tokens[i].text}
+	i++
+	for i+1 < len(tokens) && tokens[i].is(".") && (tokens[i+1].kind == tokenWord || tokens[i+1].kind == tokenQuoted) {
+		parts = append(parts, tokens[i+1].text)
+		i += 2
+	}
+	return parts, i
+}
+
+// nameList reads a parenthesized list of names, e.g. the columns of CREATE VIEW v (a, b)
+func nameList(tokens []sqlToken) []string {
+	var names []string
+	for _, tok := range tokens {
+		if tok.kind == tokenWord || tok.kind == tokenQuoted {
+			names = append(names, tok.text)
+		}
+	}
+	return names
+}
+
+// parseViewDefinition parses a view's query, with or without its CREATE VIEW
+func parseViewDefinition(definition string) *lineageQuery {
+	source := []rune(StripComments(definition))
+	tokens := tokenizeSQL(source)
+	var columns []string
+	if len(tokens) > 0 && tokens[0].isWord("CREATE") {
+		i := 0
+		for i < len(tokens) && !tokens[i].isWord("VIEW") {
+			i++
+		}
+		for i < len(tokens) && !tokens[i].isWord("AS") {
+			if tokens[i].is("(") {
+				end := matchParen(tokens, i)
+				columns = nameList(tokens[i+1 : end])
+				i = end
+			}
+			i++
+		}
+		if i < len(tokens) {
+			tokens = tokens[i+1:]
+		}
+	}
+	q := parseQuery(source, tokens, nil)
+	if len(columns) > 0 {
+		q.columns = columns
+	}
+	return q
+}
+
+// parseQuery parses a query and the CTEs it defines
+func parseQuery(source []rune, tokens []sqlToken, parent *lineageQuery) *lineageQuery {
+	q := &lineageQuery{source: source, parent: parent, ctes: make(map[string]*lineageQuery)}
+	tokens = unwrap(tokens)
+
+	if len(tokens) > 0 && tokens[0].isWord("WITH") {
+		i := 1
+		if i < len(tokens) && tokens[i].isWord("RECURSIVE") {
+			i++
+		}
+		for i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenQuoted) {
+			name := tokens[i].text
+			i++
+			var columns []string
+			if i < len(tokens) && tokens[i].is("(") {
+				end := matchParen(tokens, i)
+				columns = nameList(tokens[i+1 : end])
+				i = end + 1
+			}
+			for i < len(tokens) && !tokens[i].is("(") {
+				i++ // AS [NOT] MATERIALIZED
+			}
+			if i >= len(tokens) {
+				break
+			}
+			end := matchParen(tokens, i)
+			cte := parseQuery(source, tokens[i+1:end], q)
+			if len(columns) > 0 {
+				cte.columns = columns
+			}
+			q.ctes[strings.ToLower(name)] = cte
+			i = end + 1
+			if i >= len(tokens) || !tokens[i].is(",") {
+				break
+			}
+			i++
+		}
+		tokens = unwrap(tokens[i:])
+	}
+
+	for _, branch := range splitTopLevel(tokens, func(tok sqlToken) bool {
+		return tok.isWord("UNION") || tok.isWord("EXCEPT") || tok.isWord("INTERSECT") || tok.isWord("MINUS")
+	}) {
+		if len(branch) > 0 && (branch[0].isWord("ALL") || branch[0].isWord("DISTINCT")) {
+			branch = branch[1:]
+		}
+		if sel := q.parseSelect(unwrap(branch)); sel != nil {
+			q.branches = append(q.branches, sel)
+		}
+	}
+	return q
+}
+
+// parseSelect parses the select list and FROM clause of a SELECT
+func (q *lineageQuery) parseSelect(tokens []sqlToken) *lineageSelect {
+	if len(tokens) == 0 || !tokens[0].isWord("SELECT") {
+		return nil
+	}
+	i := 1
+	if i < len(tokens) && tokens[i].isWord("ALL") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].isWord("DISTINCT") {
+		i++
+		if i+1 < len(tokens) && tokens[i].isWord("ON") && tokens[i+1].is("(") {
+			i = matchParen(tokens, i+1) + 1
+		}
+	}
+	rest := tokens[i:]
+	listEnd := topLevelIndex(rest, func(tok sqlToken) bool {
+		return tok.kind == tokenWord && clauseWords[strings.ToLower(tok.text)]
+	})
+
+	sel := &lineageSelect{}
+	for _, item := range splitTopLevel(rest[:listEnd], func(tok sqlToken) bool { return tok.is(",") }) {
+		if len(item) > 0 {
+			sel.items = append(sel.items, parseItem(item))
+		}
+	}
+	if listEnd < len(rest) && rest[listEnd].isWord("FROM") {
+		from := rest[listEnd+1:]
+		from = from[:topLevelIndex(from, func(tok sqlToken) bool {
+			return tok.kind == tokenWord && clauseWords[strings.ToLower(tok.text)] && !tok.isWord("FROM")
+		})]
+		sel.sources = q.parseFrom(from)
+	}
+	return sel
+}
+
+// parseItem parses an expression of a select list and its output name
+func parseItem(tokens []sqlToken) lineageItem {
+	n := len(tokens)
+	if n == 1 && tokens[0].is("*") {
+		return lineageItem{star: true}
+	}
+	if n >= 3 && tokens[n-1].is("*") && tokens[n-2].is(".") {
+		return lineageItem{star: true, qualifier: tokens[n-3].text}
+	}
+
+	item := lineageItem{tokens: tokens}
+	if n >= 2 && tokens[n-1].isName() {
+		prev := tokens[n-2]
+		switch {
+		case prev.isWord("AS"):
+			item.name, item.tokens = tokens[n-1].text, tokens[:n-2]
+		case prev.is(")") || prev.kind == tokenQuoted || prev.kind == tokenString || prev.kind == tokenNumber || prev.isName():
+			item.name, item.tokens = tokens[n-1].text, tokens[:n-1]
+		}
+	}
+
+	expr := item.tokens
+	if len(expr) > 0 && expr[0].isName() {
+		parts, next := qualifiedName(expr, 0)
+		switch {
+		case next == len(expr):
+			item.bare = true
+			if item.name == "" {
+				item.name = parts[len(parts)-1]
+			}
+		case item.name == "" && len(parts) == 1 && expr[next].is("(") && matchParen(expr, next) == len(expr)-1:
+			// PostgreSQL names a lone function call after the function
+			item.name = strings.ToLower(parts[0])
+		}
+	}
+	return item
+}
+
+// parseFrom parses the tables of a FROM clause
+func (q *lineageQuery) parseFrom(tokens []sqlToken) []lineageSource {
+	var sources []lineageSource
+	for _, segment := range splitTopLevel(tokens, func(tok sqlToken) bool {
+		return tok.is(",") || (tok.kind == tokenWord && joinWords[strings.ToLower(tok.text)])
+	}) {
+		segment = segment[:topLevelIndex(segment, func(tok sqlToken) bool {
+			return tok.isWord("ON") || tok.isWord("USING")
+		})]
+		for len(segment) > 0 && (segment[0].isWord("LATERAL") || segment[0].isWord("ONLY")) {
+			segment = segment[1:]
+		}
+		if len(segment) == 0 {
+			continue
+		}
+
+		var source lineageSource
+		i := 0
+		switch {
+		case segment[0].is("("):
+			end := matchParen(segment, 0)
+			inner := unwrap(segment[1:end])
+			if len(inner) > 0 && !inner[0].isWord("SELECT") && !inner[0].isWord("WITH") {
+				// Parenthesized joins
+				sources = append(sources, q.parseFrom(inner)...)
+				continue
+			}
+			source.query = parseQuery(q.source, inner, q)
+			i = end + 1
+		case segment[0].kind == tokenWord || segment[0].kind == tokenQuoted:
+			var parts []string
+			parts, i = qualifiedName(segment, 0)
+			source.name = strings.Join(parts, ".")
+			if i < len(segment) && segment[i].is("(") {
+				source.function = true
+				i = matchParen(segment, i) + 1
+			}
+		default:
+			continue
+		}
+
+		if i < len(segment) && segment[i].isWord("AS") {
+			i++
+		}
+		if i < len(segment) && segment[i].isName() {
+			source.alias = segment[i].text
+			i++
+			if i < len(segment) && segment[i].is("(") && source.query != nil {
+				source.query.columns = nameList(segment[i+1 : matchParen(segment, i)])
+			}
+		}
+		if source.query != nil && source.alias == "" {
+			source.alias = "(subquery)"
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// lineageScope is a SELECT whose sources resolve column references, inside
+// the scopes of the queries it is nested in
+type lineageScope struct {
+	query *lineageQuery
+	sel   *lineageSelect
+	outer *lineageScope
+}
+
+type lineageKey struct {
+	query  *lineageQuery
+	column string
+}
+
+// lineageTracer follows columns through view definitions
+type lineageTracer struct {
+	views        map[string]string
+	parsed       map[string]*lineageQuery
+	tableColumns func(table string) []string
+	tracing      map[lineageKey]bool
+	depth        int
+	warnings     []string
+}
+
+func (t *lineageTracer) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	for _, existing := range t.warnings {
+		if existing == warning {
+			return
+		}
+	}
+	t.warnings = append(t.warnings, warning)
+}
+
+// view returns the parsed definition of a view and its name
+func (t *lineageTracer) view(name string) (*lineageQuery, string, bool) {
+	key := strings.ToLower(unquoteIdentifier(name))
+	definition, ok := t.views[key]
+	if !ok {
+		key = strings.ToLower(unqualifiedTable(key))
+		if definition, ok = t.views[key]; !ok {
+			return nil, "", false
+		}
+	}
+	if q, ok := t.parsed[key]; ok {
+		return q, key, true
+	}
+	q := parseViewDefinition(definition)
+	t.parsed[key] = q
+	return q, key, true
+}
+
+// fill traces the column of a node through the query defining its relation,
+// reporting whether the query has the column
+func (t *lineageTracer) fill(node *LineageNode, q *lineageQuery, outer *lineageScope) bool {
+	key := lineageKey{q, strings.ToLower(node.Column)}
+	if t.tracing[key] {
+		t.warn("%s refers to itself; its recursive part is not followed", node.Relation)
+		return true
+	}
+	if t.depth >= maxLineageDepth {
+		t.warn("stopped following %s.%s after %d levels", node.Relation, node.Column, maxLineageDepth)
+		return true
+	}
+	t.tracing[key] = true
+	t.depth++
+	defer func() {
+		delete(t.tracing, key)
+		t.depth--
+	}()
+
+	sources, expression, found := t.traceQuery(q, node.Column, outer)
+	node.Sources = sources
+	node.Expression = expression
+	return found
+}
+
+// traceQuery returns the sources and, when computed, the expression of a
+// column of a query, combining every branch of a UNION
+func (t *lineageTracer) traceQuery(q *lineageQuery, column string, outer *lineageScope) ([]*LineageNode, string, bool) {
+	position := -1
+	if q.columns != nil {
+		if position = indexFold(q.columns, column); position < 0 {
+			return nil, "", false
+		}
+	}
+
+	var sources []*LineageNode
+	expression, found := "", false
+	for b, sel := range q.branches {
+		scope := &lineageScope{query: q, sel: sel, outer: outer}
+		index := position
+		if index < 0 && b == 0 {
+			index = itemIndex(sel, column)
+		}
+		if index >= 0 && index < len(sel.items) && !hasStarBefore(sel, index) {
+			item := sel.items[index]
+			if !item.star {
+				if b == 0 && position < 0 {
+					position = index
+				}
+				if !item.bare && expression == "" {
+					expression = q.text(item.tokens)
+				}
+				sources = append(sources, t.expressionSources(scope, item.tokens)...)
+				found = true
+				continue
+			}
+		}
+		if node := t.starSource(scope, column); node != nil {
+			sources = append(sources, node)
+			found = true
+		} else if b > 0 && found {
+			t.warn("could not match %s in every branch of a UNION", column)
+		}
+	}
+	return sources, expression, found
+}
+
+// itemIndex returns the position of the select item named column, or -1
+func itemIndex(sel *lineageSelect, column string) int {
+	for i, item := range sel.items {
+		if !item.star && strings.EqualFold(item.name, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasStarBefore(sel *lineageSelect, index int) bool {
+	for _, item := range sel.items[:index] {
+		if item.star {
+			return true
+		}
+	}
+	return false
+}
+
+func indexFold(names []string, name string) int {
+	for i, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// starSource finds the source of a column selected with * or q.*
+func (t *lineageTracer) starSource(scope *lineageScope, column string) *LineageNode {
+	var unknown []lineageSource
+	for _, item := range scope.sel.items {
+		if !item.star {
+			continue
+		}
+		for _, source := range scope.sel.sources {
+			if item.qualifier != "" && !source.matches(item.qualifier) {
+				continue
+			}
+			has, known := t.sourceHas(scope, source, column)
+			if has {
+				return t.sourceColumn(scope, source, column)
+			}
+			if !known {
+				unknown = append(unknown, source)
+			}
+		}
+	}
+	if len(unknown) == 1 {
+		return t.sourceColumn(scope, unknown[0], column)
+	}
+	return nil
+}
+
+// sourceHas reports whether a source has a column, and whether that is known
+func (t *lineageTracer) sourceHas(scope *lineageScope, source lineageSource, column string) (has, known bool) {
+	var q *lineageQuery
+	switch {
+	case source.query != nil:
+		q = source.query
+	case source.function:
+		return false, false
+	case scope.query.cte(source.name) != nil:
+		q = scope.query.cte(source.name)
+	default:
+		if view, _, ok := t.view(source.name); ok {
+			q = view
+		} else if t.tableColumns != nil {
+			if columns := t.tableColumns(source.name); len(columns) > 0 {
+				return indexFold(columns, column) >= 0, true
+			}
+			return false, false
+		} else {
+			return false, false
+		}
+	}
+	if q.columns != nil {
+		return indexFold(q.columns, column) >= 0, true
+	}
+	if len(q.branches) == 0 {
+		return false, false
+	}
+	if itemIndex(q.branches[0], column) >= 0 {
+		return true, true
+	}
+	for _, item := range q.branches[0].items {
+		if item.star {
+			return false, false
+		}
+	}
+	return false, true
+}
+
+// sourceColumn returns the node of a source's column, traced through the
+// source's definition when it is not a table
+func (t *lineageTracer) sourceColumn(scope *lineageScope, source lineageSource, column string) *LineageNode {
+	node := &LineageNode{Relation: source.name, Column: column}
+	var q *lineageQuery
+	switch {
+	case source.query != nil:
+		node.Relation, node.Kind, q = source.alias, "subquery", source.query
+	case source.function:
+		node.Kind = "function"
+		return node
+	case scope.query.cte(source.name) != nil:
+		node.Kind, q = "cte", scope.query.cte(source.name)
+	default:
+		view, name, ok := t.view(source.name)
+		if !ok {
+			node.Kind = "table"
+			return node
+		}
+		node.Relation, node.Kind, q = name, "view", view
+	}
+	if !t.fill(node, q, nil) {
+		t.warn("%s has no column %s", node.Relation, column)
+	}
+	return node
+}
+
+// expressionSources returns the columns an expression reads
+func (t *lineageTracer) expressionSources(scope *lineageScope, tokens []sqlToken) []*LineageNode {
+	var sources []*LineageNode
+	seen := make(map[string]bool)
+	add := func(node *LineageNode) {
+		if node == nil {
+			return
+		}
+		key := strings.ToLower(node.Relation + "." + node.Column)
+		if !seen[key] {
+			seen[key] = true
+			sources = append(sources, node)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.is("(") && i+1 < len(tokens) && (tokens[i+1].isWord("SELECT") || tokens[i+1].isWord("WITH")):
+			end := matchParen(tokens, i)
+			add(t.scalarSubquery(scope, tokens[i+1:end]))
+			i = end
+		case tok.is("::") || tok.isWord("AS"):
+			// Type names of casts
+			for i+1 < len(tokens) && (tokens[i+1].kind == tokenWord || tokens[i+1].kind == tokenQuoted || tokens[i+1].is(".")) {
+				i++
+			}
+		case tok.isWord("NULLS"):
+			i++
+		case tok.isName():
+			parts, next := qualifiedName(tokens, i)
+			switch {
+			case next < len(tokens) && tokens[next].is("("):
+				// Function call
+			case tok.kind == tokenWord && next < len(tokens) && tokens[next].kind == tokenString:
+				// Typed literal such as DATE '2024-01-01'
+			case len(parts) == 1 && datePartWords[strings.ToLower(tok.text)] && next < len(tokens) && tokens[next].isWord("FROM"):
+			default:
+				add(t.resolveColumn(scope, parts))
+			}
+			i = next - 1
+		}
+	}
+	return sources
+}
+
+// scalarSubquery traces the value of a subquery used as an expression
+func (t *lineageTracer) scalarSubquery(scope *lineageScope, tokens []sqlToken) *LineageNode {
+	q := parseQuery(scope.query.source, tokens, scope.query)
+	if len(q.branches) == 0 || len(q.branches[0].items) == 0 {
+		return nil
+	}
+	item := q.branches[0].items[0]
+	node := &LineageNode{Relation: "(subquery)", Kind: "subquery", Column: item.name}
+	if !item.bare {
+		node.Expression = q.text(item.tokens)
+	}
+	node.Sources = t.expressionSources(&lineageScope{query: q, sel: q.branches[0], outer: scope}, item.tokens)
+	return node
+}
+
+// resolveColumn finds the source of a column reference
+func (t *lineageTracer) resolveColumn(scope *lineageScope, parts []string) *LineageNode {
+	column := parts[len(parts)-1]
+	if len(parts) > 1 {
+		qualifier := parts[len(parts)-2]
+		for s := scope; s != nil; s = s.outer {
+			for _, source := range s.sel.sources {
+				if source.matches(qualifier) {
+					return t.sourceColumn(s, source, column)
+				}
+			}
+		}
+		t.warn("cannot tell which table %s.%s refers to", qualifier, column)
+		return nil
+	}
+
+	sources := scope.sel.sources
+	if len(sources) == 1 {
+		return t.sourceColumn(scope, sources[0], column)
+	}
+	var matched, unknown []lineageSource
+	for _, source := range sources {
+		has, known := t.sourceHas(scope, source, column)
+		switch {
+		case has:
+			matched = append(matched, source)
+		case !known:
+			unknown = append(unknown, source)
+		}
+	}
+	switch {
+	case len(matched) == 1:
+		return t.sourceColumn(scope, matched[0], column)
+	case len(matched) == 0 && len(unknown) == 1:
+		return t.sourceColumn(scope, unknown[0], column)
+	case len(sources) == 0 && scope.outer != nil:
+		return t.resolveColumn(scope.outer, parts)
+	case len(sources) == 0:
+		return nil
+	}
+	t.warn("cannot tell which table column %s comes from; qualify it in the view to trace it", column)
+	return nil
+}
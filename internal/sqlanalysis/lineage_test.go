@@ -0,0 +1,114 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shopViews() map[string]string {
+	return ViewDefinitionsFromResult(&models.QueryResult{Rows: [][]interface{}{
+		// SQLite keeps the CREATE statement, with an optional column list
+		{"Order_Lines", `CREATE VIEW order_lines (order_id, amount, sku) AS
+			SELECT i.order_id, i.qty * i.price, p.sku -- line total
+			FROM order_items i JOIN products p ON p.id = i.product_id`},
+		// PostgreSQL returns the query, qualified and terminated
+		{"customer_totals", ` SELECT o.customer_id,
+    sum(l.amount)::numeric(12,2) AS total_spent,
+    count(*) AS orders,
+    (SELECT c.name FROM customers c WHERE c.id = o.customer_id) AS customer_name
+   FROM orders o
+     LEFT JOIN order_lines l ON l.order_id = o.id
+  GROUP BY o.customer_id;`},
+		{"big_spenders", `WITH ranked AS (
+			SELECT t.*, rank() OVER (ORDER BY t.total_spent DESC) AS position FROM customer_totals t
+		)
+		SELECT customer_id, total_spent AS spent, position FROM ranked WHERE position <= 10`},
+		{"all_contacts", `SELECT email, 'customer' AS kind FROM customers
+			UNION ALL SELECT s.contact_email, 'supplier' FROM suppliers s`},
+		{"latest", "select `o`.`id` AS `id`, `shop`.`orders`.`placed_at` AS `placed` from (select * from `shop`.`orders`) `o` join `shop`.`orders`"},
+		{"ambiguous", `SELECT name FROM customers, suppliers`},
+		{"loop", `SELECT x FROM loop`},
+	}})
+}
+
+func TestTraceColumn(t *testing.T) {
+	views := shopViews()
+
+	lineage, err := TraceColumn(views, "public.big_spenders", "SPENT", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "big_spenders", lineage.View)
+	assert.Equal(t, []string{"order_items.price", "order_items.qty"}, lineage.BaseColumns)
+	assert.Equal(t, `big_spenders.SPENT (view)
+  ranked.total_spent (cte)
+    customer_totals.total_spent (view) = sum(l.amount)::numeric(12,2)
+      order_lines.amount (view) = i.qty * i.price
+        order_items.qty (table)
+        order_items.price (table)`, lineage.Tree)
+	assert.Empty(t, lineage.Warnings)
+
+	// Scalar subqueries, and columns with no source column at all
+	lineage, err = TraceColumn(views, "customer_totals", "customer_name", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers.name"}, lineage.BaseColumns)
+	lineage, err = TraceColumn(views, "customer_totals", "orders", nil)
+	require.NoError(t, err)
+	assert.Empty(t, lineage.BaseColumns)
+	assert.Equal(t, "count(*)", lineage.Lineage.Expression)
+
+	// Every branch of a UNION, matched by position
+	lineage, err = TraceColumn(views, "all_contacts", "email", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers.email", "suppliers.contact_email"}, lineage.BaseColumns)
+
+	// MySQL's quoted, schema-qualified definitions and SELECT * subqueries
+	lineage, err = TraceColumn(views, "latest", "id", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop.orders.id"}, lineage.BaseColumns)
+	lineage, err = TraceColumn(views, "latest", "placed", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop.orders.placed_at"}, lineage.BaseColumns)
+}
+
+func TestTraceColumn_Unresolved(t *testing.T) {
+	views := shopViews()
+
+	lineage, err := TraceColumn(views, "ambiguous", "name", nil)
+	require.NoError(t, err)
+	assert.Empty(t, lineage.BaseColumns)
+	assert.Contains(t, lineage.Warnings[0], "cannot tell which table column name comes from")
+
+	// The table columns settle it
+	lineage, err = TraceColumn(views, "ambiguous", "name", func(table string) []string {
+		if table == "customers" {
+			return []string{"id", "name"}
+		}
+		return []string{"id", "contact_email"}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers.name"}, lineage.BaseColumns)
+
+	lineage, err = TraceColumn(views, "loop", "x", nil)
+	require.NoError(t, err)
+	assert.Contains(t, lineage.Warnings[0], "refers to itself")
+
+	_, err = TraceColumn(views, "customer_totals", "missing", nil)
+	assert.EqualError(t, err, "view customer_totals has no column missing")
+	_, err = TraceColumn(views, "orders", "id", nil)
+	assert.ErrorContains(t, err, "orders is not a view; views: all_contacts, ambiguous")
+	_, err = TraceColumn(map[string]string{}, "orders", "id", nil)
+	assert.EqualError(t, err, "orders is not a view: the database has no views")
+}
+
+func TestBuildViewDefinitionsQuery(t *testing.T) {
+	for _, dialect := range []string{"postgresql", "mysql", "sqlite"} {
+		query, err := BuildViewDefinitionsQuery(dialect)
+		require.NoError(t, err)
+		assert.NotEmpty(t, query)
+	}
+	_, err := BuildViewDefinitionsQuery("oracle")
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+package sqlanalysis
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity levels for lint findings, ordered from most to least important
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Finding represents a single issue detected in a SQL statement
+type Finding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Source     string `json:"source"` // "linter" or "optimizer"
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// lintRule is a single pattern-based check
+type lintRule struct {
+	name       string
+	severity   string
+	source     string
+	dialects   []string // Empty means all dialects
+	match      func(normalized string) bool
+	message    string
+	suggestion string
+}
+
+var (
+	stringLiteralPattern  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	selectStarPattern     = regexp.MustCompile(`(?i)\bSELECT\s+(DISTINCT\s+)?\*`)
+	deleteNoWherePattern  = regexp.MustCompile(`(?i)^\s*DELETE\s+FROM\s+[^\s;]+\s*;?\s*$`)
+	updateNoWherePattern  = regexp.MustCompile(`(?i)^\s*UPDATE\s+`)
+	wherePattern          = regexp.MustCompile(`(?i)\bWHERE\b`)
+	leadingWildcardLike   = regexp.MustCompile(`(?i)\bI?LIKE\s+'%`)
+	orderByRandomPattern  = regexp.MustCompile(`(?i)\bORDER\s+BY\s+(RAND|RANDOM|NEWID)\s*\(\s*\)`)
+	notInSubqueryPattern  = regexp.MustCompile(`(?i)\bNOT\s+IN\s*\(\s*SELECT\b`)
+	functionOnColumn      = regexp.MustCompile(`(?i)\bWHERE\b.*\b(LOWER|UPPER|DATE|YEAR|MONTH|TRIM|CAST|COALESCE|SUBSTR|SUBSTRING)\s*\(\s*[a-z_][a-z0-9_.]*\s*[,)]`)
+	implicitJoinPattern   = regexp.MustCompile(`(?i)\bFROM\s+[a-z_][a-z0-9_.]*(\s+(AS\s+)?[a-z_][a-z0-9_]*)?\s*,\s*[a-z_]`)
+	largeOffsetPattern    = regexp.MustCompile(`(?i)\bOFFSET\s+\d{4,}`)
+	unionPattern          = regexp.MustCompile(`(?i)\bUNION\s+(SELECT|\()`)
+	orInWherePattern      = regexp.MustCompile(`(?i)\bWHERE\b.*\bOR\b`)
+	countDistinctPattern  = regexp.MustCompile(`(?i)\bCOUNT\s*\(\s*DISTINCT\b`)
+	limitPattern          = regexp.MustCompile(`(?i)\bLIMIT\b`)
+	selectPattern         = regexp.MustCompile(`(?i)^\s*(SELECT|WITH)\b`)
+	aggregateOrKeyPattern = regexp.MustCompile(`(?i)\b(COUNT|SUM|AVG|MIN|MAX)\s*\(|\bGROUP\s+BY\b`)
+	mysqlSQLCalcFoundRows = regexp.MustCompile(`(?i)\bSQL_CALC_FOUND_ROWS\b`)
+	sqliteGlobPattern     = regexp.MustCompile(`(?i)\bGLOB\s+'\*`)
+)
+
+var lintRules = []lintRule{
+	{
+		name:       "update-without-where",
+		severity:   SeverityHigh,
+		source:     "linter",
+		match:      func(s string) bool { return updateNoWherePattern.MatchString(s) && !wherePattern.MatchString(s) },
+		message:    "UPDATE without WHERE modifies every row in the table",
+		suggestion: "Add a WHERE clause, or confirm a full-table update is intended",
+	},
+	{
+		name:       "delete-without-where",
+		severity:   SeverityHigh,
+		source:     "linter",
+		match:      deleteNoWherePattern.MatchString,
+		message:    "DELETE without WHERE removes every row in the table",
+		suggestion: "Add a WHERE clause, or use TRUNCATE if clearing the table is intended",
+	},
+	{
+		name:       "comparison-with-null",
+		severity:   SeverityHigh,
+		source:     "linter",
+		match:      comparesWithNull,
+		message:    "Comparing with NULL using =, != or <> never evaluates to true",
+		suggestion: "Use IS NULL or IS NOT NULL",
+	},
+	{
+		name:       "not-in-subquery",
+		severity:   SeverityMedium,
+		source:     "linter",
+		match:      notInSubqueryPattern.MatchString,
+		message:    "NOT IN (SELECT ...) returns no rows if the subquery yields any NULL",
+		suggestion: "Use NOT EXISTS, which handles NULLs correctly and usually plans better",
+	},
+	{
+		name:       "leading-wildcard-like",
+		severity:   SeverityMedium,
+		source:     "optimizer",
+		match:      leadingWildcardLike.MatchString,
+		message:    "LIKE pattern with a leading wildcard cannot use a B-tree index",
+		suggestion: "Use a trailing wildcard, or a trigram/full-text index for substring search (ask dbsage to set up full-text search)",
+	},
+	{
+		name:       "function-on-column",
+		severity:   SeverityMedium,
+		source:     "optimizer",
+		match:      functionOnColumn.MatchString,
+		message:    "Wrapping a column in a function in WHERE prevents index usage",
+		suggestion: "Compare the raw column against a transformed value, or add an expression index",
+	},
+	{
+		name:       "order-by-random",
+		severity:   SeverityMedium,
+		source:     "optimizer",
+		match:      orderByRandomPattern.MatchString,
+		message:    "ORDER BY RANDOM() sorts the entire result set",
+		suggestion: "Sample with TABLESAMPLE or pick random keys in application code",
+	},
+	{
+		name:       "select-star",
+		severity:   SeverityMedium,
+		source:     "linter",
+		match:      selectStarPattern.MatchString,
+		message:    "SELECT * fetches every column and breaks when the schema changes",
+		suggestion: "List only the columns you need",
+	},
+	{
+		name:       "large-offset",
+		severity:   SeverityLow,
+		source:     "optimizer",
+		match:      largeOffsetPattern.MatchString,
+		message:    "Large OFFSET values scan and discard all skipped rows",
+		suggestion: "Use keyset pagination (WHERE id > last_seen_id ORDER BY id LIMIT n)",
+	},
+	{
+		name:       "implicit-join",
+		severity:   SeverityLow,
+		source:     "linter",
+		match:      implicitJoinPattern.MatchString,
+		message:    "Comma-separated FROM list is an implicit join and easy to turn into a cross join",
+		suggestion: "Use explicit JOIN ... ON syntax",
+	},
+	{
+		name:       "union-without-all",
+		severity:   SeverityLow,
+		source:     "optimizer",
+		match:      unionPattern.MatchString,
+		message:    "UNION removes duplicates, which requires an extra sort or hash step",
+		suggestion: "Use UNION ALL when duplicates are impossible or acceptable",
+	},
+	{
+		name:       "or-in-where",
+		severity:   SeverityLow,
+		source:     "optimizer",
+		match:      orInWherePattern.MatchString,
+		message:    "OR conditions across different columns often prevent index usage",
+		suggestion: "Consider rewriting as UNION ALL of indexed predicates or using IN for a single column",
+	},
+	{
+		name:     "unbounded-select",
+		severity: SeverityLow,
+		source:   "linter",
+		match: func(s string) bool {
+			return selectPattern.MatchString(s) && !limitPattern.MatchString(s) && !aggregateOrKeyPattern.MatchString(s) && !wherePattern.MatchString(s)
+		},
+		message:    "SELECT without WHERE or LIMIT returns the whole table",
+		suggestion: "Add a LIMIT while exploring data",
+	},
+	{
+		name:       "count-distinct",
+		severity:   SeverityLow,
+		source:     "optimizer",
+		dialects:   []string{"postgresql"},
+		match:      countDistinctPattern.MatchString,
+		message:    "COUNT(DISTINCT ...) cannot use parallel aggregation in PostgreSQL",
+		suggestion: "Use a subquery with GROUP BY, or an approximate count if exactness is not required",
+	},
+	{
+		name:       "sql-calc-found-rows",
+		severity:   SeverityMedium,
+		source:     "optimizer",
+		dialects:   []string{"mysql"},
+		match:      mysqlSQLCalcFoundRows.MatchString,
+		message:    "SQL_CALC_FOUND_ROWS is deprecated and forces a full scan",
+		suggestion: "Run a separate COUNT(*) query instead",
+	},
+	{
+		name:       "glob-leading-wildcard",
+		severity:   SeverityMedium,
+		source:     "optimizer",
+		dialects:   []string{"sqlite"},
+		match:      sqliteGlobPattern.MatchString,
+		message:    "GLOB pattern with a leading wildcard cannot use an index",
+		suggestion: "Use an FTS5 virtual table for substring search (ask dbsage to set up full-text search)",
+	},
+}
+
+// conditionWords start a condition, where comparing with NULL is a mistake;
+// conditionEnds start clauses such as SET, where = NULL assigns
+var (
+	conditionWords = map[string]bool{"where": true, "on": true, "having": true, "when": true}
+	conditionEnds  = map[string]bool{
+		"set": true, "select": true, "from": true, "values": true, "group": true, "order": true,
+		"limit": true, "returning": true, "then": true, "else": true, "end": true, "into": true,
+		"union": true, "except": true, "intersect": true, "window": true,
+	}
+)
+
+// comparesWithNull reports whether a WHERE, ON, HAVING or WHEN condition
+// compares with NULL using =, != or <>. UPDATE ... SET col = NULL assigns and
+// is not flagged, nor is MySQL's null-safe <=>.
+func comparesWithNull(s string) bool {
+	tokens := tokenizeSQL([]rune(s))
+	condition := map[int]bool{} // By parenthesis depth
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("("):
+			condition[depth+1] = condition[depth]
+			depth++
+		case tok.is(")"):
+			delete(condition, depth)
+			depth--
+		case tok.kind == tokenWord && conditionWords[strings.ToLower(tok.text)]:
+			condition[depth] = true
+		case tok.kind == tokenWord && conditionEnds[strings.ToLower(tok.text)]:
+			condition[depth] = false
+		case condition[depth] && i+1 < len(tokens) && tokens[i+1].isWord("NULL"):
+			if tok.is("=") || (tok.is(">") && i > 0 && tokens[i-1].is("<")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LintSQL runs local lint and dialect optimizer checks against a SQL statement.
+// Findings are returned ordered by severity (high first).
+func LintSQL(query, dialect string) []Finding {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	// Literals are blanked so that their contents don't trigger rules,
+	// except for LIKE/GLOB patterns which need the leading character.
+	normalized := stringLiteralPattern.ReplaceAllStringFunc(query, func(lit string) string {
+		if strings.HasPrefix(lit, "'%") {
+			return "'%'"
+		}
+		if strings.HasPrefix(lit, "'*") {
+			return "'*'"
+		}
+		return "''"
+	})
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	dialect = strings.ToLower(dialect)
+
+	var findings []Finding
+	for _, rule := range lintRules {
+		if len(rule.dialects) > 0 && !containsString(rule.dialects, dialect) {
+			continue
+		}
+		if rule.match(normalized) {
+			findings = append(findings, Finding{
+				Rule:       rule.name,
+				Severity:   rule.severity,
+				Source:     rule.source,
+				Message:    rule.message,
+				Suggestion: rule.suggestion,
+			})
+		}
+	}
+
+	SortFindings(findings)
+	return findings
+}
+
+// SortFindings orders findings by severity, keeping the rule order within a severity
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return SeverityRank(findings[i].Severity) < SeverityRank(findings[j].Severity)
+	})
+}
+
+// SeverityRank returns a sortable rank for a severity (lower is more important)
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityHigh:
+		return 0
+	case SeverityMedium:
+		return 1
+	case SeverityLow:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ruleNames(findings []Finding) []string {
+	var names []string
+	for _, f := range findings {
+		names = append(names, f.Rule)
+	}
+	return names
+}
+
+func TestLintSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		dialect  string
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "select star without limit",
+			query:    "SELECT * FROM users",
+			expected: []string{"select-star", "unbounded-select"},
+		},
+		{
+			name:     "update without where",
+			query:    "UPDATE users SET active = false",
+			expected: []string{"update-without-where"},
+		},
+		{
+			name:   "update with where",
+			query:  "UPDATE users SET active = false WHERE id = 1",
+			absent: []string{"update-without-where"},
+		},
+		{
+			name:     "delete without where",
+			query:    "DELETE FROM sessions;",
+			expected: []string{"delete-without-where"},
+		},
+		{
+			name:     "comparison with null",
+			query:    "SELECT id FROM users WHERE deleted_at = NULL",
+			expected: []string{"comparison-with-null"},
+		},
+		{
+			name:     "comparison with null in a join and having",
+			query:    "SELECT a.id FROM a JOIN b ON (b.a_id <> NULL) GROUP BY a.id HAVING max(b.x) != NULL",
+			expected: []string{"comparison-with-null"},
+		},
+		{
+			name:   "assigning null is not a comparison",
+			query:  "UPDATE users SET deleted_at = NULL, note = NULL WHERE id = 1",
+			absent: []string{"comparison-with-null"},
+		},
+		{
+			name:   "null in select list, values and null-safe equality",
+			query:  "INSERT INTO t (a) SELECT CASE WHEN x IS NULL THEN NULL END FROM s WHERE y <=> NULL",
+			absent: []string{"comparison-with-null"},
+		},
+		{
+			name:     "comparison with null after an assignment",
+			query:    "UPDATE users SET deleted_at = NULL WHERE deleted_by = NULL",
+			expected: []string{"comparison-with-null"},
+		},
+		{
+			name:     "leading wildcard and function on column",
+			query:    "SELECT id FROM users WHERE LOWER(email) LIKE '%@example.com'",
+			expected: []string{"leading-wildcard-like", "function-on-column"},
+		},
+		{
+			name:   "literals do not trigger rules",
+			query:  "SELECT id FROM notes WHERE body = 'UPDATE x SET y = NULL OR z'",
+			absent: []string{"comparison-with-null", "or-in-where", "update-without-where"},
+		},
+		{
+			name:     "dialect specific rule",
+			query:    "SELECT COUNT(DISTINCT user_id) FROM events",
+			dialect:  "postgresql",
+			expected: []string{"count-distinct"},
+		},
+		{
+			name:    "dialect specific rule skipped for other dialects",
+			query:   "SELECT COUNT(DISTINCT user_id) FROM events",
+			dialect: "mysql",
+			absent:  []string{"count-distinct"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := ruleNames(LintSQL(tt.query, tt.dialect))
+			for _, rule := range tt.expected {
+				assert.Contains(t, names, rule)
+			}
+			for _, rule := range tt.absent {
+				assert.NotContains(t, names, rule)
+			}
+		})
+	}
+}
+
+func TestLintSQL_OrderedBySeverity(t *testing.T) {
+	findings := LintSQL("SELECT * FROM users WHERE email = NULL OR name LIKE '%x'", "")
+	assert.NotEmpty(t, findings)
+	for i := 1; i < len(findings); i++ {
+		assert.LessOrEqual(t, SeverityRank(findings[i-1].Severity), SeverityRank(findings[i].Severity))
+	}
+	assert.Equal(t, SeverityHigh, findings[0].Severity)
+}
+
+func TestLintSQL_Empty(t *testing.T) {
+	assert.Nil(t, LintSQL("   ", "postgresql"))
+}
@@ -0,0 +1,180 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaskedSchema tells what masking hides
+type MaskedSchema struct {
+	Column func(name string) bool  // Whether a column is masked by its name
+	Table  func(table string) bool // Whether a table has masked columns
+}
+
+// serverFileFunctions read files and directories of the database server
+var serverFileFunctions = map[string]bool{
+	"pg_read_file": true, "pg_read_binary_file": true, "pg_ls_dir": true, "pg_stat_file": true,
+	"lo_import": true, "lo_export": true, "load_file": true,
+}
+
+// maskClauseEnds end a select list, join or ORDER BY: columns after them are
+// compared or computed
+var maskClauseEnds = map[string]bool{
+	"where": true, "having": true, "on": true, "using": true, "values": true, "returning": true,
+	"limit": true, "offset": true, "window": true, "qualify": true, "fetch": true, "for": true,
+	"group": true, "order": true, "union": true, "except": true, "intersect": true, "minus": true,
+}
+
+// CheckMaskedAccess returns the problems that let a statement get past the
+// masking of results: masked columns used other than by selecting, sorting
+// or grouping them under their own name or comparing them with a literal,
+// whole rows of tables with masked
+// columns, server files read with pg_read_file and the like, and COPY ...
+// PROGRAM. Results are masked by column name, so a masked column that is
+// transformed, aliased or compared would reach the AI unmasked.
+func CheckMaskedAccess(sql string, schema MaskedSchema) []string {
+	var problems []string
+	seen := make(map[string]bool)
+	for _, backslash := range []bool{false, true} {
+		for _, stmt := range splitStatements(sql, backslash) {
+			for _, problem := range maskedAccess(tokenize([]rune(stripComments(stmt, backslash)), backslash), TableReferences(stmt), schema) {
+				if !seen[problem] {
+					seen[problem] = true
+					problems = append(problems, problem)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// maskedAccess returns the problems of one statement using tables
+func maskedAccess(tokens []sqlToken, tables []TableReference, schema MaskedSchema) []string {
+	rows := make(map[string]string) // Table by the names its rows go by
+	for _, ref := range tables {
+		rows[strings.ToLower(unqualifiedTable(ref.Table))] = ref.Table
+		if ref.Alias != "" {
+			rows[strings.ToLower(ref.Alias)] = ref.Table
+		}
+	}
+
+	var problems []string
+	if len(tokens) > 0 && tokens[0].isWord("COPY") && topLevelIndex(tokens, func(tok sqlToken) bool { return tok.isWord("PROGRAM") }) < len(tokens) {
+		problems = append(problems, "COPY ... PROGRAM runs a command on the database server")
+	}
+
+	// clause is the clause each parenthesis depth is in: a bare column is
+	// allowed in "select", "by" and as a "set" target, and "from" names tables
+	// and CTEs. Only a query's own result is masked, so a select list counts
+	// only in reads and in subqueries that are tables of the query: a scalar
+	// subquery or INSERT ... SELECT would carry the values under another name.
+	reads := len(tokens) > 0 && tokens[0].kind == tokenWord && readVerbs[strings.ToLower(tokens[0].text)]
+	clause := map[int]string{}
+	depth := 0
+	for i, tok := range tokens {
+		lower := strings.ToLower(tok.text)
+		switch {
+		case tok.is("("):
+			depth++
+			continue
+		case tok.is(")"):
+			delete(clause, depth)
+			depth--
+			continue
+		case tok.kind != tokenWord && tok.kind != tokenQuoted:
+			continue
+		case tok.kind == tokenWord && serverFileFunctions[lower] && i+1 < len(tokens) && tokens[i+1].is("("):
+			problems = append(problems, fmt.Sprintf("%s reads files of the database server", lower))
+			continue
+		case tok.isWord("SELECT"):
+			clause[depth] = "select"
+			continue
+		case tok.isWord("BY") && i > 0 && (tokens[i-1].isWord("ORDER") || tokens[i-1].isWord("GROUP")):
+			clause[depth] = "by"
+			continue
+		case tok.isWord("SET"):
+			clause[depth] = "set"
+			continue
+		case tok.kind == tokenWord && (lower == "from" || lower == "with" || lower == "update" || lower == "into" || lower == "table" || joinWords[lower]):
+			clause[depth] = "from"
+			continue
+		case tok.kind == tokenWord && maskClauseEnds[lower]:
+			clause[depth] = ""
+			continue
+		case tok.kind == tokenWord && lineageKeywords[lower]:
+			continue
+		case clause[depth] == "from":
+			continue
+		case i+1 < len(tokens) && tokens[i+1].is("."):
+			continue // A table or alias qualifying a column
+		case i > 0 && tokens[i-1].isWord("AS"):
+			continue // An alias: its values are masked by its name
+		}
+
+		current := clause[depth]
+		if current != "set" {
+			for d := 0; d < depth; d++ {
+				if clause[d] != "from" {
+					current = ""
+				}
+			}
+			if !reads {
+				current = ""
+			}
+		}
+		masked := schema.Column(tok.text)
+		switch {
+		case masked && !bareColumn(tokens, i, current):
+			problems = append(problems, fmt.Sprintf("%s is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal", tok.text))
+		case !masked && rows[lower] != "" && !(i > 1 && tokens[i-1].is(".")) && schema.Table(rows[lower]):
+			problems = append(problems, fmt.Sprintf("%s is a whole row of a table with masked columns; select its columns instead", tok.text))
+		}
+	}
+	return problems
+}
+
+// bareColumn reports whether the column at tokens[i] stands alone as an item
+// of its clause, optionally qualified and aliased to its own name, or is
+// compared with a literal
+func bareColumn(tokens []sqlToken, i int, clause string) bool {
+	start := i
+	for start >= 2 && tokens[start-1].is(".") {
+		start -= 2
+	}
+	if start == 0 {
+		return false
+	}
+	prev := tokens[start-1]
+	next := i + 1
+	switch clause {
+	case "select":
+		if !prev.is(",") && !prev.isWord("SELECT") && !prev.isWord("DISTINCT") && !prev.isWord("ALL") {
+			return false
+		}
+		if next+1 < len(tokens) && tokens[next].isWord("AS") && strings.EqualFold(tokens[next+1].text, tokens[i].text) {
+			next += 2
+		}
+	case "by":
+		if !prev.is(",") && !prev.isWord("BY") {
+			return false
+		}
+	case "set":
+		// The column a statement assigns, UPDATE t SET email = ...
+		return (prev.is(",") || prev.isWord("SET")) && next < len(tokens) && tokens[next].is("=")
+	default:
+		// Comparing with one literal or testing for null answers a single
+		// guess, WHERE email = 'a@example.com', unlike LIKE or a function
+		if next+1 < len(tokens) && tokens[next].is("=") {
+			value := tokens[next+1]
+			return (value.kind == tokenString || value.kind == tokenNumber) && (next+2 == len(tokens) || tokens[next+2].kind == tokenWord || tokens[next+2].is(")") || tokens[next+2].is(";"))
+		}
+		return next+1 < len(tokens) && tokens[next].isWord("IS") && (tokens[next+1].isWord("NULL") || tokens[next+1].isWord("NOT"))
+	}
+	if next == len(tokens) {
+		return true
+	}
+	after := tokens[next]
+	word := strings.ToLower(after.text)
+	return after.is(",") || after.is(")") || after.is(";") ||
+		(after.kind == tokenWord && (clauseWords[word] || maskClauseEnds[word] || word == "asc" || word == "desc" || word == "nulls"))
+}
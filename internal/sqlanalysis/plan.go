@@ -0,0 +1,352 @@
+package sqlanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// Plan warning kinds
+const (
+	WarningSeqScan    = "seq_scan"
+	WarningNestedLoop = "nested_loop"
+	WarningTempSort   = "temp_sort"
+)
+
+// PlanWarning describes a potential problem found in an execution plan
+type PlanWarning struct {
+	Kind     string  `json:"kind"`
+	Relation string  `json:"relation,omitempty"`
+	Rows     float64 `json:"rows,omitempty"`
+	Message  string  `json:"message"`
+}
+
+// PlanSummary is a dialect-independent digest of an execution plan
+type PlanSummary struct {
+	TotalCost     float64       `json:"total_cost"`
+	EstimatedRows float64       `json:"estimated_rows"`
+	ExecutionTime float64       `json:"execution_time_ms,omitempty"` // Set by EXPLAIN ANALYZE
+	Warnings      []PlanWarning `json:"warnings"`
+}
+
+var explainablePattern = regexp.MustCompile(`(?i)^\s*(SELECT|WITH|INSERT|UPDATE|DELETE|REPLACE|VALUES|TABLE)\b`)
+
+// IsExplainable reports whether a statement can be passed to EXPLAIN
+func IsExplainable(query string) bool {
+	return explainablePattern.MatchString(StripComments(query))
+}
+
+// CheckExplainable returns why a query cannot be passed to EXPLAIN. Only a
+// single statement is accepted: drivers send a query without arguments as a
+// simple query, which runs every statement after the explained one. The
+// statements are counted with and without backslash escapes, so one hidden
+// behind an escaped quote in an E-string is still found.
+func CheckExplainable(query string) error {
+	if n := CountStatements(query); n > 1 {
+		return fmt.Errorf("only one statement can be explained at a time, got %d", n)
+	}
+	if !IsExplainable(query) {
+		return fmt.Errorf("statement type cannot be explained")
+	}
+	return nil
+}
+
+// BuildExplainStatement builds an EXPLAIN statement that only plans the query
+// without executing it, in a format understood by AnalyzePlan
+func BuildExplainStatement(dialect, query string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	switch strings.ToLower(dialect) {
+	case "mysql":
+		return "EXPLAIN FORMAT=JSON " + query
+	case "sqlite":
+		return "EXPLAIN QUERY PLAN " + query
+	default:
+		return "EXPLAIN (FORMAT JSON) " + query
+	}
+}
+
+// BuildExplainAnalyzeStatement builds an EXPLAIN ANALYZE statement, which
+// runs the query to report actual rows and times, in a format understood by
+// ParsePlan. SQLite cannot analyze a query, so its plan is only estimated.
+func BuildExplainAnalyzeStatement(dialect, query string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	switch strings.ToLower(dialect) {
+	case "mysql":
+		return "EXPLAIN ANALYZE " + query
+	case "sqlite":
+		return "EXPLAIN QUERY PLAN " + query
+	default:
+		return "EXPLAIN (ANALYZE, FORMAT JSON) " + query
+	}
+}
+
+// AnalyzePlan parses an EXPLAIN result for the given dialect and extracts
+// warnings using the default thresholds
+func AnalyzePlan(dialect string, result *models.QueryResult) (*PlanSummary, error) {
+	return AnalyzePlanWithThresholds(dialect, result, DefaultThresholds())
+}
+
+// AnalyzePlanWithThresholds parses an EXPLAIN result for the given dialect and
+// extracts warnings using the given thresholds
+func AnalyzePlanWithThresholds(dialect string, result *models.QueryResult, t Thresholds) (*PlanSummary, error) {
+	if result == nil || len(result.Rows) == 0 {
+		return nil, fmt.Errorf("empty explain result")
+	}
+
+	switch strings.ToLower(dialect) {
+	case "sqlite":
+		return analyzeSQLitePlan(result), nil
+	case "mysql":
+		return analyzeMySQLPlan(planJSON(result), t)
+	default:
+		return analyzePostgreSQLPlan(planJSON(result), t)
+	}
+}
+
+// planJSON extracts the JSON document from a single-cell EXPLAIN result
+func planJSON(result *models.QueryResult) string {
+	if len(result.Rows[0]) == 0 {
+		return ""
+	}
+	switch v := result.Rows[0][0].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func analyzePostgreSQLPlan(raw string, t Thresholds) (*PlanSummary, error) {
+	var plans []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		ExecutionTime float64                `json:"Execution Time"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL plan: %w", err)
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("PostgreSQL plan is empty")
+	}
+
+	root := plans[0].Plan
+	summary := &PlanSummary{
+		TotalCost:     toFloat(root["Total Cost"]),
+		EstimatedRows: toFloat(root["Plan Rows"]),
+		ExecutionTime: plans[0].ExecutionTime,
+	}
+	walkPostgreSQLNode(root, summary, t)
+	return summary, nil
+}
+
+func walkPostgreSQLNode(node map[string]interface{}, summary *PlanSummary, t Thresholds) {
+	nodeType, _ := node["Node Type"].(string)
+	relation, _ := node["Relation Name"].(string)
+	rows := toFloat(node["Plan Rows"])
+	children, _ := node["Plans"].([]interface{})
+
+	switch nodeType {
+	case "Seq Scan":
+		if rows >= t.LargeTableRows {
+			message := fmt.Sprintf("Sequential scan on %s (~%.0f rows)", relation, rows)
+			if filter, _ := node["Filter"].(string); IsSpatialFilter(filter) {
+				message += "; the filter is spatial, so index the geometry column with GIST (a B-tree index cannot serve it)"
+			}
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:     WarningSeqScan,
+				Relation: relation,
+				Rows:     rows,
+				Message:  message,
+			})
+		}
+	case "Nested Loop":
+		if len(children) == 2 {
+			outer, _ := children[0].(map[string]interface{})
+			inner, _ := children[1].(map[string]interface{})
+			product := toFloat(outer["Plan Rows"]) * toFloat(inner["Plan Rows"])
+			if product >= t.NestedLoopRows {
+				summary.Warnings = append(summary.Warnings, PlanWarning{
+					Kind:    WarningNestedLoop,
+					Rows:    product,
+					Message: fmt.Sprintf("Nested loop over ~%.0f row combinations", product),
+				})
+			}
+		}
+	case "Sort":
+		method, _ := node["Sort Method"].(string)
+		spaceType, _ := node["Sort Space Type"].(string)
+		switch {
+		case spaceType == "Disk":
+			// EXPLAIN ANALYZE reports the spilled size in kB
+			spill := int64(toFloat(node["Sort Space Used"])) * 1024
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:    WarningTempSort,
+				Rows:    rows,
+				Message: fmt.Sprintf("Sort spills %s to disk (%s). %s", FormatBytes(spill), method, WorkMemRecommendation(spill)),
+			})
+		case method == "" && rows*toFloat(node["Plan Width"]) > DefaultWorkMemBytes:
+			// A plain EXPLAIN only estimates the rows and their width; sorting
+			// more than the default work_mem of them spills unless it was raised
+			size := int64(rows * toFloat(node["Plan Width"]))
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:    WarningTempSort,
+				Rows:    rows,
+				Message: fmt.Sprintf("Sort of ~%.0f rows (~%s) likely spills to disk with the default work_mem. %s", rows, FormatBytes(size), workMemAdvice(size*2, "sort")),
+			})
+		}
+	case "Hash":
+		if batches := toFloat(node["Hash Batches"]); batches > 1 {
+			// Each batch holds about the peak memory, so all of them together need batches times that
+			memory := int64(toFloat(node["Peak Memory Usage"])) * 1024 * int64(batches)
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:    WarningTempSort,
+				Rows:    rows,
+				Message: fmt.Sprintf("Hash spills to disk in %.0f batches. %s", batches, workMemAdvice(memory, "hash table")),
+			})
+		} else if _, analyzed := node["Hash Batches"]; !analyzed && rows*toFloat(node["Plan Width"]) > 2*DefaultWorkMemBytes {
+			// Estimated only: a hash table may use twice work_mem (hash_mem_multiplier)
+			size := int64(rows * toFloat(node["Plan Width"]))
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:    WarningTempSort,
+				Rows:    rows,
+				Message: fmt.Sprintf("Hash of ~%.0f rows (~%s) likely spills to disk with the default work_mem. %s", rows, FormatBytes(size), workMemAdvice(size, "hash table")),
+			})
+		}
+	}
+
+	for _, child := range children {
+		if childNode, ok := child.(map[string]interface{}); ok {
+			walkPostgreSQLNode(childNode, summary, t)
+		}
+	}
+}
+
+func analyzeMySQLPlan(raw string, t Thresholds) (*PlanSummary, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse MySQL plan: %w", err)
+	}
+	queryBlock, _ := doc["query_block"].(map[string]interface{})
+	if queryBlock == nil {
+		return nil, fmt.Errorf("MySQL plan has no query_block")
+	}
+
+	summary := &PlanSummary{}
+	if costInfo, ok := queryBlock["cost_info"].(map[string]interface{}); ok {
+		summary.TotalCost = toFloat(costInfo["query_cost"])
+	}
+	walkMySQLNode(queryBlock, summary, t)
+	return summary, nil
+}
+
+func walkMySQLNode(node interface{}, summary *PlanSummary, t Thresholds) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if table, ok := n["table"].(map[string]interface{}); ok {
+			name, _ := table["table_name"].(string)
+			rows := toFloat(table["rows_examined_per_scan"])
+			if summary.EstimatedRows < rows {
+				summary.EstimatedRows = rows
+			}
+			if access, _ := table["access_type"].(string); access == "ALL" && rows >= t.LargeTableRows {
+				summary.Warnings = append(summary.Warnings, PlanWarning{
+					Kind:     WarningSeqScan,
+					Relation: name,
+					Rows:     rows,
+					Message:  fmt.Sprintf("Full table scan on %s (~%.0f rows)", name, rows),
+				})
+			}
+			if usingTemp, _ := table["using_filesort"].(bool); usingTemp {
+				summary.Warnings = append(summary.Warnings, PlanWarning{
+					Kind:     WarningTempSort,
+					Relation: name,
+					Message:  fmt.Sprintf("Filesort required for %s", name),
+				})
+			}
+		}
+		if loop, ok := n["nested_loop"].([]interface{}); ok && len(loop) > 1 {
+			product := 1.0
+			for _, item := range loop {
+				if entry, ok := item.(map[string]interface{}); ok {
+					if table, ok := entry["table"].(map[string]interface{}); ok {
+						if rows := toFloat(table["rows_examined_per_scan"]); rows > 0 {
+							product *= rows
+						}
+					}
+				}
+			}
+			if product >= t.NestedLoopRows {
+				summary.Warnings = append(summary.Warnings, PlanWarning{
+					Kind:    WarningNestedLoop,
+					Rows:    product,
+					Message: fmt.Sprintf("Nested loop join produces ~%.0f row combinations", product),
+				})
+			}
+		}
+		for key, value := range n {
+			if key == "table" {
+				continue
+			}
+			walkMySQLNode(value, summary, t)
+		}
+	case []interface{}:
+		for _, item := range n {
+			walkMySQLNode(item, summary, t)
+		}
+	}
+}
+
+var sqliteScanPattern = regexp.MustCompile(`^SCAN (?:TABLE )?([^\s]+)`)
+
+func analyzeSQLitePlan(result *models.QueryResult) *PlanSummary {
+	summary := &PlanSummary{}
+	detailIndex := len(result.Columns) - 1
+	for i, col := range result.Columns {
+		if strings.EqualFold(col, "detail") {
+			detailIndex = i
+		}
+	}
+
+	for _, row := range result.Rows {
+		if detailIndex < 0 || detailIndex >= len(row) {
+			continue
+		}
+		detail := fmt.Sprintf("%v", row[detailIndex])
+		if match := sqliteScanPattern.FindStringSubmatch(detail); match != nil && !strings.Contains(detail, "USING") {
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:     WarningSeqScan,
+				Relation: match[1],
+				Message:  fmt.Sprintf("Full table scan on %s", match[1]),
+			})
+		}
+		if strings.Contains(detail, "USE TEMP B-TREE") {
+			summary.Warnings = append(summary.Warnings, PlanWarning{
+				Kind:    WarningTempSort,
+				Message: detail,
+			})
+		}
+	}
+	return summary
+}
+
+// toFloat converts JSON numbers and numeric strings to float64
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
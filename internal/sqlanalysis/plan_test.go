@@ -0,0 +1,140 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildExplainStatement(t *testing.T) {
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) SELECT 1", BuildExplainStatement("postgresql", "SELECT 1;"))
+	assert.Equal(t, "EXPLAIN FORMAT=JSON SELECT 1", BuildExplainStatement("mysql", "SELECT 1"))
+	assert.Equal(t, "EXPLAIN QUERY PLAN SELECT 1", BuildExplainStatement("sqlite", " SELECT 1 "))
+}
+
+func TestIsExplainable(t *testing.T) {
+	assert.True(t, IsExplainable("-- comment\nSELECT 1"))
+	assert.True(t, IsExplainable("with x as (select 1) select * from x"))
+	assert.True(t, IsExplainable("UPDATE t SET a = 1"))
+	assert.False(t, IsExplainable("CREATE TABLE t (id int)"))
+	assert.False(t, IsExplainable("VACUUM"))
+}
+
+func TestCheckExplainable(t *testing.T) {
+	assert.NoError(t, CheckExplainable("SELECT ';' FROM t;"))
+	assert.EqualError(t, CheckExplainable("SELECT 1; DROP TABLE t"), "only one statement can be explained at a time, got 2")
+	assert.EqualError(t, CheckExplainable(`SELECT E'x\'' ; DROP TABLE t; SELECT ''`), "only one statement can be explained at a time, got 3")
+	assert.EqualError(t, CheckExplainable(`SELECT 'x\'' ; DROP TABLE t; SELECT ''`), "only one statement can be explained at a time, got 3")
+	assert.NoError(t, CheckExplainable(`SELECT 'C:\' AS path`))
+	assert.EqualError(t, CheckExplainable("VACUUM"), "statement type cannot be explained")
+}
+
+func TestAnalyzePlan_PostgreSQL(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Total Cost": 52000.5, "Plan Rows": 2000000,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Plan Rows": 50000},
+			{"Node Type": "Index Scan", "Relation Name": "users", "Plan Rows": 40}
+		]}}]`
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	assert.Equal(t, 52000.5, summary.TotalCost)
+	assert.Equal(t, float64(2000000), summary.EstimatedRows)
+	require.Len(t, summary.Warnings, 2)
+	assert.Equal(t, WarningNestedLoop, summary.Warnings[0].Kind)
+	assert.Equal(t, WarningSeqScan, summary.Warnings[1].Kind)
+	assert.Equal(t, "orders", summary.Warnings[1].Relation)
+}
+
+func TestAnalyzePlan_PostgreSQLSpatialSeqScan(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "parcels", "Total Cost": 900, "Plan Rows": 80000,
+		"Filter": "st_intersects(geom, '0103000020E6100000'::geometry)"}}]`
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	require.Len(t, summary.Warnings, 1)
+	assert.Contains(t, summary.Warnings[0].Message, "GIST")
+}
+
+func TestAnalyzePlan_PostgreSQLSpills(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Sort", "Total Cost": 900, "Plan Rows": 100,
+		"Sort Method": "external merge", "Sort Space Used": 20480, "Sort Space Type": "Disk",
+		"Plans": [{"Node Type": "Hash", "Plan Rows": 100, "Hash Batches": 4, "Peak Memory Usage": 4096}]}}]`
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	require.Len(t, summary.Warnings, 2)
+	assert.Equal(t, "Sort spills 20.0 MB to disk (external merge). SET LOCAL work_mem = '64MB' keeps the 20.0 MB spill in memory.", summary.Warnings[0].Message)
+	assert.Equal(t, WarningTempSort, summary.Warnings[1].Kind)
+	assert.Contains(t, summary.Warnings[1].Message, "4 batches")
+	assert.Contains(t, summary.Warnings[1].Message, "'16MB'")
+}
+
+func TestAnalyzePlan_PostgreSQLEstimatedSpills(t *testing.T) {
+	// Plain EXPLAIN has no Sort Method or Hash Batches, only estimates
+	plan := `[{"Plan": {"Node Type": "Sort", "Total Cost": 900, "Plan Rows": 200000, "Plan Width": 100,
+		"Plans": [{"Node Type": "Hash", "Plan Rows": 100000, "Plan Width": 100},
+			{"Node Type": "Sort", "Plan Rows": 1000, "Plan Width": 100}]}}]`
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	require.Len(t, summary.Warnings, 2)
+	assert.Equal(t, WarningTempSort, summary.Warnings[0].Kind)
+	assert.Equal(t, "Sort of ~200000 rows (~19.1 MB) likely spills to disk with the default work_mem. SET LOCAL work_mem = '64MB' keeps the sort in memory.", summary.Warnings[0].Message)
+	assert.Contains(t, summary.Warnings[1].Message, "Hash of ~100000 rows")
+}
+
+func TestAnalyzePlan_PostgreSQLSmallSeqScan(t *testing.T) {
+	plan := []byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "settings", "Total Cost": 1.2, "Plan Rows": 12}}]`)
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	assert.Empty(t, summary.Warnings)
+}
+
+func TestAnalyzePlan_MySQL(t *testing.T) {
+	plan := `{"query_block": {"cost_info": {"query_cost": "2050.75"},
+		"table": {"table_name": "orders", "access_type": "ALL", "rows_examined_per_scan": 20000}}}`
+	result := &models.QueryResult{Columns: []string{"EXPLAIN"}, Rows: [][]interface{}{{plan}}}
+
+	summary, err := AnalyzePlan("mysql", result)
+	require.NoError(t, err)
+	assert.Equal(t, 2050.75, summary.TotalCost)
+	require.Len(t, summary.Warnings, 1)
+	assert.Equal(t, WarningSeqScan, summary.Warnings[0].Kind)
+	assert.Equal(t, "orders", summary.Warnings[0].Relation)
+}
+
+func TestAnalyzePlan_SQLite(t *testing.T) {
+	result := &models.QueryResult{
+		Columns: []string{"id", "parent", "notused", "detail"},
+		Rows: [][]interface{}{
+			{2, 0, 0, "SCAN orders"},
+			{4, 0, 0, "SEARCH users USING INDEX idx_users_id (id=?)"},
+			{6, 0, 0, "USE TEMP B-TREE FOR ORDER BY"},
+		},
+	}
+
+	summary, err := AnalyzePlan("sqlite", result)
+	require.NoError(t, err)
+	require.Len(t, summary.Warnings, 2)
+	assert.Equal(t, WarningSeqScan, summary.Warnings[0].Kind)
+	assert.Equal(t, "orders", summary.Warnings[0].Relation)
+	assert.Equal(t, WarningTempSort, summary.Warnings[1].Kind)
+}
+
+func TestAnalyzePlan_Invalid(t *testing.T) {
+	_, err := AnalyzePlan("postgresql", &models.QueryResult{})
+	assert.Error(t, err)
+
+	_, err = AnalyzePlan("postgresql", &models.QueryResult{Rows: [][]interface{}{{"not json"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,418 @@
+package sqlanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// PlanNode is a node of an execution plan tree, in the same shape for every
+// dialect. Figures a dialect does not report are left zero.
+type PlanNode struct {
+	Operation     string      `json:"operation"` // e.g. "Seq Scan", "Hash Join" or "Table scan on orders"
+	Relation      string      `json:"relation,omitempty"`
+	Index         string      `json:"index,omitempty"`
+	Details       []string    `json:"details,omitempty"`    // Conditions, sort keys and rows removed
+	TotalCost     float64     `json:"total_cost,omitempty"` // Estimated cost including the children
+	SelfCost      float64     `json:"self_cost,omitempty"`  // Estimated cost of the node alone
+	EstimatedRows float64     `json:"estimated_rows,omitempty"`
+	Analyzed      bool        `json:"analyzed,omitempty"`       // Actual figures come from EXPLAIN ANALYZE
+	ActualRows    float64     `json:"actual_rows,omitempty"`    // Over all loops
+	ActualTime    float64     `json:"actual_time_ms,omitempty"` // Over all loops, including the children
+	Loops         float64     `json:"loops,omitempty"`
+	Children      []*PlanNode `json:"children,omitempty"`
+}
+
+// Walk visits the node and its descendants depth first
+func (n *PlanNode) Walk(visit func(*PlanNode)) {
+	visit(n)
+	for _, child := range n.Children {
+		child.Walk(visit)
+	}
+}
+
+// SelfTime returns the time spent in the node without its children
+func (n *PlanNode) SelfTime() float64 {
+	self := n.ActualTime
+	for _, child := range n.Children {
+		self -= child.ActualTime
+	}
+	return max(self, 0)
+}
+
+// Misestimate returns how many times the actual rows of an analyzed node
+// differ from the estimate, in either direction, or 0 when unknown
+func (n *PlanNode) Misestimate() float64 {
+	if !n.Analyzed || n.EstimatedRows <= 0 {
+		return 0
+	}
+	// PostgreSQL estimates rows per loop
+	estimated := n.EstimatedRows * max(n.Loops, 1)
+	actual := max(n.ActualRows, 1)
+	return max(actual/estimated, estimated/actual)
+}
+
+// ParsePlan converts an EXPLAIN result to a plan tree. It recognizes
+// PostgreSQL FORMAT JSON, MySQL FORMAT=JSON, MySQL FORMAT=TREE and EXPLAIN
+// ANALYZE, and SQLite EXPLAIN QUERY PLAN results, and returns an error for
+// any other result.
+func ParsePlan(result *models.QueryResult) (*PlanNode, error) {
+	if result == nil || len(result.Rows) == 0 {
+		return nil, fmt.Errorf("empty explain result")
+	}
+	if isSQLitePlan(result) {
+		return parseSQLitePlan(result), nil
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return nil, fmt.Errorf("not an EXPLAIN result")
+	}
+
+	raw := strings.TrimSpace(planJSON(result))
+	switch {
+	case strings.HasPrefix(raw, "["):
+		return parsePostgreSQLPlan(raw)
+	case strings.HasPrefix(raw, "{"):
+		return parseMySQLPlan(raw)
+	case strings.HasPrefix(raw, "->"):
+		return parseMySQLTreePlan(raw)
+	default:
+		return nil, fmt.Errorf("not an EXPLAIN result")
+	}
+}
+
+// postgreSQLDetails are the plan keys shown as node details, in order
+var postgreSQLDetails = []string{
+	"Index Cond", "Recheck Cond", "Hash Cond", "Merge Cond", "Join Filter", "Filter",
+	"Sort Key", "Group Key", "Rows Removed by Filter", "Rows Removed by Join Filter",
+	"Sort Method", "Hash Batches",
+}
+
+func parsePostgreSQLPlan(raw string) (*PlanNode, error) {
+	var plans []struct {
+		Plan map[string]interface{} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL plan: %w", err)
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("PostgreSQL plan is empty")
+	}
+	return postgreSQLNode(plans[0].Plan), nil
+}
+
+func postgreSQLNode(plan map[string]interface{}) *PlanNode {
+	node := &PlanNode{
+		Operation:     stringField(plan, "Node Type"),
+		Index:         stringField(plan, "Index Name"),
+		TotalCost:     toFloat(plan["Total Cost"]),
+		EstimatedRows: toFloat(plan["Plan Rows"]),
+	}
+	if join := stringField(plan, "Join Type"); join != "" && join != "Inner" {
+		// Shown like EXPLAIN does, e.g. Hash Left Join
+		node.Operation = strings.Replace(node.Operation, "Join", join+" Join", 1)
+	}
+	if name := stringField(plan, "Subplan Name"); name != "" {
+		node.Operation = name + ": " + node.Operation
+	}
+	node.Relation = stringField(plan, "Relation Name")
+	if node.Relation == "" {
+		node.Relation = stringField(plan, "CTE Name")
+	}
+	if alias := stringField(plan, "Alias"); alias != "" && node.Relation != "" && alias != node.Relation {
+		node.Relation += " " + alias
+	}
+	if _, ok := plan["Actual Rows"]; ok {
+		// Actual figures are averages per loop
+		node.Analyzed = true
+		node.Loops = toFloat(plan["Actual Loops"])
+		node.ActualRows = toFloat(plan["Actual Rows"]) * node.Loops
+		node.ActualTime = toFloat(plan["Actual Total Time"]) * node.Loops
+	}
+	for _, key := range postgreSQLDetails {
+		switch value := plan[key].(type) {
+		case string:
+			node.Details = append(node.Details, key+": "+value)
+		case []interface{}:
+			parts := make([]string, len(value))
+			for i, part := range value {
+				parts[i] = fmt.Sprintf("%v", part)
+			}
+			node.Details = append(node.Details, key+": "+strings.Join(parts, ", "))
+		case float64:
+			if value > 0 && (key != "Hash Batches" || value > 1) {
+				node.Details = append(node.Details, fmt.Sprintf("%s: %.0f", key, value))
+			}
+		}
+	}
+
+	node.SelfCost = node.TotalCost
+	children, _ := plan["Plans"].([]interface{})
+	for _, child := range children {
+		if childPlan, ok := child.(map[string]interface{}); ok {
+			childNode := postgreSQLNode(childPlan)
+			node.SelfCost -= childNode.TotalCost
+			node.Children = append(node.Children, childNode)
+		}
+	}
+	node.SelfCost = max(node.SelfCost, 0)
+	return node
+}
+
+// mySQLAccessTypes names the access types of MySQL JSON plans
+var mySQLAccessTypes = map[string]string{
+	"ALL":             "Full table scan",
+	"index":           "Full index scan",
+	"range":           "Index range scan",
+	"ref":             "Index lookup",
+	"eq_ref":          "Unique index lookup",
+	"ref_or_null":     "Index lookup or null",
+	"const":           "Constant row",
+	"system":          "Constant row",
+	"fulltext":        "Full-text index",
+	"index_merge":     "Index merge",
+	"unique_subquery": "Unique subquery lookup",
+	"index_subquery":  "Index subquery lookup",
+}
+
+// mySQLOperations names the operations that wrap tables in MySQL JSON plans,
+// in the order they are visited
+var mySQLOperations = []struct {
+	key, name string
+}{
+	{"ordering_operation", "Order"},
+	{"grouping_operation", "Group"},
+	{"duplicates_removal", "Distinct"},
+	{"windowing", "Window"},
+	{"buffer_result", "Buffer"},
+}
+
+func parseMySQLPlan(raw string) (*PlanNode, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse MySQL plan: %w", err)
+	}
+	block, _ := doc["query_block"].(map[string]interface{})
+	if block == nil {
+		return nil, fmt.Errorf("MySQL plan has no query_block")
+	}
+	return mySQLBlock(block), nil
+}
+
+// mySQLBlock converts a query_block
+func mySQLBlock(block map[string]interface{}) *PlanNode {
+	node := &PlanNode{Operation: "Query block"}
+	if id := toFloat(block["select_id"]); id > 0 {
+		node.Operation = fmt.Sprintf("Query block #%.0f", id)
+	}
+	if costInfo, ok := block["cost_info"].(map[string]interface{}); ok {
+		node.TotalCost = toFloat(costInfo["query_cost"])
+	}
+	node.Children = mySQLChildren(block)
+	return node
+}
+
+// mySQLChildren converts the tables and operations inside a JSON plan object
+func mySQLChildren(object map[string]interface{}) []*PlanNode {
+	var children []*PlanNode
+	for _, operation := range mySQLOperations {
+		inner, ok := object[operation.key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		node := &PlanNode{Operation: operation.name, Children: mySQLChildren(inner)}
+		if filesort, _ := inner["using_filesort"].(bool); filesort {
+			node.Details = append(node.Details, "Using filesort")
+		}
+		if temporary, _ := inner["using_temporary_table"].(bool); temporary {
+			node.Details = append(node.Details, "Using temporary table")
+		}
+		children = append(children, node)
+	}
+	if table, ok := object["table"].(map[string]interface{}); ok {
+		children = append(children, mySQLTable(table))
+	}
+	if loop, ok := object["nested_loop"].([]interface{}); ok {
+		node := &PlanNode{Operation: "Nested loop"}
+		for _, item := range loop {
+			if entry, ok := item.(map[string]interface{}); ok {
+				node.Children = append(node.Children, mySQLChildren(entry)...)
+			}
+		}
+		children = append(children, node)
+	}
+	if union, ok := object["union_result"].(map[string]interface{}); ok {
+		node := &PlanNode{Operation: "Union"}
+		if specifications, ok := union["query_specifications"].([]interface{}); ok {
+			node.Children = mySQLBlocks(specifications)
+		}
+		children = append(children, node)
+	}
+	for _, key := range []string{"attached_subqueries", "optimized_away_subqueries"} {
+		if subqueries, ok := object[key].([]interface{}); ok {
+			children = append(children, mySQLBlocks(subqueries)...)
+		}
+	}
+	return children
+}
+
+// mySQLBlocks converts a list of objects holding a query_block
+func mySQLBlocks(items []interface{}) []*PlanNode {
+	var blocks []*PlanNode
+	for _, item := range items {
+		if entry, ok := item.(map[string]interface{}); ok {
+			if block, ok := entry["query_block"].(map[string]interface{}); ok {
+				blocks = append(blocks, mySQLBlock(block))
+			}
+		}
+	}
+	return blocks
+}
+
+// mySQLTable converts a table access
+func mySQLTable(table map[string]interface{}) *PlanNode {
+	access := stringField(table, "access_type")
+	node := &PlanNode{
+		Operation:     mySQLAccessTypes[access],
+		Relation:      stringField(table, "table_name"),
+		Index:         stringField(table, "key"),
+		EstimatedRows: toFloat(table["rows_produced_per_join"]),
+	}
+	if node.Operation == "" {
+		node.Operation = access
+	}
+	if node.EstimatedRows == 0 {
+		node.EstimatedRows = toFloat(table["rows_examined_per_scan"])
+	}
+	if costInfo, ok := table["cost_info"].(map[string]interface{}); ok {
+		node.SelfCost = toFloat(costInfo["read_cost"]) + toFloat(costInfo["eval_cost"])
+		node.TotalCost = toFloat(costInfo["prefix_cost"])
+	}
+	if condition := stringField(table, "attached_condition"); condition != "" {
+		node.Details = append(node.Details, "Condition: "+condition)
+	}
+	if materialized, ok := table["materialized_from_subquery"].(map[string]interface{}); ok {
+		if block, ok := materialized["query_block"].(map[string]interface{}); ok {
+			node.Children = append(node.Children, mySQLBlock(block))
+		}
+	}
+	return node
+}
+
+var (
+	mySQLTreeCost   = regexp.MustCompile(`\(cost=([\d.e+]+)(?:\.\.([\d.e+]+))? rows=([\d.e+]+)\)`)
+	mySQLTreeActual = regexp.MustCompile(`\(actual time=([\d.e+]+)\.\.([\d.e+]+) rows=([\d.e+]+) loops=(\d+)\)`)
+	mySQLTreeTable  = regexp.MustCompile(`\bon (\S+)(?: using (\S+))?`)
+)
+
+// parseMySQLTreePlan parses the indented text of MySQL EXPLAIN FORMAT=TREE
+// and EXPLAIN ANALYZE, one "-> " line per node
+func parseMySQLTreePlan(raw string) (*PlanNode, error) {
+	type level struct {
+		indent int
+		node   *PlanNode
+	}
+	var root *PlanNode
+	var stack []level
+	for _, line := range strings.Split(raw, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "-> ") {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		text := strings.TrimPrefix(trimmed, "-> ")
+
+		node := &PlanNode{Operation: text}
+		if i := strings.Index(text, "  ("); i >= 0 {
+			node.Operation = text[:i]
+		}
+		if match := mySQLTreeTable.FindStringSubmatch(node.Operation); match != nil {
+			node.Relation, node.Index = match[1], match[2]
+		}
+		if match := mySQLTreeCost.FindStringSubmatch(text); match != nil {
+			node.TotalCost = parseFloat(match[1])
+			if match[2] != "" {
+				node.TotalCost = parseFloat(match[2])
+			}
+			node.EstimatedRows = parseFloat(match[3])
+		}
+		if match := mySQLTreeActual.FindStringSubmatch(text); match != nil {
+			// Actual figures are averages per loop
+			node.Analyzed = true
+			node.Loops = parseFloat(match[4])
+			node.ActualTime = parseFloat(match[2]) * node.Loops
+			node.ActualRows = parseFloat(match[3]) * node.Loops
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			if root != nil {
+				return nil, fmt.Errorf("MySQL plan has more than one root")
+			}
+			root = node
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, level{indent: indent, node: node})
+	}
+	if root == nil {
+		return nil, fmt.Errorf("MySQL plan is empty")
+	}
+	root.Walk(func(node *PlanNode) {
+		// Costs include the children, except for the estimated rows
+		node.SelfCost = node.TotalCost
+		for _, child := range node.Children {
+			node.SelfCost -= child.TotalCost
+		}
+		node.SelfCost = max(node.SelfCost, 0)
+	})
+	return root, nil
+}
+
+// isSQLitePlan reports whether a result has the id, parent, notused and
+// detail columns of EXPLAIN QUERY PLAN
+func isSQLitePlan(result *models.QueryResult) bool {
+	return len(result.Columns) == 4 && strings.EqualFold(result.Columns[1], "parent") && strings.EqualFold(result.Columns[3], "detail")
+}
+
+var sqliteRelation = regexp.MustCompile(`^(?:SCAN|SEARCH)(?: TABLE)? (\S+)(?: .*?USING (?:COVERING )?INDEX (\S+))?`)
+
+// parseSQLitePlan builds the tree of EXPLAIN QUERY PLAN rows, which name
+// their parent row. SQLite reports no costs or row counts.
+func parseSQLitePlan(result *models.QueryResult) *PlanNode {
+	root := &PlanNode{Operation: "QUERY PLAN"}
+	nodes := map[string]*PlanNode{"0": root}
+	for _, row := range result.Rows {
+		if len(row) < 4 {
+			continue
+		}
+		detail := cellText(row[3])
+		node := &PlanNode{Operation: detail}
+		if match := sqliteRelation.FindStringSubmatch(detail); match != nil {
+			node.Relation, node.Index = match[1], match[2]
+		}
+		nodes[cellText(row[0])] = node
+		parent, ok := nodes[cellText(row[1])]
+		if !ok {
+			parent = root
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return root
+}
+
+func stringField(object map[string]interface{}, key string) string {
+	value, _ := object[key].(string)
+	return value
+}
+
+func parseFloat(text string) float64 {
+	value, _ := strconv.ParseFloat(text, 64)
+	return value
+}
@@ -0,0 +1,119 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func singleCell(value string) *models.QueryResult {
+	return &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{{value}}}
+}
+
+func TestParsePlan_PostgreSQL(t *testing.T) {
+	root, err := ParsePlan(singleCell(`[{"Plan": {
+		"Node Type": "Hash Join", "Join Type": "Left", "Total Cost": 120.5, "Plan Rows": 100,
+		"Actual Rows": 5000, "Actual Loops": 1, "Actual Total Time": 30.5,
+		"Hash Cond": "(o.customer_id = c.id)",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Total Cost": 100, "Plan Rows": 100,
+			 "Actual Rows": 5000, "Actual Loops": 1, "Actual Total Time": 25, "Filter": "(total > 10)", "Rows Removed by Filter": 12},
+			{"Node Type": "Hash", "Total Cost": 15, "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 0.5,
+			 "Plans": [{"Node Type": "Index Scan", "Relation Name": "customers", "Alias": "c", "Index Name": "customers_pkey",
+			  "Total Cost": 14, "Plan Rows": 10, "Actual Rows": 2, "Actual Loops": 5, "Actual Total Time": 0.1}]}
+		]}, "Execution Time": 31}]`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hash Left Join", root.Operation)
+	assert.True(t, root.Analyzed)
+	assert.Equal(t, []string{"Hash Cond: (o.customer_id = c.id)"}, root.Details)
+	assert.InDelta(t, 5.5, root.SelfCost, 0.001)
+	assert.InDelta(t, 5, root.SelfTime(), 0.001)
+	assert.InDelta(t, 50, root.Misestimate(), 0.001)
+
+	scan := root.Children[0]
+	assert.Equal(t, "orders o", scan.Relation)
+	assert.Equal(t, []string{"Filter: (total > 10)", "Rows Removed by Filter: 12"}, scan.Details)
+
+	// Actual figures are per loop
+	index := root.Children[1].Children[0]
+	assert.Equal(t, "customers_pkey", index.Index)
+	assert.Equal(t, float64(10), index.ActualRows)
+	assert.InDelta(t, 0.5, index.ActualTime, 0.001)
+	assert.InDelta(t, 5, index.Misestimate(), 0.001)
+}
+
+func TestParsePlan_MySQLJSON(t *testing.T) {
+	root, err := ParsePlan(singleCell(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "25.10"},
+		"ordering_operation": {"using_filesort": true, "nested_loop": [
+			{"table": {"table_name": "o", "access_type": "ALL", "rows_examined_per_scan": 100, "rows_produced_per_join": 33,
+			 "cost_info": {"read_cost": "9.00", "eval_cost": "3.30", "prefix_cost": "12.30"}, "attached_condition": "(o.total > 10)"}},
+			{"table": {"table_name": "c", "access_type": "eq_ref", "key": "PRIMARY", "rows_produced_per_join": 33,
+			 "cost_info": {"read_cost": "9.50", "eval_cost": "3.30", "prefix_cost": "25.10"}}}
+		]}}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Query block #1", root.Operation)
+	assert.Equal(t, 25.1, root.TotalCost)
+	order := root.Children[0]
+	assert.Equal(t, "Order", order.Operation)
+	assert.Equal(t, []string{"Using filesort"}, order.Details)
+
+	loop := order.Children[0]
+	require.Len(t, loop.Children, 2)
+	assert.Equal(t, "Full table scan", loop.Children[0].Operation)
+	assert.Equal(t, "o", loop.Children[0].Relation)
+	assert.InDelta(t, 12.3, loop.Children[0].SelfCost, 0.001)
+	assert.Equal(t, "Unique index lookup", loop.Children[1].Operation)
+	assert.Equal(t, "PRIMARY", loop.Children[1].Index)
+}
+
+func TestParsePlan_MySQLTree(t *testing.T) {
+	root, err := ParsePlan(singleCell(`-> Nested loop inner join  (cost=4.50 rows=10) (actual time=0.050..0.200 rows=10 loops=1)
+    -> Filter: (o.total > 100)  (cost=1.25 rows=3) (actual time=0.030..0.100 rows=10 loops=1)
+        -> Table scan on o  (cost=1.25 rows=10) (actual time=0.020..0.080 rows=10 loops=1)
+    -> Single-row index lookup on c using PRIMARY (id=o.customer_id)  (cost=0.30 rows=1) (actual time=0.005..0.005 rows=1 loops=10)`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Nested loop inner join", root.Operation)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, "o", root.Children[0].Children[0].Relation)
+	lookup := root.Children[1]
+	assert.Equal(t, "c", lookup.Relation)
+	assert.Equal(t, "PRIMARY", lookup.Index)
+	assert.Equal(t, float64(10), lookup.ActualRows)
+	assert.InDelta(t, 0.05, lookup.ActualTime, 0.0001)
+	assert.InDelta(t, 2.95, root.SelfCost, 0.001)
+}
+
+func TestParsePlan_SQLite(t *testing.T) {
+	root, err := ParsePlan(&models.QueryResult{
+		Columns: []string{"id", "parent", "notused", "detail"},
+		Rows: [][]interface{}{
+			{int64(3), int64(0), int64(0), "SCAN o"},
+			{int64(5), int64(0), int64(0), "SEARCH c USING INTEGER PRIMARY KEY (rowid=?)"},
+			{int64(7), int64(0), int64(0), "USE TEMP B-TREE FOR ORDER BY"},
+			{int64(9), int64(5), int64(0), "CORRELATED SCALAR SUBQUERY 1"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, root.Children, 3)
+	assert.Equal(t, "o", root.Children[0].Relation)
+	assert.Equal(t, "c", root.Children[1].Relation)
+	assert.Equal(t, "CORRELATED SCALAR SUBQUERY 1", root.Children[1].Children[0].Operation)
+}
+
+func TestParsePlan_NotAPlan(t *testing.T) {
+	for _, result := range []*models.QueryResult{
+		nil,
+		{Columns: []string{"id", "name"}, Rows: [][]interface{}{{1, "a"}}},
+		singleCell("hello"),
+		singleCell(`{"id": 1}`),
+	} {
+		_, err := ParsePlan(result)
+		assert.Error(t, err)
+	}
+}
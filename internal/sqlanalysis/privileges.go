@@ -0,0 +1,166 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// InsufficientPrivilege is what PostgreSQL shows in place of the statement of
+// another user's session or pg_stat_statements entry without pg_read_all_stats
+const InsufficientPrivilege = "<insufficient privilege>"
+
+// Privilege is a grant an introspection source needs, so an analysis that
+// cannot read the source names exactly what to ask an administrator for
+type Privilege struct {
+	Source  string `json:"source"`
+	Purpose string `json:"purpose"` // What it enables
+	Grant   string `json:"grant"`   // Statement an administrator runs to grant it
+
+	dialect string
+	objects []string // Lower-case names that permission errors on the source mention
+}
+
+// privileges are the grants dbsage knows how to suggest. Grant holds %s for
+// the user until For fills it in.
+var privileges = []Privilege{
+	{
+		Source:  "pg_stat_statements",
+		Purpose: "statement statistics for /capture and temporary file analysis",
+		Grant:   "GRANT SELECT ON pg_stat_statements TO %s;",
+		dialect: "postgresql",
+		objects: []string{"pg_stat_statements"},
+	},
+	{
+		Source:  "pg_read_all_stats",
+		Purpose: "the statements and wait events of other users' sessions in pg_stat_activity and pg_stat_statements",
+		Grant:   "GRANT pg_read_all_stats TO %s;",
+		dialect: "postgresql",
+		objects: []string{"pg_stat_activity"},
+	},
+	{
+		Source:  "performance_schema",
+		Purpose: "statement history, wait events and server variables for /capture, wait profiling and configuration advice",
+		Grant:   "GRANT SELECT ON performance_schema.* TO %s;",
+		dialect: "mysql",
+		objects: []string{"performance_schema", "events_statements_history_long", "events_waits_current", "threads", "global_variables"},
+	},
+	{
+		Source:  "PROCESS",
+		Purpose: "the statements of other users' sessions in performance_schema.threads and the process list",
+		Grant:   "GRANT PROCESS ON *.* TO %s;",
+		dialect: "mysql",
+		objects: []string{"process privilege"},
+	},
+}
+
+// permissionErrors are lower-case fragments of permission errors
+var permissionErrors = []string{"permission denied", "command denied", "access denied", "insufficient privilege"}
+
+// MissingPrivilege reports the grant whose absence caused an error reading an
+// introspection source. The grant is for an unnamed user until For is called.
+func MissingPrivilege(dialect string, err error) (Privilege, bool) {
+	if err == nil {
+		return Privilege{}, false
+	}
+	message := strings.ToLower(err.Error())
+	denied := false
+	for _, fragment := range permissionErrors {
+		denied = denied || strings.Contains(message, fragment)
+	}
+	if !denied {
+		return Privilege{}, false
+	}
+
+	dialect = normalizeDialect(dialect)
+	for _, p := range privileges {
+		if p.dialect != dialect {
+			continue
+		}
+		for _, object := range p.objects {
+			if strings.Contains(message, object) {
+				return p, true
+			}
+		}
+	}
+	return Privilege{}, false
+}
+
+// StatsPrivilege returns the grant that lets the user see other users'
+// sessions and statements, for analyses that only saw the user's own
+func StatsPrivilege(dialect string) (Privilege, bool) {
+	source := map[string]string{"postgresql": "pg_read_all_stats", "mysql": "PROCESS"}[normalizeDialect(dialect)]
+	for _, p := range privileges {
+		if p.Source == source {
+			return p, true
+		}
+	}
+	return Privilege{}, false
+}
+
+// For fills in the user the grant is for, as returned by CurrentUserQuery,
+// or a placeholder when the user is unknown
+func (p Privilege) For(user string) Privilege {
+	grantee := "<user>"
+	if user != "" {
+		grantee = formatGrantee(p.dialect, user)
+	}
+	p.Grant = fmt.Sprintf(p.Grant, grantee)
+	return p
+}
+
+// Explain describes what the missing grant hides and how to get it
+func (p Privilege) Explain() string {
+	return fmt.Sprintf("The connected user lacks access to %s, needed for %s. Ask an administrator to run: %s", p.Source, p.Purpose, p.Grant)
+}
+
+// CurrentUserQuery returns the query naming the connected user
+func CurrentUserQuery(dialect string) string {
+	if normalizeDialect(dialect) == "mysql" {
+		return "SELECT CURRENT_USER()"
+	}
+	return "SELECT current_user"
+}
+
+// CurrentUserFromResult returns the user of a CurrentUserQuery result
+func CurrentUserFromResult(result *models.QueryResult) string {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return ""
+	}
+	return cellText(result.Rows[0][0])
+}
+
+var simpleRoleName = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// formatGrantee writes a user as a GRANT names it: 'app'@'%' on MySQL,
+// a role name quoted when needed on PostgreSQL
+func formatGrantee(dialect, user string) string {
+	if dialect == "mysql" {
+		name, host := user, "%"
+		if i := strings.LastIndex(user, "@"); i >= 0 {
+			name, host = user[:i], user[i+1:]
+		}
+		return fmt.Sprintf("'%s'@'%s'", strings.ReplaceAll(name, "'", "''"), strings.ReplaceAll(host, "'", "''"))
+	}
+	if simpleRoleName.MatchString(user) {
+		return user
+	}
+	return `"` + strings.ReplaceAll(user, `"`, `""`) + `"`
+}
+
+// HiddenRows counts the rows of a result whose statement column reads
+// InsufficientPrivilege, i.e. the rows of other users the user cannot see
+func HiddenRows(result *models.QueryResult, column int) int {
+	if result == nil {
+		return 0
+	}
+	hidden := 0
+	for _, row := range result.Rows {
+		if column < len(row) && strings.TrimSpace(cellText(row[column])) == InsufficientPrivilege {
+			hidden++
+		}
+	}
+	return hidden
+}
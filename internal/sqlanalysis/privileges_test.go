@@ -0,0 +1,63 @@
+package sqlanalysis
+
+import (
+	"errors"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingPrivilege(t *testing.T) {
+	p, ok := MissingPrivilege("postgresql", errors.New("pq: permission denied for view pg_stat_statements"))
+	require.True(t, ok)
+	assert.Equal(t, "pg_stat_statements", p.Source)
+	assert.Equal(t, "GRANT SELECT ON pg_stat_statements TO app;", p.For("app").Grant)
+
+	p, ok = MissingPrivilege("mysql", errors.New("Error 1142 (42000): SELECT command denied to user 'app'@'10.0.0.%' for table 'events_statements_history_long'"))
+	require.True(t, ok)
+	assert.Equal(t, "performance_schema", p.Source)
+	assert.Equal(t, "GRANT SELECT ON performance_schema.* TO 'app'@'10.0.0.%';", p.For("app@10.0.0.%").Grant)
+
+	_, ok = MissingPrivilege("postgresql", errors.New(`pq: relation "pg_stat_statements" does not exist`))
+	assert.False(t, ok, "a missing extension is not a missing privilege")
+	_, ok = MissingPrivilege("postgresql", errors.New("pq: permission denied for table orders"))
+	assert.False(t, ok, "only introspection sources have a known grant")
+}
+
+func TestPrivilege_For(t *testing.T) {
+	p, ok := StatsPrivilege("postgresql")
+	require.True(t, ok)
+	assert.Equal(t, "GRANT pg_read_all_stats TO <user>;", p.For("").Grant)
+	assert.Equal(t, `GRANT pg_read_all_stats TO "Report-User";`, p.For("Report-User").Grant)
+	assert.Contains(t, p.For("app").Explain(), "GRANT pg_read_all_stats TO app;")
+
+	_, ok = StatsPrivilege("sqlite")
+	assert.False(t, ok)
+}
+
+func TestHiddenRows(t *testing.T) {
+	result := &models.QueryResult{Rows: [][]interface{}{
+		{"<insufficient privilege>", int64(3)},
+		{"SELECT 1", int64(1)},
+	}}
+	assert.Equal(t, 1, HiddenRows(result, 0))
+	assert.Equal(t, 0, HiddenRows(nil, 0))
+	assert.Equal(t, "app", CurrentUserFromResult(&models.QueryResult{Rows: [][]interface{}{{"app"}}}))
+}
+
+func TestWaitProfile_HiddenSessions(t *testing.T) {
+	profile := NewWaitProfile()
+	profile.AddSample(&models.QueryResult{Rows: [][]interface{}{
+		{"CPU", "CPU", InsufficientPrivilege},
+		{"IO", "DataFileRead", "SELECT * FROM orders"},
+	}})
+	profile.Finish(10)
+
+	assert.Equal(t, 1, profile.HiddenSamples)
+	assert.Equal(t, 1, profile.ActiveSamples)
+	require.Len(t, profile.Top, 1)
+	assert.Equal(t, "DataFileRead", profile.Top[0].Event)
+}
@@ -0,0 +1,209 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// QueryFilter is one condition of a built query
+type QueryFilter struct {
+	Column   string
+	Operator string // =, !=, >, >=, <, <=, contains, starts with, ends with, in, is empty, is not empty
+	Value    string
+}
+
+// QueryOrder is one ORDER BY column of a built query
+type QueryOrder struct {
+	Column     string
+	Descending bool
+}
+
+// QuerySpec describes a single-table SELECT picked in the query builder
+type QuerySpec struct {
+	Table   string
+	Columns []string // Empty for every column
+	Filters []QueryFilter
+	OrderBy []QueryOrder
+	Limit   int // 0 for no limit
+}
+
+// filterPattern matches "column operator value", with word operators in any case
+var filterPattern = regexp.MustCompile(`(?i)^([a-z_][a-z0-9_$]*)\s*(>=|<=|!=|<>|=|>|<|\s(?:contains|starts with|ends with|in|is not empty|is empty|is not null|is null)\b)\s*(.*)$`)
+
+// filterSeparator splits filters joined with "and"
+var filterSeparator = regexp.MustCompile(`(?i)\s+and\s+`)
+
+// ParseColumnList splits a comma separated column list; "*" and an empty
+// text select every column
+func ParseColumnList(text string) []string {
+	var columns []string
+	for _, column := range strings.Split(text, ",") {
+		if column = strings.TrimSpace(column); column != "" && column != "*" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// ParseFilters parses conditions joined with "and", such as
+// "status = paid and total >= 100 and email contains example.com"
+func ParseFilters(text string) ([]QueryFilter, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	var filters []QueryFilter
+	for _, part := range filterSeparator.Split(strings.TrimSpace(text), -1) {
+		m := filterPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, fmt.Errorf("cannot read the filter %q: write it as <column> <operator> <value>, e.g. status = paid", part)
+		}
+		filter := QueryFilter{Column: m[1], Operator: strings.ToLower(strings.TrimSpace(m[2])), Value: unquoteValue(strings.TrimSpace(m[3]))}
+		switch filter.Operator {
+		case "<>":
+			filter.Operator = "!="
+		case "is null":
+			filter.Operator = "is empty"
+		case "is not null":
+			filter.Operator = "is not empty"
+		}
+		needsValue := filter.Operator != "is empty" && filter.Operator != "is not empty"
+		if needsValue && filter.Value == "" {
+			return nil, fmt.Errorf("the filter %q needs a value", part)
+		}
+		if !needsValue && filter.Value != "" {
+			return nil, fmt.Errorf("the filter %q takes no value", part)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// ParseOrder parses a comma separated ORDER BY such as "created_at desc, name"
+func ParseOrder(text string) ([]QueryOrder, error) {
+	var order []QueryOrder
+	for _, part := range strings.Split(text, ",") {
+		words := strings.Fields(part)
+		switch {
+		case len(words) == 0:
+			continue
+		case len(words) == 1:
+			order = append(order, QueryOrder{Column: words[0]})
+		case len(words) == 2 && (strings.EqualFold(words[1], "asc") || strings.EqualFold(words[1], "desc")):
+			order = append(order, QueryOrder{Column: words[0], Descending: strings.EqualFold(words[1], "desc")})
+		default:
+			return nil, fmt.Errorf("cannot read the ordering %q: write it as <column> [asc|desc]", strings.TrimSpace(part))
+		}
+	}
+	return order, nil
+}
+
+// BuildSelect builds the SELECT of a query spec. Values are quoted as text
+// unless they are numbers. When known lists the table's columns, other
+// column names are rejected.
+func BuildSelect(dialect string, spec QuerySpec, known []string) (string, error) {
+	if spec.Table == "" {
+		return "", fmt.Errorf("pick a table")
+	}
+	check := func(column string) (string, error) {
+		if len(known) == 0 {
+			return identifier(dialect, column), nil
+		}
+		for _, k := range known {
+			if strings.EqualFold(k, column) {
+				return identifier(dialect, k), nil
+			}
+		}
+		return "", fmt.Errorf("%s has no column %s; its columns are %s", spec.Table, column, strings.Join(known, ", "))
+	}
+
+	selected := "*"
+	if len(spec.Columns) > 0 {
+		quoted := make([]string, len(spec.Columns))
+		for i, column := range spec.Columns {
+			name, err := check(column)
+			if err != nil {
+				return "", err
+			}
+			quoted[i] = name
+		}
+		selected = strings.Join(quoted, ", ")
+	}
+	lines := []string{"SELECT " + selected, "FROM " + identifier(dialect, spec.Table)}
+
+	for i, filter := range spec.Filters {
+		column, err := check(filter.Column)
+		if err != nil {
+			return "", err
+		}
+		keyword := "WHERE "
+		if i > 0 {
+			keyword = "  AND "
+		}
+		lines = append(lines, keyword+filterCondition(dialect, column, filter))
+	}
+
+	if len(spec.OrderBy) > 0 {
+		order := make([]string, len(spec.OrderBy))
+		for i, o := range spec.OrderBy {
+			column, err := check(o.Column)
+			if err != nil {
+				return "", err
+			}
+			if o.Descending {
+				column += " DESC"
+			}
+			order[i] = column
+		}
+		lines = append(lines, "ORDER BY "+strings.Join(order, ", "))
+	}
+	if spec.Limit > 0 {
+		lines = append(lines, fmt.Sprintf("LIMIT %d", spec.Limit))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// filterCondition renders a filter on an already quoted column
+func filterCondition(dialect, column string, filter QueryFilter) string {
+	like := "LIKE"
+	if normalizeDialect(dialect) == "postgresql" {
+		like = "ILIKE" // LIKE already ignores case on MySQL and SQLite
+	}
+	switch filter.Operator {
+	case "is empty":
+		return column + " IS NULL"
+	case "is not empty":
+		return column + " IS NOT NULL"
+	case "contains":
+		return fmt.Sprintf("%s %s %s", column, like, sqlLiteral("%"+filter.Value+"%"))
+	case "starts with":
+		return fmt.Sprintf("%s %s %s", column, like, sqlLiteral(filter.Value+"%"))
+	case "ends with":
+		return fmt.Sprintf("%s %s %s", column, like, sqlLiteral("%"+filter.Value))
+	case "in":
+		values := strings.Split(strings.Trim(filter.Value, "()"), ",")
+		for i, value := range values {
+			values[i] = valueLiteral(unquoteValue(strings.TrimSpace(value)))
+		}
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(values, ", "))
+	default:
+		return fmt.Sprintf("%s %s %s", column, filter.Operator, valueLiteral(filter.Value))
+	}
+}
+
+// valueLiteral keeps numbers as they are and quotes everything else
+func valueLiteral(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return sqlLiteral(value)
+}
+
+// unquoteValue removes the quotes users may put around a value
+func unquoteValue(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
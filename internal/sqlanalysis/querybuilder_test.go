@@ -0,0 +1,67 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilters(t *testing.T) {
+	filters, err := ParseFilters("status = 'paid' AND total >= 100 and email contains example.com and shipped_at is null and region in (eu, 'us')")
+	require.NoError(t, err)
+	assert.Equal(t, []QueryFilter{
+		{Column: "status", Operator: "=", Value: "paid"},
+		{Column: "total", Operator: ">=", Value: "100"},
+		{Column: "email", Operator: "contains", Value: "example.com"},
+		{Column: "shipped_at", Operator: "is empty"},
+		{Column: "region", Operator: "in", Value: "(eu, 'us')"},
+	}, filters)
+
+	_, err = ParseFilters("status paid")
+	assert.ErrorContains(t, err, "<column> <operator> <value>")
+	_, err = ParseFilters("status =")
+	assert.ErrorContains(t, err, "needs a value")
+	_, err = ParseFilters("note is empty yes")
+	assert.ErrorContains(t, err, "takes no value")
+}
+
+func TestParseOrder(t *testing.T) {
+	order, err := ParseOrder("created_at DESC, name")
+	require.NoError(t, err)
+	assert.Equal(t, []QueryOrder{{Column: "created_at", Descending: true}, {Column: "name"}}, order)
+
+	_, err = ParseOrder("created_at newest")
+	assert.Error(t, err)
+}
+
+func TestBuildSelect(t *testing.T) {
+	filters, err := ParseFilters("status != cancelled and email ends with @example.com and region in (eu, 7)")
+	require.NoError(t, err)
+	spec := QuerySpec{
+		Table:   "orders",
+		Columns: ParseColumnList("id, Email ,total"),
+		Filters: filters,
+		OrderBy: []QueryOrder{{Column: "total", Descending: true}},
+		Limit:   50,
+	}
+
+	sql, err := BuildSelect("postgresql", spec, []string{"id", "email", "total", "status", "region"})
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id, email, total
+FROM orders
+WHERE status != 'cancelled'
+  AND email ILIKE '%@example.com'
+  AND region IN ('eu', 7)
+ORDER BY total DESC
+LIMIT 50`, sql)
+
+	sql, err = BuildSelect("mysql", QuerySpec{Table: "Order Items"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT *\nFROM `Order Items`", sql)
+
+	_, err = BuildSelect("postgresql", QuerySpec{Table: "orders", Columns: []string{"price"}}, []string{"id", "total"})
+	assert.ErrorContains(t, err, "orders has no column price; its columns are id, total")
+	_, err = BuildSelect("postgresql", QuerySpec{}, nil)
+	assert.Error(t, err)
+}
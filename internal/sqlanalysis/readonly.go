@@ -0,0 +1,184 @@
+package sqlanalysis
+
+import (
+	"strings"
+)
+
+// readVerbs start statements that only read
+var readVerbs = map[string]bool{
+	"select": true, "with": true, "values": true, "table": true, "show": true,
+	"describe": true, "desc": true, "explain": true, "pragma": true,
+}
+
+// sessionVerbs start statements that only manage the session or its
+// transaction
+var sessionVerbs = map[string]bool{
+	"begin": true, "start": true, "commit": true, "rollback": true, "end": true,
+	"savepoint": true, "release": true, "set": true, "reset": true, "deallocate": true,
+	"discard": true, "use": true,
+}
+
+// writeFunctions change data when a read statement calls them
+var writeFunctions = map[string]bool{
+	"nextval": true, "setval": true,
+}
+
+// writeVerbs change data when they appear inside a read statement, e.g. in a
+// data-modifying CTE
+var writeVerbs = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true,
+}
+
+// FirstWrite classifies the statements of a script and returns what the first
+// one that may change data, schema or server settings does, e.g. "UPDATE",
+// "DROP" or "SELECT INTO", or "" when every statement only reads. Unknown
+// statements count as writes. Whether a backslash escapes a quote depends on
+// the dialect and its settings, so the script is read both ways and a write
+// either reading finds counts. Apart from nextval and setval it cannot see
+// side effects of functions, so it complements read-only database users
+// rather than replacing them.
+func FirstWrite(script string) string {
+	for _, backslash := range []bool{false, true} {
+		for _, stmt := range splitStatements(script, backslash) {
+			if verb := statementWrite(tokenize([]rune(stripComments(stmt, backslash)), backslash)); verb != "" {
+				return verb
+			}
+		}
+	}
+	return ""
+}
+
+// IsReadOnly reports whether a script has statements and every one of them
+// reads data: FirstWrite finds no write and none only manages the session,
+// such as BEGIN or SET. Such statements are safe to replay, and to answer
+// from a read replica.
+func IsReadOnly(script string) bool {
+	statements := 0
+	for _, stmt := range SplitStatements(script) {
+		tokens := unwrap(tokenizeSQL([]rune(StripComments(stmt))))
+		for len(tokens) > 1 && tokens[0].is("(") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		if tokens[0].kind != tokenWord || !readVerbs[strings.ToLower(tokens[0].text)] {
+			return false
+		}
+		statements++
+	}
+	return statements > 0 && FirstWrite(script) == ""
+}
+
+// isReadOnlySetting reports whether a setting controls the read-only mode the
+// server enforces on a read-only connection
+func isReadOnlySetting(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, "read_only") || name == "readonly" || name == "query_only"
+}
+
+// statementWrite returns what a statement writes, or "" when it only reads
+func statementWrite(tokens []sqlToken) string {
+	tokens = unwrap(tokens)
+	if len(tokens) == 0 {
+		return ""
+	}
+	// (SELECT ...) UNION SELECT ... starts with a parenthesis
+	first := 0
+	for first < len(tokens)-1 && tokens[first].is("(") {
+		first++
+	}
+	if tokens[first].kind != tokenWord {
+		return strings.ToUpper(tokens[first].text)
+	}
+	verb := strings.ToLower(tokens[first].text)
+
+	switch {
+	case sessionVerbs[verb]:
+		// SET GLOBAL and SET PERSIST change the server for everyone
+		if verb == "set" && len(tokens) > 1 && (tokens[1].isWord("GLOBAL") || tokens[1].isWord("PERSIST") || tokens[1].isWord("PERSIST_ONLY")) {
+			return "SET " + strings.ToUpper(tokens[1].text)
+		}
+		// BEGIN READ WRITE and SET transaction_read_only = off lift the
+		// read-only mode of the session
+		for i, tok := range tokens {
+			if (tok.isWord("WRITE") && i > 0 && tokens[i-1].isWord("READ")) || (tok.kind == tokenWord && isReadOnlySetting(tok.text)) {
+				return "READ WRITE"
+			}
+		}
+		return ""
+	case verb == "explain":
+		return explainWrite(tokens[1:])
+	case verb == "pragma":
+		// PRAGMA name = value changes the database
+		for _, tok := range tokens {
+			if tok.is("=") {
+				return "PRAGMA"
+			}
+		}
+		return ""
+	case !readVerbs[verb]:
+		return strings.ToUpper(verb)
+	}
+
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case tok.kind != tokenWord:
+		case writeVerbs[strings.ToLower(tok.text)] && i > 0 && (tokens[i-1].is("(") || (depth == 0 && tokens[i-1].is(")"))):
+			// Data-modifying CTEs, WITH moved AS (DELETE ... RETURNING *) SELECT ...,
+			// and statements after a CTE list, WITH ... INSERT INTO ...
+			return strings.ToUpper(tok.text)
+		case depth == 0 && tok.isWord("INTO") && (verb == "select" || verb == "with"):
+			// SELECT ... INTO creates a table, or writes a file on MySQL
+			return "SELECT INTO"
+		case depth == 0 && tok.isWord("FOR") && i+1 < len(tokens) && (tokens[i+1].isWord("UPDATE") || tokens[i+1].isWord("SHARE") || tokens[i+1].isWord("NO") || tokens[i+1].isWord("KEY")):
+			return "SELECT FOR UPDATE"
+		case depth == 0 && tok.isWord("LOCK") && i+2 < len(tokens) && tokens[i+1].isWord("IN") && tokens[i+2].isWord("SHARE"):
+			// MySQL SELECT ... LOCK IN SHARE MODE
+			return "SELECT FOR UPDATE"
+		case writeFunctions[strings.ToLower(tok.text)] && i+1 < len(tokens) && tokens[i+1].is("("):
+			return strings.ToUpper(tok.text)
+		}
+	}
+	return ""
+}
+
+// explainWrite returns what the statement explained writes when EXPLAIN
+// ANALYZE runs it; a plain EXPLAIN only plans it
+func explainWrite(tokens []sqlToken) string {
+	analyze := false
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		switch {
+		case tok.is("("):
+			end := matchParen(tokens, 0)
+			for _, option := range tokens[1:end] {
+				if option.isWord("ANALYZE") || option.isWord("ANALYSE") {
+					analyze = true
+				}
+			}
+			tokens = tokens[end+1:]
+			continue
+		case tok.isWord("ANALYZE") || tok.isWord("ANALYSE"):
+			analyze = true
+		case tok.isWord("FORMAT"):
+			// MySQL FORMAT=JSON
+			if len(tokens) > 2 && tokens[1].is("=") {
+				tokens = tokens[2:]
+			}
+		case tok.isWord("VERBOSE") || tok.isWord("QUERY") || tok.isWord("PLAN") || tok.isWord("EXTENDED"):
+		default:
+			if !analyze {
+				return ""
+			}
+			return statementWrite(tokens)
+		}
+		tokens = tokens[1:]
+	}
+	return ""
+}
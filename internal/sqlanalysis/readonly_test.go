@@ -0,0 +1,62 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstWrite(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM users", ""},
+		{"  -- latest\n(SELECT id FROM users) UNION SELECT id FROM admins", ""},
+		{"WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent", ""},
+		{"SHOW TABLES; DESCRIBE users", ""},
+		{"EXPLAIN SELECT * FROM users", ""},
+		{"EXPLAIN DELETE FROM users", ""},
+		{"EXPLAIN FORMAT=JSON SELECT 1", ""},
+		{"BEGIN; SELECT 1; COMMIT", ""},
+		{"SET search_path = app", ""},
+		{"PRAGMA table_info(users)", ""},
+		{"SELECT 'DELETE FROM users' AS note", ""},
+		{"SELECT * FROM users WHERE name = 'into'", ""},
+		{"insert into users (name) values ('a')", "INSERT"},
+		{"SELECT 1; UPDATE users SET name = 'b'", "UPDATE"},
+		{"DROP TABLE users", "DROP"},
+		{"create index idx on users (name)", "CREATE"},
+		{"TRUNCATE users", "TRUNCATE"},
+		{"GRANT SELECT ON users TO bob", "GRANT"},
+		{"WITH moved AS (DELETE FROM users RETURNING *) SELECT * FROM moved", "DELETE"},
+		{"WITH src AS (SELECT 1 AS id) INSERT INTO users (id) SELECT id FROM src", "INSERT"},
+		{"SELECT * INTO backup FROM users", "SELECT INTO"},
+		{"SELECT * FROM users FOR UPDATE", "SELECT FOR UPDATE"},
+		{"EXPLAIN ANALYZE DELETE FROM users", "DELETE"},
+		{"EXPLAIN (ANALYZE, BUFFERS) UPDATE users SET name = 'c'", "UPDATE"},
+		{"SET GLOBAL max_connections = 10", "SET GLOBAL"},
+		{"PRAGMA journal_mode = WAL", "PRAGMA"},
+		{"VACUUM", "VACUUM"},
+		{`SELECT E'x\'' ; DROP TABLE t; SELECT ''`, "DROP"},
+		{`SELECT 'x\'' ; DELETE FROM t; SELECT ''`, "DELETE"},
+		{`SELECT 'C:\' AS path`, ""},
+		{"SET default_transaction_read_only = off", "READ WRITE"},
+		{"START TRANSACTION READ WRITE", "READ WRITE"},
+		{"SET SESSION TRANSACTION READ ONLY", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FirstWrite(tt.sql), tt.sql)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	assert.True(t, IsReadOnly("SELECT 1; -- next\nSELECT 2"))
+	assert.True(t, IsReadOnly("WITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, IsReadOnly("WITH gone AS (DELETE FROM orders RETURNING *) SELECT count(*) FROM gone"))
+	assert.False(t, IsReadOnly("WITH ids AS (SELECT 1) INSERT INTO t SELECT * FROM ids"))
+	assert.False(t, IsReadOnly("SELECT * FROM orders LOCK IN SHARE MODE"))
+	assert.False(t, IsReadOnly("SELECT setval('orders_id_seq', 1)"))
+	assert.False(t, IsReadOnly("BEGIN"))
+	assert.False(t, IsReadOnly("-- nothing"))
+}
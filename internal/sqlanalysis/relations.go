@@ -0,0 +1,253 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// Relation is a foreign key from the columns of a table to the columns they
+// reference
+type Relation struct {
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"` // Empty on SQLite when the primary key is referenced implicitly
+}
+
+// String renders the relation as table.column → ref_table.ref_column
+func (r Relation) String() string {
+	return fmt.Sprintf("%s.%s → %s.%s", r.Table, columnList(r.Columns), r.RefTable, columnList(r.RefColumns))
+}
+
+func columnList(columns []string) string {
+	if len(columns) == 1 {
+		return columns[0]
+	}
+	return "(" + strings.Join(columns, ", ") + ")"
+}
+
+// BuildRelationsQuery returns the query reading every foreign key column pair
+// as (constraint, table, column, referenced table, referenced column), in key
+// column order
+func BuildRelationsQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT c.conname, src.relname, a.attname, ref.relname, ra.attname
+FROM pg_constraint c
+JOIN pg_class src ON src.oid = c.conrelid
+JOIN pg_class ref ON ref.oid = c.confrelid
+JOIN pg_namespace n ON n.oid = src.relnamespace
+CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(col, refcol, pos)
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.col
+JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refcol
+WHERE c.contype = 'f' AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY src.relname, c.conname, k.pos`, nil
+	case "mysql":
+		return `SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`, nil
+	case "sqlite":
+		return `SELECT p.id, m.name, p."from", p."table", p."to"
+FROM sqlite_master m
+JOIN pragma_foreign_key_list(m.name) p
+WHERE m.type = 'table'
+ORDER BY m.name, p.id, p.seq`, nil
+	default:
+		return "", fmt.Errorf("foreign key navigation is not supported for %s databases", dialect)
+	}
+}
+
+// RelationsFromResult converts a relations query result, joining the column
+// pairs of multi-column keys
+func RelationsFromResult(result *models.QueryResult) []Relation {
+	if result == nil {
+		return nil
+	}
+	var relations []Relation
+	for _, row := range result.Rows {
+		if len(row) < 5 {
+			continue
+		}
+		name, table := cellText(row[0]), cellText(row[1])
+		last := len(relations) - 1
+		if last < 0 || relations[last].Name != name || relations[last].Table != table {
+			relations = append(relations, Relation{Name: name, Table: table, RefTable: cellText(row[3])})
+			last++
+		}
+		relations[last].Columns = append(relations[last].Columns, cellText(row[2]))
+		if ref := cellText(row[4]); ref != "" {
+			relations[last].RefColumns = append(relations[last].RefColumns, ref)
+		}
+	}
+	return relations
+}
+
+// RelatedTables splits the relations touching a table into the keys it
+// holds (outgoing) and the keys referencing it (incoming). A self-reference is
+// both.
+func RelatedTables(relations []Relation, table string) (outgoing, incoming []Relation) {
+	for _, r := range relations {
+		if strings.EqualFold(r.Table, table) {
+			outgoing = append(outgoing, r)
+		}
+		if strings.EqualFold(r.RefTable, table) {
+			incoming = append(incoming, r)
+		}
+	}
+	return outgoing, incoming
+}
+
+// joinStep moves from one table to the next over a relation, against its
+// direction when reverse is set
+type joinStep struct {
+	relation Relation
+	reverse  bool
+}
+
+func (s joinStep) from() string {
+	if s.reverse {
+		return s.relation.RefTable
+	}
+	return s.relation.Table
+}
+
+func (s joinStep) to() string {
+	if s.reverse {
+		return s.relation.Table
+	}
+	return s.relation.RefTable
+}
+
+// findJoinPath returns the fewest relations leading from one table to another,
+// in either direction
+func findJoinPath(relations []Relation, from, to string) ([]joinStep, bool) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	previous := map[string]joinStep{}
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 && !visited[to] {
+		table := queue[0]
+		queue = queue[1:]
+		for _, r := range relations {
+			for _, step := range []joinStep{{relation: r}, {relation: r, reverse: true}} {
+				next := strings.ToLower(step.to())
+				if strings.ToLower(step.from()) != table || visited[next] {
+					continue
+				}
+				visited[next] = true
+				previous[next] = step
+				queue = append(queue, next)
+			}
+		}
+	}
+	if !visited[to] {
+		return nil, false
+	}
+
+	var path []joinStep
+	for table := to; table != from; {
+		step := previous[table]
+		path = append([]joinStep{step}, path...)
+		table = strings.ToLower(step.from())
+	}
+	return path, true
+}
+
+// BuildJoinSkeleton builds a SELECT joining the given tables in order over
+// their foreign keys. Tables that are not directly related are joined through
+// the shortest chain of relations between them.
+func BuildJoinSkeleton(dialect string, tables []string, relations []Relation) (string, error) {
+	if len(tables) < 2 {
+		return "", fmt.Errorf("a join needs at least two tables")
+	}
+
+	// Keywords are taken from the start so no alias reads as one
+	aliases := map[string]bool{}
+	for keyword := range reservedAliases {
+		aliases[keyword] = true
+	}
+	alias := func(table string) string {
+		base := tableAlias(table)
+		name := base
+		for i := 2; aliases[name]; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		aliases[name] = true
+		return name
+	}
+
+	current := alias(tables[0])
+	selected := []string{current + ".*"}
+	from := fmt.Sprintf("FROM %s %s", identifier(dialect, tables[0]), current)
+	var joins []string
+	for i := 1; i < len(tables); i++ {
+		if strings.EqualFold(tables[i-1], tables[i]) {
+			return "", fmt.Errorf("%s is listed twice in a row", tables[i])
+		}
+		path, ok := findJoinPath(relations, tables[i-1], tables[i])
+		if !ok {
+			return "", fmt.Errorf("no foreign keys connect %s and %s", tables[i-1], tables[i])
+		}
+		for _, step := range path {
+			next := alias(step.to())
+			fromColumns, toColumns := step.relation.Columns, step.relation.RefColumns
+			if step.reverse {
+				fromColumns, toColumns = toColumns, fromColumns
+			}
+			if len(step.relation.RefColumns) != len(step.relation.Columns) {
+				return "", fmt.Errorf("the columns referenced by %s are unknown", step.relation)
+			}
+			conditions := make([]string, len(fromColumns))
+			for j := range fromColumns {
+				conditions[j] = fmt.Sprintf("%s.%s = %s.%s", next, identifier(dialect, toColumns[j]), current, identifier(dialect, fromColumns[j]))
+			}
+			joins = append(joins, fmt.Sprintf("JOIN %s %s ON %s", identifier(dialect, step.to()), next, strings.Join(conditions, " AND ")))
+			selected = append(selected, next+".*")
+			current = next
+		}
+	}
+
+	return fmt.Sprintf("SELECT %s\n%s\n%s\nLIMIT 100", strings.Join(selected, ", "), from, strings.Join(joins, "\n")), nil
+}
+
+// reservedAliases are short keywords table initials could spell
+var reservedAliases = map[string]bool{
+	"as": true, "at": true, "by": true, "do": true, "if": true, "in": true, "is": true, "no": true,
+	"of": true, "on": true, "or": true, "to": true, "all": true, "and": true, "any": true, "asc": true,
+	"end": true, "for": true, "key": true, "not": true, "set": true, "use": true,
+}
+
+// tableAlias abbreviates a table name to the initials of its words
+func tableAlias(table string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(strings.ToLower(table), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		if word[0] >= 'a' && word[0] <= 'z' {
+			b.WriteByte(word[0])
+		}
+	}
+	if b.Len() == 0 {
+		return "t"
+	}
+	return b.String()
+}
+
+// plainIdentifier matches names that need no quoting
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// identifier quotes a name for the dialect when it is not a plain lower case identifier
+func identifier(dialect, name string) string {
+	if plainIdentifier.MatchString(name) {
+		return name
+	}
+	if normalizeDialect(dialect) == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,75 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shopRelations() []Relation {
+	return RelationsFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{"orders_customer_fk", "orders", "customer_id", "customers", "id"},
+		{"items_order_fk", "order_items", "order_id", "orders", "id"},
+		{"items_product_fk", "order_items", "product_id", "products", "id"},
+		{"items_variant_fk", "order_items", "product_id", "variants", "product_id"},
+		{"items_variant_fk", "order_items", []byte("variant"), "variants", "code"},
+		{"employees_manager_fk", "employees", "manager_id", "employees", "id"},
+	}})
+}
+
+func TestRelationsFromResult(t *testing.T) {
+	relations := shopRelations()
+	require.Len(t, relations, 5)
+	assert.Equal(t, []string{"product_id", "variant"}, relations[3].Columns)
+	assert.Equal(t, []string{"product_id", "code"}, relations[3].RefColumns)
+	assert.Equal(t, "order_items.(product_id, variant) → variants.(product_id, code)", relations[3].String())
+
+	outgoing, incoming := RelatedTables(relations, "ORDERS")
+	require.Len(t, outgoing, 1)
+	assert.Equal(t, "customers", outgoing[0].RefTable)
+	require.Len(t, incoming, 1)
+	assert.Equal(t, "order_items", incoming[0].Table)
+
+	outgoing, incoming = RelatedTables(relations, "employees")
+	assert.Len(t, outgoing, 1, "a self-reference is listed both ways")
+	assert.Len(t, incoming, 1)
+}
+
+func TestBuildJoinSkeleton(t *testing.T) {
+	relations := shopRelations()
+
+	sql, err := BuildJoinSkeleton("postgresql", []string{"customers", "orders", "order_items"}, relations)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT c.*, o.*, oi.*
+FROM customers c
+JOIN orders o ON o.customer_id = c.id
+JOIN order_items oi ON oi.order_id = o.id
+LIMIT 100`, sql)
+
+	// Tables that are not directly related are joined through the shortest chain
+	sql, err = BuildJoinSkeleton("mysql", []string{"customers", "products"}, relations)
+	require.NoError(t, err)
+	assert.Contains(t, sql, "JOIN orders o ON o.customer_id = c.id\nJOIN order_items oi ON oi.order_id = o.id\nJOIN products p ON p.id = oi.product_id")
+
+	sql, err = BuildJoinSkeleton("sqlite", []string{"order_items", "variants"}, relations)
+	require.NoError(t, err)
+	assert.Contains(t, sql, "JOIN variants v ON v.product_id = oi.product_id AND v.code = oi.variant")
+
+	_, err = BuildJoinSkeleton("postgresql", []string{"employees", "employees"}, relations)
+	assert.Error(t, err)
+	_, err = BuildJoinSkeleton("postgresql", []string{"customers", "audit_log"}, relations)
+	assert.ErrorContains(t, err, "no foreign keys connect customers and audit_log")
+}
+
+func TestBuildJoinSkeleton_AliasesAndQuoting(t *testing.T) {
+	relations := []Relation{
+		{Name: "fk1", Table: "order_requests", Columns: []string{"UserId"}, RefTable: "Users", RefColumns: []string{"Id"}},
+		{Name: "fk2", Table: "user_roles", Columns: []string{"user_id"}, RefTable: "Users", RefColumns: []string{"Id"}},
+	}
+	sql, err := BuildJoinSkeleton("mysql", []string{"order_requests", "Users", "user_roles"}, relations)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT or2.*, u.*, ur.*\nFROM order_requests or2\nJOIN `Users` u ON u.`Id` = or2.`UserId`\nJOIN user_roles ur ON ur.user_id = u.`Id`\nLIMIT 100", sql)
+}
@@ -0,0 +1,62 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+)
+
+// ReplicaLagQueries returns the statements measuring how far a replica is
+// behind its primary, to try in order until one runs. PostgreSQL reports no
+// lag while the replica has replayed everything it received, since the replay
+// timestamp only moves when the primary writes. MySQL before 8.0.22 only
+// knows SHOW SLAVE STATUS.
+func ReplicaLagQueries(dialect string) ([]string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return []string{`SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN NULL
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END AS lag_seconds`}, nil
+	case "mysql":
+		return []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"}, nil
+	default:
+		return nil, fmt.Errorf("replication lag is not supported for %s databases", dialect)
+	}
+}
+
+// ReplicaLagFromResult reads the lag from a ReplicaLagQueries result. It is
+// not known when the server is not a replica or replication is stopped.
+func ReplicaLagFromResult(result *models.QueryResult) (time.Duration, bool) {
+	if result == nil || len(result.Rows) == 0 {
+		return 0, false
+	}
+	column := 0
+	for i, name := range result.Columns {
+		switch strings.ToLower(name) {
+		case "seconds_behind_source", "seconds_behind_master":
+			column = i
+		}
+	}
+	if column >= len(result.Rows[0]) {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(cellText(result.Rows[0][column]), 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// FormatLag renders a replication lag, to the millisecond below a second and
+// to the second above
+func FormatLag(lag time.Duration) string {
+	if lag < time.Second {
+		return lag.Round(time.Millisecond).String()
+	}
+	return lag.Round(time.Second).String()
+}
@@ -0,0 +1,48 @@
+package sqlanalysis
+
+import (
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaLagQueries(t *testing.T) {
+	queries, err := ReplicaLagQueries("postgres")
+	require.NoError(t, err)
+	assert.Contains(t, queries[0], "pg_last_xact_replay_timestamp()")
+
+	queries, err = ReplicaLagQueries("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"}, queries)
+
+	_, err = ReplicaLagQueries("sqlite")
+	assert.Error(t, err)
+}
+
+func TestReplicaLagFromResult(t *testing.T) {
+	lag, ok := ReplicaLagFromResult(&models.QueryResult{Columns: []string{"lag_seconds"}, Rows: [][]interface{}{{"2.5"}}})
+	require.True(t, ok)
+	assert.Equal(t, 2500*time.Millisecond, lag)
+
+	lag, ok = ReplicaLagFromResult(&models.QueryResult{
+		Columns: []string{"Replica_IO_State", "Source_Host", "Seconds_Behind_Source"},
+		Rows:    [][]interface{}{{"Waiting for source to send event", "db-primary", int64(42)}},
+	})
+	require.True(t, ok)
+	assert.Equal(t, 42*time.Second, lag)
+
+	// Not a replica, replication stopped
+	_, ok = ReplicaLagFromResult(&models.QueryResult{Columns: []string{"lag_seconds"}, Rows: [][]interface{}{{nil}}})
+	assert.False(t, ok)
+	_, ok = ReplicaLagFromResult(&models.QueryResult{Columns: []string{"Seconds_Behind_Master"}})
+	assert.False(t, ok)
+}
+
+func TestFormatLag(t *testing.T) {
+	assert.Equal(t, "250ms", FormatLag(250*time.Millisecond+400*time.Microsecond))
+	assert.Equal(t, "2m5s", FormatLag(125*time.Second+300*time.Millisecond))
+}
@@ -0,0 +1,275 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// SchemaTable is a table with its columns, for the schema summary sent to the AI
+type SchemaTable struct {
+	Name    string
+	Columns []string // "name type", in column order
+}
+
+// String renders the table as name(column type, ...)
+func (t SchemaTable) String() string {
+	return t.Name + "(" + strings.Join(t.Columns, ", ") + ")"
+}
+
+// BuildSchemaColumnsQuery returns the query reading every column of the
+// user's tables as (table, column, type), in column order
+func BuildSchemaColumnsQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT CASE WHEN table_schema = 'public' THEN table_name ELSE table_schema || '.' || table_name END, column_name, data_type
+FROM information_schema.columns
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema') AND table_schema NOT LIKE 'pg_toast%'
+ORDER BY table_schema, table_name, ordinal_position`, nil
+	case "mysql":
+		return `SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, ORDINAL_POSITION`, nil
+	case "sqlite":
+		return `SELECT m.name, p.name, p.type
+FROM sqlite_master m
+JOIN pragma_table_info(m.name) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'
+ORDER BY m.name, p.cid`, nil
+	default:
+		return "", fmt.Errorf("schema summaries are not supported for %s", dialect)
+	}
+}
+
+// SchemaFromResult groups the rows of BuildSchemaColumnsQuery by table
+func SchemaFromResult(result *models.QueryResult) []SchemaTable {
+	if result == nil {
+		return nil
+	}
+	var tables []SchemaTable
+	for _, row := range result.Rows {
+		if len(row) < 3 {
+			continue
+		}
+		name, column := cellText(row[0]), cellText(row[1])
+		if name == "" || column == "" {
+			continue
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != name {
+			tables = append(tables, SchemaTable{Name: name})
+		}
+		column = strings.TrimSpace(column + " " + strings.ToLower(cellText(row[2])))
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, column)
+	}
+	return tables
+}
+
+// Reasons a table is included in a schema summary that does not fit whole
+const (
+	ReasonMentioned = "mentioned in the conversation"
+	ReasonQueried   = "queried recently"
+	ReasonSimilar   = "similar to the question"
+)
+
+// SchemaContextTable is a table sent to the AI with its columns
+type SchemaContextTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Reason  string   `json:"reason,omitempty"` // Why it was chosen, empty when the whole schema fits
+}
+
+// SchemaContext is the schema summary sent to the AI: the tables with their
+// columns, further tables by name only, and how many were left out
+type SchemaContext struct {
+	Tables   []SchemaContextTable `json:"tables"`
+	Listed   []string             `json:"listed,omitempty"` // Tables sent by name only
+	Omitted  int                  `json:"omitted"`          // Tables not sent at all
+	Tokens   int                  `json:"tokens"`           // Estimated size of the summary
+	Budget   int                  `json:"budget"`
+	Complete bool                 `json:"complete"` // Every table was sent with its columns
+}
+
+// schemaTokens estimates the tokens of text, at about 4 characters per token
+// like SchemaFits
+func schemaTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Header lines of the schema summary
+const (
+	schemaHeader      = "\n\nDATABASE SCHEMA:\n"
+	schemaPartialNote = "The schema is too large to send whole. These are the tables most relevant to the conversation; call get_table_schema for the columns of others.\n"
+)
+
+// SchemaFits reports whether a whole schema fits in a token budget
+func SchemaFits(tables []SchemaTable, budget int) bool {
+	size := len(schemaHeader)
+	for _, t := range tables {
+		size += len(t.String()) + 1
+	}
+	return (size+3)/4 <= budget
+}
+
+// SelectSchema chooses what of a schema fits in a token budget. When the whole
+// schema fits it is sent as is. Otherwise tables are picked by relevance:
+// those mentioned in the conversation, then those queried recently, then
+// those semantically similar to the question, then their foreign key
+// neighbors, each with its columns. The remaining tables are listed by name
+// while the budget allows, and the rest are only counted.
+func SelectSchema(tables []SchemaTable, relations []Relation, mentioned, queried, similar []string, budget int) SchemaContext {
+	ctx := SchemaContext{Budget: budget}
+	if SchemaFits(tables, budget) {
+		for _, t := range tables {
+			ctx.Tables = append(ctx.Tables, SchemaContextTable{Name: t.Name, Columns: t.Columns})
+		}
+		ctx.Complete = true
+		ctx.Tokens = schemaTokens(ctx.Prompt())
+		return ctx
+	}
+
+	byName := make(map[string]int, len(tables))
+	for i, t := range tables {
+		byName[strings.ToLower(t.Name)] = i
+		if short := strings.ToLower(unqualifiedTable(t.Name)); short != strings.ToLower(t.Name) {
+			if _, taken := byName[short]; !taken {
+				byName[short] = i
+			}
+		}
+	}
+	type candidate struct {
+		index  int
+		reason string
+	}
+	var candidates []candidate
+	chosen := make(map[int]bool)
+	add := func(name, reason string) {
+		if i, ok := byName[strings.ToLower(name)]; ok && !chosen[i] {
+			chosen[i] = true
+			candidates = append(candidates, candidate{i, reason})
+		}
+	}
+	for _, name := range mentioned {
+		add(name, ReasonMentioned)
+	}
+	for _, name := range queried {
+		add(name, ReasonQueried)
+	}
+	for _, name := range similar {
+		add(name, ReasonSimilar)
+	}
+	seeds := len(candidates)
+	for _, seed := range candidates[:seeds] {
+		name := tables[seed.index].Name
+		for _, r := range relations {
+			switch {
+			case strings.EqualFold(r.Table, name) || strings.EqualFold(r.Table, unqualifiedTable(name)):
+				add(r.RefTable, "referenced by "+name)
+			case strings.EqualFold(r.RefTable, name) || strings.EqualFold(r.RefTable, unqualifiedTable(name)):
+				add(r.Table, "references "+name)
+			}
+		}
+	}
+
+	used := schemaTokens(schemaHeader + schemaPartialNote)
+	sent := make(map[int]bool)
+	for _, c := range candidates {
+		t := tables[c.index]
+		cost := schemaTokens(t.String() + "\n")
+		if used+cost > budget {
+			continue
+		}
+		used += cost
+		sent[c.index] = true
+		ctx.Tables = append(ctx.Tables, SchemaContextTable{Name: t.Name, Columns: t.Columns, Reason: c.reason})
+	}
+
+	// Other tables by name, on one line, while they fit next to the count of
+	// those left out
+	used += schemaTokens("Other tables: \n") + schemaTokens(omittedNote(len(tables)))
+	for i, t := range tables {
+		if sent[i] {
+			continue
+		}
+		cost := schemaTokens(t.Name + ", ")
+		if used+cost > budget {
+			ctx.Omitted++
+			continue
+		}
+		used += cost
+		ctx.Listed = append(ctx.Listed, t.Name)
+	}
+	ctx.Tokens = schemaTokens(ctx.Prompt())
+	return ctx
+}
+
+// Prompt renders the summary as the system prompt section sent to the AI
+func (c SchemaContext) Prompt() string {
+	if len(c.Tables) == 0 && len(c.Listed) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(schemaHeader)
+	if !c.Complete {
+		b.WriteString(schemaPartialNote)
+	}
+	for _, t := range c.Tables {
+		b.WriteString(SchemaTable{Name: t.Name, Columns: t.Columns}.String() + "\n")
+	}
+	if len(c.Listed) > 0 {
+		b.WriteString("Other tables: " + strings.Join(c.Listed, ", ") + "\n")
+	}
+	if c.Omitted > 0 {
+		b.WriteString(omittedNote(c.Omitted))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// omittedNote tells the AI how many tables the summary leaves out
+func omittedNote(omitted int) string {
+	return fmt.Sprintf("%d more tables are not listed; call get_all_tables to see them.\n", omitted)
+}
+
+// wordPattern matches the identifiers of free text
+var wordPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$.]*`)
+
+// MentionedTables returns the tables named in text, in the order they are
+// first mentioned. A word matches a table by its name, with or without its
+// schema, or by its singular form ("order" for orders).
+func MentionedTables(text string, tables []SchemaTable) []string {
+	names := make(map[string]string, len(tables)*2)
+	for _, t := range tables {
+		for _, name := range []string{t.Name, unqualifiedTable(t.Name)} {
+			name = strings.ToLower(name)
+			if _, taken := names[name]; !taken {
+				names[name] = t.Name
+			}
+			if singular := strings.TrimSuffix(name, "s"); singular != name && len(singular) > 2 {
+				if _, taken := names[singular]; !taken {
+					names[singular] = t.Name
+				}
+			}
+		}
+	}
+
+	var mentioned []string
+	seen := make(map[string]bool)
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		word = strings.ToLower(strings.TrimRight(word, "."))
+		candidates := []string{word}
+		if parts := strings.Split(word, "."); len(parts) > 1 {
+			// orders.total names orders, public.orders names itself
+			candidates = append(candidates, parts[0], strings.Join(parts[:len(parts)-1], "."))
+		}
+		for _, candidate := range candidates {
+			if table, ok := names[candidate]; ok && !seen[table] {
+				seen[table] = true
+				mentioned = append(mentioned, table)
+				break
+			}
+		}
+	}
+	return mentioned
+}
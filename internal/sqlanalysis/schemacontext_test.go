@@ -0,0 +1,81 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wideSchema returns n tables named table_000... with ten columns each, and
+// the shop tables customers, orders and order_items
+func wideSchema(n int) []SchemaTable {
+	tables := []SchemaTable{
+		{Name: "customers", Columns: []string{"id integer", "name text"}},
+		{Name: "order_items", Columns: []string{"id integer", "order_id integer", "sku text"}},
+		{Name: "orders", Columns: []string{"id integer", "customer_id integer", "total numeric"}},
+	}
+	for i := 0; i < n; i++ {
+		table := SchemaTable{Name: fmt.Sprintf("table_%03d", i)}
+		for c := 0; c < 10; c++ {
+			table.Columns = append(table.Columns, fmt.Sprintf("column_%d text", c))
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func TestSchemaFromResult(t *testing.T) {
+	tables := SchemaFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{"orders", "id", "integer"},
+		{"orders", "total", []byte("NUMERIC")},
+		{"sales.regions", "code", "text"},
+	}})
+	assert.Equal(t, []SchemaTable{
+		{Name: "orders", Columns: []string{"id integer", "total numeric"}},
+		{Name: "sales.regions", Columns: []string{"code text"}},
+	}, tables)
+}
+
+func TestSelectSchema_Fits(t *testing.T) {
+	ctx := SelectSchema(wideSchema(0), nil, nil, nil, nil, 1000)
+	assert.True(t, ctx.Complete)
+	assert.Len(t, ctx.Tables, 3)
+	assert.Empty(t, ctx.Tables[0].Reason)
+	assert.Contains(t, ctx.Prompt(), "orders(id integer, customer_id integer, total numeric)")
+	assert.NotContains(t, ctx.Prompt(), "too large")
+}
+
+func TestSelectSchema_ChoosesRelevantTables(t *testing.T) {
+	relations := []Relation{
+		{Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+		{Table: "order_items", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+	}
+	ctx := SelectSchema(wideSchema(500), relations, []string{"orders"}, []string{"table_042"}, []string{"table_007"}, 400)
+
+	assert.False(t, ctx.Complete)
+	require.Len(t, ctx.Tables, 5)
+	assert.Equal(t, SchemaContextTable{Name: "orders", Columns: []string{"id integer", "customer_id integer", "total numeric"}, Reason: ReasonMentioned}, ctx.Tables[0])
+	assert.Equal(t, "table_042", ctx.Tables[1].Name)
+	assert.Equal(t, ReasonQueried, ctx.Tables[1].Reason)
+	assert.Equal(t, "table_007", ctx.Tables[2].Name)
+	assert.Equal(t, ReasonSimilar, ctx.Tables[2].Reason)
+	assert.Equal(t, "referenced by orders", ctx.Tables[3].Reason)
+	assert.Equal(t, "references orders", ctx.Tables[4].Reason)
+
+	// The other tables are listed by name while they fit and counted after
+	assert.NotEmpty(t, ctx.Listed)
+	assert.Positive(t, ctx.Omitted)
+	assert.Equal(t, 503, len(ctx.Tables)+len(ctx.Listed)+ctx.Omitted)
+	assert.LessOrEqual(t, ctx.Tokens, ctx.Budget)
+	assert.Contains(t, ctx.Prompt(), fmt.Sprintf("%d more tables are not listed", ctx.Omitted))
+}
+
+func TestMentionedTables(t *testing.T) {
+	tables := []SchemaTable{{Name: "orders"}, {Name: "customers"}, {Name: "sales.regions"}, {Name: "as"}}
+	mentioned := MentionedTables("Which customer placed the most Orders per regions? Use orders.total", tables)
+	assert.Equal(t, []string{"customers", "orders", "sales.regions"}, mentioned)
+}
@@ -0,0 +1,47 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// implicitCommitVerbs start MySQL statements that commit the open transaction
+// before they run, so they cannot be rolled back
+var implicitCommitVerbs = map[string]bool{
+	"create": true, "alter": true, "drop": true, "rename": true, "truncate": true,
+	"lock": true, "unlock": true, "grant": true, "revoke": true, "analyze": true,
+	"optimize": true, "repair": true, "flush": true, "install": true, "uninstall": true,
+}
+
+// ScratchpadRefusal returns why a script cannot run in a scratchpad, a
+// transaction that is always rolled back, or "" when it can. Statements that
+// end the transaction are refused, and on MySQL so are statements that
+// commit it implicitly, such as DDL.
+func ScratchpadRefusal(dialect, script string) string {
+	backslash := BackslashEscapes(dialect)
+	for _, stmt := range splitStatements(script, backslash) {
+		tokens := unwrap(tokenize([]rune(stripComments(stmt, backslash)), backslash))
+		if len(tokens) == 0 || tokens[0].kind != tokenWord {
+			continue
+		}
+		verb := strings.ToLower(tokens[0].text)
+		next := ""
+		if len(tokens) > 1 {
+			next = strings.ToLower(tokens[1].text)
+		}
+
+		switch {
+		case verb == "rollback" && (next == "to" || next == "transaction" && len(tokens) > 2 && tokens[2].isWord("TO")):
+			// ROLLBACK TO SAVEPOINT keeps the transaction open
+		case verb == "begin" || verb == "start" || verb == "commit" || verb == "end" || verb == "rollback" || verb == "abort":
+			return fmt.Sprintf("%s ends or restarts the scratchpad transaction; use /scratch end to roll it back", strings.ToUpper(verb))
+		case verb == "prepare" && next == "transaction":
+			return "PREPARE TRANSACTION would keep the scratchpad's changes after it ends"
+		case verb == "set" && next == "autocommit":
+			return "SET autocommit would commit the scratchpad transaction"
+		case normalizeDialect(dialect) == "mysql" && implicitCommitVerbs[verb] && !(verb == "create" && next == "temporary") && !(verb == "drop" && next == "temporary"):
+			return fmt.Sprintf("MySQL commits the open transaction before %s statements, so the scratchpad could not roll them back", strings.ToUpper(verb))
+		}
+	}
+	return ""
+}
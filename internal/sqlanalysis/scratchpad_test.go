@@ -0,0 +1,29 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScratchpadRefusal(t *testing.T) {
+	for _, sql := range []string{
+		"UPDATE orders SET status = 'paid'",
+		"SAVEPOINT before_delete; DELETE FROM orders; ROLLBACK TO SAVEPOINT before_delete",
+		"ROLLBACK TRANSACTION TO SAVEPOINT a",
+		"CREATE TABLE scratch_copy AS SELECT * FROM orders",
+		"/* dbsage */ SELECT 1",
+	} {
+		assert.Empty(t, ScratchpadRefusal("postgresql", sql), sql)
+	}
+
+	assert.Contains(t, ScratchpadRefusal("postgresql", "DELETE FROM orders; COMMIT"), "COMMIT ends")
+	assert.Contains(t, ScratchpadRefusal("postgresql", "begin"), "BEGIN ends")
+	assert.Contains(t, ScratchpadRefusal("sqlite", "END"), "END ends")
+	assert.Contains(t, ScratchpadRefusal("postgresql", "PREPARE TRANSACTION 'x'"), "PREPARE TRANSACTION")
+	assert.Contains(t, ScratchpadRefusal("mysql", "SET autocommit = 1"), "autocommit")
+
+	assert.Contains(t, ScratchpadRefusal("mysql", "ALTER TABLE orders ADD COLUMN note TEXT"), "MySQL commits the open transaction before ALTER")
+	assert.Empty(t, ScratchpadRefusal("mysql", "CREATE TEMPORARY TABLE t (id INT)"))
+	assert.Empty(t, ScratchpadRefusal("postgresql", "ALTER TABLE orders ADD COLUMN note TEXT"), "PostgreSQL DDL is transactional")
+}
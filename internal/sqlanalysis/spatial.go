@@ -0,0 +1,21 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// spatialFilterPattern matches PostGIS predicates that a GiST index can serve
+var spatialFilterPattern = regexp.MustCompile(`(?i)\bst_(intersects|dwithin|contains|within|covers|coveredby|overlaps|touches|crosses|equals|dfullywithin)\s*\(|&&`)
+
+// IsSpatialFilter reports whether a plan filter or condition uses a spatial predicate
+func IsSpatialFilter(filter string) bool {
+	return spatialFilterPattern.MatchString(filter)
+}
+
+// SpatialIndexSuggestion returns the index to create for a geometry or
+// geography column. Spatial predicates need GiST; a B-tree index only
+// compares whole values and is never used by them.
+func SpatialIndexSuggestion(table, column string) string {
+	return fmt.Sprintf("CREATE INDEX ON %s USING GIST (%s); -- spatial predicates (ST_Intersects, ST_DWithin, &&) cannot use a B-tree index", table, column)
+}
@@ -0,0 +1,220 @@
+package sqlanalysis
+
+import (
+	"strings"
+)
+
+// SplitStatements splits a SQL script into individual statements on semicolons,
+// ignoring semicolons inside string literals, quoted identifiers, comments and
+// PostgreSQL dollar-quoted bodies. Empty statements are dropped. Backslashes
+// only escape quotes in PostgreSQL E'...' strings; use SplitStatementsFor for
+// MySQL and ClickHouse scripts.
+func SplitStatements(script string) []string {
+	return splitStatements(script, false)
+}
+
+// SplitStatementsFor splits a script like SplitStatements, with the string
+// escapes of a dialect: a backslash escapes the next character in MySQL and
+// ClickHouse strings, so 'it\'s; fine' is one literal there
+func SplitStatementsFor(dialect, script string) []string {
+	return splitStatements(script, BackslashEscapes(dialect))
+}
+
+// CountStatements returns how many statements a script holds. Whether a
+// backslash escapes a quote depends on the dialect and its settings, so the
+// script is split both ways and the larger count wins: a statement is counted
+// even when one reading takes it for part of a string literal.
+func CountStatements(script string) int {
+	return max(len(splitStatements(script, false)), len(splitStatements(script, true)))
+}
+
+// BackslashEscapes reports whether a backslash escapes the next character in
+// the plain string literals of a dialect
+func BackslashEscapes(dialect string) bool {
+	switch strings.ToLower(dialect) {
+	case "mysql", "mariadb", "clickhouse":
+		return true
+	default:
+		return false
+	}
+}
+
+// splitStatements splits a script, with backslash escapes in every string
+// literal when backslash is set
+func splitStatements(script string, backslash bool) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		if stmt != "" && strings.TrimSpace(stripComments(stmt, backslash)) != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := findClosingQuote(runes, i, ch, backslash)
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := indexRunes(runes, i+2, []rune("*/"))
+			if end < 0 {
+				end = len(runes)
+			} else {
+				end += 2
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+
+		case ch == '$':
+			tag, ok := dollarQuoteTag(runes, i)
+			if !ok {
+				current.WriteRune(ch)
+				continue
+			}
+			tagRunes := []rune(tag)
+			end := indexRunes(runes, i+len(tagRunes), tagRunes)
+			if end < 0 {
+				end = len(runes)
+			} else {
+				end += len(tagRunes)
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+
+		case ch == ';':
+			flush()
+
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// indexRunes returns the index of pattern in runes at or after start, or -1
+func indexRunes(runes []rune, start int, pattern []rune) int {
+	for i := start; i+len(pattern) <= len(runes); i++ {
+		match := true
+		for j, r := range pattern {
+			if runes[i+j] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// findClosingQuote returns the index just past the closing quote, treating a
+// doubled quote character as an escaped quote. A backslash escapes the next
+// character in PostgreSQL E'...' strings, and in every string but backquoted
+// identifiers when backslash is set.
+func findClosingQuote(runes []rune, start int, quote rune, backslash bool) int {
+	escapes := (backslash && quote != '`') || (quote == '\'' && isEscapeStringPrefix(runes, start))
+	for j := start + 1; j < len(runes); j++ {
+		if escapes && runes[j] == '\\' {
+			j++
+			continue
+		}
+		if runes[j] == quote {
+			if j+1 < len(runes) && runes[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(runes)
+}
+
+// isEscapeStringPrefix reports whether the quote at start opens a PostgreSQL
+// escape string, E'...'
+func isEscapeStringPrefix(runes []rune, start int) bool {
+	if start == 0 || (runes[start-1] != 'E' && runes[start-1] != 'e') {
+		return false
+	}
+	return start == 1 || !isIdentifierRune(runes[start-2])
+}
+
+// dollarQuoteTag detects a PostgreSQL dollar quote opening tag like $$ or $body$
+func dollarQuoteTag(runes []rune, start int) (string, bool) {
+	if start > 0 && isIdentifierRune(runes[start-1]) {
+		return "", false
+	}
+	for j := start + 1; j < len(runes); j++ {
+		if runes[j] == '$' {
+			return string(runes[start : j+1]), true
+		}
+		if !(runes[j] == '_' || (runes[j] >= 'a' && runes[j] <= 'z') || (runes[j] >= 'A' && runes[j] <= 'Z') || (j > start+1 && isDigit(runes[j]))) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// StripComments removes -- line comments and /* */ block comments outside of literals
+func StripComments(sql string) string {
+	return stripComments(sql, false)
+}
+
+// stripComments removes comments, with backslash escapes in every string
+// literal when backslash is set
+func stripComments(sql string, backslash bool) string {
+	var result strings.Builder
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := findClosingQuote(runes, i, ch, backslash)
+			result.WriteString(string(runes[i:end]))
+			i = end - 1
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			if i < len(runes) {
+				result.WriteRune('\n')
+			}
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			result.WriteRune(' ')
+		default:
+			result.WriteRune(ch)
+		}
+	}
+	return result.String()
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
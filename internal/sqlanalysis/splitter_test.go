@@ -0,0 +1,80 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "simple statements",
+			script:   "SELECT 1; SELECT 2;\nSELECT 3",
+			expected: []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name:     "semicolons inside literals",
+			script:   "SELECT 'a;b'; SELECT \"x;y\" FROM t; SELECT 'it''s;'",
+			expected: []string{"SELECT 'a;b'", "SELECT \"x;y\" FROM t", "SELECT 'it''s;'"},
+		},
+		{
+			name:     "comments",
+			script:   "-- header; comment\nSELECT 1; /* block; comment */ SELECT 2;\n-- trailing only",
+			expected: []string{"-- header; comment\nSELECT 1", "/* block; comment */ SELECT 2"},
+		},
+		{
+			name:   "dollar quoted function body",
+			script: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql; SELECT f();",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+				"SELECT f()",
+			},
+		},
+		{
+			name:     "positional parameters are not dollar quotes",
+			script:   "SELECT * FROM t WHERE id = $1; SELECT 2",
+			expected: []string{"SELECT * FROM t WHERE id = $1", "SELECT 2"},
+		},
+		{
+			name:     "empty script",
+			script:   " ;\n; ",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SplitStatements(tt.script))
+		})
+	}
+}
+
+func TestSplitStatements_BackslashEscapes(t *testing.T) {
+	mysql := `INSERT INTO notes VALUES ('it\'s; fine'); SELECT "a\"; b"; SELECT 1`
+	assert.Equal(t, []string{`INSERT INTO notes VALUES ('it\'s; fine')`, `SELECT "a\"; b"`, "SELECT 1"}, SplitStatementsFor("mysql", mysql))
+
+	postgres := `SELECT E'it\'s; fine'; SELECT 'C:\'; SELECT 1`
+	expected := []string{`SELECT E'it\'s; fine'`, `SELECT 'C:\'`, "SELECT 1"}
+	assert.Equal(t, expected, SplitStatements(postgres), "only E'' strings escape in PostgreSQL")
+	assert.Equal(t, expected, SplitStatementsFor("postgresql", postgres))
+	assert.Equal(t, []string{`SELECT e'\\'`, "SELECT 2"}, SplitStatements(`SELECT e'\\'; SELECT 2`))
+	assert.Len(t, SplitStatements(`SELECT name'\'; SELECT 2`), 2, "a name ending in e is not an E string")
+}
+
+func TestCountStatements(t *testing.T) {
+	assert.Equal(t, 3, CountStatements(`SELECT E'x\'' ; DROP TABLE t; SELECT ''`))
+	assert.Equal(t, 3, CountStatements(`SELECT 'x\'' ; DROP TABLE t; SELECT ''`), "hidden from the standard reading")
+	assert.Equal(t, 3, CountStatements(`SELECT 'a\'; DROP TABLE t; SELECT '\'`), "hidden from the backslash reading")
+	assert.Equal(t, 1, CountStatements("SELECT 'it''s; fine'"))
+	assert.Equal(t, 0, CountStatements("-- nothing"))
+}
+
+func TestStripComments(t *testing.T) {
+	assert.Equal(t, "SELECT 1 \n FROM t", StripComments("SELECT 1 -- one\n/* x */FROM t"))
+	assert.Equal(t, "SELECT '--not a comment'", StripComments("SELECT '--not a comment'"))
+}
@@ -0,0 +1,203 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// TableStats describes the size and maintenance state of a table
+type TableStats struct {
+	Table         string      `json:"table"`
+	EstimatedRows int64       `json:"estimated_rows"` // From planner statistics, -1 if never analyzed
+	TotalSize     string      `json:"total_size"`
+	TableSize     string      `json:"table_size"`
+	IndexSize     string      `json:"index_size"`
+	DeadRows      int64       `json:"dead_rows,omitempty"`
+	LastVacuum    string      `json:"last_vacuum,omitempty"`
+	LastAnalyze   string      `json:"last_analyze,omitempty"`
+	Hypertable    *Hypertable `json:"hypertable,omitempty"`
+	Suggestions   []string    `json:"suggestions,omitempty"`
+}
+
+// Hypertable is the TimescaleDB state of a hypertable
+type Hypertable struct {
+	Chunks             int64  `json:"chunks"`
+	CompressedChunks   int64  `json:"compressed_chunks"`
+	CompressionEnabled bool   `json:"compression_enabled"`
+	CompressAfter      string `json:"compress_after,omitempty"` // Compression policy, empty without one
+	Retention          string `json:"retention,omitempty"`      // Retention policy, empty without one
+	Dimensions         string `json:"dimensions"`
+	TotalSize          string `json:"total_size"`
+}
+
+// MaxHypertableChunks is the chunk count above which a larger chunk interval is suggested
+const MaxHypertableChunks = 1000
+
+// sqlLiteral quotes a value as a SQL string literal
+func sqlLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// splitTableName splits an optionally schema-qualified table name
+func splitTableName(table string) (string, string) {
+	table = unquoteIdentifier(table)
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[:i], table[i+1:]
+	}
+	return "", table
+}
+
+// BuildTableStatsQuery returns the query reading the size and maintenance
+// state of a table: estimated rows, total, table and index bytes, dead rows,
+// last vacuum and last analyze
+func BuildTableStatsQuery(dialect, table string) (string, error) {
+	schema, name := splitTableName(table)
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return fmt.Sprintf(`SELECT c.reltuples::bigint, pg_total_relation_size(c.oid), pg_relation_size(c.oid), pg_indexes_size(c.oid),
+	COALESCE(s.n_dead_tup, 0),
+	COALESCE(GREATEST(s.last_vacuum, s.last_autovacuum)::text, ''),
+	COALESCE(GREATEST(s.last_analyze, s.last_autoanalyze)::text, '')
+FROM pg_class c
+LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+WHERE c.oid = to_regclass(%s)`, sqlLiteral(quotedPostgresName(schema, name))), nil
+	case "mysql":
+		schemaCondition := "DATABASE()"
+		if schema != "" {
+			schemaCondition = sqlLiteral(schema)
+		}
+		return fmt.Sprintf(`SELECT TABLE_ROWS, DATA_LENGTH + INDEX_LENGTH, DATA_LENGTH, INDEX_LENGTH, 0, '', ''
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = %s AND TABLE_NAME = %s`, schemaCondition, sqlLiteral(name)), nil
+	case "clickhouse":
+		// Sizes are summed over the active parts; ClickHouse has no dead rows or vacuum
+		databaseCondition := "currentDatabase()"
+		if schema != "" {
+			databaseCondition = sqlLiteral(schema)
+		}
+		return fmt.Sprintf(`SELECT coalesce(t.total_rows, p.rows), p.bytes_on_disk, p.data_compressed_bytes, p.primary_key_bytes_in_memory, 0, '', ''
+FROM system.tables t
+LEFT JOIN (
+	SELECT database, table, sum(rows) AS rows, sum(bytes_on_disk) AS bytes_on_disk,
+		sum(data_compressed_bytes) AS data_compressed_bytes, sum(primary_key_bytes_in_memory) AS primary_key_bytes_in_memory
+	FROM system.parts
+	WHERE active
+	GROUP BY database, table
+) p ON p.database = t.database AND p.table = t.name
+WHERE t.database = %s AND t.name = %s`, databaseCondition, sqlLiteral(name)), nil
+	default:
+		return "", fmt.Errorf("table statistics are not supported for %s databases", dialect)
+	}
+}
+
+// quotedPostgresName quotes a table name for to_regclass
+func quotedPostgresName(schema, name string) string {
+	quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	if schema != "" {
+		quoted = `"` + strings.ReplaceAll(schema, `"`, `""`) + `".` + quoted
+	}
+	return quoted
+}
+
+// TableStatsFromResult converts a table statistics query result, or returns
+// an error when the table was not found
+func TableStatsFromResult(table string, result *models.QueryResult) (*TableStats, error) {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) < 7 || result.Rows[0][0] == nil {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	row := result.Rows[0]
+	return &TableStats{
+		Table:         table,
+		EstimatedRows: int64(toFloat(cellString(row[0]))),
+		TotalSize:     SizeField(int64(toFloat(cellString(row[1])))),
+		TableSize:     SizeField(int64(toFloat(cellString(row[2])))),
+		IndexSize:     SizeField(int64(toFloat(cellString(row[3])))),
+		DeadRows:      int64(toFloat(cellString(row[4]))),
+		LastVacuum:    fmt.Sprintf("%v", cellString(row[5])),
+		LastAnalyze:   fmt.Sprintf("%v", cellString(row[6])),
+	}, nil
+}
+
+// TimescaleInstalledQuery reports whether the TimescaleDB extension is installed
+const TimescaleInstalledQuery = `SELECT count(*) FROM pg_extension WHERE extname = 'timescaledb'`
+
+// BuildHypertableQuery returns the query reading the TimescaleDB state of a
+// table; it returns no rows when the table is not a hypertable
+func BuildHypertableQuery(table string) string {
+	schema, name := splitTableName(table)
+	schemaCondition := ""
+	if schema != "" {
+		schemaCondition = " AND h.hypertable_schema = " + sqlLiteral(schema)
+	}
+	return `SELECT h.num_chunks, h.compression_enabled,
+	(SELECT count(*) FROM timescaledb_information.chunks c
+		WHERE c.hypertable_schema = h.hypertable_schema AND c.hypertable_name = h.hypertable_name AND c.is_compressed),
+	COALESCE((SELECT j.config->>'compress_after' FROM timescaledb_information.jobs j
+		WHERE j.hypertable_schema = h.hypertable_schema AND j.hypertable_name = h.hypertable_name AND j.proc_name = 'policy_compression' LIMIT 1), ''),
+	COALESCE((SELECT j.config->>'drop_after' FROM timescaledb_information.jobs j
+		WHERE j.hypertable_schema = h.hypertable_schema AND j.hypertable_name = h.hypertable_name AND j.proc_name = 'policy_retention' LIMIT 1), ''),
+	COALESCE((SELECT string_agg(d.column_name || COALESCE(' every ' || d.time_interval::text, ''), ', ') FROM timescaledb_information.dimensions d
+		WHERE d.hypertable_schema = h.hypertable_schema AND d.hypertable_name = h.hypertable_name), ''),
+	hypertable_size(format('%I.%I', h.hypertable_schema, h.hypertable_name)::regclass)
+FROM timescaledb_information.hypertables h
+WHERE h.hypertable_name = ` + sqlLiteral(name) + schemaCondition
+}
+
+// HypertableFromResult converts a hypertable query result, returning nil when
+// the table is not a hypertable
+func HypertableFromResult(result *models.QueryResult) *Hypertable {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) < 7 {
+		return nil
+	}
+	row := result.Rows[0]
+	return &Hypertable{
+		Chunks:             int64(toFloat(cellString(row[0]))),
+		CompressionEnabled: isTrue(cellString(row[1])),
+		CompressedChunks:   int64(toFloat(cellString(row[2]))),
+		CompressAfter:      fmt.Sprintf("%v", cellString(row[3])),
+		Retention:          fmt.Sprintf("%v", cellString(row[4])),
+		Dimensions:         fmt.Sprintf("%v", cellString(row[5])),
+		TotalSize:          SizeField(int64(toFloat(cellString(row[6])))),
+	}
+}
+
+// Suggestions returns TimescaleDB maintenance advice for a hypertable. Data is
+// already partitioned into chunks, so the advice is about compressing and
+// dropping chunks rather than partitioning.
+func (h *Hypertable) Suggestions(table string) []string {
+	var suggestions []string
+	switch {
+	case !h.CompressionEnabled:
+		suggestions = append(suggestions, fmt.Sprintf("Compression is off. Enable it with ALTER TABLE %s SET (timescaledb.compress, timescaledb.compress_segmentby = '<column you filter by>'); "+
+			"then SELECT add_compression_policy('%s', INTERVAL '7 days'); to compress chunks once they stop changing.", table, table))
+	case h.CompressAfter == "":
+		suggestions = append(suggestions, fmt.Sprintf("Compression is enabled but no policy compresses chunks (%d of %d compressed). "+
+			"Add SELECT add_compression_policy('%s', INTERVAL '7 days');", h.CompressedChunks, h.Chunks, table))
+	}
+	if h.Retention == "" {
+		suggestions = append(suggestions, fmt.Sprintf("No retention policy. If old rows can be dropped, SELECT add_retention_policy('%s', INTERVAL '90 days'); "+
+			"drops whole chunks, which is far cheaper than DELETE.", table))
+	}
+	if h.Chunks > MaxHypertableChunks {
+		suggestions = append(suggestions, fmt.Sprintf("%d chunks slow down planning. Use a larger interval for new chunks with SELECT set_chunk_time_interval('%s', INTERVAL '7 days');", h.Chunks, table))
+	}
+	return suggestions
+}
+
+// FormatBytes renders a byte count such as 1536 as 1.5 kB
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	value := float64(bytes)
+	for _, suffix := range []string{"kB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit || suffix == "TB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
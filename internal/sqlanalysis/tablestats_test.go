@@ -0,0 +1,68 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTableStatsQuery(t *testing.T) {
+	query, err := BuildTableStatsQuery("postgresql", "metrics.cpu")
+	require.NoError(t, err)
+	assert.Contains(t, query, `to_regclass('"metrics"."cpu"')`)
+
+	query, err = BuildTableStatsQuery("mysql", "orders")
+	require.NoError(t, err)
+	assert.Contains(t, query, "TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'orders'")
+
+	query, err = BuildTableStatsQuery("clickhouse", "logs.events")
+	require.NoError(t, err)
+	assert.Contains(t, query, "FROM system.parts")
+	assert.Contains(t, query, "t.database = 'logs' AND t.name = 'events'")
+
+	_, err = BuildTableStatsQuery("sqlite", "orders")
+	assert.Error(t, err)
+}
+
+func TestTableStatsFromResult(t *testing.T) {
+	_, err := TableStatsFromResult("missing", &models.QueryResult{})
+	assert.ErrorContains(t, err, "not found")
+
+	stats, err := TableStatsFromResult("orders", &models.QueryResult{Rows: [][]interface{}{
+		{int64(1200), int64(3 * 1024 * 1024), int64(2 * 1024 * 1024), int64(1024 * 1024), int64(15), "2026-01-02", ""},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), stats.EstimatedRows)
+	assert.Equal(t, "3.0 MB", stats.TotalSize)
+	assert.Equal(t, int64(15), stats.DeadRows)
+}
+
+func TestHypertableSuggestions(t *testing.T) {
+	h := HypertableFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{int64(1500), false, int64(0), "", "", "time every 1 day", int64(1 << 30)},
+	}})
+	require.NotNil(t, h)
+	assert.Equal(t, "1.0 GB", h.TotalSize)
+
+	suggestions := h.Suggestions("metrics")
+	require.Len(t, suggestions, 3)
+	assert.Contains(t, suggestions[0], "timescaledb.compress")
+	assert.Contains(t, suggestions[1], "add_retention_policy('metrics'")
+	assert.Contains(t, suggestions[2], "set_chunk_time_interval")
+
+	h = &Hypertable{Chunks: 30, CompressionEnabled: true, CompressAfter: "7 days", Retention: "90 days"}
+	assert.Empty(t, h.Suggestions("metrics"))
+	h.CompressAfter = ""
+	assert.Contains(t, h.Suggestions("metrics")[0], "add_compression_policy")
+
+	assert.Nil(t, HypertableFromResult(&models.QueryResult{}))
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 bytes", FormatBytes(512))
+	assert.Equal(t, "1.5 kB", FormatBytes(1536))
+	assert.Equal(t, "2.0 TB", FormatBytes(2<<40))
+}
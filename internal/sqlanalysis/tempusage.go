@@ -0,0 +1,168 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// MaxWorkMemBytes is the largest work_mem recommended for a query; spills
+// needing more are better fixed by rewriting the query
+const MaxWorkMemBytes = 1 << 30
+
+// DefaultWorkMemBytes is the work_mem PostgreSQL uses unless configured
+const DefaultWorkMemBytes = 4 << 20
+
+// TempUsage is the temporary file usage of a PostgreSQL database since its
+// statistics were last reset
+type TempUsage struct {
+	TempFiles    int64       `json:"temp_files"`
+	TempBytes    string      `json:"temp_bytes"`
+	StatsReset   string      `json:"stats_reset,omitempty"`
+	WorkMem      string      `json:"work_mem"`
+	LogTempFiles string      `json:"log_temp_files"` // -1 disables logging of temporary files
+	Queries      []TempQuery `json:"spilling_queries"`
+	Suggestions  []string    `json:"suggestions,omitempty"`
+
+	// Set when pg_stat_statements could not be read, or hid the statements
+	// of other users
+	MissingPrivilege *Privilege `json:"missing_privilege,omitempty"`
+	HiddenQueries    int        `json:"hidden_queries,omitempty"`
+
+	tempBytes int64 // TempBytes for the suggestions, which stay humanized
+}
+
+// TempQuery is a statement that wrote temporary files
+type TempQuery struct {
+	SQL            string  `json:"sql"`
+	Calls          int64   `json:"calls"`
+	MeanMs         float64 `json:"mean_ms"`
+	TempWritten    string  `json:"temp_written"`
+	SpillPerCall   string  `json:"spill_per_call"`
+	Recommendation string  `json:"recommendation"`
+}
+
+// TempUsageQuery reads the temporary file counters and settings of the current database
+const TempUsageQuery = `SELECT temp_files, temp_bytes, COALESCE(stats_reset::text, ''),
+	current_setting('work_mem'), current_setting('log_temp_files')
+FROM pg_stat_database
+WHERE datname = current_database()`
+
+// BuildTempQueriesQuery returns the pg_stat_statements query listing the
+// statements that wrote the most temporary blocks, in bytes
+func BuildTempQueriesQuery(limit int) string {
+	if limit <= 0 {
+		limit = 10
+	}
+	return fmt.Sprintf(`SELECT query, calls, mean_exec_time, temp_blks_written * current_setting('block_size')::bigint
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database()) AND temp_blks_written > 0
+ORDER BY temp_blks_written DESC
+LIMIT %d`, limit)
+}
+
+// TempUsageFromResult converts a TempUsageQuery result
+func TempUsageFromResult(result *models.QueryResult) (*TempUsage, error) {
+	if result == nil || len(result.Rows) == 0 || len(result.Rows[0]) < 5 {
+		return nil, fmt.Errorf("no temporary file statistics for the current database")
+	}
+	row := result.Rows[0]
+	bytes := int64(toFloat(cellString(row[1])))
+	return &TempUsage{
+		TempFiles:    int64(toFloat(cellString(row[0]))),
+		TempBytes:    SizeField(bytes),
+		StatsReset:   cellText(row[2]),
+		WorkMem:      cellText(row[3]),
+		LogTempFiles: cellText(row[4]),
+		Queries:      []TempQuery{},
+		tempBytes:    bytes,
+	}, nil
+}
+
+// AddQueries attaches the spilling statements of a BuildTempQueriesQuery
+// result with a work_mem or rewrite recommendation for each, and derives the
+// database-wide suggestions
+func (u *TempUsage) AddQueries(result *models.QueryResult) {
+	if result != nil {
+		for _, row := range result.Rows {
+			if len(row) < 4 {
+				continue
+			}
+			if strings.TrimSpace(cellText(row[0])) == InsufficientPrivilege {
+				u.HiddenQueries++
+				continue
+			}
+			calls := int64(toFloat(cellString(row[1])))
+			written := int64(toFloat(cellString(row[3])))
+			perCall := written
+			if calls > 0 {
+				perCall = written / calls
+			}
+			u.Queries = append(u.Queries, TempQuery{
+				SQL:            strings.TrimSpace(cellText(row[0])),
+				Calls:          calls,
+				MeanMs:         toFloat(cellString(row[2])),
+				TempWritten:    SizeField(written),
+				SpillPerCall:   SizeField(perCall),
+				Recommendation: WorkMemRecommendation(perCall),
+			})
+		}
+	}
+	u.Suggestions = u.suggestions()
+}
+
+func (u *TempUsage) suggestions() []string {
+	var suggestions []string
+	if u.TempFiles == 0 {
+		return append(suggestions, "No temporary files were written; work_mem is large enough for the current workload.")
+	}
+	suggestions = append(suggestions, fmt.Sprintf("%d temporary files (%s) were written since %s: sorts, hashes or materializations exceeded work_mem (%s) and spilled to disk.",
+		u.TempFiles, FormatBytes(u.tempBytes), sinceText(u.StatsReset), u.WorkMem))
+	if u.LogTempFiles == "-1" {
+		suggestions = append(suggestions, "Temporary files are not logged. ALTER SYSTEM SET log_temp_files = '10MB'; SELECT pg_reload_conf(); logs each spill over 10MB with its statement.")
+	}
+	switch {
+	case u.MissingPrivilege != nil && (len(u.Queries) == 0 || u.HiddenQueries > 0):
+		what := "The spilling statements could not be read"
+		if u.HiddenQueries > 0 {
+			what = fmt.Sprintf("%d spilling statements of other users are hidden", u.HiddenQueries)
+		}
+		suggestions = append(suggestions, what+". "+u.MissingPrivilege.Explain())
+	case len(u.Queries) == 0:
+		suggestions = append(suggestions, "No statement could be matched to the spills; enable pg_stat_statements or log_temp_files to find them.")
+	}
+	if len(u.Queries) > 0 {
+		suggestions = append(suggestions, "Raise work_mem for the spilling statements only (SET LOCAL work_mem in their transaction, or ALTER ROLE ... SET work_mem for a reporting role) rather than globally: "+
+			"every sort and hash node of every connection may use that much memory.")
+	}
+	return suggestions
+}
+
+func sinceText(reset string) string {
+	if reset == "" {
+		return "the statistics were created"
+	}
+	return reset
+}
+
+// WorkMemRecommendation suggests the work_mem that keeps a spill of the given
+// size in memory. Data takes roughly twice as much space in memory as in a
+// temporary file.
+func WorkMemRecommendation(spillBytes int64) string {
+	return workMemAdvice(spillBytes*2, FormatBytes(spillBytes)+" spill")
+}
+
+// workMemAdvice suggests a power-of-two work_mem of at least needed bytes, or
+// a rewrite when that would exceed MaxWorkMemBytes
+func workMemAdvice(needed int64, what string) string {
+	if needed > MaxWorkMemBytes {
+		return fmt.Sprintf("Keeping the %s in memory needs about %s; no sensible work_mem covers that. Rewrite the query: add an index matching ORDER BY/GROUP BY, "+
+			"filter or aggregate earlier, select fewer columns, or add a LIMIT.", what, FormatBytes(needed))
+	}
+	mb := int64(4)
+	for mb<<20 < needed {
+		mb *= 2
+	}
+	return fmt.Sprintf("SET LOCAL work_mem = '%dMB' keeps the %s in memory.", mb, what)
+}
@@ -0,0 +1,44 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTempUsage(t *testing.T) {
+	usage, err := TempUsageFromResult(&models.QueryResult{Rows: [][]interface{}{
+		{int64(12), int64(3 << 30), "2026-01-01 00:00:00+00", "4MB", "-1"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "3.0 GB", usage.TempBytes)
+
+	usage.AddQueries(&models.QueryResult{Rows: [][]interface{}{
+		{"SELECT * FROM events ORDER BY created_at", int64(4), 120.5, int64(40 << 20)},
+		{"SELECT user_id, count(*) FROM clicks GROUP BY user_id", int64(1), 9000.0, []byte("2147483648")},
+	}})
+	require.Len(t, usage.Queries, 2)
+	assert.Equal(t, "10.0 MB", usage.Queries[0].SpillPerCall)
+	assert.Contains(t, usage.Queries[0].Recommendation, "'32MB'")
+	assert.Contains(t, usage.Queries[1].Recommendation, "Rewrite the query")
+	require.Len(t, usage.Suggestions, 3)
+	assert.Contains(t, usage.Suggestions[1], "log_temp_files")
+}
+
+func TestTempUsage_NoSpills(t *testing.T) {
+	usage, err := TempUsageFromResult(&models.QueryResult{Rows: [][]interface{}{{int64(0), int64(0), "", "4MB", "0"}}})
+	require.NoError(t, err)
+	usage.AddQueries(nil)
+	assert.Equal(t, []string{"No temporary files were written; work_mem is large enough for the current workload."}, usage.Suggestions)
+
+	_, err = TempUsageFromResult(&models.QueryResult{})
+	assert.Error(t, err)
+}
+
+func TestWorkMemRecommendation(t *testing.T) {
+	assert.Equal(t, "SET LOCAL work_mem = '4MB' keeps the 100 bytes spill in memory.", WorkMemRecommendation(100))
+	assert.Contains(t, WorkMemRecommendation(MaxWorkMemBytes), "Rewrite the query")
+}
@@ -0,0 +1,370 @@
+package sqlanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTenantColumn is the tenant column assumed when none is configured
+const DefaultTenantColumn = "tenant_id"
+
+// TenantExempt marks a table as shared between tenants in TenantPolicy.Tables
+const TenantExempt = "-"
+
+// TenantPolicy describes how rows are scoped to a tenant. Tables that have the
+// default column are scoped by it; Tables overrides that per table with another
+// column, a predicate containing {tenant}, or TenantExempt.
+type TenantPolicy struct {
+	Column string            `json:"column"`
+	Tables map[string]string `json:"tables,omitempty"`
+}
+
+// TenantRule is how one table is scoped
+type TenantRule struct {
+	Column    string // Tenant column, empty for predicate rules
+	Predicate string // Predicate containing {tenant}, empty for column rules
+}
+
+// DefaultTenantPolicy returns the policy used when none has been saved
+func DefaultTenantPolicy() TenantPolicy {
+	return TenantPolicy{Column: DefaultTenantColumn}
+}
+
+// tenantPolicyFile returns the path of the tenant policy file
+func tenantPolicyFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "tenant.json")
+}
+
+// LoadTenantPolicy reads the tenant policy from ~/.dbsage/tenant.json, falling
+// back to the default policy when the file does not exist
+func LoadTenantPolicy() (TenantPolicy, error) {
+	data, err := os.ReadFile(tenantPolicyFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultTenantPolicy(), nil
+		}
+		return DefaultTenantPolicy(), err
+	}
+
+	var p TenantPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return DefaultTenantPolicy(), fmt.Errorf("failed to parse tenant policy: %w", err)
+	}
+	if p.Column == "" {
+		p.Column = DefaultTenantColumn
+	}
+	return p, nil
+}
+
+// SaveTenantPolicy writes the tenant policy to ~/.dbsage/tenant.json
+func SaveTenantPolicy(p TenantPolicy) error {
+	path := tenantPolicyFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Rule returns how a table is scoped. hasColumn reports whether the table has a
+// column; tables without the default column are not scoped unless configured.
+func (p TenantPolicy) Rule(table string, hasColumn func(table, column string) bool) (TenantRule, bool) {
+	name := strings.ToLower(unquoteIdentifier(table))
+	override, ok := p.Tables[name]
+	if !ok {
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			override, ok = p.Tables[name[i+1:]]
+		}
+	}
+	if ok {
+		switch {
+		case override == TenantExempt:
+			return TenantRule{}, false
+		case strings.Contains(override, "{tenant}"):
+			return TenantRule{Predicate: override}, true
+		default:
+			return TenantRule{Column: override}, true
+		}
+	}
+
+	column := p.Column
+	if column == "" {
+		column = DefaultTenantColumn
+	}
+	if hasColumn != nil && hasColumn(table, column) {
+		return TenantRule{Column: column}, true
+	}
+	return TenantRule{}, false
+}
+
+// Describe lists the policy for display
+func (p TenantPolicy) Describe() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Tenant column: %s (tables without it are not scoped)", p.Column))
+	names := make([]string, 0, len(p.Tables))
+	for name := range p.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule := p.Tables[name]
+		if rule == TenantExempt {
+			rule = "shared, not scoped"
+		}
+		b.WriteString(fmt.Sprintf("\n- %s: %s", name, rule))
+	}
+	return b.String()
+}
+
+// TenantLiteral renders a tenant value as a SQL literal
+func TenantLiteral(tenant string) string {
+	if _, err := strconv.ParseInt(tenant, 10, 64); err == nil {
+		return tenant
+	}
+	return "'" + strings.ReplaceAll(tenant, "'", "''") + "'"
+}
+
+// Condition returns the condition that scopes a table, referenced as qualifier
+// (the table name or its alias, empty for none), to the tenant
+func (r TenantRule) Condition(qualifier, tenant string) string {
+	if r.Predicate != "" {
+		return strings.ReplaceAll(r.Predicate, "{tenant}", TenantLiteral(tenant))
+	}
+	if qualifier != "" {
+		return qualifier + "." + r.Column + " = " + TenantLiteral(tenant)
+	}
+	return r.Column + " = " + TenantLiteral(tenant)
+}
+
+var (
+	// tableReferencePattern matches the table after FROM, JOIN, UPDATE, INTO and
+	// DELETE FROM, with an optional alias
+	tableReferencePattern = regexp.MustCompile("(?i)\\b(FROM|JOIN|UPDATE|INTO)\\s+((?:[a-z_][a-z0-9_$]*|\"[^\"]+\"|`[^`]+`)(?:\\.(?:[a-z_][a-z0-9_$]*|\"[^\"]+\"|`[^`]+`))?)(?:\\s+(?:AS\\s+)?([a-z_][a-z0-9_]*))?")
+	insertPattern         = regexp.MustCompile(`(?i)^\s*INSERT\s+INTO\s+(\S+?)\s*(\(|\s|$)`)
+	insertColumnsPattern  = regexp.MustCompile(`(?i)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)`)
+	whitespacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// aliasKeywords are words that can follow a table name but are not aliases
+var aliasKeywords = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true,
+	"cross": true, "natural": true, "on": true, "using": true, "group": true, "order": true,
+	"limit": true, "offset": true, "set": true, "values": true, "select": true, "union": true,
+	"except": true, "intersect": true, "having": true, "window": true, "returning": true,
+	"default": true, "outer": true, "lateral": true, "for": true, "fetch": true,
+}
+
+// TableReference is a table used by a statement
+type TableReference struct {
+	Table string
+	Alias string // Empty when the table has no alias
+}
+
+// TableReferences returns the tables a statement reads or writes, in order
+func TableReferences(sql string) []TableReference {
+	clean := stringLiteralPattern.ReplaceAllString(StripComments(sql), "''")
+	var refs []TableReference
+	seen := make(map[string]bool)
+	for _, m := range tableReferencePattern.FindAllStringSubmatch(clean, -1) {
+		ref := TableReference{Table: unquoteIdentifier(m[2])}
+		if alias := strings.ToLower(m[3]); alias != "" && !aliasKeywords[alias] {
+			ref.Alias = m[3]
+		}
+		key := strings.ToLower(ref.Table + " " + ref.Alias)
+		if !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// CheckTenantScope returns the problems that make a statement reach beyond the
+// tenant: every scoped table it uses must be filtered by its tenant condition,
+// and inserts into scoped tables must set the tenant column. The condition
+// counts only as a top-level AND operand of a WHERE or ON clause, outside
+// literals and comments, so an OR next to it does not pass. It is a textual
+// check that catches forgotten predicates, not a security boundary.
+func CheckTenantScope(sql, tenant string, p TenantPolicy, hasColumn func(table, column string) bool) []string {
+	clean := StripComments(sql)
+	normalized := strings.ToLower(whitespacePattern.ReplaceAllString(clean, " "))
+	target := insertTarget(clean)
+	// A condition counts when both readings of backslashes in literals find it
+	plain, escaped := filterConjuncts(sql, false), filterConjuncts(sql, true)
+
+	var problems []string
+	for _, ref := range TableReferences(clean) {
+		rule, scoped := p.Rule(ref.Table, hasColumn)
+		if !scoped {
+			continue
+		}
+
+		if target != "" && rule.Column != "" && strings.EqualFold(ref.Table, target) {
+			if !insertSetsColumn(clean, rule.Column) || !strings.Contains(normalized, strings.ToLower(TenantLiteral(tenant))) {
+				problems = append(problems, fmt.Sprintf("insert into %s must set %s to %s", ref.Table, rule.Column, TenantLiteral(tenant)))
+			}
+			continue
+		}
+
+		if !hasTenantCondition(plain, ref, rule, tenant) || !hasTenantCondition(escaped, ref, rule, tenant) {
+			qualifier := ref.Alias
+			if qualifier == "" {
+				qualifier = unqualifiedTable(ref.Table)
+			}
+			problems = append(problems, fmt.Sprintf("%s is not filtered by tenant; add %s", ref.Table, rule.Condition(qualifier, tenant)))
+		}
+	}
+	return problems
+}
+
+// hasTenantCondition reports whether one of the filter conditions of a
+// statement, as returned by filterConjuncts, filters a table by its rule
+func hasTenantCondition(conjuncts []string, ref TableReference, rule TenantRule, tenant string) bool {
+	if rule.Predicate != "" {
+		want := strings.ToLower(whitespacePattern.ReplaceAllString(rule.Condition("", tenant), " "))
+		for _, conjunct := range conjuncts {
+			if conjunct == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	qualifiers := []string{regexp.QuoteMeta(strings.ToLower(unqualifiedTable(ref.Table)))}
+	if ref.Alias != "" {
+		qualifiers = append(qualifiers, regexp.QuoteMeta(strings.ToLower(ref.Alias)))
+	}
+	literal := regexp.QuoteMeta(strings.ToLower(TenantLiteral(tenant)))
+	if !strings.HasPrefix(literal, "'") {
+		literal = "'?" + literal + "'?"
+	}
+	column := regexp.QuoteMeta(strings.ToLower(rule.Column))
+	pattern := regexp.MustCompile(fmt.Sprintf("^(((%s)|\"(%s)\"|`(%s)`)\\.)?[\"`]?%s[\"`]?\\s*(=\\s*%s|in\\s*\\(\\s*%s\\s*\\))$",
+		strings.Join(qualifiers, "|"), strings.Join(qualifiers, "|"), strings.Join(qualifiers, "|"), column, literal, literal))
+	for _, conjunct := range conjuncts {
+		if pattern.MatchString(conjunct) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterEnds are the words that end a WHERE or ON clause at its own depth
+var filterEnds = map[string]bool{
+	"group": true, "having": true, "order": true, "limit": true, "offset": true, "window": true,
+	"fetch": true, "for": true, "qualify": true, "union": true, "except": true, "intersect": true,
+	"minus": true, "returning": true, "where": true, "on": true, "using": true,
+}
+
+// filterConjuncts returns the conditions that the WHERE and ON clauses of a
+// statement require of every row: the top-level AND operands of each clause,
+// lowercased with whitespace collapsed, looking inside parentheses around a
+// whole operand. A clause with a top-level OR requires nothing. Backslashes
+// escape quotes in every string literal when backslash is set.
+func filterConjuncts(sql string, backslash bool) []string {
+	runes := []rune(stripComments(sql, backslash))
+	tokens := tokenize(runes, backslash)
+	var conjuncts []string
+	for i, tok := range tokens {
+		if !tok.isWord("WHERE") && !tok.isWord("ON") {
+			continue
+		}
+		end, depth := i+1, 0
+		for ; end < len(tokens); end++ {
+			t := tokens[end]
+			if t.is("(") {
+				depth++
+			} else if t.is(")") {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if depth == 0 && (t.is(";") || t.kind == tokenWord && (filterEnds[strings.ToLower(t.text)] || joinWords[strings.ToLower(t.text)])) {
+				break
+			}
+		}
+		conjuncts = appendConjuncts(conjuncts, runes, tokens[i+1:end])
+	}
+	return conjuncts
+}
+
+// appendConjuncts appends the top-level AND operands of a condition
+func appendConjuncts(conjuncts []string, runes []rune, tokens []sqlToken) []string {
+	if len(splitTopLevel(tokens, func(tok sqlToken) bool { return tok.isWord("OR") })) > 1 {
+		return conjuncts
+	}
+	between := false // The next AND belongs to BETWEEN
+	parts := splitTopLevel(tokens, func(tok sqlToken) bool {
+		switch {
+		case tok.isWord("BETWEEN"):
+			between = true
+		case tok.isWord("AND") && between:
+			between = false
+		case tok.isWord("AND"):
+			return true
+		}
+		return false
+	})
+	for _, part := range parts {
+		switch {
+		case len(part) == 0:
+		case part[0].is("(") && matchParen(part, 0) == len(part)-1:
+			conjuncts = appendConjuncts(conjuncts, runes, part[1:len(part)-1])
+		default:
+			text := string(runes[part[0].start:part[len(part)-1].end])
+			conjuncts = append(conjuncts, strings.ToLower(whitespacePattern.ReplaceAllString(text, " ")))
+		}
+	}
+	return conjuncts
+}
+
+// insertTarget returns the table of an INSERT statement
+func insertTarget(sql string) string {
+	m := insertPattern.FindStringSubmatch(sql)
+	if m == nil {
+		return ""
+	}
+	return unquoteIdentifier(m[1])
+}
+
+// insertSetsColumn reports whether an INSERT lists a column
+func insertSetsColumn(sql, column string) bool {
+	m := insertColumnsPattern.FindStringSubmatch(sql)
+	if m == nil {
+		return false
+	}
+	for _, name := range strings.Split(m[1], ",") {
+		if strings.EqualFold(unquoteIdentifier(strings.TrimSpace(name)), column) {
+			return true
+		}
+	}
+	return false
+}
+
+// unquoteIdentifier removes identifier quotes from each part of a possibly qualified name
+func unquoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(part, "\"`")
+	}
+	return strings.Join(parts, ".")
+}
+
+// unqualifiedTable returns a table name without its schema
+func unqualifiedTable(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
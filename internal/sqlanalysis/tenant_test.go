@@ -0,0 +1,89 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantTables have the default tenant column
+var tenantTables = map[string]bool{"orders": true, "customers": true, "invoices": true}
+
+func hasTenantColumn(table, column string) bool {
+	return column == DefaultTenantColumn && tenantTables[unqualifiedTable(table)]
+}
+
+func TestTableReferences(t *testing.T) {
+	refs := TableReferences(`SELECT o.id FROM public.orders o
+		JOIN "customers" AS c ON c.id = o.customer_id
+		LEFT JOIN countries ON countries.code = c.country -- FROM ignored
+		WHERE o.note <> 'from nowhere'`)
+	assert.Equal(t, []TableReference{
+		{Table: "public.orders", Alias: "o"},
+		{Table: "customers", Alias: "c"},
+		{Table: "countries"},
+	}, refs)
+}
+
+func TestCheckTenantScope(t *testing.T) {
+	policy := TenantPolicy{
+		Column: DefaultTenantColumn,
+		Tables: map[string]string{
+			"invoices": TenantExempt,
+			"payments": "account_id IN (SELECT id FROM accounts WHERE tenant_id = {tenant})",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		problems []string
+	}{
+		{"filtered", "SELECT * FROM orders WHERE tenant_id = 42", nil},
+		{"filtered by alias", "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.tenant_id = 42 AND c.tenant_id IN (42)", nil},
+		{"quoted value", "SELECT * FROM orders WHERE orders.tenant_id = '42'", nil},
+		{"missing", "SELECT * FROM orders", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"one of two joined tables", "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.tenant_id = 42",
+			[]string{"customers is not filtered by tenant; add c.tenant_id = 42"}},
+		{"wrong tenant", "SELECT * FROM orders WHERE tenant_id = 421", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"other alias", "SELECT * FROM orders o JOIN customers c ON true WHERE c.tenant_id = 42",
+			[]string{"orders is not filtered by tenant; add o.tenant_id = 42"}},
+		{"unscoped and exempt tables", "SELECT * FROM countries JOIN invoices ON true", nil},
+		{"predicate rule", "SELECT * FROM payments WHERE account_id IN (SELECT id FROM accounts WHERE tenant_id = 42)", nil},
+		{"predicate rule missing", "SELECT * FROM payments", []string{"payments is not filtered by tenant; add account_id IN (SELECT id FROM accounts WHERE tenant_id = 42)"}},
+		{"update", "UPDATE orders SET status = 'paid' WHERE id = 7 AND tenant_id = 42", nil},
+		{"delete", "DELETE FROM orders WHERE id = 7", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"insert", "INSERT INTO orders (id, tenant_id) VALUES (7, 42)", nil},
+		{"insert without tenant", "INSERT INTO orders (id) VALUES (7)", []string{"insert into orders must set tenant_id to 42"}},
+		{"comment does not count", "SELECT * FROM orders -- WHERE tenant_id = 42", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"or widens the filter", "SELECT * FROM orders WHERE tenant_id = 42 OR 1=1", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"or inside parentheses", "SELECT * FROM orders WHERE (tenant_id = 42 OR 1=1) AND id = 7", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"literal does not count", "SELECT * FROM orders WHERE note <> 'x tenant_id = 42'", []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"escaped quote in literal", `SELECT * FROM orders WHERE note = 'x\' AND tenant_id = 42 AND note <> ''`, []string{"orders is not filtered by tenant; add orders.tenant_id = 42"}},
+		{"parenthesized conjunct", "SELECT * FROM orders WHERE (id BETWEEN 1 AND 9 AND (tenant_id = 42)) ORDER BY id", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, CheckTenantScope(tt.sql, "42", policy, hasTenantColumn))
+		})
+	}
+
+	// Text tenants are compared as quoted literals
+	assert.Empty(t, CheckTenantScope("SELECT * FROM orders WHERE tenant_id = 'acme'", "acme", policy, hasTenantColumn))
+	assert.NotEmpty(t, CheckTenantScope("SELECT * FROM orders WHERE tenant_id = acme", "acme", policy, hasTenantColumn))
+}
+
+func TestTenantPolicyRule(t *testing.T) {
+	policy := TenantPolicy{Column: "org_id", Tables: map[string]string{"events": "workspace_id"}}
+
+	rule, scoped := policy.Rule("analytics.events", nil)
+	assert.True(t, scoped)
+	assert.Equal(t, "workspace_id", rule.Column)
+
+	_, scoped = policy.Rule("orders", func(table, column string) bool { return false })
+	assert.False(t, scoped)
+
+	rule, scoped = policy.Rule("orders", func(table, column string) bool { return column == "org_id" })
+	assert.True(t, scoped)
+	assert.Equal(t, "o.org_id = 'x''y'", rule.Condition("o", "x'y"))
+}
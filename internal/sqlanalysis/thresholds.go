@@ -0,0 +1,121 @@
+package sqlanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Analysis profile names
+const (
+	ProfileOLTP = "oltp"
+	ProfileOLAP = "olap"
+)
+
+// Thresholds controls when plan and replay analysis flag a statement
+type Thresholds struct {
+	Profile        string  `json:"profile"`
+	LargeTableRows float64 `json:"large_table_rows"` // Full scans above this many rows are flagged
+	NestedLoopRows float64 `json:"nested_loop_rows"` // Nested loops producing more row combinations are flagged
+	SlowQueryMs    float64 `json:"slow_query_ms"`    // Statements slower than this are flagged
+}
+
+// profilePresets holds the built-in threshold presets
+var profilePresets = map[string]Thresholds{
+	// Transactional workloads: short queries, scans of medium tables are already suspicious
+	ProfileOLTP: {
+		Profile:        ProfileOLTP,
+		LargeTableRows: 10000,
+		NestedLoopRows: 1000000,
+		SlowQueryMs:    1000,
+	},
+	// Analytical workloads: large scans are expected, only flag extreme cases
+	ProfileOLAP: {
+		Profile:        ProfileOLAP,
+		LargeTableRows: 10000000,
+		NestedLoopRows: 1000000000,
+		SlowQueryMs:    60000,
+	},
+}
+
+// GetProfileNames returns the names of the built-in analysis profiles
+func GetProfileNames() []string {
+	return []string{ProfileOLTP, ProfileOLAP}
+}
+
+// ThresholdsForProfile returns the preset thresholds for a profile name
+func ThresholdsForProfile(profile string) (Thresholds, error) {
+	t, exists := profilePresets[strings.ToLower(profile)]
+	if !exists {
+		return Thresholds{}, fmt.Errorf("unknown analysis profile: %s (available: %s)", profile, strings.Join(GetProfileNames(), ", "))
+	}
+	return t, nil
+}
+
+// DefaultThresholds returns the OLTP preset
+func DefaultThresholds() Thresholds {
+	return profilePresets[ProfileOLTP]
+}
+
+// thresholdsFile returns the path of the analysis settings file
+func thresholdsFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "analysis.json")
+}
+
+// LoadThresholds reads the analysis settings from ~/.dbsage/analysis.json.
+// Values missing from the file fall back to the selected profile's preset,
+// and the OLTP preset is used when the file does not exist.
+func LoadThresholds() (Thresholds, error) {
+	data, err := os.ReadFile(thresholdsFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultThresholds(), nil
+		}
+		return DefaultThresholds(), err
+	}
+	return parseThresholds(data)
+}
+
+// parseThresholds merges stored settings over the preset of their profile
+func parseThresholds(data []byte) (Thresholds, error) {
+	var stored Thresholds
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return DefaultThresholds(), fmt.Errorf("failed to parse analysis settings: %w", err)
+	}
+
+	t := DefaultThresholds()
+	if stored.Profile != "" {
+		preset, err := ThresholdsForProfile(stored.Profile)
+		if err != nil {
+			return DefaultThresholds(), err
+		}
+		t = preset
+	}
+	if stored.LargeTableRows > 0 {
+		t.LargeTableRows = stored.LargeTableRows
+	}
+	if stored.NestedLoopRows > 0 {
+		t.NestedLoopRows = stored.NestedLoopRows
+	}
+	if stored.SlowQueryMs > 0 {
+		t.SlowQueryMs = stored.SlowQueryMs
+	}
+	return t, nil
+}
+
+// SaveThresholds writes the analysis settings to ~/.dbsage/analysis.json
+func SaveThresholds(t Thresholds) error {
+	path := thresholdsFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,53 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThresholdsForProfile(t *testing.T) {
+	oltp, err := ThresholdsForProfile("OLTP")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultThresholds(), oltp)
+
+	olap, err := ThresholdsForProfile("olap")
+	require.NoError(t, err)
+	assert.Greater(t, olap.LargeTableRows, oltp.LargeTableRows)
+
+	_, err = ThresholdsForProfile("batch")
+	assert.Error(t, err)
+}
+
+func TestParseThresholds(t *testing.T) {
+	th, err := parseThresholds([]byte(`{"profile": "olap", "slow_query_ms": 5000}`))
+	require.NoError(t, err)
+	assert.Equal(t, ProfileOLAP, th.Profile)
+	assert.Equal(t, float64(5000), th.SlowQueryMs)
+	assert.Equal(t, float64(10000000), th.LargeTableRows)
+
+	th, err = parseThresholds([]byte(`{"large_table_rows": 500}`))
+	require.NoError(t, err)
+	assert.Equal(t, ProfileOLTP, th.Profile)
+	assert.Equal(t, float64(500), th.LargeTableRows)
+
+	_, err = parseThresholds([]byte(`{"profile": "unknown"}`))
+	assert.Error(t, err)
+}
+
+func TestAnalyzePlanWithThresholds_OLAP(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "events", "Total Cost": 900, "Plan Rows": 500000}}]`
+	result := &models.QueryResult{Rows: [][]interface{}{{plan}}}
+
+	oltp, err := AnalyzePlan("postgresql", result)
+	require.NoError(t, err)
+	assert.Len(t, oltp.Warnings, 1)
+
+	olapThresholds, _ := ThresholdsForProfile(ProfileOLAP)
+	olap, err := AnalyzePlanWithThresholds("postgresql", result, olapThresholds)
+	require.NoError(t, err)
+	assert.Empty(t, olap.Warnings)
+}
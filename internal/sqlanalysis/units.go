@@ -0,0 +1,115 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// humanize selects readable units for sizes and durations, e.g. 1.5 GB and
+// 2.31 s, over raw byte counts and milliseconds. DBSAGE_HUMANIZE=off starts
+// with raw values.
+var humanize atomic.Bool
+
+func init() {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("DBSAGE_HUMANIZE"))) {
+	case "off", "0", "false", "no":
+		humanize.Store(false)
+	default:
+		humanize.Store(true)
+	}
+}
+
+// SetHumanize switches between readable units and raw values
+func SetHumanize(on bool) {
+	humanize.Store(on)
+}
+
+// Humanized reports whether sizes and durations are shown in readable units
+func Humanized() bool {
+	return humanize.Load()
+}
+
+// SizeField renders a byte count for a result field: 1.5 kB when humanized,
+// 1536 otherwise
+func SizeField(bytes int64) string {
+	if Humanized() {
+		return FormatBytes(bytes)
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// FormatMillis renders a duration in milliseconds such as 2310 as 2.31 s.
+// Durations of a minute and longer are rounded to the second, e.g. 1h2m3s.
+func FormatMillis(ms float64) string {
+	switch {
+	case ms < 1:
+		return fmt.Sprintf("%.3f ms", ms)
+	case ms < 1000:
+		return fmt.Sprintf("%.1f ms", ms)
+	case ms < 60000:
+		return fmt.Sprintf("%.2f s", ms/1000)
+	default:
+		return (time.Duration(ms * float64(time.Millisecond))).Round(time.Second).String()
+	}
+}
+
+// DurationText renders a Go duration string such as "2.310456789s", as
+// reported with query results, in readable units when humanized and
+// unchanged otherwise
+func DurationText(duration string) string {
+	if !Humanized() {
+		return duration
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return duration
+	}
+	return FormatMillis(float64(d) / float64(time.Millisecond))
+}
+
+var (
+	// Sizes by name rather than any *_size, which is often a count such as
+	// batch_size; pg_*size matches unaliased pg_total_relation_size(...)
+	sizeColumnPattern    = regexp.MustCompile(`^((total|table|index|indexes|relation|data|toast|database|db|file)_size|size|pg_\w*size|\w*bytes)$`)
+	millisColumnPattern  = regexp.MustCompile(`_(ms|msec|millis)$`)
+	secondsColumnPattern = regexp.MustCompile(`_(sec|secs|seconds)$`)
+)
+
+// millisColumns are the pg_stat_statements and pg_stat_database columns
+// reported in milliseconds without a unit suffix
+var millisColumns = map[string]bool{
+	"total_exec_time": true, "mean_exec_time": true, "min_exec_time": true, "max_exec_time": true,
+	"stddev_exec_time": true, "total_plan_time": true, "mean_plan_time": true, "min_plan_time": true,
+	"max_plan_time": true, "stddev_plan_time": true, "total_time": true, "mean_time": true,
+	"min_time": true, "max_time": true, "stddev_time": true, "blk_read_time": true,
+	"blk_write_time": true, "active_time": true, "idle_in_transaction_time": true, "session_time": true,
+}
+
+// HumanizeCell renders a numeric result cell in readable units when its
+// column holds a size or a duration by name, e.g. total_size, temp_bytes or
+// mean_exec_time. Other cells, and every cell when not humanized, are
+// returned unchanged.
+func HumanizeCell(column, value string) string {
+	if !Humanized() {
+		return value
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return value
+	}
+	name := strings.ToLower(column)
+	switch {
+	case sizeColumnPattern.MatchString(name):
+		return FormatBytes(int64(number))
+	case millisColumnPattern.MatchString(name) || millisColumns[name]:
+		return FormatMillis(number)
+	case secondsColumnPattern.MatchString(name):
+		return FormatMillis(number * 1000)
+	}
+	return value
+}
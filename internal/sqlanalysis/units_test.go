@@ -0,0 +1,39 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMillis(t *testing.T) {
+	assert.Equal(t, "0.042 ms", FormatMillis(0.042))
+	assert.Equal(t, "12.5 ms", FormatMillis(12.5))
+	assert.Equal(t, "2.31 s", FormatMillis(2310))
+	assert.Equal(t, "1h2m3s", FormatMillis(3723400))
+}
+
+func TestHumanizeCell(t *testing.T) {
+	assert.Equal(t, "1.5 GB", HumanizeCell("total_size", "1610612736"))
+	assert.Equal(t, "3.0 kB", HumanizeCell("temp_bytes", "3072"))
+	assert.Equal(t, "2.31 s", HumanizeCell("mean_exec_time", "2310"))
+	assert.Equal(t, "12.5 ms", HumanizeCell("lag_ms", "12.5"))
+	assert.Equal(t, "1m30s", HumanizeCell("wait_seconds", "90"))
+	assert.Equal(t, "500", HumanizeCell("batch_size", "500"), "counts named *_size are left alone")
+	assert.Equal(t, "8 kB", HumanizeCell("total_size", "8 kB"), "already formatted")
+
+	SetHumanize(false)
+	defer SetHumanize(true)
+	assert.Equal(t, "1610612736", HumanizeCell("total_size", "1610612736"))
+}
+
+func TestSizeAndDurationFields(t *testing.T) {
+	assert.Equal(t, "1.5 kB", SizeField(1536))
+	assert.Equal(t, "1.2 ms", DurationText("1.234567ms"))
+	assert.Equal(t, "soon", DurationText("soon"))
+
+	SetHumanize(false)
+	defer SetHumanize(true)
+	assert.Equal(t, "1536", SizeField(1536))
+	assert.Equal(t, "1.234567ms", DurationText("1.234567ms"))
+}
@@ -0,0 +1,92 @@
+package sqlanalysis
+
+import (
+	"regexp"
+	"strings"
+)
+
+// variableName matches the name of a result variable saved with /as
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// variableReference matches a $name reference to a result variable in a
+// prompt. $1 style parameters never match, as names do not start with a digit.
+var variableReference = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// IsVariableName reports whether name can name a result variable
+func IsVariableName(name string) bool {
+	return variableName.MatchString(name)
+}
+
+// PromptVariables returns the lower-case names referenced as $name in a
+// prompt, in order of first reference
+func PromptVariables(prompt string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range variableReference.FindAllStringSubmatch(prompt, -1) {
+		name := strings.ToLower(match[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// InlineVariables replaces the $name references to result variables in a
+// statement with CTEs running the SQL the results came from, so a saved result
+// can be queried like a table. variables maps lower-case names to their SQL;
+// other $name references, and those in strings, dollar quotes and comments,
+// are left alone. It returns the statement unchanged when nothing was
+// replaced, and the names replaced.
+func InlineVariables(sql string, variables map[string]string) (string, []string) {
+	if !strings.Contains(sql, "$") || len(variables) == 0 {
+		return sql, nil
+	}
+	source := []rune(StripComments(sql))
+	tokens := tokenizeSQL(source)
+
+	// The CTEs join an existing WITH clause rather than nesting another one
+	prefix, separator, first := "WITH ", "\n", 0
+	if len(tokens) > 1 && tokens[0].isWord("with") {
+		prefix, separator, first = "WITH ", ", ", 1
+		if tokens[1].isWord("recursive") && len(tokens) > 2 {
+			prefix, first = "WITH RECURSIVE ", 2
+		}
+	}
+
+	var body strings.Builder
+	var used []string
+	seen := make(map[string]bool)
+	last := 0
+	if len(tokens) > 0 {
+		last = tokens[first].start
+	}
+	for i := first; i+1 < len(tokens); i++ {
+		dollar, word := tokens[i], tokens[i+1]
+		if dollar.kind != tokenNumber || dollar.text != "$" || word.kind != tokenWord || word.start != dollar.end {
+			continue
+		}
+		name := strings.ToLower(word.text)
+		if _, ok := variables[name]; !ok {
+			continue
+		}
+		body.WriteString(string(source[last:dollar.start]))
+		body.WriteString(name)
+		last = word.end
+		if !seen[name] {
+			seen[name] = true
+			used = append(used, name)
+		}
+		i++
+	}
+	if len(used) == 0 {
+		return sql, nil
+	}
+	body.WriteString(string(source[last:]))
+
+	ctes := make([]string, len(used))
+	for i, name := range used {
+		ctes[i] = name + " AS (" + strings.TrimRight(strings.TrimSpace(variables[name]), "; \t\n") + ")"
+	}
+	return prefix + strings.Join(ctes, ", ") + separator + strings.TrimSpace(body.String()), used
+}
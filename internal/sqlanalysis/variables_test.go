@@ -0,0 +1,37 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptVariables(t *testing.T) {
+	assert.Equal(t, []string{"monthly_revenue", "churn"},
+		PromptVariables("compare $monthly_revenue with $churn and $Monthly_Revenue, costs $5"))
+	assert.Empty(t, PromptVariables("no variables here"))
+}
+
+func TestInlineVariables(t *testing.T) {
+	variables := map[string]string{"revenue": "SELECT month, sum(total) AS total FROM orders GROUP BY month;"}
+
+	sql, used := InlineVariables("SELECT * FROM $revenue WHERE total > 100", variables)
+	assert.Equal(t, []string{"revenue"}, used)
+	assert.Equal(t, "WITH revenue AS (SELECT month, sum(total) AS total FROM orders GROUP BY month)\nSELECT * FROM revenue WHERE total > 100", sql)
+
+	// An existing WITH clause is joined, keeping RECURSIVE
+	sql, _ = InlineVariables("WITH RECURSIVE last AS (SELECT * FROM $revenue) SELECT * FROM last JOIN $revenue r USING (month)", variables)
+	assert.Equal(t, "WITH RECURSIVE revenue AS (SELECT month, sum(total) AS total FROM orders GROUP BY month), last AS (SELECT * FROM revenue) SELECT * FROM last JOIN revenue r USING (month)", sql)
+
+	// Strings, dollar quotes, parameters and unknown names are left alone
+	for _, query := range []string{
+		"SELECT '$revenue'",
+		"SELECT $tag$ $revenue $tag$",
+		"SELECT * FROM orders WHERE id = $1",
+		"SELECT * FROM $other",
+	} {
+		sql, used := InlineVariables(query, variables)
+		assert.Equal(t, query, sql)
+		assert.Empty(t, used)
+	}
+}
@@ -0,0 +1,126 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// cpuWait is the wait type of sessions that are active but not waiting
+const cpuWait = "CPU"
+
+// WaitCount is how often a wait event was seen across samples
+type WaitCount struct {
+	Type         string  `json:"type"`
+	Event        string  `json:"event"`
+	Samples      int     `json:"samples"`
+	Percent      float64 `json:"percent"`
+	ExampleQuery string  `json:"example_query,omitempty"`
+	Hint         string  `json:"hint,omitempty"`
+}
+
+// WaitProfile aggregates samples of the wait events of active sessions
+type WaitProfile struct {
+	Samples       int         `json:"samples"`
+	ActiveSamples int         `json:"active_session_samples"`           // Sum of active sessions over all samples
+	HiddenSamples int         `json:"hidden_session_samples,omitempty"` // Sessions of other users whose waits could not be read
+	Top           []WaitCount `json:"top_waits"`
+
+	counts map[string]*WaitCount
+}
+
+// BuildWaitSampleQuery returns the query sampling the (wait type, wait event,
+// query) of the other active sessions of the current database. On PostgreSQL
+// it also returns the sessions of other users whose state is hidden from the
+// user, so AddSample can count them.
+func BuildWaitSampleQuery(dialect string) (string, error) {
+	switch normalizeDialect(dialect) {
+	case "postgresql":
+		return `SELECT COALESCE(wait_event_type, 'CPU'), COALESCE(wait_event, 'CPU'), left(query, 200)
+FROM pg_stat_activity
+WHERE (state = 'active' OR query = '<insufficient privilege>') AND pid <> pg_backend_pid() AND datname = current_database()`, nil
+	case "mysql":
+		return `SELECT COALESCE(SUBSTRING_INDEX(SUBSTRING_INDEX(w.EVENT_NAME, '/', 2), '/', -1), 'CPU'), COALESCE(w.EVENT_NAME, 'CPU'), LEFT(t.PROCESSLIST_INFO, 200)
+FROM performance_schema.threads t
+LEFT JOIN performance_schema.events_waits_current w ON w.THREAD_ID = t.THREAD_ID AND w.END_EVENT_ID IS NULL
+WHERE t.PROCESSLIST_ID IS NOT NULL AND t.PROCESSLIST_ID <> CONNECTION_ID() AND t.PROCESSLIST_COMMAND <> 'Sleep'`, nil
+	default:
+		return "", fmt.Errorf("wait event profiling is not supported for %s databases", dialect)
+	}
+}
+
+// NewWaitProfile returns an empty wait profile
+func NewWaitProfile() *WaitProfile {
+	return &WaitProfile{Top: []WaitCount{}, counts: make(map[string]*WaitCount)}
+}
+
+// AddSample counts the wait events of one BuildWaitSampleQuery result
+func (p *WaitProfile) AddSample(result *models.QueryResult) {
+	p.Samples++
+	if result == nil {
+		return
+	}
+	for _, row := range result.Rows {
+		if len(row) < 3 {
+			continue
+		}
+		if strings.TrimSpace(cellText(row[2])) == InsufficientPrivilege {
+			// Neither the state nor the wait of the session can be read
+			p.HiddenSamples++
+			continue
+		}
+		waitType, event := cellText(row[0]), cellText(row[1])
+		key := waitType + "/" + event
+		count, exists := p.counts[key]
+		if !exists {
+			count = &WaitCount{Type: waitType, Event: event, Hint: WaitHint(waitType)}
+			p.counts[key] = count
+		}
+		count.Samples++
+		if count.ExampleQuery == "" {
+			count.ExampleQuery = strings.TrimSpace(cellText(row[2]))
+		}
+		p.ActiveSamples++
+	}
+}
+
+// Finish ranks the wait events by how often they were seen and keeps the top n
+func (p *WaitProfile) Finish(n int) {
+	p.Top = []WaitCount{}
+	for _, count := range p.counts {
+		count.Percent = float64(count.Samples) / float64(p.ActiveSamples) * 100
+		p.Top = append(p.Top, *count)
+	}
+	sort.Slice(p.Top, func(i, j int) bool {
+		if p.Top[i].Samples != p.Top[j].Samples {
+			return p.Top[i].Samples > p.Top[j].Samples
+		}
+		return p.Top[i].Type+p.Top[i].Event < p.Top[j].Type+p.Top[j].Event
+	})
+	if len(p.Top) > n {
+		p.Top = p.Top[:n]
+	}
+}
+
+// waitHints explains what PostgreSQL wait_event_type and MySQL wait classes point at
+var waitHints = map[string]string{
+	cpuWait:     "Running on CPU: look at the plans of the example queries (analyze_query / explain_query).",
+	"lock":      "Blocked by locks held by other transactions: look for long-running or idle-in-transaction sessions and hot rows.",
+	"lwlock":    "Contention on internal locks (buffer mapping, WAL insert, lock manager): usually too many concurrent connections or a small shared_buffers.",
+	"io":        "Reading or writing data files or WAL: missing indexes, a cache that is too small or slow storage.",
+	"ipc":       "Waiting for other processes: parallel query workers or synchronous replication.",
+	"client":    "Waiting for the client to send or read data: a slow application or network, not the database.",
+	"bufferpin": "Waiting for a buffer pin, usually VACUUM waiting for a long-running scan.",
+	"timeout":   "Sleeping on purpose (pg_sleep, vacuum cost delay).",
+	"synch":     "Contention on InnoDB mutexes and rw-locks: usually too many concurrent writers to the same pages.",
+}
+
+// WaitHint explains a wait type, or returns "" for unknown types
+func WaitHint(waitType string) string {
+	if waitType == cpuWait {
+		return waitHints[cpuWait]
+	}
+	return waitHints[strings.ToLower(waitType)]
+}
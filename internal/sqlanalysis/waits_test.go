@@ -0,0 +1,42 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitProfile(t *testing.T) {
+	profile := NewWaitProfile()
+	profile.AddSample(&models.QueryResult{Rows: [][]interface{}{
+		{"Lock", "transactionid", "UPDATE accounts SET balance = balance - 1 WHERE id = 1"},
+		{"CPU", "CPU", "SELECT count(*) FROM events"},
+	}})
+	profile.AddSample(&models.QueryResult{Rows: [][]interface{}{
+		{"Lock", "transactionid", "UPDATE accounts SET balance = balance + 1 WHERE id = 1"},
+	}})
+	profile.AddSample(nil)
+	profile.Finish(10)
+
+	assert.Equal(t, 3, profile.Samples)
+	assert.Equal(t, 3, profile.ActiveSamples)
+	require.Len(t, profile.Top, 2)
+	assert.Equal(t, "transactionid", profile.Top[0].Event)
+	assert.Equal(t, 2, profile.Top[0].Samples)
+	assert.InDelta(t, 66.7, profile.Top[0].Percent, 0.1)
+	assert.Contains(t, profile.Top[0].ExampleQuery, "balance - 1")
+	assert.Contains(t, profile.Top[0].Hint, "locks")
+	assert.Contains(t, profile.Top[1].Hint, "CPU")
+}
+
+func TestWaitHint(t *testing.T) {
+	assert.Contains(t, WaitHint("IO"), "indexes")
+	assert.Contains(t, WaitHint("synch"), "InnoDB")
+	assert.Empty(t, WaitHint("Extension"))
+
+	_, err := BuildWaitSampleQuery("sqlite")
+	assert.Error(t, err)
+}
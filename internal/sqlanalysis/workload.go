@@ -0,0 +1,228 @@
+package sqlanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// workloadHeader prefixes the metadata comment written before each captured statement
+const workloadHeader = "-- dbsage:workload"
+
+// WorkloadEntry is a captured query together with its baseline statistics
+type WorkloadEntry struct {
+	SQL    string  `json:"sql"`
+	Calls  int64   `json:"calls"`
+	MeanMs float64 `json:"mean_ms"`
+}
+
+// ReplayResult holds the measured latency of a replayed workload entry
+type ReplayResult struct {
+	Entry     WorkloadEntry `json:"entry"`
+	LatencyMs float64       `json:"latency_ms"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// DeltaMs returns the latency difference against the captured baseline
+func (r ReplayResult) DeltaMs() float64 {
+	return r.LatencyMs - r.Entry.MeanMs
+}
+
+// DeltaPercent returns the relative latency change against the captured baseline
+func (r ReplayResult) DeltaPercent() float64 {
+	if r.Entry.MeanMs <= 0 {
+		return 0
+	}
+	return r.DeltaMs() / r.Entry.MeanMs * 100
+}
+
+var (
+	workloadMetaPattern = regexp.MustCompile(`(?m)^\s*-- dbsage:workload\s+calls=(\d+)\s+mean_ms=([0-9.]+)\s*$`)
+	placeholderPattern  = regexp.MustCompile(`\$\d+|\?`)
+	utilityPattern      = regexp.MustCompile(`(?i)^\s*(BEGIN|COMMIT|ROLLBACK|SET|SHOW|START|SAVEPOINT|RELEASE|DEALLOCATE|DISCARD)\b`)
+
+	// generalLogHeaderPattern matches the column header MySQL writes at the top
+	// of a general query log file
+	generalLogHeaderPattern = regexp.MustCompile(`(?m)^Time\s+Id\s+Command\s+Argument\s*$`)
+	// generalLogEntryPattern matches the first line of a general log entry:
+	// an optional timestamp, the thread id, the command and its argument
+	generalLogEntryPattern = regexp.MustCompile(`^(?:\d{4}-\d\d-\d\dT\S+|\d{6}\s+\d{1,2}:\d\d:\d\d)?\t+\s*\d+\s+([A-Za-z][A-Za-z ]*?)\t(.*)$`)
+)
+
+// BuildCaptureQuery returns the query used to sample the heaviest statements
+// of the current database, or an error if the dialect is not supported
+func BuildCaptureQuery(dialect string, limit int) (string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	switch strings.ToLower(dialect) {
+	case "postgresql", "postgres", "":
+		return fmt.Sprintf(`SELECT query, calls, mean_exec_time
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+ORDER BY total_exec_time DESC
+LIMIT %d`, limit), nil
+	case "mysql":
+		return fmt.Sprintf(`SELECT SQL_TEXT, 1 AS calls, TIMER_WAIT / 1000000000 AS mean_ms
+FROM performance_schema.events_statements_history_long
+WHERE CURRENT_SCHEMA = DATABASE() AND SQL_TEXT IS NOT NULL
+ORDER BY TIMER_WAIT DESC
+LIMIT %d`, limit), nil
+	case "clickhouse":
+		return fmt.Sprintf(`SELECT any(query), count(), avg(query_duration_ms) AS mean_ms
+FROM system.query_log
+WHERE type = 'QueryFinish' AND current_database = currentDatabase() AND is_initial_query
+GROUP BY normalized_query_hash
+ORDER BY sum(query_duration_ms) DESC
+LIMIT %d`, limit), nil
+	default:
+		return "", fmt.Errorf("workload capture is not supported for %s databases", dialect)
+	}
+}
+
+// WorkloadFromResult converts a capture query result into workload entries,
+// dropping utility statements, statements hidden by InsufficientPrivilege and
+// merging duplicates
+func WorkloadFromResult(result *models.QueryResult) []WorkloadEntry {
+	if result == nil {
+		return nil
+	}
+
+	seen := make(map[string]int)
+	var entries []WorkloadEntry
+	for _, row := range result.Rows {
+		if len(row) < 3 {
+			continue
+		}
+		sql := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf("%v", cellString(row[0]))), ";")
+		if sql == "" || sql == InsufficientPrivilege || utilityPattern.MatchString(sql) {
+			continue
+		}
+		calls := int64(toFloat(cellString(row[1])))
+		meanMs := toFloat(cellString(row[2]))
+
+		if i, exists := seen[sql]; exists {
+			// Average the latency across repeated samples of the same text
+			total := entries[i].MeanMs*float64(entries[i].Calls) + meanMs*float64(calls)
+			entries[i].Calls += calls
+			if entries[i].Calls > 0 {
+				entries[i].MeanMs = total / float64(entries[i].Calls)
+			}
+			continue
+		}
+		seen[sql] = len(entries)
+		entries = append(entries, WorkloadEntry{SQL: sql, Calls: calls, MeanMs: meanMs})
+	}
+	return entries
+}
+
+// FormatWorkload renders workload entries as a SQL file. Each statement is
+// preceded by a metadata comment so the file can also be used with /explain-file.
+func FormatWorkload(entries []WorkloadEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(fmt.Sprintf("%s calls=%d mean_ms=%.3f\n", workloadHeader, e.Calls, e.MeanMs))
+		b.WriteString(e.SQL)
+		b.WriteString(";\n\n")
+	}
+	return b.String()
+}
+
+// ParseWorkload reads a workload file. Statements without a metadata comment
+// are accepted with an unknown (zero) baseline.
+func ParseWorkload(content string) []WorkloadEntry {
+	var entries []WorkloadEntry
+	for _, stmt := range SplitStatements(content) {
+		entry := WorkloadEntry{}
+		if match := workloadMetaPattern.FindStringSubmatch(stmt); match != nil {
+			entry.Calls, _ = strconv.ParseInt(match[1], 10, 64)
+			entry.MeanMs, _ = strconv.ParseFloat(match[2], 64)
+		}
+		entry.SQL = strings.TrimSpace(StripComments(stmt))
+		if entry.SQL != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// IsGeneralLog reports whether a file is a MySQL general query log rather
+// than a workload file
+func IsGeneralLog(content string) bool {
+	if generalLogHeaderPattern.MatchString(content) {
+		return true
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return generalLogEntryPattern.MatchString(strings.TrimRight(line, "\r"))
+		}
+	}
+	return false
+}
+
+// ParseGeneralLog reads the statements of a MySQL general query log as a
+// workload. Query and Execute entries are kept, with repeated statements
+// merged into their number of calls; connects, utility statements and
+// statements still holding ? placeholders are dropped. The log records no
+// durations, so the baseline is unknown (zero).
+func ParseGeneralLog(content string) []WorkloadEntry {
+	seen := make(map[string]int)
+	var entries []WorkloadEntry
+	var command string
+	var statement strings.Builder
+	flush := func() {
+		sql := strings.TrimSuffix(strings.TrimSpace(statement.String()), ";")
+		statement.Reset()
+		if (command != "Query" && command != "Execute") || sql == "" || utilityPattern.MatchString(sql) || HasPlaceholders(sql) {
+			return
+		}
+		if i, exists := seen[sql]; exists {
+			entries[i].Calls++
+			return
+		}
+		seen[sql] = len(entries)
+		entries = append(entries, WorkloadEntry{SQL: sql, Calls: 1})
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := generalLogEntryPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			command = m[1]
+			statement.WriteString(m[2])
+			continue
+		}
+		// Lines of a multi-line statement follow its entry unprefixed
+		if command != "" {
+			statement.WriteString("\n" + line)
+		}
+	}
+	flush()
+	return entries
+}
+
+// HasPlaceholders reports whether a statement contains normalized parameters
+// ($1 or ?) outside of literals, which means it cannot be replayed as-is
+func HasPlaceholders(query string) bool {
+	return placeholderPattern.MatchString(stringLiteralPattern.ReplaceAllString(StripComments(query), "''"))
+}
+
+// SortByRegression orders replay results by absolute latency increase, worst first
+func SortByRegression(results []ReplayResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DeltaMs() > results[j].DeltaMs()
+	})
+}
+
+// cellString converts byte slices returned by some drivers into strings
+func cellString(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package sqlanalysis
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkloadRoundTrip(t *testing.T) {
+	entries := []WorkloadEntry{
+		{SQL: "SELECT * FROM orders WHERE status = 'paid'", Calls: 120, MeanMs: 4.5},
+		{SQL: "SELECT count(*) FROM users", Calls: 3, MeanMs: 0.25},
+	}
+
+	parsed := ParseWorkload(FormatWorkload(entries))
+	assert.Equal(t, entries, parsed)
+}
+
+func TestParseWorkload_PlainSQL(t *testing.T) {
+	parsed := ParseWorkload("-- hand written\nSELECT 1;\nSELECT 2")
+	require.Len(t, parsed, 2)
+	assert.Equal(t, "SELECT 1", parsed[0].SQL)
+	assert.Zero(t, parsed[0].MeanMs)
+}
+
+func TestParseGeneralLog(t *testing.T) {
+	log := "/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:\n" +
+		"Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock\n" +
+		"Time                 Id Command    Argument\n" +
+		"2024-05-01T10:00:00.000001Z\t    8 Connect\tapp@localhost on shop using TCP/IP\n" +
+		"2024-05-01T10:00:00.000002Z\t    8 Query\tSET NAMES utf8mb4\n" +
+		"2024-05-01T10:00:01.000000Z\t    8 Query\tSELECT *\nFROM orders\nWHERE id = 1\n" +
+		"2024-05-01T10:00:02.000000Z\t    9 Prepare\tSELECT * FROM users WHERE id = ?\n" +
+		"2024-05-01T10:00:02.000001Z\t    9 Execute\tSELECT * FROM users WHERE id = 7\n" +
+		"2024-05-01T10:00:03.000000Z\t    8 Query\tSELECT *\nFROM orders\nWHERE id = 1\n" +
+		"2024-05-01T10:00:04.000000Z\t    8 Quit\t\n"
+	require.True(t, IsGeneralLog(log))
+	assert.Equal(t, []WorkloadEntry{
+		{SQL: "SELECT *\nFROM orders\nWHERE id = 1", Calls: 2},
+		{SQL: "SELECT * FROM users WHERE id = 7", Calls: 1},
+	}, ParseGeneralLog(log))
+
+	// MySQL 5.6 writes the time once per second and tabs on the lines that follow
+	old := "150101 10:00:00\t    3 Query\tSELECT 1\n\t\t    3 Query\tSELECT 2\n"
+	require.True(t, IsGeneralLog(old))
+	assert.Len(t, ParseGeneralLog(old), 2)
+
+	assert.False(t, IsGeneralLog(FormatWorkload([]WorkloadEntry{{SQL: "SELECT 1", Calls: 1}})))
+}
+
+func TestWorkloadFromResult(t *testing.T) {
+	result := &models.QueryResult{
+		Columns: []string{"query", "calls", "mean_exec_time"},
+		Rows: [][]interface{}{
+			{"SELECT * FROM t WHERE id = $1", int64(10), 2.0},
+			{"BEGIN", int64(500), 0.01},
+			{[]byte("SELECT * FROM t WHERE id = $1"), int64(30), []byte("6.0")},
+			{"SELECT now()", int64(1), 0.1},
+		},
+	}
+
+	entries := WorkloadFromResult(result)
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(40), entries[0].Calls)
+	assert.InDelta(t, 5.0, entries[0].MeanMs, 0.0001)
+	assert.Equal(t, "SELECT now()", entries[1].SQL)
+}
+
+func TestBuildCaptureQuery(t *testing.T) {
+	query, err := BuildCaptureQuery("postgresql", 10)
+	require.NoError(t, err)
+	assert.Contains(t, query, "pg_stat_statements")
+	assert.Contains(t, query, "LIMIT 10")
+
+	query, err = BuildCaptureQuery("mysql", 0)
+	require.NoError(t, err)
+	assert.Contains(t, query, "performance_schema")
+	assert.Contains(t, query, "LIMIT 50")
+
+	query, err = BuildCaptureQuery("clickhouse", 20)
+	require.NoError(t, err)
+	assert.Contains(t, query, "system.query_log")
+	assert.Contains(t, query, "GROUP BY normalized_query_hash")
+	assert.Contains(t, query, "LIMIT 20")
+
+	_, err = BuildCaptureQuery("sqlite", 10)
+	assert.Error(t, err)
+}
+
+func TestHasPlaceholders(t *testing.T) {
+	assert.True(t, HasPlaceholders("SELECT * FROM t WHERE id = $1"))
+	assert.True(t, HasPlaceholders("SELECT * FROM t WHERE id = ?"))
+	assert.False(t, HasPlaceholders("SELECT * FROM t WHERE note = 'why?'"))
+}
+
+func TestSortByRegression(t *testing.T) {
+	results := []ReplayResult{
+		{Entry: WorkloadEntry{SQL: "a", MeanMs: 10}, LatencyMs: 9},
+		{Entry: WorkloadEntry{SQL: "b", MeanMs: 10}, LatencyMs: 30},
+		{Entry: WorkloadEntry{SQL: "c", MeanMs: 10}, LatencyMs: 12},
+	}
+	SortByRegression(results)
+	assert.Equal(t, "b", results[0].Entry.SQL)
+	assert.Equal(t, 200.0, results[0].DeltaPercent())
+	assert.Equal(t, "a", results[2].Entry.SQL)
+}
@@ -0,0 +1,396 @@
+// Package sqlerrors explains database errors locally: it maps SQLSTATE and
+// engine error codes to what went wrong and what to do next, without asking
+// the AI.
+package sqlerrors
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Explanation describes a database error and the next steps to take
+type Explanation struct {
+	Code    string   // SQLSTATE or engine error code, e.g. "23505" or "MySQL 1062"
+	Title   string   // Short name of the error
+	Meaning string   // What the error means
+	Steps   []string // Concrete things to try
+	Detail  string   // Detail and hint reported by the database, if any
+}
+
+// entry is one kind of error in the knowledge base, recognized by any of its
+// codes or message fragments
+type entry struct {
+	sqlStates []string
+	mysql     []uint16
+	sqlite    []sqlite3.ErrNoExtended
+	fragments []string // Lower case, matched when the driver gave no code
+	title     string
+	meaning   string
+	steps     []string
+}
+
+// knowledge lists the errors with specific advice. Entries are tried in order,
+// so the more specific ones come first.
+var knowledge = []entry{
+	{
+		sqlStates: []string{"23505"},
+		mysql:     []uint16{1062, 1586},
+		sqlite:    []sqlite3.ErrNoExtended{sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey},
+		fragments: []string{"duplicate key value", "duplicate entry", "unique constraint failed"},
+		title:     "Unique violation",
+		meaning:   "A row with the same value in a unique or primary key column already exists.",
+		steps: []string{
+			"Look up the existing row by the key named in the error before inserting again.",
+			"Use an upsert to update the existing row instead: INSERT ... ON CONFLICT (PostgreSQL, SQLite) or INSERT ... ON DUPLICATE KEY UPDATE (MySQL).",
+			"If the key comes from a sequence or auto increment, check that it is ahead of the largest existing id.",
+		},
+	},
+	{
+		sqlStates: []string{"23503"},
+		mysql:     []uint16{1451, 1452},
+		sqlite:    []sqlite3.ErrNoExtended{sqlite3.ErrConstraintForeignKey},
+		fragments: []string{"foreign key constraint"},
+		title:     "Foreign key violation",
+		meaning:   "The statement references a row that does not exist, or deletes a row other rows still reference.",
+		steps: []string{
+			"For inserts and updates, create the referenced parent row first or fix the referencing value.",
+			"For deletes, delete or reassign the child rows first, or check whether the key is declared ON DELETE CASCADE.",
+		},
+	},
+	{
+		sqlStates: []string{"23502"},
+		mysql:     []uint16{1048, 1364},
+		sqlite:    []sqlite3.ErrNoExtended{sqlite3.ErrConstraintNotNull},
+		fragments: []string{"violates not-null constraint", "not null constraint failed", "cannot be null", "doesn't have a default value"},
+		title:     "Not-null violation",
+		meaning:   "A column that does not allow NULL got no value.",
+		steps: []string{
+			"Provide a value for the column named in the error.",
+			"Check the table definition for the columns without a default.",
+		},
+	},
+	{
+		sqlStates: []string{"23514"},
+		mysql:     []uint16{3819},
+		sqlite:    []sqlite3.ErrNoExtended{sqlite3.ErrConstraintCheck},
+		fragments: []string{"violates check constraint", "check constraint failed"},
+		title:     "Check violation",
+		meaning:   "A value does not satisfy a CHECK constraint of the table.",
+		steps: []string{
+			"Look up the constraint definition to see which values are allowed.",
+			"Fix the value, or ask the table owner whether the constraint is still right.",
+		},
+	},
+	{
+		sqlStates: []string{"40P01"},
+		mysql:     []uint16{1213},
+		fragments: []string{"deadlock"},
+		title:     "Deadlock detected",
+		meaning:   "Two transactions waited on locks held by each other; the database aborted this one to break the cycle.",
+		steps: []string{
+			"Retry the statement: the other transaction has been allowed to finish.",
+			"If it keeps happening, make concurrent transactions lock rows in the same order and keep them short.",
+		},
+	},
+	{
+		sqlStates: []string{"40001"},
+		fragments: []string{"could not serialize access"},
+		title:     "Serialization failure",
+		meaning:   "A concurrent transaction changed the data this transaction read, so it could not be committed consistently.",
+		steps: []string{
+			"Retry the whole transaction.",
+			"Keep serializable transactions short and touch as few rows as possible.",
+		},
+	},
+	{
+		sqlStates: []string{"55P03"},
+		mysql:     []uint16{1205},
+		fragments: []string{"lock wait timeout", "could not obtain lock", "database is locked", "database table is locked"},
+		title:     "Lock timeout",
+		meaning:   "The statement waited too long for a lock held by another session.",
+		steps: []string{
+			"Find the blocking session, for example in pg_stat_activity (PostgreSQL) or SHOW PROCESSLIST (MySQL), and wait for it or end it.",
+			"Retry when the other transaction has finished.",
+		},
+	},
+	{
+		sqlStates: []string{"57014"},
+		mysql:     []uint16{3024},
+		fragments: []string{"canceling statement due to statement timeout", "maximum statement execution time exceeded"},
+		title:     "Statement timeout",
+		meaning:   "The statement ran longer than the allowed time and was cancelled.",
+		steps: []string{
+			"Check the plan with EXPLAIN for full scans of large tables.",
+			"Narrow the query with a WHERE clause or LIMIT, or add an index on the filtered columns.",
+		},
+	},
+	{
+		sqlStates: []string{"25006"},
+		mysql:     []uint16{1290, 1792},
+		fragments: []string{"read-only transaction", "read only transaction", "--read-only option", "readonly database"},
+		title:     "Read-only connection",
+		meaning:   "The connection or server only allows reads, for example a replica or a read-only user session.",
+		steps: []string{
+			"Run writes against the primary database.",
+			"Check whether the session was opened read-only on purpose before changing it.",
+		},
+	},
+	{
+		sqlStates: []string{"42501"},
+		mysql:     []uint16{1142, 1143, 1044, 1227, 1370},
+		fragments: []string{"permission denied", "command denied", "access denied for user"},
+		title:     "Permission denied",
+		meaning:   "The database user lacks the privilege the statement needs.",
+		steps: []string{
+			"Check the user's privileges, for example with \\dp (PostgreSQL) or SHOW GRANTS (MySQL).",
+			"Ask the database owner for the missing GRANT, or use a connection with the right role.",
+		},
+	},
+	{
+		sqlStates: []string{"28P01", "28000"},
+		mysql:     []uint16{1045},
+		fragments: []string{"password authentication failed"},
+		title:     "Authentication failed",
+		meaning:   "The server rejected the user name or password.",
+		steps: []string{
+			"Check the credentials of the connection with /edit.",
+			"Check that the user may connect from this host (pg_hba.conf on PostgreSQL, the user's host on MySQL).",
+		},
+	},
+	{
+		sqlStates: []string{"42P01"},
+		mysql:     []uint16{1146},
+		fragments: []string{"no such table"},
+		title:     "Undefined table",
+		meaning:   "The table does not exist, or is not in the schemas on the search path.",
+		steps: []string{
+			"List the tables to check the spelling and case of the name.",
+			"Qualify the table with its schema, such as reporting.orders.",
+		},
+	},
+	{
+		sqlStates: []string{"42703"},
+		mysql:     []uint16{1054},
+		fragments: []string{"no such column", "unknown column"},
+		title:     "Undefined column",
+		meaning:   "A column in the statement does not exist in the table it is read from.",
+		steps: []string{
+			"Describe the table to check the column names.",
+			"Check the table alias the column is qualified with.",
+		},
+	},
+	{
+		sqlStates: []string{"42601"},
+		mysql:     []uint16{1064},
+		fragments: []string{"syntax error", "error in your sql syntax"},
+		title:     "Syntax error",
+		meaning:   "The statement is not valid SQL for this database.",
+		steps: []string{
+			"Look at the statement near the position given in the error.",
+			"Check for dialect differences, such as LIMIT versus TOP or quoting with \" versus `.",
+		},
+	},
+	{
+		sqlStates: []string{"22012"},
+		mysql:     []uint16{1365},
+		fragments: []string{"division by zero"},
+		title:     "Division by zero",
+		meaning:   "An expression divided by zero.",
+		steps: []string{
+			"Guard the divisor with NULLIF(divisor, 0) to get NULL instead.",
+		},
+	},
+	{
+		sqlStates: []string{"22001"},
+		mysql:     []uint16{1406},
+		fragments: []string{"value too long", "data too long"},
+		title:     "Value too long",
+		meaning:   "A string is longer than the column allows.",
+		steps: []string{
+			"Shorten the value, or widen the column if longer values are valid.",
+		},
+	},
+	{
+		sqlStates: []string{"22003"},
+		mysql:     []uint16{1264, 1690},
+		fragments: []string{"out of range"},
+		title:     "Numeric value out of range",
+		meaning:   "A number does not fit the column or expression type.",
+		steps: []string{
+			"Check the value, or use a wider type such as bigint or numeric.",
+		},
+	},
+	{
+		sqlStates: []string{"22P02", "22007", "22008"},
+		mysql:     []uint16{1292, 1366},
+		fragments: []string{"invalid input syntax", "incorrect integer value", "incorrect datetime value"},
+		title:     "Invalid value",
+		meaning:   "A value cannot be converted to the column's type.",
+		steps: []string{
+			"Check the format of the value, such as quoted numbers or the date format.",
+			"Cast the value explicitly if the conversion is intended.",
+		},
+	},
+	{
+		sqlStates: []string{"53300"},
+		mysql:     []uint16{1040},
+		fragments: []string{"too many connections", "too many clients"},
+		title:     "Too many connections",
+		meaning:   "The server has reached its connection limit.",
+		steps: []string{
+			"Close idle sessions or wait for other clients to disconnect.",
+			"Use a connection pooler if the limit is reached often.",
+		},
+	},
+	{
+		sqlStates: []string{"53100"},
+		mysql:     []uint16{1114, 1021},
+		fragments: []string{"could not extend file", "no space left on device", "database or disk is full"},
+		title:     "Disk full",
+		meaning:   "The database ran out of disk space.",
+		steps: []string{
+			"Free disk space on the database server.",
+			"Drop or archive data that is no longer needed.",
+		},
+	},
+}
+
+// classes explain SQLSTATE classes without a specific entry
+var classes = map[string]entry{
+	"08": {title: "Connection problem", meaning: "The connection to the database failed or was lost.", steps: []string{"Check that the server is reachable, then retry; /status shows the connection health."}},
+	"22": {title: "Data exception", meaning: "A value is invalid for its type or operation.", steps: []string{"Check the values and casts in the statement."}},
+	"23": {title: "Integrity constraint violation", meaning: "The change breaks a constraint of the table.", steps: []string{"Look up the constraint named in the error and fix the data it rejects."}},
+	"40": {title: "Transaction rolled back", meaning: "The database rolled back the transaction, usually because of a conflict with another one.", steps: []string{"Retry the transaction."}},
+	"42": {title: "Syntax error or access rule violation", meaning: "The statement is invalid or refers to something it may not use.", steps: []string{"Check the names in the statement and the user's privileges."}},
+	"53": {title: "Insufficient resources", meaning: "The server ran out of a resource such as memory, disk or connections.", steps: []string{"Retry later, or ask the administrator to check the server."}},
+	"57": {title: "Operator intervention", meaning: "The statement was cancelled or the server is shutting down.", steps: []string{"Retry when the server is available again."}},
+}
+
+// sqlStatePattern finds a SQLSTATE in error messages of drivers that only
+// return text
+var sqlStatePattern = regexp.MustCompile(`SQLSTATE[ =:]*([0-9A-Z]{5})`)
+
+// Explain returns the explanation of a database error, or nil when the error
+// is not recognized
+func Explain(err error) *Explanation {
+	if err == nil {
+		return nil
+	}
+
+	var sqlState, code, detail string
+	var mysqlNumber uint16
+	var sqliteCode sqlite3.ErrNoExtended
+
+	var pqErr *pq.Error
+	var mysqlErr *mysql.MySQLError
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.As(err, &pqErr):
+		sqlState = string(pqErr.Code)
+		code = sqlState
+		detail = strings.TrimSpace(strings.Join([]string{pqErr.Detail, pqErr.Hint}, " "))
+	case errors.As(err, &mysqlErr):
+		mysqlNumber = mysqlErr.Number
+		sqlState = string(mysqlErr.SQLState[:])
+		code = fmt.Sprintf("MySQL %d", mysqlErr.Number)
+	case errors.As(err, &sqliteErr):
+		sqliteCode = sqliteErr.ExtendedCode
+		code = fmt.Sprintf("SQLite %d", sqliteErr.ExtendedCode)
+	default:
+		if match := sqlStatePattern.FindStringSubmatch(err.Error()); match != nil {
+			sqlState = match[1]
+			code = sqlState
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, e := range knowledge {
+		if e.matches(sqlState, mysqlNumber, sqliteCode, message) {
+			return e.explain(code, detail)
+		}
+	}
+	if len(sqlState) == 5 {
+		if e, ok := classes[sqlState[:2]]; ok {
+			return e.explain(code, detail)
+		}
+	}
+	return nil
+}
+
+// matches reports whether an error belongs to the entry. Codes decide when
+// the driver gave one; the message is only used without a code.
+func (e entry) matches(sqlState string, mysqlNumber uint16, sqliteCode sqlite3.ErrNoExtended, message string) bool {
+	switch {
+	case mysqlNumber != 0:
+		for _, number := range e.mysql {
+			if number == mysqlNumber {
+				return true
+			}
+		}
+		return false
+	case sqliteCode != 0:
+		for _, code := range e.sqlite {
+			if code == sqliteCode {
+				return true
+			}
+		}
+		// SQLite reports many errors with a generic code, so also check the message
+	case sqlState != "":
+		for _, state := range e.sqlStates {
+			if state == sqlState {
+				return true
+			}
+		}
+		return false
+	}
+	for _, fragment := range e.fragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e entry) explain(code, detail string) *Explanation {
+	return &Explanation{Code: code, Title: e.title, Meaning: e.meaning, Steps: e.steps, Detail: detail}
+}
+
+// Summary returns the explanation on one line, e.g. for notices
+func (x *Explanation) Summary() string {
+	summary := x.Title
+	if x.Code != "" {
+		summary += " (" + x.Code + ")"
+	}
+	summary += ": " + x.Meaning
+	if len(x.Steps) > 0 {
+		summary += " Next: " + x.Steps[0]
+	}
+	return summary
+}
+
+// String returns the explanation with all next steps, one per line
+func (x *Explanation) String() string {
+	var b strings.Builder
+	b.WriteString(x.Title)
+	if x.Code != "" {
+		b.WriteString(" (" + x.Code + ")")
+	}
+	b.WriteString(": " + x.Meaning)
+	if x.Detail != "" {
+		b.WriteString("\nDatabase says: " + x.Detail)
+	}
+	if len(x.Steps) > 0 {
+		b.WriteString("\nNext steps:")
+		for i, step := range x.Steps {
+			b.WriteString("\n  " + strconv.Itoa(i+1) + ". " + step)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package sqlerrors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain_PostgreSQLCodes(t *testing.T) {
+	err := fmt.Errorf("query execution failed: %w", &pq.Error{
+		Code:    "23505",
+		Message: `duplicate key value violates unique constraint "users_email_key"`,
+		Detail:  "Key (email)=(a@example.com) already exists.",
+	})
+
+	explanation := Explain(err)
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Unique violation", explanation.Title)
+	assert.Equal(t, "23505", explanation.Code)
+	assert.Equal(t, "Key (email)=(a@example.com) already exists.", explanation.Detail)
+	assert.Contains(t, explanation.String(), "Next steps:\n  1. Look up the existing row")
+	assert.Contains(t, explanation.String(), "Database says: Key (email)")
+
+	// The code decides, whatever the message says
+	explanation = Explain(&pq.Error{Code: "40P01", Message: "permission denied"})
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Deadlock detected", explanation.Title)
+
+	// Codes without an entry fall back to their class
+	explanation = Explain(&pq.Error{Code: "23P01", Message: "conflicting key value violates exclusion constraint"})
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Integrity constraint violation", explanation.Title)
+}
+
+func TestExplain_MySQLNumbers(t *testing.T) {
+	err := fmt.Errorf("query execution failed: %w", &mysql.MySQLError{
+		Number:   1142,
+		SQLState: [5]byte{'4', '2', '0', '0', '0'},
+		Message:  "DELETE command denied to user 'app'@'localhost' for table 'orders'",
+	})
+
+	explanation := Explain(err)
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Permission denied", explanation.Title)
+	assert.Equal(t, "MySQL 1142", explanation.Code)
+	assert.Equal(t, "Permission denied (MySQL 1142): The database user lacks the privilege the statement needs. Next: Check the user's privileges, for example with \\dp (PostgreSQL) or SHOW GRANTS (MySQL).", explanation.Summary())
+}
+
+func TestExplain_SQLiteCodes(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE NOT NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO users (email) VALUES ('a@example.com')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO users (email) VALUES ('a@example.com')")
+	explanation := Explain(fmt.Errorf("query execution failed: %w", err))
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Unique violation", explanation.Title)
+
+	_, err = db.Exec("INSERT INTO users (email) VALUES (NULL)")
+	explanation = Explain(err)
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Not-null violation", explanation.Title)
+}
+
+func TestExplain_MessagesWithoutCodes(t *testing.T) {
+	explanation := Explain(errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"))
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Deadlock detected", explanation.Title)
+	assert.Equal(t, "40P01", explanation.Code)
+
+	explanation = Explain(errors.New("pq: permission denied for table orders"))
+	require.NotNil(t, explanation)
+	assert.Equal(t, "Permission denied", explanation.Title)
+	assert.Empty(t, explanation.Code)
+
+	assert.Nil(t, Explain(errors.New("no database selected in the picker")))
+	assert.Nil(t, Explain(nil))
+}
@@ -0,0 +1,199 @@
+package templatevars
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+)
+
+// integerValue matches values bound as numbers when referenced outside a
+// string literal, so {{limit}} works in LIMIT
+var integerValue = regexp.MustCompile(`^-?[0-9]+$`)
+
+// CanBind reports whether statements of a dialect can take bind parameters.
+// ClickHouse and file connections cannot, so their values are inlined.
+func CanBind(dialect string) bool {
+	switch strings.ToLower(dialect) {
+	case "postgresql", "postgres", "mysql", "sqlite":
+		return true
+	}
+	return false
+}
+
+// BindSQL replaces the references of a SQL statement with bind parameters in
+// the placeholder style of the dialect, $1 for PostgreSQL and ? otherwise, and
+// returns their values in order. A string literal holding references, such
+// as '%{{customer}}%', becomes one parameter with the references expanded,
+// so no value is ever read as SQL. Dialects that cannot bind get the
+// statement of ExpandSQL and no parameters.
+func BindSQL(sql, dialect string, values map[string]string) (string, []interface{}, error) {
+	if !CanBind(dialect) {
+		expanded, err := ExpandSQL(sql, dialect, values)
+		return expanded, nil, err
+	}
+	postgres := strings.HasPrefix(strings.ToLower(dialect), "postgres")
+	var args []interface{}
+	bound, err := rewriteSQL(sql, dialect, values, func(value string, literal bool) string {
+		if !literal && integerValue.MatchString(value) {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			args = append(args, n)
+		} else {
+			args = append(args, value)
+		}
+		if postgres {
+			return "$" + strconv.Itoa(len(args))
+		}
+		return "?"
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return bound, args, nil
+}
+
+// ExpandSQL replaces the references of a SQL statement with complete string
+// literals, escaped for the dialect, for statements that cannot take bind
+// parameters such as the EXPLAIN of an analysis. Like BindSQL, a literal
+// holding references is replaced as a whole, and integers outside literals
+// are written as numbers.
+func ExpandSQL(sql, dialect string, values map[string]string) (string, error) {
+	backslash := sqlanalysis.BackslashEscapes(dialect)
+	return rewriteSQL(sql, dialect, values, func(value string, literal bool) string {
+		if !literal && integerValue.MatchString(value) {
+			return value
+		}
+		if backslash {
+			value = strings.ReplaceAll(value, `\`, `\\`)
+		}
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	})
+}
+
+// rewriteSQL replaces the references of a statement outside comments, and
+// the string literals holding references, with what param makes of their
+// value. literal tells param whether the value was written in quotes.
+func rewriteSQL(sql, dialect string, values map[string]string, param func(value string, literal bool) string) (string, error) {
+	backslash := sqlanalysis.BackslashEscapes(dialect)
+	runes := []rune(sql)
+	var out strings.Builder
+	var firstErr error
+	plain := 0 // Start of the text not written yet
+	flushPlain := func(end int) {
+		replaced, err := replaceReferences(string(runes[plain:end]), values, func(value string) string { return param(value, false) })
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		out.WriteString(replaced)
+	}
+
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			flushPlain(i)
+			out.WriteString(string(runes[i:end]))
+			plain, i = end, end
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := i + 2
+			for end < len(runes) && !(runes[end] == '*' && end+1 < len(runes) && runes[end+1] == '/') {
+				end++
+			}
+			end = min(end+2, len(runes))
+			flushPlain(i)
+			out.WriteString(string(runes[i:end]))
+			plain, i = end, end
+		case ch == '"' || ch == '`':
+			end := closingQuote(runes, i, ch, false)
+			if m := reference.FindString(string(runes[i:end])); m != "" && firstErr == nil {
+				firstErr = fmt.Errorf("%s is inside a quoted identifier; variables can only stand for values", m)
+			}
+			flushPlain(i)
+			out.WriteString(string(runes[i:end]))
+			plain, i = end, end
+		case ch == '\'':
+			// E'...' strings take backslash escapes on PostgreSQL
+			prefixed := i > 0 && (runes[i-1] == 'E' || runes[i-1] == 'e') && (i == 1 || !isWordRune(runes[i-2]))
+			escapes := backslash || prefixed
+			end := closingQuote(runes, i, ch, escapes)
+			body := string(runes[i+1 : max(end-1, i+1)])
+			if !reference.MatchString(body) {
+				i = end
+				continue
+			}
+			start := i
+			if prefixed {
+				start--
+			}
+			flushPlain(start)
+			value, err := replaceReferences(unescapeLiteral(body, escapes), values, func(value string) string { return value })
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			out.WriteString(param(value, true))
+			plain, i = end, end
+		default:
+			i++
+		}
+	}
+	flushPlain(len(runes))
+	return out.String(), firstErr
+}
+
+// closingQuote returns the position after the quote closing the quoted text
+// starting at start, or the end of the text when it is not closed
+func closingQuote(runes []rune, start int, quote rune, escapes bool) int {
+	for j := start + 1; j < len(runes); j++ {
+		switch {
+		case escapes && runes[j] == '\\':
+			j++
+		case runes[j] != quote:
+		case j+1 < len(runes) && runes[j+1] == quote:
+			j++
+		default:
+			return j + 1
+		}
+	}
+	return len(runes)
+}
+
+// unescapeLiteral returns the text a string literal body stands for
+func unescapeLiteral(body string, escapes bool) string {
+	var b strings.Builder
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case escapes && runes[i] == '\\' && i+1 < len(runes):
+			i++
+			switch runes[i] {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			case '0':
+				b.WriteRune(0)
+			default:
+				b.WriteRune(runes[i])
+			}
+		case runes[i] == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			b.WriteRune('\'')
+			i++
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return b.String()
+}
+
+// isWordRune reports whether a rune can be part of an identifier
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,240 @@
+// Package templatevars expands {{variables}} in saved SQL and templates, so one
+// query can be reused across date ranges and tenants.
+//
+// A reference is {{name}}, optionally with a date offset and a default:
+// {{today-7d}}, {{last_monday+1w}}, {{tenant|42}}. Date helpers such as
+// {{today}} and {{last_monday}} are built in; other values are passed with
+// --var name=value or prompted for.
+package templatevars
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of date helper values
+const DateLayout = "2006-01-02"
+
+// reference matches {{name}}, {{name-7d}} and {{name|default}}
+var reference = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*(?:([+-])\s*(\d+)\s*([dwmy]))?\s*(?:\|([^}]*))?\}\}`)
+
+// Definition declares a variable of a template
+type Definition struct {
+	Prompt  string `yaml:"prompt"`  // Question asked when no value is given
+	Default string `yaml:"default"` // Used when no value is given and nobody can be asked
+}
+
+// Prompter asks for the value of a variable
+type Prompter func(name, prompt, defaultValue string) (string, error)
+
+// Builtins returns the date helpers relative to now. Passing today explicitly,
+// e.g. --var today=2026-03-02, moves every helper to that date.
+func Builtins(now time.Time) map[string]string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Days since Monday, with Sunday as the last day of the week
+	sinceMonday := (int(today.Weekday()) + 6) % 7
+	thisMonday := today.AddDate(0, 0, -sinceMonday)
+	startOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+
+	return map[string]string{
+		"now":                 now.Format("2006-01-02 15:04:05"),
+		"today":               today.Format(DateLayout),
+		"yesterday":           today.AddDate(0, 0, -1).Format(DateLayout),
+		"tomorrow":            today.AddDate(0, 0, 1).Format(DateLayout),
+		"this_monday":         thisMonday.Format(DateLayout),
+		"last_monday":         thisMonday.AddDate(0, 0, -7).Format(DateLayout),
+		"last_sunday":         thisMonday.AddDate(0, 0, -1).Format(DateLayout),
+		"start_of_month":      startOfMonth.Format(DateLayout),
+		"start_of_last_month": startOfMonth.AddDate(0, -1, 0).Format(DateLayout),
+		"end_of_last_month":   startOfMonth.AddDate(0, 0, -1).Format(DateLayout),
+		"start_of_year":       time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location()).Format(DateLayout),
+	}
+}
+
+// BuiltinNames returns the names of the date helpers, sorted
+func BuiltinNames() []string {
+	builtins := Builtins(time.Now())
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns the variables referenced in texts that are not date helpers, in
+// order of first use
+func Names(texts ...string) []string {
+	builtins := Builtins(time.Now())
+	seen := make(map[string]bool)
+	var names []string
+	for _, text := range texts {
+		for _, m := range reference.FindAllStringSubmatch(text, -1) {
+			name := m[1]
+			if _, builtin := builtins[name]; builtin || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseAssignments parses name=value pairs, as given with --var
+func ParseAssignments(assignments []string) (map[string]string, error) {
+	values := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q, expected name=value", assignment)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Resolve determines a value for every variable referenced in texts: given
+// values first, then the prompter (when not nil), then declared defaults. The
+// result also holds the date helpers, moved to a given today if there is one.
+func Resolve(texts []string, defs map[string]Definition, given map[string]string, prompt Prompter, now time.Time) (map[string]string, error) {
+	if today, ok := given["today"]; ok {
+		t, err := time.ParseInLocation(DateLayout, today, now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("today must be a date like %s, got %q", DateLayout, today)
+		}
+		now = t
+	}
+
+	values := Builtins(now)
+	for name, value := range given {
+		values[name] = value
+	}
+
+	inlineDefaults := inlineDefaults(texts)
+	var missing []string
+	for _, name := range Names(texts...) {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		def := defs[name]
+		if def.Default == "" {
+			def.Default = inlineDefaults[name]
+		}
+		if prompt != nil {
+			question := def.Prompt
+			if question == "" {
+				question = name
+			}
+			value, err := prompt(name, question, def.Default)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				value = def.Default
+			}
+			if value != "" {
+				values[name] = value
+				continue
+			}
+		} else if def.Default != "" {
+			values[name] = def.Default
+			continue
+		}
+		missing = append(missing, name)
+	}
+
+	if len(missing) > 0 {
+		flags := make([]string, len(missing))
+		for i, name := range missing {
+			flags[i] = "--var " + name + "=..."
+		}
+		return nil, fmt.Errorf("no value for %s; pass %s", strings.Join(missing, ", "), strings.Join(flags, " "))
+	}
+	return values, nil
+}
+
+// inlineDefaults collects {{name|default}} defaults
+func inlineDefaults(texts []string) map[string]string {
+	defaults := make(map[string]string)
+	for _, text := range texts {
+		for _, m := range reference.FindAllStringSubmatch(text, -1) {
+			if m[5] != "" {
+				if _, ok := defaults[m[1]]; !ok {
+					defaults[m[1]] = strings.TrimSpace(m[5])
+				}
+			}
+		}
+	}
+	return defaults
+}
+
+// Expand replaces every reference in plain text, such as a title or file
+// name. SQL is expanded with BindSQL or ExpandSQL instead.
+func Expand(text string, values map[string]string) (string, error) {
+	return replaceReferences(text, values, func(value string) string { return value })
+}
+
+// replaceReferences replaces every reference in text with what replace makes
+// of its value
+func replaceReferences(text string, values map[string]string, replace func(value string) string) (string, error) {
+	var firstErr error
+	expanded := reference.ReplaceAllStringFunc(text, func(match string) string {
+		value, err := referenceValue(reference.FindStringSubmatch(match), values)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return match
+		}
+		return replace(value)
+	})
+	return expanded, firstErr
+}
+
+// referenceValue returns the value of a reference matched by reference
+func referenceValue(m []string, values map[string]string) (string, error) {
+	value, ok := values[m[1]]
+	if !ok {
+		if m[5] == "" {
+			return "", fmt.Errorf("no value for {{%s}}", m[1])
+		}
+		value = strings.TrimSpace(m[5])
+	}
+	if m[2] != "" {
+		shifted, err := shiftDate(value, m[2], m[3], m[4])
+		if err != nil {
+			return "", fmt.Errorf("{{%s%s%s%s}}: %w", m[1], m[2], m[3], m[4], err)
+		}
+		value = shifted
+	}
+	return value, nil
+}
+
+// shiftDate adds an offset such as -7d or +1m to a date value
+func shiftDate(value, sign, amount, unit string) (string, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return "", fmt.Errorf("offsets need a date value like %s, got %q", DateLayout, value)
+	}
+	n, _ := strconv.Atoi(amount)
+	if sign == "-" {
+		n = -n
+	}
+	switch unit {
+	case "d":
+		t = t.AddDate(0, 0, n)
+	case "w":
+		t = t.AddDate(0, 0, 7*n)
+	case "m":
+		t = t.AddDate(0, n, 0)
+	case "y":
+		t = t.AddDate(n, 0, 0)
+	}
+	return t.Format(DateLayout), nil
+}
@@ -0,0 +1,135 @@
+package templatevars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wednesday is 2026-10-21
+var wednesday = time.Date(2026, 10, 21, 14, 30, 0, 0, time.UTC)
+
+func TestBuiltins(t *testing.T) {
+	b := Builtins(wednesday)
+	assert.Equal(t, "2026-10-21", b["today"])
+	assert.Equal(t, "2026-10-19", b["this_monday"])
+	assert.Equal(t, "2026-10-12", b["last_monday"])
+	assert.Equal(t, "2026-10-18", b["last_sunday"])
+	assert.Equal(t, "2026-09-01", b["start_of_last_month"])
+	assert.Equal(t, "2026-09-30", b["end_of_last_month"])
+
+	// On a Sunday the week started six days earlier
+	assert.Equal(t, "2026-10-19", Builtins(time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC))["this_monday"])
+}
+
+func TestExpand(t *testing.T) {
+	values := map[string]string{"today": "2026-10-21", "customer": "O'Brien"}
+
+	sql, err := ExpandSQL("SELECT * FROM orders WHERE customer = '{{customer}}' AND day >= '{{ today-7d }}' AND day < '{{today+1m}}' AND tenant = {{tenant|42}}", "postgresql", values)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE customer = 'O''Brien' AND day >= '2026-10-14' AND day < '2026-11-21' AND tenant = 42", sql)
+
+	text, err := Expand("Orders for {{customer}}", values)
+	require.NoError(t, err)
+	assert.Equal(t, "Orders for O'Brien", text)
+
+	_, err = Expand("{{missing}}", values)
+	assert.ErrorContains(t, err, "no value for {{missing}}")
+
+	_, err = Expand("{{customer-1d}}", values)
+	assert.ErrorContains(t, err, "offsets need a date value")
+}
+
+func TestBindSQL(t *testing.T) {
+	values := map[string]string{"id": "7", "name": `x\' OR 1=1 -- `, "customer": "O'Brien"}
+
+	// A bare reference becomes a parameter, numbers bound as numbers
+	sql, args, err := BindSQL("SELECT * FROM users WHERE id = {{id}} AND name = {{name}}", "postgresql", values)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1 AND name = $2", sql)
+	assert.Equal(t, []interface{}{int64(7), `x\' OR 1=1 -- `}, args)
+
+	// A literal holding references becomes one parameter; others stay as written
+	sql, args, err = BindSQL("SELECT 'a''b' AS label FROM users WHERE name LIKE '%{{customer}}%' -- by {{customer}}\nAND id = '{{id}}'", "mysql", values)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 'a''b' AS label FROM users WHERE name LIKE ? -- by {{customer}}\nAND id = ?", sql)
+	assert.Equal(t, []interface{}{"%O'Brien%", "7"}, args)
+
+	// Escapes in the literal around a reference are read as the dialect reads them
+	_, args, err = BindSQL(`SELECT * FROM t WHERE a = 'it\'s {{id}}'`, "mysql", values)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"it's 7"}, args)
+	_, args, err = BindSQL(`SELECT * FROM t WHERE a = E'\\{{id}}' OR b = 'it''s {{id}}'`, "postgresql", values)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{`\7`, "it's 7"}, args)
+
+	_, _, err = BindSQL(`SELECT "{{name}}" FROM t`, "postgresql", values)
+	assert.ErrorContains(t, err, "variables can only stand for values")
+	_, _, err = BindSQL("SELECT * FROM t WHERE a = {{missing}}", "sqlite", values)
+	assert.ErrorContains(t, err, "no value for {{missing}}")
+}
+
+func TestExpandSQL_MySQLBackslash(t *testing.T) {
+	values := map[string]string{"id": "7", "name": `x\' OR 1=1 -- `}
+
+	// Without bind parameters the value stays one literal on MySQL too
+	sql, err := ExpandSQL("SELECT * FROM users WHERE name = '{{name}}' AND id = {{id}}", "mysql", values)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM users WHERE name = 'x\\'' OR 1=1 -- ' AND id = 7`, sql)
+
+	sql, args, err := BindSQL("SELECT * FROM users WHERE name = {{name}}", "clickhouse", values)
+	require.NoError(t, err)
+	assert.Nil(t, args)
+	assert.Equal(t, `SELECT * FROM users WHERE name = 'x\\'' OR 1=1 -- '`, sql)
+}
+
+func TestResolve(t *testing.T) {
+	texts := []string{"WHERE tenant = {{tenant}} AND region = '{{region|eu}}' AND day > '{{last_monday}}'", "{{tenant}} {{owner}}"}
+	assert.Equal(t, []string{"tenant", "region", "owner"}, Names(texts...))
+
+	defs := map[string]Definition{"owner": {Prompt: "Owner e-mail", Default: "ops@example.com"}}
+
+	// Without a prompter, given values and defaults are used and the rest is reported
+	_, err := Resolve(texts, defs, nil, nil, wednesday)
+	assert.ErrorContains(t, err, "no value for tenant; pass --var tenant=...")
+
+	values, err := Resolve(texts, defs, map[string]string{"tenant": "7"}, nil, wednesday)
+	require.NoError(t, err)
+	assert.Equal(t, "7", values["tenant"])
+	assert.Equal(t, "eu", values["region"])
+	assert.Equal(t, "ops@example.com", values["owner"])
+	assert.Equal(t, "2026-10-12", values["last_monday"])
+
+	// A prompter is asked for every missing value; an empty answer takes the default
+	var asked []string
+	prompt := func(name, question, def string) (string, error) {
+		asked = append(asked, question+"="+def)
+		if name == "tenant" {
+			return "9", nil
+		}
+		return "", nil
+	}
+	values, err = Resolve(texts, defs, nil, prompt, wednesday)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant=", "region=eu", "Owner e-mail=ops@example.com"}, asked)
+	assert.Equal(t, "9", values["tenant"])
+
+	// Passing today moves the date helpers
+	values, err = Resolve(texts, defs, map[string]string{"today": "2026-03-04", "tenant": "1"}, nil, wednesday)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02-23", values["last_monday"])
+
+	_, err = Resolve(texts, defs, map[string]string{"today": "last week"}, nil, wednesday)
+	assert.Error(t, err)
+}
+
+func TestParseAssignments(t *testing.T) {
+	values, err := ParseAssignments([]string{"tenant=42", "filter=a=b", "empty="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"tenant": "42", "filter": "a=b", "empty": ""}, values)
+
+	_, err = ParseAssignments([]string{"tenant"})
+	assert.Error(t, err)
+}
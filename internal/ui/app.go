@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"context"
+	"time"
+
 	"dbsage/internal/ai"
 	"dbsage/internal/models"
 	"dbsage/internal/ui/components"
@@ -8,11 +11,14 @@ import (
 	"dbsage/internal/ui/renderers"
 	"dbsage/internal/ui/state"
 	"dbsage/internal/version"
+	"dbsage/pkg/database"
 	"dbsage/pkg/dbinterfaces"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // Model is the main Bubble Tea model
@@ -24,11 +30,26 @@ type Model struct {
 	inputHandler      *handlers.InputHandler
 	toolHandler       *handlers.ToolHandler
 	textInput         textinput.Model
+	editor            textarea.Model // Multi-line editor, used for pasted multi-line input
+	multiline         bool
+	form              *components.ConnectionForm // Connection edit form opened by /edit, nil when closed
+	builder           *components.QueryBuilder   // Query builder opened by /build, nil when closed
+	pager             *components.ResultPager    // Result pager opened by /browse, nil when closed
+	grid              *components.DataGrid       // Data grid opened by /grid, nil when closed
+	gridQuery         string                     // Query of the rows in the data grid
 	confirmationList  list.Model
 	width             int
 	height            int
 	streamingResponse string
+	cancelTurn        context.CancelFunc // Cancels the running AI turn, nil when none runs
+	workloadProgress  string             // Progress of the running /capture or /replay, "" when none runs
+	renderInterval    time.Duration      // Streamed chunks are coalesced for this long before re-rendering
 	program           *tea.Program
+	// Turn timing, recorded for /timing
+	timingTurn          bool
+	renderTime          time.Duration
+	renderFrames        int
+	confirmationShownAt time.Time
 }
 
 // NewModel creates a new Bubble Tea model
@@ -53,7 +74,9 @@ func NewModel(client *ai.Client, dbTools dbinterfaces.DatabaseInterface, connSer
 		inputHandler:     inputHandler,
 		toolHandler:      toolHandler,
 		textInput:        textInput,
+		editor:           components.CreateEditor(),
 		confirmationList: confirmationList,
+		renderInterval:   renderInterval(),
 		width:            80,
 		height:           24,
 	}
@@ -126,6 +149,25 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case models.VersionUpdateMsg:
 		return m.handleVersionUpdate(msg)
+
+	case models.HealthChangedMsg:
+		// Nothing to update: the status bar reads the health when redrawn
+		return m, nil
+
+	case models.GridWriteMsg:
+		return m.handleGridWrite(msg)
+
+	case models.ResultPageMsg:
+		return m.handleResultPage(msg)
+
+	case models.TurnAbortedMsg:
+		return m.handleTurnAborted(msg)
+
+	case models.WorkloadProgressMsg:
+		return m.handleWorkloadProgress(msg)
+
+	case models.WorkloadDoneMsg:
+		return m.handleWorkloadDone(msg)
 	}
 
 	return m, nil
@@ -133,6 +175,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View renders the interface
 func (m *Model) View() string {
+	defer m.timeRender(time.Now())
+
 	var contentSections []string
 
 	// Fixed welcome message box with status
@@ -155,7 +199,7 @@ func (m *Model) View() string {
 
 	// Help information (if needed)
 	if m.stateManager.IsShowHelp() {
-		help := m.contentRenderer.RenderHelp()
+		help := m.contentRenderer.RenderHelp(handlers.HelpSections())
 		contentSections = append(contentSections, help)
 	}
 
@@ -179,7 +223,7 @@ func (m *Model) View() string {
 
 	// Thinking state
 	if m.stateManager.GetState() == models.StateThinking {
-		thinking := m.contentRenderer.RenderThinking()
+		thinking := m.contentRenderer.RenderThinking(m.workloadProgress)
 		contentSections = append(contentSections, thinking)
 	}
 
@@ -197,7 +241,17 @@ func (m *Model) View() string {
 	var commandList string
 	var parameterHelp string
 
-	if m.stateManager.GetState() != models.StateToolConfirmation {
+	if m.stateManager.GetState() != models.StateToolConfirmation && m.grid != nil {
+		inputBox = m.contentRenderer.RenderDataGrid(m.gridQuery, m.grid.View())
+	} else if m.stateManager.GetState() != models.StateToolConfirmation && m.pager != nil {
+		inputBox = m.contentRenderer.RenderResultPager(m.pager.Query(), m.pager.Result(), m.pager.Status())
+	} else if m.stateManager.GetState() != models.StateToolConfirmation && m.builder != nil {
+		inputBox = m.contentRenderer.RenderQueryBuilder(m.builder.View())
+	} else if m.stateManager.GetState() != models.StateToolConfirmation && m.form != nil {
+		inputBox = m.contentRenderer.RenderConnectionForm(m.form.Name(), m.form.View())
+	} else if m.stateManager.GetState() != models.StateToolConfirmation && m.multiline {
+		inputBox = m.contentRenderer.RenderEditor(m.editor.View())
+	} else if m.stateManager.GetState() != models.StateToolConfirmation {
 		inputBox = m.renderInputBoxWithConnection()
 
 		if m.stateManager.IsShowSuggestions() {
@@ -216,7 +270,9 @@ func (m *Model) View() string {
 	showDivider := len(history) > 0 && !m.stateManager.IsShowHelp() &&
 		!m.stateManager.IsShowSuggestions() && !m.stateManager.IsShowParameterHelp()
 
-	return m.layoutRenderer.BuildLayout(contentSections, inputBox, commandList, parameterHelp, showDivider)
+	statusBar := m.layoutRenderer.RenderStatusBar(m.stateManager.GetStatusBarInfo())
+
+	return m.layoutRenderer.BuildLayout(contentSections, inputBox, commandList, parameterHelp, statusBar, showDivider)
 }
 
 // renderInputBoxWithConnection renders the input box with connection indicator
@@ -244,7 +300,22 @@ func (m *Model) renderInputBoxWithConnection() string {
 
 // Run runs the Bubble Tea program
 func Run(aiClient *ai.Client, dbTools dbinterfaces.DatabaseInterface, connService dbinterfaces.ConnectionServiceInterface) error {
-	model := NewModel(aiClient, dbTools, connService)
+	return run(NewModel(aiClient, dbTools, connService), connService)
+}
+
+// RunDemo runs the Bubble Tea program on the current connection of
+// connService with a guided tour, and firstQuestion placed in the input
+func RunDemo(aiClient *ai.Client, connService dbinterfaces.ConnectionServiceInterface, tour *models.GuidanceInfo, firstQuestion string) error {
+	model := NewModel(aiClient, connService.GetCurrentTools(), connService)
+	model.stateManager.SetTour(tour)
+	model.textInput.SetValue(firstQuestion)
+	model.textInput.CursorEnd()
+	return run(model, connService)
+}
+
+func run(model *Model, connService dbinterfaces.ConnectionServiceInterface) error {
+	// Ask the terminal for its background before the program reads input
+	renderers.SetDarkBackground(lipgloss.HasDarkBackground())
 
 	p := tea.NewProgram(
 		model,
@@ -255,6 +326,14 @@ func Run(aiClient *ai.Client, dbTools dbinterfaces.DatabaseInterface, connServic
 	// Start version checking service with the program reference
 	version.StartVersionService(p)
 
+	// Check the connections in the background and redraw the status bar when one drops or recovers
+	if connService != nil {
+		connService.StartHealthMonitor(database.MonitorInterval(), func() {
+			p.Send(models.HealthChangedMsg{})
+		})
+		defer connService.StopHealthMonitor()
+	}
+
 	_, err := p.Run()
 	return err
 }
@@ -17,11 +17,13 @@ type ConfirmationItem struct {
 	title       string
 	description string
 	action      string
+	key         string
 }
 
 func (i ConfirmationItem) Title() string       { return i.title }
 func (i ConfirmationItem) Description() string { return i.description }
 func (i ConfirmationItem) FilterValue() string { return i.title }
+func (i ConfirmationItem) Action() string      { return i.action }
 
 // Custom styles for confirmation list - minimal padding
 var (
@@ -46,6 +48,9 @@ func (d confirmationDelegate) Render(w io.Writer, m list.Model, index int, listI
 	}
 
 	str := fmt.Sprintf("%d. %s", index+1, i.title)
+	if i.key != "" {
+		str = fmt.Sprintf("%d. [%s] %s", index+1, i.key, i.title)
+	}
 
 	fn := confirmationItemStyle.Render
 	if index == m.Index() {
@@ -83,6 +88,7 @@ func SetupConfirmationList(confirmationList *list.Model, toolInfo *models.ToolCo
 				title:       option.Label,
 				description: option.Description,
 				action:      option.Action,
+				key:         option.Key,
 			})
 		}
 	}
@@ -90,6 +96,17 @@ func SetupConfirmationList(confirmationList *list.Model, toolInfo *models.ToolCo
 	confirmationList.SetItems(items)
 	// Set simple title without duplicating SQL
 	if toolInfo != nil {
-		confirmationList.Title = "Choose an action:"
+		confirmationList.Title = "Choose an action (press a key or number, or ↑/↓ and Enter):"
+	}
+}
+
+// OptionForKey returns the option chosen by a key press: the option's own key
+// (case-insensitive) or its 1-based number
+func OptionForKey(options []models.ConfirmationOption, key string) (models.ConfirmationOption, bool) {
+	for i, option := range options {
+		if (option.Key != "" && strings.EqualFold(option.Key, key)) || key == fmt.Sprint(i+1) {
+			return option, true
+		}
 	}
+	return models.ConfirmationOption{}, false
 }
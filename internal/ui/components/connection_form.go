@@ -0,0 +1,140 @@
+package components
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// ConnectionForm edits the settings of an existing connection, one field per line
+type ConnectionForm struct {
+	fieldSet
+	original dbinterfaces.ConnectionConfig
+	err      string
+}
+
+// Connection form field order, also the order of the fields slice
+const (
+	fieldType = iota
+	fieldHost
+	fieldPort
+	fieldDatabase
+	fieldUsername
+	fieldPassword
+	fieldPasswordRef
+	fieldSSLMode
+	fieldReplicas
+	fieldMaxConcurrency
+	fieldReadOnly
+	fieldDescription
+)
+
+// NewConnectionForm creates a form pre-filled with a connection's settings
+func NewConnectionForm(config *dbinterfaces.ConnectionConfig) *ConnectionForm {
+	form := &ConnectionForm{original: *config}
+	port := ""
+	if config.Port != 0 {
+		port = strconv.Itoa(config.Port)
+	}
+	concurrency := ""
+	if config.MaxConcurrency != 0 {
+		concurrency = strconv.Itoa(config.MaxConcurrency)
+	}
+	readOnly := "no"
+	if config.ReadOnly {
+		readOnly = "yes"
+	}
+
+	form.add("Type", config.Type)
+	form.add("Host", config.Host)
+	form.add("Port", port)
+	form.add("Database", config.Database)
+	form.add("Username", config.Username)
+	form.add("Password", config.Password)
+	form.add("Password ref", config.PasswordRef)
+	form.add("SSL mode", config.SSLMode)
+	form.add("Replicas", strings.Join(config.Replicas, ", "))
+	form.add("Max concurrency", concurrency)
+	form.add("Read-only", readOnly)
+	form.add("Description", config.Description)
+	form.fields[fieldPassword].input.EchoMode = textinput.EchoPassword
+	return form
+}
+
+// Name returns the name of the connection being edited
+func (f *ConnectionForm) Name() string {
+	return f.original.Name
+}
+
+// SetError shows why the settings were not saved
+func (f *ConnectionForm) SetError(err string) {
+	f.err = err
+}
+
+// Config returns the edited settings, or an error when a number or the
+// read-only flag is invalid
+func (f *ConnectionForm) Config() (*dbinterfaces.ConnectionConfig, error) {
+	number := func(field int) (int, error) {
+		if f.value(field) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(f.value(field))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a positive number", strings.ToLower(f.fields[field].label))
+		}
+		return n, nil
+	}
+
+	port, err := number(fieldPort)
+	if err != nil {
+		return nil, err
+	}
+	if port > 65535 {
+		return nil, fmt.Errorf("port must be at most 65535")
+	}
+	concurrency, err := number(fieldMaxConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	var readOnly bool
+	switch strings.ToLower(f.value(fieldReadOnly)) {
+	case "yes", "y", "true", "on":
+		readOnly = true
+	case "no", "n", "false", "off", "":
+	default:
+		return nil, fmt.Errorf("read-only must be yes or no")
+	}
+
+	config := f.original
+	config.Type = strings.ToLower(f.value(fieldType))
+	config.Host = f.value(fieldHost)
+	config.Port = port
+	config.Database = f.value(fieldDatabase)
+	config.Username = f.value(fieldUsername)
+	config.Password = f.fields[fieldPassword].input.Value() // Spaces may be part of a password
+	config.PasswordRef = f.value(fieldPasswordRef)
+	config.SSLMode = f.value(fieldSSLMode)
+	config.Replicas = nil
+	for _, replica := range strings.Split(f.value(fieldReplicas), ",") {
+		if replica = strings.TrimSpace(replica); replica != "" {
+			config.Replicas = append(config.Replicas, replica)
+		}
+	}
+	config.MaxConcurrency = concurrency
+	config.ReadOnly = readOnly
+	config.Description = f.value(fieldDescription)
+	return &config, nil
+}
+
+// View renders the fields with their labels and the last error
+func (f *ConnectionForm) View() string {
+	lines := f.view()
+	if f.err != "" {
+		lines = append(lines, errorLine("Not saved: ", f.err))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,344 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	gridVisibleRows = 15 // Rows shown at once
+	gridCellWidth   = 24 // Characters shown of a cell
+)
+
+// gridMode is what the data grid is doing
+type gridMode int
+
+const (
+	gridBrowsing   gridMode = iota
+	gridEditing             // A cell value is being typed
+	gridConfirming          // The UPDATE of an edit waits for y or n
+	gridWriting             // The UPDATE runs in the background
+)
+
+// DataGrid shows the rows of a query as a table with a cursor on one cell.
+// Editing a cell previews the UPDATE writing it back, which runs once it is
+// confirmed; the result arrives as GridWriteMsg.
+type DataGrid struct {
+	columns []string
+	rows    [][]interface{}
+	more    bool // The query has more rows than the grid holds
+	canEdit func(column int) error
+	prepare func(row []interface{}, column int, value *string) (string, func() error, error)
+
+	row, column int // Cursor
+	top, left   int // First row and column shown
+	width       int
+
+	mode    gridMode
+	input   textinput.Model
+	value   *string      // Value of the edit waiting for confirmation, nil for NULL
+	preview string       // UPDATE of the edit waiting for confirmation
+	apply   func() error // Runs that UPDATE
+	message string
+	failed  bool // message is an error
+}
+
+// NewDataGrid creates a grid over the rows of a result. canEdit says why a
+// column cannot be edited, and prepare returns the statement setting a cell
+// (nil value for NULL) and the function running it.
+func NewDataGrid(result *models.QueryResult, more bool, canEdit func(column int) error, prepare func(row []interface{}, column int, value *string) (string, func() error, error)) *DataGrid {
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 0
+	return &DataGrid{
+		columns: result.Columns,
+		rows:    result.Rows,
+		more:    more,
+		canEdit: canEdit,
+		prepare: prepare,
+		width:   80,
+		input:   input,
+	}
+}
+
+// SetWidth updates the width the grid fits its columns in
+func (g *DataGrid) SetWidth(windowWidth int) {
+	g.width = windowWidth - 4
+	g.input.Width = max(windowWidth-24, 20)
+}
+
+// Idle reports whether the grid is not editing a cell, so it can be closed
+func (g *DataGrid) Idle() bool {
+	return g.mode == gridBrowsing
+}
+
+// Update handles a key
+func (g *DataGrid) Update(msg tea.KeyMsg) tea.Cmd {
+	switch g.mode {
+	case gridEditing:
+		return g.updateEditing(msg)
+	case gridConfirming:
+		return g.updateConfirming(msg)
+	case gridWriting:
+		return nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		g.moveTo(g.row-1, g.column)
+	case "down", "j":
+		g.moveTo(g.row+1, g.column)
+	case "left", "h", "shift+tab":
+		g.moveTo(g.row, g.column-1)
+	case "right", "l", "tab":
+		g.moveTo(g.row, g.column+1)
+	case "pgup":
+		g.moveTo(g.row-gridVisibleRows, g.column)
+	case "pgdown", " ":
+		g.moveTo(g.row+gridVisibleRows, g.column)
+	case "home", "g":
+		g.moveTo(0, g.column)
+	case "end", "G":
+		g.moveTo(len(g.rows)-1, g.column)
+	case "enter", "e":
+		return g.startEditing()
+	}
+	return nil
+}
+
+// moveTo moves the cursor, keeping it on the grid and in view
+func (g *DataGrid) moveTo(row, column int) {
+	g.row = min(max(row, 0), max(len(g.rows)-1, 0))
+	g.column = min(max(column, 0), max(len(g.columns)-1, 0))
+	if g.row < g.top {
+		g.top = g.row
+	}
+	if g.row >= g.top+gridVisibleRows {
+		g.top = g.row - gridVisibleRows + 1
+	}
+	if g.column < g.left {
+		g.left = g.column
+	}
+	g.message = ""
+}
+
+// startEditing opens the input on the cell under the cursor
+func (g *DataGrid) startEditing() tea.Cmd {
+	if len(g.rows) == 0 {
+		return nil
+	}
+	if err := g.canEdit(g.column); err != nil {
+		g.setMessage(err.Error(), true)
+		return nil
+	}
+	value := ""
+	if cell := g.rows[g.row][g.column]; cell != nil {
+		value = cellValue(cell)
+	}
+	g.input.SetValue(value)
+	g.input.CursorEnd()
+	g.mode = gridEditing
+	g.message = ""
+	return g.input.Focus()
+}
+
+func (g *DataGrid) updateEditing(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		g.input.Blur()
+		g.mode = gridBrowsing
+		return nil
+	case "enter":
+		value := g.input.Value()
+		g.confirm(&value)
+		return nil
+	case "ctrl+n":
+		g.confirm(nil)
+		return nil
+	}
+	var cmd tea.Cmd
+	g.input, cmd = g.input.Update(msg)
+	return cmd
+}
+
+// confirm prepares the UPDATE setting the cell to value and asks for confirmation
+func (g *DataGrid) confirm(value *string) {
+	preview, apply, err := g.prepare(g.rows[g.row], g.column, value)
+	if err != nil {
+		g.setMessage(err.Error(), true)
+		return
+	}
+	g.input.Blur()
+	g.mode = gridConfirming
+	g.value, g.preview, g.apply = value, preview, apply
+	g.message = ""
+}
+
+func (g *DataGrid) updateConfirming(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "enter":
+		g.mode = gridWriting
+		row, column, value, apply := g.row, g.column, g.value, g.apply
+		return func() tea.Msg {
+			return models.GridWriteMsg{Row: row, Column: column, Value: value, Err: apply()}
+		}
+	case "n", "esc":
+		g.mode = gridBrowsing
+		g.setMessage("Edit discarded.", false)
+	}
+	return nil
+}
+
+// Apply shows the outcome of a write started by a confirmed edit
+func (g *DataGrid) Apply(msg models.GridWriteMsg) {
+	g.mode = gridBrowsing
+	if msg.Err != nil {
+		g.setMessage("Not saved: "+msg.Err.Error(), true)
+		return
+	}
+	if msg.Row < len(g.rows) && msg.Column < len(g.rows[msg.Row]) {
+		if msg.Value == nil {
+			g.rows[msg.Row][msg.Column] = nil
+		} else {
+			g.rows[msg.Row][msg.Column] = *msg.Value
+		}
+	}
+	g.setMessage("Saved: "+g.preview, false)
+}
+
+func (g *DataGrid) setMessage(message string, failed bool) {
+	g.message, g.failed = message, failed
+}
+
+// cellValue renders a cell for display and editing
+func cellValue(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// gridCell flattens and shortens a cell to the grid's cell width
+func gridCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > gridCellWidth {
+		return string(runes[:gridCellWidth-1]) + "…"
+	}
+	return text
+}
+
+// visibleColumns returns the columns that fit the width from the first shown,
+// scrolling right until the cursor's column fits, and their widths
+func (g *DataGrid) visibleColumns(widths []int) (int, int) {
+	fits := func(left int) int {
+		used, last := 0, left
+		for c := left; c < len(widths); c++ {
+			used += widths[c] + 3
+			if used > g.width && c > left {
+				break
+			}
+			last = c
+		}
+		return last
+	}
+	last := fits(g.left)
+	for g.column > last && g.left < g.column {
+		g.left++
+		last = fits(g.left)
+	}
+	return g.left, last
+}
+
+// View renders the rows in view with the cursor, the position, and the edit
+// in progress
+func (g *DataGrid) View() string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	if len(g.columns) == 0 {
+		return muted.Render("The query returned no columns.")
+	}
+
+	bottom := min(g.top+gridVisibleRows, len(g.rows))
+	widths := make([]int, len(g.columns))
+	for c, name := range g.columns {
+		widths[c] = lipgloss.Width(gridCell(name))
+		for _, row := range g.rows[g.top:bottom] {
+			if c < len(row) {
+				widths[c] = max(widths[c], lipgloss.Width(gridCell(cellValue(row[c]))))
+			}
+		}
+	}
+	left, last := g.visibleColumns(widths)
+
+	header := lipgloss.NewStyle().Bold(true)
+	cursor := lipgloss.NewStyle().Reverse(true)
+	pad := func(text string, width int) string {
+		return text + strings.Repeat(" ", max(width-lipgloss.Width(text), 0))
+	}
+
+	var lines []string
+	var line strings.Builder
+	for c := left; c <= last; c++ {
+		line.WriteString(" " + header.Render(pad(gridCell(g.columns[c]), widths[c])) + " │")
+	}
+	lines = append(lines, line.String())
+	for r := g.top; r < bottom; r++ {
+		line.Reset()
+		for c := left; c <= last; c++ {
+			text := "NULL"
+			if c < len(g.rows[r]) {
+				text = cellValue(g.rows[r][c])
+			}
+			cell := pad(gridCell(text), widths[c])
+			switch {
+			case r == g.row && c == g.column:
+				cell = cursor.Render(cell)
+			case c < len(g.rows[r]) && g.rows[r][c] == nil:
+				cell = muted.Render(cell)
+			}
+			line.WriteString(" " + cell + " │")
+		}
+		lines = append(lines, line.String())
+	}
+
+	position := "no rows"
+	if len(g.rows) > 0 {
+		position = fmt.Sprintf("row %d of %d", g.row+1, len(g.rows))
+		if g.more {
+			position += "+ (only the first rows are shown)"
+		}
+		position += " · column " + g.columns[g.column]
+		if last < len(g.columns)-1 || left > 0 {
+			position += fmt.Sprintf(" (%d-%d of %d)", left+1, last+1, len(g.columns))
+		}
+	}
+	lines = append(lines, muted.Render(position))
+
+	switch g.mode {
+	case gridEditing:
+		lines = append(lines, header.Render(g.columns[g.column]+": ")+g.input.View())
+		lines = append(lines, muted.Render("enter to preview the UPDATE · ctrl+n to set NULL · esc to cancel"))
+	case gridConfirming:
+		lines = append(lines, g.preview)
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("Run this UPDATE? It is rolled back unless it changes exactly one row. y to run · n to discard"))
+	case gridWriting:
+		lines = append(lines, muted.Render("Saving..."))
+	}
+	if g.message != "" {
+		if g.failed {
+			lines = append(lines, errorLine("", g.message))
+		} else {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("46")).Render(g.message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,41 @@
+package components
+
+import (
+	"github.com/charmbracelet/bubbles/textarea"
+)
+
+// editorMaxVisibleLines limits how tall the multi-line editor grows
+const editorMaxVisibleLines = 12
+
+// CreateEditor creates the multi-line editor used for pasted SQL
+func CreateEditor() textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = ""
+	ta.ShowLineNumbers = true
+	ta.CharLimit = 0 // No limit, pasted scripts can be long
+	ta.MaxHeight = 0
+	ta.SetWidth(70)
+	ta.SetHeight(3)
+	return ta
+}
+
+// UpdateEditorWidth updates the editor width based on window dimensions
+func UpdateEditorWidth(ta *textarea.Model, windowWidth int) {
+	width := windowWidth - 4
+	if width < 20 {
+		width = 20
+	}
+	ta.SetWidth(width)
+}
+
+// FitEditorHeight grows the editor with its content up to a fixed maximum
+func FitEditorHeight(ta *textarea.Model) {
+	height := ta.LineCount()
+	if height < 3 {
+		height = 3
+	}
+	if height > editorMaxVisibleLines {
+		height = editorMaxVisibleLines
+	}
+	ta.SetHeight(height)
+}
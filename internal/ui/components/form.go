@@ -0,0 +1,91 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// formField is one labelled input of a form
+type formField struct {
+	label string
+	input textinput.Model
+}
+
+// fieldSet is a column of labelled inputs with one focused at a time,
+// shared by the forms replacing the input box
+type fieldSet struct {
+	fields []formField
+	focus  int
+}
+
+// add appends a field holding value, focusing it when it is the first
+func (s *fieldSet) add(label, value string) {
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.CharLimit = 500
+	ti.Width = 50
+	ti.SetValue(value)
+	if len(s.fields) == 0 {
+		ti.Focus()
+	}
+	s.fields = append(s.fields, formField{label: label, input: ti})
+}
+
+// value returns the trimmed text of a field
+func (s *fieldSet) value(field int) string {
+	return strings.TrimSpace(s.fields[field].input.Value())
+}
+
+// SetWidth updates the width of the inputs based on window dimensions
+func (s *fieldSet) SetWidth(windowWidth int) {
+	width := windowWidth - 24 // Reserve space for the labels
+	if width < 20 {
+		width = 20
+	}
+	for i := range s.fields {
+		s.fields[i].input.Width = width
+	}
+}
+
+// OnLastField reports whether the last field has the focus
+func (s *fieldSet) OnLastField() bool {
+	return s.focus == len(s.fields)-1
+}
+
+// Move moves the focus by delta fields, wrapping around
+func (s *fieldSet) Move(delta int) tea.Cmd {
+	s.fields[s.focus].input.Blur()
+	s.focus = (s.focus + delta + len(s.fields)) % len(s.fields)
+	return s.fields[s.focus].input.Focus()
+}
+
+// Update passes a key to the focused field
+func (s *fieldSet) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	s.fields[s.focus].input, cmd = s.fields[s.focus].input.Update(msg)
+	return cmd
+}
+
+// view renders one line per field with the label of the focused one highlighted
+func (s *fieldSet) view() []string {
+	labelStyle := lipgloss.NewStyle().Width(17).Foreground(lipgloss.Color("240"))
+	focusedStyle := labelStyle.Foreground(lipgloss.Color("39"))
+
+	lines := make([]string, 0, len(s.fields))
+	for i, field := range s.fields {
+		style := labelStyle
+		if i == s.focus {
+			style = focusedStyle
+		}
+		lines = append(lines, style.Render(field.label)+field.input.View())
+	}
+	return lines
+}
+
+// errorLine renders why a form could not be used
+func errorLine(prefix, err string) string {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(prefix + err)
+}
@@ -0,0 +1,166 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxBuilderHints is the number of table or column names shown under the builder
+const maxBuilderHints = 12
+
+// Query builder field order, also the order of the fields slice
+const (
+	builderTable = iota
+	builderColumns
+	builderFilters
+	builderOrder
+	builderLimit
+)
+
+// QueryBuilder builds a single-table SELECT from a table, columns, filters,
+// ordering and limit, previewing the SQL while the fields are filled in
+type QueryBuilder struct {
+	fieldSet
+	dialect string
+	tables  []string
+	columns func(table string) ([]string, error)
+	known   map[string][]string // Columns per table, looked up once
+}
+
+// NewQueryBuilder creates a builder for the tables of a database, starting
+// with table when it is not empty. columns looks up the columns of a table.
+func NewQueryBuilder(dialect, table string, tables []string, columns func(table string) ([]string, error)) *QueryBuilder {
+	b := &QueryBuilder{dialect: dialect, tables: tables, columns: columns, known: make(map[string][]string)}
+	b.add("Table", table)
+	b.add("Columns", "")
+	b.add("Filters", "")
+	b.add("Order by", "")
+	b.add("Limit", "100")
+	for i, placeholder := range []string{
+		"table name",
+		"all columns, or e.g. id, email, created_at",
+		"e.g. status = paid and total > 100",
+		"e.g. created_at desc",
+		"empty for no limit",
+	} {
+		b.fields[i].input.Placeholder = placeholder
+	}
+	if table != "" {
+		b.Move(1)
+	}
+	return b
+}
+
+// tableColumns returns the columns of the chosen table, or nil when unknown
+func (b *QueryBuilder) tableColumns() []string {
+	table := b.value(builderTable)
+	if columns, ok := b.known[table]; ok {
+		return columns
+	}
+	var columns []string
+	if b.columns != nil && b.hasTable(table) {
+		columns, _ = b.columns(table)
+	}
+	b.known[table] = columns
+	return columns
+}
+
+// hasTable reports whether a table exists, or true when the tables are unknown
+func (b *QueryBuilder) hasTable(table string) bool {
+	if len(b.tables) == 0 {
+		return true
+	}
+	for _, t := range b.tables {
+		if strings.EqualFold(t, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// SQL returns the query of the current fields, or why it cannot be built
+func (b *QueryBuilder) SQL() (string, error) {
+	table := b.value(builderTable)
+	if table != "" && !b.hasTable(table) {
+		return "", fmt.Errorf("there is no table %s", table)
+	}
+	filters, err := sqlanalysis.ParseFilters(b.value(builderFilters))
+	if err != nil {
+		return "", err
+	}
+	order, err := sqlanalysis.ParseOrder(b.value(builderOrder))
+	if err != nil {
+		return "", err
+	}
+	limit := 0
+	if text := b.value(builderLimit); text != "" {
+		limit, err = strconv.Atoi(text)
+		if err != nil || limit < 0 {
+			return "", fmt.Errorf("limit must be a positive number")
+		}
+	}
+
+	return sqlanalysis.BuildSelect(b.dialect, sqlanalysis.QuerySpec{
+		Table:   table,
+		Columns: sqlanalysis.ParseColumnList(b.value(builderColumns)),
+		Filters: filters,
+		OrderBy: order,
+		Limit:   limit,
+	}, b.tableColumns())
+}
+
+// hint lists the names that fit the focused field
+func (b *QueryBuilder) hint() string {
+	if b.focus == builderLimit {
+		return ""
+	}
+	if b.focus == builderTable {
+		prefix := strings.ToLower(b.value(builderTable))
+		var matches []string
+		for _, t := range b.tables {
+			if strings.HasPrefix(strings.ToLower(t), prefix) {
+				matches = append(matches, t)
+			}
+		}
+		sort.Strings(matches)
+		return "Tables: " + hintList(matches)
+	}
+
+	hint := "Columns: " + hintList(b.tableColumns())
+	if b.focus == builderFilters {
+		hint += "\nOperators: = != > >= < <= contains, starts with, ends with, in (a, b), is empty, is not empty; join filters with and"
+	}
+	return hint
+}
+
+// hintList joins names, shortened to the first few
+func hintList(names []string) string {
+	if len(names) == 0 {
+		return "none found"
+	}
+	if len(names) > maxBuilderHints {
+		return strings.Join(names[:maxBuilderHints], ", ") + fmt.Sprintf(" and %d more", len(names)-maxBuilderHints)
+	}
+	return strings.Join(names, ", ")
+}
+
+// View renders the fields, hints for the focused field and the SQL preview
+func (b *QueryBuilder) View() string {
+	lines := b.view()
+	if hint := b.hint(); hint != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(hint))
+	}
+	lines = append(lines, "")
+	if sql, err := b.SQL(); err != nil {
+		lines = append(lines, errorLine("Cannot build the query: ", err.Error()))
+	} else {
+		lines = append(lines, sql)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,213 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	pagerPageSize    = 50 // Rows per page
+	pagerCachedPages = 20 // Pages kept around the shown one; pages further back are read again
+)
+
+// errPagerClosed is returned by reads that finish after the pager was closed
+var errPagerClosed = errors.New("result pager closed")
+
+// ResultPager pages through the rows of a query, reading them from a cursor a
+// page at a time in the background, so large results are never held in
+// memory at once. Pages are read with Load and arrive as ResultPageMsg.
+type ResultPager struct {
+	query   string
+	reader  *pageReader
+	columns []string
+	pages   map[int][][]interface{} // Cached pages by index
+	page    int                     // Page shown
+	last    int                     // Index of the last page, -1 until the end was read
+	loading bool
+	err     error
+}
+
+// NewResultPager creates a pager for a query; open runs it and returns a
+// cursor over its rows, and is called again to go back past the cached pages
+func NewResultPager(query string, open func() (dbinterfaces.RowCursor, error)) *ResultPager {
+	return &ResultPager{
+		query:  query,
+		reader: &pageReader{open: open},
+		pages:  make(map[int][][]interface{}),
+		last:   -1,
+	}
+}
+
+// Query returns the query being paged
+func (p *ResultPager) Query() string {
+	return p.query
+}
+
+// Load shows a page, reading it in the background when it is not cached.
+// It returns nil when the page does not exist or another read is running.
+func (p *ResultPager) Load(page int) tea.Cmd {
+	if page < 0 || p.loading || (p.last >= 0 && page > p.last) {
+		return nil
+	}
+	if _, ok := p.pages[page]; ok {
+		p.page = page
+		return nil
+	}
+	p.loading = true
+	p.err = nil
+	reader := p.reader
+	return func() tea.Msg {
+		index, columns, rows, last, err := reader.read(page)
+		return models.ResultPageMsg{Page: index, Columns: columns, Rows: rows, Last: last, Err: err}
+	}
+}
+
+// Next shows the next page
+func (p *ResultPager) Next() tea.Cmd {
+	return p.Load(p.page + 1)
+}
+
+// Previous shows the previous page
+func (p *ResultPager) Previous() tea.Cmd {
+	return p.Load(p.page - 1)
+}
+
+// Apply shows a page read by Load
+func (p *ResultPager) Apply(msg models.ResultPageMsg) {
+	p.loading = false
+	if msg.Err != nil {
+		p.err = msg.Err
+		return
+	}
+	if msg.Columns != nil {
+		p.columns = msg.Columns
+	}
+	if msg.Last {
+		p.last = msg.Page
+	}
+	p.page = msg.Page
+	p.pages[msg.Page] = msg.Rows
+	for index := range p.pages {
+		if index < p.page-pagerCachedPages || index > p.page+pagerCachedPages {
+			delete(p.pages, index)
+		}
+	}
+}
+
+// Close releases the cursor, in the background when a read is running
+func (p *ResultPager) Close() {
+	go p.reader.close()
+}
+
+// Result returns the rows of the shown page as a result, or nil before the
+// first page was read
+func (p *ResultPager) Result() *models.QueryResult {
+	rows, ok := p.pages[p.page]
+	if !ok {
+		return nil
+	}
+	return &models.QueryResult{Columns: p.columns, Rows: rows, RowCount: len(rows)}
+}
+
+// Status describes the shown page, e.g. "rows 51-100 · page 2 of 3"
+func (p *ResultPager) Status() string {
+	switch {
+	case p.err != nil:
+		return "Error: " + p.err.Error()
+	case p.loading && p.Result() == nil:
+		return "Running the query..."
+	}
+
+	rows := len(p.pages[p.page])
+	first := p.page*pagerPageSize + 1
+	var status string
+	switch {
+	case rows == 0:
+		status = "no rows"
+	case p.last >= 0:
+		if lastRows, ok := p.pages[p.last]; ok {
+			status = fmt.Sprintf("rows %d-%d of %d · page %d of %d", first, first+rows-1, p.last*pagerPageSize+len(lastRows), p.page+1, p.last+1)
+		} else {
+			status = fmt.Sprintf("rows %d-%d · page %d of %d", first, first+rows-1, p.page+1, p.last+1)
+		}
+	default:
+		status = fmt.Sprintf("rows %d-%d · page %d · more rows follow", first, first+rows-1, p.page+1)
+	}
+	if p.loading {
+		status += " · loading..."
+	}
+	return status
+}
+
+// pageReader reads pages from a cursor for a ResultPager. It is only used by
+// one read at a time, from the background.
+type pageReader struct {
+	mu     sync.Mutex
+	open   func() (dbinterfaces.RowCursor, error)
+	cursor dbinterfaces.RowCursor
+	next   int             // Index of the page the cursor reads next
+	prev   [][]interface{} // Rows of page next-1
+	done   bool            // The cursor reached the end of the result
+	closed bool
+}
+
+// read returns the columns and rows of a page and whether it is the last one.
+// A page after the last returns the last page with its index.
+func (r *pageReader) read(page int) (int, []string, [][]interface{}, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, nil, nil, false, errPagerClosed
+	}
+
+	if r.cursor == nil || page < r.next-1 {
+		// Pages before the cursor are read again from the start
+		if r.cursor != nil {
+			r.cursor.Close()
+		}
+		cursor, err := r.open()
+		if err != nil {
+			r.cursor = nil
+			return 0, nil, nil, false, err
+		}
+		r.cursor, r.next, r.prev, r.done = cursor, 0, nil, false
+	}
+	if page == r.next-1 {
+		return page, r.cursor.Columns(), r.prev, r.done, nil
+	}
+
+	for !r.done {
+		rows, err := r.cursor.Next(pagerPageSize)
+		if err != nil {
+			return 0, nil, nil, false, err
+		}
+		r.done = len(rows) < pagerPageSize
+		if len(rows) == 0 && r.next > 0 {
+			// The previous page was full and the last one
+			return r.next - 1, r.cursor.Columns(), r.prev, true, nil
+		}
+		r.prev = rows
+		r.next++
+		if r.next-1 == page {
+			break
+		}
+	}
+	return r.next - 1, r.cursor.Columns(), r.prev, r.done, nil
+}
+
+// close releases the cursor once a running read finished
+func (r *pageReader) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.cursor != nil {
+		r.cursor.Close()
+		r.cursor = nil
+	}
+}
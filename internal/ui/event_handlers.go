@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"dbsage/internal/ai"
+	"dbsage/internal/ai/tools"
 	"dbsage/internal/models"
 	"dbsage/internal/ui/components"
+	"dbsage/pkg/dbinterfaces"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -25,9 +27,20 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 	m.layoutRenderer.SetDimensions(m.width, m.height)
 	m.contentRenderer.SetWidth(m.width)
 	m.commandRenderer.SetWidth(m.width)
+	m.stateManager.SetTerminalSize(m.width, m.height)
 
-	// Update text input width
+	// Update text input and editor width
 	components.UpdateTextInputWidth(&m.textInput, m.width)
+	components.UpdateEditorWidth(&m.editor, m.width)
+	if m.form != nil {
+		m.form.SetWidth(m.width)
+	}
+	if m.builder != nil {
+		m.builder.SetWidth(m.width)
+	}
+	if m.grid != nil {
+		m.grid.SetWidth(m.width)
+	}
 
 	// Update confirmation list dimensions
 	m.confirmationList.SetWidth(m.width - 4)
@@ -38,6 +51,29 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress handles keyboard input
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.form != nil {
+		return m.handleFormKeyPress(msg)
+	}
+	if m.builder != nil {
+		return m.handleBuilderKeyPress(msg)
+	}
+	if m.pager != nil {
+		return m.handlePagerKeyPress(msg)
+	}
+	if m.grid != nil {
+		return m.handleGridKeyPress(msg)
+	}
+	if m.multiline {
+		return m.handleEditorKeyPress(msg)
+	}
+
+	// A bracketed paste arrives as a single message; multi-line content
+	// opens the editor instead of being flattened into the single-line input
+	if msg.Paste && strings.ContainsAny(string(msg.Runes), "\r\n") &&
+		(m.stateManager.GetState() == models.StateInput || m.stateManager.GetState() == models.StateResponse) {
+		return m.openEditor(m.textInput.Value() + string(msg.Runes))
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		return m, tea.Quit
@@ -48,6 +84,9 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return models.ToolConfirmationResponseMsg{Confirmed: false, Action: "cancel"}
 			}
 		}
+		if m.cancelTurn != nil {
+			return m.abortTurn()
+		}
 		return m, tea.Quit
 
 	case "ctrl+h", "?":
@@ -104,20 +143,18 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.handleInput()
 		} else if m.stateManager.GetState() == models.StateToolConfirmation {
 			if selectedItem, ok := m.confirmationList.SelectedItem().(components.ConfirmationItem); ok {
-				confirmed := selectedItem.Title() == "Execute"
-				action := "execute"
-				if !confirmed {
-					action = "cancel"
-				}
-				return m, func() tea.Msg {
-					return models.ToolConfirmationResponseMsg{Confirmed: confirmed, Action: action}
-				}
+				return m, confirmationResponse(selectedItem.Action())
 			}
 		}
 		return m, nil
 
 	default:
 		if m.stateManager.GetState() == models.StateToolConfirmation {
+			if toolInfo := m.stateManager.GetPendingToolConfirmation(); toolInfo != nil {
+				if option, ok := components.OptionForKey(toolInfo.Options, msg.String()); ok {
+					return m, confirmationResponse(option.Action)
+				}
+			}
 			var cmd tea.Cmd
 			m.confirmationList, cmd = m.confirmationList.Update(msg)
 			return m, cmd
@@ -141,6 +178,13 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// confirmationResponse answers the pending tool confirmation with an option's action
+func confirmationResponse(action string) tea.Cmd {
+	return func() tea.Msg {
+		return models.ToolConfirmationResponseMsg{Confirmed: action == "execute", Action: action}
+	}
+}
+
 // handleTabCompletion handles tab completion
 func (m *Model) handleTabCompletion() (tea.Model, tea.Cmd) {
 	if m.stateManager.IsShowSuggestions() {
@@ -178,10 +222,237 @@ func (m *Model) handleSuggestionSelection() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// openEditor switches from the single-line input to the multi-line editor
+func (m *Model) openEditor(content string) (tea.Model, tea.Cmd) {
+	if m.stateManager.GetState() == models.StateResponse {
+		m.stateManager.SetState(models.StateInput)
+		m.stateManager.SetResponse("")
+		m.stateManager.SetError(nil)
+	}
+	m.stateManager.SetShowSuggestions(false)
+	m.stateManager.SetCommandSuggestions(nil)
+
+	m.multiline = true
+	m.textInput.Blur()
+	m.editor.SetValue(strings.ReplaceAll(content, "\r\n", "\n"))
+	components.FitEditorHeight(&m.editor)
+	return m, m.editor.Focus()
+}
+
+// closeEditor returns to the single-line input, keeping the text on one line
+func (m *Model) closeEditor() {
+	m.multiline = false
+	m.editor.Blur()
+	m.textInput.SetValue(strings.Join(strings.Fields(m.editor.Value()), " "))
+	m.textInput.CursorEnd()
+	m.editor.Reset()
+	m.textInput.Focus()
+}
+
+// handleEditorKeyPress handles keyboard input while the multi-line editor is open
+func (m *Model) handleEditorKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.closeEditor()
+		return m, textinput.Blink
+
+	case "ctrl+s", "ctrl+d":
+		input := strings.TrimSpace(m.editor.Value())
+		if input == "" {
+			return m, nil
+		}
+		m.multiline = false
+		m.editor.Blur()
+		m.editor.Reset()
+		return m.submitInput(input)
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	components.FitEditorHeight(&m.editor)
+	return m, cmd
+}
+
+// openForm replaces the input with the edit form of a connection
+func (m *Model) openForm(config *dbinterfaces.ConnectionConfig) {
+	m.form = components.NewConnectionForm(config)
+	m.form.SetWidth(m.width)
+	m.textInput.Blur()
+}
+
+// closeForm returns to the single-line input
+func (m *Model) closeForm() {
+	m.form = nil
+	m.textInput.Focus()
+}
+
+// handleFormKeyPress handles keyboard input while the connection edit form is open
+func (m *Model) handleFormKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.stateManager.SetResponse(fmt.Sprintf("Edit of '%s' cancelled, nothing was changed.", m.form.Name()))
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.closeForm()
+		return m, textinput.Blink
+
+	case "tab", "down":
+		return m, m.form.Move(1)
+
+	case "shift+tab", "up":
+		return m, m.form.Move(-1)
+
+	case "enter":
+		if !m.form.OnLastField() {
+			return m, m.form.Move(1)
+		}
+		return m.saveForm()
+
+	case "ctrl+s":
+		return m.saveForm()
+	}
+
+	return m, m.form.Update(msg)
+}
+
+// saveForm tests and saves the edited connection. The form stays open with
+// the error when the settings are invalid or the connection test fails.
+func (m *Model) saveForm() (tea.Model, tea.Cmd) {
+	config, err := m.form.Config()
+	if err != nil {
+		m.form.SetError(err.Error())
+		return m, nil
+	}
+	if err := m.stateManager.SaveConnectionEdit(config); err != nil {
+		m.form.SetError(err.Error())
+		return m, nil
+	}
+	m.closeForm()
+	return m, textinput.Blink
+}
+
+// handleBuilderKeyPress handles keyboard input while the query builder is open
+func (m *Model) handleBuilderKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.builder = nil
+		m.stateManager.SetResponse("Query builder closed.")
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.textInput.Focus()
+		return m, textinput.Blink
+
+	case "tab", "down", "enter":
+		return m, m.builder.Move(1)
+
+	case "shift+tab", "up":
+		return m, m.builder.Move(-1)
+
+	case "ctrl+r", "ctrl+e":
+		sql, err := m.builder.SQL()
+		if err != nil {
+			// The preview already shows why
+			return m, nil
+		}
+		// Both go through the AI, which runs the query or changes it as asked
+		sql = strings.Join(strings.Fields(sql), " ")
+		fill := "Execute this SQL: " + sql
+		if msg.String() == "ctrl+e" {
+			fill = "Refine this SQL: " + sql + " Change: "
+		}
+		m.builder = nil
+		m.stateManager.SetResponse("Query placed in the input: press Enter to send it, or edit it first.")
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.textInput.SetValue(fill)
+		m.textInput.CursorEnd()
+		m.textInput.Focus()
+		return m, textinput.Blink
+	}
+
+	return m, m.builder.Update(msg)
+}
+
+// handlePagerKeyPress pages through the result pager or closes it
+func (m *Model) handlePagerKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.pager.Close()
+		return m, tea.Quit
+
+	case "esc", "q":
+		m.pager.Close()
+		m.pager = nil
+		m.stateManager.SetResponse("Result pager closed.")
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.textInput.Focus()
+		return m, textinput.Blink
+
+	case "n", "right", "pgdown", " ":
+		return m, m.pager.Next()
+
+	case "p", "left", "pgup":
+		return m, m.pager.Previous()
+
+	case "g", "home":
+		return m, m.pager.Load(0)
+	}
+	return m, nil
+}
+
+// handleResultPage shows a page read for the result pager
+func (m *Model) handleResultPage(msg models.ResultPageMsg) (tea.Model, tea.Cmd) {
+	if m.pager != nil {
+		m.pager.Apply(msg)
+	}
+	return m, nil
+}
+
+// handleGridKeyPress moves through and edits the data grid, or closes it
+func (m *Model) handleGridKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc", "q":
+		if !m.grid.Idle() {
+			break
+		}
+		m.grid = nil
+		m.stateManager.SetResponse("Data grid closed.")
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.textInput.Focus()
+		return m, textinput.Blink
+	}
+	return m, m.grid.Update(msg)
+}
+
+// handleGridWrite shows the outcome of writing an edited cell of the data grid
+func (m *Model) handleGridWrite(msg models.GridWriteMsg) (tea.Model, tea.Cmd) {
+	if m.grid != nil {
+		m.grid.Apply(msg)
+	}
+	return m, nil
+}
+
 // handleInput handles user input submission
 func (m *Model) handleInput() (tea.Model, tea.Cmd) {
-	input := strings.TrimSpace(m.textInput.Value())
+	return m.submitInput(strings.TrimSpace(m.textInput.Value()))
+}
 
+// submitInput processes submitted input from the single-line input or the editor
+func (m *Model) submitInput(input string) (tea.Model, tea.Cmd) {
 	// Clear suggestions
 	m.stateManager.SetShowSuggestions(false)
 	m.stateManager.SetCommandSuggestions(nil)
@@ -189,7 +460,7 @@ func (m *Model) handleInput() (tea.Model, tea.Cmd) {
 	m.stateManager.SetParameterHelp("")
 
 	// Process input through state manager (handles commands)
-	shouldContinue, _ := m.stateManager.ProcessInput(input)
+	shouldContinue, aiPrompt := m.stateManager.ProcessInput(input)
 	if !shouldContinue {
 		// Exit was requested
 		return m, tea.Quit
@@ -197,15 +468,61 @@ func (m *Model) handleInput() (tea.Model, tea.Cmd) {
 
 	// Check if it was handled as a command
 	if m.stateManager.GetState() == models.StateResponse {
-		// Command was handled, reset input and ensure focus
-		m.textInput.SetValue("")
+		// Command was handled, reset input (or fill it, for /jump) and ensure focus
+		m.textInput.SetValue(m.stateManager.TakeInputFill())
+		m.textInput.CursorEnd()
+		if config := m.stateManager.TakeConnectionEdit(); config != nil {
+			m.openForm(config)
+			return m, textinput.Blink
+		}
+		if schema := m.stateManager.TakeQueryBuilder(); schema != nil {
+			m.builder = components.NewQueryBuilder(schema.Dialect, schema.Table, schema.Tables, schema.Columns)
+			m.builder.SetWidth(m.width)
+			m.textInput.Blur()
+			return m, textinput.Blink
+		}
+		if source := m.stateManager.TakeResultPager(); source != nil {
+			m.pager = components.NewResultPager(source.Query, source.Open)
+			m.textInput.Blur()
+			return m, m.pager.Load(0)
+		}
+		if job := m.stateManager.TakeWorkloadJob(); job != nil {
+			return m.runWorkload(job)
+		}
+		if source := m.stateManager.TakeDataGrid(); source != nil {
+			m.grid = components.NewDataGrid(source.Result, source.More, source.CanEdit, source.Prepare)
+			m.grid.SetWidth(m.width)
+			m.gridQuery = source.Query
+			m.textInput.Blur()
+			return m, nil
+		}
 		m.textInput.Focus()
 		return m, func() tea.Msg { return models.CommandCompletedMsg{} }
 	}
 
 	// Not a command, process as AI query
+	// Commands like /review may expand the input into a fuller prompt
+	if aiPrompt != "" {
+		input = aiPrompt
+	}
+	input = m.stateManager.ExpandVariables(input)
+
+	// Large contexts are held back with a token and cost estimate until /send
+	if preview, held := m.stateManager.HoldForCostPreview(input); held {
+		m.stateManager.SetResponse(preview)
+		m.stateManager.SetError(nil)
+		m.stateManager.SetState(models.StateResponse)
+		m.textInput.SetValue("")
+		m.textInput.Focus()
+		return m, func() tea.Msg { return models.CommandCompletedMsg{} }
+	}
+
 	// Add user message to history
 	m.stateManager.AddToHistory(openai.ChatMessageRoleUser, input)
+	if aiClient := m.stateManager.GetAIClient(); aiClient != nil {
+		aiClient.RecordQuestion(input)
+	}
+	m.beginTurnTiming()
 
 	// Send thinking state message and start AI query
 	return m, tea.Batch(
@@ -223,6 +540,8 @@ func (m *Model) handleAIThinking() (tea.Model, tea.Cmd) {
 
 // handleAIResponse handles AI response
 func (m *Model) handleAIResponse(msg models.AIResponseMsg) (tea.Model, tea.Cmd) {
+	m.endTurn()
+	m.finishTurnTiming()
 	if msg.Err != nil {
 		m.stateManager.SetError(msg.Err)
 		m.stateManager.SetState(models.StateResponse)
@@ -273,9 +592,11 @@ func (m *Model) handleStreamChunk(msg models.AIStreamChunkMsg) (tea.Model, tea.C
 
 // handleStreamComplete handles streaming completion
 func (m *Model) handleStreamComplete(msg models.AIStreamCompleteMsg) (tea.Model, tea.Cmd) {
+	m.endTurn()
 	m.stateManager.AddToHistory(openai.ChatMessageRoleAssistant, msg.FullResponse)
 
 	if m.stateManager.GetState() != models.StateToolConfirmation {
+		m.finishTurnTiming()
 		m.stateManager.SetState(models.StateInput)
 		m.textInput.SetValue("")
 		m.textInput.Focus()
@@ -290,6 +611,7 @@ func (m *Model) handleToolConfirmation(msg models.ToolConfirmationMsg) (tea.Mode
 	m.stateManager.SetPendingToolConfirmation(msg.ToolInfo)
 	m.stateManager.SetState(models.StateToolConfirmation)
 	m.textInput.Blur()
+	m.confirmationShownAt = time.Now()
 
 	components.SetupConfirmationList(&m.confirmationList, msg.ToolInfo)
 
@@ -298,9 +620,21 @@ func (m *Model) handleToolConfirmation(msg models.ToolConfirmationMsg) (tea.Mode
 
 // handleToolConfirmationResponse handles tool confirmation responses
 func (m *Model) handleToolConfirmationResponse(msg models.ToolConfirmationResponseMsg) (tea.Model, tea.Cmd) {
+	if toolInfo := m.stateManager.GetPendingToolConfirmation(); toolInfo != nil {
+		m.recordConfirmationWait(toolInfo.ToolName)
+	}
+
+	if msg.Action == "continue" {
+		if aiClient := m.stateManager.GetAIClient(); aiClient != nil {
+			aiClient.StopStepping()
+		}
+		return m.executePendingTool()
+	}
+
 	if msg.Confirmed && msg.Action == "execute" {
 		return m.executePendingTool()
 	} else {
+		m.finishTurnTiming()
 		toolInfo := m.stateManager.GetPendingToolConfirmation()
 		m.stateManager.ClearPendingToolConfirmation()
 		m.stateManager.SetState(models.StateInput)
@@ -326,26 +660,39 @@ func (m *Model) tick() tea.Cmd {
 	})
 }
 
+// newStreamThrottle returns a throttle that sends coalesced chunks of a turn
+// to the program until the turn is aborted
+func (m *Model) newStreamThrottle(ctx context.Context) *streamThrottle {
+	return newStreamThrottle(m.renderInterval, func(chunk string) {
+		if m.program != nil && ctx.Err() == nil {
+			m.program.Send(models.AIStreamChunkMsg{Chunk: chunk})
+		}
+	})
+}
+
 // queryAI queries AI with streaming support
 func (m *Model) queryAI() tea.Cmd {
+	ctx := m.beginTurn()
 	return func() tea.Msg {
-		ctx := context.Background()
 		history := m.stateManager.GetHistory()
 		aiClient := m.stateManager.GetAIClient()
 
 		go func() {
 			var fullResponse strings.Builder
+			throttle := m.newStreamThrottle(ctx)
 
 			err := aiClient.QueryWithToolsStreaming(ctx, history, func(chunk string) error {
-				fullResponse.WriteString(chunk)
-
-				if m.program != nil {
-					m.program.Send(models.AIStreamChunkMsg{Chunk: chunk})
+				if ctx.Err() != nil {
+					return ctx.Err()
 				}
+				fullResponse.WriteString(chunk)
+				throttle.Write(chunk)
 				return nil
 			})
+			throttle.Flush()
 
-			if m.program != nil {
+			// An aborted turn was already closed by abortTurn
+			if m.program != nil && ctx.Err() == nil {
 				if err != nil {
 					m.program.Send(models.AIResponseMsg{Response: "", Err: err})
 				} else {
@@ -380,14 +727,16 @@ func (m *Model) executePendingTool() (tea.Model, tea.Cmd) {
 	m.stateManager.ClearPendingToolConfirmation()
 	m.stateManager.SetState(models.StateThinking)
 
+	ctx := m.beginTurn()
 	return m, func() tea.Msg {
-		ctx := context.Background()
 		var fullResponse strings.Builder
+		throttle := m.newStreamThrottle(ctx)
 
 		streamingCallback := func(chunk string) error {
-			if m.program != nil {
-				m.program.Send(models.AIStreamChunkMsg{Chunk: chunk})
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			throttle.Write(chunk)
 			fullResponse.WriteString(chunk)
 			return nil
 		}
@@ -399,8 +748,9 @@ func (m *Model) executePendingTool() (tea.Model, tea.Cmd) {
 			aiContext.ToolCall,
 			streamingCallback,
 		)
+		throttle.Flush()
 
-		if m.program != nil {
+		if m.program != nil && ctx.Err() == nil {
 			if err != nil {
 				m.program.Send(models.AIResponseMsg{Response: "", Err: err})
 			} else {
@@ -414,20 +764,51 @@ func (m *Model) executePendingTool() (tea.Model, tea.Cmd) {
 
 // handleToolConfirmationFromAI handles tool confirmation requests from the AI client
 func (m *Model) handleToolConfirmationFromAI(ctx context.Context, messages []openai.ChatCompletionMessage, completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall, streamingCallback ai.StreamingCallback) (bool, error) {
-	if !m.stateManager.RequiresConfirmation(toolCall.Function.Name) {
-		return true, nil
-	}
-
+	aiClient := m.stateManager.GetAIClient()
+	stepping := aiClient != nil && aiClient.Stepping()
 	var args map[string]interface{}
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
 		return false, fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
+	if !stepping && !m.stateManager.RequiresConfirmation(toolCall.Function.Name) && !tools.RunsStatement(toolCall.Function.Name, args) {
+		return true, nil
+	}
+
+	// Tools that build their statement show it in the confirmation. Invalid
+	// arguments write nothing, so they run unconfirmed and report the problems.
+	if tools.IsStatementBuilder(toolCall.Function.Name) && aiClient != nil {
+		preview, err := aiClient.PreviewStatement(toolCall)
+		if err != nil {
+			return true, nil
+		}
+		args["sql"] = preview
+	}
 
-	toolInfo := m.stateManager.CreateToolConfirmationInfo(toolCall.Function.Name, toolCall.ID, args)
+	// $name references run the SQL saved with /as: show it rather than the names
+	if sql, ok := args["sql"].(string); ok && toolCall.Function.Name == "execute_sql" && aiClient != nil {
+		args["sql"] = aiClient.InlineVariables(sql)
+	}
+
+	var toolInfo *models.ToolConfirmationInfo
+	if stepping {
+		toolInfo = m.stateManager.CreateStepConfirmationInfo(toolCall.Function.Name, toolCall.ID, args,
+			aiClient.NextStep(), strings.TrimSpace(completeMessage.Content), remainingToolCalls(completeMessage, toolCall))
+	} else {
+		toolInfo = m.stateManager.CreateToolConfirmationInfo(toolCall.Function.Name, toolCall.ID, args)
+	}
 	if toolInfo == nil {
 		return true, nil
 	}
 
+	// Show what an ALTER or DROP breaks before it is approved
+	if sql, ok := args["sql"].(string); ok && toolCall.Function.Name == "execute_sql" && aiClient != nil {
+		impact, err := aiClient.DDLImpact(sql)
+		if err != nil {
+			impact = []string{fmt.Sprintf("Could not check dependent objects: %v", err)}
+		}
+		toolInfo.Impact = impact
+	}
+
 	aiContext := &models.PendingAIContext{
 		Messages:          messages,
 		CompleteMessage:   completeMessage,
@@ -444,6 +825,21 @@ func (m *Model) handleToolConfirmationFromAI(ctx context.Context, messages []ope
 	return true, nil
 }
 
+// remainingToolCalls names the tool calls of a message that follow toolCall
+func remainingToolCalls(completeMessage openai.ChatCompletionMessage, toolCall openai.ToolCall) []string {
+	var remaining []string
+	for i, tc := range completeMessage.ToolCalls {
+		if tc.ID != toolCall.ID {
+			continue
+		}
+		for _, next := range completeMessage.ToolCalls[i+1:] {
+			remaining = append(remaining, next.Function.Name)
+		}
+		break
+	}
+	return remaining
+}
+
 // handleVersionUpdate handles version update notifications
 func (m *Model) handleVersionUpdate(msg models.VersionUpdateMsg) (tea.Model, tea.Cmd) {
 	m.stateManager.SetVersionUpdate(msg.UpdateInfo)
@@ -0,0 +1,693 @@
+package ui
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbsage/internal/ai"
+	"dbsage/internal/models"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleKeyPress_MultiLinePasteOpensEditor(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+	m.textInput.SetValue("-- ")
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("SELECT id\r\nFROM users;"), Paste: true})
+
+	assert.True(t, m.multiline)
+	assert.Equal(t, "-- SELECT id\nFROM users;", m.editor.Value())
+}
+
+func TestHandleKeyPress_SingleLinePasteStaysInInput(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("SELECT 1"), Paste: true})
+
+	assert.False(t, m.multiline)
+	assert.Equal(t, "SELECT 1", m.textInput.Value())
+}
+
+func TestHandleEditorKeyPress_EscReturnsToSingleLine(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+	m.openEditor("SELECT id\nFROM users")
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.False(t, m.multiline)
+	assert.Equal(t, "SELECT id FROM users", m.textInput.Value())
+}
+
+func TestHandleKeyPress_EscAbortsRunningTurn(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+	ctx := m.beginTurn()
+	m.stateManager.SetState(models.StateThinking)
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Nil(t, m.cancelTurn)
+	assert.Equal(t, models.StateResponse, m.stateManager.GetState())
+	assert.Equal(t, abortedMessage, m.stateManager.GetResponse())
+
+	m.handleTurnAborted(models.TurnAbortedMsg{Cancelled: 1})
+	assert.Contains(t, m.stateManager.GetResponse(), "cancelled on the server")
+}
+
+func TestHandleKeyPress_TourShownUntilDismissed(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+	tour := &models.GuidanceInfo{Type: "demo_tour", Title: "Tour"}
+	m.stateManager.SetTour(tour)
+	m.textInput.SetValue("What tables are there?")
+
+	m.stateManager.UpdateDatabaseTools(nil)
+	assert.Same(t, tour, m.stateManager.GetCurrentGuidance())
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	assert.Nil(t, m.stateManager.GetCurrentGuidance())
+
+	m.stateManager.UpdateDatabaseTools(nil)
+	assert.Equal(t, "api_key_missing", m.stateManager.GetCurrentGuidance().Type)
+}
+
+func TestSubmitInput_LargeContextHeldUntilSend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DBSAGE_TOKEN_PREVIEW", "100")
+	m := NewModel(nil, nil, nil)
+	prompt := strings.Repeat("explain this schema ", 200)
+
+	m.submitInput(prompt)
+
+	assert.Equal(t, models.StateResponse, m.stateManager.GetState())
+	assert.Contains(t, m.stateManager.GetResponse(), "Large context")
+	assert.Contains(t, m.stateManager.GetResponse(), "gpt-4o-mini")
+	assert.Empty(t, m.stateManager.GetHistory())
+
+	m.submitInput("/send")
+
+	history := m.stateManager.GetHistory()
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, prompt, history[0].Content)
+	}
+	assert.False(t, m.stateManager.HasPendingPrompt())
+}
+
+func TestSubmitInput_OtherInputDiscardsHeldPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DBSAGE_TOKEN_PREVIEW", "100")
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput(strings.Repeat("x", 2000))
+	assert.True(t, m.stateManager.HasPendingPrompt())
+
+	m.submitInput("/help")
+	assert.False(t, m.stateManager.HasPendingPrompt())
+
+	m.submitInput("/send")
+	assert.Equal(t, "Nothing to send.", m.stateManager.GetResponse())
+}
+
+func TestHandleKeyPress_ConfirmationHotkeys(t *testing.T) {
+	m := NewModel(nil, nil, nil)
+	info := m.stateManager.CreateToolConfirmationInfo("execute_sql", "call-1", map[string]interface{}{"sql": "DELETE FROM users"})
+	m.stateManager.SetPendingToolConfirmation(info)
+	m.stateManager.SetState(models.StateToolConfirmation)
+
+	cases := map[string]models.ToolConfirmationResponseMsg{
+		"y": {Confirmed: true, Action: "execute"},
+		"N": {Confirmed: false, Action: "cancel"},
+		"e": {Confirmed: false, Action: "edit"},
+		"3": {Confirmed: false, Action: "edit"},
+	}
+	for key, want := range cases {
+		_, cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		if assert.NotNil(t, cmd, key) {
+			assert.Equal(t, want, cmd(), key)
+		}
+	}
+
+	_, cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if cmd != nil {
+		_, isResponse := cmd().(models.ToolConfirmationResponseMsg)
+		assert.False(t, isResponse)
+	}
+}
+
+func TestHandleToolConfirmationResponse_StepControls(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	client := ai.NewClient("test-key", "", nil)
+	m := NewModel(client, nil, nil)
+	m.stateManager.ProcessInput("/step on")
+	m.beginTurnTiming()
+	require.True(t, client.Stepping())
+
+	info := m.stateManager.CreateStepConfirmationInfo("list_tables", "call-1", map[string]interface{}{}, 1, "Find the tables first.", []string{"describe_table"})
+	require.NotNil(t, info)
+	m.stateManager.SetPendingToolConfirmation(info)
+	m.stateManager.SetState(models.StateToolConfirmation)
+
+	cases := map[string]models.ToolConfirmationResponseMsg{
+		"s": {Confirmed: true, Action: "execute"},
+		"c": {Confirmed: false, Action: "continue"},
+		"a": {Confirmed: false, Action: "cancel"},
+	}
+	for key, want := range cases {
+		_, cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		if assert.NotNil(t, cmd, key) {
+			assert.Equal(t, want, cmd(), key)
+		}
+	}
+
+	// Continue stops pausing for the rest of the turn only
+	m.handleToolConfirmationResponse(models.ToolConfirmationResponseMsg{Action: "continue"})
+	assert.False(t, client.Stepping())
+	assert.True(t, client.StepMode())
+}
+
+func TestSubmitInput_DiscoverPrefillsAdd(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "app.db"))
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput("/discover sqlite " + dir)
+
+	assert.Contains(t, m.stateManager.GetResponse(), "1. "+filepath.Join(dir, "app.db"))
+	assert.Contains(t, m.stateManager.GetResponse(), "1 table,")
+	assert.Equal(t, "/discover add ", m.textInput.Value())
+}
+
+func TestSubmitInput_BookmarkLastAnswer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput("/bookmark add nothing yet")
+	assert.Contains(t, m.stateManager.GetResponse(), "Nothing to bookmark yet")
+
+	m.stateManager.AddToHistory("user", "How many users signed up today?")
+	m.stateManager.AddToHistory("assistant", "42 users signed up today.")
+	m.submitInput("/bookmark add  signup spike")
+	assert.Contains(t, m.stateManager.GetResponse(), "Bookmarked the last answer as 1: signup spike")
+
+	m.submitInput("/bookmark list")
+	assert.Contains(t, m.stateManager.GetResponse(), "1. signup spike")
+	assert.Contains(t, m.stateManager.GetResponse(), "How many users signed up today?")
+
+	m.submitInput("/bookmark run 1")
+	assert.Equal(t, "How many users signed up today?", m.textInput.Value())
+}
+
+func TestSubmitInput_ShareAndOpenTranscript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "incident.json")
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput("/share " + path)
+	assert.Contains(t, m.stateManager.GetResponse(), "Nothing to share yet")
+
+	m.stateManager.AddToHistory("user", "Why does postgres://app:hunter22@db/prod refuse logins?")
+	m.stateManager.AddToHistory("assistant", "The app role has no LOGIN privilege.")
+	m.submitInput("/share " + path)
+	assert.Contains(t, m.stateManager.GetResponse(), "Shared 1 questions, 0 SQL statements and 0 results")
+	assert.Contains(t, m.stateManager.GetResponse(), "1 secrets redacted")
+
+	m.submitInput("/open " + path)
+	response := m.stateManager.GetResponse()
+	assert.Contains(t, response, "Read-only: nothing below is run.")
+	assert.Contains(t, response, "postgres://app:[redacted]@db/prod")
+	assert.NotContains(t, response, "hunter22")
+	assert.Contains(t, response, "The app role has no LOGIN privilege.")
+}
+
+func TestSubmitInput_EditConnectionForm(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	connService := database.NewConnectionService()
+	path := filepath.Join(t.TempDir(), "app.db")
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "local", Type: "sqlite", Database: path, Description: "app"}))
+	m := NewModel(nil, nil, connService)
+
+	m.submitInput("/edit local")
+	require.NotNil(t, m.form)
+	assert.Contains(t, m.form.View(), "app.db")
+
+	// Typing goes to the focused field; invalid numbers keep the form open with the reason
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyTab})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyTab})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlS})
+	require.NotNil(t, m.form)
+	assert.Contains(t, m.form.View(), "port must be a positive number")
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" edited")})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlS})
+	assert.Nil(t, m.form)
+	assert.Contains(t, m.stateManager.GetResponse(), "Saved connection 'local'")
+	assert.Equal(t, "app edited", connService.GetConnectionManager().ListConnections()["local"].Description)
+
+	m.submitInput("/edit undo local")
+	assert.Contains(t, m.stateManager.GetResponse(), "Restored the previous settings of 'local'")
+	assert.Equal(t, "app", connService.GetConnectionManager().ListConnections()["local"].Description)
+}
+
+func TestSubmitInput_RelatedNavigationAndJoin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE customers (id INTEGER PRIMARY KEY);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers);
+CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER REFERENCES orders(id))`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("shop"))
+	m := NewModel(nil, nil, connService)
+
+	m.submitInput("/related orders")
+	response := m.stateManager.GetResponse()
+	assert.Contains(t, response, " 1. customers  (orders.customer_id → customers.id)")
+	assert.Contains(t, response, " 2. order_items  (order_items.order_id → orders.id)")
+
+	m.submitInput("/related 1")
+	assert.Contains(t, m.stateManager.GetResponse(), "trail: orders → customers")
+
+	m.submitInput("/related join")
+	assert.Equal(t, "SELECT o.*, c.* FROM orders o JOIN customers c ON c.id = o.customer_id LIMIT 100", m.textInput.Value())
+
+	m.submitInput("/related join customers order_items")
+	assert.Contains(t, m.textInput.Value(), "JOIN orders o ON o.customer_id = c.id JOIN order_items oi ON oi.order_id = o.id")
+}
+
+func TestSubmitInput_PreviewTable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, note TEXT, created_at TIMESTAMP);
+INSERT INTO events VALUES (1, 'first', '2026-01-01'), (2, 'second', '2026-01-03'), (3, '` + strings.Repeat("long ", 60) + `', '2026-01-02')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "events", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("events"))
+	m := NewModel(nil, nil, connService)
+
+	m.submitInput("/preview events 2 newest")
+	response := m.stateManager.GetResponse()
+	assert.Contains(t, response, "Newest 2 rows by created_at of events")
+	assert.Contains(t, response, "second")
+	assert.Contains(t, response, "(2 rows")
+	assert.Contains(t, response, "Long values of note are shortened")
+	assert.NotContains(t, response, "first")
+
+	m.submitInput("/preview events newest id")
+	assert.Contains(t, m.stateManager.GetResponse(), "Newest 10 rows by id")
+
+	m.submitInput("/preview events oldest")
+	assert.Contains(t, m.stateManager.GetResponse(), "unknown preview mode")
+}
+
+func TestSubmitInput_ERD(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers(id))`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("shop"))
+	m := NewModel(nil, nil, connService)
+
+	m.submitInput("/erd")
+	response := m.stateManager.GetResponse()
+	assert.Contains(t, response, "ER diagram (2 tables, 1 relationships)")
+	assert.Contains(t, response, "| customers ")
+	assert.Contains(t, response, "orders.customer_id → customers.id")
+
+	m.submitInput("/erd --format mermaid")
+	assert.Contains(t, m.stateManager.GetResponse(), "```mermaid\nerDiagram")
+
+	out := filepath.Join(dir, "schema.dot")
+	m.submitInput("/erd --out " + out)
+	assert.Contains(t, m.stateManager.GetResponse(), "Wrote the dot ER diagram")
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), `"orders":"customer_id" -> "customers":"id";`)
+
+	m.submitInput("/erd --format svg")
+	assert.Contains(t, m.stateManager.GetResponse(), "unknown diagram format")
+}
+
+func TestSubmitInput_BuildQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT, total REAL)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("shop"))
+	m := NewModel(nil, nil, connService)
+
+	m.submitInput("/build orders")
+	require.NotNil(t, m.builder)
+	assert.Contains(t, m.builder.View(), "Columns: id, status, total")
+
+	// The focus starts on the columns when the table is given
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("id, price")})
+	assert.Contains(t, m.builder.View(), "orders has no column price")
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlR})
+	require.NotNil(t, m.builder, "an invalid query is not used")
+
+	for range len("price") + 2 {
+		m.handleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("status = paid")})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlE})
+	assert.Nil(t, m.builder)
+	assert.Equal(t, "Refine this SQL: SELECT id FROM orders WHERE status = 'paid' LIMIT 100 Change: ", m.textInput.Value())
+}
+
+func TestSubmitInput_BrowseResults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT);
+WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 120)
+INSERT INTO events SELECT i, 'click' FROM n`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "events", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("events"))
+	m := NewModel(nil, nil, connService)
+	run := func(cmd tea.Cmd) {
+		if cmd != nil {
+			m.Update(cmd())
+		}
+	}
+
+	m.submitInput("/browse DELETE FROM events")
+	assert.Nil(t, m.pager)
+	assert.Contains(t, m.stateManager.GetResponse(), "only reads")
+
+	_, cmd := m.submitInput("/browse events")
+	require.NotNil(t, m.pager)
+	assert.Equal(t, "SELECT * FROM events", m.pager.Query())
+	run(cmd)
+	assert.Equal(t, "rows 1-50 · page 1 · more rows follow", m.pager.Status())
+
+	_, cmd = m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	run(cmd)
+	_, cmd = m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	run(cmd)
+	assert.Equal(t, "rows 101-120 of 120 · page 3 of 3", m.pager.Status())
+	assert.Equal(t, int64(101), m.pager.Result().Rows[0][0])
+
+	_, cmd = m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	assert.Nil(t, cmd, "there is no page after the last")
+	_, cmd = m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	assert.Nil(t, cmd, "cached pages are shown without reading")
+	assert.Equal(t, "rows 51-100 of 120 · page 2 of 3", m.pager.Status())
+	assert.Contains(t, m.View(), "| 51  | click |")
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Nil(t, m.pager)
+	assert.Equal(t, "Result pager closed.", m.stateManager.GetResponse())
+}
+
+func TestSubmitInput_GridEditsCell(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT); INSERT INTO orders VALUES (1, 'new'), (2, 'new')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("shop"))
+	m := NewModel(nil, nil, connService)
+	run := func(cmd tea.Cmd) {
+		if cmd != nil {
+			m.Update(cmd())
+		}
+	}
+	key := func(text string) tea.Cmd {
+		_, cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+		return cmd
+	}
+
+	m.submitInput("/grid SELECT status FROM orders")
+	require.NotNil(t, m.grid)
+	assert.Contains(t, m.stateManager.GetResponse(), "read-only: the result does not include the primary-key column id")
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Nil(t, m.grid)
+
+	m.submitInput("/grid orders")
+	require.NotNil(t, m.grid)
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Contains(t, m.grid.View(), "id is part of the primary key")
+
+	// Edit the status of the second order
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRight})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	for range len("new") {
+		m.handleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	key("paid")
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Contains(t, m.grid.View(), `UPDATE "orders" SET "status" = 'paid' WHERE "id" = 2`)
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	require.NotNil(t, m.grid, "esc discards the edit and keeps the grid open")
+	assert.Contains(t, m.grid.View(), "Edit discarded.")
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlN})
+	run(key("y"))
+	assert.Contains(t, m.grid.View(), `Saved: UPDATE "orders" SET "status" = NULL WHERE "id" = 2`)
+
+	result, err := connService.GetCurrentTools().ExecuteSQL("SELECT status FROM orders ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"new"}, {nil}}, result.Rows)
+
+	key("q")
+	assert.Nil(t, m.grid)
+	assert.Equal(t, "Data grid closed.", m.stateManager.GetResponse())
+}
+
+func TestSubmitInput_HelpAndUsageFromRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput("/help")
+	help := m.stateManager.GetResponse()
+	m.stateManager.UpdateCommandSuggestions("/")
+	suggestions := m.stateManager.GetCommandSuggestions()
+	require.NotEmpty(t, suggestions)
+	for _, cmd := range suggestions {
+		assert.Contains(t, help, cmd.Usage, "every suggested command is in /help")
+	}
+	assert.Contains(t, help, "- /exit or /quit: Exit application")
+
+	m.submitInput("/switch")
+	assert.Equal(t, "Usage: /switch <name>\nExample: /switch mydb", m.stateManager.GetResponse())
+	m.submitInput("/replay ./workload.sql")
+	assert.Equal(t, "Usage: /replay <file> <connection> [rate]\nExample: /replay ./workload.sql staging 5", m.stateManager.GetResponse())
+	m.submitInput("/nope")
+	assert.Contains(t, m.stateManager.GetResponse(), "Unknown command: /nope")
+}
+
+// workloadDone runs the command of a started /capture or /replay and returns
+// the message it finishes with, or nil when it was aborted
+func workloadDone(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok, "the job runs in a command")
+	for _, c := range batch {
+		if msg := c(); msg != nil {
+			if _, tick := msg.(models.TickMsg); !tick {
+				return msg
+			}
+		}
+	}
+	return nil
+}
+
+func TestSubmitInput_ReplayRunsInBackground(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	workload := filepath.Join(dir, "workload.sql")
+	require.NoError(t, os.WriteFile(workload, []byte("SELECT COUNT(*) FROM orders;\nSELECT 1;\nDELETE FROM orders;\n"), 0644))
+	m := NewModel(nil, nil, connService)
+
+	_, cmd := m.submitInput("/replay " + workload + " shop 1000")
+	assert.Equal(t, models.StateThinking, m.stateManager.GetState(), "the interface keeps running while replaying")
+	assert.Contains(t, m.workloadProgress, "Replaying 3 statements")
+	m.Update(models.WorkloadProgressMsg{Text: "Replaying statement 2 of 3"})
+	assert.Contains(t, m.View(), "Replaying statement 2 of 3 (esc to abort)")
+
+	m.Update(workloadDone(t, cmd))
+	assert.Equal(t, models.StateResponse, m.stateManager.GetState())
+	assert.Contains(t, m.stateManager.GetResponse(), "Statements: 2 replayed, 0 failed, 1 skipped")
+	assert.Nil(t, m.cancelTurn)
+
+	// esc stops a replay and its report is dropped
+	_, cmd = m.submitInput("/replay " + workload + " shop 1")
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, abortedMessage, m.stateManager.GetResponse())
+	assert.Nil(t, workloadDone(t, cmd))
+	assert.Equal(t, abortedMessage, m.stateManager.GetResponse())
+}
+
+func TestUpdateCommandSuggestions_RankedByUse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE customers (id INTEGER PRIMARY KEY);
+CREATE TABLE orders (id INTEGER PRIMARY KEY);
+CREATE TABLE order_items (id INTEGER PRIMARY KEY)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	connService := database.NewConnectionService()
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "shop", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: "staging", Type: "sqlite", Database: path}))
+	require.NoError(t, connService.SwitchConnection("shop"))
+	m := NewModel(nil, nil, connService)
+
+	suggested := func(input string) []string {
+		m.stateManager.UpdateCommandSuggestions(input)
+		var names []string
+		for _, cmd := range m.stateManager.GetCommandSuggestions() {
+			names = append(names, cmd.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"/build", "/browse", "/bookmark"}, suggested("/b"))
+	assert.Equal(t, []string{"@", "@shop", "@staging"}, suggested("@s"))
+	require.Eventually(t, func() bool { return len(suggested("/browse ")) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"/browse order_items", "/browse orders"}, suggested("/browse or"))
+
+	m.submitInput("/bookmark list")
+	m.submitInput("/bookmark list")
+	m.submitInput("/browse orders")
+	assert.Equal(t, []string{"/bookmark", "/browse", "/build"}, suggested("/b"))
+	assert.Equal(t, []string{"/grid orders", "/grid order_items"}, suggested("/grid OR"), "tables rank the same across commands")
+
+	m.submitInput("@staging")
+	assert.Equal(t, []string{"@", "@staging", "@shop"}, suggested("@s"))
+}
+
+func TestSubmitInput_SessionsResumeAndNew(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewModel(nil, nil, nil)
+
+	m.submitInput("/sessions")
+	assert.Contains(t, m.stateManager.GetResponse(), "No saved conversations yet")
+
+	m.stateManager.AddToHistory("user", "Why is the orders report slow?")
+	m.stateManager.AddToHistory("assistant", "It scans orders; an index on created_at would help.")
+	m.submitInput("/new")
+	assert.Contains(t, m.stateManager.GetResponse(), "The previous one is saved as")
+	assert.Empty(t, m.stateManager.GetHistory())
+
+	m.submitInput("/sessions")
+	assert.Contains(t, m.stateManager.GetResponse(), "2 messages")
+	assert.Contains(t, m.stateManager.GetResponse(), "Why is the orders report slow?")
+
+	m.submitInput("/resume last")
+	assert.Contains(t, m.stateManager.GetResponse(), `Resumed "Why is the orders report slow?"`)
+	history := m.stateManager.GetHistory()
+	require.Len(t, history, 2)
+	assert.Equal(t, "It scans orders; an index on created_at would help.", history[1].Content)
+
+	// Later messages continue the resumed conversation instead of starting another
+	m.stateManager.AddToHistory("user", "And for order_items?")
+	m.submitInput("/sessions")
+	assert.Contains(t, m.stateManager.GetResponse(), "3 messages")
+	assert.Equal(t, 1, strings.Count(m.stateManager.GetResponse(), "messages"))
+
+	m.submitInput("/resume 1999")
+	assert.Contains(t, m.stateManager.GetResponse(), "no saved conversation 1999")
+}
+
+func TestSubmitInput_ListSortedAndFiltered(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "list.db")
+	connService := database.NewConnectionService()
+	for _, c := range []struct{ name, lastUsed string }{
+		{"analytics", ""}, // Current, so last used now
+		{"prod-main", "2026-10-01T09:00:00Z"},
+		{"staging", "2026-10-03T09:00:00Z"},
+		{"dev-local", "2026-10-02T09:00:00Z"},
+		{"prod-archive", ""},
+	} {
+		require.NoError(t, connService.AddConnection(&dbinterfaces.ConnectionConfig{Name: c.name, Type: "sqlite", Database: path, LastUsed: c.lastUsed}))
+	}
+	m := NewModel(nil, nil, connService)
+
+	listed := func(input string) []string {
+		m.submitInput(input)
+		var names []string
+		for _, line := range strings.Split(m.stateManager.GetResponse(), "\n") {
+			if fields := strings.Fields(strings.TrimPrefix(line, "*")); strings.Contains(line, " - ") && len(fields) > 0 {
+				names = append(names, strings.TrimPrefix(fields[0], "@"))
+			}
+		}
+		return names
+	}
+
+	byName := []string{"analytics", "dev-local", "prod-archive", "prod-main", "staging"}
+	assert.Equal(t, byName, listed("/list"))
+	assert.Equal(t, byName, listed("/list"), "the order is the same on every render")
+	assert.Equal(t, byName, listed("@"), "@ lists connections in the same order")
+	assert.Equal(t, []string{"analytics", "staging", "dev-local", "prod-main", "prod-archive"}, listed("/list --sort used"))
+	assert.Equal(t, []string{"prod-archive", "prod-main", "staging", "dev-local", "analytics"}, listed("/list --sort env"))
+
+	assert.Equal(t, []string{"prod-main", "prod-archive"}, listed("/list PROD --sort=used"))
+	assert.Contains(t, m.stateManager.GetResponse(), `2 of 5 matching "PROD", most recently used first`)
+	assert.Equal(t, []string{"dev-local"}, listed("/list development"), "environments match too")
+
+	m.submitInput("/list nothing")
+	assert.Equal(t, `No connection matches "nothing".`, m.stateManager.GetResponse())
+	m.submitInput("/list --sort size")
+	assert.Contains(t, m.stateManager.GetResponse(), `unknown order "size"`)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/bookmarks"
+)
+
+const bookmarkUsage = "Usage: /bookmark add <note> | list | run <id> | remove <id> | export <file.md|file.json>"
+
+// bookmark manages bookmarked answers. rest is the input after the command,
+// so notes keep their spacing.
+func (h *CommandHandler) bookmark(args []string, rest string) (bool, string, error) {
+	if len(args) == 0 || args[0] == "list" {
+		return h.listBookmarks()
+	}
+
+	switch args[0] {
+	case "add":
+		note := strings.TrimSpace(strings.TrimPrefix(rest, "add"))
+		if note == "" {
+			return true, "Usage: /bookmark add <note>\nBookmarks the last answer with its question, SQL and connection.", nil
+		}
+		// The answer lives in the conversation transcript of the state manager
+		return true, "BOOKMARK_ADD:" + note, nil
+
+	case "run", "remove":
+		if len(args) < 2 {
+			return true, fmt.Sprintf("Usage: /bookmark %s <id>", args[0]), nil
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return true, fmt.Sprintf("Invalid bookmark id: %s", args[1]), nil
+		}
+		if args[0] == "remove" {
+			if err := bookmarks.Remove(id); err != nil {
+				return true, fmt.Sprintf("Failed to remove bookmark: %v", err), nil
+			}
+			return true, fmt.Sprintf("Removed bookmark %d", id), nil
+		}
+		return h.runBookmark(id)
+
+	case "export":
+		if len(args) < 2 {
+			return true, "Usage: /bookmark export <file.md|file.json>", nil
+		}
+		list, err := bookmarks.Load()
+		if err != nil {
+			return true, fmt.Sprintf("Failed to read bookmarks: %v", err), nil
+		}
+		if len(list) == 0 {
+			return true, "No bookmarks to export.", nil
+		}
+		path := expandHomePath(args[1])
+		if err := bookmarks.Export(path, list); err != nil {
+			return true, fmt.Sprintf("Failed to export bookmarks: %v", err), nil
+		}
+		return true, fmt.Sprintf("Exported %d bookmarks to %s", len(list), path), nil
+	}
+	return true, bookmarkUsage, nil
+}
+
+// listBookmarks lists the bookmarks, newest first
+func (h *CommandHandler) listBookmarks() (bool, string, error) {
+	list, err := bookmarks.Load()
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read bookmarks: %v", err), nil
+	}
+	if len(list) == 0 {
+		return true, "No bookmarks yet. Use /bookmark add <note> after an answer worth keeping.", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Bookmarks:\n")
+	for i := len(list) - 1; i >= 0; i-- {
+		bm := list[i]
+		details := []string{bm.Time.Format("Mon Jan 2 15:04")}
+		if bm.Connection != "" {
+			details = append(details, bm.Connection)
+		}
+		if n := len(bm.SQL); n == 1 {
+			details = append(details, "1 statement")
+		} else if n > 1 {
+			details = append(details, fmt.Sprintf("%d statements", n))
+		}
+		b.WriteString(fmt.Sprintf("\n%3d. %s  (%s)", bm.ID, bm.Note, strings.Join(details, ", ")))
+		if bm.Question != "" {
+			question := strings.Join(strings.Fields(bm.Question), " ")
+			if len(question) > 80 {
+				question = question[:77] + "..."
+			}
+			b.WriteString("\n     " + question)
+		}
+	}
+	b.WriteString("\n\nUse /bookmark run <id> to re-run one, /bookmark export <file> to share them.")
+	return true, b.String(), nil
+}
+
+// runBookmark switches to the bookmark's connection and places its SQL (or
+// its question, when no SQL ran) in the input
+func (h *CommandHandler) runBookmark(id int) (bool, string, error) {
+	bm, err := bookmarks.Get(id)
+	if err != nil {
+		return true, err.Error(), nil
+	}
+
+	message := ""
+	if bm.Connection != "" && h.connService != nil {
+		if _, _, current := h.connService.GetConnectionInfo(); current != bm.Connection {
+			if err := h.connService.SwitchConnection(bm.Connection); err != nil {
+				return true, fmt.Sprintf("Bookmark %d ran on '%s', which could not be switched to: %v", bm.ID, bm.Connection, err), nil
+			}
+			h.IndexSchema()
+			message = fmt.Sprintf("Switched to connection: %s\n", bm.Connection)
+		}
+	}
+
+	input := bm.Question
+	if len(bm.SQL) > 0 {
+		input = strings.Join(bm.SQL, "; ")
+	}
+	return true, FillInput(input, message+fmt.Sprintf("Bookmark %d (%s) is in the input: press Enter to run it again, or edit it first.", bm.ID, bm.Note)), nil
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// PagerSource is what the result pager reads: a query and a way to open a
+// cursor over its rows on the connection it was started on
+type PagerSource struct {
+	Query string
+	Open  func() (dbinterfaces.RowCursor, error)
+}
+
+// browseResults opens the result pager on a table or a read-only query
+func (h *CommandHandler) browseResults(query string) (bool, string, error) {
+	if query == "" {
+		return true, "Usage: /browse <table | SELECT ...>\nExample: /browse orders\nExample: /browse SELECT * FROM events WHERE kind = 'click' ORDER BY id", nil
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	if h.connService.GetCurrentTools() == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	if !strings.ContainsAny(query, " \t\n") {
+		query = "SELECT * FROM " + query
+	}
+	if sqlanalysis.CountStatements(query) > 1 {
+		return true, "/browse pages through one query at a time", nil
+	}
+	if verb := sqlanalysis.FirstWrite(query); verb != "" {
+		return true, fmt.Sprintf("/browse only reads; run %s statements by asking the AI", verb), nil
+	}
+	// The pager is part of the model, the state manager opens it
+	return true, "BROWSE:" + query, nil
+}
+
+// PagerSource returns the source of the result pager for a query, on the
+// current connection
+func (h *CommandHandler) PagerSource(query string) (*PagerSource, error) {
+	if h.connService == nil {
+		return nil, fmt.Errorf("connection service not available")
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return nil, fmt.Errorf("no active database connection")
+	}
+	return &PagerSource{
+		Query: query,
+		Open: func() (dbinterfaces.RowCursor, error) {
+			return dbinterfaces.OpenCursor(db, query)
+		},
+	}, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BuilderSchema is what the query builder needs to know about the current
+// database
+type BuilderSchema struct {
+	Dialect string
+	Table   string // Table to start with, may be empty
+	Tables  []string
+	Columns func(table string) ([]string, error)
+}
+
+// buildQuery opens the query builder on the current connection
+func (h *CommandHandler) buildQuery(args []string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	if h.connService.GetCurrentTools() == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	table := ""
+	if len(args) > 0 {
+		table = args[0]
+	}
+	// The builder is part of the model, the state manager opens it
+	return true, "BUILD_QUERY:" + table, nil
+}
+
+// QueryBuilderSchema returns the tables of the current connection and a
+// lookup of their columns for the query builder
+func (h *CommandHandler) QueryBuilderSchema(table string) (*BuilderSchema, error) {
+	if h.connService == nil {
+		return nil, fmt.Errorf("connection service not available")
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return nil, fmt.Errorf("no active database connection")
+	}
+	tables, err := db.GetAllTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	schema := &BuilderSchema{Dialect: h.currentDatabaseType(), Table: table}
+	for _, t := range tables {
+		schema.Tables = append(schema.Tables, t.TableName)
+	}
+	sort.Strings(schema.Tables)
+	schema.Columns = func(table string) ([]string, error) {
+		columns, err := db.GetTableSchema(table)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.ColumnName
+		}
+		return names, nil
+	}
+	return schema, nil
+}
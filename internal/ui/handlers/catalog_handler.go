@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strings"
+
+	"dbsage/internal/catalog"
+)
+
+// IndexSchema starts indexing the tables of the current connection in the
+// background, so later table listings don't block on large catalogs
+func (h *CommandHandler) IndexSchema() {
+	h.schemaCatalog()
+}
+
+// schemaCatalog returns the table catalog of the current connection, starting
+// a new background indexer when the connection changed. It returns nil when
+// there is no connection.
+func (h *CommandHandler) schemaCatalog() *catalog.Catalog {
+	if h.connService == nil {
+		return nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return nil
+	}
+	if h.catalog == nil || h.catalogDB != db {
+		if h.catalog != nil {
+			h.catalog.Stop()
+		}
+		h.catalog = catalog.New(db)
+		h.catalogDB = db
+		h.catalog.Start()
+	}
+	return h.catalog
+}
+
+// requestSchemas asks the indexer to load schemas named in schema.table words first
+func requestSchemas(cat *catalog.Catalog, text string) {
+	for _, word := range strings.Fields(text) {
+		if schema, _, ok := strings.Cut(word, "."); ok && schema != "" {
+			cat.Request(strings.Trim(schema, `"`+"`"))
+		}
+	}
+}
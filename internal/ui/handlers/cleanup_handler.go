@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/generated"
+	"dbsage/pkg/database"
+)
+
+const cleanupUsage = "Usage: /cleanup [generated]\n/cleanup lists the test rows generated on the current connection; /cleanup generated deletes them."
+
+// cleanup lists or deletes the test rows generate_test_data inserted on the
+// current connection, in this or earlier sessions
+func (h *CommandHandler) cleanup(args []string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	_, _, name := h.connService.GetConnectionInfo()
+
+	rows, err := generated.Load(name)
+	if err != nil {
+		return true, fmt.Sprintf("Cannot read the generated rows: %v", err), nil
+	}
+
+	switch {
+	case len(args) == 0:
+		if len(rows) == 0 {
+			return true, fmt.Sprintf("No generated test rows are recorded on '%s'.", name), nil
+		}
+		return true, fmt.Sprintf("%d generated test rows are recorded on '%s': %s.\n/cleanup generated deletes them.",
+			len(rows), name, tableCounts(generated.CountByTable(rows))), nil
+
+	case strings.EqualFold(args[0], "generated"):
+		if len(rows) == 0 {
+			return true, fmt.Sprintf("No generated test rows are recorded on '%s'.", name), nil
+		}
+		result := database.CleanupGenerated(db, rows)
+		if err := generated.Forget(result.Removed); err != nil {
+			return true, fmt.Sprintf("Deleted the rows but could not update the record of generated rows: %v", err), nil
+		}
+
+		deleted := 0
+		for _, n := range result.Deleted {
+			deleted += n
+		}
+		lines := []string{fmt.Sprintf("Deleted %d generated test rows on '%s'.", deleted, name)}
+		if deleted > 0 {
+			lines[0] = fmt.Sprintf("Deleted %d generated test rows on '%s': %s.", deleted, name, tableCounts(result.Deleted))
+		}
+		if result.Gone > 0 {
+			lines = append(lines, fmt.Sprintf("%d were already gone.", result.Gone))
+		}
+		if len(result.Failed) > 0 {
+			lines = append(lines, fmt.Sprintf("%d could not be deleted and stay recorded; run /cleanup generated again once the rows referring to them are gone:", len(result.Failed)))
+			for _, failure := range result.Failed {
+				lines = append(lines, "  "+failure)
+			}
+		}
+		return true, strings.Join(lines, "\n"), nil
+	}
+	return true, cleanupUsage, nil
+}
+
+// tableCounts renders row counts per table, e.g. "customers 3, orders 12"
+func tableCounts(counts map[string]int) string {
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	parts := make([]string, len(tables))
+	for i, table := range tables {
+		parts[i] = fmt.Sprintf("%s %d", table, counts[table])
+	}
+	return strings.Join(parts, ", ")
+}
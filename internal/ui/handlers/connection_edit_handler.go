@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+const editUsage = "Usage: /edit <connection_name> | /edit undo <connection_name>\nExample: /edit mydb"
+
+// editConnection opens the edit form of a connection, or restores the
+// settings it had before its last edit
+func (h *CommandHandler) editConnection(args []string) (bool, string, error) {
+	if len(args) == 0 {
+		return true, editUsage, nil
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+
+	if args[0] == "undo" {
+		if len(args) < 2 {
+			return true, "Usage: /edit undo <connection_name>\nRestores the settings the connection had before its last edit.", nil
+		}
+		if err := h.connService.UndoConnectionEdit(args[1]); err != nil {
+			return true, fmt.Sprintf("Failed to undo the last edit of '%s': %v", args[1], err), nil
+		}
+		h.reindexIfCurrent(args[1])
+		return true, fmt.Sprintf("Restored the previous settings of '%s'. Run /edit undo %s again to redo the edit.", args[1], args[1]), nil
+	}
+
+	if _, err := h.EditableConnection(args[0]); err != nil {
+		return true, err.Error(), nil
+	}
+	// The form is part of the model, the state manager opens it
+	return true, "EDIT_CONNECTION:" + args[0], nil
+}
+
+// EditableConnection returns a copy of the settings of a connection that can be edited
+func (h *CommandHandler) EditableConnection(name string) (*dbinterfaces.ConnectionConfig, error) {
+	if h.connService == nil {
+		return nil, fmt.Errorf("connection service not available")
+	}
+	config, exists := h.connService.GetConnectionManager().ListConnections()[name]
+	if !exists {
+		return nil, fmt.Errorf("connection '%s' not found. Use /list to see the connections", name)
+	}
+	if config.AliasOf != "" {
+		return nil, fmt.Errorf("'%s' is an alias of '%s'. Use /edit %s instead", name, config.AliasOf, config.AliasOf)
+	}
+	edited := *config
+	return &edited, nil
+}
+
+// SaveConnectionEdit tests the edited settings of a connection and saves them
+// when the connection works
+func (h *CommandHandler) SaveConnectionEdit(config *dbinterfaces.ConnectionConfig) (string, error) {
+	if h.connService == nil {
+		return "", fmt.Errorf("connection service not available")
+	}
+	if err := h.connService.UpdateConnection(config); err != nil {
+		return "", err
+	}
+	h.reindexIfCurrent(config.Name)
+	return fmt.Sprintf("Saved connection '%s' after a successful connection test.\nUse /edit undo %s to restore the previous settings.", config.Name, config.Name), nil
+}
+
+// reindexIfCurrent refreshes the table index when the current connection changed
+func (h *CommandHandler) reindexIfCurrent(name string) {
+	if config := h.CurrentConnection(); config != nil && config.Name == name {
+		h.IndexSchema()
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Orders of connection listings
+const (
+	sortByName        = "name"
+	sortByLastUsed    = "used"
+	sortByEnvironment = "env"
+)
+
+// environmentRank orders environments from production down; connections
+// without one come last
+var environmentRank = map[string]int{
+	models.EnvProduction:  0,
+	models.EnvStaging:     1,
+	models.EnvDevelopment: 2,
+	"":                    3,
+}
+
+// listedConnection is a connection as shown by /list and @
+type listedConnection struct {
+	name        string
+	config      *dbinterfaces.ConnectionConfig
+	environment string
+	lastUsed    time.Time
+}
+
+// parseListArgs reads the filter and --sort order of /list
+func parseListArgs(args []string) (filter, order string, err error) {
+	order = sortByName
+	var terms []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if value, ok := strings.CutPrefix(arg, "--sort="); ok {
+			order = value
+		} else if arg == "--sort" {
+			if i+1 == len(args) {
+				return "", "", fmt.Errorf("--sort needs an order: name, used or env")
+			}
+			i++
+			order = args[i]
+		} else {
+			terms = append(terms, arg)
+		}
+	}
+	switch order {
+	case sortByName, sortByLastUsed, sortByEnvironment:
+	case "last", "recent":
+		order = sortByLastUsed
+	case "environment":
+		order = sortByEnvironment
+	default:
+		return "", "", fmt.Errorf("unknown order %q: use name, used or env", order)
+	}
+	return strings.Join(terms, " "), order, nil
+}
+
+// orderedConnections returns the connections matching a filter in a stable
+// order. The filter matches, ignoring case, part of the name, type, host,
+// database, description or environment.
+func orderedConnections(connections map[string]*dbinterfaces.ConnectionConfig, filter, order string) []listedConnection {
+	filter = strings.ToLower(filter)
+	var listed []listedConnection
+	for name, config := range connections {
+		c := listedConnection{name: name, config: config, environment: database.DetectEnvironment(name, config.Description)}
+		if filter != "" && !strings.Contains(strings.ToLower(strings.Join([]string{
+			name, config.Type, config.Host, config.Database, config.Description, c.environment,
+		}, "\n")), filter) {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, config.LastUsed); err == nil {
+			c.lastUsed = t
+		}
+		listed = append(listed, c)
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		a, b := listed[i], listed[j]
+		switch order {
+		case sortByLastUsed:
+			if !a.lastUsed.Equal(b.lastUsed) {
+				return a.lastUsed.After(b.lastUsed)
+			}
+		case sortByEnvironment:
+			if environmentRank[a.environment] != environmentRank[b.environment] {
+				return environmentRank[a.environment] < environmentRank[b.environment]
+			}
+		}
+		return a.name < b.name
+	})
+	return listed
+}
+
+// listingNote describes the filter and order of a listing, or is empty for
+// the default listing of every connection by name
+func listingNote(filter, order string, shown, total int) string {
+	var parts []string
+	if filter != "" {
+		parts = append(parts, fmt.Sprintf("%d of %d matching %q", shown, total, filter))
+	}
+	switch order {
+	case sortByLastUsed:
+		parts = append(parts, "most recently used first")
+	case sortByEnvironment:
+		parts = append(parts, "by environment")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/conversations"
+)
+
+// maxListedConversations is the number of conversations /sessions lists
+const maxListedConversations = 20
+
+// listConversations lists the saved conversations for /sessions
+func (h *CommandHandler) listConversations() (bool, string, error) {
+	summaries, err := conversations.List()
+	if err != nil {
+		return true, fmt.Sprintf("Failed to list conversations: %v", err), nil
+	}
+	if len(summaries) == 0 {
+		if !conversations.Enabled() {
+			return true, "Conversations are not saved while DBSAGE_SAVE_SESSIONS is off.", nil
+		}
+		return true, "No saved conversations yet: they are saved as you ask questions.", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Saved conversations (resume one with /resume <id>):\n")
+	for i, s := range summaries {
+		if i == maxListedConversations {
+			fmt.Fprintf(&b, "\n\n%d older conversations not shown.", len(summaries)-i)
+			break
+		}
+		connection := ""
+		if s.Connection != "" {
+			connection = " @" + s.Connection
+		}
+		fmt.Fprintf(&b, "\n%s  %s  %d messages%s\n  %s", s.ID, s.Updated.Local().Format("2006-01-02 15:04"), s.Messages, connection, s.Title)
+	}
+	return true, b.String(), nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/database/sqlite"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// discover handles /discover sqlite <directory> and /discover add <n|all>
+func (h *CommandHandler) discover(args []string) (bool, string, error) {
+	usage := "Usage: /discover sqlite <directory>\nThen /discover add <n> (or all) to add found files as connections."
+	if len(args) < 2 {
+		return true, usage, nil
+	}
+
+	switch args[0] {
+	case "sqlite":
+		return h.discoverSQLite(strings.Join(args[1:], " "))
+	case "add":
+		return h.addDiscovered(args[1])
+	default:
+		return true, usage, nil
+	}
+}
+
+// discoverSQLite lists the SQLite files under a directory and offers to add them
+func (h *CommandHandler) discoverSQLite(root string) (bool, string, error) {
+	found, truncated, err := sqlite.Discover(expandHomePath(root))
+	if err != nil {
+		return true, fmt.Sprintf("Failed to scan %s: %v", root, err), nil
+	}
+	h.lastDiscovery = found
+	if len(found) == 0 {
+		return true, fmt.Sprintf("No SQLite databases found under %s.", root), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("SQLite databases under %s:\n", root))
+	for i, file := range found {
+		tables := fmt.Sprintf("%d tables", file.Tables)
+		if file.Tables == 1 {
+			tables = "1 table"
+		} else if file.Tables < 0 {
+			tables = "unreadable: " + file.Err
+		}
+		b.WriteString(fmt.Sprintf("\n%2d. %s  (%s, %s, modified %s)", i+1, file.Path,
+			sqlanalysis.FormatBytes(file.Size), tables, file.Modified.Format("2006-01-02 15:04")))
+	}
+	if truncated {
+		b.WriteString(fmt.Sprintf("\n\nOnly the first %d files are listed; narrow the directory to see more.", sqlite.MaxDiscoveredFiles))
+	}
+	b.WriteString("\n\nType a number and press Enter to add it as a connection (or all).")
+	return true, FillInput("/discover add ", b.String()), nil
+}
+
+// addDiscovered adds files found by the last /discover as SQLite connections
+func (h *CommandHandler) addDiscovered(which string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	if len(h.lastDiscovery) == 0 {
+		return true, "Run /discover sqlite <directory> first.", nil
+	}
+
+	files := h.lastDiscovery
+	if which != "all" {
+		n, err := strconv.Atoi(which)
+		if err != nil || n < 1 || n > len(h.lastDiscovery) {
+			return true, fmt.Sprintf("No discovered file %s; choose 1-%d or all.", which, len(h.lastDiscovery)), nil
+		}
+		files = h.lastDiscovery[n-1 : n]
+	}
+
+	connections, _, _ := h.connService.GetConnectionInfo()
+	added := make(map[string]bool)
+	taken := func(name string) bool {
+		_, exists := connections[name]
+		return exists || added[name]
+	}
+
+	var b strings.Builder
+	var last string
+	for _, file := range files {
+		if existing := findMatchingSQLite(connections, file.Path); existing != "" {
+			b.WriteString(fmt.Sprintf("%s is already connection '%s'\n", file.Path, existing))
+			last = existing
+			continue
+		}
+		config := &dbinterfaces.ConnectionConfig{
+			Name:        sqlite.ConnectionName(file.Path, taken),
+			Type:        "sqlite",
+			Database:    file.Path,
+			Description: "Discovered SQLite file",
+		}
+		if err := h.connService.AddConnection(config); err != nil {
+			b.WriteString(fmt.Sprintf("Failed to add %s: %v\n", file.Path, err))
+			continue
+		}
+		added[config.Name] = true
+		last = config.Name
+		b.WriteString(fmt.Sprintf("Added connection '%s' for %s\n", config.Name, file.Path))
+	}
+	if len(files) == 1 && last != "" {
+		b.WriteString(fmt.Sprintf("Use /switch %s or @%s to connect.", last, last))
+	} else if len(added) > 0 {
+		b.WriteString("Use /list to see them and /switch <name> to connect.")
+	}
+	return true, strings.TrimRight(b.String(), "\n"), nil
+}
+
+// findMatchingSQLite returns the connection already pointing at a SQLite file
+func findMatchingSQLite(connections map[string]*dbinterfaces.ConnectionConfig, path string) string {
+	for name, config := range connections {
+		if config != nil && config.Type == "sqlite" && config.Database == path && config.AliasOf == "" {
+			return name
+		}
+	}
+	return ""
+}
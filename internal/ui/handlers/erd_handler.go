@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/sqlanalysis"
+)
+
+// erdOptions are the arguments of /erd
+type erdOptions struct {
+	schema string
+	format string // Empty to pick one by the size of the schema or the file
+	out    string
+}
+
+// parseERDArgs reads the schema, --format and --out of /erd
+func parseERDArgs(args []string) (erdOptions, error) {
+	var options erdOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		flag, value, hasValue := strings.Cut(arg, "=")
+		switch flag {
+		case "--format", "--out":
+			if !hasValue {
+				if i+1 == len(args) {
+					return options, fmt.Errorf("%s needs a value", flag)
+				}
+				i++
+				value = args[i]
+			}
+			if flag == "--out" {
+				options.out = value
+				continue
+			}
+			format, err := sqlanalysis.ParseDiagramFormat(value)
+			if err != nil {
+				return options, err
+			}
+			options.format = format
+		default:
+			if strings.HasPrefix(arg, "--") || options.schema != "" {
+				return options, fmt.Errorf("unexpected argument %s", arg)
+			}
+			options.schema = arg
+		}
+	}
+	return options, nil
+}
+
+// erd draws an entity-relationship diagram of the current connection, shown
+// as text boxes for small schemas or written to a file
+func (h *CommandHandler) erd(args []string) (bool, string, error) {
+	options, err := parseERDArgs(args)
+	if err != nil {
+		return true, fmt.Sprintf("%v\n%s", err, commandUsage("/erd")), nil
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	diagram, err := tools.BuildERDiagram(db, options.schema, nil)
+	if err != nil {
+		return true, fmt.Sprintf("Cannot draw the diagram: %v", err), nil
+	}
+	format := options.format
+	if format == "" && options.out != "" {
+		format, _ = sqlanalysis.DiagramFormatForPath(options.out)
+	}
+	if format == "" {
+		format = sqlanalysis.DiagramASCII
+		if len(diagram.Entities) > sqlanalysis.MaxASCIIDiagramTables {
+			format = sqlanalysis.DiagramMermaid
+		}
+	}
+	text, err := diagram.Render(format, h.termWidth-4)
+	if err != nil {
+		return true, fmt.Sprintf("Cannot draw the diagram: %v", err), nil
+	}
+
+	summary := fmt.Sprintf("%d tables, %d relationships", len(diagram.Entities), len(diagram.Relations))
+	if options.schema != "" {
+		summary = fmt.Sprintf("schema %s: %s", options.schema, summary)
+	}
+	notes := ""
+	for _, note := range diagram.Notes {
+		notes += "\n" + note
+	}
+
+	if options.out != "" {
+		path := expandHomePath(options.out)
+		if strings.EqualFold(filepath.Ext(path), ".md") && format == sqlanalysis.DiagramMermaid {
+			text = "```mermaid\n" + text + "\n```"
+		}
+		if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+			return true, fmt.Sprintf("Failed to write the diagram: %v", err), nil
+		}
+		return true, fmt.Sprintf("Wrote the %s ER diagram (%s) to %s%s", format, summary, path, notes), nil
+	}
+
+	if format == sqlanalysis.DiagramASCII {
+		return true, fmt.Sprintf("ER diagram (%s):\n\n%s%s", summary, text, notes), nil
+	}
+	hint := "\nRender it with a Mermaid viewer, or save it with /erd --out schema.mmd"
+	if format == sqlanalysis.DiagramDOT {
+		hint = "\nRender it with Graphviz (dot -Tsvg), or save it with /erd --out schema.dot"
+	}
+	if options.format == "" {
+		hint = fmt.Sprintf("\n%d tables are too many to draw as text, so this is Mermaid source.", len(diagram.Entities)) + hint
+	}
+	return true, fmt.Sprintf("ER diagram (%s):\n\n```%s\n%s\n```%s%s", summary, format, text, notes, hint), nil
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// maxExplainFileOffenders limits the number of statements shown in the ranking
+const maxExplainFileOffenders = 10
+
+// explainedStatement holds the plan analysis for one statement of a workload file
+type explainedStatement struct {
+	index   int
+	sql     string
+	summary *sqlanalysis.PlanSummary
+}
+
+// explainFile runs EXPLAIN on every statement of a workload file in the
+// background and ranks the worst offenders
+func (h *CommandHandler) explainFile(path string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	path = expandHomePath(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read workload file: %v", err), nil
+	}
+
+	dialect := h.currentDatabaseType()
+	statements := sqlanalysis.SplitStatementsFor(dialect, string(content))
+	if len(statements) == 0 {
+		return true, fmt.Sprintf("No SQL statements found in %s", path), nil
+	}
+
+	thresholds, _ := sqlanalysis.LoadThresholds()
+	return h.startWorkload(fmt.Sprintf("Explaining %d statements from %s...", len(statements), path), func(ctx context.Context, progress func(string)) string {
+		return runExplainFile(ctx, db, dialect, path, statements, thresholds, progress)
+	})
+}
+
+// runExplainFile explains the statements one by one, stopping when the user
+// aborts, and returns the report
+func runExplainFile(ctx context.Context, db dbinterfaces.DatabaseInterface, dialect, path string, statements []string,
+	thresholds sqlanalysis.Thresholds, progress func(string)) string {
+	var explained []explainedStatement
+	var skipped, failed []string
+	warningCounts := make(map[string]int)
+
+	for i, stmt := range statements {
+		if ctx.Err() != nil {
+			return ""
+		}
+		progress(fmt.Sprintf("Explaining statement %d of %d from %s...", i+1, len(statements), path))
+		if !sqlanalysis.IsExplainable(stmt) {
+			skipped = append(skipped, fmt.Sprintf("#%d %s", i+1, truncateSQL(stmt, 60)))
+			continue
+		}
+
+		result, err := db.ExecuteSQL(sqlanalysis.BuildExplainStatement(dialect, stmt))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("#%d %s: %v", i+1, truncateSQL(stmt, 60), err))
+			continue
+		}
+
+		summary, err := sqlanalysis.AnalyzePlanWithThresholds(dialect, result, thresholds)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("#%d %s: %v", i+1, truncateSQL(stmt, 60), err))
+			continue
+		}
+
+		for _, w := range summary.Warnings {
+			warningCounts[w.Kind]++
+		}
+		explained = append(explained, explainedStatement{index: i + 1, sql: stmt, summary: summary})
+	}
+
+	// Rank by number of warnings, then by estimated cost
+	sort.SliceStable(explained, func(i, j int) bool {
+		wi, wj := len(explained[i].summary.Warnings), len(explained[j].summary.Warnings)
+		if wi != wj {
+			return wi > wj
+		}
+		return explained[i].summary.TotalCost > explained[j].summary.TotalCost
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Workload EXPLAIN: %s (profile: %s)\n", path, thresholds.Profile))
+	result.WriteString(fmt.Sprintf("Statements: %d total, %d explained, %d skipped, %d failed\n",
+		len(statements), len(explained), len(skipped), len(failed)))
+
+	if len(warningCounts) > 0 {
+		result.WriteString("\nPlan warnings:\n")
+		kinds := make([]string, 0, len(warningCounts))
+		for kind := range warningCounts {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			result.WriteString(fmt.Sprintf("- %s: %d\n", kind, warningCounts[kind]))
+		}
+	}
+
+	if len(explained) > 0 {
+		result.WriteString("\nWorst offenders:\n")
+		for rank, stmt := range explained {
+			if rank >= maxExplainFileOffenders {
+				result.WriteString(fmt.Sprintf("... and %d more\n", len(explained)-rank))
+				break
+			}
+			result.WriteString(fmt.Sprintf("%d. #%d (cost %.2f, %d warnings) %s\n",
+				rank+1, stmt.index, stmt.summary.TotalCost, len(stmt.summary.Warnings), truncateSQL(stmt.sql, 80)))
+			for _, w := range stmt.summary.Warnings {
+				result.WriteString(fmt.Sprintf("   - %s\n", w.Message))
+			}
+		}
+	}
+
+	if len(skipped) > 0 {
+		result.WriteString("\nSkipped (not explainable):\n")
+		for _, s := range skipped {
+			result.WriteString("- " + s + "\n")
+		}
+	}
+
+	if len(failed) > 0 {
+		result.WriteString("\nFailed:\n")
+		for _, f := range failed {
+			result.WriteString("- " + f + "\n")
+		}
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// truncateSQL drops comments, collapses whitespace and shortens a statement for display
+func truncateSQL(sql string, maxLen int) string {
+	sql = strings.Join(strings.Fields(sqlanalysis.StripComments(sql)), " ")
+	if len([]rune(sql)) <= maxLen {
+		return sql
+	}
+	return string([]rune(sql)[:maxLen-3]) + "..."
+}
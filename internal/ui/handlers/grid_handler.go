@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// gridMaxRows is how many rows of a query the data grid reads
+const gridMaxRows = 500
+
+// GridSource is what the data grid shows: the first rows of a query and, when
+// the rows can be traced to a table by its primary key, how to write edited
+// cells back
+type GridSource struct {
+	Query    string
+	Result   *models.QueryResult
+	More     bool  // The query has more rows than were read
+	ReadOnly error // Why the rows cannot be edited, nil when they can
+	CanEdit  func(column int) error
+	// Prepare returns the UPDATE setting a cell, for confirmation, and the
+	// function running it
+	Prepare func(row []interface{}, column int, value *string) (string, func() error, error)
+}
+
+// editGrid opens the data grid on a table, a read-only query, or the last
+// query the AI ran when query is empty
+func (h *CommandHandler) editGrid(query string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	if h.connService.GetCurrentTools() == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	if query != "" && !strings.ContainsAny(query, " \t\n") {
+		query = "SELECT * FROM " + query
+	}
+	if sqlanalysis.CountStatements(query) > 1 {
+		return true, "/grid shows one query at a time", nil
+	}
+	if verb := sqlanalysis.FirstWrite(query); verb != "" {
+		return true, fmt.Sprintf("/grid shows the rows of a SELECT; run %s statements by asking the AI", verb), nil
+	}
+	// The grid is part of the model and the last query is known to the AI
+	// client, the state manager opens it
+	return true, "GRID:" + query, nil
+}
+
+// GridSource reads the rows of a query on the current connection for the
+// data grid. Rows are editable unless the connection is read-only or they do
+// not map to single rows of one table.
+func (h *CommandHandler) GridSource(query string) (*GridSource, error) {
+	if h.connService == nil {
+		return nil, fmt.Errorf("connection service not available")
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return nil, fmt.Errorf("no active database connection")
+	}
+
+	cursor, err := dbinterfaces.OpenCursor(db, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+	rows, err := cursor.Next(gridMaxRows + 1)
+	if err != nil {
+		return nil, err
+	}
+	source := &GridSource{Query: query, More: len(rows) > gridMaxRows}
+	if source.More {
+		rows = rows[:gridMaxRows]
+	}
+	source.Result = &models.QueryResult{Columns: cursor.Columns(), Rows: rows, RowCount: len(rows)}
+
+	editor, err := database.NewRowEditor(db, query, source.Result.Columns)
+	if err == nil && dbinterfaces.IsReadOnly(db) {
+		err = fmt.Errorf("the connection is read-only")
+	}
+	if err != nil {
+		source.ReadOnly = err
+		source.CanEdit = func(int) error { return fmt.Errorf("rows cannot be edited: %w", err) }
+		source.Prepare = func([]interface{}, int, *string) (string, func() error, error) {
+			return "", nil, fmt.Errorf("rows cannot be edited: %w", err)
+		}
+		return source, nil
+	}
+	source.CanEdit = editor.CanEdit
+	source.Prepare = func(row []interface{}, column int, value *string) (string, func() error, error) {
+		update, err := editor.Update(row, column, value)
+		if err != nil {
+			return "", nil, err
+		}
+		return update.Preview, func() error { return editor.Apply(update) }, nil
+	}
+	return source, nil
+}
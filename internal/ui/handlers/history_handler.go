@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/internal/history"
+)
+
+// defaultHistoryLimit is the number of query groups shown by /history
+const defaultHistoryLimit = 20
+
+// historyUsage describes the forms of /history
+const historyUsage = "Usage: /history [today|week|all] [limit] | search <term> [@connection] | run <n>"
+
+// showQueryHistory lists the executed statements of a period grouped by
+// fingerprint, or searches and re-runs individual statements
+func (h *CommandHandler) showQueryHistory(args []string) (bool, string, error) {
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "search":
+			return h.searchQueryHistory(args[1:])
+		case "run":
+			return h.rerunQueryHistory(args[1:])
+		}
+	}
+
+	period := "today"
+	limit := defaultHistoryLimit
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			limit = n
+			continue
+		}
+		period = strings.ToLower(arg)
+	}
+
+	now := time.Now()
+	var since time.Time
+	switch period {
+	case "today":
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	case "week":
+		since = now.AddDate(0, 0, -7)
+	case "all":
+	default:
+		return true, historyUsage, nil
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read query history: %v", err), nil
+	}
+	groups := history.GroupByFingerprint(entries, since)
+	if len(groups) == 0 {
+		return true, fmt.Sprintf("No statements in the query history for %s.", period), nil
+	}
+
+	runs := 0
+	for _, g := range groups {
+		runs += g.Runs
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Query history (%s): %d runs of %d distinct queries, most total time first\n", period, runs, len(groups)))
+	for i, g := range groups {
+		if i == limit {
+			b.WriteString(fmt.Sprintf("\n... %d more. Use /history %s %d to see all.", len(groups)-limit, period, len(groups)))
+			break
+		}
+		lastRun := g.LastRun.Format("Jan 2 15:04")
+		if period == "today" {
+			lastRun = g.LastRun.Format("15:04")
+		}
+		b.WriteString(fmt.Sprintf("\n%d. %dx  total %s  mean %s  max %s  last %s", i+1, g.Runs,
+			formatMs(g.TotalMs), formatMs(g.MeanMs()), formatMs(g.MaxMs), lastRun))
+		if g.Errors > 0 {
+			b.WriteString(fmt.Sprintf("  (%d failed)", g.Errors))
+		}
+		b.WriteString("\n   " + g.Fingerprint + "\n")
+	}
+	return true, strings.TrimRight(b.String(), "\n"), nil
+}
+
+// searchQueryHistory lists the individual runs of the statements containing
+// every word of a term, newest first, for /history run
+func (h *CommandHandler) searchQueryHistory(args []string) (bool, string, error) {
+	connection := ""
+	var words []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") && len(arg) > 1 {
+			connection = arg[1:]
+			continue
+		}
+		words = append(words, arg)
+	}
+	term := strings.Join(words, " ")
+	if term == "" && connection == "" {
+		return true, historyUsage, nil
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read query history: %v", err), nil
+	}
+	h.lastHistory = history.Statements(entries, term, connection, defaultHistoryLimit)
+	if len(h.lastHistory) == 0 {
+		return true, "No statements in the query history match.", nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Statements matching %q, newest first:\n", strings.TrimSpace(strings.Join(args, " "))))
+	for i, entry := range h.lastHistory {
+		b.WriteString(fmt.Sprintf("\n%2d. %s", i+1, entry.Time.Format("Mon Jan 2 15:04")))
+		if entry.Connection != "" {
+			b.WriteString("  " + entry.Connection)
+		}
+		if entry.Error != "" {
+			b.WriteString("  failed: " + entry.Error)
+		} else {
+			b.WriteString(fmt.Sprintf("  %s  %d rows", formatMs(entry.DurationMs), entry.Rows))
+		}
+		sql := strings.Join(strings.Fields(entry.SQL), " ")
+		if len(sql) > 100 {
+			sql = sql[:97] + "..."
+		}
+		b.WriteString("\n    " + sql)
+	}
+	b.WriteString("\n\nUse /history run <n> to run one again.")
+	return true, b.String(), nil
+}
+
+// rerunQueryHistory switches to the connection a /history search result ran
+// on and places its SQL in the input
+func (h *CommandHandler) rerunQueryHistory(args []string) (bool, string, error) {
+	if len(args) < 1 {
+		return true, "Usage: /history run <n> (a result number from /history search)", nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(h.lastHistory) {
+		return true, fmt.Sprintf("No history result %s. Run /history search <term> first.", args[0]), nil
+	}
+	entry := h.lastHistory[n-1]
+
+	message := ""
+	if entry.Connection != "" && h.connService != nil {
+		if _, _, current := h.connService.GetConnectionInfo(); current != entry.Connection {
+			if err := h.connService.SwitchConnection(entry.Connection); err != nil {
+				return true, fmt.Sprintf("The statement ran on '%s', which could not be switched to: %v", entry.Connection, err), nil
+			}
+			h.IndexSchema()
+			message = fmt.Sprintf("Switched to connection: %s\n", entry.Connection)
+		}
+	}
+	return true, FillInput(strings.TrimSpace(entry.SQL), message+"The statement is in the input: press Enter to run it again, or edit it first."), nil
+}
+
+// formatMs renders a duration in milliseconds, switching to seconds above one second
+func formatMs(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.2fs", ms/1000)
+	}
+	return fmt.Sprintf("%.1fms", ms)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/masking"
+)
+
+const maskingUsage = "Usage: /masking [on|off] | rule <name> on|off | column <column> mask|unmask|auto"
+
+// maskingCommand shows or changes how the current connection masks sensitive
+// values before results are sent to the AI
+func (h *CommandHandler) maskingCommand(args []string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	if h.connService.GetCurrentTools() == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	_, _, name := h.connService.GetConnectionInfo()
+
+	settings, err := masking.Load(name)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read the masking settings: %v", err), nil
+	}
+	if len(args) == 0 {
+		return true, describeMasking(name, settings), nil
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on", "off":
+		if len(args) != 1 {
+			return true, maskingUsage, nil
+		}
+		settings.Disabled = strings.EqualFold(args[0], "off")
+	case "rule":
+		if len(args) != 3 || (!strings.EqualFold(args[2], "on") && !strings.EqualFold(args[2], "off")) {
+			return true, maskingUsage, nil
+		}
+		if err := settings.SetRule(args[1], strings.EqualFold(args[2], "on")); err != nil {
+			return true, fmt.Sprintf("%v. Rules: %s", err, ruleNames()), nil
+		}
+	case "column":
+		if len(args) != 3 {
+			return true, maskingUsage, nil
+		}
+		if err := settings.SetColumn(args[1], strings.ToLower(args[2])); err != nil {
+			return true, err.Error(), nil
+		}
+	default:
+		return true, maskingUsage, nil
+	}
+
+	if err := masking.Save(name, settings); err != nil {
+		return true, fmt.Sprintf("Failed to save the masking settings: %v", err), nil
+	}
+	return true, describeMasking(name, settings), nil
+}
+
+// describeMasking lists the masking rules and column overrides of a connection
+func describeMasking(name string, settings masking.Settings) string {
+	if settings.Disabled {
+		return fmt.Sprintf("Masking is off on '%s': the AI sees every value. /masking on masks sensitive values again.", name)
+	}
+	lines := []string{fmt.Sprintf("Masking on '%s' redacts these values before results are sent to the AI:", name)}
+	for _, rule := range masking.Rules {
+		state := "on"
+		if !settings.RuleEnabled(rule.Name) {
+			state = "off"
+		}
+		lines = append(lines, fmt.Sprintf("  %-12s %-3s  %s, by column name and value", rule.Name, state, rule.Description))
+	}
+	columns := make([]string, 0, len(settings.Columns))
+	for column := range settings.Columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		lines = append(lines, fmt.Sprintf("  column %s: %s", column, settings.Columns[column]))
+	}
+	lines = append(lines, "Results you see and export are not masked. "+maskingUsage)
+	return strings.Join(lines, "\n")
+}
+
+// ruleNames lists the names of the built-in masking rules
+func ruleNames() string {
+	names := make([]string, len(masking.Rules))
+	for i, rule := range masking.Rules {
+		names[i] = rule.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/internal/ui/renderers"
+)
+
+// showPlan runs EXPLAIN on a statement and draws its plan as a tree. With
+// analyze the statement is run too, for actual rows and times, which is
+// refused for statements that change data.
+func (h *CommandHandler) showPlan(sql string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	analyze := false
+	if fields := strings.Fields(sql); len(fields) > 1 && strings.EqualFold(fields[0], "analyze") {
+		analyze = true
+		sql = strings.TrimSpace(sql[len(fields[0]):])
+	}
+	if !sqlanalysis.IsExplainable(sql) {
+		return true, "Only SELECT, WITH, INSERT, UPDATE, DELETE, REPLACE, VALUES and TABLE statements have a plan", nil
+	}
+	if analyze {
+		if write := sqlanalysis.FirstWrite(sql); write != "" {
+			return true, fmt.Sprintf("/plan analyze runs the statement, and %s changes data; use /plan without analyze for its estimated plan", write), nil
+		}
+	}
+
+	dialect := h.currentDatabaseType()
+	statement := sqlanalysis.BuildExplainStatement(dialect, sql)
+	if analyze {
+		statement = sqlanalysis.BuildExplainAnalyzeStatement(dialect, sql)
+	}
+	result, err := db.ExecuteSQL(statement)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to explain the statement: %v", err), nil
+	}
+	root, err := sqlanalysis.ParsePlan(result)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read the plan: %v", err), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(renderers.RenderPlan(root, 0))
+	if analyze && dialect == "sqlite" {
+		b.WriteString("\n\nSQLite cannot analyze a statement, so this is its estimated plan.")
+	}
+	thresholds, _ := sqlanalysis.LoadThresholds()
+	if summary, err := sqlanalysis.AnalyzePlanWithThresholds(dialect, result, thresholds); err == nil && len(summary.Warnings) > 0 {
+		b.WriteString("\n\nWarnings:")
+		for _, warning := range summary.Warnings {
+			b.WriteString("\n- " + warning.Message)
+		}
+	}
+	return true, b.String(), nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/ui/renderers"
+)
+
+const previewUsage = "Usage: /preview <table> [rows] [first | random | newest [column]]\n" +
+	"Example: /preview orders\nExample: /preview events 20 newest\nExample: /preview users random"
+
+// parsePreviewArgs reads the table, row count, mode and newest column of /preview
+func parsePreviewArgs(args []string) (tools.PreviewOptions, error) {
+	options := tools.PreviewOptions{Table: args[0], Mode: tools.PreviewFirst}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n < 1 {
+				return options, fmt.Errorf("the number of rows must be positive")
+			}
+			options.Rows = n
+			continue
+		}
+		mode, err := tools.ParsePreviewMode(arg)
+		if err != nil {
+			if options.Mode == tools.PreviewNewest && options.OrderColumn == "" {
+				options.OrderColumn = arg
+				continue
+			}
+			return options, err
+		}
+		options.Mode = mode
+	}
+	return options, nil
+}
+
+// preview shows a sample of a table's rows, with long text and JSON values
+// shortened
+func (h *CommandHandler) preview(args []string) (bool, string, error) {
+	if len(args) == 0 {
+		return true, previewUsage, nil
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	options, err := parsePreviewArgs(args)
+	if err != nil {
+		return true, fmt.Sprintf("%v\n%s", err, previewUsage), nil
+	}
+	if options.Rows > tools.MaxPreviewRows {
+		options.Rows = tools.MaxPreviewRows
+	}
+	preview, err := tools.PreviewTable(db, options, "")
+	if err != nil {
+		return true, fmt.Sprintf("Cannot preview %s: %v", options.Table, err), nil
+	}
+
+	result, shortened := tools.ShortenValues(preview.Result, preview.Columns, tools.PreviewValueLength)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s of %s\n\n", preview.Result.Sampling, options.Table)
+	b.WriteString(renderers.FormatQueryResultTable(result))
+	if len(shortened) > 0 {
+		fmt.Fprintf(&b, "\nLong values of %s are shortened; /browse %s shows them in full.", strings.Join(shortened, ", "), options.Table)
+	}
+	return true, b.String(), nil
+}
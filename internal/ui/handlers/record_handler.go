@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+
+	"dbsage/internal/session"
+)
+
+// startRecording starts recording the session to a file
+func (h *CommandHandler) startRecording(path string) (bool, string, error) {
+	if h.recorder != nil {
+		return true, fmt.Sprintf("Already recording to %s. Use /record stop first.", h.recorder.Path()), nil
+	}
+
+	path = expandHomePath(path)
+	recorder, err := session.StartRecording(path, h.termWidth, h.termHeight)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to start recording: %v", err), nil
+	}
+	h.recorder = recorder
+
+	return true, fmt.Sprintf("Recording session to %s (%s format)\nUse /record stop to finish, then play it back with: dbsage replay %s",
+		path, recorder.Format(), path), nil
+}
+
+// stopRecording finishes the active recording
+func (h *CommandHandler) stopRecording() (bool, string, error) {
+	if h.recorder == nil {
+		return true, "Not recording. Use /record start <file> to begin.", nil
+	}
+
+	recorder := h.recorder
+	h.recorder = nil
+	if err := recorder.Close(); err != nil {
+		return true, fmt.Sprintf("Failed to save recording: %v", err), nil
+	}
+	return true, fmt.Sprintf("Recording saved to %s (%d events)", recorder.Path(), recorder.Events()), nil
+}
+
+// StopRecording closes the active recording, if any, e.g. when the application exits
+func (h *CommandHandler) StopRecording() {
+	if h.recorder != nil {
+		h.recorder.Close()
+		h.recorder = nil
+	}
+}
+
+// RecordInput adds user input to the active recording
+func (h *CommandHandler) RecordInput(text string) {
+	if h.recorder != nil {
+		h.recorder.RecordInput(text)
+	}
+}
+
+// RecordOutput adds a response to the active recording
+func (h *CommandHandler) RecordOutput(text string) {
+	if h.recorder != nil {
+		h.recorder.RecordOutput(text)
+	}
+}
+
+// SetTerminalSize sets the terminal size written to new recordings
+func (h *CommandHandler) SetTerminalSize(width, height int) {
+	h.termWidth = width
+	h.termHeight = height
+}
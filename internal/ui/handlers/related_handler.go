@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+const relatedUsage = "Usage: /related <table> | <n> | back | join [table...]\nExample: /related orders"
+
+// related shows the tables a table is linked to by foreign keys. Moving to a
+// listed table extends the trail, which /related join turns into a JOIN.
+func (h *CommandHandler) related(args []string) (bool, string, error) {
+	if len(args) == 0 {
+		return true, relatedUsage, nil
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	relations, err := h.loadRelations(db)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read foreign keys: %v", err), nil
+	}
+
+	switch args[0] {
+	case "join":
+		tables := args[1:]
+		if len(tables) == 0 {
+			tables = h.relatedTrail
+		}
+		if len(tables) < 2 {
+			return true, "Usage: /related join <table> <table> [table...]\nWithout tables the trail of /related is joined; move to a related table with /related <n> first.", nil
+		}
+		sql, err := sqlanalysis.BuildJoinSkeleton(h.currentDatabaseType(), tables, relations)
+		if err != nil {
+			return true, fmt.Sprintf("Cannot build the join: %v", err), nil
+		}
+		return true, FillInput(sql, fmt.Sprintf("JOIN across %s placed in the input: pick the columns, then run or edit it.", strings.Join(tables, " → "))), nil
+
+	case "back":
+		if len(h.relatedTrail) < 2 {
+			return true, "Nothing to go back to. Use /related <table> to start.", nil
+		}
+		h.relatedTrail = h.relatedTrail[:len(h.relatedTrail)-1]
+		return true, h.showRelated(relations), nil
+	}
+
+	if n, err := strconv.Atoi(args[0]); err == nil {
+		if n < 1 || n > len(h.relatedChoices) {
+			return true, fmt.Sprintf("No related table %d. Run /related <table> first.", n), nil
+		}
+		h.relatedTrail = append(h.relatedTrail, h.relatedChoices[n-1])
+		return true, h.showRelated(relations), nil
+	}
+
+	h.relatedTrail = []string{args[0]}
+	return true, h.showRelated(relations), nil
+}
+
+// loadRelations reads the foreign keys of the current connection, filling in
+// the primary key columns SQLite leaves out of implicit references
+func (h *CommandHandler) loadRelations(db dbinterfaces.DatabaseInterface) ([]sqlanalysis.Relation, error) {
+	query, err := sqlanalysis.BuildRelationsQuery(h.currentDatabaseType())
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.ExecuteSQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	relations := sqlanalysis.RelationsFromResult(result)
+	for i, r := range relations {
+		if len(r.RefColumns) > 0 {
+			continue
+		}
+		columns, err := db.GetTableSchema(r.RefTable)
+		if err != nil {
+			continue
+		}
+		for _, col := range columns {
+			if col.IsPrimaryKey {
+				relations[i].RefColumns = append(relations[i].RefColumns, col.ColumnName)
+			}
+		}
+	}
+	return relations, nil
+}
+
+// showRelated lists the tables related to the last table of the trail and
+// remembers them for /related <n>
+func (h *CommandHandler) showRelated(relations []sqlanalysis.Relation) string {
+	table := h.relatedTrail[len(h.relatedTrail)-1]
+	outgoing, incoming := sqlanalysis.RelatedTables(relations, table)
+	h.relatedChoices = nil
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Tables related to %s", table))
+	if len(h.relatedTrail) > 1 {
+		b.WriteString(fmt.Sprintf(" (trail: %s)", strings.Join(h.relatedTrail, " → ")))
+	}
+	b.WriteString(":\n")
+	if len(outgoing) == 0 && len(incoming) == 0 {
+		b.WriteString("\nNo foreign keys reference or leave this table.")
+		return b.String()
+	}
+
+	list := func(title string, relations []sqlanalysis.Relation, target func(sqlanalysis.Relation) string) {
+		if len(relations) == 0 {
+			return
+		}
+		b.WriteString("\n" + title + ":\n")
+		for _, r := range relations {
+			h.relatedChoices = append(h.relatedChoices, target(r))
+			b.WriteString(fmt.Sprintf("%2d. %s  (%s)\n", len(h.relatedChoices), target(r), r))
+		}
+	}
+	list("References", outgoing, func(r sqlanalysis.Relation) string { return r.RefTable })
+	list("Referenced by", incoming, func(r sqlanalysis.Relation) string { return r.Table })
+
+	b.WriteString("\nUse /related <n> to move to a table, /related back to return")
+	if len(h.relatedTrail) > 1 {
+		b.WriteString(", /related join to put a JOIN across the trail in the input")
+	}
+	b.WriteString(".")
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dbsage/internal/report"
+	"dbsage/internal/runbook"
+	"dbsage/internal/templatevars"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// runbookUsage describes /runbook
+const runbookUsage = "Usage: /runbook <name|file.yaml> [variable=value ...]\nRunbooks are read from ~/.dbsage/runbooks/<name>.yaml; /runbook alone lists them."
+
+// runRunbook runs a saved triage procedure against the current connection and
+// shows the report of its steps, or lists the runbooks without arguments
+func (h *CommandHandler) runRunbook(args []string) (bool, string, error) {
+	if len(args) == 0 {
+		return h.listRunbooks()
+	}
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	path, err := runbook.Find(expandHomePath(args[0]))
+	if err != nil {
+		return true, err.Error(), nil
+	}
+	rb, err := runbook.Load(path)
+	if err != nil {
+		return true, err.Error(), nil
+	}
+
+	now := time.Now()
+	given, err := templatevars.ParseAssignments(args[1:])
+	if err != nil {
+		return true, err.Error() + "\n" + runbookUsage, nil
+	}
+	values, err := templatevars.Resolve(rb.Texts(), rb.Variables, given, nil, now)
+	if err != nil {
+		names := templatevars.Names(rb.Texts()...)
+		return true, fmt.Sprintf("Runbook %s needs values for its variables (%s): pass them as name=value after the runbook name.", rb.Name(), strings.Join(names, ", ")), nil
+	}
+	if err := rb.ApplyVariables(values, dbinterfaces.GetDatabaseType(db)); err != nil {
+		return true, err.Error(), nil
+	}
+	if err := rb.Validate(); err != nil {
+		return true, fmt.Sprintf("Invalid runbook %s: %v", rb.Name(), err), nil
+	}
+
+	_, _, connection := h.connService.GetConnectionInfo()
+	r := runbook.Run(db, rb, connection, now)
+	content := r.Markdown()
+	if rb.Output != "" {
+		out := expandHomePath(report.ExpandPlaceholders(rb.Output, rb.Name(), now))
+		if err := report.WriteFile(out, content); err != nil {
+			content += fmt.Sprintf("\nFailed to write the report: %v", err)
+		} else {
+			content += "\nReport written to " + out
+		}
+	}
+	if failed := r.Failed(); failed > 0 {
+		content += fmt.Sprintf("\n%d of %d steps failed.", failed, len(r.Sections))
+	}
+	return true, strings.TrimRight(content, "\n"), nil
+}
+
+// listRunbooks lists the runbooks in the runbook directory with their descriptions
+func (h *CommandHandler) listRunbooks() (bool, string, error) {
+	names, err := runbook.List()
+	if err != nil {
+		return true, err.Error(), nil
+	}
+	if len(names) == 0 {
+		return true, fmt.Sprintf("No runbooks in %s.\n%s", runbook.Dir(), runbookUsage), nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Runbooks:\n")
+	for _, name := range names {
+		b.WriteString("\n- " + name)
+		path, err := runbook.Find(name)
+		if err != nil {
+			continue
+		}
+		if rb, err := runbook.Load(path); err != nil {
+			b.WriteString(": invalid, " + err.Error())
+		} else if rb.Description != "" {
+			b.WriteString(": " + strings.Join(strings.Fields(rb.Description), " "))
+		}
+	}
+	b.WriteString("\n\nUse /runbook <name> to run one.")
+	return true, b.String(), nil
+}
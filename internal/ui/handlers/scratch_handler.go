@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+const scratchUsage = "Usage: /scratch start [duration] | end\nExample: /scratch start 15m"
+
+// scratchpad opens, ends or shows the scratchpad of the current connection: a
+// transaction that every statement runs in until it is rolled back
+func (h *CommandHandler) scratchpad(args []string) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+	_, _, name := h.connService.GetConnectionInfo()
+
+	action := "status"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	switch action {
+	case "status":
+		expiry := dbinterfaces.ScratchpadExpiry(db)
+		if expiry.IsZero() {
+			return true, fmt.Sprintf("No scratchpad is open on '%s'.\n%s", name, scratchUsage), nil
+		}
+		return true, fmt.Sprintf("Scratchpad open on '%s' until %s (%s left). /scratch end rolls it back now.",
+			name, expiry.Format("15:04:05"), time.Until(expiry).Round(time.Second)), nil
+
+	case "start":
+		timeout := database.ScratchpadTimeout()
+		if len(args) > 1 {
+			d, err := time.ParseDuration(args[1])
+			if err != nil || d <= 0 {
+				return true, fmt.Sprintf("Invalid duration: %s\n%s", args[1], scratchUsage), nil
+			}
+			timeout = d
+		}
+		if err := dbinterfaces.StartScratchpad(db, timeout); err != nil {
+			return true, fmt.Sprintf("Cannot open a scratchpad: %v", err), nil
+		}
+		message := fmt.Sprintf("Scratchpad open on '%s' for %s: statements from you and the AI run in one transaction that is rolled back at %s or on /scratch end, so nothing they change persists.\n"+
+			"COMMIT and statements that would commit are refused. Sequences still advance, and schema lookups do not see tables created in the scratchpad.",
+			name, timeout, time.Now().Add(timeout).Format("15:04:05"))
+		if dbinterfaces.IsReadOnly(db) {
+			message += fmt.Sprintf("\n'%s' is read-only, so writes are still refused.", name)
+		}
+		return true, message, nil
+
+	case "end":
+		if err := dbinterfaces.EndScratchpad(db); err != nil {
+			return true, fmt.Sprintf("Cannot end the scratchpad: %v", err), nil
+		}
+		return true, fmt.Sprintf("Scratchpad on '%s' rolled back: nothing it changed was kept.", name), nil
+
+	default:
+		return true, scratchUsage, nil
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbsage/internal/catalog"
+	"dbsage/internal/history"
+	"dbsage/internal/models"
+)
+
+// maxSearchResults is the number of matches listed by /search
+const maxSearchResults = 20
+
+// search lists past questions, executed SQL and tables matching a term
+func (h *CommandHandler) search(term string) (bool, string, error) {
+	if term == "" {
+		return true, "Usage: /search <term>\nSearches past questions, executed SQL and table names; /jump <n> puts a result in the input.", nil
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read query history: %v", err), nil
+	}
+	// Schema matches are optional; history is still searched without a
+	// connection or while the catalog is still being indexed
+	var tables []models.TableInfo
+	indexing := ""
+	if cat := h.schemaCatalog(); cat != nil {
+		requestSchemas(cat, term)
+		var progress catalog.Progress
+		tables, progress = cat.Tables()
+		if !progress.Done {
+			indexing = progress.String()
+		}
+	}
+
+	matches := history.Search(term, entries, tables, time.Now())
+	if len(matches) > maxSearchResults {
+		matches = matches[:maxSearchResults]
+	}
+	h.lastSearch = matches
+	note := ""
+	if indexing != "" {
+		note = "\n\nTable matches may be incomplete: " + indexing
+	}
+	if len(matches) == 0 {
+		return true, fmt.Sprintf("Nothing matches %q.", term) + note, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Results for %q:\n", term))
+	for i, match := range matches {
+		text := strings.Join(strings.Fields(match.Text), " ")
+		if len(text) > 100 {
+			text = text[:97] + "..."
+		}
+		b.WriteString(fmt.Sprintf("\n%2d. [%s] %s", i+1, match.Kind, text))
+		if !match.Time.IsZero() {
+			b.WriteString(fmt.Sprintf("  (%s", match.Time.Format("Mon Jan 2 15:04")))
+			if match.Runs > 1 {
+				b.WriteString(fmt.Sprintf(", %dx", match.Runs))
+			}
+			b.WriteString(")")
+		}
+	}
+	b.WriteString("\n\nUse /jump <n> to put a result in the input.")
+	b.WriteString(note)
+	return true, b.String(), nil
+}
+
+// jump places the input of a /search result in the input box
+func (h *CommandHandler) jump(args []string) (bool, string, error) {
+	if len(args) < 1 {
+		return true, "Usage: /jump <n> (a result number from /search)", nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(h.lastSearch) {
+		return true, fmt.Sprintf("No search result %s. Run /search <term> first.", args[0]), nil
+	}
+	return true, FillInput(h.lastSearch[n-1].Input, ""), nil
+}
+
+// fillInputSeparator separates the text of a FILL_INPUT response from the
+// message shown with it
+const fillInputSeparator = "\x00"
+
+// FillInput builds a response that places text in the input box, showing
+// message (or a default hint when empty) as the command's output
+func FillInput(text, message string) string {
+	if message == "" {
+		return "FILL_INPUT:" + text
+	}
+	return "FILL_INPUT:" + text + fillInputSeparator + message
+}
+
+// ParseFillInput splits a FILL_INPUT response into the input text and the
+// message to show, which is empty when the command did not provide one
+func ParseFillInput(response string) (string, string) {
+	text, message, _ := strings.Cut(strings.TrimPrefix(response, "FILL_INPUT:"), fillInputSeparator)
+	return text, message
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dbsage/internal/share"
+	"dbsage/internal/ui/renderers"
+)
+
+// shareCommand returns the file /share writes the session transcript to,
+// a dated file in the current directory by default
+func shareCommand(args []string) (bool, string, error) {
+	if len(args) > 1 {
+		return true, "Usage: /share [file.json]\nWrites the session's questions, SQL, answers and results with secrets and masked values removed; open it with /open.", nil
+	}
+	path := fmt.Sprintf("dbsage-share-%s.json", time.Now().Format("20060102-150405"))
+	if len(args) == 1 {
+		path = expandHomePath(args[0])
+	}
+	return true, "SHARE:" + path, nil
+}
+
+// openTranscript shows a shared transcript for review. Nothing in it is run,
+// and it does not join the conversation with the AI.
+func (h *CommandHandler) openTranscript(path string) (bool, string, error) {
+	t, err := share.Read(expandHomePath(path))
+	if err != nil {
+		return true, err.Error(), nil
+	}
+
+	var b strings.Builder
+	source := "a shared session"
+	if t.Connection != "" {
+		source = fmt.Sprintf("connection '%s'", t.Connection)
+		if t.DatabaseType != "" {
+			source += " (" + t.DatabaseType + ")"
+		}
+	}
+	fmt.Fprintf(&b, "Transcript of %s, shared %s with dbsage %s. Read-only: nothing below is run.\n",
+		source, t.SharedAt.Local().Format("2006-01-02 15:04"), t.DbsageVersion)
+	if t.Redactions > 0 || t.Masked > 0 {
+		fmt.Fprintf(&b, "%d secrets were redacted and %d values masked before sharing.\n", t.Redactions, t.Masked)
+	}
+
+	for i, turn := range t.Turns {
+		fmt.Fprintf(&b, "\n── Question %d ──\n", i+1)
+		if turn.Question != "" {
+			b.WriteString(turn.Question + "\n")
+		}
+		for _, sql := range turn.SQL {
+			b.WriteString("\n" + strings.TrimSpace(sql) + "\n")
+		}
+		if turn.Result != nil {
+			b.WriteString("\n" + renderers.FormatQueryResultTable(turn.Result) + "\n")
+			if turn.Result.Masked != "" {
+				b.WriteString("Masked: " + turn.Result.Masked + "\n")
+			}
+		}
+		if turn.Answer != "" {
+			b.WriteString("\n" + turn.Answer + "\n")
+		}
+	}
+	return true, strings.TrimRight(b.String(), "\n"), nil
+}
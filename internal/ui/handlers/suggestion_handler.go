@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/internal/usage"
+)
+
+// tableCommands take a table name as their first argument, which is suggested
+// from the tables of the current connection
+var tableCommands = map[string]bool{"/browse": true, "/grid": true, "/build": true, "/related": true, "/preview": true}
+
+// maxTableSuggestions is the number of tables suggested at once
+const maxTableSuggestions = 10
+
+// recordedUsage returns the recorded use of commands, connections and tables,
+// read once per session and then kept up to date by recordUse
+func (h *CommandHandler) recordedUsage() usage.Usage {
+	if h.usage == nil {
+		u, err := usage.Load()
+		if err != nil {
+			u = usage.Usage{}
+		}
+		h.usage = u
+	}
+	return h.usage
+}
+
+// recordUse counts a use of a name for ranking suggestions. It is best
+// effort: a failure to save it never fails the command.
+func (h *CommandHandler) recordUse(kind, name string) {
+	if u, err := usage.Record(kind, name, time.Now()); err == nil {
+		h.usage = u
+	}
+}
+
+// recordCommandUse counts a use of a known slash command, and of the table
+// it was given when it takes one
+func (h *CommandHandler) recordCommandUse(input string) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return
+	}
+	for _, cmd := range slashCommands {
+		if cmd.Name == fields[0] {
+			h.recordUse(usage.KindCommand, cmd.Name)
+			break
+		}
+	}
+	if len(fields) == 2 && tableCommands[fields[0]] {
+		if table, ok := h.knownTable(fields[1]); ok {
+			_, _, connection := h.connService.GetConnectionInfo()
+			h.recordUse(usage.TableKind(connection), table)
+		}
+	}
+}
+
+// knownTable returns the indexed table of the current connection a name refers
+// to, with or without its schema
+func (h *CommandHandler) knownTable(name string) (string, bool) {
+	for _, table := range h.indexedTables() {
+		if strings.EqualFold(table, name) || strings.EqualFold(unqualified(table), name) {
+			return table, true
+		}
+	}
+	return "", false
+}
+
+// indexedTables returns the names of the tables of the current connection
+// indexed so far, qualified by their schema when there are several
+func (h *CommandHandler) indexedTables() []string {
+	cat := h.schemaCatalog()
+	if cat == nil {
+		return nil
+	}
+	tables, _ := cat.Tables()
+	schemas := map[string]bool{}
+	for _, table := range tables {
+		schemas[table.Schema] = true
+	}
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.TableName
+		if len(schemas) > 1 && table.Schema != "" {
+			names[i] = table.Schema + "." + table.TableName
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unqualified returns a table name without its schema
+func unqualified(table string) string {
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+// tableSuggestions suggests the tables of the current connection whose name
+// starts with prefix as arguments of a command, the most used recently first
+func (h *CommandHandler) tableSuggestions(command, prefix string, now time.Time) []*models.CommandInfo {
+	if strings.ContainsAny(prefix, " \t") {
+		return nil
+	}
+	var tables []string
+	for _, table := range h.indexedTables() {
+		if hasFoldPrefix(table, prefix) || hasFoldPrefix(unqualified(table), prefix) {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	_, _, connection := h.connService.GetConnectionInfo()
+	usage.Rank(h.recordedUsage(), usage.TableKind(connection), tables, func(table string) string { return table }, now)
+	if len(tables) > maxTableSuggestions {
+		tables = tables[:maxTableSuggestions]
+	}
+
+	suggestions := make([]*models.CommandInfo, len(tables))
+	for i, table := range tables {
+		suggestions[i] = &models.CommandInfo{Name: command + " " + table, Description: "Table " + table, Category: "table"}
+	}
+	return suggestions
+}
+
+// hasFoldPrefix reports whether s starts with prefix, ignoring case
+func hasFoldPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// commandName returns the name of a suggested command
+func commandName(cmd *models.CommandInfo) string {
+	return cmd.Name
+}
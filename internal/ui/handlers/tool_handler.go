@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"dbsage/internal/ai"
+	"dbsage/internal/ai/tools"
 	"dbsage/internal/models"
 
 	"github.com/sashabaranov/go-openai"
@@ -47,24 +48,43 @@ func (h *ToolHandler) CreateToolConfirmationInfo(toolName, toolCallID string, ar
 		}
 	}
 
+	// Tools that only plan a statement say when their arguments make them run it
+	if tools.RunsStatement(toolName, args) {
+		if sql, ok := args["sql"].(string); ok {
+			description = fmt.Sprintf("Run with EXPLAIN ANALYZE, with each rewrite: %s", sql)
+		}
+		riskLevel = "high"
+	}
+
+	// Name the file a tool writes to
+	if path, ok := args["path"].(string); ok && path != "" {
+		description += " to " + path
+	}
+
 	if riskLevel == "" {
 		riskLevel = "medium"
 	}
 
-	// Create confirmation options
+	// Create confirmation options; each can also be chosen with its key or number
 	options := []models.ConfirmationOption{
 		{
-			Key:         "1",
+			Key:         "y",
 			Label:       "Execute",
 			Description: "Execute the operation",
 			Action:      "execute",
 		},
 		{
-			Key:         "2",
+			Key:         "n",
 			Label:       "Cancel",
 			Description: "Cancel the operation",
 			Action:      "cancel",
 		},
+		{
+			Key:         "e",
+			Label:       "Edit",
+			Description: "Put the request in the input to change it",
+			Action:      "edit",
+		},
 	}
 
 	return &models.ToolConfirmationInfo{
@@ -77,6 +97,35 @@ func (h *ToolHandler) CreateToolConfirmationInfo(toolName, toolCallID string, ar
 	}
 }
 
+// SetStepControls turns a confirmation into a pause of a stepped turn: the
+// user can run this tool call, run the rest of the turn without pausing, or
+// abort the turn
+func (h *ToolHandler) SetStepControls(info *models.ToolConfirmationInfo, step int, reason string, remaining []string) {
+	info.Step = step
+	info.Reason = reason
+	info.Remaining = remaining
+	info.Options = []models.ConfirmationOption{
+		{
+			Key:         "s",
+			Label:       "Step",
+			Description: "Run this tool call and pause before the next one",
+			Action:      "execute",
+		},
+		{
+			Key:         "c",
+			Label:       "Continue",
+			Description: "Run the rest of the turn without pausing",
+			Action:      "continue",
+		},
+		{
+			Key:         "a",
+			Label:       "Abort turn",
+			Description: "Stop the turn without running this tool call",
+			Action:      "cancel",
+		},
+	}
+}
+
 // HandleToolConfirmation handles tool confirmation requests
 func (h *ToolHandler) HandleToolConfirmation(
 	ctx context.Context,
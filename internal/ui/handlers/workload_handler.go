@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// defaultReplayRate is the number of statements replayed per second
+const defaultReplayRate = 10.0
+
+// replayProgressInterval is how often a replay reports its progress
+const replayProgressInterval = 100 * time.Millisecond
+
+// WorkloadJob is a /capture, /replay or /explain-file run in the background, so a long
+// workload neither freezes the interface nor keeps esc from stopping it. Run
+// reports its progress and returns the response to show; once ctx is
+// cancelled it stops and its response is discarded.
+type WorkloadJob struct {
+	Run func(ctx context.Context, progress func(string)) string
+}
+
+// TakeWorkloadJob returns the job /capture, /replay or /explain-file started
+// and clears it
+func (h *CommandHandler) TakeWorkloadJob() *WorkloadJob {
+	job := h.workloadJob
+	h.workloadJob = nil
+	return job
+}
+
+// startWorkload hands a job to the interface, which shows message until the
+// job reports progress
+func (h *CommandHandler) startWorkload(message string, run func(ctx context.Context, progress func(string)) string) (bool, string, error) {
+	h.workloadJob = &WorkloadJob{Run: run}
+	return true, "WORKLOAD:" + message, nil
+}
+
+// captureWorkload samples the heaviest queries of the current connection into a workload file
+func (h *CommandHandler) captureWorkload(path string, limit int) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+
+	db := h.connService.GetCurrentTools()
+	if db == nil {
+		return true, "No active database connection. Use /add or /switch first.", nil
+	}
+
+	dialect := h.currentDatabaseType()
+	query, err := sqlanalysis.BuildCaptureQuery(dialect, limit)
+	if err != nil {
+		return true, err.Error(), nil
+	}
+
+	path = expandHomePath(path)
+	return h.startWorkload(fmt.Sprintf("Capturing the %d heaviest queries into %s...", limit, path), func(ctx context.Context, _ func(string)) string {
+		return runCapture(ctx, db, dialect, query, path)
+	})
+}
+
+// runCapture reads the captured queries and writes them to the workload file.
+// The query is cancelled on the server when the user aborts it.
+func runCapture(ctx context.Context, db dbinterfaces.DatabaseInterface, dialect, query, path string) string {
+	result, err := db.ExecuteSQL(query)
+	if ctx.Err() != nil {
+		return ""
+	}
+	if err != nil {
+		if capability, ok := sqlanalysis.MissingCapability(dialect, err); ok {
+			return fmt.Sprintf("Failed to capture workload: %s is not installed.\nTo enable %s: %s",
+				capability.Name, capability.Purpose, capability.Install)
+		}
+		if privilege, ok := sqlanalysis.MissingPrivilege(dialect, err); ok {
+			return "Failed to capture workload: " + grantFor(db, dialect, privilege).Explain()
+		}
+		return fmt.Sprintf("Failed to capture workload: %v\n"+
+			"PostgreSQL requires the pg_stat_statements extension; MySQL requires the "+
+			"events_statements_history_long consumer in performance_schema; ClickHouse requires log_queries for system.query_log.", err)
+	}
+
+	// Statements of other users are hidden without the stats privilege, which
+	// leaves a capture of only the user's own workload
+	var hiddenNote string
+	if hidden := sqlanalysis.HiddenRows(result, 0); hidden > 0 {
+		if privilege, ok := sqlanalysis.StatsPrivilege(dialect); ok {
+			hiddenNote = fmt.Sprintf("\n%d statements of other users were hidden and left out. %s",
+				hidden, grantFor(db, dialect, privilege).Explain())
+		}
+	}
+
+	entries := sqlanalysis.WorkloadFromResult(result)
+	if len(entries) == 0 {
+		return "No queries captured. The statistics source is empty." + hiddenNote
+	}
+
+	if err := os.WriteFile(path, []byte(sqlanalysis.FormatWorkload(entries)), 0644); err != nil {
+		return fmt.Sprintf("Failed to write workload file: %v", err)
+	}
+
+	parameterized := 0
+	for _, e := range entries {
+		if sqlanalysis.HasPlaceholders(e.SQL) {
+			parameterized++
+		}
+	}
+
+	response := fmt.Sprintf("Captured %d queries to %s", len(entries), path)
+	if parameterized > 0 {
+		response += fmt.Sprintf("\n%d queries are normalized with placeholders ($1, ?). "+
+			"Replace them with sample values before replaying, or they will be skipped.", parameterized)
+	}
+	return response + hiddenNote
+}
+
+// grantFor fills in the connected user of a missing privilege
+func grantFor(db dbinterfaces.DatabaseInterface, dialect string, privilege sqlanalysis.Privilege) sqlanalysis.Privilege {
+	user := ""
+	if result, err := db.ExecuteSQL(sqlanalysis.CurrentUserQuery(dialect)); err == nil {
+		user = sqlanalysis.CurrentUserFromResult(result)
+	}
+	return privilege.For(user)
+}
+
+// replayWorkload replays a workload file, or a MySQL general query log,
+// against a named connection at a fixed rate and reports latency changes
+// compared to the captured baseline
+func (h *CommandHandler) replayWorkload(path, target string, rate float64) (bool, string, error) {
+	if h.connService == nil {
+		return true, "Connection service not available", nil
+	}
+
+	connections, _, _ := h.connService.GetConnectionInfo()
+	config, exists := connections[target]
+	if !exists {
+		return true, fmt.Sprintf("Connection '%s' not found. Use /list to see available connections.", target), nil
+	}
+
+	path = expandHomePath(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return true, fmt.Sprintf("Failed to read workload file: %v", err), nil
+	}
+
+	entries := sqlanalysis.ParseWorkload(string(content))
+	if sqlanalysis.IsGeneralLog(string(content)) {
+		entries = sqlanalysis.ParseGeneralLog(string(content))
+	}
+	if len(entries) == 0 {
+		return true, fmt.Sprintf("No SQL statements found in %s", path), nil
+	}
+
+	if rate <= 0 {
+		rate = defaultReplayRate
+	}
+	return h.startWorkload(fmt.Sprintf("Replaying %d statements of %s against %s at %.1f stmt/s...", len(entries), path, target, rate), func(ctx context.Context, progress func(string)) string {
+		return runReplay(ctx, progress, config, path, target, rate, entries)
+	})
+}
+
+// runReplay runs the statements of a workload one by one, reporting after
+// each how far it got. Aborting stops between statements and cancels the one
+// running on the server.
+func runReplay(ctx context.Context, progress func(string), config *dbinterfaces.ConnectionConfig, path, target string, rate float64, entries []sqlanalysis.WorkloadEntry) string {
+	// Use a dedicated connection so replaying does not change the active one
+	db, err := database.NewProviderManager().OpenConnection(config)
+	if err != nil {
+		return fmt.Sprintf("Failed to connect to '%s': %v", target, err)
+	}
+	defer db.Close()
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_, _ = dbinterfaces.CancelRunning(db)
+		case <-finished:
+		}
+	}()
+
+	interval := time.Duration(float64(time.Second) / rate)
+	var results []sqlanalysis.ReplayResult
+	var skipped []string
+	failed := 0
+	var reported time.Time
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			return ""
+		}
+		if time.Since(reported) >= replayProgressInterval {
+			progress(fmt.Sprintf("Replaying statement %d of %d from %s against %s (%d failed, %d skipped)...",
+				i+1, len(entries), path, target, failed, len(skipped)))
+			reported = time.Now()
+		}
+		if !sqlanalysis.IsReadOnly(entry.SQL) {
+			skipped = append(skipped, truncateSQL(entry.SQL, 60)+" (not read-only)")
+			continue
+		}
+		if sqlanalysis.HasPlaceholders(entry.SQL) {
+			skipped = append(skipped, truncateSQL(entry.SQL, 60)+" (has placeholders)")
+			continue
+		}
+
+		start := time.Now()
+		_, execErr := db.ExecuteSQL(entry.SQL)
+		elapsed := time.Since(start)
+		if ctx.Err() != nil {
+			return ""
+		}
+
+		result := sqlanalysis.ReplayResult{
+			Entry:     entry,
+			LatencyMs: float64(elapsed.Microseconds()) / 1000,
+		}
+		if execErr != nil {
+			result.Error = execErr.Error()
+			failed++
+		}
+		results = append(results, result)
+
+		if wait := interval - elapsed; wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ""
+			case <-time.After(wait):
+			}
+		}
+	}
+
+	thresholds, _ := sqlanalysis.LoadThresholds()
+	return formatReplayReport(path, target, rate, thresholds, results, skipped)
+}
+
+// formatReplayReport summarizes replay results with the largest regressions first
+func formatReplayReport(path, target string, rate float64, thresholds sqlanalysis.Thresholds, results []sqlanalysis.ReplayResult, skipped []string) string {
+	sqlanalysis.SortByRegression(results)
+
+	var latencies []float64
+	var baselineTotal, replayTotal float64
+	failed, slow := 0, 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+			continue
+		}
+		if r.LatencyMs >= thresholds.SlowQueryMs {
+			slow++
+		}
+		latencies = append(latencies, r.LatencyMs)
+		if r.Entry.MeanMs > 0 {
+			baselineTotal += r.Entry.MeanMs
+			replayTotal += r.LatencyMs
+		}
+	}
+	sort.Float64s(latencies)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Workload replay: %s → %s (%.1f stmt/s)\n", path, target, rate))
+	b.WriteString(fmt.Sprintf("Statements: %d replayed, %d failed, %d skipped\n", len(results), failed, len(skipped)))
+	if len(latencies) > 0 {
+		b.WriteString(fmt.Sprintf("Latency: p50 %.2fms, p95 %.2fms, max %.2fms\n",
+			percentile(latencies, 50), percentile(latencies, 95), latencies[len(latencies)-1]))
+	}
+	if slow > 0 {
+		b.WriteString(fmt.Sprintf("Slow statements (≥ %.0fms, %s profile): %d\n", thresholds.SlowQueryMs, thresholds.Profile, slow))
+	}
+	if baselineTotal > 0 {
+		b.WriteString(fmt.Sprintf("Total vs baseline: %.2fms → %.2fms (%+.1f%%)\n",
+			baselineTotal, replayTotal, (replayTotal-baselineTotal)/baselineTotal*100))
+	}
+
+	if len(results) > 0 {
+		b.WriteString("\nLargest regressions:\n")
+		for i, r := range results {
+			if i >= maxExplainFileOffenders {
+				b.WriteString(fmt.Sprintf("... and %d more\n", len(results)-i))
+				break
+			}
+			if r.Error != "" {
+				b.WriteString(fmt.Sprintf("%d. ERROR %s: %s\n", i+1, truncateSQL(r.Entry.SQL, 60), r.Error))
+				continue
+			}
+			if r.Entry.MeanMs > 0 {
+				b.WriteString(fmt.Sprintf("%d. %.2fms → %.2fms (%+.2fms, %+.1f%%) %s\n",
+					i+1, r.Entry.MeanMs, r.LatencyMs, r.DeltaMs(), r.DeltaPercent(), truncateSQL(r.Entry.SQL, 60)))
+			} else {
+				b.WriteString(fmt.Sprintf("%d. %.2fms (no baseline) %s\n", i+1, r.LatencyMs, truncateSQL(r.Entry.SQL, 60)))
+			}
+		}
+	}
+
+	if len(skipped) > 0 {
+		b.WriteString("\nSkipped:\n")
+		for _, s := range skipped {
+			b.WriteString("- " + s + "\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// expandHomePath expands a leading ~/ to the user's home directory
+func expandHomePath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
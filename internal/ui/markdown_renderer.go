@@ -3,6 +3,8 @@ package ui
 import (
 	"strings"
 
+	"dbsage/internal/ui/renderers"
+
 	"github.com/charmbracelet/glamour"
 )
 
@@ -31,7 +33,8 @@ func (mr *MarkdownRenderer) RenderMarkdown(content string) string {
 		maxWidth = 40
 	}
 
-	// Pre-process content to optimize tables
+	// Replace QueryResult JSON with tables, then optimize tables
+	content = renderers.RenderQueryResultsAsTables(content)
 	tableRenderer := NewTableRenderer(maxWidth)
 	content = tableRenderer.OptimizeTablesForDisplay(content)
 
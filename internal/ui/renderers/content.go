@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/internal/sqlerrors"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sashabaranov/go-openai"
@@ -33,11 +35,7 @@ func (r *ContentRenderer) RenderWelcomeBox() string {
 		"\n\n" +
 		lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240")).
-			Render("Ask me anything about your database! You can use natural language or commands.") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("? for help")
+			Render("Ask me anything about your database! You can use natural language or commands.")
 
 	return welcome
 }
@@ -81,61 +79,31 @@ func (r *ContentRenderer) RenderWelcomeBoxWithStatus(hasApiKey bool, hasDatabase
 		statusIndicators = append(statusIndicators, dbStatus)
 	}
 
-	help := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
-		Render("? for help")
-
 	var content []string
 	content = append(content, title, "", description, "")
 	content = append(content, statusIndicators...)
-	content = append(content, "", help)
 
 	return strings.Join(content, "\n")
 }
 
-// RenderHelp renders the help information
-func (r *ContentRenderer) RenderHelp() string {
-	helpContent := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")).
-		Render("Available commands:") +
-		"\n\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("Database Commands:") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /add <name>: Add database connection") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /switch <name>: Switch to connection") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /list: List all connections") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /remove <name>: Remove connection") +
-		"\n\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("General Commands:") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /help: Show this help") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /clear: Clear screen") +
-		"\n" +
-		lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("- /exit or /quit: Exit application")
+// HelpSection is a titled group of help lines
+type HelpSection struct {
+	Title string
+	Lines []string
+}
 
-	return helpContent
+// RenderHelp renders the help information from the command sections
+func (r *ContentRenderer) RenderHelp(sections []HelpSection) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	parts := []string{lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Render("Available commands:")}
+	for _, section := range sections {
+		lines := []string{style.Render(section.Title)}
+		for _, line := range section.Lines {
+			lines = append(lines, style.Render(line))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 // RenderHistory renders conversation history
@@ -170,7 +138,7 @@ func (r *ContentRenderer) renderAssistantMessage(content string) string {
 	return lipgloss.NewStyle().
 		Foreground(lipgloss.Color("252")).
 		Width(r.width - 4). // Leave some margin
-		Render("• " + content)
+		Render("• " + HighlightSQLBlocks(RenderQueryResultsAsTables(content)))
 }
 
 // RenderResponse renders a response message
@@ -178,6 +146,56 @@ func (r *ContentRenderer) RenderResponse(response string) string {
 	return r.renderAssistantMessage(response)
 }
 
+// RenderEditor renders the multi-line editor with its key hints
+func (r *ContentRenderer) RenderEditor(editorView string) string {
+	hint := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render("Multi-line input · ctrl+s to submit · esc to return to single line")
+
+	return hint + "\n" + editorView
+}
+
+// RenderConnectionForm renders the connection edit form with its key hints
+func (r *ContentRenderer) RenderConnectionForm(name, formView string) string {
+	hint := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render(fmt.Sprintf("Editing connection '%s' · tab/shift+tab to move · ctrl+s to test and save · esc to cancel", name))
+
+	return hint + "\n" + formView
+}
+
+// RenderQueryBuilder renders the query builder with its key hints
+func (r *ContentRenderer) RenderQueryBuilder(builderView string) string {
+	hint := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render("Query builder · tab/shift+tab to move · ctrl+r to use the query · ctrl+e to refine it with the AI · esc to cancel")
+
+	return hint + "\n" + builderView
+}
+
+// RenderResultPager renders a page of the result pager with the query, the
+// position in the result and the key hints
+func (r *ContentRenderer) RenderResultPager(query string, page *models.QueryResult, status string) string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	hint := muted.Render("Results · n/→ next page · p/← previous page · g first page · esc to close")
+	header := muted.Width(r.width - 4).Render(strings.Join(strings.Fields(query), " "))
+
+	var table string
+	if page != nil {
+		table = FormatResultPage(page) + "\n"
+	}
+	return hint + "\n" + header + "\n" + table + muted.Render(status)
+}
+
+// RenderDataGrid renders the data grid with the query of its rows and the key hints
+func (r *ContentRenderer) RenderDataGrid(query, gridView string) string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	hint := muted.Render("Data grid · arrows to move · enter to edit a cell · pgup/pgdown to scroll · esc to close")
+	header := muted.Width(r.width - 4).Render(strings.Join(strings.Fields(query), " "))
+
+	return hint + "\n" + header + "\n" + gridView
+}
+
 // RenderError renders an error message
 func (r *ContentRenderer) RenderError(err error) string {
 	errorContent := lipgloss.NewStyle().
@@ -185,15 +203,27 @@ func (r *ContentRenderer) RenderError(err error) string {
 		Width(r.width - 4). // Leave some margin
 		Render("Error: " + err.Error())
 
+	// Database errors come with a local explanation and next steps
+	if explanation := sqlerrors.Explain(err); explanation != nil {
+		errorContent += "\n\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Width(r.width-4).
+			Render(explanation.String())
+	}
+
 	return errorContent
 }
 
-// RenderThinking renders the thinking animation
-func (r *ContentRenderer) RenderThinking() string {
+// RenderThinking renders the thinking animation, or the progress of a
+// running command when there is one
+func (r *ContentRenderer) RenderThinking(progress string) string {
+	if progress == "" {
+		progress = "Processing..."
+	}
 	thinkingContent := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
 		Width(r.width - 4). // Leave some margin
-		Render("Processing...")
+		Render(progress + " (esc to abort)")
 
 	return thinkingContent
 }
@@ -216,10 +246,14 @@ func (r *ContentRenderer) RenderToolConfirmationBox(toolInfo *models.ToolConfirm
 	}
 
 	// Build content with proper width constraints
+	heading := "Tool Confirmation Required"
+	if toolInfo.Step > 0 {
+		heading = fmt.Sprintf("Step %d: paused before the next tool call", toolInfo.Step)
+	}
 	title := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("252")).
 		Width(r.width - 4).
-		Render("Tool Confirmation Required")
+		Render(heading)
 
 	toolName := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
@@ -236,8 +270,54 @@ func (r *ContentRenderer) RenderToolConfirmationBox(toolInfo *models.ToolConfirm
 		Width(r.width - 4).
 		Render(fmt.Sprintf("Risk Level: %s", strings.ToUpper(toolInfo.RiskLevel)))
 
-	content := title + "\n\n" + toolName + "\n" + description + "\n" + riskLevel + "\n\n" + listView
-	return content
+	content := title + "\n\n" + toolName + "\n" + description + "\n" + riskLevel
+
+	// A stepped turn also shows why the AI made the call and what follows it
+	if toolInfo.Step > 0 {
+		reason := toolInfo.Reason
+		if reason == "" {
+			reason = "The AI did not say."
+		}
+		next := "Nothing else in this message; the AI then sees the results."
+		if len(toolInfo.Remaining) > 0 {
+			next = strings.Join(toolInfo.Remaining, ", ")
+		}
+		content += "\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Width(r.width-4).
+			Render(fmt.Sprintf("Why: %s\nThen: %s", reason, next))
+	}
+
+	// List what an ALTER or DROP affects, so the user sees what will break
+	if len(toolInfo.Impact) > 0 {
+		content += "\n\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Width(r.width-4).
+			Render("Impact:\n- "+strings.Join(toolInfo.Impact, "\n- "))
+	}
+
+	// Show the statement about to run, highlighted and indented below the details
+	if sql, ok := toolInfo.Arguments["sql"].(string); ok && strings.TrimSpace(sql) != "" {
+		statement := lipgloss.NewStyle().
+			PaddingLeft(2).
+			Width(r.width - 4).
+			Render(HighlightSQL(strings.TrimSpace(sql)))
+		content += "\n\n" + statement
+
+		// Say how a script runs, since statements before a failure stay applied
+		if n := len(sqlanalysis.SplitStatements(sql)); n > 1 && toolInfo.ToolName == "execute_sql" {
+			onError := "stopping at the first failure"
+			if continueOnError, _ := toolInfo.Arguments["continueOnError"].(bool); continueOnError {
+				onError = "continuing after failures"
+			}
+			content += "\n" + lipgloss.NewStyle().
+				Foreground(lipgloss.Color("240")).
+				Width(r.width-4).
+				Render(fmt.Sprintf("Script of %d statements run one by one, %s; statements before a failure stay applied.", n, onError))
+		}
+	}
+
+	return content + "\n\n" + listView
 }
 
 // RenderGuidance renders user guidance information
@@ -1,8 +1,11 @@
 package renderers
 
 import (
+	"fmt"
 	"strings"
 
+	"dbsage/internal/models"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -31,6 +34,7 @@ func (r *LayoutRenderer) BuildLayout(
 	inputBox string,
 	commandList string,
 	parameterHelp string,
+	statusBar string,
 	showDivider bool,
 ) string {
 	var sections []string
@@ -57,6 +61,10 @@ func (r *LayoutRenderer) BuildLayout(
 		sections = append(sections, parameterHelp)
 	}
 
+	if statusBar != "" {
+		sections = append(sections, statusBar)
+	}
+
 	return strings.Join(sections, "\n\n")
 }
 
@@ -82,3 +90,93 @@ func (r *LayoutRenderer) RenderConnectionIndicator(connectionName string) string
 
 	return style.Render(connectionName + " >")
 }
+
+// RenderStatusBar renders the one-line status bar with connection, model, last query
+// duration and context size on the left and the help hint on the right
+func (r *LayoutRenderer) RenderStatusBar(info models.StatusBarInfo) string {
+	mutedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	separator := mutedStyle.Render(" │ ")
+
+	var parts []string
+	if info.Connection != "" {
+		connStyle := lipgloss.NewStyle().
+			Foreground(environmentColor(info.Environment)).
+			Bold(true)
+		connection := "● " + info.Connection
+		if info.Environment != "" {
+			connection += " (" + info.Environment + ")"
+		}
+		parts = append(parts, connStyle.Render(connection))
+	} else {
+		parts = append(parts, mutedStyle.Render("○ no connection"))
+	}
+
+	if info.Health != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true).Render(info.Health))
+	}
+
+	if info.DownCount > 0 {
+		down := fmt.Sprintf("%d other connections down", info.DownCount)
+		if info.DownCount == 1 {
+			down = "1 other connection down"
+		}
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(down))
+	}
+
+	if info.Latency != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("remote "+info.Latency))
+	}
+
+	if info.Tenant != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("tenant "+info.Tenant))
+	}
+
+	if info.Scratchpad != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("scratchpad "+info.Scratchpad+" left"))
+	}
+
+	if info.Model != "" {
+		parts = append(parts, mutedStyle.Render(info.Model))
+	}
+
+	if info.QueryDuration != "" {
+		parts = append(parts, mutedStyle.Render("last query "+info.QueryDuration))
+	}
+
+	if info.TurnTiming != "" {
+		parts = append(parts, mutedStyle.Render(info.TurnTiming))
+	}
+
+	parts = append(parts, mutedStyle.Render(fmt.Sprintf("~%s tokens", formatTokenCount(info.ContextTokens))))
+
+	left := strings.Join(parts, separator)
+	right := mutedStyle.Render("? for help")
+
+	gap := r.width - 4 - lipgloss.Width(left) - lipgloss.Width(right)
+	if gap < 1 {
+		gap = 1
+	}
+	return left + strings.Repeat(" ", gap) + right
+}
+
+// environmentColor returns the status bar color for a connection environment
+func environmentColor(environment string) lipgloss.Color {
+	switch environment {
+	case models.EnvProduction:
+		return lipgloss.Color("196")
+	case models.EnvStaging:
+		return lipgloss.Color("214")
+	case models.EnvDevelopment:
+		return lipgloss.Color("46")
+	default:
+		return lipgloss.Color("69")
+	}
+}
+
+// formatTokenCount shortens large token counts, e.g. 12345 -> 12.3k
+func formatTokenCount(tokens int) string {
+	if tokens >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	}
+	return fmt.Sprintf("%d", tokens)
+}
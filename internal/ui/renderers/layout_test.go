@@ -0,0 +1,46 @@
+package renderers
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderStatusBar(t *testing.T) {
+	r := NewLayoutRenderer()
+	r.SetDimensions(120, 40)
+
+	bar := r.RenderStatusBar(models.StatusBarInfo{
+		Connection:    "prod-main",
+		Environment:   models.EnvProduction,
+		Model:         "gpt-4o-mini",
+		QueryDuration: "12ms",
+		ContextTokens: 2345,
+		Latency:       "180ms",
+	})
+
+	assert.Contains(t, bar, "prod-main (production)")
+	assert.Contains(t, bar, "gpt-4o-mini")
+	assert.Contains(t, bar, "last query 12ms")
+	assert.Contains(t, bar, "remote 180ms")
+	assert.Contains(t, bar, "~2.3k tokens")
+	assert.Contains(t, bar, "? for help")
+}
+
+func TestRenderStatusBar_NoConnection(t *testing.T) {
+	bar := NewLayoutRenderer().RenderStatusBar(models.StatusBarInfo{ContextTokens: 42})
+
+	assert.Contains(t, bar, "no connection")
+	assert.Contains(t, bar, "~42 tokens")
+	assert.NotContains(t, bar, "last query")
+	assert.NotContains(t, bar, "remote")
+}
+
+func TestRenderStatusBar_Health(t *testing.T) {
+	bar := NewLayoutRenderer().RenderStatusBar(models.StatusBarInfo{Connection: "main", Health: "down, retry in 8s", DownCount: 1})
+
+	assert.Contains(t, bar, "down, retry in 8s")
+	assert.Contains(t, bar, "1 other connection down")
+}
@@ -0,0 +1,169 @@
+package renderers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Shares of the plan's time or cost from which a node is highlighted
+const (
+	planHotShare  = 0.4 // Red
+	planWarmShare = 0.1 // Orange
+)
+
+// planMisestimate is how many times the actual rows may differ from the
+// estimate before the row counts are highlighted
+const planMisestimate = 10
+
+var (
+	planHotStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+	planWarmStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	planDetailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// FormatPlanResult renders an EXPLAIN result as a plan tree, reporting false
+// for results that are not plans
+func FormatPlanResult(result *models.QueryResult) (string, bool) {
+	root, err := sqlanalysis.ParsePlan(result)
+	if err != nil {
+		return "", false
+	}
+	return RenderPlan(root, 0), true
+}
+
+// RenderPlan draws a plan tree with one line per node: its operation, cost,
+// estimated and actual rows and time, followed by its conditions. The nodes
+// taking the largest share of the time, or of the estimated cost when the plan
+// was not analyzed, are highlighted, as are row estimates that are off by
+// more than planMisestimate times. Lines are cut at width, 0 for no limit.
+func RenderPlan(root *sqlanalysis.PlanNode, width int) string {
+	var total float64
+	byTime := root.Analyzed && root.ActualTime > 0
+	root.Walk(func(node *sqlanalysis.PlanNode) {
+		if byTime {
+			total += node.SelfTime()
+		} else {
+			total += node.SelfCost
+		}
+	})
+
+	header := "Execution plan"
+	switch {
+	case byTime:
+		header += ": " + sqlanalysis.FormatMillis(root.ActualTime) + " actual"
+	case root.TotalCost > 0:
+		header += ": estimated cost " + formatPlanNumber(root.TotalCost)
+	}
+	var b strings.Builder
+	b.WriteString(cutPlanLine(header, width) + "\n")
+	renderPlanNode(&b, root, "", "", total, byTime, width)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderPlanNode writes a node and its children. prefix starts the node's
+// line, and indent the lines below it.
+func renderPlanNode(b *strings.Builder, node *sqlanalysis.PlanNode, prefix, indent string, total float64, byTime bool, width int) {
+	share := 0.0
+	if total > 0 {
+		if byTime {
+			share = node.SelfTime() / total
+		} else {
+			share = node.SelfCost / total
+		}
+	}
+
+	label := node.Operation
+	if node.Relation != "" && !strings.Contains(label, node.Relation) {
+		label += " on " + node.Relation
+	}
+	if node.Index != "" && !strings.Contains(label, node.Index) {
+		label += " using " + node.Index
+	}
+	switch {
+	case share >= planHotShare:
+		label = planHotStyle.Render(label)
+	case share >= planWarmShare:
+		label = planWarmStyle.Render(label)
+	}
+
+	var stats []string
+	if node.TotalCost > 0 {
+		stats = append(stats, "cost "+formatPlanNumber(node.TotalCost))
+	}
+	if rows := planRows(node); rows != "" {
+		stats = append(stats, rows)
+	}
+	if node.Analyzed {
+		stats = append(stats, sqlanalysis.FormatMillis(node.ActualTime))
+	}
+	if share >= planWarmShare {
+		stats = append(stats, fmt.Sprintf("%.0f%%", share*100))
+	}
+	line := prefix + label
+	if len(stats) > 0 {
+		line += "  " + strings.Join(stats, " · ")
+	}
+	b.WriteString(cutPlanLine(line, width) + "\n")
+
+	childIndent := indent + "│  "
+	if len(node.Children) == 0 {
+		childIndent = indent + "   "
+	}
+	for _, detail := range node.Details {
+		b.WriteString(planDetailStyle.Render(cutPlanLine(childIndent+detail, width)) + "\n")
+	}
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			renderPlanNode(b, child, indent+"└─ ", indent+"   ", total, byTime, width)
+		} else {
+			renderPlanNode(b, child, indent+"├─ ", indent+"│  ", total, byTime, width)
+		}
+	}
+}
+
+// planRows describes the estimated and actual rows of a node
+func planRows(node *sqlanalysis.PlanNode) string {
+	if !node.Analyzed {
+		if node.EstimatedRows == 0 {
+			return ""
+		}
+		return "rows " + formatPlanNumber(node.EstimatedRows) + " est"
+	}
+	rows := "rows " + formatPlanNumber(node.ActualRows) + " actual / " + formatPlanNumber(node.EstimatedRows*max(node.Loops, 1)) + " est"
+	if off := node.Misestimate(); off >= planMisestimate {
+		rows = planWarmStyle.Render(fmt.Sprintf("%s (%.0f× off)", rows, off))
+	}
+	return rows
+}
+
+// formatPlanNumber renders costs and row counts with thousands separators and
+// at most one decimal
+func formatPlanNumber(value float64) string {
+	text := strconv.FormatFloat(value, 'f', 0, 64)
+	if value < 100 && value != float64(int64(value)) {
+		return strconv.FormatFloat(value, 'f', 1, 64)
+	}
+	var b strings.Builder
+	for i, digit := range text {
+		if i > 0 && (len(text)-i)%3 == 0 {
+			b.WriteRune(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// cutPlanLine shortens a line to width cells, 0 for no limit
+func cutPlanLine(line string, width int) string {
+	if width <= 0 {
+		return line
+	}
+	return ansi.Truncate(line, width, "...")
+}
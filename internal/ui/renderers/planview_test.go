@@ -0,0 +1,61 @@
+package renderers
+
+import (
+	"strings"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/output"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPlan(t *testing.T) {
+	root := &sqlanalysis.PlanNode{
+		Operation: "Hash Join", TotalCost: 1200, SelfCost: 50, EstimatedRows: 100,
+		Details: []string{"Hash Cond: (o.customer_id = c.id)"},
+		Children: []*sqlanalysis.PlanNode{
+			{Operation: "Seq Scan", Relation: "orders o", TotalCost: 1100, SelfCost: 1100, EstimatedRows: 50000, Details: []string{"Filter: (total > 10)"}},
+			{Operation: "Index Scan", Relation: "customers c", Index: "customers_pkey", TotalCost: 50, SelfCost: 50, EstimatedRows: 10},
+		},
+	}
+
+	plan := ansi.Strip(RenderPlan(root, 0))
+	assert.Equal(t, `Execution plan: estimated cost 1,200
+Hash Join  cost 1,200 · rows 100 est
+│  Hash Cond: (o.customer_id = c.id)
+├─ Seq Scan on orders o  cost 1,100 · rows 50,000 est · 92%
+│     Filter: (total > 10)
+└─ Index Scan on customers c using customers_pkey  cost 50 · rows 10 est`, plan)
+
+	// Lines are cut to the width
+	for _, line := range strings.Split(ansi.Strip(RenderPlan(root, 30)), "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 30)
+	}
+}
+
+func TestRenderPlan_Analyzed(t *testing.T) {
+	root := &sqlanalysis.PlanNode{
+		Operation: "Nested Loop", Analyzed: true, ActualTime: 40, ActualRows: 5000, EstimatedRows: 10, Loops: 1,
+		Children: []*sqlanalysis.PlanNode{
+			{Operation: "Seq Scan", Relation: "orders", Analyzed: true, ActualTime: 38, ActualRows: 5000, EstimatedRows: 4000, Loops: 1},
+		},
+	}
+
+	plan := ansi.Strip(RenderPlan(root, 0))
+	assert.Contains(t, plan, "Execution plan: 40.0 ms actual")
+	assert.Contains(t, plan, "Nested Loop  rows 5,000 actual / 10 est (500× off) · 40.0 ms")
+	assert.Contains(t, plan, "└─ Seq Scan on orders  rows 5,000 actual / 4,000 est · 38.0 ms · 95%")
+}
+
+func TestFormatQueryResult_Plan(t *testing.T) {
+	result := &models.QueryResult{Columns: []string{"QUERY PLAN"}, Rows: [][]interface{}{
+		{`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 35.5, "Plan Rows": 2550}}]`},
+	}}
+	assert.Contains(t, ansi.Strip(FormatQueryResult(result, output.FormatTable)), "Seq Scan on orders  cost 35.5 · rows 2,550 est")
+
+	// Other formats keep the raw result
+	assert.Contains(t, FormatQueryResult(result, output.FormatMarkdown), "QUERY PLAN")
+}
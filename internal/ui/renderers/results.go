@@ -0,0 +1,323 @@
+package renderers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dbsage/internal/ai/tools"
+	"dbsage/internal/models"
+	"dbsage/internal/output"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	maxResultTableRows = 50 // Rows shown before the table is cut off
+	maxResultCellWidth = 30 // Characters shown per cell before truncation
+)
+
+var (
+	resultFormatMu sync.RWMutex
+	resultFormat   = output.FormatTable
+)
+
+// SetResultFormat sets how query results in messages are rendered
+func SetResultFormat(format output.Format) {
+	resultFormatMu.Lock()
+	defer resultFormatMu.Unlock()
+	resultFormat = format
+}
+
+// ResultFormat returns how query results in messages are rendered
+func ResultFormat() output.Format {
+	resultFormatMu.RLock()
+	defer resultFormatMu.RUnlock()
+	return resultFormat
+}
+
+var (
+	fencedBlockPattern   = regexp.MustCompile("(?s)```(?:json)?[ \t]*\n(.*?)\n?```")
+	bareResultStartRegex = regexp.MustCompile(`\{\s*"(?:columns|statements)"\s*:`)
+)
+
+// RenderQueryResultsAsTables finds QueryResult or script result JSON in a
+// message, either in a fenced code block or inline, and replaces it with the
+// result rendered in the current result format. Anything that does not decode
+// as a result is left untouched.
+func RenderQueryResultsAsTables(content string) string {
+	if !strings.Contains(content, `"columns"`) && !strings.Contains(content, `"statements"`) {
+		return content
+	}
+
+	format := ResultFormat()
+	content = fencedBlockPattern.ReplaceAllStringFunc(content, func(block string) string {
+		body := fencedBlockPattern.FindStringSubmatch(block)[1]
+		if rendered, ok := renderResultJSON([]byte(strings.TrimSpace(body)), format); ok {
+			return rendered
+		}
+		return block
+	})
+
+	return replaceBareQueryResults(content, format)
+}
+
+// FormatQueryResult renders a QueryResult in the given format
+func FormatQueryResult(result *models.QueryResult, format output.Format) string {
+	switch format {
+	case output.FormatJSON:
+		var b strings.Builder
+		output.NewResultDocument("", "", result).WriteJSON(&b)
+		return "```json\n" + strings.TrimRight(b.String(), "\n") + "\n```"
+	case output.FormatMarkdown:
+		return formatTable(result, 0, 0)
+	default:
+		// EXPLAIN results are drawn as a plan tree rather than a table of JSON
+		if plan, ok := FormatPlanResult(result); ok {
+			return plan
+		}
+		return FormatQueryResultTable(result)
+	}
+}
+
+// replaceBareQueryResults replaces QueryResult JSON objects that appear outside code blocks
+func replaceBareQueryResults(content string, format output.Format) string {
+	var out strings.Builder
+	for {
+		loc := bareResultStartRegex.FindStringIndex(content)
+		if loc == nil {
+			out.WriteString(content)
+			return out.String()
+		}
+
+		start := loc[0]
+		decoder := json.NewDecoder(strings.NewReader(content[start:]))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			out.WriteString(content[:loc[1]])
+			content = content[loc[1]:]
+			continue
+		}
+		end := start + int(decoder.InputOffset())
+
+		out.WriteString(content[:start])
+		if rendered, ok := renderResultJSON(raw, format); ok {
+			out.WriteString(rendered)
+		} else {
+			out.WriteString(content[start:end])
+		}
+		content = content[end:]
+	}
+}
+
+// renderResultJSON renders JSON that decodes as a QueryResult or a script result
+func renderResultJSON(data []byte, format output.Format) (string, bool) {
+	if result, ok := decodeQueryResult(data); ok {
+		return FormatQueryResult(result, format), true
+	}
+	if script, ok := decodeScriptResult(data); ok {
+		return FormatScriptResult(script, format), true
+	}
+	return "", false
+}
+
+// decodeQueryResult decodes JSON into a QueryResult, requiring at least one column
+func decodeQueryResult(data []byte) (*models.QueryResult, bool) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result models.QueryResult
+	if err := decoder.Decode(&result); err != nil || len(result.Columns) == 0 {
+		return nil, false
+	}
+	return &result, true
+}
+
+// FormatQueryResultTable renders a QueryResult as an aligned markdown-style table
+func FormatQueryResultTable(result *models.QueryResult) string {
+	return formatTable(result, maxResultTableRows, maxResultCellWidth)
+}
+
+// FormatResultPage renders a page of results as an aligned table without the
+// row count footer, for the result pager
+func FormatResultPage(result *models.QueryResult) string {
+	return strings.TrimRight(formatRows(result.Columns, result.Rows, maxResultCellWidth), "\n")
+}
+
+// formatTable renders an aligned table, limited to maxRows rows and maxCellWidth
+// characters per cell (0 for no limit). Size and duration cells are shown in
+// readable units unless humanizing is off.
+func formatTable(result *models.QueryResult, maxRows, maxCellWidth int) string {
+	rowCount := len(result.Rows)
+	shown := result.Rows
+	if maxRows > 0 && len(shown) > maxRows {
+		shown = shown[:maxRows]
+	}
+
+	var b strings.Builder
+	b.WriteString(formatRows(result.Columns, shown, maxCellWidth))
+	if rowCount > len(shown) {
+		b.WriteString(fmt.Sprintf("... %d more rows\n", rowCount-len(shown)))
+	}
+	if result.Truncated && result.TotalRows > rowCount {
+		b.WriteString(fmt.Sprintf("(%d of %d rows, truncated: %s", rowCount, result.TotalRows, result.TruncationReason))
+	} else {
+		b.WriteString(fmt.Sprintf("(%d rows", rowCount))
+	}
+	if result.Duration != "" {
+		b.WriteString(", " + sqlanalysis.DurationText(result.Duration))
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+// formatRows renders rows as an aligned table with a header, one line per
+// row, cutting cells to maxCellWidth characters (0 for no limit)
+func formatRows(columns []string, shown [][]interface{}, maxCellWidth int) string {
+	cells := make([][]string, len(shown))
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = lipgloss.Width(truncateCell(col, maxCellWidth))
+	}
+	for r, row := range shown {
+		cells[r] = make([]string, len(columns))
+		for c := range columns {
+			value := "NULL"
+			if c < len(row) {
+				value = sqlanalysis.HumanizeCell(columns[c], FormatCell(row[c]))
+			}
+			cells[r][c] = truncateCell(value, maxCellWidth)
+			if w := lipgloss.Width(cells[r][c]); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(values []string) {
+		b.WriteString("|")
+		for i, v := range values {
+			b.WriteString(" " + v + strings.Repeat(" ", widths[i]-lipgloss.Width(v)) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	headers := make([]string, len(columns))
+	separators := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = truncateCell(col, maxCellWidth)
+		separators[i] = strings.Repeat("-", widths[i])
+	}
+	writeRow(headers)
+	writeRow(separators)
+	for _, row := range cells {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// FormatCell converts a decoded JSON value into display text
+func FormatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// truncateCell flattens newlines and shortens cell values longer than maxWidth (0 for no limit)
+func truncateCell(value string, maxWidth int) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	value = strings.ReplaceAll(value, "|", "\\|")
+	runes := []rune(value)
+	if maxWidth > 0 && len(runes) > maxWidth {
+		return string(runes[:maxWidth-3]) + "..."
+	}
+	return value
+}
+
+// decodeScriptResult decodes JSON into the result of a script, requiring at
+// least one statement
+func decodeScriptResult(data []byte) (*tools.ScriptResult, bool) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+
+	var script tools.ScriptResult
+	if err := json.Unmarshal(data, &script); err != nil || len(script.Statements) == 0 {
+		return nil, false
+	}
+	for _, stmt := range script.Statements {
+		if stmt.SQL == "" {
+			return nil, false
+		}
+	}
+	return &script, true
+}
+
+// FormatScriptResult renders the statements of a script in order, each headed
+// by its position, first line and duration, followed by its rows, the rows it
+// affected or its error, and a summary of the script
+func FormatScriptResult(script *tools.ScriptResult, format output.Format) string {
+	total := len(script.Statements) + script.Skipped
+	var b strings.Builder
+	for i, stmt := range script.Statements {
+		header := fmt.Sprintf("-- [%d/%d] %s", i+1, total, firstLine(stmt.SQL))
+		if stmt.Duration != "" {
+			header += " · " + sqlanalysis.DurationText(stmt.Duration)
+		}
+		b.WriteString(header + "\n")
+
+		result, ok := decodeQueryResult(stmt.Result)
+		switch {
+		case stmt.Error != "":
+			b.WriteString("✗ " + stmt.Error + "\n")
+		case ok:
+			b.WriteString(FormatQueryResult(result, format) + "\n")
+		default:
+			var affected models.QueryResult
+			_ = json.Unmarshal(stmt.Result, &affected)
+			b.WriteString(fmt.Sprintf("✓ %d rows affected\n", affected.RowCount))
+		}
+		b.WriteString("\n")
+	}
+
+	summary := fmt.Sprintf("(%d statements: %d succeeded, %d failed", total, script.Succeeded, script.Failed)
+	if script.Skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", script.Skipped)
+	}
+	if script.Duration != "" {
+		summary += ", " + sqlanalysis.DurationText(script.Duration)
+	}
+	return b.String() + summary + ")"
+}
+
+// firstLine returns the first line of a statement, marking when more follows
+func firstLine(stmt string) string {
+	if i := strings.IndexByte(stmt, '\n'); i >= 0 {
+		return strings.TrimSpace(stmt[:i]) + " ..."
+	}
+	return stmt
+}
@@ -0,0 +1,144 @@
+package renderers
+
+import (
+	"strings"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/internal/output"
+	"dbsage/internal/sqlanalysis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderQueryResultsAsTables_FencedBlock(t *testing.T) {
+	content := "Here are the users:\n```json\n" +
+		`{"columns":["id","name","score"],"rows":[[1,"alice",9.5],[2,null,1000000]],"row_count":2,"duration":"3ms"}` +
+		"\n```\nDone."
+
+	rendered := RenderQueryResultsAsTables(content)
+
+	assert.NotContains(t, rendered, `"columns"`)
+	assert.Contains(t, rendered, "Here are the users:")
+	assert.Contains(t, rendered, "| id | name  | score   |")
+	assert.Contains(t, rendered, "| 2  | NULL  | 1000000 |")
+	assert.Contains(t, rendered, "(2 rows, 3.0 ms)")
+	assert.True(t, strings.HasSuffix(rendered, "Done."))
+}
+
+func TestRenderQueryResultsAsTables_BareJSON(t *testing.T) {
+	content := `Result: {"columns":["count"],"rows":[[42]],"row_count":1} as expected`
+
+	rendered := RenderQueryResultsAsTables(content)
+
+	assert.Contains(t, rendered, "| count |")
+	assert.Contains(t, rendered, "| 42    |")
+	assert.True(t, strings.HasSuffix(rendered, " as expected"))
+}
+
+func TestRenderQueryResultsAsTables_Fallback(t *testing.T) {
+	tests := []string{
+		"plain text without results",
+		"```json\n{\"columns\": \"not a list\"}\n```",
+		"```json\n{\"name\": \"value\"}\n```",
+		`broken {"columns": [ json`,
+	}
+
+	for _, content := range tests {
+		assert.Equal(t, content, RenderQueryResultsAsTables(content))
+	}
+}
+
+func TestFormatQueryResultTable_Truncation(t *testing.T) {
+	result := &models.QueryResult{Columns: []string{"text"}}
+	for i := 0; i < maxResultTableRows+5; i++ {
+		result.Rows = append(result.Rows, []interface{}{strings.Repeat("x", 100)})
+	}
+
+	table := FormatQueryResultTable(result)
+
+	assert.Contains(t, table, "... 5 more rows")
+	assert.Contains(t, table, strings.Repeat("x", maxResultCellWidth-3)+"...")
+	assert.NotContains(t, table, strings.Repeat("x", maxResultCellWidth))
+}
+
+func TestFormatQueryResultTable_TruncationNotice(t *testing.T) {
+	result := &models.QueryResult{
+		Columns:          []string{"id"},
+		Rows:             [][]interface{}{{1}, {2}},
+		RowCount:         2,
+		Truncated:        true,
+		TotalRows:        900,
+		TruncationReason: "row limit of 200 rows",
+	}
+
+	assert.Contains(t, FormatQueryResultTable(result), "(2 of 900 rows, truncated: row limit of 200 rows)")
+}
+
+func TestFormatQueryResult_Formats(t *testing.T) {
+	long := strings.Repeat("x", 50)
+	result := &models.QueryResult{
+		Columns:  []string{"id", "note"},
+		Rows:     [][]interface{}{{1, long}, {2, "a|b"}},
+		RowCount: 2,
+	}
+
+	table := FormatQueryResult(result, output.FormatTable)
+	assert.NotContains(t, table, long, "table format truncates long cells")
+
+	markdown := FormatQueryResult(result, output.FormatMarkdown)
+	assert.Contains(t, markdown, long, "markdown format keeps full cell values")
+	assert.Contains(t, markdown, `a\|b`)
+
+	jsonOut := FormatQueryResult(result, output.FormatJSON)
+	assert.True(t, strings.HasPrefix(jsonOut, "```json\n"))
+	assert.Contains(t, jsonOut, `"schema_version": 1`)
+	assert.Contains(t, jsonOut, `"kind": "query_result"`)
+}
+
+func TestRenderQueryResultsAsTables_UsesResultFormat(t *testing.T) {
+	SetResultFormat(output.FormatJSON)
+	defer SetResultFormat(output.FormatTable)
+
+	rendered := RenderQueryResultsAsTables(`{"columns":["n"],"rows":[[1]],"row_count":1}`)
+	assert.Contains(t, rendered, `"kind": "query_result"`)
+}
+
+func TestFormatQueryResultTable_Humanize(t *testing.T) {
+	result := &models.QueryResult{
+		Columns:  []string{"relname", "total_size", "mean_exec_time"},
+		Rows:     [][]interface{}{{"orders", int64(3 * 1024 * 1024), 2310.0}},
+		RowCount: 1,
+		Duration: "12.345678ms",
+	}
+
+	table := FormatQueryResultTable(result)
+	assert.Contains(t, table, "3.0 MB")
+	assert.Contains(t, table, "2.31 s")
+	assert.Contains(t, table, "12.3 ms")
+
+	sqlanalysis.SetHumanize(false)
+	defer sqlanalysis.SetHumanize(true)
+	table = FormatQueryResultTable(result)
+	assert.Contains(t, table, "3145728")
+	assert.Contains(t, table, "12.345678ms")
+}
+
+func TestRenderQueryResultsAsTables_Script(t *testing.T) {
+	content := "The migration ran:\n```json\n" +
+		`{"statements":[` +
+		`{"sql":"UPDATE users SET active = 1","result":{"columns":null,"rows":null,"row_count":3,"duration":"2ms"},"duration":"2ms"},` +
+		`{"sql":"SELECT id\nFROM users","result":{"columns":["id"],"rows":[[1]],"row_count":1,"duration":"1ms"},"duration":"1ms"},` +
+		`{"sql":"DROP TABLE nope","duration":"1ms","error":"no such table: nope"}],` +
+		`"succeeded":2,"failed":1,"skipped":2,"duration":"5ms"}` +
+		"\n```"
+
+	rendered := RenderQueryResultsAsTables(content)
+
+	assert.NotContains(t, rendered, `"statements"`)
+	assert.Contains(t, rendered, "-- [1/5] UPDATE users SET active = 1 · 2.0 ms\n✓ 3 rows affected")
+	assert.Contains(t, rendered, "-- [2/5] SELECT id ...")
+	assert.Contains(t, rendered, "| id |")
+	assert.Contains(t, rendered, "-- [3/5] DROP TABLE nope · 1.0 ms\n✗ no such table: nope")
+	assert.Contains(t, rendered, "(5 statements: 2 succeeded, 1 failed, 2 skipped, 5.0 ms)")
+}
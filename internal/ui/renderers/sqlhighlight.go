@@ -0,0 +1,89 @@
+package renderers
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Chroma styles used for SQL on dark and light terminal backgrounds
+const (
+	darkSQLStyle  = "monokai"
+	lightSQLStyle = "github"
+)
+
+var (
+	sqlBlockPattern = regexp.MustCompile("(?s)```(?i:sql|postgresql|mysql|sqlite)[ \t]*\n(.*?)\n?```")
+
+	sqlStyleMu sync.RWMutex
+	sqlStyle   = styles.Get(darkSQLStyle)
+)
+
+// SetDarkBackground picks the SQL style for the terminal background. The
+// background is detected before the program starts: querying the terminal
+// while it runs would race with reading its input.
+func SetDarkBackground(dark bool) {
+	name := lightSQLStyle
+	if dark {
+		name = darkSQLStyle
+	}
+	sqlStyleMu.Lock()
+	defer sqlStyleMu.Unlock()
+	sqlStyle = styles.Get(name)
+}
+
+// HighlightSQL returns the statement colored with SQL syntax highlighting.
+// The style follows the terminal background given to SetDarkBackground, and
+// the statement is returned unchanged when the terminal has no color support
+// or highlighting fails.
+func HighlightSQL(sql string) string {
+	if sql == "" || lipgloss.ColorProfile() == termenv.Ascii {
+		return sql
+	}
+
+	lexer := lexers.Get("sql")
+	if lexer == nil {
+		return sql
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, sql)
+	if err != nil {
+		return sql
+	}
+
+	formatter := formatters.Get("terminal256")
+	if lipgloss.ColorProfile() == termenv.TrueColor {
+		formatter = formatters.Get("terminal16m")
+	}
+
+	var b strings.Builder
+	if err := formatter.Format(&b, currentSQLStyle(), iterator); err != nil {
+		return sql
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// HighlightSQLBlocks replaces fenced SQL code blocks in a message with their
+// highlighted statements. Other content is left untouched.
+func HighlightSQLBlocks(content string) string {
+	if !strings.Contains(content, "```") {
+		return content
+	}
+	return sqlBlockPattern.ReplaceAllStringFunc(content, func(block string) string {
+		body := sqlBlockPattern.FindStringSubmatch(block)[1]
+		return HighlightSQL(body)
+	})
+}
+
+// currentSQLStyle returns the chroma style set for the terminal background
+func currentSQLStyle() *chroma.Style {
+	sqlStyleMu.RLock()
+	defer sqlStyleMu.RUnlock()
+	return sqlStyle
+}
@@ -0,0 +1,59 @@
+package renderers
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func withColorProfile(t *testing.T, profile termenv.Profile) {
+	previous := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(profile)
+	t.Cleanup(func() { lipgloss.SetColorProfile(previous) })
+}
+
+func TestHighlightSQL(t *testing.T) {
+	withColorProfile(t, termenv.ANSI256)
+	sql := "SELECT id, name FROM users WHERE active = true"
+
+	highlighted := HighlightSQL(sql)
+
+	assert.NotEqual(t, sql, highlighted)
+	assert.Contains(t, highlighted, "\x1b[")
+	assert.Equal(t, sql, ansi.Strip(highlighted))
+}
+
+func TestHighlightSQL_Background(t *testing.T) {
+	withColorProfile(t, termenv.ANSI256)
+	t.Cleanup(func() { SetDarkBackground(true) })
+	sql := "SELECT id FROM users"
+
+	SetDarkBackground(true)
+	dark := HighlightSQL(sql)
+	SetDarkBackground(false)
+	light := HighlightSQL(sql)
+
+	assert.NotEqual(t, dark, light)
+	assert.Equal(t, sql, ansi.Strip(light))
+}
+
+func TestHighlightSQL_NoColor(t *testing.T) {
+	withColorProfile(t, termenv.Ascii)
+	sql := "SELECT 1"
+
+	assert.Equal(t, sql, HighlightSQL(sql))
+}
+
+func TestHighlightSQLBlocks(t *testing.T) {
+	withColorProfile(t, termenv.ANSI256)
+	content := "Run this:\n```sql\nSELECT * FROM orders\n```\nand this:\n```go\nfmt.Println(1)\n```"
+
+	rendered := HighlightSQLBlocks(content)
+
+	assert.NotContains(t, rendered, "```sql")
+	assert.Contains(t, ansi.Strip(rendered), "Run this:\nSELECT * FROM orders\nand this:")
+	assert.Contains(t, rendered, "```go\nfmt.Println(1)\n```")
+}
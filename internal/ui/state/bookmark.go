@@ -0,0 +1,28 @@
+package state
+
+import (
+	"fmt"
+
+	"dbsage/internal/bookmarks"
+)
+
+// addBookmark saves the last answer of the transcript with its question, SQL
+// and connection
+func (sm *StateManager) addBookmark(note string) string {
+	if len(sm.transcript) == 0 || sm.transcript[len(sm.transcript)-1].Answer == "" {
+		return "Nothing to bookmark yet: ask a question first."
+	}
+	turn := sm.transcript[len(sm.transcript)-1]
+
+	b := bookmarks.Bookmark{Note: note, Question: turn.Question, SQL: turn.SQL, Answer: turn.Answer}
+	if sm.cmdHandler != nil {
+		if config := sm.cmdHandler.CurrentConnection(); config != nil {
+			b.Connection = config.Name
+		}
+	}
+	b, err := bookmarks.Add(b)
+	if err != nil {
+		return fmt.Sprintf("Failed to save bookmark: %v", err)
+	}
+	return fmt.Sprintf("Bookmarked the last answer as %d: %s", b.ID, note)
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"fmt"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// openConnectionEdit prepares the edit form of a connection for the model
+func (sm *StateManager) openConnectionEdit(name string) string {
+	config, err := sm.cmdHandler.EditableConnection(name)
+	if err != nil {
+		return err.Error()
+	}
+	sm.connectionEdit = config
+	return fmt.Sprintf("Editing connection '%s'.", name)
+}
+
+// TakeConnectionEdit returns the settings of the connection /edit opened and clears them
+func (sm *StateManager) TakeConnectionEdit() *dbinterfaces.ConnectionConfig {
+	config := sm.connectionEdit
+	sm.connectionEdit = nil
+	return config
+}
+
+// SaveConnectionEdit tests and saves the settings from the edit form. On
+// failure nothing is saved and the error explains why.
+func (sm *StateManager) SaveConnectionEdit(config *dbinterfaces.ConnectionConfig) error {
+	message, err := sm.cmdHandler.SaveConnectionEdit(config)
+	if err != nil {
+		return err
+	}
+	sm.refreshDatabaseTools()
+	sm.SetResponse(message)
+	sm.SetError(nil)
+	sm.SetState(models.StateResponse)
+	return nil
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/ai"
+)
+
+// contextReport describes what the last request sent to the model: the size
+// of the conversation and which tables of the schema summary it carried, and why
+func (sm *StateManager) contextReport() string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so nothing is sent to a model."
+	}
+	schema, sent := sm.aiClient.LastSchemaContext()
+	if !sent {
+		return "Nothing has been sent to the model yet. Ask a question, then run /context show."
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Conversation: %d messages, about %d tokens with the system prompt and tools\n\n", len(sm.history), ai.EstimateContextTokens(sm.history)))
+	switch {
+	case schema == nil:
+		b.WriteString("No schema summary was sent: it is off (DBSAGE_SCHEMA_BUDGET=0), the schema is kept from the model, or it could not be read.")
+		return b.String()
+	case schema.Complete:
+		b.WriteString(fmt.Sprintf("Schema: all %d tables with their columns (%d of %d tokens)", len(schema.Tables), schema.Tokens, schema.Budget))
+		for _, t := range schema.Tables {
+			b.WriteString("\n  " + t.Name)
+		}
+		return b.String()
+	}
+
+	total := len(schema.Tables) + len(schema.Listed) + schema.Omitted
+	b.WriteString(fmt.Sprintf("Schema: the %d tables do not fit in %d tokens, so the most relevant were chosen (%d tokens sent)\n", total, schema.Budget, schema.Tokens))
+	if len(schema.Tables) > 0 {
+		b.WriteString("\nWith columns:")
+		for _, t := range schema.Tables {
+			b.WriteString(fmt.Sprintf("\n  %s (%d columns): %s", t.Name, len(t.Columns), t.Reason))
+		}
+		b.WriteString("\n")
+	}
+	if len(schema.Listed) > 0 {
+		b.WriteString(fmt.Sprintf("\nBy name only (%d): %s\n", len(schema.Listed), strings.Join(schema.Listed, ", ")))
+	}
+	if schema.Omitted > 0 {
+		b.WriteString(fmt.Sprintf("\nNot sent: %d tables; the AI can list them with get_all_tables\n", schema.Omitted))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
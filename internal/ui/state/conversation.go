@@ -0,0 +1,82 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"dbsage/internal/conversations"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// recordConversation saves a message to the current conversation, starting
+// one at the first question. Saving is best effort: a failure never
+// interrupts the chat.
+func (sm *StateManager) recordConversation(role, content string) {
+	if !conversations.Enabled() {
+		return
+	}
+	now := time.Now()
+	if sm.conversation == nil {
+		if role != openai.ChatMessageRoleUser {
+			return
+		}
+		sm.conversation = conversations.New(sm.currentConnectionName(), now)
+		sm.conversationStart = len(sm.transcript) - 1
+	}
+
+	sm.conversation.Add(role, content, now)
+	if sm.conversationStart >= 0 && sm.conversationStart < len(sm.transcript) {
+		sm.conversation.Turns = sm.transcript[sm.conversationStart:]
+	}
+	_ = conversations.Save(sm.conversation)
+}
+
+// startConversation ends the current conversation, which stays saved, so the
+// next question starts a new one
+func (sm *StateManager) startConversation() string {
+	previous := sm.conversation
+	sm.conversation = nil
+	sm.ClearHistory()
+	if previous == nil {
+		return "Started a new conversation."
+	}
+	return fmt.Sprintf("Started a new conversation. The previous one is saved as %s; continue it with /resume %s", previous.ID, previous.ID)
+}
+
+// resumeConversation restores a saved conversation into the AI history, so
+// the next question continues it with its full context
+func (sm *StateManager) resumeConversation(id string) string {
+	c, err := conversations.Load(id)
+	if err != nil {
+		return fmt.Sprintf("Failed to resume: %v", err)
+	}
+
+	history := make([]openai.ChatCompletionMessage, 0, len(c.Messages))
+	for _, m := range c.Messages {
+		history = append(history, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	sm.history = history
+	sm.contextTokens = 0
+	sm.conversation = c
+	sm.conversationStart = len(sm.transcript)
+	sm.transcript = append(sm.transcript, c.Turns...)
+
+	response := fmt.Sprintf("Resumed \"%s\" (%s): %d messages restored into the AI context. Ask a question to continue.",
+		c.Title, c.ID, len(c.Messages))
+	if current := sm.currentConnectionName(); c.Connection != "" && c.Connection != current {
+		response += fmt.Sprintf("\nIt was held on connection '%s'; use /switch %s to continue there.", c.Connection, c.Connection)
+	}
+	return response
+}
+
+// currentConnectionName returns the name of the current connection, if any
+func (sm *StateManager) currentConnectionName() string {
+	if sm.cmdHandler == nil {
+		return ""
+	}
+	if config := sm.cmdHandler.CurrentConnection(); config != nil {
+		return config.Name
+	}
+	return ""
+}
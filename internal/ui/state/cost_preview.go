@@ -0,0 +1,121 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/ai"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Messages longer than this are shortened by CompactHistory
+const (
+	compactMessageChars = 2000
+	compactKeepHead     = 800
+	compactKeepTail     = 400
+)
+
+// HoldForCostPreview checks the size of the context that sending prompt would
+// produce. Above the preview threshold the prompt is held until /send and a
+// preview with the estimated tokens and cost is returned.
+func (sm *StateManager) HoldForCostPreview(prompt string) (string, bool) {
+	if sm.sendPending {
+		sm.sendPending = false
+		sm.pendingPrompt = ""
+		return "", false
+	}
+
+	threshold := ai.TokenPreviewThreshold()
+	if threshold == 0 {
+		return "", false
+	}
+
+	tokens := sm.estimateTurnTokens(prompt)
+	if tokens < threshold {
+		return "", false
+	}
+
+	sm.pendingPrompt = prompt
+	return sm.costPreviewMessage(tokens), true
+}
+
+// HasPendingPrompt reports whether a prompt is waiting for /send after a cost preview
+func (sm *StateManager) HasPendingPrompt() bool {
+	return sm.pendingPrompt != ""
+}
+
+// TrimHistory keeps only the last n messages of the conversation
+func (sm *StateManager) TrimHistory(n int) int {
+	removed := 0
+	if n >= 0 && len(sm.history) > n {
+		removed = len(sm.history) - n
+		sm.history = append([]openai.ChatCompletionMessage{}, sm.history[removed:]...)
+		sm.contextTokens = 0
+	}
+	return removed
+}
+
+// CompactHistory shortens long messages, keeping their beginning and end,
+// and returns the number of characters removed
+func (sm *StateManager) CompactHistory() int {
+	removed := 0
+	for i, msg := range sm.history {
+		runes := []rune(msg.Content)
+		if len(runes) <= compactMessageChars {
+			continue
+		}
+		cut := len(runes) - compactKeepHead - compactKeepTail
+		sm.history[i].Content = string(runes[:compactKeepHead]) +
+			fmt.Sprintf("\n…[%d characters trimmed]…\n", cut) +
+			string(runes[len(runes)-compactKeepTail:])
+		removed += cut
+	}
+	if removed > 0 {
+		sm.contextTokens = 0
+	}
+	return removed
+}
+
+// pendingPreview returns the cost preview for the held prompt after the history changed
+func (sm *StateManager) pendingPreview() string {
+	if sm.pendingPrompt == "" {
+		return ""
+	}
+	return sm.costPreviewMessage(sm.estimateTurnTokens(sm.pendingPrompt))
+}
+
+func (sm *StateManager) estimateTurnTokens(prompt string) int {
+	messages := append(append([]openai.ChatCompletionMessage{}, sm.history...), openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: prompt,
+	})
+	return ai.EstimateContextTokens(messages)
+}
+
+func (sm *StateManager) costPreviewMessage(tokens int) string {
+	model := ai.DefaultModel
+	if sm.aiClient != nil {
+		model = sm.aiClient.Model()
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("⚠ Large context: this turn will send ~%s tokens", formatTokens(tokens)))
+	if cost, ok := ai.EstimateInputCost(model, tokens); ok {
+		b.WriteString(fmt.Sprintf(" (~$%.4f input on %s, plus tool calls and output)", cost, model))
+	}
+	b.WriteString(".\n\n")
+	b.WriteString("- /send: send it anyway\n")
+	b.WriteString("- /trim [n]: keep only the last n messages (default 4), then review again\n")
+	b.WriteString("- /compact: shorten long messages in the history, then review again\n")
+	b.WriteString("Anything else discards the held message.")
+	return b.String()
+}
+
+// formatTokens shortens large token counts, e.g. 14200 -> 14.2k
+func formatTokens(tokens int) string {
+	if tokens >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	}
+	return fmt.Sprintf("%d", tokens)
+}
@@ -0,0 +1,43 @@
+package state
+
+import (
+	"fmt"
+
+	"dbsage/internal/ui/handlers"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// openDataGrid reads the rows of a query for the data grid, or of the last
+// query the AI ran when query is empty
+func (sm *StateManager) openDataGrid(query string) string {
+	if query == "" {
+		if sm.aiClient == nil {
+			return "Usage: /grid <table | SELECT ...>"
+		}
+		sql, connection := sm.aiClient.LastResultQuery()
+		if sql == "" {
+			return "Nothing to edit yet: ask a question that runs a query first, or use /grid <table | SELECT ...>"
+		}
+		if current := dbinterfaces.ConnectionName(sm.connService.GetCurrentTools()); connection != "" && current != connection {
+			return fmt.Sprintf("The last query ran on '%s'; switch back to it to edit its rows", connection)
+		}
+		query = sql
+	}
+
+	source, err := sm.cmdHandler.GridSource(query)
+	if err != nil {
+		return fmt.Sprintf("Cannot open the data grid: %v", err)
+	}
+	sm.dataGrid = source
+	if source.ReadOnly != nil {
+		return fmt.Sprintf("Showing the rows read-only: %v", source.ReadOnly)
+	}
+	return "Editing the rows: move to a cell and press enter to change it."
+}
+
+// TakeDataGrid returns the rows /grid opened and clears them
+func (sm *StateManager) TakeDataGrid() *handlers.GridSource {
+	source := sm.dataGrid
+	sm.dataGrid = nil
+	return source
+}
@@ -0,0 +1,27 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"dbsage/internal/export"
+)
+
+// exportResults saves the last query result to a file for /export
+func (sm *StateManager) exportResults(path string) string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there is no query result to export."
+	}
+	result := sm.aiClient.LastResult()
+	if result == nil {
+		return "Nothing to export yet: ask a question that runs a query first."
+	}
+
+	query, connection := sm.aiClient.LastResultQuery()
+	format, _ := export.FormatForPath(path)
+	summary, err := export.Write(path, format, result, export.NewMetadata(connection, query, time.Now()))
+	if err != nil {
+		return fmt.Sprintf("Failed to export results: %v", err)
+	}
+	return summary.String()
+}
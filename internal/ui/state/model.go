@@ -0,0 +1,24 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/ai/providers"
+)
+
+// applyModel handles the SHOW_MODEL and MODEL:<spec> responses of /model
+func (sm *StateManager) applyModel(response string) string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there is no model to switch. Set DBSAGE_PROVIDER and its API key, then restart DBSage."
+	}
+
+	if strings.HasPrefix(response, "MODEL:") {
+		if err := sm.aiClient.SetModel(strings.TrimPrefix(response, "MODEL:")); err != nil {
+			return fmt.Sprintf("Model not switched: %v", err)
+		}
+		return fmt.Sprintf("Switched to %s on %s.", sm.aiClient.Model(), sm.aiClient.Provider())
+	}
+	return fmt.Sprintf("Model: %s on %s\nProviders: %s\nUse /model <model>, /model <provider> or /model <provider>:<model> to switch.",
+		sm.aiClient.Model(), sm.aiClient.Provider(), strings.Join(providers.Names(), ", "))
+}
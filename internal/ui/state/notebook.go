@@ -0,0 +1,55 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"dbsage/internal/notebook"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// addToTranscript records a conversation message for /export. A question
+// starts a turn; the answer closes it together with the SQL executed meanwhile.
+// Unlike the conversation history, the transcript is not trimmed or cleared.
+func (sm *StateManager) addToTranscript(role, content string) {
+	switch role {
+	case openai.ChatMessageRoleUser:
+		sm.transcript = append(sm.transcript, notebook.Turn{Question: content})
+	case openai.ChatMessageRoleAssistant:
+		if len(sm.transcript) == 0 {
+			sm.transcript = append(sm.transcript, notebook.Turn{})
+		}
+		turn := &sm.transcript[len(sm.transcript)-1]
+		if sm.aiClient != nil {
+			executed := sm.aiClient.TakeExecutedSQL()
+			turn.SQL = append(turn.SQL, executed...)
+			if query, _ := sm.aiClient.LastResultQuery(); query != "" && slices.Contains(executed, query) {
+				turn.Result = sm.aiClient.LastResult()
+			}
+		}
+		turn.Answer = strings.TrimSpace(turn.Answer + "\n\n" + content)
+	}
+}
+
+// exportNotebook writes the session transcript as a notebook
+func (sm *StateManager) exportNotebook(path string) string {
+	if len(sm.transcript) == 0 {
+		return "Nothing to export yet: ask a question first."
+	}
+
+	var connectionURL string
+	if sm.cmdHandler != nil {
+		connectionURL = notebook.ConnectionURL(sm.cmdHandler.CurrentConnection())
+	}
+	data, sqlCells, err := notebook.Export(path, sm.transcript, connectionURL)
+	if err != nil {
+		return fmt.Sprintf("Failed to export notebook: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Sprintf("Failed to write notebook: %v", err)
+	}
+	return fmt.Sprintf("Exported %d questions and %d SQL cells to %s", len(sm.transcript), sqlCells, path)
+}
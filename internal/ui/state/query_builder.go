@@ -0,0 +1,24 @@
+package state
+
+import (
+	"fmt"
+
+	"dbsage/internal/ui/handlers"
+)
+
+// openQueryBuilder prepares the query builder for the model
+func (sm *StateManager) openQueryBuilder(table string) string {
+	schema, err := sm.cmdHandler.QueryBuilderSchema(table)
+	if err != nil {
+		return fmt.Sprintf("Cannot open the query builder: %v", err)
+	}
+	sm.queryBuilder = schema
+	return "Build a query: pick a table, columns, filters, ordering and limit."
+}
+
+// TakeQueryBuilder returns the schema of the query builder /build opened and clears it
+func (sm *StateManager) TakeQueryBuilder() *handlers.BuilderSchema {
+	schema := sm.queryBuilder
+	sm.queryBuilder = nil
+	return schema
+}
@@ -0,0 +1,24 @@
+package state
+
+import (
+	"fmt"
+
+	"dbsage/internal/ui/handlers"
+)
+
+// openResultPager prepares the result pager for the model
+func (sm *StateManager) openResultPager(query string) string {
+	source, err := sm.cmdHandler.PagerSource(query)
+	if err != nil {
+		return fmt.Sprintf("Cannot browse the results: %v", err)
+	}
+	sm.resultPager = source
+	return "Browsing the results a page at a time."
+}
+
+// TakeResultPager returns the source of the result pager /browse opened and clears it
+func (sm *StateManager) TakeResultPager() *handlers.PagerSource {
+	source := sm.resultPager
+	sm.resultPager = nil
+	return source
+}
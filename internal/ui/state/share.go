@@ -0,0 +1,45 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"dbsage/internal/masking"
+	"dbsage/internal/share"
+)
+
+// shareTranscript writes the session transcript for /share, with secrets
+// redacted and result values masked by the current connection's rules
+func (sm *StateManager) shareTranscript(path string) string {
+	if len(sm.transcript) == 0 {
+		return "Nothing to share yet: ask a question first."
+	}
+
+	var connection, databaseType string
+	secrets := []string{os.Getenv("OPENAI_API_KEY"), os.Getenv("ANTHROPIC_API_KEY"), os.Getenv("DBSAGE_MASTER_PASSPHRASE")}
+	if sm.cmdHandler != nil {
+		if config := sm.cmdHandler.CurrentConnection(); config != nil {
+			connection, databaseType = config.Name, config.Type
+			secrets = append(secrets, config.Password)
+		}
+	}
+	settings, err := masking.Load(connection)
+	if err != nil {
+		return fmt.Sprintf("Not shared: the masking settings could not be read: %v", err)
+	}
+
+	t := share.New(sm.transcript, connection, databaseType, settings, secrets, time.Now())
+	if err := share.Write(path, t); err != nil {
+		return fmt.Sprintf("Failed to share the transcript: %v", err)
+	}
+	statements, results := 0, 0
+	for _, turn := range t.Turns {
+		statements += len(turn.SQL)
+		if turn.Result != nil {
+			results++
+		}
+	}
+	return fmt.Sprintf("Shared %d questions, %d SQL statements and %d results to %s, with %d secrets redacted and %d values masked.\nReview the file before sending it; others open it read-only with /open %s",
+		len(t.Turns), statements, results, path, t.Redactions, t.Masked, path)
+}
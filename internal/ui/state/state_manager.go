@@ -1,10 +1,14 @@
 package state
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"dbsage/internal/ai"
+	"dbsage/internal/conversations"
 	"dbsage/internal/models"
+	"dbsage/internal/notebook"
 	"dbsage/internal/ui/handlers"
 	"dbsage/pkg/dbinterfaces"
 
@@ -16,8 +20,10 @@ type StateManager struct {
 	aiClient           *ai.Client
 	dbTools            dbinterfaces.DatabaseInterface
 	connMgr            dbinterfaces.ConnectionManagerInterface
+	connService        dbinterfaces.ConnectionServiceInterface
 	cmdHandler         *handlers.CommandHandler
 	history            []openai.ChatCompletionMessage
+	contextTokens      int // Estimate for history shown in the status bar, 0 until computed
 	currentState       models.AppState
 	response           string
 	error              error
@@ -33,9 +39,32 @@ type StateManager struct {
 	pendingAIContext        *models.PendingAIContext // Store AI context for resuming after confirmation
 	// Guidance fields
 	currentGuidance *models.GuidanceInfo
+	tour            *models.GuidanceInfo // Shown instead of the other guidance until dismissed, set by dbsage demo
 	hasApiKey       bool
 	// Version update fields
 	versionUpdate *models.VersionUpdateInfo
+	// Cost preview fields
+	pendingPrompt string // Prompt held back until /send because of its context size
+	sendPending   bool   // Set by /send so the held prompt skips the preview
+	// Turn timing
+	showTiming bool // Show the last turn's timing summary in the status bar
+	// Text placed in the input box after a command, set by /jump
+	inputFill string
+	// Questions, executed SQL and answers of the session, for /export
+	transcript []notebook.Turn
+	// Conversation saved for /resume, and the transcript turn it started at
+	conversation      *conversations.Conversation
+	conversationStart int
+	// Settings of the connection /edit opened, until the model shows the form
+	connectionEdit *dbinterfaces.ConnectionConfig
+	// Schema for the query builder /build opened, until the model shows it
+	queryBuilder *handlers.BuilderSchema
+	// Query /browse pages through, until the model shows the pager
+	resultPager *handlers.PagerSource
+	// Rows /grid opened, until the model shows the data grid
+	dataGrid *handlers.GridSource
+	// Capture or replay started by /capture or /replay, until the model runs it
+	workloadJob *handlers.WorkloadJob
 }
 
 // NewStateManager creates a new state manager
@@ -46,13 +75,18 @@ func NewStateManager(aiClient *ai.Client, dbTools dbinterfaces.DatabaseInterface
 	}
 
 	cmdHandler := handlers.NewCommandHandler(connService)
+	if connService != nil {
+		cmdHandler.IndexSchema()
+	}
 
 	hasApiKey := aiClient != nil
+	cmdHandler.SetAIEnabled(hasApiKey)
 
 	sm := &StateManager{
 		aiClient:               aiClient,
 		dbTools:                dbTools,
 		connMgr:                connMgr,
+		connService:            connService,
 		cmdHandler:             cmdHandler,
 		currentState:           models.StateInput,
 		history:                make([]openai.ChatCompletionMessage, 0),
@@ -183,28 +217,51 @@ func (sm *StateManager) GetConnectionManager() dbinterfaces.ConnectionManagerInt
 	return sm.connMgr
 }
 
+// SetTerminalSize records the terminal size for features that need it, such as session recording
+func (sm *StateManager) SetTerminalSize(width, height int) {
+	if sm.cmdHandler != nil {
+		sm.cmdHandler.SetTerminalSize(width, height)
+	}
+}
+
 // History management
 func (sm *StateManager) GetHistory() []openai.ChatCompletionMessage {
 	return sm.history
 }
 
 func (sm *StateManager) AddToHistory(role string, content string) {
+	if role == openai.ChatMessageRoleAssistant && sm.cmdHandler != nil {
+		sm.cmdHandler.RecordOutput(content)
+	}
 	sm.history = append(sm.history, openai.ChatCompletionMessage{
 		Role:    role,
 		Content: content,
 	})
+	sm.contextTokens = 0
+	sm.addToTranscript(role, content)
+	sm.recordConversation(role, content)
 }
 
 func (sm *StateManager) ClearHistory() {
 	sm.history = make([]openai.ChatCompletionMessage, 0)
+	sm.contextTokens = 0
 }
 
-// Command processing
+// ProcessInput processes user input through the command handler. It returns
+// false when the application should exit, and optionally a prompt that should
+// be sent to the AI in place of the raw input.
 func (sm *StateManager) ProcessInput(input string) (bool, string) {
 	if sm.cmdHandler == nil {
 		return false, ""
 	}
 
+	sm.cmdHandler.RecordInput(input)
+
+	// Any input other than the preview commands discards a held prompt
+	if sm.pendingPrompt != "" && !isCostPreviewCommand(input) {
+		sm.pendingPrompt = ""
+	}
+
 	handled, response, err := sm.cmdHandler.ProcessCommand(input)
 	if err != nil {
 		sm.SetError(err)
@@ -214,37 +271,158 @@ func (sm *StateManager) ProcessInput(input string) (bool, string) {
 
 	if handled {
 		if response == "CLEAR_SCREEN" {
-			sm.ClearHistory()
+			sm.startConversation()
 			sm.SetResponse("")
 			sm.SetError(nil)
 			return true, ""
 		}
 
+		if response == "SEND_PENDING" {
+			if sm.pendingPrompt == "" {
+				response = "Nothing to send."
+			} else {
+				sm.sendPending = true
+				sm.SetState(models.StateInput)
+				return true, sm.pendingPrompt
+			}
+		}
+
+		if strings.HasPrefix(response, "TRIM_HISTORY:") {
+			keep, _ := strconv.Atoi(strings.TrimPrefix(response, "TRIM_HISTORY:"))
+			removed := sm.TrimHistory(keep)
+			response = fmt.Sprintf("Removed %d older messages from the conversation.", removed)
+			if preview := sm.pendingPreview(); preview != "" {
+				response += "\n\n" + preview
+			}
+		}
+
+		if response == "COMPACT_HISTORY" {
+			removed := sm.CompactHistory()
+			response = fmt.Sprintf("Trimmed %d characters from long messages.", removed)
+			if preview := sm.pendingPreview(); preview != "" {
+				response += "\n\n" + preview
+			}
+		}
+
+		if response == "SHOW_TIMING" {
+			response = sm.timingReport()
+		}
+
+		if response == "SHOW_CONTEXT" {
+			response = sm.contextReport()
+		}
+
+		if strings.HasPrefix(response, "TIMING:") {
+			sm.showTiming = strings.TrimPrefix(response, "TIMING:") == "on"
+			if sm.showTiming {
+				response = "The last turn's timing will be shown in the status bar."
+			} else {
+				response = "Turn timing hidden from the status bar."
+			}
+		}
+
+		if response == "SHOW_STEP" || strings.HasPrefix(response, "STEP:") {
+			response = sm.applyStepMode(response)
+		}
+
+		if response == "SHOW_TENANT" || strings.HasPrefix(response, "TENANT:") {
+			response = sm.applyTenant(response)
+		}
+
+		if response == "SHOW_MODEL" || strings.HasPrefix(response, "MODEL:") {
+			response = sm.applyModel(response)
+		}
+
+		if strings.HasPrefix(response, "FILL_INPUT:") {
+			fill, message := handlers.ParseFillInput(response)
+			sm.inputFill = strings.ReplaceAll(fill, "\n", " ")
+			response = message
+			if response == "" {
+				response = "Placed in the input: press Enter to send it, or edit it first."
+			}
+		}
+
+		if strings.HasPrefix(response, "BOOKMARK_ADD:") {
+			response = sm.addBookmark(strings.TrimPrefix(response, "BOOKMARK_ADD:"))
+		}
+
+		if strings.HasPrefix(response, "EDIT_CONNECTION:") {
+			response = sm.openConnectionEdit(strings.TrimPrefix(response, "EDIT_CONNECTION:"))
+		}
+
+		if strings.HasPrefix(response, "BUILD_QUERY:") {
+			response = sm.openQueryBuilder(strings.TrimPrefix(response, "BUILD_QUERY:"))
+		}
+
+		if strings.HasPrefix(response, "BROWSE:") {
+			response = sm.openResultPager(strings.TrimPrefix(response, "BROWSE:"))
+		}
+
+		if strings.HasPrefix(response, "GRID:") {
+			response = sm.openDataGrid(strings.TrimPrefix(response, "GRID:"))
+		}
+
+		if strings.HasPrefix(response, "WORKLOAD:") {
+			sm.workloadJob = sm.cmdHandler.TakeWorkloadJob()
+			response = strings.TrimPrefix(response, "WORKLOAD:")
+		}
+
+		if strings.HasPrefix(response, "EXPORT_RESULTS:") {
+			response = sm.exportResults(strings.TrimPrefix(response, "EXPORT_RESULTS:"))
+		}
+
+		if strings.HasPrefix(response, "SAVE_RESULT:") {
+			response = sm.saveResult(strings.TrimPrefix(response, "SAVE_RESULT:"))
+		}
+
+		if strings.HasPrefix(response, "EXPORT_NOTEBOOK:") {
+			response = sm.exportNotebook(strings.TrimPrefix(response, "EXPORT_NOTEBOOK:"))
+		}
+
+		if strings.HasPrefix(response, "RESUME:") {
+			response = sm.resumeConversation(strings.TrimPrefix(response, "RESUME:"))
+		}
+
+		if response == "NEW_CONVERSATION" {
+			response = sm.startConversation()
+		}
+
+		if strings.HasPrefix(response, "SHARE:") {
+			response = sm.shareTranscript(strings.TrimPrefix(response, "SHARE:"))
+		}
+
 		if response == "EXIT" {
+			sm.cmdHandler.StopRecording()
 			return false, "" // Signal to exit
 		}
 
+		sm.cmdHandler.RecordOutput(response)
 		sm.SetResponse(response)
 		sm.SetError(nil)
 		sm.SetState(models.StateResponse)
 
 		// Check if command may have affected database connections and refresh guidance
-		if strings.HasPrefix(input, "/add") || strings.HasPrefix(input, "/switch") || strings.HasPrefix(input, "/remove") {
-			// Get updated database tools from connection service
-			if sm.connMgr != nil {
-				if connService, ok := sm.connMgr.(interface {
-					GetCurrentTools() dbinterfaces.DatabaseInterface
-				}); ok {
-					updatedTools := connService.GetCurrentTools()
-					sm.UpdateDatabaseTools(updatedTools)
-				}
-			}
+		if strings.HasPrefix(input, "/add") || strings.HasPrefix(input, "/switch") || strings.HasPrefix(input, "/remove") || strings.HasPrefix(input, "/bookmark run") || strings.HasPrefix(input, "/edit undo") {
+			sm.refreshDatabaseTools()
 		}
 
 		return true, ""
 	}
 
-	return true, "" // Not handled as command, continue with AI processing
+	return true, response // Not handled as command, continue with AI processing
+}
+
+// isCostPreviewCommand reports whether input is one of the commands that act on a held prompt
+func isCostPreviewCommand(input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "/send", "/trim", "/compact":
+		return true
+	}
+	return false
 }
 
 // UpdateCommandSuggestions updates command suggestions based on input
@@ -271,7 +449,18 @@ func (sm *StateManager) HasApiKey() bool {
 	return sm.hasApiKey
 }
 
+// SetTour shows a guided tour until it is dismissed
+func (sm *StateManager) SetTour(tour *models.GuidanceInfo) {
+	sm.tour = tour
+	sm.checkAndSetInitialGuidance()
+}
+
 func (sm *StateManager) checkAndSetInitialGuidance() {
+	if sm.tour != nil {
+		sm.currentGuidance = sm.tour
+		return
+	}
+
 	if !sm.hasApiKey {
 		sm.currentGuidance = &models.GuidanceInfo{
 			Type:    "api_key_missing",
@@ -281,7 +470,8 @@ func (sm *StateManager) checkAndSetInitialGuidance() {
 				"1. Get your API key from OpenAI (https://platform.openai.com/api-keys)",
 				"2. Set the environment variable: 'export OPENAI_API_KEY=your_api_key_here'",
 				"3. Optionally set: 'export OPENAI_BASE_URL=https://api.openai.com/v1'",
-				"4. Restart DBSage to use AI features",
+				"4. Or use Claude or a local model: 'export DBSAGE_PROVIDER=anthropic' with ANTHROPIC_API_KEY, or 'export DBSAGE_PROVIDER=ollama'",
+				"5. Restart DBSage to use AI features",
 			},
 			Actions: []string{
 				"You can still use database commands like '/add', '/list', '/switch' without API key",
@@ -333,6 +523,7 @@ func (sm *StateManager) checkAndSetInitialGuidance() {
 
 func (sm *StateManager) DismissGuidance() {
 	sm.currentGuidance = nil
+	sm.tour = nil
 }
 
 func (sm *StateManager) UpdateDatabaseTools(dbTools dbinterfaces.DatabaseInterface) {
@@ -341,6 +532,18 @@ func (sm *StateManager) UpdateDatabaseTools(dbTools dbinterfaces.DatabaseInterfa
 	sm.checkAndSetInitialGuidance()
 }
 
+// refreshDatabaseTools picks up the current database tools after a command
+// changed the connections
+func (sm *StateManager) refreshDatabaseTools() {
+	if sm.connMgr != nil {
+		if connService, ok := sm.connMgr.(interface {
+			GetCurrentTools() dbinterfaces.DatabaseInterface
+		}); ok {
+			sm.UpdateDatabaseTools(connService.GetCurrentTools())
+		}
+	}
+}
+
 func (sm *StateManager) RefreshGuidance() {
 	// Re-check guidance state (useful after connection changes)
 	sm.checkAndSetInitialGuidance()
@@ -358,3 +561,10 @@ func (sm *StateManager) SetVersionUpdate(updateInfo *models.VersionUpdateInfo) {
 func (sm *StateManager) DismissVersionUpdate() {
 	sm.versionUpdate = nil
 }
+
+// TakeInputFill returns the text a command placed for the input box and clears it
+func (sm *StateManager) TakeInputFill() string {
+	fill := sm.inputFill
+	sm.inputFill = ""
+	return fill
+}
@@ -0,0 +1,90 @@
+package state
+
+import (
+	"time"
+
+	"dbsage/internal/ai"
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/database"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// GetStatusBarInfo collects the values shown in the bottom status bar
+func (sm *StateManager) GetStatusBarInfo() models.StatusBarInfo {
+	info := models.StatusBarInfo{}
+
+	// Called on every render: read the cached connection and health state
+	// rather than pinging the database
+	if sm.connService != nil {
+		if db, name := sm.connService.CurrentConnection(); name != "" {
+			info.Connection = name
+			if db != nil {
+				if expiry := dbinterfaces.ScratchpadExpiry(db); !expiry.IsZero() {
+					info.Scratchpad = time.Until(expiry).Round(time.Second).String()
+				}
+			}
+			description := ""
+			if sm.connMgr != nil {
+				if config, ok := sm.connMgr.ListConnections()[name]; ok && config != nil {
+					description = config.Description
+				}
+			}
+			info.Environment = database.DetectEnvironment(name, description)
+		}
+		health := sm.connService.GetHealthStatus()
+		if health.Remote {
+			info.Latency = health.Latency.Round(time.Millisecond).String()
+		}
+		info.Health = healthProblem(health)
+		for _, other := range sm.connService.ConnectionsHealth() {
+			if other.State == dbinterfaces.HealthDown {
+				info.DownCount++
+			}
+		}
+	}
+
+	if sm.aiClient != nil {
+		info.Model = sm.aiClient.Model()
+		info.QueryDuration = sqlanalysis.DurationText(sm.aiClient.LastQueryDuration())
+		info.Tenant = sm.aiClient.Tenant()
+		if sm.showTiming {
+			if timing := sm.aiClient.LastTurnTiming(); timing != nil {
+				info.TurnTiming = timing.Summary()
+			}
+		}
+	}
+	if sm.contextTokens == 0 {
+		sm.contextTokens = ai.EstimateContextTokens(sm.history)
+	}
+	info.ContextTokens = sm.contextTokens
+
+	return info
+}
+
+// healthProblem describes what is wrong with the current connection for the
+// status bar, or returns "" when nothing is
+func healthProblem(health dbinterfaces.HealthStatus) string {
+	switch health.State {
+	case dbinterfaces.HealthDegraded:
+		return "reconnecting"
+	case dbinterfaces.HealthDown:
+		if wait := time.Until(health.NextAttempt); wait > 0 {
+			return "down, retry in " + wait.Round(time.Second).String()
+		}
+		return "down, retrying"
+	}
+	return ""
+}
+
+// timingReport describes where the last turn spent its time
+func (sm *StateManager) timingReport() string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there are no turns to time."
+	}
+	timing := sm.aiClient.LastTurnTiming()
+	if timing == nil {
+		return "No turn has finished yet. Ask a question, then run /timing last."
+	}
+	return timing.Report()
+}
@@ -0,0 +1,18 @@
+package state
+
+import "strings"
+
+// applyStepMode handles the SHOW_STEP and STEP:<on|off> responses of /step
+func (sm *StateManager) applyStepMode(response string) string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there are no tool calls to step through."
+	}
+
+	if strings.HasPrefix(response, "STEP:") {
+		sm.aiClient.SetStepMode(strings.TrimPrefix(response, "STEP:") == "on")
+	}
+	if sm.aiClient.StepMode() {
+		return "Step mode is on. Each tool call of a turn pauses for review: s runs it and pauses at the next, c runs the rest of the turn, a aborts the turn."
+	}
+	return "Step mode is off. Tool calls run without pausing, except those that need confirmation."
+}
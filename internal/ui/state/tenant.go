@@ -0,0 +1,33 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/sqlanalysis"
+)
+
+// applyTenant handles the SHOW_TENANT and TENANT:<tenant> responses of /tenant
+func (sm *StateManager) applyTenant(response string) string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there are no AI statements to scope to a tenant."
+	}
+
+	if strings.HasPrefix(response, "TENANT:") {
+		tenant := strings.TrimSpace(strings.TrimPrefix(response, "TENANT:"))
+		sm.aiClient.SetTenant(tenant)
+		if tenant == "" {
+			return "Tenant context cleared. AI statements are no longer scoped to a tenant."
+		}
+	}
+
+	tenant := sm.aiClient.Tenant()
+	policy, err := sqlanalysis.LoadTenantPolicy()
+	if err != nil {
+		return fmt.Sprintf("Failed to read the tenant policy: %v", err)
+	}
+	if tenant == "" {
+		return "No tenant is set. Use /tenant set <id> to scope AI statements to one tenant.\n\n" + policy.Describe()
+	}
+	return fmt.Sprintf("AI statements are scoped to tenant %s. Queries that miss the tenant condition are rejected, and inserts and updates are limited to the tenant.\n\n%s", tenant, policy.Describe())
+}
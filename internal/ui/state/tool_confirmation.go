@@ -42,6 +42,17 @@ func (sm *StateManager) CreateToolConfirmationInfo(toolName, toolCallID string,
 	return toolHandler.CreateToolConfirmationInfo(toolName, toolCallID, args, sm.toolConfirmationConfig)
 }
 
+// CreateStepConfirmationInfo creates the pause shown before a tool call of a
+// stepped turn
+func (sm *StateManager) CreateStepConfirmationInfo(toolName, toolCallID string, args map[string]interface{}, step int, reason string, remaining []string) *models.ToolConfirmationInfo {
+	toolHandler := handlers.NewToolHandler()
+	info := toolHandler.CreateToolConfirmationInfo(toolName, toolCallID, args, sm.toolConfirmationConfig)
+	if info != nil {
+		toolHandler.SetStepControls(info, step, reason, remaining)
+	}
+	return info
+}
+
 // GetDefaultToolConfirmationConfig returns the default tool confirmation configuration
 func GetDefaultToolConfirmationConfig() *models.ToolConfirmationConfig {
 	return &models.ToolConfirmationConfig{
@@ -52,11 +63,34 @@ func GetDefaultToolConfirmationConfig() *models.ToolConfirmationConfig {
 			"get_table_schema":       false,
 			"explain_query":          false,
 			"get_table_indexes":      false,
+			"get_rls_policies":       false,
+			"setup_fts":              false,
+			"list_extensions":        false,
+			"list_features":          false,
 			"get_table_stats":        false,
+			"get_collations":         false,
+			"get_temp_usage":         false,
+			"advise_config":          false,
+			"profile_waits":          false,
+			"copy_table":             true,
+			"watch_table":            true,
+			"export_results":         true,
+			"sample_results":         false,
+			"preview_table":          false,
+			"generate_erd":           false,
+			"trace_column":           false,
+			"get_query_history":      false,
 			"get_slow_queries":       false,
 			"get_database_size":      false,
 			"get_table_sizes":        false,
 			"get_active_connections": false,
+			"generate_code":          false,
+			"analyze_query":          false,
+			"advise_ctes":            false,
+			"advise_indexes":         false,
+			"insert_row":             true,
+			"update_rows":            true,
+			"generate_test_data":     true,
 		},
 		RiskLevels: map[string]string{
 			"execute_sql":            "high",
@@ -65,11 +99,34 @@ func GetDefaultToolConfirmationConfig() *models.ToolConfirmationConfig {
 			"get_table_schema":       "low",
 			"explain_query":          "low",
 			"get_table_indexes":      "low",
+			"get_rls_policies":       "low",
+			"setup_fts":              "low",
+			"list_extensions":        "low",
+			"list_features":          "low",
 			"get_table_stats":        "low",
+			"get_collations":         "low",
+			"get_temp_usage":         "low",
+			"advise_config":          "low",
+			"profile_waits":          "low",
+			"copy_table":             "medium",
+			"watch_table":            "medium",
+			"export_results":         "low",
+			"sample_results":         "low",
+			"preview_table":          "low",
+			"generate_erd":           "low",
+			"trace_column":           "low",
+			"get_query_history":      "low",
 			"get_slow_queries":       "low",
 			"get_database_size":      "low",
 			"get_table_sizes":        "low",
 			"get_active_connections": "low",
+			"generate_code":          "low",
+			"analyze_query":          "low",
+			"advise_ctes":            "low",
+			"advise_indexes":         "low",
+			"insert_row":             "medium",
+			"update_rows":            "high",
+			"generate_test_data":     "medium",
 		},
 		Descriptions: map[string]string{
 			"execute_sql":            "Execute SQL query on the database",
@@ -78,11 +135,34 @@ func GetDefaultToolConfirmationConfig() *models.ToolConfirmationConfig {
 			"get_table_schema":       "Get table schema information",
 			"explain_query":          "Analyze query execution plan",
 			"get_table_indexes":      "Get table index information",
+			"get_rls_policies":       "Get row-level security policies",
+			"setup_fts":              "Generate full-text search setup statements",
+			"list_extensions":        "List installed extensions and plugins",
+			"list_features":          "List server settings and features",
 			"get_table_stats":        "Get table statistics",
+			"get_collations":         "Check encodings and collations",
+			"get_temp_usage":         "Analyze temporary file usage",
+			"advise_config":          "Suggest server configuration changes",
+			"profile_waits":          "Sample wait events of active sessions",
+			"copy_table":             "Copy a table to another connection",
+			"watch_table":            "Watch table changes with temporary triggers",
+			"export_results":         "Write query results",
+			"sample_results":         "Sample query results for analysis",
+			"preview_table":          "Preview table rows",
+			"generate_erd":           "Draw an ER diagram of the schema",
+			"trace_column":           "Trace view column lineage",
+			"get_query_history":      "Read the query history",
 			"get_slow_queries":       "Get slow query information",
 			"get_database_size":      "Get database size information",
 			"get_table_sizes":        "Get table size information",
 			"get_active_connections": "Get active database connections",
+			"generate_code":          "Generate a code snippet for a SQL query",
+			"analyze_query":          "Run the SQL optimizer on a query",
+			"advise_ctes":            "Suggest CTE and subquery restructuring",
+			"advise_indexes":         "Suggest indexes from the workload",
+			"insert_row":             "Insert a row built from validated field values",
+			"update_rows":            "Update rows, rolled back unless the expected number of rows changes",
+			"generate_test_data":     "Insert generated test rows, recorded for /cleanup generated",
 		},
 	}
 }
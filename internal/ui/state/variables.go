@@ -0,0 +1,42 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// saveResult saves the last query result as a $name variable for /as, or
+// lists the saved ones when name is empty
+func (sm *StateManager) saveResult(name string) string {
+	if sm.aiClient == nil {
+		return "AI is not configured, so there is no query result to save."
+	}
+	if name == "" {
+		variables := sm.aiClient.Variables()
+		if len(variables) == 0 {
+			return "No saved results. Run a query, then /as <name> saves its result as $name."
+		}
+		var b strings.Builder
+		b.WriteString("Saved results:\n")
+		for _, v := range variables {
+			fmt.Fprintf(&b, "- $%s: %d rows of (%s) from '%s'\n", v.Name, len(v.Result.Rows), strings.Join(v.Result.Columns, ", "), v.Connection)
+		}
+		return strings.TrimRight(b.String(), "\n")
+	}
+
+	variable, err := sm.aiClient.SaveResult(name)
+	if err != nil {
+		return fmt.Sprintf("Failed to save the result: %v", err)
+	}
+	return fmt.Sprintf("Saved %d rows of (%s) as $%s. Refer to it in a question, e.g. \"compare $%s with last year\".",
+		len(variable.Result.Rows), strings.Join(variable.Result.Columns, ", "), variable.Name, variable.Name)
+}
+
+// ExpandVariables adds the saved results a prompt refers to as $name, so the
+// AI gets their cached rows and the SQL they came from
+func (sm *StateManager) ExpandVariables(prompt string) string {
+	if sm.aiClient == nil || !strings.Contains(prompt, "$") {
+		return prompt
+	}
+	return sm.aiClient.ExpandPrompt(prompt)
+}
@@ -0,0 +1,24 @@
+package state
+
+import (
+	"dbsage/internal/models"
+	"dbsage/internal/ui/handlers"
+)
+
+// TakeWorkloadJob returns the job /capture, /replay or /explain-file started
+// and clears it
+func (sm *StateManager) TakeWorkloadJob() *handlers.WorkloadJob {
+	job := sm.workloadJob
+	sm.workloadJob = nil
+	return job
+}
+
+// FinishWorkload shows the response of a finished background job
+func (sm *StateManager) FinishWorkload(response string) {
+	if sm.cmdHandler != nil {
+		sm.cmdHandler.RecordOutput(response)
+	}
+	sm.SetResponse(response)
+	sm.SetError(nil)
+	sm.SetState(models.StateResponse)
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRenderInterval is how long streamed chunks are coalesced before the
+// response is re-rendered
+const DefaultRenderInterval = 75 * time.Millisecond
+
+// renderInterval returns the streaming render interval, set with
+// DBSAGE_RENDER_INTERVAL (a Go duration; 0 renders every chunk)
+func renderInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_RENDER_INTERVAL")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultRenderInterval
+}
+
+// streamThrottle coalesces streamed chunks so the UI re-renders at most once
+// per interval instead of on every chunk. A trailing chunk is delivered once
+// the interval has passed even if no further chunk arrives.
+type streamThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	send     func(string)
+	pending  strings.Builder
+	last     time.Time
+	timer    *time.Timer
+}
+
+func newStreamThrottle(interval time.Duration, send func(string)) *streamThrottle {
+	return &streamThrottle{interval: interval, send: send}
+}
+
+// Write buffers a chunk, delivering the buffer right away if the interval has
+// passed since the last delivery
+func (t *streamThrottle) Write(chunk string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending.WriteString(chunk)
+	wait := t.interval - time.Since(t.last)
+	if wait <= 0 {
+		t.flushLocked()
+		return
+	}
+	if t.timer == nil {
+		t.timer = time.AfterFunc(wait, t.Flush)
+	}
+}
+
+// Flush delivers any buffered chunks; call it before the stream completes
+func (t *streamThrottle) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushLocked()
+}
+
+func (t *streamThrottle) flushLocked() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if t.pending.Len() == 0 {
+		return
+	}
+	t.send(t.pending.String())
+	t.pending.Reset()
+	t.last = time.Now()
+}
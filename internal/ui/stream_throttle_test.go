@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamThrottle_CoalescesChunks(t *testing.T) {
+	var sent []string
+	throttle := newStreamThrottle(time.Hour, func(chunk string) { sent = append(sent, chunk) })
+
+	throttle.Write("Hel")
+	throttle.Write("lo, ")
+	throttle.Write("world")
+	throttle.Flush()
+
+	assert.Equal(t, []string{"Hel", "lo, world"}, sent)
+}
+
+func TestStreamThrottle_DeliversTrailingChunk(t *testing.T) {
+	sent := make(chan string, 2)
+	throttle := newStreamThrottle(20*time.Millisecond, func(chunk string) { sent <- chunk })
+
+	throttle.Write("a")
+	throttle.Write("b")
+
+	assert.Equal(t, "a", <-sent)
+	select {
+	case chunk := <-sent:
+		assert.Equal(t, "b", chunk)
+	case <-time.After(time.Second):
+		t.Fatal("trailing chunk was not delivered")
+	}
+}
+
+func TestStreamThrottle_ZeroIntervalSendsEveryChunk(t *testing.T) {
+	var sent []string
+	throttle := newStreamThrottle(0, func(chunk string) { sent = append(sent, chunk) })
+
+	throttle.Write("a")
+	throttle.Write("b")
+
+	assert.Equal(t, []string{"a", "b"}, sent)
+}
+
+func TestRenderInterval(t *testing.T) {
+	t.Setenv("DBSAGE_RENDER_INTERVAL", "")
+	assert.Equal(t, DefaultRenderInterval, renderInterval())
+
+	t.Setenv("DBSAGE_RENDER_INTERVAL", "0")
+	assert.Equal(t, time.Duration(0), renderInterval())
+
+	t.Setenv("DBSAGE_RENDER_INTERVAL", "120ms")
+	assert.Equal(t, 120*time.Millisecond, renderInterval())
+}
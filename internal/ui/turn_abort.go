@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// abortedMessage is shown once the user aborted a turn, until the running
+// statements were cancelled
+const abortedMessage = "Aborted."
+
+// beginTurn returns the context of an AI turn, cancelled when the user
+// aborts the turn with esc
+func (m *Model) beginTurn() context.Context {
+	m.endTurn()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelTurn = cancel
+	return ctx
+}
+
+// endTurn releases the context of a finished turn
+func (m *Model) endTurn() {
+	if m.cancelTurn != nil {
+		m.cancelTurn()
+		m.cancelTurn = nil
+	}
+}
+
+// abortTurn stops the running AI turn, or /capture and /replay. Its answer
+// stops streaming, and the statements running on the connection are cancelled
+// on the server rather than left running there after the wait for them is
+// abandoned.
+func (m *Model) abortTurn() (tea.Model, tea.Cmd) {
+	m.endTurn()
+	m.workloadProgress = ""
+	m.finishTurnTiming()
+	m.streamingResponse = ""
+	m.stateManager.SetError(nil)
+	m.stateManager.SetResponse(abortedMessage)
+	m.stateManager.SetState(models.StateResponse)
+	m.textInput.SetValue("")
+	m.textInput.Focus()
+
+	db := m.stateManager.GetDatabaseTools()
+	if db == nil {
+		return m, textinput.Blink
+	}
+	return m, tea.Batch(textinput.Blink, func() tea.Msg {
+		cancelled, err := dbinterfaces.CancelRunning(db)
+		return models.TurnAbortedMsg{Cancelled: cancelled, Err: err}
+	})
+}
+
+// handleTurnAborted reports the statements cancelled for an aborted turn,
+// unless the user moved on since
+func (m *Model) handleTurnAborted(msg models.TurnAbortedMsg) (tea.Model, tea.Cmd) {
+	if m.stateManager.GetState() != models.StateResponse || m.stateManager.GetResponse() != abortedMessage {
+		return m, nil
+	}
+	switch {
+	case msg.Err != nil:
+		m.stateManager.SetError(fmt.Errorf("aborted, but the running statement could not be cancelled on the server and may still be running: %w", msg.Err))
+	case msg.Cancelled == 1:
+		m.stateManager.SetResponse("Aborted. The running statement was cancelled on the server.")
+	case msg.Cancelled > 1:
+		m.stateManager.SetResponse(fmt.Sprintf("Aborted. %d running statements were cancelled on the server.", msg.Cancelled))
+	}
+	return m, nil
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"dbsage/internal/ai"
+)
+
+// beginTurnTiming starts timing a turn sent to the AI
+func (m *Model) beginTurnTiming() {
+	m.renderTime = 0
+	m.renderFrames = 0
+	m.timingTurn = true
+	if aiClient := m.stateManager.GetAIClient(); aiClient != nil {
+		aiClient.BeginTurn()
+	}
+}
+
+// finishTurnTiming records the rendering spent on the turn and closes it
+func (m *Model) finishTurnTiming() {
+	if !m.timingTurn {
+		return
+	}
+	m.timingTurn = false
+	if aiClient := m.stateManager.GetAIClient(); aiClient != nil {
+		aiClient.RecordPhase(ai.PhaseRender, "view", fmt.Sprintf("%d frames", m.renderFrames), m.renderTime)
+		aiClient.FinishTurn()
+	}
+}
+
+// recordConfirmationWait records how long the user took to answer a tool confirmation
+func (m *Model) recordConfirmationWait(toolName string) {
+	if m.confirmationShownAt.IsZero() {
+		return
+	}
+	if aiClient := m.stateManager.GetAIClient(); aiClient != nil {
+		aiClient.RecordPhase(ai.PhaseConfirmation, toolName, "", time.Since(m.confirmationShownAt))
+	}
+	m.confirmationShownAt = time.Time{}
+}
+
+// timeRender adds the duration of a View call to the current turn
+func (m *Model) timeRender(start time.Time) {
+	if m.timingTurn {
+		m.renderTime += time.Since(start)
+		m.renderFrames++
+	}
+}
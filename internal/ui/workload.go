@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"dbsage/internal/models"
+	"dbsage/internal/ui/handlers"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runWorkload runs a /capture, /replay or /explain-file job outside the update loop, showing
+// its progress until it finishes. esc aborts it like an AI turn.
+func (m *Model) runWorkload(job *handlers.WorkloadJob) (tea.Model, tea.Cmd) {
+	ctx := m.beginTurn()
+	m.workloadProgress = m.stateManager.GetResponse()
+	m.stateManager.SetState(models.StateThinking)
+	m.textInput.Blur()
+
+	return m, tea.Batch(m.tick(), func() tea.Msg {
+		response := job.Run(ctx, func(text string) {
+			if m.program != nil && ctx.Err() == nil {
+				m.program.Send(models.WorkloadProgressMsg{Text: text})
+			}
+		})
+		// An aborted job was already closed by abortTurn
+		if ctx.Err() != nil {
+			return nil
+		}
+		return models.WorkloadDoneMsg{Response: response}
+	})
+}
+
+// handleWorkloadProgress shows how far the running job got
+func (m *Model) handleWorkloadProgress(msg models.WorkloadProgressMsg) (tea.Model, tea.Cmd) {
+	if m.workloadProgress != "" {
+		m.workloadProgress = msg.Text
+	}
+	return m, nil
+}
+
+// handleWorkloadDone shows the response of a finished job, unless it was
+// aborted since
+func (m *Model) handleWorkloadDone(msg models.WorkloadDoneMsg) (tea.Model, tea.Cmd) {
+	if m.workloadProgress == "" {
+		return m, nil
+	}
+	m.endTurn()
+	m.workloadProgress = ""
+	m.stateManager.FinishWorkload(msg.Response)
+	m.textInput.SetValue("")
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
@@ -0,0 +1,151 @@
+// Package usage keeps how often and how recently commands, connections and
+// tables were used, so suggestions list the most likely completion first.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of names whose use is recorded; tables are recorded per connection
+// under TableKind
+const (
+	KindCommand    = "command"
+	KindConnection = "connection"
+)
+
+// TableKind returns the kind under which the tables of a connection are recorded
+func TableKind(connection string) string {
+	return "table:" + connection
+}
+
+// forgetAfter is how long an unused name is kept
+const forgetAfter = 90 * 24 * time.Hour
+
+// Entry is the recorded use of one name
+type Entry struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Usage is the recorded use of names, by kind and then by name
+type Usage map[string]map[string]Entry
+
+var mu sync.Mutex
+
+// usageFile returns the path of the usage file
+func usageFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "usage.json")
+}
+
+// Load reads the recorded usage. A missing file means nothing was used yet.
+func Load() (Usage, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+func load() (Usage, error) {
+	data, err := os.ReadFile(usageFile())
+	if os.IsNotExist(err) {
+		return Usage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	u := Usage{}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	return u, nil
+}
+
+func save(u Usage) error {
+	path := usageFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Record counts a use of a name now and returns the updated usage. Names
+// unused for 90 days are forgotten. The file is re-read first, so uses
+// recorded by other dbsage sessions are kept.
+func Record(kind, name string, now time.Time) (Usage, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	u, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if u[kind] == nil {
+		u[kind] = map[string]Entry{}
+	}
+	entry := u[kind][name]
+	u[kind][name] = Entry{Count: entry.Count + 1, LastUsed: now}
+
+	for k, names := range u {
+		for n, e := range names {
+			if now.Sub(e.LastUsed) > forgetAfter {
+				delete(names, n)
+			}
+		}
+		if len(names) == 0 {
+			delete(u, k)
+		}
+	}
+	return u, save(u)
+}
+
+// Score rates how likely a name is to be used next: its use count weighted
+// by how recently it was last used. Unused names score 0.
+func (u Usage) Score(kind, name string, now time.Time) float64 {
+	entry, ok := u[kind][name]
+	if !ok {
+		return 0
+	}
+	age := now.Sub(entry.LastUsed)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	case age < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(entry.Count) * weight
+}
+
+// Rank orders items by the score of their names, most likely first. Items
+// with equal scores keep their order.
+func Rank[T any](u Usage, kind string, items []T, name func(T) string, now time.Time) {
+	scores := make([]float64, len(items))
+	order := make([]int, len(items))
+	for i, item := range items {
+		scores[i] = u.Score(kind, name(item), now)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	ranked := make([]T, len(items))
+	for i, j := range order {
+		ranked[i] = items[j]
+	}
+	copy(items, ranked)
+}
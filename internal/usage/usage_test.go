@@ -0,0 +1,44 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndRank(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	u, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, u)
+
+	for i := 0; i < 3; i++ {
+		_, err = Record(KindCommand, "/history", now.Add(-10*24*time.Hour))
+		require.NoError(t, err)
+	}
+	_, err = Record(KindCommand, "/browse", now.Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = Record(TableKind("shop"), "orders", now)
+	require.NoError(t, err)
+	u, err = Record(KindCommand, "/stale", now.Add(-100*24*time.Hour))
+	require.NoError(t, err)
+	u, err = Record(KindCommand, "/browse", now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 8.0, u.Score(KindCommand, "/browse", now), "2 uses within the hour")
+	assert.Equal(t, 1.5, u.Score(KindCommand, "/history", now), "3 uses 10 days ago")
+	assert.Zero(t, u.Score(KindCommand, "/stale", now), "forgotten after 90 days")
+	assert.Zero(t, u.Score(TableKind("staging"), "orders", now), "tables are kept per connection")
+
+	names := []string{"/add", "/history", "/help", "/browse"}
+	Rank(u, KindCommand, names, func(name string) string { return name }, now)
+	assert.Equal(t, []string{"/browse", "/history", "/add", "/help"}, names)
+
+	loaded, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, u, loaded)
+}
@@ -0,0 +1,105 @@
+// Package watches records the helper triggers and log tables of running
+// table watches, so the objects of a watch cut short by a crash or a killed
+// process are removed the next time dbsage opens the connection.
+package watches
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Watch is the helper objects of one table watch
+type Watch struct {
+	Connection string    `json:"connection"`
+	Table      string    `json:"table"`
+	LogTable   string    `json:"log_table"`
+	Teardown   []string  `json:"teardown"` // Statements removing the objects, safe to run more than once
+	Until      time.Time `json:"until"`    // When the watch ends at the latest
+}
+
+var mu sync.Mutex
+
+// watchesFile returns the path of the file recording running watches
+func watchesFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".dbsage", "watches.json")
+}
+
+func load() ([]Watch, error) {
+	data, err := os.ReadFile(watchesFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read running watches: %w", err)
+	}
+	var watches []Watch
+	if err := json.Unmarshal(data, &watches); err != nil {
+		return nil, fmt.Errorf("failed to parse running watches: %w", err)
+	}
+	return watches, nil
+}
+
+func save(watches []Watch) error {
+	path := watchesFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(watches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Record adds a watch before its objects are created
+func Record(w Watch) error {
+	mu.Lock()
+	defer mu.Unlock()
+	existing, err := load()
+	if err != nil {
+		return err
+	}
+	return save(append(existing, w))
+}
+
+// Forget removes the watch with a log table once its objects are gone
+func Forget(connection, logTable string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	existing, err := load()
+	if err != nil {
+		return err
+	}
+	kept := existing[:0]
+	for _, w := range existing {
+		if w.Connection != connection || w.LogTable != logTable {
+			kept = append(kept, w)
+		}
+	}
+	return save(kept)
+}
+
+// Abandoned returns the watches of a connection that should have ended by
+// now. A running watch removes its record when it ends, so these were cut
+// short and their objects are left behind. Watches still running, possibly
+// in another dbsage process, are not returned.
+func Abandoned(connection string, now time.Time) ([]Watch, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	existing, err := load()
+	if err != nil {
+		return nil, err
+	}
+	var abandoned []Watch
+	for _, w := range existing {
+		if w.Connection == connection && now.After(w.Until) {
+			abandoned = append(abandoned, w)
+		}
+	}
+	return abandoned, nil
+}
@@ -0,0 +1,38 @@
+package watches
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAbandonedForget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	abandoned, err := Abandoned("dev", now)
+	require.NoError(t, err)
+	assert.Empty(t, abandoned)
+
+	require.NoError(t, Record(Watch{Connection: "dev", Table: "orders", LogTable: "dbsage_watch_a", Teardown: []string{"DROP TABLE IF EXISTS dbsage_watch_a"}, Until: now.Add(-time.Minute)}))
+	require.NoError(t, Record(Watch{Connection: "dev", Table: "orders", LogTable: "dbsage_watch_b", Until: now.Add(time.Minute)}))
+	require.NoError(t, Record(Watch{Connection: "staging", Table: "orders", LogTable: "dbsage_watch_a", Until: now.Add(-time.Minute)}))
+
+	abandoned, err = Abandoned("dev", now)
+	require.NoError(t, err)
+	require.Len(t, abandoned, 1, "a watch still running is not abandoned")
+	assert.Equal(t, "dbsage_watch_a", abandoned[0].LogTable)
+	assert.Equal(t, []string{"DROP TABLE IF EXISTS dbsage_watch_a"}, abandoned[0].Teardown)
+
+	require.NoError(t, Forget("dev", "dbsage_watch_a"))
+	abandoned, err = Abandoned("dev", now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, abandoned, 1)
+	assert.Equal(t, "dbsage_watch_b", abandoned[0].LogTable)
+
+	staging, err := Abandoned("staging", now)
+	require.NoError(t, err)
+	assert.Len(t, staging, 1, "forgetting a watch of one connection keeps the same log table on another")
+}
@@ -0,0 +1,263 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbsage/internal/models"
+)
+
+// maxErrorBody is the longest server error message kept
+const maxErrorBody = 4096
+
+// client runs statements through the ClickHouse HTTP interface
+type client struct {
+	endpoint string // URL of the interface, without credentials
+	database string
+	readOnly string // readonly setting sent with every statement, "" for none
+	username string
+	password string
+	http     *http.Client
+
+	mu      sync.Mutex
+	running map[string]struct{} // IDs of the queries in flight, for CancelRunning
+}
+
+// response is a JSONCompact result
+type response struct {
+	Meta []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"meta"`
+	Data [][]interface{} `json:"data"`
+}
+
+// summary is the X-ClickHouse-Summary header of a response
+type summary struct {
+	WrittenRows string `json:"written_rows"`
+}
+
+// newClient creates a client from the URL of the HTTP interface, which
+// carries the credentials, the database, the timeout and the readonly setting
+func newClient(connectionURL string) (*client, error) {
+	u, err := url.Parse(connectionURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q, expected http or https", u.Scheme)
+	}
+
+	c := &client{
+		database: u.Query().Get("database"),
+		readOnly: u.Query().Get("readonly"),
+		username: "default",
+		running:  make(map[string]struct{}),
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	timeout := 300 * time.Second
+	if t := u.Query().Get("timeout"); t != "" {
+		if timeout, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", t, err)
+		}
+	}
+	c.http = &http.Client{Timeout: timeout}
+
+	u.User = nil
+	u.RawQuery = ""
+	u.Path = "/"
+	c.endpoint = u.String()
+	return c, nil
+}
+
+// query runs a statement and returns its rows, if any
+func (c *client) query(ctx context.Context, query string) (*models.QueryResult, error) {
+	start := time.Now()
+	queryID := newQueryID()
+	c.track(queryID, true)
+	defer c.track(queryID, false)
+
+	resp, err := c.post(ctx, query, queryID)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result: %w", err)
+	}
+
+	result := &models.QueryResult{Columns: []string{}, Rows: [][]interface{}{}}
+	if len(bytes.TrimSpace(body)) == 0 {
+		// Statements without a result, such as INSERT or ALTER, report the rows they wrote
+		var s summary
+		if json.Unmarshal([]byte(resp.Header.Get("X-ClickHouse-Summary")), &s) == nil {
+			written, _ := strconv.Atoi(s.WrittenRows)
+			result.RowCount = written
+		}
+		result.Duration = time.Since(start).String()
+		return result, nil
+	}
+
+	var decoded response
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %w", err)
+	}
+
+	for _, column := range decoded.Meta {
+		result.Columns = append(result.Columns, column.Name)
+	}
+	for _, row := range decoded.Data {
+		values := make([]interface{}, len(row))
+		for i, value := range row {
+			columnType := ""
+			if i < len(decoded.Meta) {
+				columnType = decoded.Meta[i].Type
+			}
+			values[i] = convertValue(columnType, value)
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	result.RowCount = len(result.Rows)
+	result.Duration = time.Since(start).String()
+	return result, nil
+}
+
+// post sends a statement to the server and returns its response, or the
+// error the server reported
+func (c *client) post(ctx context.Context, query, queryID string) (*http.Response, error) {
+	params := url.Values{}
+	if c.database != "" {
+		params.Set("database", c.database)
+	}
+	params.Set("default_format", "JSONCompact")
+	params.Set("output_format_json_quote_64bit_integers", "0")
+	if c.readOnly != "" {
+		// readonly=2 refuses writes but still lets the settings above be changed
+		params.Set("readonly", c.readOnly)
+	}
+	if queryID != "" {
+		params.Set("query_id", queryID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"?"+params.Encode(), strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-ClickHouse-User", c.username)
+	req.Header.Set("X-ClickHouse-Key", c.password)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// track records a query as in flight, or no longer
+func (c *client) track(queryID string, running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if running {
+		c.running[queryID] = struct{}{}
+	} else {
+		delete(c.running, queryID)
+	}
+}
+
+// cancel kills the queries in flight on the server and returns how many
+// were asked to stop
+func (c *client) cancel() (int, error) {
+	c.mu.Lock()
+	ids := make([]string, 0, len(c.running))
+	for id := range c.running {
+		ids = append(ids, "'"+id+"'")
+	}
+	c.mu.Unlock()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	resp, err := c.post(ctx, "KILL QUERY WHERE query_id IN ("+strings.Join(ids, ", ")+") ASYNC", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel queries: %w", err)
+	}
+	resp.Body.Close()
+	return len(ids), nil
+}
+
+// newQueryID returns a random ID identifying a query on the server
+func newQueryID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "dbsage-" + hex.EncodeToString(b)
+}
+
+// convertValue converts a JSON value to the Go type of its ClickHouse column
+// type: integers to int64 (uint64 beyond its range), floats to float64 and
+// decimals to their exact text
+func convertValue(columnType string, value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	columnType = baseType(columnType)
+	switch {
+	case strings.HasPrefix(columnType, "Int"), strings.HasPrefix(columnType, "UInt"):
+		if i, err := number.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+			return u
+		}
+		return number.String() // 128 and 256 bit integers
+	case strings.HasPrefix(columnType, "Decimal"):
+		return number.String()
+	default:
+		if f, err := number.Float64(); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+		return number.String()
+	}
+}
+
+// baseType strips the Nullable and LowCardinality wrappers of a column type
+func baseType(columnType string) string {
+	for {
+		unwrapped := false
+		for _, wrapper := range []string{"Nullable(", "LowCardinality("} {
+			if strings.HasPrefix(columnType, wrapper) && strings.HasSuffix(columnType, ")") {
+				columnType = columnType[len(wrapper) : len(columnType)-1]
+				unwrapped = true
+			}
+		}
+		if !unwrapped {
+			return columnType
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+const (
+	defaultPort       = 8123 // HTTP interface
+	defaultSecurePort = 8443 // HTTPS interface
+)
+
+// ClickHouseProvider implements the DatabaseProviderInterface for ClickHouse
+type ClickHouseProvider struct{}
+
+// Ensure ClickHouseProvider implements DatabaseProviderInterface
+var _ dbinterfaces.DatabaseProviderInterface = (*ClickHouseProvider)(nil)
+
+// NewClickHouseProvider creates a new ClickHouse provider
+func NewClickHouseProvider() *ClickHouseProvider {
+	return &ClickHouseProvider{}
+}
+
+// CreateConnection creates a new ClickHouse database connection
+func (p *ClickHouseProvider) CreateConnection(config *dbinterfaces.ConnectionConfig) (dbinterfaces.DatabaseInterface, error) {
+	localConfig := toLocalConfig(config)
+	if err := p.validateLocalConfig(localConfig); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	connURL := p.buildLocalConnectionURL(localConfig)
+	return NewClickHouseDatabase(connURL)
+}
+
+// GetSupportedDrivers returns the supported ClickHouse drivers
+func (p *ClickHouseProvider) GetSupportedDrivers() []string {
+	return []string{"clickhouse"}
+}
+
+// ValidateConfig validates the ClickHouse connection configuration (using generic interface)
+func (p *ClickHouseProvider) ValidateConfig(config *dbinterfaces.ConnectionConfig) error {
+	return p.validateLocalConfig(toLocalConfig(config))
+}
+
+// BuildConnectionURL builds a ClickHouse HTTP URL from the configuration (using generic interface)
+func (p *ClickHouseProvider) BuildConnectionURL(config *dbinterfaces.ConnectionConfig) string {
+	localConfig := toLocalConfig(config)
+	_ = p.validateLocalConfig(localConfig)
+	return p.buildLocalConnectionURL(localConfig)
+}
+
+// toLocalConfig converts a generic configuration. Any SSL mode other than
+// disable connects over HTTPS.
+func toLocalConfig(config *dbinterfaces.ConnectionConfig) *ConnectionConfig {
+	return &ConnectionConfig{
+		Name:        config.Name,
+		Host:        config.Host,
+		Port:        config.Port,
+		Database:    config.Database,
+		Username:    config.Username,
+		Password:    config.Password,
+		Secure:      config.SSLMode != "" && config.SSLMode != "disable",
+		Timeout:     "300s",
+		Description: config.Description,
+		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
+	}
+}
+
+// validateLocalConfig validates the local ClickHouse connection configuration
+func (p *ClickHouseProvider) validateLocalConfig(config *ConnectionConfig) error {
+	if config.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if config.Port <= 0 {
+		config.Port = defaultPort
+		if config.Secure {
+			config.Port = defaultSecurePort
+		}
+	}
+	if config.Database == "" {
+		config.Database = "default" // Default ClickHouse database
+	}
+	if config.Username == "" {
+		config.Username = "default" // Default ClickHouse user
+	}
+	if config.Timeout == "" {
+		config.Timeout = "300s" // Default timeout
+	}
+	return nil
+}
+
+// buildLocalConnectionURL builds the URL of the HTTP interface from the local configuration
+func (p *ClickHouseProvider) buildLocalConnectionURL(config *ConnectionConfig) string {
+	scheme := "http"
+	if config.Secure {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(config.Username, config.Password),
+		Host:   net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		Path:   "/",
+	}
+	query := url.Values{}
+	query.Set("database", config.Database)
+	query.Set("timeout", config.Timeout)
+	if config.ReadOnly {
+		query.Set("readonly", "2")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,16 @@
+package clickhouse
+
+// ConnectionConfig represents a ClickHouse database connection configuration
+type ConnectionConfig struct {
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Database    string `json:"database"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Secure      bool   `json:"secure"`  // Connect over HTTPS
+	Timeout     string `json:"timeout"` // Longest wait for a statement
+	Description string `json:"description"`
+	LastUsed    string `json:"last_used,omitempty"` // ISO 8601 timestamp
+	ReadOnly    bool   `json:"read_only,omitempty"` // Run every statement with readonly=2
+}
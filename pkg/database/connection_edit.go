@@ -0,0 +1,116 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// previousFile returns the file keeping the version of each connection before
+// its last edit, next to the connections file
+func (cm *ConnectionManager) previousFile() string {
+	return filepath.Join(filepath.Dir(cm.configFile), "connections.previous.json")
+}
+
+func (cm *ConnectionManager) loadPrevious() (map[string]*dbinterfaces.ConnectionConfig, error) {
+	previous := make(map[string]*dbinterfaces.ConnectionConfig)
+	data, err := os.ReadFile(cm.previousFile())
+	if os.IsNotExist(err) {
+		return previous, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", cm.previousFile(), err)
+	}
+	if cm.credentials != nil {
+		if err := cm.credentials.decrypt(previous); err != nil {
+			return nil, err
+		}
+	}
+	return previous, nil
+}
+
+func (cm *ConnectionManager) savePrevious(previous map[string]*dbinterfaces.ConnectionConfig) error {
+	previous, err := cm.credentials.seal(previous)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(previous, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cm.previousFile(), data, 0600)
+}
+
+// UpdateConnection replaces the settings of an existing connection after
+// connecting with them successfully. The settings it replaces are kept for
+// UndoConnectionEdit.
+func (cm *ConnectionManager) UpdateConnection(config *dbinterfaces.ConnectionConfig) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	old, exists := cm.configs[config.Name]
+	if !exists {
+		return fmt.Errorf("connection '%s' not found", config.Name)
+	}
+	if old.AliasOf != "" {
+		return fmt.Errorf("'%s' is an alias of '%s'; edit '%s' instead", config.Name, old.AliasOf, old.AliasOf)
+	}
+	return cm.replaceConfig(old, config)
+}
+
+// UndoConnectionEdit restores the settings a connection had before its last
+// edit. Undoing again restores the edit.
+func (cm *ConnectionManager) UndoConnectionEdit(name string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	current, exists := cm.configs[name]
+	if !exists {
+		return fmt.Errorf("connection '%s' not found", name)
+	}
+	previous, err := cm.loadPrevious()
+	if err != nil {
+		return err
+	}
+	restored, exists := previous[name]
+	if !exists {
+		return fmt.Errorf("connection '%s' has no previous version to restore", name)
+	}
+	return cm.replaceConfig(current, restored)
+}
+
+// replaceConfig connects with config and, when that works, makes it the
+// settings of its connection and keeps old as the previous version
+func (cm *ConnectionManager) replaceConfig(old, config *dbinterfaces.ConnectionConfig) error {
+	dbInterface, err := cm.providerManager.CreateConnection(config)
+	if err != nil {
+		return fmt.Errorf("connection test failed, nothing was changed: %w", err)
+	}
+
+	previous, err := cm.loadPrevious()
+	if err != nil {
+		dbInterface.Close()
+		return err
+	}
+	kept := *old
+	previous[old.Name] = &kept
+	if err := cm.savePrevious(previous); err != nil {
+		dbInterface.Close()
+		return fmt.Errorf("failed to keep the previous version: %w", err)
+	}
+
+	updated := *config
+	updated.LastUsed = old.LastUsed
+	cm.configs[old.Name] = &updated
+	if conn, exists := cm.connections[old.Name]; exists {
+		conn.Close()
+	}
+	cm.connections[old.Name] = newConnection(dbInterface, old.Name, &updated)
+	return cm.saveConnections()
+}
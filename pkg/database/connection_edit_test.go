@@ -0,0 +1,53 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionManager_UpdateConnectionAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.db")
+	second := filepath.Join(dir, "second.db")
+	cm := newTestConnectionManager(t, map[string]*dbinterfaces.ConnectionConfig{
+		"local": {Name: "local", Type: "sqlite", Database: first, LastUsed: "2026-01-02T03:04:05Z"},
+	})
+
+	require.NoError(t, cm.UpdateConnection(&dbinterfaces.ConnectionConfig{Name: "local", Type: "sqlite", Database: second, Description: "edited"}))
+	assert.Equal(t, second, cm.configs["local"].Database)
+	assert.Equal(t, "edited", cm.configs["local"].Description)
+	assert.Equal(t, "2026-01-02T03:04:05Z", cm.configs["local"].LastUsed, "the last use survives an edit")
+	require.Contains(t, cm.connections, "local", "the tested connection is kept open")
+
+	require.NoError(t, cm.UndoConnectionEdit("local"))
+	assert.Equal(t, first, cm.configs["local"].Database)
+	assert.Empty(t, cm.configs["local"].Description)
+
+	// Undoing again restores the edit
+	require.NoError(t, cm.UndoConnectionEdit("local"))
+	assert.Equal(t, second, cm.configs["local"].Database)
+	for _, conn := range cm.connections {
+		conn.Close()
+	}
+}
+
+func TestConnectionManager_UpdateConnectionRejected(t *testing.T) {
+	cm := newTestConnectionManager(t, map[string]*dbinterfaces.ConnectionConfig{
+		"local": {Name: "local", Type: "sqlite", Database: filepath.Join(t.TempDir(), "app.db")},
+		"short": {Name: "short", AliasOf: "local"},
+	})
+
+	err := cm.UpdateConnection(&dbinterfaces.ConnectionConfig{Name: "local", Type: "sqlite"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nothing was changed")
+	assert.NotEmpty(t, cm.configs["local"].Database)
+
+	assert.Error(t, cm.UpdateConnection(&dbinterfaces.ConnectionConfig{Name: "short", Type: "sqlite", Database: "x.db"}), "aliases are edited through their connection")
+	assert.Error(t, cm.UpdateConnection(&dbinterfaces.ConnectionConfig{Name: "missing", Type: "sqlite", Database: "x.db"}))
+	assert.Error(t, cm.UndoConnectionEdit("local"), "nothing to undo before the first edit")
+}
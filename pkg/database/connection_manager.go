@@ -7,10 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"dbsage/pkg/dbinterfaces"
+	"dbsage/pkg/secrets"
 )
 
 // ConnectionManager manages multiple database connections
@@ -21,6 +23,7 @@ type ConnectionManager struct {
 	current         string
 	mu              sync.RWMutex
 	configFile      string
+	credentials     *credentialStore // Encrypts saved passwords, nil to save them as they are
 }
 
 // Ensure ConnectionManager implements ConnectionManagerInterface
@@ -29,13 +32,18 @@ var _ dbinterfaces.ConnectionManagerInterface = (*ConnectionManager)(nil)
 // NewConnectionManager creates a new connection manager
 func NewConnectionManager() dbinterfaces.ConnectionManagerInterface {
 	homeDir, _ := os.UserHomeDir()
-	configFile := filepath.Join(homeDir, ".dbsage", "connections.json")
+	return NewConnectionManagerAt(filepath.Join(homeDir, ".dbsage", "connections.json"))
+}
 
+// NewConnectionManagerAt creates a connection manager keeping its connections
+// in configFile instead of the user's connections file
+func NewConnectionManagerAt(configFile string) dbinterfaces.ConnectionManagerInterface {
 	cm := &ConnectionManager{
 		connections:     make(map[string]dbinterfaces.DatabaseInterface),
 		configs:         make(map[string]*dbinterfaces.ConnectionConfig),
 		providerManager: NewProviderManager(),
 		configFile:      configFile,
+		credentials:     newCredentialStore(configFile, secrets.SystemKeyring()),
 	}
 
 	// Load existing connections
@@ -47,19 +55,24 @@ func NewConnectionManager() dbinterfaces.ConnectionManagerInterface {
 	return cm
 }
 
-// AddConnection adds a new database connection
+// AddConnection adds a new database connection. A config with AliasOf set
+// adds an alias that shares the configuration and connection of its target.
 func (cm *ConnectionManager) AddConnection(config *dbinterfaces.ConnectionConfig) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if config.AliasOf != "" {
+		return cm.addAlias(config)
+	}
+
 	// Create connection using the provider manager
-	dbInterface, err := cm.providerManager.CreateConnection(config)
+	conn, err := cm.providerManager.OpenConnection(config)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Store connection
-	cm.connections[config.Name] = dbInterface
+	cm.connections[config.Name] = conn
 	cm.configs[config.Name] = config
 
 	// Set as current if it's the first connection
@@ -73,13 +86,61 @@ func (cm *ConnectionManager) AddConnection(config *dbinterfaces.ConnectionConfig
 	return cm.saveConnections()
 }
 
+// addAlias stores an alias config after validating its target
+func (cm *ConnectionManager) addAlias(config *dbinterfaces.ConnectionConfig) error {
+	if _, exists := cm.configs[config.Name]; exists {
+		return fmt.Errorf("connection '%s' already exists", config.Name)
+	}
+
+	target, exists := cm.configs[config.AliasOf]
+	if !exists {
+		return fmt.Errorf("connection '%s' not found", config.AliasOf)
+	}
+	if target.AliasOf != "" {
+		return fmt.Errorf("'%s' is itself an alias of '%s'; alias the original connection instead", config.AliasOf, target.AliasOf)
+	}
+
+	cm.configs[config.Name] = &dbinterfaces.ConnectionConfig{
+		Name:        config.Name,
+		Description: config.Description,
+		AliasOf:     config.AliasOf,
+	}
+	return cm.saveConnections()
+}
+
+// connectionKey returns the name under which the connection for name is stored,
+// which is the target connection for aliases
+func (cm *ConnectionManager) connectionKey(name string) string {
+	if config, exists := cm.configs[name]; exists && config.AliasOf != "" {
+		return config.AliasOf
+	}
+	return name
+}
+
+// aliasesOf returns the sorted names of aliases referring to name
+func (cm *ConnectionManager) aliasesOf(name string) []string {
+	var aliases []string
+	for aliasName, config := range cm.configs {
+		if config.AliasOf == name {
+			aliases = append(aliases, aliasName)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
 // RemoveConnection removes a database connection
 func (cm *ConnectionManager) RemoveConnection(name string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	// Close connection if exists
-	if conn, exists := cm.connections[name]; exists {
+	if aliases := cm.aliasesOf(name); len(aliases) > 0 {
+		return fmt.Errorf("connection '%s' is referenced by aliases: %s; remove them first", name, strings.Join(aliases, ", "))
+	}
+
+	// Close connection if exists; aliases share their target's connection
+	isAlias := cm.connectionKey(name) != name
+	if conn, exists := cm.connections[name]; exists && !isAlias {
 		conn.Close()
 		delete(cm.connections, name)
 	}
@@ -99,18 +160,66 @@ func (cm *ConnectionManager) RemoveConnection(name string) error {
 	return cm.saveConnections()
 }
 
-// ListConnections returns all connection configurations
+// ListConnections returns all connection configurations. Aliases are returned
+// with the connection details of their target and AliasOf set.
 func (cm *ConnectionManager) ListConnections() map[string]*dbinterfaces.ConnectionConfig {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
 	result := make(map[string]*dbinterfaces.ConnectionConfig)
 	for name, config := range cm.configs {
+		if target, exists := cm.configs[config.AliasOf]; exists && config.AliasOf != "" {
+			resolved := *target
+			resolved.Name = name
+			resolved.AliasOf = config.AliasOf
+			resolved.LastUsed = config.LastUsed
+			if config.Description != "" {
+				resolved.Description = config.Description
+			}
+			result[name] = &resolved
+			continue
+		}
 		result[name] = config
 	}
 	return result
 }
 
+// FindMatchingConnection returns the name of a connection that points to the same
+// database (type, host, port, database and user) as config, or "" if there is none.
+// Aliases are ignored so the original connection is reported.
+func FindMatchingConnection(connections map[string]*dbinterfaces.ConnectionConfig, config *dbinterfaces.ConnectionConfig) string {
+	names := make([]string, 0, len(connections))
+	for name := range connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		existing := connections[name]
+		if existing == nil || existing.AliasOf != "" || name == config.Name {
+			continue
+		}
+		if strings.EqualFold(normalizeType(existing.Type), normalizeType(config.Type)) &&
+			strings.EqualFold(existing.Host, config.Host) &&
+			existing.Port == config.Port &&
+			existing.Database == config.Database &&
+			existing.Username == config.Username {
+			return name
+		}
+	}
+	return ""
+}
+
+// normalizeType maps database type spellings to a canonical name
+func normalizeType(dbType string) string {
+	switch strings.ToLower(dbType) {
+	case "", "postgres", "postgresql":
+		return "postgresql"
+	default:
+		return strings.ToLower(dbType)
+	}
+}
+
 // GetCurrentConnection returns the current active connection
 func (cm *ConnectionManager) GetCurrentConnection() (dbinterfaces.DatabaseInterface, string, error) {
 	cm.mu.RLock()
@@ -120,7 +229,7 @@ func (cm *ConnectionManager) GetCurrentConnection() (dbinterfaces.DatabaseInterf
 		return nil, "", fmt.Errorf("no active database connection")
 	}
 
-	conn, exists := cm.connections[cm.current]
+	conn, exists := cm.connections[cm.connectionKey(cm.current)]
 	if !exists {
 		return nil, "", fmt.Errorf("current connection '%s' not found", cm.current)
 	}
@@ -142,30 +251,33 @@ func (cm *ConnectionManager) SwitchConnection(name string) error {
 		return fmt.Errorf("connection '%s' not found", name)
 	}
 
+	// Aliases connect through their target connection
+	key := cm.connectionKey(name)
+	config, exists := cm.configs[key]
+	if !exists {
+		return fmt.Errorf("alias '%s' refers to missing connection '%s'", name, key)
+	}
+
 	// Reconnect if connection doesn't exist or check health if it exists
-	if conn, exists := cm.connections[name]; !exists {
-		config := cm.configs[name]
-		dbInterface, err := cm.providerManager.CreateConnection(config)
+	if conn, exists := cm.connections[key]; !exists {
+		conn, err := cm.providerManager.OpenConnection(config)
 		if err != nil {
 			return fmt.Errorf("failed to reconnect to database '%s': %w", name, err)
 		}
-		cm.connections[name] = dbInterface
+		cm.connections[key] = conn
 	} else {
 		// Check existing connection health
 		if err := conn.CheckConnection(); err != nil {
-			// Connection is unhealthy, try to reconnect
-			config := cm.configs[name]
-
-			// Close the unhealthy connection
+			// Connection is unhealthy, close it and reconnect
 			conn.Close()
-			delete(cm.connections, name)
+			delete(cm.connections, key)
 
 			// Create new connection
-			dbInterface, err := cm.providerManager.CreateConnection(config)
+			conn, err := cm.providerManager.OpenConnection(config)
 			if err != nil {
 				return fmt.Errorf("failed to reconnect to database '%s' after health check failure: %w", name, err)
 			}
-			cm.connections[name] = dbInterface
+			cm.connections[key] = conn
 		}
 	}
 
@@ -183,6 +295,69 @@ func (cm *ConnectionManager) SwitchConnection(name string) error {
 	return nil
 }
 
+// IdleConnections returns the sorted names of the open connections other than
+// the current one, for the health monitor. Aliases share their target's
+// connection and are not returned.
+func (cm *ConnectionManager) IdleConnections() []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	current := ""
+	if cm.current != "" {
+		current = cm.connectionKey(cm.current)
+	}
+	var names []string
+	for name := range cm.connections {
+		if name != current {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PingConnection checks that an open connection still works
+func (cm *ConnectionManager) PingConnection(name string) error {
+	cm.mu.RLock()
+	conn, exists := cm.connections[cm.connectionKey(name)]
+	cm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("connection '%s' is not open", name)
+	}
+	return conn.CheckConnection()
+}
+
+// Reconnect replaces an open connection with a new one. The old connection is
+// kept when the new one cannot be opened, so a later attempt can retry.
+func (cm *ConnectionManager) Reconnect(name string) error {
+	cm.mu.RLock()
+	key := cm.connectionKey(name)
+	config, exists := cm.configs[key]
+	cm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("connection '%s' not found", name)
+	}
+
+	// Connecting can take a while, so it happens outside the lock
+	conn, err := cm.providerManager.OpenConnection(config)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to database '%s': %w", name, err)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, exists := cm.configs[key]; !exists {
+		// Removed while connecting
+		conn.Close()
+		return fmt.Errorf("connection '%s' not found", name)
+	}
+	if old, exists := cm.connections[key]; exists {
+		old.Close()
+	}
+	cm.connections[key] = conn
+	return nil
+}
+
 // Close closes all connections
 func (cm *ConnectionManager) Close() error {
 	cm.mu.Lock()
@@ -221,6 +396,16 @@ func (cm *ConnectionManager) loadConnections() error {
 	// Store configs (don't auto-connect yet)
 	cm.configs = configs
 
+	// Decrypt the saved passwords, and encrypt those still saved in plaintext
+	migrate, err := cm.credentials.open(configs)
+	if err != nil {
+		log.Printf("Warning: failed to decrypt saved passwords: %v", err)
+	} else if migrate {
+		if err := cm.saveConnections(); err != nil {
+			log.Printf("Warning: failed to encrypt saved passwords: %v", err)
+		}
+	}
+
 	// Set current to the most recently used connection if available
 	if len(configs) > 0 && cm.current == "" {
 		lastUsedName := cm.GetLastUsedConnection()
@@ -246,14 +431,19 @@ func (cm *ConnectionManager) saveConnections() error {
 		return err
 	}
 
+	configs, err := cm.credentials.seal(cm.configs)
+	if err != nil {
+		return err
+	}
+
 	// Marshal to JSON
-	data, err := json.MarshalIndent(cm.configs, "", "  ")
+	data, err := json.MarshalIndent(configs, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	// Write to file
-	return os.WriteFile(cm.configFile, data, 0644)
+	// Write to file, readable by the user only as it may hold passwords
+	return os.WriteFile(cm.configFile, data, 0600)
 }
 
 // GetConnectionStatus returns status information about connections
@@ -263,7 +453,7 @@ func (cm *ConnectionManager) GetConnectionStatus() map[string]string {
 
 	status := make(map[string]string)
 	for name := range cm.configs {
-		if conn, exists := cm.connections[name]; exists {
+		if conn, exists := cm.connections[cm.connectionKey(name)]; exists {
 			// Check connection health
 			if err := conn.CheckConnection(); err != nil {
 				status[name] = "unhealthy"
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConnectionManager(t *testing.T, configs map[string]*dbinterfaces.ConnectionConfig) *ConnectionManager {
+	return &ConnectionManager{
+		connections:     make(map[string]dbinterfaces.DatabaseInterface),
+		configs:         configs,
+		providerManager: NewProviderManager(),
+		configFile:      filepath.Join(t.TempDir(), "connections.json"),
+	}
+}
+
+func TestConnectionManager_AddAlias(t *testing.T) {
+	cm := newTestConnectionManager(t, map[string]*dbinterfaces.ConnectionConfig{
+		"main": {Name: "main", Type: "postgresql", Host: "db.internal", Port: 5432, Database: "app", Username: "app"},
+	})
+
+	require.NoError(t, cm.AddConnection(&dbinterfaces.ConnectionConfig{Name: "prod", AliasOf: "main"}))
+
+	connections := cm.ListConnections()
+	require.Contains(t, connections, "prod")
+	assert.Equal(t, "main", connections["prod"].AliasOf)
+	assert.Equal(t, "db.internal", connections["prod"].Host)
+	assert.Equal(t, "prod", connections["prod"].Name)
+	assert.Empty(t, cm.configs["prod"].Host, "alias config should not duplicate connection details")
+
+	// Aliases of aliases and unknown targets are rejected
+	assert.Error(t, cm.AddConnection(&dbinterfaces.ConnectionConfig{Name: "p2", AliasOf: "prod"}))
+	assert.Error(t, cm.AddConnection(&dbinterfaces.ConnectionConfig{Name: "p3", AliasOf: "missing"}))
+	assert.Error(t, cm.AddConnection(&dbinterfaces.ConnectionConfig{Name: "prod", AliasOf: "main"}))
+}
+
+func TestConnectionManager_RemoveAliasedConnection(t *testing.T) {
+	cm := newTestConnectionManager(t, map[string]*dbinterfaces.ConnectionConfig{
+		"main": {Name: "main", Type: "postgresql", Host: "db.internal", Port: 5432},
+		"prod": {Name: "prod", AliasOf: "main"},
+	})
+
+	assert.Error(t, cm.RemoveConnection("main"))
+	require.NoError(t, cm.RemoveConnection("prod"))
+	require.NoError(t, cm.RemoveConnection("main"))
+	assert.Empty(t, cm.ListConnections())
+}
+
+func TestFindMatchingConnection(t *testing.T) {
+	connections := map[string]*dbinterfaces.ConnectionConfig{
+		"main":  {Name: "main", Type: "postgres", Host: "DB.internal", Port: 5432, Database: "app", Username: "app"},
+		"alias": {Name: "alias", Type: "postgresql", Host: "db.internal", Port: 5432, Database: "app", Username: "app", AliasOf: "main"},
+		"other": {Name: "other", Type: "postgresql", Host: "db.internal", Port: 5432, Database: "reports", Username: "app"},
+	}
+
+	match := FindMatchingConnection(connections, &dbinterfaces.ConnectionConfig{
+		Name: "new", Type: "postgresql", Host: "db.internal", Port: 5432, Database: "app", Username: "app",
+	})
+	assert.Equal(t, "main", match)
+
+	match = FindMatchingConnection(connections, &dbinterfaces.ConnectionConfig{
+		Name: "new", Type: "postgresql", Host: "db.internal", Port: 5432, Database: "app", Username: "readonly",
+	})
+	assert.Empty(t, match)
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+	"dbsage/pkg/secrets"
+)
+
+// Sources of the key that encrypts the passwords in connections.json, chosen
+// with DBSAGE_CREDENTIAL_KEY. The default, auto, uses a passphrase when
+// DBSAGE_MASTER_PASSPHRASE is set, otherwise the system keyring when there is
+// one, otherwise stores passwords in plaintext.
+const (
+	KeySourceAuto       = "auto"
+	KeySourceKeyring    = "keyring"
+	KeySourcePassphrase = "passphrase"
+	KeySourceOff        = "off"
+)
+
+// credentialCheck is encrypted into the key file, so a wrong passphrase or
+// keyring entry is reported as such rather than as corrupted passwords
+const credentialCheck = "dbsage credentials"
+
+// keyringTimeout bounds a keyring access, long enough for an unlock prompt
+const keyringTimeout = time.Minute
+
+// keyFile describes the key the passwords are encrypted with. It never holds
+// the key itself.
+type keyFile struct {
+	Source string `json:"source"`
+	Salt   string `json:"salt,omitempty"` // Base64 salt of a passphrase key
+	Check  string `json:"check"`          // credentialCheck encrypted with the key
+}
+
+// credentialStore encrypts the passwords of saved connections with AES-GCM,
+// using a key kept in the system keyring or derived from a master passphrase
+type credentialStore struct {
+	file    string // Key file next to the connections file
+	account string // Keyring account of the key, the connections file path
+	keyring secrets.Keyring
+
+	cipher *secrets.Cipher // Loaded key, nil until a password needs it
+	source string          // Source of the loaded key
+	locked error           // Why stored passwords could not be decrypted
+}
+
+// newCredentialStore creates the credential store of a connections file
+func newCredentialStore(configFile string, keyring secrets.Keyring) *credentialStore {
+	account, err := filepath.Abs(configFile)
+	if err != nil {
+		account = configFile
+	}
+	return &credentialStore{
+		file:    filepath.Join(filepath.Dir(configFile), "credentials.json"),
+		account: account,
+		keyring: keyring,
+	}
+}
+
+// wantedSource returns the key source new passwords are encrypted with
+func (s *credentialStore) wantedSource() (string, error) {
+	source := strings.ToLower(strings.TrimSpace(os.Getenv("DBSAGE_CREDENTIAL_KEY")))
+	switch source {
+	case "", KeySourceAuto:
+		if os.Getenv("DBSAGE_MASTER_PASSPHRASE") != "" {
+			return KeySourcePassphrase, nil
+		}
+		if s.keyring != nil && s.keyring.Available() {
+			return KeySourceKeyring, nil
+		}
+		return KeySourceOff, nil
+	case KeySourceKeyring, KeySourcePassphrase, KeySourceOff:
+		return source, nil
+	default:
+		return "", fmt.Errorf("invalid DBSAGE_CREDENTIAL_KEY %q: expected auto, keyring, passphrase or off", source)
+	}
+}
+
+// open decrypts the passwords of loaded connections in place. It reports
+// whether the connections should be saved again to migrate them: passwords
+// stored in plaintext get encrypted, and passwords encrypted with another key
+// source get re-encrypted. Passwords that cannot be decrypted are left
+// encrypted, and saving is refused until the key is available.
+func (s *credentialStore) open(configs map[string]*dbinterfaces.ConnectionConfig) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	plaintext, encrypted := false, false
+	for _, config := range configs {
+		if config.Password == "" {
+			continue
+		}
+		if secrets.IsEncrypted(config.Password) {
+			encrypted = true
+		} else {
+			plaintext = true
+		}
+	}
+
+	if err := s.decrypt(configs); err != nil {
+		s.locked = err
+		return false, err
+	}
+	wanted, err := s.wantedSource()
+	if err != nil {
+		return false, err
+	}
+	return (plaintext && wanted != KeySourceOff) || (encrypted && wanted != s.source), nil
+}
+
+// decrypt decrypts the encrypted passwords of configs in place
+func (s *credentialStore) decrypt(configs map[string]*dbinterfaces.ConnectionConfig) error {
+	for name, config := range configs {
+		if !secrets.IsEncrypted(config.Password) {
+			continue
+		}
+		if s.cipher == nil {
+			if err := s.loadKey(); err != nil {
+				return err
+			}
+		}
+		password, err := s.cipher.Decrypt(config.Password)
+		if err != nil {
+			return fmt.Errorf("password of connection '%s': %w", name, err)
+		}
+		config.Password = password
+	}
+	return nil
+}
+
+// seal returns copies of configs whose passwords are encrypted with the
+// wanted key source, creating the key the first time a password needs it
+func (s *credentialStore) seal(configs map[string]*dbinterfaces.ConnectionConfig) (map[string]*dbinterfaces.ConnectionConfig, error) {
+	if s == nil {
+		return configs, nil
+	}
+	if s.locked != nil {
+		return nil, fmt.Errorf("stored passwords are encrypted and could not be decrypted, so connections are not saved: %w", s.locked)
+	}
+	wanted, err := s.wantedSource()
+	if err != nil {
+		return nil, err
+	}
+	if wanted == KeySourceOff {
+		return configs, nil
+	}
+
+	sealed := make(map[string]*dbinterfaces.ConnectionConfig, len(configs))
+	for name, config := range configs {
+		if config.Password == "" || secrets.IsEncrypted(config.Password) {
+			sealed[name] = config
+			continue
+		}
+		if s.cipher == nil || s.source != wanted {
+			if err := s.createKey(wanted); err != nil {
+				return nil, fmt.Errorf("failed to encrypt passwords (set DBSAGE_CREDENTIAL_KEY=off to store them in plaintext): %w", err)
+			}
+		}
+		password, err := s.cipher.Encrypt(config.Password)
+		if err != nil {
+			return nil, err
+		}
+		copied := *config
+		copied.Password = password
+		sealed[name] = &copied
+	}
+	return sealed, nil
+}
+
+// loadKey loads the key described by the key file
+func (s *credentialStore) loadKey() error {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("passwords are encrypted but their key file could not be read: %w", err)
+	}
+	var meta keyFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.file, err)
+	}
+
+	var key []byte
+	switch meta.Source {
+	case KeySourceKeyring:
+		if key, err = s.keyringKey(); err != nil {
+			return err
+		}
+	case KeySourcePassphrase:
+		salt, err := base64.StdEncoding.DecodeString(meta.Salt)
+		if err != nil {
+			return fmt.Errorf("invalid salt in %s: %w", s.file, err)
+		}
+		if key, err = passphraseKey(salt); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown key source %q in %s", meta.Source, s.file)
+	}
+
+	cipher, err := secrets.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	if check, err := cipher.Decrypt(meta.Check); err != nil || check != credentialCheck {
+		if meta.Source == KeySourcePassphrase {
+			return fmt.Errorf("wrong DBSAGE_MASTER_PASSPHRASE: it does not decrypt the stored passwords")
+		}
+		return fmt.Errorf("the key in the %s does not decrypt the stored passwords", s.keyring.Name())
+	}
+	s.cipher, s.source = cipher, meta.Source
+	return nil
+}
+
+// createKey creates a key from source and describes it in the key file
+func (s *credentialStore) createKey(source string) error {
+	meta := keyFile{Source: source}
+	var key []byte
+	switch source {
+	case KeySourceKeyring:
+		if s.keyring == nil || !s.keyring.Available() {
+			return fmt.Errorf("no system keyring is available")
+		}
+		var err error
+		if key, err = secrets.NewKey(); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+		defer cancel()
+		if err := s.keyring.Set(ctx, s.account, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return err
+		}
+	case KeySourcePassphrase:
+		salt, err := secrets.NewSalt()
+		if err != nil {
+			return err
+		}
+		if key, err = passphraseKey(salt); err != nil {
+			return err
+		}
+		meta.Salt = base64.StdEncoding.EncodeToString(salt)
+	default:
+		return fmt.Errorf("unknown key source %q", source)
+	}
+
+	cipher, err := secrets.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	if meta.Check, err = cipher.Encrypt(credentialCheck); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.file, data, 0600); err != nil {
+		return err
+	}
+	s.cipher, s.source = cipher, source
+	return nil
+}
+
+// keyringKey reads the key of the connections file from the keyring
+func (s *credentialStore) keyringKey() ([]byte, error) {
+	if s.keyring == nil || !s.keyring.Available() {
+		return nil, fmt.Errorf("passwords are encrypted with a key in the system keyring, but no keyring is available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	encoded, err := s.keyring.Get(ctx, s.account)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in the %s: %w", s.keyring.Name(), err)
+	}
+	return key, nil
+}
+
+// passphraseKey derives the key from DBSAGE_MASTER_PASSPHRASE
+func passphraseKey(salt []byte) ([]byte, error) {
+	passphrase := os.Getenv("DBSAGE_MASTER_PASSPHRASE")
+	if passphrase == "" {
+		return nil, fmt.Errorf("passwords are encrypted with a master passphrase: set DBSAGE_MASTER_PASSPHRASE")
+	}
+	return secrets.DeriveKey(passphrase, salt)
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+	"dbsage/pkg/secrets"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKeyring is a keyring kept in memory
+type memoryKeyring struct {
+	secrets map[string]string
+}
+
+func (k *memoryKeyring) Name() string    { return "test keyring" }
+func (k *memoryKeyring) Available() bool { return true }
+
+func (k *memoryKeyring) Get(ctx context.Context, account string) (string, error) {
+	secret, ok := k.secrets[account]
+	if !ok {
+		return "", fmt.Errorf("%s is not in the keyring", account)
+	}
+	return secret, nil
+}
+
+func (k *memoryKeyring) Set(ctx context.Context, account, secret string) error {
+	k.secrets[account] = secret
+	return nil
+}
+
+// loadTestConnections loads the connections file like NewConnectionManagerAt
+func loadTestConnections(t *testing.T, configFile string, keyring secrets.Keyring) *ConnectionManager {
+	cm := &ConnectionManager{
+		connections:     make(map[string]dbinterfaces.DatabaseInterface),
+		configs:         make(map[string]*dbinterfaces.ConnectionConfig),
+		providerManager: NewProviderManager(),
+		configFile:      configFile,
+		credentials:     newCredentialStore(configFile, keyring),
+	}
+	require.NoError(t, cm.loadConnections())
+	return cm
+}
+
+func TestCredentials_MigratesPlaintextToKeyring(t *testing.T) {
+	t.Setenv("DBSAGE_CREDENTIAL_KEY", "")
+	t.Setenv("DBSAGE_MASTER_PASSPHRASE", "")
+	configFile := filepath.Join(t.TempDir(), "connections.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"main": {"name": "main", "type": "postgresql", "password": "s3cret"}}`), 0644))
+	keyring := &memoryKeyring{secrets: map[string]string{}}
+
+	cm := loadTestConnections(t, configFile, keyring)
+	assert.Equal(t, "s3cret", cm.configs["main"].Password, "passwords stay usable in memory")
+
+	data, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+	assert.Contains(t, string(data), secrets.EncryptedPrefix)
+	assert.Len(t, keyring.secrets, 1)
+
+	reloaded := loadTestConnections(t, configFile, keyring)
+	assert.Equal(t, "s3cret", reloaded.configs["main"].Password)
+
+	// Without the key the password stays encrypted, connecting reports why
+	// and saving is refused rather than losing it
+	locked := loadTestConnections(t, configFile, &memoryKeyring{secrets: map[string]string{}})
+	assert.True(t, secrets.IsEncrypted(locked.configs["main"].Password))
+	_, err = ResolveConfig(locked.configs["main"])
+	assert.ErrorContains(t, err, "could not be decrypted")
+	assert.Error(t, locked.saveConnections())
+
+	// Switching encryption off decrypts the file again
+	t.Setenv("DBSAGE_CREDENTIAL_KEY", "off")
+	loadTestConnections(t, configFile, keyring)
+	data, _ = os.ReadFile(configFile)
+	assert.Contains(t, string(data), `"password": "s3cret"`)
+}
+
+func TestCredentials_Passphrase(t *testing.T) {
+	t.Setenv("DBSAGE_CREDENTIAL_KEY", "")
+	t.Setenv("DBSAGE_MASTER_PASSPHRASE", "correct horse")
+	configFile := filepath.Join(t.TempDir(), "connections.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"main": {"name": "main", "type": "mysql", "password": "s3cret"}}`), 0644))
+
+	loadTestConnections(t, configFile, nil)
+	data, _ := os.ReadFile(configFile)
+	assert.NotContains(t, string(data), "s3cret")
+
+	reloaded := loadTestConnections(t, configFile, nil)
+	assert.Equal(t, "s3cret", reloaded.configs["main"].Password)
+
+	t.Setenv("DBSAGE_MASTER_PASSPHRASE", "wrong horse")
+	locked := loadTestConnections(t, configFile, nil)
+	assert.True(t, secrets.IsEncrypted(locked.configs["main"].Password))
+	assert.ErrorContains(t, locked.credentials.locked, "wrong DBSAGE_MASTER_PASSPHRASE")
+}
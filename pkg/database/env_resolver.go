@@ -0,0 +1,130 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"dbsage/pkg/dbinterfaces"
+	"dbsage/pkg/secrets"
+)
+
+// EnvFileName is the project-local file read for variables that are not set
+// in the environment
+const EnvFileName = ".dbsage.env"
+
+// envReference matches a ${NAME} reference in a connection config field
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// HasEnvReferences reports whether a config references environment variables
+func HasEnvReferences(config *dbinterfaces.ConnectionConfig) bool {
+	for _, field := range []string{config.Host, config.Database, config.Username, config.Password, config.SSLMode} {
+		if envReference.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveConfig returns a copy of a config with the ${NAME} references of its
+// host, database, username, password and SSL mode replaced, and the password
+// fetched from its secret backend when PasswordRef is set. Variables set in
+// the environment win over those in .dbsage.env in the working directory. The
+// config itself is left untouched, so the references are what gets saved.
+func ResolveConfig(config *dbinterfaces.ConnectionConfig) (*dbinterfaces.ConnectionConfig, error) {
+	if secrets.IsEncrypted(config.Password) {
+		return nil, fmt.Errorf("the saved password of connection '%s' could not be decrypted: set DBSAGE_MASTER_PASSPHRASE if it was encrypted with a passphrase, or unlock the system keyring, then restart", config.Name)
+	}
+	if !HasEnvReferences(config) && config.PasswordRef == "" {
+		return config, nil
+	}
+
+	fileVars, err := LoadEnvFile(EnvFileName)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	expand := func(value string) string {
+		return envReference.ReplaceAllStringFunc(value, func(ref string) string {
+			name := envReference.FindStringSubmatch(ref)[1]
+			if v, ok := os.LookupEnv(name); ok {
+				return v
+			}
+			if v, ok := fileVars[name]; ok {
+				return v
+			}
+			missing = append(missing, name)
+			return ref
+		})
+	}
+
+	resolved := *config
+	resolved.Host = expand(config.Host)
+	resolved.Database = expand(config.Database)
+	resolved.Username = expand(config.Username)
+	resolved.Password = expand(config.Password)
+	resolved.SSLMode = expand(config.SSLMode)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("connection '%s' references unset variables: %s (set them in the environment or in %s)",
+			config.Name, strings.Join(missing, ", "), EnvFileName)
+	}
+
+	if config.PasswordRef != "" {
+		password, err := secrets.Resolve(config.PasswordRef)
+		if err != nil {
+			return nil, fmt.Errorf("connection '%s': %w", config.Name, err)
+		}
+		resolved.Password = password
+	}
+	return &resolved, nil
+}
+
+// LoadEnvFile reads KEY=VALUE lines from a .env style file. Blank lines,
+// comments and an "export " prefix are allowed and values may be quoted. A
+// missing file yields no variables.
+func LoadEnvFile(path string) (map[string]string, error) {
+	vars := make(map[string]string)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return vars, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// escapeEnvReferences percent-encodes ${NAME} references so they survive URL
+// parsing and end up in the config as written
+func escapeEnvReferences(rawURL string) string {
+	return envReference.ReplaceAllStringFunc(rawURL, url.QueryEscape)
+}
@@ -0,0 +1,81 @@
+package files
+
+import (
+	"database/sql"
+	"fmt"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/database/sqlite"
+	"dbsage/pkg/dbinterfaces"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// FilesDatabase queries local CSV, TSV and Parquet files through an in-memory
+// SQLite database holding one table per file. Changes are never written back.
+type FilesDatabase struct {
+	*sqlite.SQLiteDatabase
+	tables []LoadedTable
+}
+
+// Ensure FilesDatabase implements DatabaseInterface
+var _ dbinterfaces.DatabaseInterface = (*FilesDatabase)(nil)
+
+// NewFilesDatabase loads the files matched by a comma-separated list of paths or globs
+func NewFilesDatabase(spec string) (*FilesDatabase, error) {
+	paths, err := ExpandPatterns(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// Every connection to :memory: is a separate database, so keep exactly one
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	tables, err := loadFiles(db, paths)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &FilesDatabase{
+		SQLiteDatabase: sqlite.NewSQLiteDatabaseFromDB(db),
+		tables:         tables,
+	}, nil
+}
+
+// BeginTx refuses transactions: one would hold the only connection to the
+// in-memory database, and changes are never written back to the files anyway
+func (f *FilesDatabase) BeginTx() (*sql.Tx, error) {
+	return nil, fmt.Errorf("files connections do not support transactions; changes are never written back to the files")
+}
+
+// Tables returns the tables loaded from files
+func (f *FilesDatabase) Tables() []LoadedTable {
+	return f.tables
+}
+
+// GetAllTables returns the tables, describing which file each was loaded from
+func (f *FilesDatabase) GetAllTables() ([]models.TableInfo, error) {
+	tables, err := f.SQLiteDatabase.GetAllTables()
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]LoadedTable, len(f.tables))
+	for _, table := range f.tables {
+		sources[table.Name] = table
+	}
+	for i := range tables {
+		if source, ok := sources[tables[i].TableName]; ok {
+			tables[i].Description = fmt.Sprintf("loaded from %s (%d rows)", source.Path, source.Rows)
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,120 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestIsFilePattern(t *testing.T) {
+	assert.True(t, IsFilePattern("./data/*.csv"))
+	assert.True(t, IsFilePattern("orders.TSV,users.csv"))
+	assert.True(t, IsFilePattern("files:./exports/*"))
+	assert.True(t, IsFilePattern("events.parquet"))
+	assert.False(t, IsFilePattern("postgres://user@host/db"))
+	assert.False(t, IsFilePattern("orders.csv,app.db"))
+	assert.False(t, IsFilePattern(""))
+}
+
+func TestExpandPatterns(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.csv", "id\n1\n")
+	b := writeFile(t, dir, "b.csv", "id\n2\n")
+
+	paths, err := ExpandPatterns(filepath.Join(dir, "*.csv") + "," + a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{a, b}, paths)
+
+	_, err = ExpandPatterns(filepath.Join(dir, "*.tsv"))
+	assert.ErrorContains(t, err, "no files match")
+}
+
+func TestInferTypes(t *testing.T) {
+	records := [][]string{
+		{"1", "1.5", "x", "", "7"},
+		{"2", "3", "4", "", ""},
+		{"", "-2e3", "y"},
+	}
+	assert.Equal(t, []string{typeInteger, typeReal, typeText, typeText, typeInteger}, inferTypes(records, 5))
+
+	// Leading zeros, plus signs and words ParseFloat accepts stay text
+	records = [][]string{{"02134", "+5", "007.5", "NaN", "0", "0.25"}}
+	assert.Equal(t, []string{typeText, typeText, typeText, typeText, typeInteger, typeReal}, inferTypes(records, 6))
+}
+
+func TestNewFilesDatabaseExtraFields(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "zips.csv", "zip,city\n02134,Boston\n10001,New York,NY,extra\n")
+
+	db, err := NewFilesDatabase(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := db.ExecuteSQL("SELECT * FROM zips ORDER BY city")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zip", "city", "column_3", "column_4"}, result.Columns)
+	assert.Equal(t, []interface{}{"02134", "Boston", nil, nil}, result.Rows[0])
+	assert.Equal(t, []interface{}{"10001", "New York", "NY", "extra"}, result.Rows[1])
+}
+
+func TestColumnAndTableNames(t *testing.T) {
+	assert.Equal(t, []string{"order_id", "total_eur", "column_3", "order_id_2", "c_2024"},
+		columnNames([]string{"Order ID", "Total (EUR)", "", "order-id", "2024"}))
+	assert.Equal(t, "sales_q1", tableNameForPath("/tmp/Sales Q1.csv"))
+	assert.Equal(t, "t_2024_orders", tableNameForPath("2024-orders.tsv"))
+}
+
+func TestNewFilesDatabase(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "orders.csv", "\ufeffid,customer,amount\n1,alice,9.5\n2,\"bob\",\"12\"\n3,carol,\n")
+	writeFile(t, dir, "customers.tsv", "name\tcountry\nalice\tNL\nbob\tDE\n")
+
+	db, err := NewFilesDatabase(filepath.Join(dir, "*.csv") + "," + filepath.Join(dir, "*.tsv"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, "sqlite", db.DatabaseType())
+
+	tables, err := db.GetAllTables()
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	assert.Equal(t, "customers", tables[0].TableName)
+	assert.Contains(t, tables[1].Description, "orders.csv (3 rows)")
+
+	columns, err := db.GetTableSchema("orders")
+	require.NoError(t, err)
+	require.Len(t, columns, 3)
+	assert.Equal(t, "id", columns[0].ColumnName)
+	assert.Equal(t, "INTEGER", columns[0].DataType)
+	assert.Equal(t, "REAL", columns[2].DataType)
+
+	result, err := db.ExecuteSQL("SELECT o.customer, c.country FROM orders o JOIN customers c ON c.name = o.customer ORDER BY o.id")
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+
+	result, err = db.ExecuteSQL("SELECT customer FROM orders WHERE amount IS NULL")
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+}
+
+func TestProviderValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.csv", "id\n1\n")
+
+	p := NewFilesProvider()
+	assert.Error(t, p.ValidateConfig(&dbinterfaces.ConnectionConfig{Type: "files"}))
+	assert.Error(t, p.ValidateConfig(&dbinterfaces.ConnectionConfig{Type: "files", Database: filepath.Join(dir, "*.tsv")}))
+	assert.NoError(t, p.ValidateConfig(&dbinterfaces.ConnectionConfig{Type: "files", Database: filepath.Join(dir, "*.csv")}))
+}
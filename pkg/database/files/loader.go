@@ -0,0 +1,415 @@
+package files
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Column types inferred from file contents
+const (
+	typeInteger = "INTEGER"
+	typeReal    = "REAL"
+	typeText    = "TEXT"
+)
+
+// LoadedTable describes a table created from a file
+type LoadedTable struct {
+	Name    string
+	Path    string
+	Columns []string
+	Types   []string
+	Rows    int
+}
+
+// IsFilePattern reports whether a connection target names local data files
+// rather than a database URL, e.g. ./data/*.csv or files:orders.tsv
+func IsFilePattern(target string) bool {
+	if strings.HasPrefix(target, "files:") {
+		return true
+	}
+	for _, pattern := range SplitPatterns(target) {
+		switch strings.ToLower(filepath.Ext(pattern)) {
+		case ".csv", ".tsv", ".parquet":
+		default:
+			return false
+		}
+	}
+	return target != ""
+}
+
+// SplitPatterns splits a comma-separated list of file paths or globs
+func SplitPatterns(spec string) []string {
+	spec = strings.TrimPrefix(spec, "files:")
+	var patterns []string
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			patterns = append(patterns, part)
+		}
+	}
+	return patterns
+}
+
+// ExpandPatterns resolves file paths and globs to the sorted list of files they match
+func ExpandPatterns(spec string) ([]string, error) {
+	patterns := SplitPatterns(spec)
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no file paths given")
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(expandHome(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", pattern)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				paths = append(paths, match)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// loadFiles creates one table per file in db and fills it with the file's rows
+func loadFiles(db *sql.DB, paths []string) ([]LoadedTable, error) {
+	used := make(map[string]bool)
+	var tables []LoadedTable
+	for _, path := range paths {
+		name := uniqueName(tableNameForPath(path), used)
+		table, err := loadFile(db, name, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		tables = append(tables, *table)
+	}
+	return tables, nil
+}
+
+// loadFile loads one data file into a new table
+func loadFile(db *sql.DB, name, path string) (*LoadedTable, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return loadDelimited(db, name, path, ',')
+	case ".tsv":
+		return loadDelimited(db, name, path, '\t')
+	case ".parquet":
+		return loadParquet(db, name, path)
+	}
+	return nil, fmt.Errorf("unsupported file type %s, expected .csv, .tsv or .parquet", filepath.Ext(path))
+}
+
+// openDelimited opens a delimited file and reads its header row
+func openDelimited(path string, comma rune) (*os.File, *csv.Reader, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	reader := csv.NewReader(f)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("file is empty, a header row is required")
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	header = append([]string(nil), header...)
+	if len(header) > 0 {
+		// Drop the byte order mark spreadsheet exports often start with
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	return f, reader, header, nil
+}
+
+// loadDelimited loads a delimited file into a new table. The file is read
+// twice, once to infer the column types and once to insert the rows, so it is
+// never held in memory. Fields beyond the header become extra columns.
+func loadDelimited(db *sql.DB, name, path string, comma rune) (*LoadedTable, error) {
+	f, reader, header, err := openDelimited(path, comma)
+	if err != nil {
+		return nil, err
+	}
+	var inferred columnTypes
+	inferred.add(make([]string, len(header)))
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		inferred.add(record)
+		rows++
+	}
+	f.Close()
+
+	// Rows with more fields than the header get unnamed columns
+	for len(header) < len(inferred.types) {
+		header = append(header, "")
+	}
+	table := &LoadedTable{
+		Name:    name,
+		Path:    path,
+		Columns: columnNames(header),
+		Types:   inferred.result(),
+		Rows:    rows,
+	}
+
+	f, reader, _, err = openDelimited(path, comma)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	next := func() ([]interface{}, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(table.Columns))
+		for i := range values {
+			values[i] = convertValue(fieldAt(record, i), table.Types[i])
+		}
+		return values, nil
+	}
+	if err := createTable(db, table, next); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// createTable creates the table and inserts the rows next returns, up to
+// io.EOF, in one transaction
+func createTable(db *sql.DB, table *LoadedTable, next func() ([]interface{}, error)) error {
+	defs := make([]string, len(table.Columns))
+	markers := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		defs[i] = quoteIdentifier(column) + " " + table.Types[i]
+		markers[i] = "?"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(table.Name), strings.Join(defs, ", "))); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", table.Name, err)
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdentifier(table.Name), strings.Join(markers, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for n := 1; ; n++ {
+		values, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return fmt.Errorf("failed to insert row %d: %w", n, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// columnTypes infers the narrowest type holding every non-empty value of
+// each column, one record at a time
+type columnTypes struct {
+	types []string // "" for a column with no value yet
+}
+
+// add widens the column types to hold the values of a record
+func (c *columnTypes) add(record []string) {
+	for len(c.types) < len(record) {
+		c.types = append(c.types, "")
+	}
+	for i, field := range record {
+		value := strings.TrimSpace(field)
+		if value == "" || c.types[i] == typeText {
+			continue
+		}
+		switch {
+		case isInteger(value):
+			if c.types[i] == "" {
+				c.types[i] = typeInteger
+			}
+		case isReal(value):
+			c.types[i] = typeReal
+		default:
+			c.types[i] = typeText
+		}
+	}
+}
+
+// result returns the inferred types, TEXT for columns without values
+func (c *columnTypes) result() []string {
+	types := make([]string, len(c.types))
+	for i, t := range c.types {
+		types[i] = t
+		if t == "" {
+			types[i] = typeText
+		}
+	}
+	return types
+}
+
+// inferTypes infers the types of the first columns of records
+func inferTypes(records [][]string, columns int) []string {
+	var inferred columnTypes
+	inferred.add(make([]string, columns))
+	for _, record := range records {
+		inferred.add(record)
+	}
+	return inferred.result()[:columns]
+}
+
+// isInteger reports whether a value is an integer written the way it would
+// be printed: leading zeros and plus signs make it text, so identifiers such
+// as zip codes survive unchanged
+func isInteger(value string) bool {
+	n, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && strconv.FormatInt(n, 10) == value
+}
+
+// isReal reports whether a value is a decimal number without a plus sign or
+// leading zeros. Words ParseFloat accepts, such as Inf or NaN, are text.
+func isReal(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return false
+	}
+	if strings.HasPrefix(value, "+") || strings.Trim(value, "0123456789.eE+-") != "" {
+		return false
+	}
+	digits := strings.TrimPrefix(value, "-")
+	return !(len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9')
+}
+
+// convertValue converts a field to the column's type; empty fields become NULL
+func convertValue(field, columnType string) interface{} {
+	trimmed := strings.TrimSpace(field)
+	if trimmed == "" {
+		return nil
+	}
+	switch columnType {
+	case typeInteger:
+		n, _ := strconv.ParseInt(trimmed, 10, 64)
+		return n
+	case typeReal:
+		f, _ := strconv.ParseFloat(trimmed, 64)
+		return f
+	default:
+		return field
+	}
+}
+
+// fieldAt returns a record's i-th field, or "" for short rows
+func fieldAt(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+// columnNames turns a header row into unique identifiers
+func columnNames(header []string) []string {
+	used := make(map[string]bool)
+	names := make([]string, len(header))
+	for i, title := range header {
+		name := sanitizeIdentifier(title)
+		switch {
+		case name == "":
+			name = fmt.Sprintf("column_%d", i+1)
+		case unicode.IsDigit(rune(name[0])):
+			name = "c_" + name
+		}
+		names[i] = uniqueName(name, used)
+	}
+	return names
+}
+
+// tableNameForPath derives a table name from a file's base name
+func tableNameForPath(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := sanitizeIdentifier(base)
+	if name == "" {
+		return "data"
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "t_" + name
+	}
+	return name
+}
+
+// sanitizeIdentifier lower-cases a name and replaces everything but letters,
+// digits and underscores, so it can be used without quoting
+func sanitizeIdentifier(name string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastUnderscore = false
+			continue
+		}
+		if !lastUnderscore {
+			b.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// uniqueName appends a numeric suffix until name is unused, then marks it used
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s_%d", name, n)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,634 @@
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Parquet files are read with a small decoder of their own, enough for the
+// flat tables data exports are: PLAIN and dictionary encoded columns in
+// uncompressed, Snappy or Gzip pages. Nested columns and other encodings or
+// codecs are refused with a hint to convert the file.
+
+// Physical types
+const (
+	parquetBoolean = iota
+	parquetInt32
+	parquetInt64
+	parquetInt96
+	parquetFloat
+	parquetDouble
+	parquetByteArray
+	parquetFixedLenByteArray
+)
+
+// Converted types, the annotations older writers use
+const (
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimeMillis      = 7
+	convertedTimeMicros      = 8
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+	convertedUint8           = 11
+	convertedUint16          = 12
+	convertedUint32          = 13
+	convertedUint64          = 14
+)
+
+// Logical types, the annotations of newer writers, by union field id
+const (
+	logicalDecimal   = 5
+	logicalDate      = 6
+	logicalTime      = 7
+	logicalTimestamp = 8
+	logicalUUID      = 14
+)
+
+// Page types and encodings
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingRLEDictionary   = 8
+)
+
+var parquetEncodings = map[int64]string{
+	1: "GROUP_VAR_INT", 4: "BIT_PACKED", 5: "DELTA_BINARY_PACKED", 6: "DELTA_LENGTH_BYTE_ARRAY",
+	7: "DELTA_BYTE_ARRAY", 9: "BYTE_STREAM_SPLIT",
+}
+
+var parquetCodecs = map[int64]string{3: "LZO", 4: "Brotli", 5: "LZ4", 6: "Zstd", 7: "LZ4"}
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// maxParquetFooter bounds the metadata read, so a corrupt length cannot
+// allocate the whole file
+const maxParquetFooter = 64 << 20
+
+// parquetColumn is a top-level column of a flat Parquet schema
+type parquetColumn struct {
+	name       string
+	physical   int64
+	typeLength int
+	optional   bool
+	converted  int64 // -1 when not annotated
+	logical    thriftFields
+	scale      int
+}
+
+// parquetFile is an open Parquet file and its footer
+type parquetFile struct {
+	r         io.ReaderAt
+	size      int64
+	columns   []parquetColumn
+	rowGroups []thriftFields
+	rows      int64
+}
+
+// loadParquet loads a Parquet file into a new table, one row group at a time
+func loadParquet(db *sql.DB, name, path string) (*LoadedTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pf, err := openParquet(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(pf.columns))
+	types := make([]string, len(pf.columns))
+	for i, col := range pf.columns {
+		names[i] = col.name
+		types[i] = col.sqlType()
+	}
+	table := &LoadedTable{Name: name, Path: path, Columns: columnNames(names), Types: types, Rows: int(pf.rows)}
+
+	group, row := -1, 0
+	var columns [][]interface{}
+	next := func() ([]interface{}, error) {
+		for columns == nil || row == len(columns[0]) {
+			if group++; group == len(pf.rowGroups) {
+				return nil, io.EOF
+			}
+			if columns, err = pf.readRowGroup(group); err != nil {
+				return nil, err
+			}
+			row = 0
+		}
+		values := make([]interface{}, len(columns))
+		for i := range columns {
+			values[i] = columns[i][row]
+		}
+		row++
+		return values, nil
+	}
+	if err := createTable(db, table, next); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// openParquet reads the footer of a Parquet file
+func openParquet(r io.ReaderAt, size int64) (*parquetFile, error) {
+	tail := make([]byte, 8)
+	if size < 12 {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return nil, err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+	length := int64(binary.LittleEndian.Uint32(tail))
+	if length > size-12 || length > maxParquetFooter {
+		return nil, fmt.Errorf("corrupt Parquet footer")
+	}
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-8-length); err != nil {
+		return nil, err
+	}
+	meta, err := (&compactReader{buf: footer}).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("corrupt Parquet footer: %w", err)
+	}
+
+	pf := &parquetFile{r: r, size: size, rowGroups: meta.structs(4), rows: meta.int(3, 0)}
+	schema := meta.structs(2)
+	if len(schema) < 2 {
+		return nil, fmt.Errorf("the Parquet file has no columns")
+	}
+	for _, element := range schema[1:] {
+		name := element.string(4)
+		if element.int(5, 0) > 0 || element.int(3, 0) == 2 {
+			return nil, fmt.Errorf("column %s is nested (a list, map or struct), which file connections cannot load; flatten the file or convert it to CSV", name)
+		}
+		col := parquetColumn{
+			name:       name,
+			physical:   element.int(1, 0),
+			typeLength: int(element.int(2, 0)),
+			optional:   element.int(3, 0) == 1,
+			converted:  element.int(6, -1),
+			logical:    element.child(10),
+			scale:      int(element.int(7, 0)),
+		}
+		if decimal := col.logical.child(logicalDecimal); decimal != nil {
+			col.scale = int(decimal.int(1, 0))
+		}
+		pf.columns = append(pf.columns, col)
+	}
+	if int64(len(pf.columns)) != schema[0].int(5, 0) {
+		return nil, fmt.Errorf("corrupt Parquet schema")
+	}
+	return pf, nil
+}
+
+// is reports whether the column carries a converted or logical annotation
+func (c parquetColumn) is(converted int64, logical int16) bool {
+	return c.converted == converted || c.logical.child(logical) != nil
+}
+
+// sqlType returns the SQLite type values of the column are stored as
+func (c parquetColumn) sqlType() string {
+	switch {
+	case c.is(convertedDecimal, logicalDecimal):
+		return typeReal
+	case c.is(convertedDate, logicalDate), c.is(convertedTimestampMillis, logicalTimestamp),
+		c.converted == convertedTimestampMicros, c.is(convertedTimeMillis, logicalTime), c.converted == convertedTimeMicros:
+		return typeText
+	}
+	switch c.physical {
+	case parquetBoolean, parquetInt32, parquetInt64:
+		return typeInteger
+	case parquetFloat, parquetDouble:
+		return typeReal
+	}
+	return typeText
+}
+
+// readRowGroup decodes every column of a row group
+func (pf *parquetFile) readRowGroup(index int) ([][]interface{}, error) {
+	group := pf.rowGroups[index]
+	chunks := group.structs(1)
+	if len(chunks) != len(pf.columns) {
+		return nil, fmt.Errorf("corrupt Parquet row group %d", index)
+	}
+	rows := group.int(3, 0)
+	columns := make([][]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		values, err := pf.readColumn(pf.columns[i], chunk.child(3))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", pf.columns[i].name, err)
+		}
+		if int64(len(values)) != rows {
+			return nil, fmt.Errorf("column %s has %d values in a row group of %d rows", pf.columns[i].name, len(values), rows)
+		}
+		columns[i] = values
+	}
+	return columns, nil
+}
+
+// readColumn decodes the pages of a column chunk
+func (pf *parquetFile) readColumn(col parquetColumn, meta thriftFields) ([]interface{}, error) {
+	if meta == nil {
+		return nil, fmt.Errorf("column chunk without metadata")
+	}
+	codec := meta.int(4, 0)
+	start := meta.int(9, 0)
+	if dict := meta.int(11, 0); dict > 0 && dict < start {
+		start = dict
+	}
+	length := meta.int(7, 0)
+	if start < 4 || length < 0 || start+length > pf.size {
+		return nil, fmt.Errorf("corrupt column chunk")
+	}
+	buf := make([]byte, length)
+	if _, err := pf.r.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+
+	total := meta.int(5, 0)
+	values := make([]interface{}, 0, total)
+	var dictionary []interface{}
+	reader := &compactReader{buf: buf}
+	for int64(len(values)) < total && reader.pos < len(buf) {
+		header, err := reader.readStruct()
+		if err != nil {
+			return nil, fmt.Errorf("corrupt page header: %w", err)
+		}
+		page, err := reader.bytes(uint64(header.int(3, 0)))
+		if err != nil {
+			return nil, fmt.Errorf("truncated page")
+		}
+		uncompressed := int(header.int(2, 0))
+
+		switch header.int(1, -1) {
+		case pageDictionary:
+			data, err := decompress(codec, page, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			if dictionary, err = decodePlain(col, data, int(header.child(7).int(1, 0))); err != nil {
+				return nil, err
+			}
+
+		case pageData:
+			h := header.child(5)
+			n := int(h.int(1, 0))
+			data, err := decompress(codec, page, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			var levels []uint32
+			if col.optional {
+				if len(data) < 4 {
+					return nil, fmt.Errorf("truncated page")
+				}
+				size := int(binary.LittleEndian.Uint32(data))
+				if size > len(data)-4 {
+					return nil, fmt.Errorf("truncated page")
+				}
+				if levels, err = decodeHybrid(data[4:4+size], 1, n); err != nil {
+					return nil, err
+				}
+				data = data[4+size:]
+			}
+			if values, err = appendPage(values, col, h.int(2, 0), data, n, levels, dictionary); err != nil {
+				return nil, err
+			}
+
+		case pageDataV2:
+			h := header.child(8)
+			n := int(h.int(1, 0))
+			defLength, repLength := h.int(5, 0), h.int(6, 0)
+			if defLength < 0 || repLength < 0 || defLength+repLength > int64(len(page)) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			var levels []uint32
+			if col.optional {
+				if levels, err = decodeHybrid(page[repLength:repLength+defLength], 1, n); err != nil {
+					return nil, err
+				}
+			}
+			data := page[repLength+defLength:]
+			if h.bool(7, true) {
+				if data, err = decompress(codec, data, uncompressed-int(repLength+defLength)); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = appendPage(values, col, h.int(4, 0), data, n, levels, dictionary); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+// appendPage decodes the values of a data page and appends them to values,
+// with nil where the definition levels mark a null
+func appendPage(values []interface{}, col parquetColumn, encoding int64, data []byte, n int, levels []uint32, dictionary []interface{}) ([]interface{}, error) {
+	present := n
+	if levels != nil {
+		present = 0
+		for _, level := range levels {
+			present += int(level)
+		}
+	}
+
+	var decoded []interface{}
+	var err error
+	switch encoding {
+	case encodingPlain:
+		decoded, err = decodePlain(col, data, present)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dictionary == nil {
+			return nil, fmt.Errorf("dictionary-encoded page without a dictionary")
+		}
+		if len(data) == 0 {
+			if present > 0 {
+				return nil, fmt.Errorf("truncated page")
+			}
+			break
+		}
+		var indexes []uint32
+		if indexes, err = decodeHybrid(data[1:], int(data[0]), present); err != nil {
+			return nil, err
+		}
+		decoded = make([]interface{}, present)
+		for i, index := range indexes {
+			if int(index) >= len(dictionary) {
+				return nil, fmt.Errorf("dictionary index out of range")
+			}
+			decoded[i] = dictionary[index]
+		}
+	case encodingRLE:
+		// Booleans, prefixed with their length like definition levels
+		if col.physical != parquetBoolean || len(data) < 4 {
+			return nil, fmt.Errorf("unexpected RLE encoded values")
+		}
+		var bits []uint32
+		if bits, err = decodeHybrid(data[4:], 1, present); err != nil {
+			return nil, err
+		}
+		decoded = make([]interface{}, present)
+		for i, bit := range bits {
+			decoded[i] = bit == 1
+		}
+	default:
+		name := parquetEncodings[encoding]
+		if name == "" {
+			name = fmt.Sprintf("%d", encoding)
+		}
+		return nil, fmt.Errorf("values use the %s encoding, which file connections cannot read yet; convert the file to CSV", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	next := 0
+	for i := 0; i < n; i++ {
+		if levels != nil && levels[i] == 0 {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, col.sqlValue(decoded[next]))
+		next++
+	}
+	return values, nil
+}
+
+// decompress decompresses page data with the codec of its column chunk
+func decompress(codec int64, data []byte, size int) ([]byte, error) {
+	switch codec {
+	case 0:
+		return data, nil
+	case 1:
+		return snappyDecode(data, size)
+	case 2:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, int64(size)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > size {
+			return nil, fmt.Errorf("page larger than its header declares")
+		}
+		return out, nil
+	}
+	name := parquetCodecs[codec]
+	if name == "" {
+		name = fmt.Sprintf("codec %d", codec)
+	}
+	return nil, fmt.Errorf("pages are compressed with %s, which file connections cannot read yet; rewrite the file with Snappy or Gzip compression, or convert it to CSV", name)
+}
+
+// decodeHybrid decodes n values of the RLE/bit-packing hybrid encoding used
+// for definition levels, dictionary indexes and booleans
+func decodeHybrid(data []byte, bitWidth, n int) ([]uint32, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	values := make([]uint32, 0, n)
+	pos := 0
+	for len(values) < n {
+		header, size := binary.Uvarint(data[pos:])
+		if size <= 0 {
+			return nil, fmt.Errorf("truncated page")
+		}
+		pos += size
+		if header&1 == 0 {
+			// A run of one repeated value
+			count := int(header >> 1)
+			width := (bitWidth + 7) / 8
+			if pos+width > len(data) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			var value uint32
+			for k := width - 1; k >= 0; k-- {
+				value = value<<8 | uint32(data[pos+k])
+			}
+			pos += width
+			for ; count > 0 && len(values) < n; count-- {
+				values = append(values, value)
+			}
+			continue
+		}
+		// Groups of eight bit-packed values, least significant bit first
+		count := int(header>>1) * 8
+		end := pos + int(header>>1)*bitWidth
+		if end > len(data) || end < pos {
+			return nil, fmt.Errorf("truncated page")
+		}
+		for i := 0; i < count && len(values) < n; i++ {
+			var value uint32
+			for b := 0; b < bitWidth; b++ {
+				bit := i*bitWidth + b
+				value |= uint32(data[pos+bit/8]>>(bit%8)&1) << b
+			}
+			values = append(values, value)
+		}
+		pos = end
+	}
+	return values, nil
+}
+
+// decodePlain decodes n PLAIN encoded values of a column's physical type
+func decodePlain(col parquetColumn, data []byte, n int) ([]interface{}, error) {
+	values := make([]interface{}, 0, n)
+	width := map[int64]int{parquetInt32: 4, parquetInt64: 8, parquetInt96: 12, parquetFloat: 4, parquetDouble: 8, parquetFixedLenByteArray: col.typeLength}[col.physical]
+	pos := 0
+	for i := 0; i < n; i++ {
+		switch col.physical {
+		case parquetBoolean:
+			if i/8 >= len(data) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			values = append(values, data[i/8]>>(i%8)&1 == 1)
+			continue
+		case parquetByteArray:
+			if pos+4 > len(data) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			width = int(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		}
+		if width < 0 || pos+width > len(data) || pos+width < pos {
+			return nil, fmt.Errorf("truncated page")
+		}
+		b := data[pos : pos+width]
+		pos += width
+		switch col.physical {
+		case parquetInt32:
+			values = append(values, int64(int32(binary.LittleEndian.Uint32(b))))
+		case parquetInt64:
+			values = append(values, int64(binary.LittleEndian.Uint64(b)))
+		case parquetInt96:
+			// Nanoseconds of the day, then the Julian day
+			day := int64(binary.LittleEndian.Uint32(b[8:])) - 2440588
+			values = append(values, time.Unix(day*86400, int64(binary.LittleEndian.Uint64(b))).UTC())
+		case parquetFloat:
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+		case parquetDouble:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		default:
+			values = append(values, append([]byte(nil), b...))
+		}
+	}
+	return values, nil
+}
+
+// sqlValue converts a decoded value to what the column stores, following the
+// column's annotations
+func (c parquetColumn) sqlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999")
+	case float64:
+		return v
+	case int64:
+		switch {
+		case c.is(convertedDecimal, logicalDecimal):
+			return float64(v) / math.Pow10(c.scale)
+		case c.is(convertedDate, logicalDate):
+			return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+		case c.is(convertedTimestampMillis, logicalTimestamp), c.converted == convertedTimestampMicros:
+			return c.timestamp(v).Format("2006-01-02 15:04:05.999999999")
+		case c.is(convertedTimeMillis, logicalTime), c.converted == convertedTimeMicros:
+			return time.Unix(0, 0).UTC().Add(c.duration(v)).Format("15:04:05.999999999")
+		case c.converted == convertedUint8, c.converted == convertedUint16, c.converted == convertedUint32:
+			return int64(uint32(v))
+		case c.converted == convertedUint64 && v < 0:
+			return float64(uint64(v))
+		}
+		return v
+	case []byte:
+		switch {
+		case c.is(convertedDecimal, logicalDecimal):
+			unscaled := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				// Two's complement
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v))*8))
+			}
+			f, _ := new(big.Float).Quo(new(big.Float).SetInt(unscaled), new(big.Float).SetFloat64(math.Pow10(c.scale))).Float64()
+			return f
+		case c.logical.child(logicalUUID) != nil && len(v) == 16:
+			h := hex.EncodeToString(v)
+			return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		case utf8.Valid(v):
+			return string(v)
+		}
+		return v
+	}
+	return v
+}
+
+// unit returns the time unit of a TIME or TIMESTAMP column: 1 for
+// milliseconds, 2 for microseconds and 3 for nanoseconds
+func (c parquetColumn) unit() int16 {
+	for _, id := range []int16{logicalTimestamp, logicalTime} {
+		if unit := c.logical.child(id).child(2); unit != nil {
+			for _, u := range []int16{1, 2, 3} {
+				if unit.child(u) != nil {
+					return u
+				}
+			}
+		}
+	}
+	if c.converted == convertedTimestampMicros || c.converted == convertedTimeMicros {
+		return 2
+	}
+	return 1
+}
+
+// duration converts a TIME or TIMESTAMP value to a duration in its unit
+func (c parquetColumn) duration(v int64) time.Duration {
+	switch c.unit() {
+	case 2:
+		return time.Duration(v) * time.Microsecond
+	case 3:
+		return time.Duration(v)
+	}
+	return time.Duration(v) * time.Millisecond
+}
+
+// timestamp converts a TIMESTAMP value to a time
+func (c parquetColumn) timestamp(v int64) time.Time {
+	switch c.unit() {
+	case 2:
+		return time.UnixMicro(v).UTC()
+	case 3:
+		return time.Unix(0, v).UTC()
+	}
+	return time.UnixMilli(v).UTC()
+}
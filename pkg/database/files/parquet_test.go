@@ -0,0 +1,384 @@
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftField is a field written by the test's Thrift compact encoder
+type thriftField struct {
+	id    int16
+	typ   byte
+	value interface{} // int64, []byte, []thriftField or thriftItems
+}
+
+type thriftItems struct {
+	typ   byte
+	items []interface{}
+}
+
+func tI32(id int16, v int64) thriftField  { return thriftField{id, thriftI32, v} }
+func tI64(id int16, v int64) thriftField  { return thriftField{id, thriftI64, v} }
+func tBin(id int16, v string) thriftField { return thriftField{id, thriftBinary, []byte(v)} }
+func tStruct(id int16, fields ...thriftField) thriftField {
+	return thriftField{id, thriftStruct, fields}
+}
+func tList(id int16, typ byte, items ...interface{}) thriftField {
+	return thriftField{id, thriftList, thriftItems{typ, items}}
+}
+
+func writeThrift(buf *bytes.Buffer, fields []thriftField) {
+	var last int16
+	for _, f := range fields {
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			buf.WriteByte(byte(delta)<<4 | f.typ)
+		} else {
+			buf.WriteByte(f.typ)
+			buf.Write(binary.AppendUvarint(nil, uint64(f.id)<<1^uint64(f.id>>15)))
+		}
+		last = f.id
+		writeThriftValue(buf, f.typ, f.value)
+	}
+	buf.WriteByte(thriftStop)
+}
+
+func writeThriftValue(buf *bytes.Buffer, typ byte, v interface{}) {
+	switch typ {
+	case thriftI32, thriftI64:
+		n := v.(int64)
+		buf.Write(binary.AppendUvarint(nil, uint64(n<<1^(n>>63))))
+	case thriftBinary:
+		buf.Write(binary.AppendUvarint(nil, uint64(len(v.([]byte)))))
+		buf.Write(v.([]byte))
+	case thriftStruct:
+		writeThrift(buf, v.([]thriftField))
+	case thriftList:
+		list := v.(thriftItems)
+		if len(list.items) < 15 {
+			buf.WriteByte(byte(len(list.items))<<4 | list.typ)
+		} else {
+			buf.WriteByte(0xf0 | list.typ)
+			buf.Write(binary.AppendUvarint(nil, uint64(len(list.items))))
+		}
+		for _, item := range list.items {
+			writeThriftValue(buf, list.typ, item)
+		}
+	}
+}
+
+// testColumn describes a column of a test Parquet file
+type testColumn struct {
+	name      string
+	physical  int64
+	optional  bool
+	annotate  []thriftField // Converted or logical type fields of the schema element
+	codec     int64
+	v2        bool
+	plain     func(v interface{}) []byte // PLAIN encoding of one value
+	dictValue bool                       // Dictionary-encode the values
+}
+
+// bitPacked writes values of bitWidth as one bit-packed run
+func bitPacked(values []uint32, bitWidth int) []byte {
+	groups := (len(values) + 7) / 8
+	out := binary.AppendUvarint(nil, uint64(groups<<1|1))
+	packed := make([]byte, groups*bitWidth)
+	for i, v := range values {
+		for b := 0; b < bitWidth; b++ {
+			if v>>b&1 == 1 {
+				bit := i*bitWidth + b
+				packed[bit/8] |= 1 << (bit % 8)
+			}
+		}
+	}
+	return append(out, packed...)
+}
+
+// rleRuns writes each value as its own one-byte RLE run
+func rleRuns(values []uint32) []byte {
+	var out []byte
+	for _, v := range values {
+		out = binary.AppendUvarint(out, 1<<1)
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+func compressPage(t *testing.T, codec int64, data []byte) []byte {
+	switch codec {
+	case 1:
+		// Snappy literals only; copies are covered by TestSnappyDecode
+		out := binary.AppendUvarint(nil, uint64(len(data)))
+		for len(data) > 0 {
+			n := min(len(data), 60)
+			out = append(out, byte(n-1)<<2)
+			out = append(out, data[:n]...)
+			data = data[n:]
+		}
+		return out
+	case 2:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+	return data
+}
+
+// writeParquet writes a Parquet file with one row group per entry of groups,
+// each holding the values of every column, nil for nulls
+func writeParquet(t *testing.T, path string, columns []testColumn, groups [][][]interface{}) {
+	t.Helper()
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	var rowGroups []interface{}
+	total := 0
+	for _, group := range groups {
+		var chunks []interface{}
+		rows := len(group[0])
+		total += rows
+		for c, col := range columns {
+			start := int64(file.Len())
+			var dictOffset int64
+			values := group[c]
+
+			var levels []uint32
+			var present []interface{}
+			for _, v := range values {
+				if v == nil {
+					levels = append(levels, 0)
+					continue
+				}
+				levels = append(levels, 1)
+				present = append(present, v)
+			}
+
+			var encoded []byte
+			encoding := int64(encodingPlain)
+			if col.dictValue {
+				var dict []byte
+				index := map[interface{}]uint32{}
+				var indexes []uint32
+				for _, v := range present {
+					if _, ok := index[v]; !ok {
+						index[v] = uint32(len(index))
+						dict = append(dict, col.plain(v)...)
+					}
+					indexes = append(indexes, index[v])
+				}
+				compressed := compressPage(t, col.codec, dict)
+				var header bytes.Buffer
+				writeThrift(&header, []thriftField{
+					tI32(1, pageDictionary), tI32(2, int64(len(dict))), tI32(3, int64(len(compressed))),
+					tStruct(7, tI32(1, int64(len(index))), tI32(2, encodingPlain)),
+				})
+				dictOffset = start
+				file.Write(header.Bytes())
+				file.Write(compressed)
+				encoded = append([]byte{8}, rleRuns(indexes)...)
+				encoding = encodingRLEDictionary
+			} else {
+				for _, v := range present {
+					encoded = append(encoded, col.plain(v)...)
+				}
+			}
+
+			dataOffset := int64(file.Len())
+			var header bytes.Buffer
+			if col.v2 {
+				var defs []byte
+				if col.optional {
+					defs = bitPacked(levels, 1)
+				}
+				compressed := compressPage(t, col.codec, encoded)
+				writeThrift(&header, []thriftField{
+					tI32(1, pageDataV2), tI32(2, int64(len(defs)+len(encoded))), tI32(3, int64(len(defs)+len(compressed))),
+					tStruct(8, tI32(1, int64(rows)), tI32(2, int64(rows-len(present))), tI32(3, int64(rows)), tI32(4, encoding),
+						tI32(5, int64(len(defs))), tI32(6, 0)),
+				})
+				file.Write(header.Bytes())
+				file.Write(defs)
+				file.Write(compressed)
+			} else {
+				page := encoded
+				if col.optional {
+					defs := bitPacked(levels, 1)
+					page = binary.LittleEndian.AppendUint32(nil, uint32(len(defs)))
+					page = append(append(page, defs...), encoded...)
+				}
+				compressed := compressPage(t, col.codec, page)
+				writeThrift(&header, []thriftField{
+					tI32(1, pageData), tI32(2, int64(len(page))), tI32(3, int64(len(compressed))),
+					tStruct(5, tI32(1, int64(rows)), tI32(2, encoding), tI32(3, encodingRLE), tI32(4, encodingRLE)),
+				})
+				file.Write(header.Bytes())
+				file.Write(compressed)
+			}
+
+			meta := []thriftField{
+				tI32(1, col.physical), tList(2, thriftI32, int64(encoding)), tList(3, thriftBinary, []byte(col.name)),
+				tI32(4, col.codec), tI64(5, int64(rows)), tI64(6, 0), tI64(7, int64(file.Len())-start), tI64(9, dataOffset),
+			}
+			if dictOffset > 0 {
+				meta = append(meta, tI64(11, dictOffset))
+			}
+			chunks = append(chunks, []thriftField{tI64(2, start), tStruct(3, meta...)})
+		}
+		rowGroups = append(rowGroups, []thriftField{tList(1, thriftStruct, chunks...), tI64(2, 0), tI64(3, int64(len(group[0])))})
+	}
+
+	schema := []interface{}{[]thriftField{tBin(4, "schema"), tI32(5, int64(len(columns)))}}
+	for _, col := range columns {
+		repetition := int64(0)
+		if col.optional {
+			repetition = 1
+		}
+		element := []thriftField{tI32(1, col.physical), tI32(3, repetition), tBin(4, col.name)}
+		schema = append(schema, append(element, col.annotate...))
+	}
+	var footer bytes.Buffer
+	writeThrift(&footer, []thriftField{
+		tI32(1, 1), tList(2, thriftStruct, schema...), tI64(3, int64(total)), tList(4, thriftStruct, rowGroups...),
+	})
+	file.Write(footer.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(footer.Len())))
+	file.WriteString(parquetMagic)
+	require.NoError(t, os.WriteFile(path, file.Bytes(), 0o644))
+}
+
+func plainInt32(v interface{}) []byte {
+	return binary.LittleEndian.AppendUint32(nil, uint32(int32(v.(int))))
+}
+
+func plainInt64(v interface{}) []byte {
+	return binary.LittleEndian.AppendUint64(nil, uint64(v.(int)))
+}
+
+func plainDouble(v interface{}) []byte {
+	return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v.(float64)))
+}
+
+func plainString(v interface{}) []byte {
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(v.(string)))), v.(string)...)
+}
+
+func TestNewFilesDatabaseParquet(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.parquet")
+	columns := []testColumn{
+		{name: "id", physical: parquetInt64, plain: plainInt64},
+		{name: "Customer", physical: parquetByteArray, optional: true, annotate: []thriftField{tI32(6, 0)}, codec: 1, plain: plainString, dictValue: true},
+		{name: "amount", physical: parquetInt64, optional: true, annotate: []thriftField{tI32(7, 2), tStruct(10, tStruct(logicalDecimal, tI32(1, 2), tI32(2, 10)))}, codec: 2, v2: true, plain: plainInt64},
+		{name: "day", physical: parquetInt32, annotate: []thriftField{tI32(6, convertedDate)}, plain: plainInt32},
+		{name: "at", physical: parquetInt64, annotate: []thriftField{tStruct(10, tStruct(logicalTimestamp, thriftField{1, thriftTrue, nil}, tStruct(2, tStruct(2))))}, plain: plainInt64},
+		{name: "score", physical: parquetDouble, codec: 1, plain: plainDouble},
+		{name: "active", physical: parquetBoolean, plain: func(v interface{}) []byte {
+			if v.(bool) {
+				return []byte{1}
+			}
+			return []byte{0}
+		}},
+	}
+	writeParquet(t, path, columns, [][][]interface{}{
+		{
+			{1, 2, 3},
+			{"alice", nil, "alice"},
+			{1250, -5, nil},
+			{20744, 20745, 20746},
+			{1792296000000000, 1792296000123456, 0},
+			{0.5, 1.25, -2.0},
+			{true, false, true},
+		},
+		{
+			{4}, {"bob"}, {99}, {20747}, {0}, {3.0}, {false},
+		},
+	})
+
+	db, err := NewFilesDatabase(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	tables, err := db.GetAllTables()
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Contains(t, tables[0].Description, "events.parquet (4 rows)")
+
+	schema, err := db.GetTableSchema("events")
+	require.NoError(t, err)
+	var types []string
+	for _, col := range schema {
+		types = append(types, col.ColumnName+" "+col.DataType)
+	}
+	assert.Equal(t, []string{"id INTEGER", "customer TEXT", "amount REAL", "day TEXT", "at TEXT", "score REAL", "active INTEGER"}, types)
+
+	result, err := db.ExecuteSQL("SELECT id, customer, amount, day, at, score, active FROM events ORDER BY id")
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 4)
+	assert.Equal(t, []interface{}{int64(1), "alice", 12.5, "2026-10-18", "2026-10-18 04:00:00", 0.5, int64(1)}, result.Rows[0])
+	assert.Equal(t, []interface{}{int64(2), nil, -0.05, "2026-10-19", "2026-10-18 04:00:00.123456", 1.25, int64(0)}, result.Rows[1])
+	assert.Nil(t, result.Rows[2][2])
+	assert.Equal(t, []interface{}{int64(4), "bob", 0.99, "2026-10-21"}, result.Rows[3][:4])
+}
+
+func TestNewFilesDatabaseParquetUnsupported(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewFilesDatabase(writeFile(t, dir, "broken.parquet", "PAR1"))
+	assert.ErrorContains(t, err, "not a Parquet file")
+
+	path := filepath.Join(dir, "zstd.parquet")
+	writeParquet(t, path, []testColumn{{name: "id", physical: parquetInt64, codec: 6, plain: plainInt64}}, [][][]interface{}{{{1}}})
+	_, err = NewFilesDatabase(path)
+	assert.ErrorContains(t, err, "compressed with Zstd")
+
+	nested := filepath.Join(dir, "nested.parquet")
+	var footer bytes.Buffer
+	writeThrift(&footer, []thriftField{
+		tI32(1, 1),
+		tList(2, thriftStruct,
+			[]thriftField{tBin(4, "schema"), tI32(5, 1)},
+			[]thriftField{tI32(3, 1), tBin(4, "address"), tI32(5, 1)},
+			[]thriftField{tI32(1, parquetByteArray), tI32(3, 1), tBin(4, "city")}),
+		tI64(3, 0),
+	})
+	data := append([]byte(parquetMagic), footer.Bytes()...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(footer.Len()))
+	require.NoError(t, os.WriteFile(nested, append(data, parquetMagic...), 0o644))
+	_, err = NewFilesDatabase(nested)
+	assert.ErrorContains(t, err, "column address is nested")
+}
+
+func TestSnappyDecode(t *testing.T) {
+	// A literal, then an overlapping copy with a one-byte offset and one with a two-byte offset
+	block := []byte{14, 0x0c, 'a', 'b', 'c', 'd', 0x11, 4, 0x02 | 1<<2, 4, 0}
+	out, err := snappyDecode(block, 14)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdabcdabcdab", string(out))
+
+	_, err = snappyDecode(block, 10)
+	assert.Error(t, err, "a block larger than the page is refused")
+	_, err = snappyDecode([]byte{4, 0x11, 4}, 4)
+	assert.Error(t, err, "a copy before any output is refused")
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	values := []uint32{1, 0, 3, 2, 1, 1, 0, 2}
+	data := append(bitPacked(values, 2), rleRuns([]uint32{3})...)
+	decoded, err := decodeHybrid(data, 2, len(values)+1)
+	require.NoError(t, err)
+	assert.Equal(t, append(values, 3), decoded)
+
+	_, err = decodeHybrid([]byte{0x03}, 8, 8)
+	assert.ErrorContains(t, err, "truncated")
+}
@@ -0,0 +1,46 @@
+package files
+
+import (
+	"fmt"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// FilesProvider implements the DatabaseProviderInterface for local data files.
+// The connection's Database field holds the comma-separated paths or globs.
+type FilesProvider struct{}
+
+// Ensure FilesProvider implements DatabaseProviderInterface
+var _ dbinterfaces.DatabaseProviderInterface = (*FilesProvider)(nil)
+
+// NewFilesProvider creates a new files provider
+func NewFilesProvider() *FilesProvider {
+	return &FilesProvider{}
+}
+
+// CreateConnection loads the configured files into a new in-memory database
+func (p *FilesProvider) CreateConnection(config *dbinterfaces.ConnectionConfig) (dbinterfaces.DatabaseInterface, error) {
+	if err := p.ValidateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return NewFilesDatabase(config.Database)
+}
+
+// GetSupportedDrivers returns the drivers used to query files
+func (p *FilesProvider) GetSupportedDrivers() []string {
+	return []string{"sqlite3"}
+}
+
+// ValidateConfig checks that the configured patterns match at least one file
+func (p *FilesProvider) ValidateConfig(config *dbinterfaces.ConnectionConfig) error {
+	if config.Database == "" {
+		return fmt.Errorf("file paths are required")
+	}
+	_, err := ExpandPatterns(config.Database)
+	return err
+}
+
+// BuildConnectionURL returns the files: URL for the configured patterns
+func (p *FilesProvider) BuildConnectionURL(config *dbinterfaces.ConnectionConfig) string {
+	return "files:" + config.Database
+}
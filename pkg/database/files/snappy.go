@@ -0,0 +1,76 @@
+package files
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// snappyDecode decompresses a block in the raw Snappy format Parquet pages use.
+// The decoded length must not exceed limit, the size the page header declares.
+func snappyDecode(src []byte, limit int) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > uint64(limit) {
+		return nil, fmt.Errorf("corrupt snappy block")
+	}
+	dst := make([]byte, 0, length)
+	for i := n; i < len(src); {
+		tag := src[i]
+		i++
+		var size, offset int
+		switch tag & 0x03 {
+		case 0: // Literal
+			size = int(tag>>2) + 1
+			if size > 60 {
+				extra := size - 60
+				if i+extra > len(src) {
+					return nil, fmt.Errorf("corrupt snappy block")
+				}
+				size = 0
+				for k := extra - 1; k >= 0; k-- {
+					size = size<<8 | int(src[i+k])
+				}
+				size++
+				i += extra
+			}
+			if size <= 0 || size > len(src)-i || len(dst)+size > int(length) {
+				return nil, fmt.Errorf("corrupt snappy block")
+			}
+			dst = append(dst, src[i:i+size]...)
+			i += size
+			continue
+		case 1: // Copy with a one-byte offset
+			if i >= len(src) {
+				return nil, fmt.Errorf("corrupt snappy block")
+			}
+			size = 4 + int(tag>>2&0x07)
+			offset = int(tag>>5)<<8 | int(src[i])
+			i++
+		case 2: // Copy with a two-byte offset
+			if i+2 > len(src) {
+				return nil, fmt.Errorf("corrupt snappy block")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[i:]))
+			i += 2
+		case 3: // Copy with a four-byte offset
+			if i+4 > len(src) {
+				return nil, fmt.Errorf("corrupt snappy block")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[i:]))
+			i += 4
+		}
+		if offset <= 0 || offset > len(dst) || len(dst)+size > int(length) {
+			return nil, fmt.Errorf("corrupt snappy block")
+		}
+		// Copies may overlap their own output, so go byte by byte
+		start := len(dst) - offset
+		for k := 0; k < size; k++ {
+			dst = append(dst, dst[start+k])
+		}
+	}
+	if len(dst) != int(length) {
+		return nil, fmt.Errorf("corrupt snappy block")
+	}
+	return dst, nil
+}
@@ -0,0 +1,254 @@
+package files
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Parquet metadata is written with the Thrift compact protocol. Only decoding
+// is needed, so structs are read generically into their fields by id and the
+// Parquet code picks out the fields it knows.
+
+// Compact protocol field and element types
+const (
+	thriftStop   = 0
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// maxThriftDepth bounds the nesting of structs and lists, so a corrupt file
+// cannot exhaust the stack
+const maxThriftDepth = 64
+
+// thriftFields is a decoded struct by field id: integers are int64, binary
+// fields []byte, lists []interface{} and nested structs thriftFields
+type thriftFields map[int16]interface{}
+
+// int returns an integer field, or def when it is not set
+func (s thriftFields) int(id int16, def int64) int64 {
+	if v, ok := s[id].(int64); ok {
+		return v
+	}
+	return def
+}
+
+// bool returns a boolean field, or def when it is not set
+func (s thriftFields) bool(id int16, def bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// string returns a binary field as text
+func (s thriftFields) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+// list returns a list field
+func (s thriftFields) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// structs returns a list field of structs
+func (s thriftFields) structs(id int16) []thriftFields {
+	var structs []thriftFields
+	for _, v := range s.list(id) {
+		if child, ok := v.(thriftFields); ok {
+			structs = append(structs, child)
+		}
+	}
+	return structs
+}
+
+// child returns a struct field, or nil when it is not set
+func (s thriftFields) child(id int16) thriftFields {
+	v, _ := s[id].(thriftFields)
+	return v
+}
+
+// compactReader decodes the Thrift compact protocol from a buffer
+type compactReader struct {
+	buf   []byte
+	pos   int
+	depth int
+}
+
+var errThriftTruncated = fmt.Errorf("truncated metadata")
+
+func (r *compactReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errThriftTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *compactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errThriftTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+// zigzag reads a zigzag-encoded varint, as i16, i32 and i64 values are written
+func (r *compactReader) zigzag() (int64, error) {
+	v, err := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *compactReader) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(r.buf)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// readStruct reads the fields of a struct up to its stop marker
+func (r *compactReader) readStruct() (thriftFields, error) {
+	if r.depth++; r.depth > maxThriftDepth {
+		return nil, fmt.Errorf("metadata nested too deeply")
+	}
+	defer func() { r.depth-- }()
+
+	s := make(thriftFields)
+	var id int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		typ := header & 0x0f
+		if typ == thriftStop {
+			return s, nil
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			long, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(long)
+		}
+		if s[id], err = r.readValue(typ); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readValue reads one value of a compact protocol type
+func (r *compactReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	case thriftByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftDouble:
+		b, err := r.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		return r.bytes(n)
+	case thriftList, thriftSet:
+		return r.readList()
+	case thriftMap:
+		return r.readMap()
+	case thriftStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("unknown metadata type %d", typ)
+}
+
+// readList reads a list or set
+func (r *compactReader) readList() ([]interface{}, error) {
+	if r.depth++; r.depth > maxThriftDepth {
+		return nil, fmt.Errorf("metadata nested too deeply")
+	}
+	defer func() { r.depth-- }()
+
+	header, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(header >> 4)
+	if size == 15 {
+		if size, err = r.uvarint(); err != nil {
+			return nil, err
+		}
+	}
+	// Every element takes at least one byte, which bounds a corrupt size
+	if size > uint64(len(r.buf)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	typ := header & 0x0f
+	list := make([]interface{}, 0, size)
+	for i := uint64(0); i < size; i++ {
+		var v interface{}
+		if typ == thriftTrue || typ == thriftFalse {
+			// Booleans in lists are a byte each
+			b, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			v = b == thriftTrue
+		} else if v, err = r.readValue(typ); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// readMap reads a map, which Parquet metadata does not use, to skip it
+func (r *compactReader) readMap() (interface{}, error) {
+	size, err := r.uvarint()
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	if size > uint64(len(r.buf)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	types, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < size; i++ {
+		if _, err := r.readValue(types >> 4); err != nil {
+			return nil, err
+		}
+		if _, err := r.readValue(types & 0x0f); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
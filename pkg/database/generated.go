@@ -0,0 +1,98 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dbsage/internal/generated"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// GeneratedCleanup is the outcome of deleting recorded test rows
+type GeneratedCleanup struct {
+	Deleted map[string]int  // Rows deleted per table
+	Gone    int             // Rows that were already deleted
+	Removed []generated.Row // Rows no longer in the database, to forget
+	Failed  []string        // Rows that could not be deleted, with the reason
+}
+
+// CleanupGenerated deletes recorded test rows by primary key, newest first so
+// rows referring to earlier ones go before them. Each DELETE is rolled back
+// unless it removes at most one row. Rows blocked by others, such as a parent
+// of a row deleted later in the pass, are retried while a pass makes progress.
+func CleanupGenerated(db dbinterfaces.DatabaseInterface, rows []generated.Row) GeneratedCleanup {
+	cleanup := GeneratedCleanup{Deleted: make(map[string]int)}
+	dialect := dbinterfaces.GetDatabaseType(db)
+
+	pending := make([]generated.Row, len(rows))
+	for i, row := range rows {
+		pending[len(rows)-1-i] = row
+	}
+	failures := make(map[int]error)
+	for len(pending) > 0 {
+		var retry []generated.Row
+		clear(failures)
+		for _, row := range pending {
+			deleted, err := deleteByKey(db, dialect, row.Table, row.Key)
+			switch {
+			case err != nil:
+				failures[len(retry)] = err
+				retry = append(retry, row)
+			case deleted:
+				cleanup.Deleted[row.Table]++
+				cleanup.Removed = append(cleanup.Removed, row)
+			default:
+				cleanup.Gone++
+				cleanup.Removed = append(cleanup.Removed, row)
+			}
+		}
+		if len(retry) == len(pending) {
+			for i, row := range retry {
+				cleanup.Failed = append(cleanup.Failed, fmt.Sprintf("%s %s: %v", row.Table, describeKey(row.Key), failures[i]))
+			}
+			break
+		}
+		pending = retry
+	}
+	return cleanup
+}
+
+// deleteByKey deletes the row of a table with the given primary-key values and
+// reports whether it existed
+func deleteByKey(db dbinterfaces.DatabaseInterface, dialect, table string, key map[string]interface{}) (bool, error) {
+	if len(key) == 0 {
+		return false, fmt.Errorf("no primary key recorded")
+	}
+	columns := make([]string, 0, len(key))
+	for column := range key {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]string, len(columns))
+	params := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s = %s", quoteColumn(dialect, column), bindMarker(dialect, i+1))
+		params[i] = key[column]
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteColumn(dialect, table), strings.Join(conditions, " AND "))
+
+	_, err := dbinterfaces.ExecuteExpectingRows(db, query, 1, params...)
+	var mismatch *dbinterfaces.RowCountMismatchError
+	if errors.As(err, &mismatch) && mismatch.Actual == 0 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// describeKey renders primary-key values as column=value pairs
+func describeKey(key map[string]interface{}) string {
+	pairs := make([]string, 0, len(key))
+	for column, value := range key {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", column, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
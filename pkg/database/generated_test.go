@@ -0,0 +1,26 @@
+package database
+
+import (
+	"testing"
+
+	"dbsage/internal/generated"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupGenerated(t *testing.T) {
+	db := openScratchTestDB(t)
+
+	cleanup := CleanupGenerated(db, []generated.Row{
+		{Connection: "shop", Table: "orders", Key: map[string]interface{}{"id": int64(2)}},
+		{Connection: "shop", Table: "orders", Key: map[string]interface{}{"id": int64(99)}},
+		{Connection: "shop", Table: "missing_table", Key: map[string]interface{}{"id": int64(1)}},
+	})
+	assert.Equal(t, map[string]int{"orders": 1}, cleanup.Deleted)
+	assert.Equal(t, 1, cleanup.Gone, "a row deleted by someone else is forgotten")
+	assert.Len(t, cleanup.Removed, 2)
+	require.Len(t, cleanup.Failed, 1)
+	assert.Contains(t, cleanup.Failed[0], "missing_table id=1")
+	assert.Equal(t, int64(1), countOrders(t, db))
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// HealthPolicy controls how often the current connection is checked and how
+// reconnect attempts back off after failures. The zero value checks on every
+// call and never backs off.
+type HealthPolicy struct {
+	CheckTTL         time.Duration // How long a successful check is trusted
+	FailureThreshold int           // Consecutive failures before the connection is considered down
+	BaseBackoff      time.Duration // Wait before the first reconnect attempt once down
+	MaxBackoff       time.Duration // Upper bound for the exponential backoff
+}
+
+// DefaultHealthPolicy returns the policy used by NewConnectionService, overridable with
+// DBSAGE_HEALTH_TTL, DBSAGE_HEALTH_MAX_BACKOFF (Go durations) and DBSAGE_HEALTH_FAILURES
+func DefaultHealthPolicy() HealthPolicy {
+	policy := HealthPolicy{
+		CheckTTL:         5 * time.Second,
+		FailureThreshold: 3,
+		BaseBackoff:      time.Second,
+		MaxBackoff:       time.Minute,
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_HEALTH_TTL")); err == nil && d >= 0 {
+		policy.CheckTTL = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_HEALTH_MAX_BACKOFF")); err == nil && d > 0 {
+		policy.MaxBackoff = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_HEALTH_FAILURES")); err == nil && n > 0 {
+		policy.FailureThreshold = n
+	}
+	return policy
+}
+
+// healthTracker caches health check results and acts as a circuit breaker
+// for reconnect attempts
+type healthTracker struct {
+	mu          sync.Mutex
+	policy      HealthPolicy
+	state       string
+	lastCheck   time.Time
+	lastChange  time.Time
+	lastError   string
+	failures    int
+	nextAttempt time.Time
+	latency     time.Duration
+	remote      bool
+}
+
+// effectivePolicy returns the policy relaxed for remote connections; callers hold mu
+func (t *healthTracker) effectivePolicy() HealthPolicy {
+	if t.remote {
+		return RemotePolicy(t.policy)
+	}
+	return t.policy
+}
+
+// setLatency records the round trip measured on connect
+func (t *healthTracker) setLatency(latency time.Duration, remote bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.latency = latency
+	t.remote = remote
+}
+
+// checkDue reports whether the cached result has expired and the connection should be pinged
+func (t *healthTracker) checkDue(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state != dbinterfaces.HealthHealthy || now.Sub(t.lastCheck) >= t.effectivePolicy().CheckTTL
+}
+
+// attemptAllowed reports whether a reconnect may be attempted, i.e. the breaker is not open
+func (t *healthTracker) attemptAllowed(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state != dbinterfaces.HealthDown || !now.Before(t.nextAttempt)
+}
+
+// hasFailed reports whether the last check failed
+func (t *healthTracker) hasFailed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failures > 0
+}
+
+func (t *healthTracker) recordSuccess(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastCheck = now
+	t.failures = 0
+	t.lastError = ""
+	t.nextAttempt = time.Time{}
+	t.setState(dbinterfaces.HealthHealthy, now)
+}
+
+func (t *healthTracker) recordFailure(now time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastCheck = now
+	t.failures++
+	if err != nil {
+		t.lastError = err.Error()
+	}
+
+	policy := t.effectivePolicy()
+	threshold := policy.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	if t.failures < threshold {
+		t.setState(dbinterfaces.HealthDegraded, now)
+		return
+	}
+
+	// Double the wait for every failure past the threshold
+	backoff := policy.BaseBackoff
+	for i := threshold; i < t.failures && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	t.nextAttempt = now.Add(backoff)
+	t.setState(dbinterfaces.HealthDown, now)
+}
+
+// reset forgets previous results, e.g. after switching connections
+func (t *healthTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state = ""
+	t.lastCheck = time.Time{}
+	t.lastChange = time.Time{}
+	t.lastError = ""
+	t.failures = 0
+	t.nextAttempt = time.Time{}
+	t.latency = 0
+	t.remote = false
+}
+
+func (t *healthTracker) setState(state string, now time.Time) {
+	if t.state != state {
+		t.state = state
+		t.lastChange = now
+	}
+}
+
+func (t *healthTracker) status() dbinterfaces.HealthStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.state
+	if state == "" {
+		state = dbinterfaces.HealthUnknown
+	}
+	return dbinterfaces.HealthStatus{
+		State:               state,
+		CheckTTL:            t.effectivePolicy().CheckTTL,
+		LastCheck:           t.lastCheck,
+		LastChange:          t.lastChange,
+		LastError:           t.lastError,
+		ConsecutiveFailures: t.failures,
+		NextAttempt:         t.nextAttempt,
+		Latency:             t.latency,
+		Remote:              t.remote,
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+)
+
+// DefaultQueryLabel is the comment template used when DBSAGE_QUERY_LABEL is not set
+const DefaultQueryLabel = "dbsage user={user}"
+
+// queryLabels holds the values of the label placeholders
+var queryLabels = struct {
+	sync.RWMutex
+	values map[string]string
+	once   sync.Once
+}{values: make(map[string]string)}
+
+// SetQueryLabelValue sets the value of a label placeholder, such as the
+// conversation turn for {turn}
+func SetQueryLabelValue(key, value string) {
+	queryLabels.Lock()
+	defer queryLabels.Unlock()
+	queryLabels.values[key] = value
+}
+
+// initQueryLabels fills in the placeholders known for the whole process
+func initQueryLabels() {
+	queryLabels.once.Do(func() {
+		name := os.Getenv("USER")
+		if current, err := user.Current(); err == nil && current.Username != "" {
+			name = current.Username
+		}
+		session := make([]byte, 4)
+		_, _ = rand.Read(session)
+
+		queryLabels.Lock()
+		defer queryLabels.Unlock()
+		queryLabels.values["user"] = name
+		queryLabels.values["session"] = hex.EncodeToString(session)
+	})
+}
+
+// QueryLabelTemplate returns the template of the comment prepended to
+// executed statements, from DBSAGE_QUERY_LABEL, or "" when set to off
+func QueryLabelTemplate() string {
+	template, ok := os.LookupEnv("DBSAGE_QUERY_LABEL")
+	if !ok {
+		return DefaultQueryLabel
+	}
+	template = strings.TrimSpace(template)
+	if strings.EqualFold(template, "off") || strings.EqualFold(template, "none") {
+		return ""
+	}
+	return template
+}
+
+// QueryLabel renders the label comment for a statement on a connection, e.g.
+// "/* dbsage user=jane turn=42 */ ". Placeholders are {user}, {session},
+// {turn} and {connection}; words whose placeholders have no value are left
+// out. It returns "" when labels are off.
+func QueryLabel(connection string) string {
+	template := QueryLabelTemplate()
+	if template == "" {
+		return ""
+	}
+	initQueryLabels()
+
+	queryLabels.RLock()
+	values := make(map[string]string, len(queryLabels.values)+1)
+	for key, value := range queryLabels.values {
+		values[key] = value
+	}
+	queryLabels.RUnlock()
+	values["connection"] = connection
+
+	var words []string
+	for _, word := range strings.Fields(template) {
+		complete := true
+		for {
+			start := strings.Index(word, "{")
+			end := strings.Index(word, "}")
+			if start < 0 || end < start {
+				break
+			}
+			value := labelSafe(values[word[start+1:end]])
+			if value == "" {
+				complete = false
+				break
+			}
+			word = word[:start] + value + word[end+1:]
+		}
+		if complete {
+			words = append(words, labelSafe(word))
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return "/* " + strings.Join(words, " ") + " */ "
+}
+
+// LabelQuery prepends the label comment to a statement
+func LabelQuery(query, connection string) string {
+	return QueryLabel(connection) + query
+}
+
+// labelSafe keeps the characters that cannot end the comment or confuse log
+// parsers, replacing the others with underscores
+func labelSafe(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("_-.:=@,", r):
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
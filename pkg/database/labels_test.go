@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQueryLabel(t *testing.T) {
+	initQueryLabels()
+	SetQueryLabelValue("user", "jane")
+	SetQueryLabelValue("turn", "")
+
+	assert.Equal(t, "/* dbsage user=jane */ ", QueryLabel("prod"))
+
+	// Words without a value are left out until one is set
+	t.Setenv("DBSAGE_QUERY_LABEL", "dbsage user={user} conn={connection} turn={turn}")
+	assert.Equal(t, "/* dbsage user=jane conn=prod */ ", QueryLabel("prod"))
+	SetQueryLabelValue("turn", "42")
+	assert.Equal(t, "/* dbsage user=jane conn=prod turn=42 */ ", QueryLabel("prod"))
+
+	// Values cannot close the comment
+	assert.Equal(t, "/* dbsage user=jane conn=a__b_ turn=42 */ SELECT 1", LabelQuery("SELECT 1", "a*/b;"))
+
+	t.Setenv("DBSAGE_QUERY_LABEL", "off")
+	assert.Equal(t, "SELECT 1", LabelQuery("SELECT 1", "prod"))
+}
+
+func TestLimitedDatabase_LabelsStatements(t *testing.T) {
+	t.Setenv("DBSAGE_QUERY_LABEL", "dbsage conn={connection}")
+	mockDB := &MockDatabaseInterface{}
+	mockDB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+
+	limited := NewLimitedDatabase(mockDB, "reports", 1)
+	_, err := limited.ExecuteSQL("SELECT 1")
+	assert.NoError(t, err)
+	mockDB.AssertCalled(t, "ExecuteSQL", "/* dbsage conn=reports */ SELECT 1")
+}
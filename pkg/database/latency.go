@@ -0,0 +1,143 @@
+package database
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Remote mode settings, used for connections whose round trip exceeds the
+// remote latency threshold
+const (
+	defaultRemoteLatency = 50 * time.Millisecond
+	latencySamples       = 3
+	remoteHealthFactor   = 6 // Health checks are trusted this many times longer
+	remoteTimeoutFactor  = 3 // Statements wait this many times longer for a free slot
+	metadataCacheTTL     = 5 * time.Minute
+)
+
+// RemoteLatencyThreshold returns the round trip from which a connection is
+// treated as remote, overridable with DBSAGE_REMOTE_LATENCY (a Go duration,
+// 0 disables remote mode)
+func RemoteLatencyThreshold() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_REMOTE_LATENCY")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultRemoteLatency
+}
+
+// MeasureLatency pings a database a few times and returns the fastest round
+// trip, so a single slow ping does not make a nearby database look remote
+func MeasureLatency(db dbinterfaces.DatabaseInterface) (time.Duration, error) {
+	var fastest time.Duration
+	for i := 0; i < latencySamples; i++ {
+		start := time.Now()
+		if err := db.CheckConnection(); err != nil {
+			return 0, err
+		}
+		if elapsed := time.Since(start); i == 0 || elapsed < fastest {
+			fastest = elapsed
+		}
+	}
+	return fastest, nil
+}
+
+// RemotePolicy returns the health policy for a remote connection: every check
+// costs a slow round trip, so results are trusted longer and reconnects back
+// off from a longer first wait
+func RemotePolicy(policy HealthPolicy) HealthPolicy {
+	policy.CheckTTL *= remoteHealthFactor
+	policy.BaseBackoff *= 2
+	if policy.MaxBackoff > 0 && policy.BaseBackoff > policy.MaxBackoff {
+		policy.BaseBackoff = policy.MaxBackoff
+	}
+	return policy
+}
+
+// schemaChangeKeywords start statements that can change the answers of the
+// introspection methods
+var schemaChangeKeywords = []string{"CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE", "COMMENT"}
+
+// isSchemaChange reports whether a statement may change tables, columns or indexes
+func isSchemaChange(query string) bool {
+	statement := strings.ToUpper(strings.TrimSpace(query))
+	for _, keyword := range schemaChangeKeywords {
+		if strings.HasPrefix(statement, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataEntry is a cached introspection answer of a remote connection
+type metadataEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// SetRemote switches remote mode of the connection on or off. In remote mode
+// introspection answers are cached until a statement changes the schema, and
+// statements wait longer for a free slot.
+func (l *LimitedDatabase) SetRemote(remote bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remote = remote
+	l.metadata = nil
+}
+
+// IsRemote reports whether the connection is in remote mode
+func (l *LimitedDatabase) IsRemote() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.remote
+}
+
+func (l *LimitedDatabase) waitTimeout() time.Duration {
+	if l.IsRemote() {
+		return concurrencyWaitTimeout * remoteTimeoutFactor
+	}
+	return concurrencyWaitTimeout
+}
+
+// forgetMetadata drops the cached introspection answers after a statement
+// that may have changed the schema
+func (l *LimitedDatabase) forgetMetadata(query string) {
+	if !isSchemaChange(query) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metadata = nil
+}
+
+// cachedMetadata returns the cached answer for key on a remote connection,
+// loading and caching it when missing or expired. Errors are not cached.
+func cachedMetadata[T any](l *LimitedDatabase, key string, load func() (T, error)) (T, error) {
+	if !l.IsRemote() {
+		return load()
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	entry, ok := l.metadata[key]
+	l.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value.(T), nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	l.mu.Lock()
+	if l.remote {
+		if l.metadata == nil {
+			l.metadata = make(map[string]metadataEntry)
+		}
+		l.metadata[key] = metadataEntry{value: value, expires: now.Add(metadataCacheTTL)}
+	}
+	l.mu.Unlock()
+	return value, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureLatency(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	mockDB.On("CheckConnection").Run(func(mock.Arguments) {
+		time.Sleep(20 * time.Millisecond)
+	}).Return(nil)
+
+	latency, err := MeasureLatency(mockDB)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, latency, 20*time.Millisecond)
+	mockDB.AssertNumberOfCalls(t, "CheckConnection", latencySamples)
+}
+
+func TestRemoteLatencyThreshold(t *testing.T) {
+	assert.Equal(t, defaultRemoteLatency, RemoteLatencyThreshold())
+	t.Setenv("DBSAGE_REMOTE_LATENCY", "0")
+	assert.Equal(t, time.Duration(0), RemoteLatencyThreshold())
+	t.Setenv("DBSAGE_REMOTE_LATENCY", "120ms")
+	assert.Equal(t, 120*time.Millisecond, RemoteLatencyThreshold())
+}
+
+func TestLimitedDatabase_RemoteCachesMetadata(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	columns := []models.ColumnInfo{{ColumnName: "id"}}
+	mockDB.On("GetTableSchema", "users").Return(columns, nil)
+	mockDB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	limited := NewLimitedDatabase(mockDB, "far", 2)
+
+	// Nearby connections always ask the database
+	limited.GetTableSchema("users")
+	limited.GetTableSchema("users")
+	mockDB.AssertNumberOfCalls(t, "GetTableSchema", 2)
+
+	limited.SetRemote(true)
+	got, err := limited.GetTableSchema("users")
+	require.NoError(t, err)
+	assert.Equal(t, columns, got)
+	limited.GetTableSchema("users")
+	mockDB.AssertNumberOfCalls(t, "GetTableSchema", 3)
+
+	// Queries keep the cache, schema changes drop it
+	limited.ExecuteSQL("SELECT * FROM users")
+	limited.GetTableSchema("users")
+	mockDB.AssertNumberOfCalls(t, "GetTableSchema", 3)
+	limited.ExecuteSQL("  alter table users add column name text")
+	limited.GetTableSchema("users")
+	mockDB.AssertNumberOfCalls(t, "GetTableSchema", 4)
+}
+
+func TestHealthTracker_RemoteChecksLessOften(t *testing.T) {
+	tracker := healthTracker{policy: HealthPolicy{CheckTTL: 5 * time.Second, FailureThreshold: 1, BaseBackoff: time.Second, MaxBackoff: time.Minute}}
+	now := time.Now()
+	tracker.recordSuccess(now)
+	assert.True(t, tracker.checkDue(now.Add(10*time.Second)))
+
+	tracker.setLatency(200*time.Millisecond, true)
+	assert.False(t, tracker.checkDue(now.Add(10*time.Second)))
+	assert.True(t, tracker.checkDue(now.Add(30*time.Second)))
+
+	status := tracker.status()
+	assert.True(t, status.Remote)
+	assert.Equal(t, 200*time.Millisecond, status.Latency)
+	assert.Equal(t, 30*time.Second, status.CheckTTL)
+
+	tracker.recordFailure(now, nil)
+	assert.Equal(t, now.Add(2*time.Second), tracker.status().NextAttempt)
+}
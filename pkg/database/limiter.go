@@ -0,0 +1,350 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Default number of statements run concurrently on one connection, by environment
+var defaultConcurrencyLimits = map[string]int{
+	models.EnvProduction:  2,
+	models.EnvStaging:     4,
+	models.EnvDevelopment: 8,
+	"":                    4,
+}
+
+// concurrencyWaitTimeout is how long a statement waits for a free slot before failing
+const concurrencyWaitTimeout = 30 * time.Second
+
+// DetectEnvironment guesses the environment of a connection from its name and description
+func DetectEnvironment(name, description string) string {
+	text := strings.ToLower(name + " " + description)
+	switch {
+	case strings.Contains(text, "prod"):
+		return models.EnvProduction
+	case strings.Contains(text, "stag"), strings.Contains(text, "uat"):
+		return models.EnvStaging
+	case strings.Contains(text, "dev"), strings.Contains(text, "local"), strings.Contains(text, "test"):
+		return models.EnvDevelopment
+	default:
+		return ""
+	}
+}
+
+// ConcurrencyLimit returns the maximum number of concurrent statements for a
+// connection. The connection's max_concurrency setting wins, then the
+// DBSAGE_CONCURRENCY_<ENVIRONMENT> variable (PRODUCTION, STAGING, DEVELOPMENT
+// or DEFAULT), then the built-in default for its environment.
+func ConcurrencyLimit(config *dbinterfaces.ConnectionConfig) int {
+	if config.MaxConcurrency > 0 {
+		return config.MaxConcurrency
+	}
+
+	env := DetectEnvironment(config.Name, config.Description)
+	key := "DEFAULT"
+	if env != "" {
+		key = strings.ToUpper(env)
+	}
+	if n, err := strconv.Atoi(os.Getenv("DBSAGE_CONCURRENCY_" + key)); err == nil && n > 0 {
+		return n
+	}
+	return defaultConcurrencyLimits[env]
+}
+
+// LimitedDatabase wraps a database connection with a semaphore limiting how many
+// statements run on it at the same time. Statements are prefixed with the
+// QueryLabel comment on the way, writes are refused on read-only connections,
+// and statements run in the open scratchpad, if any.
+//
+// Every connection is reached through this wrapper, so each optional
+// capability interface of dbinterfaces the wrapped database may implement
+// must be forwarded here too, or it disappears for every caller.
+type LimitedDatabase struct {
+	dbinterfaces.DatabaseInterface
+	name  string
+	slots chan struct{}
+
+	mu       sync.Mutex
+	remote   bool                     // Set for high-latency connections, see SetRemote
+	readOnly bool                     // Writes are refused, see SetReadOnly
+	scratch  *scratchpad              // Open scratchpad statements run in, see StartScratchpad
+	metadata map[string]metadataEntry // Introspection answers cached in remote mode
+}
+
+// NewLimitedDatabase wraps db so that at most limit statements run concurrently
+func NewLimitedDatabase(db dbinterfaces.DatabaseInterface, name string, limit int) *LimitedDatabase {
+	if limit < 1 {
+		limit = 1
+	}
+	return &LimitedDatabase{
+		DatabaseInterface: db,
+		name:              name,
+		slots:             make(chan struct{}, limit),
+	}
+}
+
+// ConcurrencyStats returns the number of running statements and the limit
+func (l *LimitedDatabase) ConcurrencyStats() (int, int) {
+	return len(l.slots), cap(l.slots)
+}
+
+// ConnectionName returns the name the connection was configured under
+func (l *LimitedDatabase) ConnectionName() string {
+	return l.name
+}
+
+// DatabaseType reports the type of the wrapped database
+func (l *LimitedDatabase) DatabaseType() string {
+	return dbinterfaces.GetDatabaseType(l.DatabaseInterface)
+}
+
+func (l *LimitedDatabase) acquire() error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(l.waitTimeout())
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("too many concurrent statements on connection '%s' (limit %d); try again later", l.name, cap(l.slots))
+	}
+}
+
+func (l *LimitedDatabase) release() {
+	<-l.slots
+}
+
+// ExecuteSQL runs a query once a slot is free
+func (l *LimitedDatabase) ExecuteSQL(query string) (*models.QueryResult, error) {
+	if err := l.checkWrite(query); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	defer l.forgetMetadata(query)
+	if s := l.activeScratchpad(); s != nil {
+		return s.executeSQL(LabelQuery(query, l.name))
+	}
+	return l.DatabaseInterface.ExecuteSQL(LabelQuery(query, l.name))
+}
+
+// ExecuteSQLWithArgs runs a parameterized statement once a slot is free
+func (l *LimitedDatabase) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
+	if err := l.checkWrite(query); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	defer l.forgetMetadata(query)
+	if s := l.activeScratchpad(); s != nil {
+		return s.executeSQL(LabelQuery(query, l.name), args...)
+	}
+	return dbinterfaces.ExecuteSQLWithArgs(l.DatabaseInterface, LabelQuery(query, l.name), args...)
+}
+
+// ExecuteSQLOnPrimary runs a parameterized statement on the primary once a
+// slot is free
+func (l *LimitedDatabase) ExecuteSQLOnPrimary(query string, args ...interface{}) (*models.QueryResult, error) {
+	if err := l.checkWrite(query); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	defer l.forgetMetadata(query)
+	if s := l.activeScratchpad(); s != nil {
+		return s.executeSQL(LabelQuery(query, l.name), args...)
+	}
+	return dbinterfaces.ExecuteSQLOnPrimary(l.DatabaseInterface, LabelQuery(query, l.name), args...)
+}
+
+// ExecuteExpectingRows runs a guarded write statement once a slot is free
+func (l *LimitedDatabase) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	if err := l.checkWrite(query); err != nil {
+		return 0, err
+	}
+	if err := l.acquire(); err != nil {
+		return 0, err
+	}
+	defer l.release()
+	defer l.forgetMetadata(query)
+	if s := l.activeScratchpad(); s != nil {
+		return s.executeExpectingRows(LabelQuery(query, l.name), expected, args...)
+	}
+	return dbinterfaces.ExecuteExpectingRows(l.DatabaseInterface, LabelQuery(query, l.name), expected, args...)
+}
+
+// ExplainQuery explains a query once a slot is free. PostgreSQL runs the
+// statement to explain it, so writes are refused on read-only connections.
+func (l *LimitedDatabase) ExplainQuery(query string) (*models.QueryResult, error) {
+	if err := l.checkWrite(query); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	if s := l.activeScratchpad(); s != nil {
+		return s.explainQuery(LabelQuery(query, l.name))
+	}
+	return l.DatabaseInterface.ExplainQuery(LabelQuery(query, l.name))
+}
+
+// OpenCursor opens a cursor once a slot is free. The cursor takes a slot
+// again for each batch it reads rather than for its whole life, so a result
+// left open does not block other statements.
+func (l *LimitedDatabase) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	if err := l.checkWrite(query); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	if s := l.activeScratchpad(); s != nil {
+		// The transaction's connection cannot read a cursor while other
+		// statements run on it, so the result is read in full
+		result, err := s.executeSQL(LabelQuery(query, l.name))
+		if err != nil {
+			return nil, err
+		}
+		return dbinterfaces.NewResultCursor(result), nil
+	}
+	cursor, err := dbinterfaces.OpenCursor(l.DatabaseInterface, LabelQuery(query, l.name))
+	if err != nil {
+		return nil, err
+	}
+	return &limitedCursor{RowCursor: cursor, limiter: l}, nil
+}
+
+// CancelRunning cancels the statements running on the connection, including
+// one running in the scratchpad. It does not wait for a slot, so it reaches
+// statements holding all of them.
+func (l *LimitedDatabase) CancelRunning() (int, error) {
+	cancelled, err := dbinterfaces.CancelRunning(l.DatabaseInterface)
+	if s := l.activeScratchpad(); s != nil {
+		n, scratchErr := s.cancel(l.DatabaseInterface)
+		cancelled += n
+		err = errors.Join(err, scratchErr)
+	}
+	return cancelled, err
+}
+
+// limitedCursor reads batches of a cursor once a slot is free
+type limitedCursor struct {
+	dbinterfaces.RowCursor
+	limiter *LimitedDatabase
+}
+
+// Next reads the next batch once a slot is free
+func (c *limitedCursor) Next(n int) ([][]interface{}, error) {
+	if err := c.limiter.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
+	return c.RowCursor.Next(n)
+}
+
+// GetAllTables lists tables once a slot is free
+func (l *LimitedDatabase) GetAllTables() ([]models.TableInfo, error) {
+	return cachedMetadata(l, "tables", func() ([]models.TableInfo, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return l.DatabaseInterface.GetAllTables()
+	})
+}
+
+// ListSchemas lists schemas once a slot is free, if the wrapped database can
+func (l *LimitedDatabase) ListSchemas() ([]string, error) {
+	provider, ok := l.DatabaseInterface.(dbinterfaces.SchemaCatalogProvider)
+	if !ok {
+		return nil, fmt.Errorf("this connection cannot list tables by schema")
+	}
+	return cachedMetadata(l, "schemas", func() ([]string, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return provider.ListSchemas()
+	})
+}
+
+// GetTablesInSchema lists the tables of one schema once a slot is free
+func (l *LimitedDatabase) GetTablesInSchema(schema string) ([]models.TableInfo, error) {
+	provider, ok := l.DatabaseInterface.(dbinterfaces.SchemaCatalogProvider)
+	if !ok {
+		return nil, fmt.Errorf("this connection cannot list tables by schema")
+	}
+	return cachedMetadata(l, "tables:"+schema, func() ([]models.TableInfo, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return provider.GetTablesInSchema(schema)
+	})
+}
+
+// GetRLSPolicies lists row-level security policies once a slot is free, if
+// the wrapped database has them
+func (l *LimitedDatabase) GetRLSPolicies(tableName string) (*models.RLSReport, error) {
+	provider, ok := l.DatabaseInterface.(dbinterfaces.RLSPolicyProvider)
+	if !ok {
+		return dbinterfaces.GetRLSPolicies(l.DatabaseInterface, tableName)
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return provider.GetRLSPolicies(tableName)
+}
+
+// GetTableSchema describes a table once a slot is free
+func (l *LimitedDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, error) {
+	return cachedMetadata(l, "columns:"+tableName, func() ([]models.ColumnInfo, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return l.DatabaseInterface.GetTableSchema(tableName)
+	})
+}
+
+// GetTableIndexes lists indexes once a slot is free
+func (l *LimitedDatabase) GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
+	return cachedMetadata(l, "indexes:"+tableName, func() ([]models.IndexInfo, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return l.DatabaseInterface.GetTableIndexes(tableName)
+	})
+}
+
+// FindDuplicateData searches for duplicates once a slot is free
+func (l *LimitedDatabase) FindDuplicateData(tableName string, columns []string) (*models.QueryResult, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.DatabaseInterface.FindDuplicateData(tableName, columns)
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/database/sqlite"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedDatabase_LimitsConcurrentStatements(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	var running, peak int32
+	mockDB.On("ExecuteSQL", mock.Anything).Run(func(mock.Arguments) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}).Return(&models.QueryResult{}, nil)
+
+	limited := NewLimitedDatabase(mockDB, "prod", 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := limited.ExecuteSQL("SELECT 1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	inUse, limit := limited.ConcurrencyStats()
+	assert.Equal(t, 0, inUse)
+	assert.Equal(t, 2, limit)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	assert.Equal(t, 2, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "prod-main"}))
+	assert.Equal(t, 8, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "local"}))
+	assert.Equal(t, 4, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "reports", Description: "staging copy"}))
+	assert.Equal(t, 4, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "main"}))
+	assert.Equal(t, 10, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "prod-main", MaxConcurrency: 10}))
+
+	t.Setenv("DBSAGE_CONCURRENCY_PRODUCTION", "1")
+	assert.Equal(t, 1, ConcurrencyLimit(&dbinterfaces.ConnectionConfig{Name: "prod-main"}))
+}
+
+func TestLimitedDatabase_OpenCursor(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	mockDB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{
+		Columns: []string{"id"},
+		Rows:    [][]interface{}{{1}, {2}, {3}},
+	}, nil)
+
+	limited := NewLimitedDatabase(mockDB, "prod", 1)
+	cursor, err := limited.OpenCursor("SELECT id FROM orders")
+	assert.NoError(t, err)
+	defer cursor.Close()
+	assert.Equal(t, []string{"id"}, cursor.Columns())
+
+	rows, err := cursor.Next(2)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	rows, err = cursor.Next(2)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{3}}, rows)
+	inUse, _ := limited.ConcurrencyStats()
+	assert.Equal(t, 0, inUse, "an open cursor does not hold a slot between batches")
+
+	limited.SetReadOnly(true)
+	_, err = limited.OpenCursor("DELETE FROM orders RETURNING id")
+	assert.ErrorIs(t, err, ErrReadOnlyConnection)
+}
+
+func TestLimitedDatabase_CancelRunning(t *testing.T) {
+	db, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "shop.db"))
+	require.NoError(t, err)
+	limited := NewLimitedDatabase(db, "shop", 1)
+	defer limited.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		// Never finishes on its own, and holds the only slot
+		_, err := limited.ExecuteSQL("WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c")
+		errs <- err
+	}()
+	require.Eventually(t, func() bool {
+		cancelled, err := limited.CancelRunning()
+		require.NoError(t, err)
+		return cancelled == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("statement was not cancelled")
+	}
+	result, err := limited.ExecuteSQL("SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.RowCount, "the connection is usable after a cancellation")
+}
+
+// rlsDatabase is a mock database with row-level security policies
+type rlsDatabase struct {
+	*MockDatabaseInterface
+}
+
+func (r rlsDatabase) GetRLSPolicies(tableName string) (*models.RLSReport, error) {
+	return &models.RLSReport{}, nil
+}
+
+func TestNewConnection_ForwardsRLSPolicies(t *testing.T) {
+	direct := newConnection(rlsDatabase{&MockDatabaseInterface{}}, "app", &dbinterfaces.ConnectionConfig{Name: "app"})
+	report, err := dbinterfaces.GetRLSPolicies(direct, "orders")
+	require.NoError(t, err)
+	assert.NotNil(t, report)
+
+	replicated := NewReplicatedDatabase(rlsDatabase{&MockDatabaseInterface{}}, "db:5432", map[string]dbinterfaces.DatabaseInterface{
+		"replica:5432": rlsDatabase{&MockDatabaseInterface{}},
+	})
+	report, err = dbinterfaces.GetRLSPolicies(newConnection(replicated, "app", &dbinterfaces.ConnectionConfig{Name: "app"}), "orders")
+	require.NoError(t, err)
+	assert.NotNil(t, report)
+
+	_, err = dbinterfaces.GetRLSPolicies(newConnection(&MockDatabaseInterface{}, "app", &dbinterfaces.ConnectionConfig{Name: "app"}), "orders")
+	assert.EqualError(t, err, "row-level security policies are only available for PostgreSQL connections")
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// DefaultMonitorInterval is how often the health monitor pings the open connections
+const DefaultMonitorInterval = 15 * time.Second
+
+// MonitorInterval returns the interval of the background health monitor, set
+// with DBSAGE_HEALTH_INTERVAL (0 disables the monitor)
+func MonitorInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_HEALTH_INTERVAL")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultMonitorInterval
+}
+
+// idleConnections is implemented by connection managers that can check and
+// reopen the connections other than the current one
+type idleConnections interface {
+	IdleConnections() []string
+	PingConnection(name string) error
+	Reconnect(name string) error
+}
+
+// healthMonitor pings the open connections on an interval and reopens the
+// ones that dropped, backing off like the lazy checks of GetCurrentTools
+type healthMonitor struct {
+	stop chan struct{}
+	done chan struct{}
+
+	mu   sync.Mutex
+	idle map[string]*healthTracker // Open connections other than the current one, by name
+}
+
+// StartHealthMonitor checks the current and the other open connections every
+// interval in the background instead of only when the current one is used,
+// and reconnects dropped ones with exponential backoff. onChange, which may be
+// nil, is called from the monitor's goroutine when a connection's health
+// changes. Connections that were never opened are left closed. Starting a
+// running monitor or an interval of 0 does nothing.
+func (cs *ConnectionService) StartHealthMonitor(interval time.Duration, onChange func()) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.monitor != nil || interval <= 0 {
+		return
+	}
+	monitor := &healthMonitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		idle: make(map[string]*healthTracker),
+	}
+	cs.monitor = monitor
+
+	go func() {
+		defer close(monitor.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-monitor.stop:
+				return
+			case now := <-ticker.C:
+				before := cs.healthSummary()
+				cs.checkHealth(now)
+				if onChange != nil && cs.healthSummary() != before {
+					onChange()
+				}
+			}
+		}
+	}()
+}
+
+// StopHealthMonitor stops the background health checks and waits for a check
+// in progress to finish
+func (cs *ConnectionService) StopHealthMonitor() {
+	cs.mu.Lock()
+	monitor := cs.monitor
+	cs.monitor = nil
+	cs.mu.Unlock()
+	if monitor == nil {
+		return
+	}
+	close(monitor.stop)
+	<-monitor.done
+}
+
+// checkHealth runs one round of the monitor: the current connection is pinged
+// regardless of the check TTL and restored when it fails, then every other
+// open connection is pinged and reopened when it fails, unless its backoff
+// has not expired yet
+func (cs *ConnectionService) checkHealth(now time.Time) {
+	cs.mu.Lock()
+	switch {
+	case cs.current != nil && cs.current.IsConnectionHealthy():
+		cs.health.recordSuccess(now)
+	case cs.current != nil || (cs.health.hasFailed() && cs.health.attemptAllowed(now)):
+		cs.refreshCurrent(now)
+		if cs.current != nil {
+			log.Printf("Reconnected to database: %s", cs.currentName)
+		}
+	}
+	monitor := cs.monitor
+	cs.mu.Unlock()
+
+	idle, ok := cs.manager.(idleConnections)
+	if !ok || monitor == nil {
+		return
+	}
+	names := idle.IdleConnections()
+	policy := cs.healthPolicy()
+
+	monitor.mu.Lock()
+	trackers := make(map[string]*healthTracker, len(names))
+	for _, name := range names {
+		tracker := monitor.idle[name]
+		if tracker == nil {
+			tracker = &healthTracker{}
+		}
+		tracker.mu.Lock()
+		tracker.policy = policy
+		tracker.mu.Unlock()
+		trackers[name] = tracker
+	}
+	// Connections closed or made current since the last round are forgotten
+	monitor.idle = trackers
+	monitor.mu.Unlock()
+
+	for _, name := range names {
+		tracker := trackers[name]
+		if !tracker.attemptAllowed(now) {
+			continue
+		}
+		if err := idle.PingConnection(name); err == nil {
+			tracker.recordSuccess(now)
+			continue
+		}
+		if err := idle.Reconnect(name); err != nil {
+			tracker.recordFailure(now, err)
+			continue
+		}
+		log.Printf("Reconnected to database: %s", name)
+		tracker.recordSuccess(now)
+	}
+}
+
+// healthPolicy returns the health check policy of the current connection
+func (cs *ConnectionService) healthPolicy() HealthPolicy {
+	cs.health.mu.Lock()
+	defer cs.health.mu.Unlock()
+	return cs.health.policy
+}
+
+// ConnectionsHealth returns the health of the open connections other than the
+// current one, as last seen by the health monitor. It is empty while the
+// monitor is not running.
+func (cs *ConnectionService) ConnectionsHealth() map[string]dbinterfaces.HealthStatus {
+	cs.mu.Lock()
+	monitor := cs.monitor
+	cs.mu.Unlock()
+
+	health := make(map[string]dbinterfaces.HealthStatus)
+	if monitor == nil {
+		return health
+	}
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	for name, tracker := range monitor.idle {
+		health[name] = tracker.status()
+	}
+	return health
+}
+
+// healthSummary describes the state of every monitored connection, for
+// noticing when one changes
+func (cs *ConnectionService) healthSummary() string {
+	current := cs.health.status()
+	parts := []string{fmt.Sprintf("current=%s/%d", current.State, current.ConsecutiveFailures)}
+	for name, status := range cs.ConnectionsHealth() {
+		parts = append(parts, fmt.Sprintf("%s=%s", name, status.State))
+	}
+	sort.Strings(parts[1:])
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdleManager is a connection manager whose idle connections can be
+// pinged and reopened
+type mockIdleManager struct {
+	*MockConnectionManager
+}
+
+func (m mockIdleManager) IdleConnections() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m mockIdleManager) PingConnection(name string) error {
+	return m.Called(name).Error(0)
+}
+
+func (m mockIdleManager) Reconnect(name string) error {
+	return m.Called(name).Error(0)
+}
+
+func TestConnectionService_CheckHealth_RestoresCurrent(t *testing.T) {
+	mockManager := &MockConnectionManager{}
+	mockDB := &MockDatabaseInterface{}
+	service := &ConnectionService{manager: mockManager, current: mockDB, currentName: "main"}
+	service.SetHealthPolicy(HealthPolicy{CheckTTL: time.Hour})
+
+	// The monitor pings even within the check TTL
+	mockDB.On("IsConnectionHealthy").Return(true).Once()
+	service.checkHealth(time.Now())
+	mockDB.On("IsConnectionHealthy").Return(false).Once()
+	restored := &MockDatabaseInterface{}
+	mockManager.On("GetCurrentConnection").Return(restored, "main", nil).Once()
+	service.checkHealth(time.Now())
+
+	assert.Equal(t, restored, service.current)
+	assert.Equal(t, dbinterfaces.HealthHealthy, service.GetHealthStatus().State)
+	mockDB.AssertExpectations(t)
+	mockManager.AssertExpectations(t)
+}
+
+func TestConnectionService_CheckHealth_ReconnectsIdleWithBackoff(t *testing.T) {
+	mockManager := mockIdleManager{&MockConnectionManager{}}
+	service := &ConnectionService{manager: mockManager, monitor: &healthMonitor{idle: map[string]*healthTracker{}}}
+	service.SetHealthPolicy(HealthPolicy{FailureThreshold: 1, BaseBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	mockManager.On("IdleConnections").Return([]string{"reporting", "staging"})
+	mockManager.On("PingConnection", "staging").Return(nil)
+	mockManager.On("PingConnection", "reporting").Return(errors.New("broken pipe"))
+	mockManager.On("Reconnect", "reporting").Return(errors.New("connection refused")).Once()
+
+	now := time.Now()
+	service.checkHealth(now)
+	health := service.ConnectionsHealth()
+	assert.Equal(t, dbinterfaces.HealthHealthy, health["staging"].State)
+	assert.Equal(t, dbinterfaces.HealthDown, health["reporting"].State)
+	assert.Equal(t, "connection refused", health["reporting"].LastError)
+
+	// Within the backoff the connection is left alone, after it it is reopened
+	service.checkHealth(now.Add(30 * time.Second))
+	mockManager.AssertNumberOfCalls(t, "Reconnect", 1)
+	mockManager.On("Reconnect", "reporting").Return(nil).Once()
+	service.checkHealth(now.Add(2 * time.Minute))
+	assert.Equal(t, dbinterfaces.HealthHealthy, service.ConnectionsHealth()["reporting"].State)
+	mockManager.AssertExpectations(t)
+}
+
+func TestConnectionService_HealthMonitor(t *testing.T) {
+	mockManager := &MockConnectionManager{}
+	mockDB := &MockDatabaseInterface{}
+	service := &ConnectionService{manager: mockManager, current: mockDB}
+	mockDB.On("IsConnectionHealthy").Return(true)
+
+	changed := make(chan struct{}, 1)
+	service.StartHealthMonitor(5*time.Millisecond, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		require.Fail(t, "the monitor did not report the first check")
+	}
+	service.StopHealthMonitor()
+
+	assert.Equal(t, dbinterfaces.HealthHealthy, service.GetHealthStatus().State)
+	assert.Empty(t, service.ConnectionsHealth())
+}
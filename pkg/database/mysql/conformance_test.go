@@ -0,0 +1,19 @@
+package mysql
+
+import (
+	"testing"
+
+	"dbsage/pkg/database/drivertest"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	url := drivertest.RequireEnv(t, "DBSAGE_TEST_MYSQL_URL")
+	drivertest.Run(t, func(t *testing.T) dbinterfaces.DatabaseInterface {
+		db, err := NewMySQLDatabase(url)
+		require.NoError(t, err)
+		return db
+	}, drivertest.Options{Dialect: "mysql"})
+}
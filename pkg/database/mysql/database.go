@@ -39,6 +39,11 @@ func NewMySQLDatabase(connectionURL string) (*MySQLDatabase, error) {
 	}, nil
 }
 
+// DatabaseType returns the database type name
+func (m *MySQLDatabase) DatabaseType() string {
+	return "mysql"
+}
+
 // Close closes the database connection
 func (m *MySQLDatabase) Close() error {
 	if m.db != nil {
@@ -68,11 +73,36 @@ func (m *MySQLDatabase) ExecuteSQL(query string) (*models.QueryResult, error) {
 	return m.queryExecutor.ExecuteSQL(query)
 }
 
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters
+func (m *MySQLDatabase) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
+	return m.queryExecutor.ExecuteSQLWithArgs(query, args...)
+}
+
+// ExecuteExpectingRows runs a write statement that is rolled back unless it affects the expected rows
+func (m *MySQLDatabase) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	return m.queryExecutor.ExecuteExpectingRows(query, expected, args...)
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (m *MySQLDatabase) ExplainQuery(query string) (*models.QueryResult, error) {
 	return m.queryExecutor.ExplainQuery(query)
 }
 
+// OpenCursor runs a query and returns a cursor reading its rows in batches
+func (m *MySQLDatabase) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	return m.queryExecutor.OpenCursor(query)
+}
+
+// BeginTx starts a transaction on one connection of the pool
+func (m *MySQLDatabase) BeginTx() (*sql.Tx, error) {
+	return m.db.Begin()
+}
+
+// CancelRunning cancels the statements the connection is running
+func (m *MySQLDatabase) CancelRunning() (int, error) {
+	return m.queryExecutor.CancelRunning()
+}
+
 // GetAllTables returns a list of all tables
 func (m *MySQLDatabase) GetAllTables() ([]models.TableInfo, error) {
 	query := `
@@ -117,7 +147,8 @@ func (m *MySQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, e
 		"numeric_scale, " +
 		"CASE WHEN column_key = 'PRI' THEN true ELSE false END as is_primary_key, " +
 		"CASE WHEN column_key = 'MUL' THEN true ELSE false END as is_foreign_key, " +
-		"COALESCE(column_comment, '') as description " +
+		"COALESCE(column_comment, '') as description, " +
+		"column_type " +
 		"FROM information_schema.columns " +
 		"WHERE table_schema = DATABASE() AND table_name = ? " +
 		"ORDER BY ordinal_position"
@@ -133,6 +164,7 @@ func (m *MySQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, e
 		var col models.ColumnInfo
 		var defaultValue sql.NullString
 		var description sql.NullString
+		var columnType string
 		err := rows.Scan(
 			&col.ColumnName,
 			&col.DataType,
@@ -144,6 +176,7 @@ func (m *MySQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, e
 			&col.IsPrimaryKey,
 			&col.IsForeignKey,
 			&description,
+			&columnType,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan column row: %w", err)
@@ -156,6 +189,7 @@ func (m *MySQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, e
 		if description.Valid {
 			col.Description = description.String
 		}
+		col.EnumValues = parseEnumValues(columnType)
 
 		columns = append(columns, col)
 	}
@@ -163,6 +197,36 @@ func (m *MySQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, e
 	return columns, nil
 }
 
+// parseEnumValues extracts the allowed values from a column type such as enum('a','b')
+func parseEnumValues(columnType string) []string {
+	lower := strings.ToLower(columnType)
+	if !strings.HasPrefix(lower, "enum(") || !strings.HasSuffix(lower, ")") {
+		return nil
+	}
+	body := columnType[len("enum(") : len(columnType)-1]
+
+	var values []string
+	var current strings.Builder
+	inQuote := false
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		switch {
+		case ch == '\'' && inQuote && i+1 < len(body) && body[i+1] == '\'':
+			current.WriteByte('\'')
+			i++
+		case ch == '\'':
+			if inQuote {
+				values = append(values, current.String())
+				current.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			current.WriteByte(ch)
+		}
+	}
+	return values
+}
+
 // GetTableIndexes returns index information for a table
 func (m *MySQLDatabase) GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
 	query := "SELECT " +
@@ -34,6 +34,7 @@ func (p *MySQLProvider) CreateConnection(config *dbinterfaces.ConnectionConfig)
 		Timeout:     "30s",                // Default timeout
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	}
 
 	if err := p.validateLocalConfig(localConfig); err != nil {
@@ -63,6 +64,7 @@ func (p *MySQLProvider) ValidateConfig(config *dbinterfaces.ConnectionConfig) er
 		Timeout:     "30s",
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
@@ -106,6 +108,7 @@ func (p *MySQLProvider) BuildConnectionURL(config *dbinterfaces.ConnectionConfig
 		Timeout:     "30s",
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
@@ -128,6 +131,13 @@ func (p *MySQLProvider) buildLocalConnectionURL(config *ConnectionConfig) string
 	params["writeTimeout"] = config.Timeout
 	params["parseTime"] = "true"
 	params["loc"] = "Local"
+	// Report matched rather than changed rows, so guarded updates count rows set to their current value
+	params["clientFoundRows"] = "true"
+	if config.ReadOnly {
+		// The driver sets unknown parameters as session variables on connect,
+		// so the server refuses writes in every session
+		params["transaction_read_only"] = "1"
+	}
 
 	// Build query string
 	var paramPairs []string
@@ -1,37 +1,78 @@
 package queries
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"dbsage/internal/models"
+	"dbsage/pkg/database/sessions"
 	"dbsage/pkg/dbinterfaces"
 )
 
 // MySQLExecutor handles SQL query execution for MySQL
 type MySQLExecutor struct {
-	db *sql.DB
+	db       *sql.DB
+	sessions *sessions.Tracker // Connections running statements, for CancelRunning
 }
 
 // NewMySQLExecutor creates a new MySQL query executor
 func NewMySQLExecutor(db *sql.DB) *MySQLExecutor {
-	return &MySQLExecutor{db: db}
+	return &MySQLExecutor{
+		db:       db,
+		sessions: sessions.NewTracker(db, "SELECT CONNECTION_ID()", killQuery),
+	}
 }
 
 // Ensure MySQLExecutor implements QueryExecutorInterface
 var _ dbinterfaces.QueryExecutorInterface = (*MySQLExecutor)(nil)
+var _ dbinterfaces.ParameterizedExecutor = (*MySQLExecutor)(nil)
+var _ dbinterfaces.GuardedExecutor = (*MySQLExecutor)(nil)
+var _ dbinterfaces.Canceller = (*MySQLExecutor)(nil)
+
+// killQuery cancels the statement of a connection with KILL QUERY, which
+// leaves the connection open
+func killQuery(ctx context.Context, db *sql.DB, id int64) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", id))
+	return err
+}
+
+// CancelRunning cancels the statements running on the server from another
+// connection and returns how many were cancelled
+func (e *MySQLExecutor) CancelRunning() (int, error) {
+	return e.sessions.Cancel()
+}
 
 // ExecuteSQL executes a SQL query and returns structured results
 func (e *MySQLExecutor) ExecuteSQL(query string) (*models.QueryResult, error) {
+	return e.ExecuteSQLWithArgs(query)
+}
+
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters and returns structured results
+func (e *MySQLExecutor) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
 	start := time.Now()
 
-	rows, err := e.db.Query(query)
+	var result *models.QueryResult
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query execution failed: %w", err)
+		}
+		defer rows.Close()
+		result, err = readResult(rows)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
+	result.Duration = time.Since(start).String()
+	return result, nil
+}
+
+// readResult reads the columns and rows of a query
+func readResult(rows *sql.Rows) (*models.QueryResult, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
@@ -76,16 +117,50 @@ func (e *MySQLExecutor) ExecuteSQL(query string) (*models.QueryResult, error) {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	duration := time.Since(start)
-
 	return &models.QueryResult{
 		Columns:  columns,
 		Rows:     resultRows,
 		RowCount: len(resultRows),
-		Duration: duration.String(),
 	}, nil
 }
 
+// ExecuteExpectingRows runs a write statement in a transaction and commits it
+// only if it affected exactly the expected number of rows
+func (e *MySQLExecutor) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	var affected int64
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("statement execution failed: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rowsAffected != expected {
+			affected = rowsAffected
+			if err := tx.Rollback(); err != nil {
+				return fmt.Errorf("failed to roll back transaction: %w", err)
+			}
+			return &dbinterfaces.RowCountMismatchError{Expected: expected, Actual: rowsAffected}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		affected = rowsAffected
+		return nil
+	})
+	return affected, err
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (e *MySQLExecutor) ExplainQuery(query string) (*models.QueryResult, error) {
 	explainQuery := fmt.Sprintf("EXPLAIN FORMAT=JSON %s", query)
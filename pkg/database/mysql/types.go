@@ -13,4 +13,5 @@ type ConnectionConfig struct {
 	Timeout     string `json:"timeout"`   // Connection timeout
 	Description string `json:"description"`
 	LastUsed    string `json:"last_used,omitempty"` // ISO 8601 timestamp
+	ReadOnly    bool   `json:"read_only,omitempty"` // Make the session read-only
 }
@@ -0,0 +1,19 @@
+package postgresql
+
+import (
+	"testing"
+
+	"dbsage/pkg/database/drivertest"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	url := drivertest.RequireEnv(t, "DBSAGE_TEST_POSTGRES_URL")
+	drivertest.Run(t, func(t *testing.T) dbinterfaces.DatabaseInterface {
+		db, err := NewPostgreSQLDatabase(url)
+		require.NoError(t, err)
+		return db
+	}, drivertest.Options{Dialect: "postgresql"})
+}
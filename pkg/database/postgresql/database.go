@@ -9,7 +9,7 @@ import (
 	"dbsage/pkg/database/postgresql/queries"
 	"dbsage/pkg/dbinterfaces"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgreSQLDatabase implements the DatabaseInterface for PostgreSQL
@@ -39,6 +39,11 @@ func NewPostgreSQLDatabase(connectionURL string) (*PostgreSQLDatabase, error) {
 	}, nil
 }
 
+// DatabaseType returns the database type name
+func (pg *PostgreSQLDatabase) DatabaseType() string {
+	return "postgresql"
+}
+
 // Close closes the database connection
 func (pg *PostgreSQLDatabase) Close() error {
 	if pg.db != nil {
@@ -68,11 +73,36 @@ func (pg *PostgreSQLDatabase) ExecuteSQL(query string) (*models.QueryResult, err
 	return pg.queryExecutor.ExecuteSQL(query)
 }
 
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters
+func (pg *PostgreSQLDatabase) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
+	return pg.queryExecutor.ExecuteSQLWithArgs(query, args...)
+}
+
+// ExecuteExpectingRows runs a write statement that is rolled back unless it affects the expected rows
+func (pg *PostgreSQLDatabase) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	return pg.queryExecutor.ExecuteExpectingRows(query, expected, args...)
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (pg *PostgreSQLDatabase) ExplainQuery(query string) (*models.QueryResult, error) {
 	return pg.queryExecutor.ExplainQuery(query)
 }
 
+// OpenCursor runs a query and returns a cursor reading its rows in batches
+func (pg *PostgreSQLDatabase) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	return pg.queryExecutor.OpenCursor(query)
+}
+
+// BeginTx starts a transaction on one connection of the pool
+func (pg *PostgreSQLDatabase) BeginTx() (*sql.Tx, error) {
+	return pg.db.Begin()
+}
+
+// CancelRunning cancels the statements the connection is running
+func (pg *PostgreSQLDatabase) CancelRunning() (int, error) {
+	return pg.queryExecutor.CancelRunning()
+}
+
 // GetAllTables returns a list of all tables
 func (pg *PostgreSQLDatabase) GetAllTables() ([]models.TableInfo, error) {
 	query := `
@@ -106,6 +136,64 @@ func (pg *PostgreSQLDatabase) GetAllTables() ([]models.TableInfo, error) {
 	return tables, nil
 }
 
+// ListSchemas lists the user schemas, the current schema first
+func (pg *PostgreSQLDatabase) ListSchemas() ([]string, error) {
+	rows, err := pg.db.Query(`
+		SELECT nspname
+		FROM pg_namespace
+		WHERE nspname NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
+			AND nspname NOT LIKE 'pg_temp_%'
+			AND nspname NOT LIKE 'pg_toast_temp_%'
+		ORDER BY nspname <> current_schema(), nspname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, fmt.Errorf("failed to scan schema row: %w", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// GetTablesInSchema lists the tables of one schema
+func (pg *PostgreSQLDatabase) GetTablesInSchema(schema string) ([]models.TableInfo, error) {
+	query := `
+		SELECT
+			t.table_name,
+			t.table_schema,
+			t.table_type,
+			COALESCE(obj_description(c.oid, 'pg_class'), '') as table_comment
+		FROM information_schema.tables t
+		LEFT JOIN pg_namespace n ON n.nspname = t.table_schema
+		LEFT JOIN pg_class c ON c.relname = t.table_name AND c.relnamespace = n.oid
+		WHERE t.table_schema = $1
+		ORDER BY t.table_name
+	`
+
+	rows, err := pg.db.Query(query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables of schema %s: %w", schema, err)
+	}
+	defer rows.Close()
+
+	var tables []models.TableInfo
+	for rows.Next() {
+		var table models.TableInfo
+		if err := rows.Scan(&table.TableName, &table.Schema, &table.TableType, &table.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan table row: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
 // GetTableSchema returns detailed schema information for a table
 func (pg *PostgreSQLDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, error) {
 	query := `
@@ -119,7 +207,14 @@ func (pg *PostgreSQLDatabase) GetTableSchema(tableName string) ([]models.ColumnI
 			isc.numeric_scale,
 			CASE WHEN pk."column_name" IS NOT NULL THEN true ELSE false END as is_primary_key,
 			CASE WHEN fk."column_name" IS NOT NULL THEN true ELSE false END as is_foreign_key,
-			COALESCE(col_description(c.oid, a.attnum), '') as description
+			COALESCE(col_description(c.oid, a.attnum), '') as description,
+			ARRAY(
+				SELECT e.enumlabel
+				FROM pg_type t
+				JOIN pg_enum e ON e.enumtypid = t.oid
+				WHERE t.typname = isc.udt_name
+				ORDER BY e.enumsortorder
+			) as enum_values
 		FROM information_schema.columns isc
 		LEFT JOIN pg_class c ON c.relname = isc.table_name
 		LEFT JOIN pg_attribute a ON a.attrelid = c.oid AND a.attname = isc."column_name"
@@ -161,6 +256,7 @@ func (pg *PostgreSQLDatabase) GetTableSchema(tableName string) ([]models.ColumnI
 			&col.IsPrimaryKey,
 			&col.IsForeignKey,
 			&description,
+			pq.Array(&col.EnumValues),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan column row: %w", err)
@@ -177,6 +273,15 @@ func (pg *PostgreSQLDatabase) GetTableSchema(tableName string) ([]models.ColumnI
 		columns = append(columns, col)
 	}
 
+	for _, col := range columns {
+		if col.DataType == "USER-DEFINED" && len(col.EnumValues) == 0 {
+			if err := pg.addSpatialInfo(tableName, columns); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
 	return columns, nil
 }
 
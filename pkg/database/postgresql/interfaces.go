@@ -5,5 +5,7 @@ import "dbsage/internal/models"
 // QueryExecutorInterface defines the interface for query execution
 type QueryExecutorInterface interface {
 	ExecuteSQL(query string) (*models.QueryResult, error)
+	ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error)
+	ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error)
 	ExplainQuery(query string) (*models.QueryResult, error)
 }
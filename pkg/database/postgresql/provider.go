@@ -31,6 +31,7 @@ func (p *PostgreSQLProvider) CreateConnection(config *dbinterfaces.ConnectionCon
 		SSLMode:     config.SSLMode,
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	}
 
 	if err := p.validateLocalConfig(localConfig); err != nil {
@@ -58,6 +59,7 @@ func (p *PostgreSQLProvider) ValidateConfig(config *dbinterfaces.ConnectionConfi
 		SSLMode:     config.SSLMode,
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
@@ -93,17 +95,24 @@ func (p *PostgreSQLProvider) BuildConnectionURL(config *dbinterfaces.ConnectionC
 		SSLMode:     config.SSLMode,
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
-// buildLocalConnectionURL builds a PostgreSQL connection URL from the local configuration
+// buildLocalConnectionURL builds a PostgreSQL connection URL from the local
+// configuration. Read-only connections pass default_transaction_read_only as
+// a startup parameter, so the server refuses writes in every session.
 func (p *PostgreSQLProvider) buildLocalConnectionURL(config *ConnectionConfig) string {
+	readOnly := ""
+	if config.ReadOnly {
+		readOnly = "&default_transaction_read_only=on"
+	}
 	if config.Password == "" {
-		return fmt.Sprintf("postgres://%s@%s:%d/%s?sslmode=%s",
+		return fmt.Sprintf("postgres://%s@%s:%d/%s?sslmode=%s%s",
 			url.QueryEscape(config.Username), config.Host, config.Port,
-			url.QueryEscape(config.Database), config.SSLMode)
+			url.QueryEscape(config.Database), config.SSLMode, readOnly)
 	}
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s%s",
 		url.QueryEscape(config.Username), url.QueryEscape(config.Password),
-		config.Host, config.Port, url.QueryEscape(config.Database), config.SSLMode)
+		config.Host, config.Port, url.QueryEscape(config.Database), config.SSLMode, readOnly)
 }
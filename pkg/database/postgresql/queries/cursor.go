@@ -0,0 +1,97 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"dbsage/pkg/dbinterfaces"
+)
+
+// Ensure PostgreSQLExecutor can stream results
+var _ dbinterfaces.StreamingExecutor = (*PostgreSQLExecutor)(nil)
+
+// OpenCursor runs a query and returns a cursor reading its rows in batches.
+// The cursor holds a connection of the pool until it is closed.
+func (e *PostgreSQLExecutor) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	cursor := &rowsCursor{}
+	release, err := e.sessions.Pin(func(ctx context.Context, conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("query execution failed: %w", err)
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to get column names: %w", err)
+		}
+		cursor.rows, cursor.columns = rows, columns
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	cursor.release = release
+	return cursor, nil
+}
+
+// rowsCursor reads the rows of an open query as they are needed
+type rowsCursor struct {
+	rows    *sql.Rows
+	columns []string
+	release func() // Returns the connection to the pool
+	done    bool
+}
+
+// Columns returns the column names of the query
+func (c *rowsCursor) Columns() []string {
+	return c.columns
+}
+
+// Next returns up to n more rows, closing the rows at the end of the result
+func (c *rowsCursor) Next(n int) ([][]interface{}, error) {
+	var result [][]interface{}
+	if c.done {
+		return result, nil
+	}
+
+	values := make([]interface{}, len(c.columns))
+	valuePtrs := make([]interface{}, len(c.columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	for len(result) < n {
+		if !c.rows.Next() {
+			c.done = true
+			if err := c.rows.Err(); err != nil {
+				return result, fmt.Errorf("error iterating rows: %w", err)
+			}
+			err := c.rows.Close()
+			c.release()
+			return result, err
+		}
+		if err := c.rows.Scan(valuePtrs...); err != nil {
+			return result, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make([]interface{}, len(c.columns))
+		for i, val := range values {
+			if v, ok := val.([]byte); ok {
+				row[i] = string(v)
+			} else {
+				row[i] = val
+			}
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// Close releases the connection held by the cursor
+func (c *rowsCursor) Close() error {
+	c.done = true
+	err := c.rows.Close()
+	c.release()
+	return err
+}
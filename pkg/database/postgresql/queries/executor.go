@@ -1,37 +1,77 @@
 package queries
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"dbsage/internal/models"
+	"dbsage/pkg/database/sessions"
 	"dbsage/pkg/dbinterfaces"
 )
 
 // PostgreSQLExecutor handles SQL query execution for PostgreSQL
 type PostgreSQLExecutor struct {
-	db *sql.DB
+	db       *sql.DB
+	sessions *sessions.Tracker // Backends running statements, for CancelRunning
 }
 
 // NewPostgreSQLExecutor creates a new PostgreSQL query executor
 func NewPostgreSQLExecutor(db *sql.DB) *PostgreSQLExecutor {
-	return &PostgreSQLExecutor{db: db}
+	return &PostgreSQLExecutor{
+		db:       db,
+		sessions: sessions.NewTracker(db, "SELECT pg_backend_pid()", cancelBackend),
+	}
 }
 
 // Ensure PostgreSQLExecutor implements QueryExecutorInterface
 var _ dbinterfaces.QueryExecutorInterface = (*PostgreSQLExecutor)(nil)
+var _ dbinterfaces.ParameterizedExecutor = (*PostgreSQLExecutor)(nil)
+var _ dbinterfaces.GuardedExecutor = (*PostgreSQLExecutor)(nil)
+var _ dbinterfaces.Canceller = (*PostgreSQLExecutor)(nil)
+
+// cancelBackend cancels the statement of a backend with pg_cancel_backend
+func cancelBackend(ctx context.Context, db *sql.DB, pid int64) error {
+	_, err := db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", pid)
+	return err
+}
+
+// CancelRunning cancels the statements running on the server from another
+// connection and returns how many were cancelled
+func (e *PostgreSQLExecutor) CancelRunning() (int, error) {
+	return e.sessions.Cancel()
+}
 
 // ExecuteSQL executes a SQL query and returns structured results
 func (e *PostgreSQLExecutor) ExecuteSQL(query string) (*models.QueryResult, error) {
+	return e.ExecuteSQLWithArgs(query)
+}
+
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters and returns structured results
+func (e *PostgreSQLExecutor) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
 	start := time.Now()
 
-	rows, err := e.db.Query(query)
+	var result *models.QueryResult
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query execution failed: %w", err)
+		}
+		defer rows.Close()
+		result, err = readResult(rows)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
+	result.Duration = time.Since(start).String()
+	return result, nil
+}
+
+// readResult reads the columns and rows of a query
+func readResult(rows *sql.Rows) (*models.QueryResult, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
@@ -76,16 +116,50 @@ func (e *PostgreSQLExecutor) ExecuteSQL(query string) (*models.QueryResult, erro
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	duration := time.Since(start)
-
 	return &models.QueryResult{
 		Columns:  columns,
 		Rows:     resultRows,
 		RowCount: len(resultRows),
-		Duration: duration.String(),
 	}, nil
 }
 
+// ExecuteExpectingRows runs a write statement in a transaction and commits it
+// only if it affected exactly the expected number of rows
+func (e *PostgreSQLExecutor) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	var affected int64
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("statement execution failed: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rowsAffected != expected {
+			affected = rowsAffected
+			if err := tx.Rollback(); err != nil {
+				return fmt.Errorf("failed to roll back transaction: %w", err)
+			}
+			return &dbinterfaces.RowCountMismatchError{Expected: expected, Actual: rowsAffected}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		affected = rowsAffected
+		return nil
+	})
+	return affected, err
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (e *PostgreSQLExecutor) ExplainQuery(query string) (*models.QueryResult, error) {
 	explainQuery := fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) %s", query)
@@ -0,0 +1,110 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// rlsTableFilter selects user tables, either the named one ($1 = table, $2 =
+// schema or an empty string) or, when no table is named, those using row-level security
+const rlsTableFilter = `
+	c.relkind IN ('r', 'p')
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND CASE WHEN $1::text = '' THEN c.relrowsecurity OR EXISTS (SELECT 1 FROM pg_policy p2 WHERE p2.polrelid = c.oid)
+		ELSE c.relname = $1::text AND ($2::text = '' OR n.nspname = $2::text) END`
+
+// policyCommands maps pg_policy.polcmd to the command it applies to
+var policyCommands = map[string]string{
+	"*": "ALL",
+	"r": "SELECT",
+	"a": "INSERT",
+	"w": "UPDATE",
+	"d": "DELETE",
+}
+
+// GetRLSPolicies returns the row-level security policies of a table, or of
+// every table using them when tableName is empty, with the role attributes
+// that decide whether they apply to the connected role
+func (pg *PostgreSQLDatabase) GetRLSPolicies(tableName string) (*models.RLSReport, error) {
+	schema, table := "", tableName
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		schema, table = tableName[:i], tableName[i+1:]
+	}
+
+	report := &models.RLSReport{}
+	err := pg.db.QueryRow(`SELECT current_user, rolsuper, rolbypassrls FROM pg_roles WHERE rolname = current_user`).
+		Scan(&report.CurrentRole, &report.Superuser, &report.BypassRLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current role: %w", err)
+	}
+
+	rows, err := pg.db.Query(`
+		SELECT n.nspname, c.relname, pg_get_userbyid(c.relowner), c.relrowsecurity, c.relforcerowsecurity,
+			pg_has_role(current_user, c.relowner, 'USAGE')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE `+rlsTableFilter+`
+		ORDER BY n.nspname, c.relname
+	`, table, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query row-level security: %w", err)
+	}
+	defer rows.Close()
+
+	byName := make(map[string]int)
+	for rows.Next() {
+		var t models.RLSTable
+		if err := rows.Scan(&t.Schema, &t.Table, &t.Owner, &t.Enabled, &t.Forced, &t.OwnedByCurrentRole); err != nil {
+			return nil, fmt.Errorf("failed to scan table row: %w", err)
+		}
+		byName[t.Schema+"."+t.Table] = len(report.Tables)
+		report.Tables = append(report.Tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read row-level security: %w", err)
+	}
+	if tableName != "" && len(report.Tables) == 0 {
+		return nil, fmt.Errorf("table %s not found", tableName)
+	}
+
+	policyRows, err := pg.db.Query(`
+		SELECT n.nspname, c.relname, p.polname, p.polcmd, p.polpermissive,
+			COALESCE(array_to_string(ARRAY(
+				SELECT CASE WHEN r = 0 THEN 'public' ELSE pg_get_userbyid(r) END FROM unnest(p.polroles) r
+			), ','), ''),
+			COALESCE(pg_get_expr(p.polqual, p.polrelid), ''),
+			COALESCE(pg_get_expr(p.polwithcheck, p.polrelid), ''),
+			EXISTS (SELECT 1 FROM unnest(p.polroles) r WHERE r = 0 OR pg_has_role(current_user, r, 'USAGE'))
+		FROM pg_policy p
+		JOIN pg_class c ON c.oid = p.polrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE `+rlsTableFilter+`
+		ORDER BY n.nspname, c.relname, p.polname
+	`, table, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query row-level security policies: %w", err)
+	}
+	defer policyRows.Close()
+
+	for policyRows.Next() {
+		var schemaName, relName, command, roles string
+		var p models.RLSPolicy
+		if err := policyRows.Scan(&schemaName, &relName, &p.Name, &command, &p.Permissive, &roles, &p.Using, &p.Check, &p.AppliesToCurrentRole); err != nil {
+			return nil, fmt.Errorf("failed to scan policy row: %w", err)
+		}
+		p.Command = policyCommands[command]
+		if roles != "" {
+			p.Roles = strings.Split(roles, ",")
+		}
+		if i, ok := byName[schemaName+"."+relName]; ok {
+			report.Tables[i].Policies = append(report.Tables[i].Policies, p)
+		}
+	}
+	if err := policyRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read row-level security policies: %w", err)
+	}
+
+	return report, nil
+}
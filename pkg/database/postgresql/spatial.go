@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+)
+
+// spatialTypePattern matches formatted PostGIS types such as geometry(Point,4326)
+var spatialTypePattern = regexp.MustCompile(`(?i)^(?:[a-z_]+\.)?(geometry|geography)(?:\(\s*([a-z0-9]+)\s*(?:,\s*(\d+)\s*)?\))?$`)
+
+// parseSpatialType parses a formatted geometry or geography type, returning
+// nil for other types
+func parseSpatialType(formatted string) *models.Spatial {
+	m := spatialTypePattern.FindStringSubmatch(strings.TrimSpace(formatted))
+	if m == nil {
+		return nil
+	}
+	s := &models.Spatial{
+		Type:         strings.ToLower(m[1]),
+		GeometryType: strings.ToUpper(m[2]),
+	}
+	if s.GeometryType == "" {
+		s.GeometryType = "GEOMETRY"
+	}
+	if m[3] != "" {
+		s.SRID, _ = strconv.Atoi(m[3])
+	} else if s.Type == "geography" {
+		// Geography columns default to WGS 84
+		s.SRID = 4326
+	}
+	return s
+}
+
+// addSpatialInfo fills in the PostGIS type, SRID and spatial index coverage of
+// geometry and geography columns. It needs no PostGIS functions, so it is safe
+// to run when PostGIS is not installed.
+func (pg *PostgreSQLDatabase) addSpatialInfo(tableName string, columns []models.ColumnInfo) error {
+	rows, err := pg.db.Query(`
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod),
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				JOIN pg_class ic ON ic.oid = i.indexrelid
+				JOIN pg_am am ON am.oid = ic.relam
+				WHERE i.indrelid = a.attrelid AND a.attnum = ANY(i.indkey) AND am.amname IN ('gist', 'spgist', 'brin')
+			)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE c.relname = $1 AND a.attnum > 0 AND NOT a.attisdropped AND t.typname IN ('geometry', 'geography')
+	`, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to query spatial columns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, formatted string
+		var indexed bool
+		if err := rows.Scan(&name, &formatted, &indexed); err != nil {
+			return fmt.Errorf("failed to scan spatial column: %w", err)
+		}
+		spatial := parseSpatialType(formatted)
+		if spatial == nil {
+			continue
+		}
+		spatial.Indexed = indexed
+		for i := range columns {
+			if columns[i].ColumnName == name {
+				columns[i].DataType = spatial.Type
+				columns[i].Spatial = spatial
+			}
+		}
+	}
+	return rows.Err()
+}
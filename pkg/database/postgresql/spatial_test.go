@@ -0,0 +1,28 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpatialType(t *testing.T) {
+	s := parseSpatialType("geometry(Point,4326)")
+	require.NotNil(t, s)
+	assert.Equal(t, "geometry", s.Type)
+	assert.Equal(t, "POINT", s.GeometryType)
+	assert.Equal(t, 4326, s.SRID)
+
+	s = parseSpatialType("public.geometry")
+	require.NotNil(t, s)
+	assert.Equal(t, "GEOMETRY", s.GeometryType)
+	assert.Equal(t, 0, s.SRID)
+
+	s = parseSpatialType("geography(MultiPolygon)")
+	require.NotNil(t, s)
+	assert.Equal(t, "geography", s.Type)
+	assert.Equal(t, 4326, s.SRID)
+
+	assert.Nil(t, parseSpatialType("character varying(20)"))
+}
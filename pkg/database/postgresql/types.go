@@ -11,4 +11,5 @@ type ConnectionConfig struct {
 	SSLMode     string `json:"ssl_mode"`
 	Description string `json:"description"`
 	LastUsed    string `json:"last_used,omitempty"` // ISO 8601 timestamp
+	ReadOnly    bool   `json:"read_only,omitempty"` // Start every transaction read-only
 }
@@ -2,12 +2,17 @@ package database
 
 import (
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
+	"dbsage/pkg/database/clickhouse"
+	"dbsage/pkg/database/files"
 	"dbsage/pkg/database/mysql"
 	"dbsage/pkg/database/postgresql"
 	"dbsage/pkg/database/sqlite"
 	"dbsage/pkg/dbinterfaces"
+	"dbsage/pkg/secrets"
 )
 
 // DatabaseType represents supported database types
@@ -18,6 +23,8 @@ const (
 	MySQL      DatabaseType = "mysql"
 	SQLite     DatabaseType = "sqlite"
 	MongoDB    DatabaseType = "mongodb"
+	ClickHouse DatabaseType = "clickhouse"
+	Files      DatabaseType = "files" // Local CSV, TSV or Parquet files loaded into SQLite
 )
 
 // ProviderManager manages database providers for different database types
@@ -35,6 +42,8 @@ func NewProviderManager() *ProviderManager {
 	pm.RegisterProvider(PostgreSQL, postgresql.NewPostgreSQLProvider())
 	pm.RegisterProvider(MySQL, mysql.NewMySQLProvider())
 	pm.RegisterProvider(SQLite, sqlite.NewSQLiteProvider())
+	pm.RegisterProvider(Files, files.NewFilesProvider())
+	pm.RegisterProvider(ClickHouse, clickhouse.NewClickHouseProvider())
 
 	// TODO: Add other providers when implemented
 	// pm.RegisterProvider(MongoDB, mongodb.NewMongoDBProvider())
@@ -76,7 +85,62 @@ func (pm *ProviderManager) CreateConnection(config *dbinterfaces.ConnectionConfi
 		return nil, fmt.Errorf("failed to get provider for type %s: %w", config.Type, err)
 	}
 
-	return provider.CreateConnection(config)
+	primary, err := pm.connect(provider, config)
+	if err != nil || len(config.Replicas) == 0 {
+		return primary, err
+	}
+
+	// Replicas are optional: an unreachable one is skipped so the primary stays usable
+	replicas := make(map[string]dbinterfaces.DatabaseInterface)
+	for _, endpoint := range config.Replicas {
+		host, port, err := ParseReplica(endpoint, config.Port)
+		if err != nil {
+			log.Printf("Skipping replica of %s: %v", config.Name, err)
+			continue
+		}
+		replicaConfig := *config
+		replicaConfig.Host = host
+		replicaConfig.Port = port
+		replicaConfig.Replicas = nil
+		db, err := pm.connect(provider, &replicaConfig)
+		if err != nil {
+			log.Printf("Skipping replica %s of %s: %v", endpoint, config.Name, err)
+			continue
+		}
+		replicas[endpointName(host, port)] = db
+	}
+	return NewReplicatedDatabase(primary, endpointName(config.Host, config.Port), replicas), nil
+}
+
+// OpenConnection opens a connection the way the connection manager does:
+// with its read replicas, concurrency limit and read-only setting. Code that
+// opens a connection of its own uses it rather than CreateConnection.
+func (pm *ProviderManager) OpenConnection(config *dbinterfaces.ConnectionConfig) (*LimitedDatabase, error) {
+	db, err := pm.CreateConnection(config)
+	if err != nil {
+		return nil, err
+	}
+	conn := newConnection(db, config.Name, config)
+	SweepAbandonedWatches(conn, config.Name, time.Now())
+	return conn, nil
+}
+
+// connect opens one endpoint of a connection
+func (pm *ProviderManager) connect(provider dbinterfaces.DatabaseProviderInterface, config *dbinterfaces.ConnectionConfig) (dbinterfaces.DatabaseInterface, error) {
+	// Environment and secret references are resolved here, on every connect, and never stored
+	resolved, err := ResolveConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	db, err := provider.CreateConnection(resolved)
+	if err != nil && config.PasswordRef != "" {
+		// The cached password may have been rotated: fetch it again and retry once
+		secrets.Invalidate(config.PasswordRef)
+		if resolved, resolveErr := ResolveConfig(config); resolveErr == nil {
+			db, err = provider.CreateConnection(resolved)
+		}
+	}
+	return db, err
 }
 
 // GetSupportedTypes returns all supported database types
@@ -123,6 +187,10 @@ func ParseDatabaseType(s string) (DatabaseType, error) {
 		return SQLite, nil
 	case "mongodb", "mongo":
 		return MongoDB, nil
+	case "files", "file", "csv":
+		return Files, nil
+	case "clickhouse", "ch":
+		return ClickHouse, nil
 	default:
 		return "", fmt.Errorf("unsupported database type: %s", s)
 	}
@@ -140,10 +208,12 @@ func GetDefaultPort(dbType DatabaseType) int {
 		return 5432
 	case MySQL:
 		return 3306
-	case SQLite:
+	case SQLite, Files:
 		return 0 // SQLite doesn't use ports
 	case MongoDB:
 		return 27017
+	case ClickHouse:
+		return 8123 // HTTP interface
 	default:
 		return 0
 	}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// ErrReadOnlyConnection is wrapped by the errors of statements refused on a
+// read-only connection
+var ErrReadOnlyConnection = errors.New("connection is read-only")
+
+// SetReadOnly makes the connection refuse statements that change data,
+// schema or server settings before they reach the database
+func (l *LimitedDatabase) SetReadOnly(readOnly bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.readOnly = readOnly
+}
+
+// IsReadOnly reports whether the connection refuses writes
+func (l *LimitedDatabase) IsReadOnly() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readOnly
+}
+
+// checkWrite returns why a read-only connection refuses a statement, or nil
+func (l *LimitedDatabase) checkWrite(query string) error {
+	if !l.IsReadOnly() {
+		return nil
+	}
+	if verb := sqlanalysis.FirstWrite(query); verb != "" {
+		return fmt.Errorf("%w: '%s' refuses %s statements; only reads run on it", ErrReadOnlyConnection, l.name, verb)
+	}
+	return nil
+}
+
+// newConnection wraps a database opened for config with the concurrency
+// limit and read-only setting of the connection
+func newConnection(db dbinterfaces.DatabaseInterface, name string, config *dbinterfaces.ConnectionConfig) *LimitedDatabase {
+	limited := NewLimitedDatabase(db, name, ConcurrencyLimit(config))
+	limited.SetReadOnly(config.ReadOnly)
+	return limited
+}
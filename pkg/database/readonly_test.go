@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedDatabase_ReadOnlyRefusesWrites(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	mockDB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	mockDB.On("ExplainQuery", mock.Anything).Return(&models.QueryResult{}, nil)
+	limited := newConnection(mockDB, "replica", &dbinterfaces.ConnectionConfig{Name: "replica", ReadOnly: true})
+	assert.True(t, dbinterfaces.IsReadOnly(limited))
+
+	_, err := limited.ExecuteSQL("SELECT * FROM users")
+	require.NoError(t, err)
+	_, err = limited.ExplainQuery("SELECT * FROM users")
+	require.NoError(t, err)
+
+	_, err = limited.ExecuteSQL("SELECT 1; DELETE FROM users")
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection))
+	assert.Contains(t, err.Error(), "DELETE")
+	_, err = limited.ExecuteSQLWithArgs("UPDATE users SET name = $1", "x")
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection))
+	_, err = limited.ExecuteExpectingRows("DELETE FROM users WHERE id = 1", 1)
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection))
+	_, err = limited.ExplainQuery("DROP TABLE users")
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection))
+
+	// Refused statements never reach the database
+	mockDB.AssertNumberOfCalls(t, "ExecuteSQL", 1)
+	mockDB.AssertNumberOfCalls(t, "ExplainQuery", 1)
+
+	limited.SetReadOnly(false)
+	_, err = limited.ExecuteSQL("DELETE FROM users")
+	assert.NoError(t, err)
+}
+
+func TestLimitedDatabase_ReadOnlyEscapedStrings(t *testing.T) {
+	mockDB := &MockDatabaseInterface{}
+	limited := newConnection(mockDB, "replica", &dbinterfaces.ConnectionConfig{Name: "replica", ReadOnly: true})
+
+	_, err := limited.ExecuteSQL(`SELECT E'x\'' ; DROP TABLE t; SELECT ''`)
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection), "a statement after an escaped quote is still seen")
+	_, err = limited.ExecuteSQL(`SELECT 'x\'' ; DELETE FROM t; SELECT ''`)
+	assert.True(t, errors.Is(err, ErrReadOnlyConnection), "MySQL escapes quotes in plain strings")
+	mockDB.AssertNotCalled(t, "ExecuteSQL", mock.Anything)
+}
+
+func TestProviderManager_OpenConnectionEnforcesReadOnlyOnServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	pm := NewProviderManager()
+	writable, err := pm.OpenConnection(&dbinterfaces.ConnectionConfig{Name: "app", Type: "sqlite", Database: path})
+	require.NoError(t, err)
+	_, err = writable.ExecuteSQL("CREATE TABLE users (id INTEGER)")
+	require.NoError(t, err)
+	require.NoError(t, writable.Close())
+
+	db, err := pm.OpenConnection(&dbinterfaces.ConnectionConfig{Name: "app", Type: "sqlite", Database: path, ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+	assert.True(t, dbinterfaces.IsReadOnly(db))
+	_, err = db.ExecuteSQL("SELECT * FROM users")
+	require.NoError(t, err)
+
+	// Past the statement check, the database itself refuses the write
+	_, err = db.DatabaseInterface.ExecuteSQL("INSERT INTO users VALUES (1)")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readonly")
+}
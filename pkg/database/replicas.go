@@ -0,0 +1,351 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// routesToReplica reports whether a statement only reads data and can be
+// answered by a read replica. Locking reads, data-modifying CTEs and
+// sequence functions need the primary.
+func routesToReplica(query string) bool {
+	return sqlanalysis.IsReadOnly(query)
+}
+
+// DefaultReplicaLagThreshold is the replication lag above which results read
+// from a replica carry a staleness warning
+const DefaultReplicaLagThreshold = 30 * time.Second
+
+// ReplicaLagThreshold returns the lag above which replica results are flagged
+// as stale, set with DBSAGE_REPLICA_LAG_WARN (0 flags any lag)
+func ReplicaLagThreshold() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_REPLICA_LAG_WARN")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultReplicaLagThreshold
+}
+
+// lagTTL is how long a measured replication lag is reused, so a burst of
+// reads does not measure it before each one
+const lagTTL = 5 * time.Second
+
+// measuredLag is the replication lag of a replica as last measured
+type measuredLag struct {
+	lag   time.Duration
+	known bool // false when the lag could not be measured
+	at    time.Time
+}
+
+// replicaEndpoint is one read replica of a ReplicatedDatabase
+type replicaEndpoint struct {
+	name string
+	db   dbinterfaces.DatabaseInterface
+}
+
+// ReplicatedDatabase routes reads to read replicas in turn and everything
+// else to the primary. Query results name the endpoint that ran them, and
+// results from a replica carry its measured replication lag and a staleness
+// warning when the lag is over the threshold or cannot be measured.
+//
+// Like LimitedDatabase, it must forward each optional capability interface of
+// dbinterfaces that the databases it wraps may implement.
+type ReplicatedDatabase struct {
+	dbinterfaces.DatabaseInterface // The primary
+	primaryName                    string
+	replicas                       []replicaEndpoint
+	next                           uint32
+
+	lagThreshold time.Duration
+	lagMu        sync.Mutex
+	lags         map[string]measuredLag // By replica name
+}
+
+// NewReplicatedDatabase wraps a primary and its replicas, named by endpoint
+func NewReplicatedDatabase(primary dbinterfaces.DatabaseInterface, primaryName string, replicas map[string]dbinterfaces.DatabaseInterface) *ReplicatedDatabase {
+	r := &ReplicatedDatabase{
+		DatabaseInterface: primary,
+		primaryName:       primaryName,
+		lagThreshold:      ReplicaLagThreshold(),
+		lags:              make(map[string]measuredLag),
+	}
+	for name, db := range replicas {
+		r.replicas = append(r.replicas, replicaEndpoint{name: name, db: db})
+	}
+	// Map order is random: keep the rotation stable
+	sort.Slice(r.replicas, func(i, j int) bool {
+		return r.replicas[i].name < r.replicas[j].name
+	})
+	return r
+}
+
+// Endpoints returns the primary and replica endpoint names
+func (r *ReplicatedDatabase) Endpoints() (string, []string) {
+	names := make([]string, len(r.replicas))
+	for i, replica := range r.replicas {
+		names[i] = replica.name
+	}
+	return r.primaryName, names
+}
+
+// DatabaseType reports the type of the primary
+func (r *ReplicatedDatabase) DatabaseType() string {
+	return dbinterfaces.GetDatabaseType(r.DatabaseInterface)
+}
+
+// Close closes the primary and every replica
+func (r *ReplicatedDatabase) Close() error {
+	err := r.DatabaseInterface.Close()
+	for _, replica := range r.replicas {
+		replica.db.Close()
+	}
+	return err
+}
+
+// read runs a read on the next replica, falling back to the primary when
+// there is none or the replica is unreachable. It returns the endpoint used.
+func read[T any](r *ReplicatedDatabase, run func(db dbinterfaces.DatabaseInterface) (T, error)) (T, string, error) {
+	if len(r.replicas) == 0 {
+		value, err := run(r.DatabaseInterface)
+		return value, "", err
+	}
+
+	replica := r.replicas[int(atomic.AddUint32(&r.next, 1)-1)%len(r.replicas)]
+	value, err := run(replica.db)
+	if err == nil || replica.db.CheckConnection() == nil {
+		return value, replica.name, err
+	}
+	log.Printf("Replica %s is unreachable, reading from the primary: %v", replica.name, err)
+	value, err = run(r.DatabaseInterface)
+	return value, r.primaryName, err
+}
+
+// tag records the endpoint that ran a query in its result, and for a replica
+// its replication lag
+func (r *ReplicatedDatabase) tag(result *models.QueryResult, endpoint string) *models.QueryResult {
+	if result == nil {
+		return nil
+	}
+	if endpoint == "" || endpoint == r.primaryName {
+		result.Endpoint = "primary " + r.primaryName
+		return result
+	}
+	result.Endpoint = "replica " + endpoint
+	lag, known := r.replicaLag(endpoint)
+	if !known {
+		result.StalenessWarning = fmt.Sprintf("read from replica %s, whose replication lag could not be measured: recent changes can be missing", endpoint)
+		return result
+	}
+	result.ReplicaLag = sqlanalysis.FormatLag(lag)
+	if lag > r.lagThreshold {
+		result.StalenessWarning = fmt.Sprintf("read from replica %s, which is %s behind the primary (warning above %s): changes from the last %s are missing", endpoint, result.ReplicaLag, r.lagThreshold, result.ReplicaLag)
+	}
+	return result
+}
+
+// replicaLag returns the replication lag of a replica, measuring it when the
+// last measurement is older than lagTTL
+func (r *ReplicatedDatabase) replicaLag(name string) (time.Duration, bool) {
+	r.lagMu.Lock()
+	defer r.lagMu.Unlock()
+	if measured, ok := r.lags[name]; ok && time.Since(measured.at) < lagTTL {
+		return measured.lag, measured.known
+	}
+
+	measured := measuredLag{at: time.Now()}
+	queries, err := sqlanalysis.ReplicaLagQueries(r.DatabaseType())
+	for _, replica := range r.replicas {
+		if replica.name != name || err != nil {
+			continue
+		}
+		for _, query := range queries {
+			result, err := replica.db.ExecuteSQL(query)
+			if err == nil {
+				measured.lag, measured.known = sqlanalysis.ReplicaLagFromResult(result)
+				break
+			}
+		}
+	}
+	r.lags[name] = measured
+	return measured.lag, measured.known
+}
+
+// ExecuteSQL runs reads on a replica and everything else on the primary
+func (r *ReplicatedDatabase) ExecuteSQL(query string) (*models.QueryResult, error) {
+	if !routesToReplica(query) {
+		result, err := r.DatabaseInterface.ExecuteSQL(query)
+		return r.tag(result, r.primaryName), err
+	}
+	result, endpoint, err := read(r, func(db dbinterfaces.DatabaseInterface) (*models.QueryResult, error) {
+		return db.ExecuteSQL(query)
+	})
+	return r.tag(result, endpoint), err
+}
+
+// ExecuteSQLWithArgs runs parameterized reads on a replica and everything else on the primary
+func (r *ReplicatedDatabase) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
+	if !routesToReplica(query) {
+		result, err := dbinterfaces.ExecuteSQLWithArgs(r.DatabaseInterface, query, args...)
+		return r.tag(result, r.primaryName), err
+	}
+	result, endpoint, err := read(r, func(db dbinterfaces.DatabaseInterface) (*models.QueryResult, error) {
+		return dbinterfaces.ExecuteSQLWithArgs(db, query, args...)
+	})
+	return r.tag(result, endpoint), err
+}
+
+// ExecuteSQLOnPrimary runs a parameterized statement on the primary, even a read
+func (r *ReplicatedDatabase) ExecuteSQLOnPrimary(query string, args ...interface{}) (*models.QueryResult, error) {
+	result, err := dbinterfaces.ExecuteSQLOnPrimary(r.DatabaseInterface, query, args...)
+	return r.tag(result, r.primaryName), err
+}
+
+// ExecuteExpectingRows runs guarded writes on the primary
+func (r *ReplicatedDatabase) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	return dbinterfaces.ExecuteExpectingRows(r.DatabaseInterface, query, expected, args...)
+}
+
+// ExplainQuery explains reads on a replica and other statements on the primary
+func (r *ReplicatedDatabase) ExplainQuery(query string) (*models.QueryResult, error) {
+	if !routesToReplica(query) {
+		result, err := r.DatabaseInterface.ExplainQuery(query)
+		return r.tag(result, r.primaryName), err
+	}
+	result, endpoint, err := read(r, func(db dbinterfaces.DatabaseInterface) (*models.QueryResult, error) {
+		return db.ExplainQuery(query)
+	})
+	return r.tag(result, endpoint), err
+}
+
+// OpenCursor streams reads from a replica and everything else from the primary
+func (r *ReplicatedDatabase) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	if !routesToReplica(query) {
+		return dbinterfaces.OpenCursor(r.DatabaseInterface, query)
+	}
+	cursor, _, err := read(r, func(db dbinterfaces.DatabaseInterface) (dbinterfaces.RowCursor, error) {
+		return dbinterfaces.OpenCursor(db, query)
+	})
+	return cursor, err
+}
+
+// CancelRunning cancels the statements running on the primary and every
+// replica
+func (r *ReplicatedDatabase) CancelRunning() (int, error) {
+	cancelled, err := dbinterfaces.CancelRunning(r.DatabaseInterface)
+	for _, replica := range r.replicas {
+		n, replicaErr := dbinterfaces.CancelRunning(replica.db)
+		cancelled += n
+		err = errors.Join(err, replicaErr)
+	}
+	return cancelled, err
+}
+
+// BeginTx starts a transaction on the primary
+func (r *ReplicatedDatabase) BeginTx() (*sql.Tx, error) {
+	return dbinterfaces.BeginTx(r.DatabaseInterface)
+}
+
+// GetAllTables lists tables on a replica
+func (r *ReplicatedDatabase) GetAllTables() ([]models.TableInfo, error) {
+	tables, _, err := read(r, func(db dbinterfaces.DatabaseInterface) ([]models.TableInfo, error) {
+		return db.GetAllTables()
+	})
+	return tables, err
+}
+
+// ListSchemas lists schemas on a replica, if the database can
+func (r *ReplicatedDatabase) ListSchemas() ([]string, error) {
+	schemas, _, err := read(r, func(db dbinterfaces.DatabaseInterface) ([]string, error) {
+		provider, ok := db.(dbinterfaces.SchemaCatalogProvider)
+		if !ok {
+			return nil, fmt.Errorf("this connection cannot list tables by schema")
+		}
+		return provider.ListSchemas()
+	})
+	return schemas, err
+}
+
+// GetTablesInSchema lists the tables of one schema on a replica
+func (r *ReplicatedDatabase) GetTablesInSchema(schema string) ([]models.TableInfo, error) {
+	tables, _, err := read(r, func(db dbinterfaces.DatabaseInterface) ([]models.TableInfo, error) {
+		provider, ok := db.(dbinterfaces.SchemaCatalogProvider)
+		if !ok {
+			return nil, fmt.Errorf("this connection cannot list tables by schema")
+		}
+		return provider.GetTablesInSchema(schema)
+	})
+	return tables, err
+}
+
+// GetRLSPolicies lists row-level security policies on a replica, if the
+// database has them
+func (r *ReplicatedDatabase) GetRLSPolicies(tableName string) (*models.RLSReport, error) {
+	report, _, err := read(r, func(db dbinterfaces.DatabaseInterface) (*models.RLSReport, error) {
+		return dbinterfaces.GetRLSPolicies(db, tableName)
+	})
+	return report, err
+}
+
+// GetTableSchema describes a table on a replica
+func (r *ReplicatedDatabase) GetTableSchema(tableName string) ([]models.ColumnInfo, error) {
+	columns, _, err := read(r, func(db dbinterfaces.DatabaseInterface) ([]models.ColumnInfo, error) {
+		return db.GetTableSchema(tableName)
+	})
+	return columns, err
+}
+
+// GetTableIndexes lists the indexes of a table on a replica
+func (r *ReplicatedDatabase) GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
+	indexes, _, err := read(r, func(db dbinterfaces.DatabaseInterface) ([]models.IndexInfo, error) {
+		return db.GetTableIndexes(tableName)
+	})
+	return indexes, err
+}
+
+// FindDuplicateData searches for duplicates on a replica
+func (r *ReplicatedDatabase) FindDuplicateData(tableName string, columns []string) (*models.QueryResult, error) {
+	result, endpoint, err := read(r, func(db dbinterfaces.DatabaseInterface) (*models.QueryResult, error) {
+		return db.FindDuplicateData(tableName, columns)
+	})
+	return r.tag(result, endpoint), err
+}
+
+// ParseReplica splits a replica endpoint of the form host or host:port,
+// using defaultPort when no port is given
+func ParseReplica(endpoint string, defaultPort int) (string, int, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return "", 0, fmt.Errorf("empty replica endpoint")
+	}
+	host, portText, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		// No port given
+		return strings.Trim(endpoint, "[]"), defaultPort, nil
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in replica endpoint %s", endpoint)
+	}
+	return host, port, nil
+}
+
+// endpointName names an endpoint by host and port
+func endpointName(host string, port int) string {
+	if port == 0 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
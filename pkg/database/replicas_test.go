@@ -0,0 +1,155 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/pkg/database/sqlite"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutesToReplica(t *testing.T) {
+	assert.True(t, routesToReplica("SELECT * FROM orders"))
+	assert.True(t, routesToReplica("-- totals\nWITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.True(t, routesToReplica("SHOW TABLES"))
+	assert.False(t, routesToReplica("SELECT * FROM orders WHERE id = 1 FOR UPDATE"))
+	assert.False(t, routesToReplica("WITH gone AS (DELETE FROM orders RETURNING *) SELECT count(*) FROM gone"))
+	assert.False(t, routesToReplica("SELECT nextval('orders_id_seq')"))
+	assert.False(t, routesToReplica("UPDATE orders SET status = 'paid'"))
+}
+
+func TestReplicatedDatabase_RoutesReadsToReplicas(t *testing.T) {
+	primary := &MockDatabaseInterface{}
+	replicaA := &MockDatabaseInterface{}
+	replicaB := &MockDatabaseInterface{}
+	primary.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	replicaA.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	replicaB.On("ExecuteSQL", mock.Anything).Return(&models.QueryResult{}, nil)
+	replicaA.On("GetTableSchema", "orders").Return([]models.ColumnInfo{{ColumnName: "id"}}, nil)
+
+	db := NewReplicatedDatabase(primary, "db:5432", map[string]dbinterfaces.DatabaseInterface{
+		"replica-b:5432": replicaB,
+		"replica-a:5432": replicaA,
+	})
+
+	first, err := db.ExecuteSQL("SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "replica replica-a:5432", first.Endpoint)
+	assert.Contains(t, first.StalenessWarning, "replica-a:5432")
+
+	second, err := db.ExecuteSQL("SELECT 2")
+	require.NoError(t, err)
+	assert.Equal(t, "replica replica-b:5432", second.Endpoint)
+
+	write, err := db.ExecuteSQL("DELETE FROM orders WHERE id = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "primary db:5432", write.Endpoint)
+	assert.Empty(t, write.StalenessWarning)
+	primary.AssertCalled(t, "ExecuteSQL", "DELETE FROM orders WHERE id = 1")
+	primary.AssertNumberOfCalls(t, "ExecuteSQL", 1)
+
+	columns, err := db.GetTableSchema("orders")
+	require.NoError(t, err)
+	assert.Len(t, columns, 1)
+}
+
+func TestReplicatedDatabase_FallsBackToPrimary(t *testing.T) {
+	primary := &MockDatabaseInterface{}
+	replica := &MockDatabaseInterface{}
+	primary.On("ExecuteSQL", "SELECT 1").Return(&models.QueryResult{RowCount: 1}, nil)
+	replica.On("ExecuteSQL", "SELECT 1").Return((*models.QueryResult)(nil), fmt.Errorf("connection refused"))
+	replica.On("CheckConnection").Return(fmt.Errorf("connection refused"))
+
+	db := NewReplicatedDatabase(primary, "db:5432", map[string]dbinterfaces.DatabaseInterface{"replica:5432": replica})
+	result, err := db.ExecuteSQL("SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "primary db:5432", result.Endpoint)
+	assert.Equal(t, 1, result.RowCount)
+}
+
+func TestReplicatedDatabase_ExecuteSQLOnPrimary(t *testing.T) {
+	primary, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "primary.db"))
+	require.NoError(t, err)
+	replica, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "replica.db"))
+	require.NoError(t, err)
+	db := NewLimitedDatabase(NewReplicatedDatabase(primary, "primary", map[string]dbinterfaces.DatabaseInterface{"replica": replica}), "app", 2)
+	defer db.Close()
+
+	// The replica has not caught up with the table yet
+	_, err = db.ExecuteSQL("CREATE TABLE events (id INTEGER)")
+	require.NoError(t, err)
+	_, err = dbinterfaces.ExecuteSQLWithArgs(db, "SELECT id FROM events WHERE id > ?", 0)
+	assert.Error(t, err, "reads go to the replica")
+
+	result, err := dbinterfaces.ExecuteSQLOnPrimary(db, "SELECT id FROM events WHERE id > ?", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "primary primary", result.Endpoint)
+}
+
+func TestReplicatedDatabase_ReplicaLag(t *testing.T) {
+	primary := &MockDatabaseInterface{}
+	replica := &MockDatabaseInterface{}
+	lagQuery := mock.MatchedBy(func(query string) bool { return strings.Contains(query, "pg_last_xact_replay_timestamp") })
+	replica.On("ExecuteSQL", lagQuery).Return(&models.QueryResult{Columns: []string{"lag_seconds"}, Rows: [][]interface{}{{"0.25"}}}, nil).Once()
+	for i := 0; i < 4; i++ {
+		replica.On("ExecuteSQL", "SELECT count(*) FROM orders").Return(&models.QueryResult{RowCount: 1}, nil).Once()
+	}
+
+	db := NewReplicatedDatabase(primary, "db:5432", map[string]dbinterfaces.DatabaseInterface{"replica:5432": replica})
+	db.lagThreshold = 30 * time.Second
+
+	// Under the threshold the lag is reported without a warning
+	result, err := db.ExecuteSQL("SELECT count(*) FROM orders")
+	require.NoError(t, err)
+	assert.Equal(t, "250ms", result.ReplicaLag)
+	assert.Empty(t, result.StalenessWarning)
+
+	// A recent measurement is reused
+	_, err = db.ExecuteSQL("SELECT count(*) FROM orders")
+	require.NoError(t, err)
+	replica.AssertNumberOfCalls(t, "ExecuteSQL", 3)
+
+	// Over the threshold the result is flagged
+	db.lags["replica:5432"] = measuredLag{lag: 95 * time.Second, known: true, at: time.Now()}
+	result, err = db.ExecuteSQL("SELECT count(*) FROM orders")
+	require.NoError(t, err)
+	assert.Equal(t, "1m35s", result.ReplicaLag)
+	assert.Equal(t, "read from replica replica:5432, which is 1m35s behind the primary (warning above 30s): changes from the last 1m35s are missing", result.StalenessWarning)
+
+	// A lag that cannot be measured is flagged too
+	db.lags["replica:5432"] = measuredLag{at: time.Now()}
+	result, err = db.ExecuteSQL("SELECT count(*) FROM orders")
+	require.NoError(t, err)
+	assert.Empty(t, result.ReplicaLag)
+	assert.Contains(t, result.StalenessWarning, "could not be measured")
+}
+
+func TestReplicaLagThreshold(t *testing.T) {
+	t.Setenv("DBSAGE_REPLICA_LAG_WARN", "")
+	assert.Equal(t, DefaultReplicaLagThreshold, ReplicaLagThreshold())
+	t.Setenv("DBSAGE_REPLICA_LAG_WARN", "2m")
+	assert.Equal(t, 2*time.Minute, ReplicaLagThreshold())
+}
+
+func TestParseReplica(t *testing.T) {
+	host, port, err := ParseReplica("replica.internal", 5432)
+	require.NoError(t, err)
+	assert.Equal(t, "replica.internal", host)
+	assert.Equal(t, 5432, port)
+
+	host, port, err = ParseReplica(" 10.0.0.7:5433 ", 5432)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.7", host)
+	assert.Equal(t, 5433, port)
+
+	_, _, err = ParseReplica("replica:http", 5432)
+	assert.Error(t, err)
+}
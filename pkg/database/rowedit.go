@@ -0,0 +1,213 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// bindMarkerPattern matches the bound parameter markers of the dialects
+var bindMarkerPattern = regexp.MustCompile(`\$\d+|\?`)
+
+// combinedRowsPattern matches queries whose rows are not rows of one table
+var combinedRowsPattern = regexp.MustCompile(`(?i)\b(JOIN|GROUP\s+BY|DISTINCT|UNION|INTERSECT|EXCEPT|COUNT\s*\(|SUM\s*\(|AVG\s*\(|MIN\s*\(|MAX\s*\()`)
+
+// RowEditor writes edited cells of a query result back to the table the rows
+// came from. Rows are found by their primary key, which the result must
+// include, and every update must change exactly one row or is rolled back.
+type RowEditor struct {
+	db        dbinterfaces.DatabaseInterface
+	dialect   string
+	table     string
+	columns   []string                     // Result columns
+	byName    map[string]models.ColumnInfo // Table columns by lowercase name
+	keyColumn []int                        // Result column of each primary-key column
+}
+
+// CellUpdate is the UPDATE writing one edited cell
+type CellUpdate struct {
+	SQL     string
+	Params  []interface{}
+	Preview string // SQL with the parameters inlined, for display only
+}
+
+// NewRowEditor prepares the write-back of the rows a query returned with the
+// given columns. It fails when the rows cannot be traced to single rows of a
+// table: the query reads several tables or combines rows, or the result lacks
+// the table's primary key.
+func NewRowEditor(db dbinterfaces.DatabaseInterface, query string, columns []string) (*RowEditor, error) {
+	if verb := sqlanalysis.FirstWrite(query); verb != "" {
+		return nil, fmt.Errorf("only the rows of a SELECT can be edited, not %s", verb)
+	}
+	clean := sqlanalysis.StripComments(query)
+	refs := sqlanalysis.TableReferences(clean)
+	if len(refs) != 1 || combinedRowsPattern.MatchString(clean) {
+		return nil, fmt.Errorf("only rows read from one table, without joins, grouping or aggregates, can be edited")
+	}
+	table := refs[0].Table
+
+	tableColumns, err := db.GetTableSchema(unqualifiedTable(table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	e := &RowEditor{
+		db:      db,
+		dialect: dbinterfaces.GetDatabaseType(db),
+		table:   table,
+		columns: columns,
+		byName:  make(map[string]models.ColumnInfo),
+	}
+	for _, col := range tableColumns {
+		e.byName[strings.ToLower(col.ColumnName)] = col
+		if !col.IsPrimaryKey {
+			continue
+		}
+		index := e.resultColumn(col.ColumnName)
+		if index < 0 {
+			return nil, fmt.Errorf("the result does not include the primary-key column %s of %s; select it to edit rows", col.ColumnName, table)
+		}
+		e.keyColumn = append(e.keyColumn, index)
+	}
+	if len(e.keyColumn) == 0 {
+		return nil, fmt.Errorf("%s has no primary key, so its rows cannot be told apart", table)
+	}
+	return e, nil
+}
+
+// Table returns the table the rows are written back to
+func (e *RowEditor) Table() string {
+	return e.table
+}
+
+// resultColumn returns the index of a result column by case-insensitive name, or -1
+func (e *RowEditor) resultColumn(name string) int {
+	for i, column := range e.columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// CanEdit reports why a result column cannot be edited, or nil when it can
+func (e *RowEditor) CanEdit(column int) error {
+	if column < 0 || column >= len(e.columns) {
+		return fmt.Errorf("no such column")
+	}
+	col, ok := e.byName[strings.ToLower(e.columns[column])]
+	switch {
+	case !ok:
+		return fmt.Errorf("%s is not a column of %s", e.columns[column], e.table)
+	case col.IsPrimaryKey:
+		return fmt.Errorf("%s is part of the primary key, which identifies the row", col.ColumnName)
+	}
+	return nil
+}
+
+// Update builds the UPDATE setting a cell of a result row to value, or to
+// NULL when value is nil
+func (e *RowEditor) Update(row []interface{}, column int, value *string) (*CellUpdate, error) {
+	if err := e.CanEdit(column); err != nil {
+		return nil, err
+	}
+	col := e.byName[strings.ToLower(e.columns[column])]
+	if value == nil && strings.EqualFold(col.IsNullable, "NO") {
+		return nil, fmt.Errorf("%s is NOT NULL", col.ColumnName)
+	}
+
+	var params []interface{}
+	var newValue interface{}
+	if value != nil {
+		newValue = *value
+	}
+	params = append(params, newValue)
+	assignment := fmt.Sprintf("%s = %s", quoteColumn(e.dialect, col.ColumnName), bindMarker(e.dialect, len(params)))
+
+	var conditions []string
+	for _, index := range e.keyColumn {
+		if index >= len(row) || row[index] == nil {
+			return nil, fmt.Errorf("the row has no value for its primary-key column %s", e.columns[index])
+		}
+		params = append(params, row[index])
+		key := e.byName[strings.ToLower(e.columns[index])]
+		conditions = append(conditions, fmt.Sprintf("%s = %s", quoteColumn(e.dialect, key.ColumnName), bindMarker(e.dialect, len(params))))
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteColumn(e.dialect, e.table), assignment, strings.Join(conditions, " AND "))
+	literals := make([]string, len(params))
+	for i, param := range params {
+		literals[i] = previewLiteral(param)
+	}
+	return &CellUpdate{SQL: sql, Params: params, Preview: inlineMarkers(sql, literals)}, nil
+}
+
+// Apply runs an update, rolling it back unless it changed exactly one row
+func (e *RowEditor) Apply(update *CellUpdate) error {
+	_, err := dbinterfaces.ExecuteExpectingRows(e.db, update.SQL, 1, update.Params...)
+	return err
+}
+
+// unqualifiedTable returns a table name without its schema, as GetTableSchema expects
+func unqualifiedTable(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+// quoteColumn quotes a possibly schema-qualified name for the dialect
+func quoteColumn(dialect, name string) string {
+	quote := `"`
+	if dialect == "mysql" {
+		quote = "`"
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// bindMarker returns the marker of the n-th (1-based) bound parameter
+func bindMarker(dialect string, n int) string {
+	if dialect == "postgresql" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// previewLiteral renders a parameter as a SQL literal, for display
+func previewLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case int, int32, int64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		return "'" + strings.ReplaceAll(string(v), "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+// inlineMarkers replaces the bind markers of a statement with literals
+func inlineMarkers(sql string, literals []string) string {
+	next := 0
+	return bindMarkerPattern.ReplaceAllStringFunc(sql, func(marker string) string {
+		n := next
+		if marker != "?" {
+			n, _ = strconv.Atoi(marker[1:])
+			n--
+		}
+		next++
+		if n < 0 || n >= len(literals) {
+			return marker
+		}
+		return literals[n]
+	})
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowEditor_WritesCellBack(t *testing.T) {
+	db := openScratchTestDB(t)
+	result, err := db.ExecuteSQL("SELECT id, status FROM orders ORDER BY id")
+	require.NoError(t, err)
+
+	editor, err := NewRowEditor(db, "SELECT id, status FROM orders ORDER BY id", result.Columns)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", editor.Table())
+	assert.ErrorContains(t, editor.CanEdit(0), "primary key")
+	assert.NoError(t, editor.CanEdit(1))
+
+	value := "it's paid"
+	update, err := editor.Update(result.Rows[1], 1, &value)
+	require.NoError(t, err)
+	assert.Equal(t, `UPDATE "orders" SET "status" = ? WHERE "id" = ?`, update.SQL)
+	assert.Equal(t, `UPDATE "orders" SET "status" = 'it''s paid' WHERE "id" = 2`, update.Preview)
+	require.NoError(t, editor.Apply(update))
+
+	after, err := db.ExecuteSQL("SELECT status FROM orders WHERE id = 2")
+	require.NoError(t, err)
+	assert.Equal(t, "it's paid", after.Rows[0][0])
+
+	update, err = editor.Update(result.Rows[0], 1, nil)
+	require.NoError(t, err)
+	assert.Contains(t, update.Preview, `SET "status" = NULL WHERE "id" = 1`)
+
+	// A row that is gone changes nothing and is reported
+	_, err = db.ExecuteSQL("DELETE FROM orders WHERE id = 1")
+	require.NoError(t, err)
+	assert.Error(t, editor.Apply(update))
+}
+
+func TestNewRowEditor_RefusesRowsWithoutIdentity(t *testing.T) {
+	db := openScratchTestDB(t)
+
+	_, err := NewRowEditor(db, "SELECT status FROM orders", []string{"status"})
+	assert.ErrorContains(t, err, "primary-key column id")
+
+	_, err = NewRowEditor(db, "SELECT status, COUNT(*) FROM orders GROUP BY status", []string{"status", "count"})
+	assert.ErrorContains(t, err, "one table")
+
+	_, err = NewRowEditor(db, "SELECT o.id FROM orders o JOIN orders p ON p.id = o.id", []string{"id"})
+	assert.ErrorContains(t, err, "one table")
+
+	_, err = NewRowEditor(db, "DELETE FROM orders", nil)
+	assert.ErrorContains(t, err, "DELETE")
+}
+
+func TestInlineMarkers(t *testing.T) {
+	assert.Equal(t, "SET a = 'x?' WHERE id = 1", inlineMarkers("SET a = ? WHERE id = ?", []string{"'x?'", "1"}))
+	assert.Equal(t, "SET a = '$2' WHERE id = 7", inlineMarkers("SET a = $1 WHERE id = $2", []string{"'$2'", "7"}))
+}
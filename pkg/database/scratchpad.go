@@ -0,0 +1,303 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// DefaultScratchpadTimeout is how long a scratchpad stays open unless
+// DBSAGE_SCRATCH_TIMEOUT or /scratch start says otherwise
+const DefaultScratchpadTimeout = 10 * time.Minute
+
+// ErrScratchpadRefused is wrapped by the errors of statements a scratchpad
+// refuses because they would end its transaction or commit it
+var ErrScratchpadRefused = errors.New("statement refused in a scratchpad")
+
+// ScratchpadTimeout returns how long a scratchpad stays open by default, set
+// with DBSAGE_SCRATCH_TIMEOUT
+func ScratchpadTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_SCRATCH_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultScratchpadTimeout
+}
+
+// explainPrefixes turn a statement into the plan ExplainQuery returns, per dialect
+var explainPrefixes = map[string]string{
+	"postgresql": "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) ",
+	"mysql":      "EXPLAIN FORMAT=JSON ",
+	"sqlite":     "EXPLAIN QUERY PLAN ",
+}
+
+// scratchSessions return the server session of the scratchpad's connection
+// and cancel the statement running in it from another connection, per dialect
+var scratchSessions = map[string]struct{ id, cancel string }{
+	"postgresql": {"SELECT pg_backend_pid()", "SELECT pg_cancel_backend(%d)"},
+	"mysql":      {"SELECT CONNECTION_ID()", "KILL QUERY %d"},
+}
+
+// scratchpad runs statements in a transaction on one connection. The
+// transaction is rolled back when the scratchpad ends or times out and is
+// never committed. Each statement runs under a savepoint, so a failed one is
+// undone on its own instead of aborting the transaction.
+type scratchpad struct {
+	mu      sync.Mutex // One statement at a time on the transaction's connection
+	tx      *sql.Tx
+	dialect string
+	session int64       // Server session of the transaction, 0 when unknown
+	running atomic.Bool // A statement is running, see cancel
+	expires time.Time
+	timer   *time.Timer
+	ended   bool
+}
+
+// StartScratchpad opens a scratchpad: until it ends, statements run in a
+// transaction that is rolled back after timeout or on EndScratchpad. Schema
+// lookups still run outside it.
+func (l *LimitedDatabase) StartScratchpad(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = ScratchpadTimeout()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.scratch != nil {
+		return fmt.Errorf("a scratchpad is already open on '%s' until %s; end it with /scratch end first", l.name, l.scratch.expires.Format("15:04:05"))
+	}
+
+	tx, err := dbinterfaces.BeginTx(l.DatabaseInterface)
+	if err != nil {
+		return fmt.Errorf("failed to open a scratchpad on '%s': %w", l.name, err)
+	}
+	s := &scratchpad{tx: tx, dialect: dbinterfaces.GetDatabaseType(l.DatabaseInterface), expires: time.Now().Add(timeout)}
+	if q, ok := scratchSessions[s.dialect]; ok {
+		if err := tx.QueryRow(q.id).Scan(&s.session); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to open a scratchpad on '%s': %w", l.name, err)
+		}
+	}
+	s.timer = time.AfterFunc(timeout, func() {
+		if l.endScratchpad(s) {
+			log.Printf("Scratchpad on '%s' timed out and was rolled back", l.name)
+		}
+	})
+	l.scratch = s
+	return nil
+}
+
+// EndScratchpad rolls back the open scratchpad
+func (l *LimitedDatabase) EndScratchpad() error {
+	l.mu.Lock()
+	s := l.scratch
+	l.mu.Unlock()
+	if s == nil {
+		return fmt.Errorf("no scratchpad is open on '%s'", l.name)
+	}
+	l.endScratchpad(s)
+	return nil
+}
+
+// ScratchpadExpiry returns when the open scratchpad is rolled back, or the
+// zero time when none is open
+func (l *LimitedDatabase) ScratchpadExpiry() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.scratch == nil {
+		return time.Time{}
+	}
+	return l.scratch.expires
+}
+
+// activeScratchpad returns the open scratchpad, or nil
+func (l *LimitedDatabase) activeScratchpad() *scratchpad {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.scratch
+}
+
+// endScratchpad rolls back a scratchpad, once a running statement finished.
+// It reports whether the scratchpad was still open.
+func (l *LimitedDatabase) endScratchpad(s *scratchpad) bool {
+	l.mu.Lock()
+	if l.scratch == s {
+		l.scratch = nil
+	}
+	l.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	s.ended = true
+	s.timer.Stop()
+	if err := s.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Printf("Failed to roll back the scratchpad on '%s': %v", l.name, err)
+	}
+	return true
+}
+
+// Close rolls back an open scratchpad and closes the connection
+func (l *LimitedDatabase) Close() error {
+	if s := l.activeScratchpad(); s != nil {
+		l.endScratchpad(s)
+	}
+	return l.DatabaseInterface.Close()
+}
+
+// cancel cancels the statement running in the scratchpad from another
+// connection of db, leaving the transaction open. It returns how many
+// statements were cancelled.
+func (s *scratchpad) cancel(db dbinterfaces.DatabaseInterface) (int, error) {
+	q, ok := scratchSessions[s.dialect]
+	if !ok || s.session == 0 || !s.running.Load() {
+		return 0, nil
+	}
+	if r, ok := db.(*ReplicatedDatabase); ok {
+		// The transaction runs on the primary
+		db = r.DatabaseInterface
+	}
+	if _, err := db.ExecuteSQL(fmt.Sprintf(q.cancel, s.session)); err != nil {
+		return 0, fmt.Errorf("failed to cancel the scratchpad statement: %w", err)
+	}
+	return 1, nil
+}
+
+// check refuses statements that would end or commit the transaction
+func (s *scratchpad) check(query string) error {
+	if s.ended {
+		return fmt.Errorf("the scratchpad was rolled back")
+	}
+	if reason := sqlanalysis.ScratchpadRefusal(s.dialect, query); reason != "" {
+		return fmt.Errorf("%w: %s", ErrScratchpadRefused, reason)
+	}
+	return nil
+}
+
+// savepoint runs fn under a savepoint that is rolled back when fn fails
+func (s *scratchpad) savepoint(fn func() error) error {
+	s.running.Store(true)
+	defer s.running.Store(false)
+	if _, err := s.tx.Exec("SAVEPOINT dbsage_scratch"); err != nil {
+		return fmt.Errorf("scratchpad savepoint failed: %w", err)
+	}
+	if err := fn(); err != nil {
+		_, _ = s.tx.Exec("ROLLBACK TO SAVEPOINT dbsage_scratch")
+		return err
+	}
+	_, err := s.tx.Exec("RELEASE SAVEPOINT dbsage_scratch")
+	return err
+}
+
+// executeSQL runs a statement in the scratchpad
+func (s *scratchpad) executeSQL(query string, args ...interface{}) (*models.QueryResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(query); err != nil {
+		return nil, err
+	}
+
+	var result *models.QueryResult
+	err := s.savepoint(func() error {
+		var err error
+		result, err = queryTx(s.tx, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Scratchpad = true
+	return result, nil
+}
+
+// explainQuery explains a query in the scratchpad, so it sees the
+// scratchpad's changes
+func (s *scratchpad) explainQuery(query string) (*models.QueryResult, error) {
+	prefix, ok := explainPrefixes[s.dialect]
+	if !ok {
+		return nil, fmt.Errorf("query plans are not supported in a scratchpad on %s databases", s.dialect)
+	}
+	return s.executeSQL(prefix + query)
+}
+
+// executeExpectingRows runs a write in the scratchpad, undoing it unless it
+// affects exactly expected rows
+func (s *scratchpad) executeExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(query); err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	err := s.savepoint(func() error {
+		result, err := s.tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("statement execution failed: %w", err)
+		}
+		if affected, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected != expected {
+			return &dbinterfaces.RowCountMismatchError{Expected: expected, Actual: affected}
+		}
+		return nil
+	})
+	return affected, err
+}
+
+// queryTx runs a statement in a transaction and reads its rows, converting
+// text returned as bytes to strings like the drivers' executors
+func queryTx(tx *sql.Tx, query string, args ...interface{}) (*models.QueryResult, error) {
+	start := time.Now()
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column names: %w", err)
+	}
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	var resultRows [][]interface{}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make([]interface{}, len(columns))
+		for i, val := range values {
+			if v, ok := val.([]byte); ok {
+				row[i] = string(v)
+			} else {
+				row[i] = val
+			}
+		}
+		resultRows = append(resultRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return &models.QueryResult{
+		Columns:  columns,
+		Rows:     resultRows,
+		RowCount: len(resultRows),
+		Duration: time.Since(start).String(),
+	}, nil
+}
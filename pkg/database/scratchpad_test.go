@@ -0,0 +1,76 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbsage/pkg/database/sqlite"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openScratchTestDB(t *testing.T) *LimitedDatabase {
+	t.Helper()
+	db, err := sqlite.NewSQLiteDatabase(filepath.Join(t.TempDir(), "shop.db"))
+	require.NoError(t, err)
+	limited := NewLimitedDatabase(db, "shop", 2)
+	t.Cleanup(func() { limited.Close() })
+	for _, statement := range []string{
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT)",
+		"INSERT INTO orders VALUES (1, 'new'), (2, 'new')",
+	} {
+		_, err = limited.ExecuteSQL(statement)
+		require.NoError(t, err)
+	}
+	return limited
+}
+
+func countOrders(t *testing.T, db dbinterfaces.DatabaseInterface) interface{} {
+	t.Helper()
+	result, err := db.ExecuteSQL("SELECT COUNT(*) FROM orders")
+	require.NoError(t, err)
+	return result.Rows[0][0]
+}
+
+func TestScratchpad_RollsBackOnEnd(t *testing.T) {
+	db := openScratchTestDB(t)
+	assert.True(t, dbinterfaces.ScratchpadExpiry(db).IsZero())
+
+	require.NoError(t, dbinterfaces.StartScratchpad(db, time.Minute))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), dbinterfaces.ScratchpadExpiry(db), 5*time.Second)
+	assert.Error(t, db.StartScratchpad(time.Minute), "one scratchpad at a time")
+
+	result, err := db.ExecuteSQL("DELETE FROM orders WHERE id = 1")
+	require.NoError(t, err)
+	assert.True(t, result.Scratchpad)
+	assert.Equal(t, int64(1), countOrders(t, db), "the scratchpad sees its own changes")
+
+	_, err = db.ExecuteSQL("SELECT * FROM missing_table")
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), countOrders(t, db), "a failed statement leaves the scratchpad usable")
+
+	affected, err := dbinterfaces.ExecuteExpectingRows(db, "UPDATE orders SET status = ?", 5, "paid")
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	_, err = db.ExecuteSQL("COMMIT")
+	assert.ErrorIs(t, err, ErrScratchpadRefused)
+
+	require.NoError(t, dbinterfaces.EndScratchpad(db))
+	assert.Equal(t, int64(2), countOrders(t, db), "nothing the scratchpad changed is kept")
+	assert.Error(t, db.EndScratchpad(), "no scratchpad is open")
+}
+
+func TestScratchpad_RollsBackOnTimeout(t *testing.T) {
+	db := openScratchTestDB(t)
+
+	require.NoError(t, db.StartScratchpad(50*time.Millisecond))
+	_, err := db.ExecuteSQL("INSERT INTO orders VALUES (3, 'new')")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return db.ScratchpadExpiry().IsZero() }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), countOrders(t, db))
+}
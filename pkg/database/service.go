@@ -3,6 +3,8 @@ package database
 import (
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"dbsage/pkg/dbinterfaces"
 )
@@ -10,7 +12,12 @@ import (
 // ConnectionService provides a high-level interface for database connection management
 type ConnectionService struct {
 	manager dbinterfaces.ConnectionManagerInterface
-	current dbinterfaces.DatabaseInterface
+	health  healthTracker
+
+	mu          sync.Mutex // Guards the current connection, which the health monitor also restores
+	current     dbinterfaces.DatabaseInterface
+	currentName string         // Name of the current connection, used for lazy reconnection
+	monitor     *healthMonitor // Background health checks, nil until started
 }
 
 // Ensure ConnectionService implements ConnectionServiceInterface
@@ -18,13 +25,24 @@ var _ dbinterfaces.ConnectionServiceInterface = (*ConnectionService)(nil)
 
 // NewConnectionService creates a new connection service
 func NewConnectionService() dbinterfaces.ConnectionServiceInterface {
-	manager := NewConnectionManager()
+	return newConnectionService(NewConnectionManager())
+}
+
+// NewConnectionServiceAt creates a connection service whose connections are
+// kept in configFile, apart from the user's own connections
+func NewConnectionServiceAt(configFile string) dbinterfaces.ConnectionServiceInterface {
+	return newConnectionService(NewConnectionManagerAt(configFile))
+}
+
+func newConnectionService(manager dbinterfaces.ConnectionManagerInterface) dbinterfaces.ConnectionServiceInterface {
 	service := &ConnectionService{
 		manager: manager,
 	}
+	service.health.policy = DefaultHealthPolicy()
 
 	// Try to establish initial connection
 	service.initializeConnection()
+	service.detectLatency()
 	return service
 }
 
@@ -38,6 +56,7 @@ func (cs *ConnectionService) initializeConnection() {
 		if err := cs.manager.SwitchConnection(lastUsedName); err == nil {
 			if dbTools, name, err := cs.manager.GetCurrentConnection(); err == nil {
 				cs.current = dbTools
+				cs.currentName = name
 				log.Printf("Reconnected to last used database: %s", name)
 				return
 			}
@@ -48,26 +67,113 @@ func (cs *ConnectionService) initializeConnection() {
 	// Fallback to current connection from manager
 	if dbTools, name, err := cs.manager.GetCurrentConnection(); err == nil {
 		cs.current = dbTools
+		cs.currentName = name
 		log.Printf("Connected to database: %s", name)
 	} else {
 		log.Println("No database connections configured. Use '/add' command to add connections.")
 	}
 }
 
-// GetCurrentTools returns the current database tools
+// GetCurrentTools returns the current database tools. Health check results are
+// cached for the policy's TTL, and after a lost connection reconnects are retried
+// lazily with exponential backoff.
 func (cs *ConnectionService) GetCurrentTools() dbinterfaces.DatabaseInterface {
-	// Check if current connection is healthy
-	if cs.current != nil && !cs.current.IsConnectionHealthy() {
-		// Try to refresh the current connection
-		if dbInterface, _, err := cs.manager.GetCurrentConnection(); err == nil {
-			cs.current = dbInterface
-		} else {
-			cs.current = nil
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	now := time.Now()
+
+	if cs.current == nil {
+		// Only reconnect lazily if a connection was lost, not if none was configured
+		if cs.health.hasFailed() && cs.health.attemptAllowed(now) {
+			cs.refreshCurrent(now)
 		}
+		return cs.current
+	}
+
+	if !cs.health.checkDue(now) {
+		return cs.current
 	}
+
+	if cs.current.IsConnectionHealthy() {
+		cs.health.recordSuccess(now)
+		return cs.current
+	}
+
+	cs.refreshCurrent(now)
 	return cs.current
 }
 
+// refreshCurrent tries to restore the current connection, reconnecting by name
+// if the manager's connection is no longer usable; callers hold mu
+func (cs *ConnectionService) refreshCurrent(now time.Time) {
+	if !cs.health.attemptAllowed(now) {
+		cs.current = nil
+		return
+	}
+
+	dbInterface, name, err := cs.manager.GetCurrentConnection()
+	if err != nil && cs.currentName != "" {
+		if switchErr := cs.manager.SwitchConnection(cs.currentName); switchErr == nil {
+			dbInterface, name, err = cs.manager.GetCurrentConnection()
+		} else {
+			err = switchErr
+		}
+	}
+
+	if err != nil {
+		cs.health.recordFailure(now, err)
+		cs.current = nil
+		return
+	}
+
+	cs.health.recordSuccess(now)
+	cs.current = dbInterface
+	cs.currentName = name
+	cs.detectLatency()
+}
+
+// detectLatency measures the round trip of the current connection and puts
+// it in remote mode when slower than the remote threshold. Only connections
+// of the manager can be put in remote mode. Callers hold mu.
+func (cs *ConnectionService) detectLatency() {
+	limited, ok := cs.current.(*LimitedDatabase)
+	if !ok {
+		return
+	}
+	latency, err := MeasureLatency(limited)
+	if err != nil {
+		return
+	}
+	threshold := RemoteLatencyThreshold()
+	remote := threshold > 0 && latency >= threshold
+	limited.SetRemote(remote)
+	cs.health.setLatency(latency, remote)
+	if remote {
+		log.Printf("Connection %s has a %s round trip, using remote mode", cs.currentName, latency.Round(time.Millisecond))
+	}
+}
+
+// CurrentConnection returns the current connection and its name as the health
+// checks last left them, without checking it, for display on every render.
+// The connection is nil while it is down.
+func (cs *ConnectionService) CurrentConnection() (dbinterfaces.DatabaseInterface, string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.current, cs.currentName
+}
+
+// GetHealthStatus returns the health state of the current connection
+func (cs *ConnectionService) GetHealthStatus() dbinterfaces.HealthStatus {
+	return cs.health.status()
+}
+
+// SetHealthPolicy changes the health check TTL and reconnect backoff
+func (cs *ConnectionService) SetHealthPolicy(policy HealthPolicy) {
+	cs.health.mu.Lock()
+	defer cs.health.mu.Unlock()
+	cs.health.policy = policy
+}
+
 // GetConnectionManager returns the connection manager
 func (cs *ConnectionService) GetConnectionManager() dbinterfaces.ConnectionManagerInterface {
 	return cs.manager
@@ -81,9 +187,14 @@ func (cs *ConnectionService) AddConnection(config *dbinterfaces.ConnectionConfig
 	}
 
 	// Update current connection if this is the first one or if requested
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	if cs.current == nil {
-		if dbInterface, _, err := cs.manager.GetCurrentConnection(); err == nil {
+		if dbInterface, name, err := cs.manager.GetCurrentConnection(); err == nil {
 			cs.current = dbInterface
+			cs.currentName = name
+			cs.health.reset()
+			cs.detectLatency()
 		}
 	}
 
@@ -98,14 +209,54 @@ func (cs *ConnectionService) SwitchConnection(name string) error {
 	}
 
 	// Update current tools
-	if dbInterface, _, err := cs.manager.GetCurrentConnection(); err == nil {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if dbInterface, current, err := cs.manager.GetCurrentConnection(); err == nil {
 		cs.current = dbInterface
+		cs.currentName = current
+		cs.health.reset()
+		cs.health.recordSuccess(time.Now())
+		cs.detectLatency()
 		return nil
 	}
 
 	return fmt.Errorf("failed to update current connection after switch")
 }
 
+// UpdateConnection replaces the settings of a connection, keeping the previous ones for undo
+func (cs *ConnectionService) UpdateConnection(config *dbinterfaces.ConnectionConfig) error {
+	if err := cs.manager.UpdateConnection(config); err != nil {
+		return err
+	}
+	cs.refreshAfterEdit(config.Name)
+	return nil
+}
+
+// UndoConnectionEdit restores the settings a connection had before its last edit
+func (cs *ConnectionService) UndoConnectionEdit(name string) error {
+	if err := cs.manager.UndoConnectionEdit(name); err != nil {
+		return err
+	}
+	cs.refreshAfterEdit(name)
+	return nil
+}
+
+// refreshAfterEdit picks up the new connection when the edited one is current
+func (cs *ConnectionService) refreshAfterEdit(name string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.currentName != name {
+		return
+	}
+	if dbInterface, current, err := cs.manager.GetCurrentConnection(); err == nil {
+		cs.current = dbInterface
+		cs.currentName = current
+		cs.health.reset()
+		cs.health.recordSuccess(time.Now())
+		cs.detectLatency()
+	}
+}
+
 // RemoveConnection removes a database connection
 func (cs *ConnectionService) RemoveConnection(name string) error {
 	err := cs.manager.RemoveConnection(name)
@@ -114,11 +265,17 @@ func (cs *ConnectionService) RemoveConnection(name string) error {
 	}
 
 	// Update current tools if the removed connection was current
-	if dbInterface, _, err := cs.manager.GetCurrentConnection(); err == nil {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if dbInterface, current, err := cs.manager.GetCurrentConnection(); err == nil {
 		cs.current = dbInterface
+		cs.currentName = current
 	} else {
 		cs.current = nil
+		cs.currentName = ""
 	}
+	cs.health.reset()
+	cs.detectLatency()
 
 	return nil
 }
@@ -151,21 +308,31 @@ func (cs *ConnectionService) TestConnection(config *dbinterfaces.ConnectionConfi
 	return fmt.Errorf("unable to access provider manager for connection testing")
 }
 
-// Close closes all connections
+// Close stops the health monitor and closes all connections
 func (cs *ConnectionService) Close() error {
-	if cs.current != nil {
-		cs.current = nil
-	}
+	cs.StopHealthMonitor()
+	cs.mu.Lock()
+	cs.current = nil
+	cs.mu.Unlock()
 	return cs.manager.Close()
 }
 
 // IsConnected returns whether there's an active database connection
 func (cs *ConnectionService) IsConnected() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	return cs.current != nil
 }
 
 // IsConnectionHealthy returns whether the current connection is healthy
 func (cs *ConnectionService) IsConnectionHealthy() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.currentHealthy()
+}
+
+// currentHealthy pings the current connection; callers hold mu
+func (cs *ConnectionService) currentHealthy() bool {
 	if cs.current == nil {
 		return false
 	}
@@ -174,6 +341,8 @@ func (cs *ConnectionService) IsConnectionHealthy() bool {
 
 // EnsureHealthyConnection ensures the current connection is healthy, attempts to reconnect if not
 func (cs *ConnectionService) EnsureHealthyConnection() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	if cs.current == nil {
 		return fmt.Errorf("no active database connection")
 	}
@@ -182,14 +351,17 @@ func (cs *ConnectionService) EnsureHealthyConnection() error {
 		// Try to get a healthy connection from the manager
 		if dbInterface, name, err := cs.manager.GetCurrentConnection(); err == nil {
 			cs.current = dbInterface
+			cs.health.recordSuccess(time.Now())
 			log.Printf("Reconnected to database: %s", name)
 			return nil
 		} else {
 			cs.current = nil
+			cs.health.recordFailure(time.Now(), err)
 			return fmt.Errorf("failed to restore healthy connection: %w", err)
 		}
 	}
 
+	cs.health.recordSuccess(time.Now())
 	return nil
 }
 
@@ -198,14 +370,18 @@ func (cs *ConnectionService) GetConnectionStats() map[string]interface{} {
 	connections := cs.manager.ListConnections()
 	status := cs.manager.GetConnectionStatus()
 
+	cs.mu.Lock()
+	hasCurrent, healthy := cs.current != nil, cs.currentHealthy()
+	cs.mu.Unlock()
+
 	stats := map[string]interface{}{
 		"total_connections":        len(connections),
 		"active_connections":       0,
 		"connected_connections":    0,
 		"unhealthy_connections":    0,
 		"disconnected_connections": 0,
-		"has_current":              cs.current != nil,
-		"current_is_healthy":       cs.IsConnectionHealthy(),
+		"has_current":              hasCurrent,
+		"current_is_healthy":       healthy,
 	}
 
 	for _, s := range status {
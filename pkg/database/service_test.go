@@ -3,6 +3,7 @@ package database
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"dbsage/internal/models"
 	"dbsage/pkg/dbinterfaces"
@@ -72,6 +73,16 @@ func (m *MockConnectionManager) AddConnection(config *dbinterfaces.ConnectionCon
 	return args.Error(0)
 }
 
+func (m *MockConnectionManager) UpdateConnection(config *dbinterfaces.ConnectionConfig) error {
+	args := m.Called(config)
+	return args.Error(0)
+}
+
+func (m *MockConnectionManager) UndoConnectionEdit(name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
 func (m *MockConnectionManager) RemoveConnection(name string) error {
 	args := m.Called(name)
 	return args.Error(0)
@@ -84,7 +95,8 @@ func (m *MockConnectionManager) ListConnections() map[string]*dbinterfaces.Conne
 
 func (m *MockConnectionManager) GetCurrentConnection() (dbinterfaces.DatabaseInterface, string, error) {
 	args := m.Called()
-	return args.Get(0).(dbinterfaces.DatabaseInterface), args.String(1), args.Error(2)
+	db, _ := args.Get(0).(dbinterfaces.DatabaseInterface)
+	return db, args.String(1), args.Error(2)
 }
 
 func (m *MockConnectionManager) SwitchConnection(name string) error {
@@ -122,12 +134,12 @@ func TestConnectionService_GetCurrentTools(t *testing.T) {
 	}
 
 	// Test healthy connection
-	mockDB.On("IsConnectionHealthy").Return(true)
+	mockDB.On("IsConnectionHealthy").Return(true).Once()
 	result := service.GetCurrentTools()
 	assert.Equal(t, mockDB, result)
 
 	// Test unhealthy connection with successful refresh
-	mockDB.On("IsConnectionHealthy").Return(false)
+	mockDB.On("IsConnectionHealthy").Return(false).Once()
 	newMockDB := &MockDatabaseInterface{}
 	mockManager.On("GetCurrentConnection").Return(newMockDB, "test_conn", nil)
 
@@ -139,6 +151,19 @@ func TestConnectionService_GetCurrentTools(t *testing.T) {
 	mockManager.AssertExpectations(t)
 }
 
+func TestConnectionService_CurrentConnection(t *testing.T) {
+	mockManager := &MockConnectionManager{}
+	mockDB := &MockDatabaseInterface{}
+	service := &ConnectionService{manager: mockManager, current: mockDB, currentName: "main"}
+
+	// Neither the connection nor the manager is asked, so nothing is pinged
+	db, name := service.CurrentConnection()
+	assert.Equal(t, mockDB, db)
+	assert.Equal(t, "main", name)
+	mockDB.AssertNotCalled(t, "IsConnectionHealthy")
+	mockManager.AssertNotCalled(t, "GetCurrentConnection")
+}
+
 func TestConnectionService_GetCurrentTools_UnhealthyConnectionFailedRefresh(t *testing.T) {
 	mockManager := &MockConnectionManager{}
 	mockDB := &MockDatabaseInterface{}
@@ -349,12 +374,12 @@ func TestConnectionService_IsConnectionHealthy(t *testing.T) {
 
 	// Test with healthy connection
 	mockDB := &MockDatabaseInterface{}
-	mockDB.On("IsConnectionHealthy").Return(true)
+	mockDB.On("IsConnectionHealthy").Return(true).Once()
 	service.current = mockDB
 	assert.True(t, service.IsConnectionHealthy())
 
 	// Test with unhealthy connection
-	mockDB.On("IsConnectionHealthy").Return(false)
+	mockDB.On("IsConnectionHealthy").Return(false).Once()
 	assert.False(t, service.IsConnectionHealthy())
 
 	mockDB.AssertExpectations(t)
@@ -369,12 +394,12 @@ func TestConnectionService_EnsureHealthyConnection(t *testing.T) {
 	}
 
 	// Test with healthy connection
-	mockDB.On("IsConnectionHealthy").Return(true)
+	mockDB.On("IsConnectionHealthy").Return(true).Once()
 	err := service.EnsureHealthyConnection()
 	require.NoError(t, err)
 
 	// Test with unhealthy connection that can be restored
-	mockDB.On("IsConnectionHealthy").Return(false)
+	mockDB.On("IsConnectionHealthy").Return(false).Once()
 	newMockDB := &MockDatabaseInterface{}
 	mockManager.On("GetCurrentConnection").Return(newMockDB, "restored_db", nil)
 
@@ -470,3 +495,51 @@ func TestConnectionService_Close(t *testing.T) {
 
 	mockManager.AssertExpectations(t)
 }
+
+func TestConnectionService_GetCurrentTools_CachesHealthWithinTTL(t *testing.T) {
+	mockManager := &MockConnectionManager{}
+	mockDB := &MockDatabaseInterface{}
+	service := &ConnectionService{
+		manager: mockManager,
+		current: mockDB,
+	}
+	service.SetHealthPolicy(HealthPolicy{CheckTTL: time.Hour})
+
+	// Only the first call pings the database
+	mockDB.On("IsConnectionHealthy").Return(true).Once()
+	assert.Equal(t, mockDB, service.GetCurrentTools())
+	assert.Equal(t, mockDB, service.GetCurrentTools())
+	assert.Equal(t, dbinterfaces.HealthHealthy, service.GetHealthStatus().State)
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestConnectionService_GetCurrentTools_BacksOffWhenDown(t *testing.T) {
+	mockManager := &MockConnectionManager{}
+	mockDB := &MockDatabaseInterface{}
+	service := &ConnectionService{
+		manager: mockManager,
+		current: mockDB,
+	}
+	service.SetHealthPolicy(HealthPolicy{FailureThreshold: 2, BaseBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	mockDB.On("IsConnectionHealthy").Return(false).Once()
+	mockManager.On("GetCurrentConnection").Return(nil, "", errors.New("connection refused")).Twice()
+
+	// First failure degrades, the lazy reconnect on the next call fails again and opens the breaker
+	assert.Nil(t, service.GetCurrentTools())
+	assert.Equal(t, dbinterfaces.HealthDegraded, service.GetHealthStatus().State)
+	assert.Nil(t, service.GetCurrentTools())
+
+	status := service.GetHealthStatus()
+	assert.Equal(t, dbinterfaces.HealthDown, status.State)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Equal(t, "connection refused", status.LastError)
+	assert.True(t, status.NextAttempt.After(time.Now()))
+
+	// While the breaker is open no further reconnects are attempted
+	assert.Nil(t, service.GetCurrentTools())
+
+	mockDB.AssertExpectations(t)
+	mockManager.AssertExpectations(t)
+}
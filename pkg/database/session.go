@@ -0,0 +1,44 @@
+package database
+
+import (
+	"fmt"
+
+	"dbsage/internal/models"
+	"dbsage/internal/sqlanalysis"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// RunInSession runs fn with a function querying one session of the database,
+// in a transaction that is rolled back when fn returns, so session state such
+// as HypoPG's hypothetical indexes goes with it. Only reads run in the
+// session, each under a savepoint so a failed one leaves the session usable.
+func (l *LimitedDatabase) RunInSession(fn func(query dbinterfaces.SessionQuery) error) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.release()
+
+	tx, err := dbinterfaces.BeginTx(l.DatabaseInterface)
+	if err != nil {
+		return fmt.Errorf("failed to open a session on '%s': %w", l.name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	return fn(func(query string, args ...interface{}) (*models.QueryResult, error) {
+		if verb := sqlanalysis.FirstWrite(query); verb != "" {
+			return nil, fmt.Errorf("%s statements cannot run in a read session", verb)
+		}
+		if _, err := tx.Exec("SAVEPOINT dbsage_session"); err != nil {
+			return nil, fmt.Errorf("session savepoint failed: %w", err)
+		}
+		result, err := queryTx(tx, LabelQuery(query, l.name), args...)
+		if err != nil {
+			_, _ = tx.Exec("ROLLBACK TO SAVEPOINT dbsage_session")
+			return nil, err
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT dbsage_session"); err != nil {
+			return nil, fmt.Errorf("session savepoint failed: %w", err)
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"testing"
+
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInSession(t *testing.T) {
+	db := openScratchTestDB(t)
+
+	err := dbinterfaces.RunInSession(db, func(query dbinterfaces.SessionQuery) error {
+		_, err := query("DELETE FROM orders")
+		assert.ErrorContains(t, err, "cannot run in a read session")
+
+		_, err = query("SELECT * FROM missing_table")
+		assert.Error(t, err)
+
+		result, err := query("SELECT status FROM orders WHERE id = ?", 2)
+		require.NoError(t, err, "a failed statement leaves the session usable")
+		assert.Equal(t, [][]interface{}{{"new"}}, result.Rows)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), countOrders(t, db))
+
+	assert.Error(t, dbinterfaces.RunInSession(&MockDatabaseInterface{}, func(dbinterfaces.SessionQuery) error { return nil }))
+}
@@ -0,0 +1,172 @@
+// Package sessions runs statements on pinned connections of a pool while
+// recording the server session each one runs in, so an aborted statement can
+// be cancelled on the server instead of being left running there.
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	badConnRetries = 2                // Further connections tried when the driver reports a bad one, as the pool does
+	maxCachedIDs   = 64               // Session IDs kept before the cache is reset, dropping connections the pool closed
+	cancelTimeout  = 10 * time.Second // Longest wait for the server to accept a cancellation
+)
+
+// CancelFunc cancels the statement running in a server session, using
+// another connection of the pool
+type CancelFunc func(ctx context.Context, db *sql.DB, id int64) error
+
+// Tracker records the statements running on the connections of a pool
+type Tracker struct {
+	db      *sql.DB
+	idQuery string // Returns the session of a connection, e.g. SELECT pg_backend_pid()
+	cancel  CancelFunc
+
+	mu      sync.Mutex
+	ids     map[interface{}]int64 // Session IDs by driver connection
+	running map[*statement]struct{}
+}
+
+// statement is a statement running on a pinned connection
+type statement struct {
+	id   int64
+	stop context.CancelFunc
+}
+
+// NewTracker creates a tracker for a pool. idQuery returns the server session
+// of a connection and cancel cancels the statement running in a session.
+// Without them, as for SQLite, statements are cancelled through their context,
+// which interrupts them in the driver.
+func NewTracker(db *sql.DB, idQuery string, cancel CancelFunc) *Tracker {
+	return &Tracker{
+		db:      db,
+		idQuery: idQuery,
+		cancel:  cancel,
+		ids:     make(map[interface{}]int64),
+		running: make(map[*statement]struct{}),
+	}
+}
+
+// Pin takes a connection of the pool, runs fn on it and records it as running
+// until release is called. fn runs again on another connection when the
+// driver reports a bad one, and the connection is released when fn fails.
+func (t *Tracker) Pin(fn func(ctx context.Context, conn *sql.Conn) error) (release func(), err error) {
+	for attempt := 0; ; attempt++ {
+		release, err = t.pin(fn)
+		if err == nil || !errors.Is(err, driver.ErrBadConn) || attempt >= badConnRetries {
+			return release, err
+		}
+	}
+}
+
+// Run runs fn on a pinned connection and releases it
+func (t *Tracker) Run(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	release, err := t.Pin(fn)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
+}
+
+// pin makes one attempt of Pin
+func (t *Tracker) pin(fn func(ctx context.Context, conn *sql.Conn) error) (func(), error) {
+	ctx, stop := context.WithCancel(context.Background())
+	conn, err := t.db.Conn(ctx)
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+	id, err := t.sessionID(ctx, conn)
+	if err != nil {
+		conn.Close()
+		stop()
+		return nil, err
+	}
+
+	s := &statement{id: id, stop: stop}
+	t.mu.Lock()
+	t.running[s] = struct{}{}
+	t.mu.Unlock()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.running, s)
+			t.mu.Unlock()
+			conn.Close()
+			stop()
+		})
+	}
+
+	if err := fn(ctx, conn); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// sessionID returns the server session of a connection, asking the server
+// only the first time the connection is used
+func (t *Tracker) sessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	if t.idQuery == "" {
+		return 0, nil
+	}
+	var key interface{}
+	if err := conn.Raw(func(driverConn interface{}) error {
+		key = driverConn
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	id, ok := t.ids[key]
+	t.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	if err := conn.QueryRowContext(ctx, t.idQuery).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get the server session of a connection: %w", err)
+	}
+
+	t.mu.Lock()
+	if len(t.ids) >= maxCachedIDs {
+		t.ids = make(map[interface{}]int64)
+	}
+	t.ids[key] = id
+	t.mu.Unlock()
+	return id, nil
+}
+
+// Cancel cancels the running statements and returns how many were cancelled.
+// Their callers get the error the driver reports for a cancelled statement.
+func (t *Tracker) Cancel() (int, error) {
+	t.mu.Lock()
+	statements := make([]*statement, 0, len(t.running))
+	for s := range t.running {
+		statements = append(statements, s)
+	}
+	t.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+	var errs []error
+	for _, s := range statements {
+		if t.cancel == nil {
+			s.stop()
+			continue
+		}
+		if err := t.cancel(ctx, t.db, s.id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to cancel the statement of session %d: %w", s.id, err))
+		}
+	}
+	return len(statements) - len(errs), errors.Join(errs...)
+}
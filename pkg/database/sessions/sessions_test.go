@@ -0,0 +1,74 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// cancelWhenRunning cancels the statements of a tracker once one runs
+func cancelWhenRunning(t *testing.T, tracker *Tracker) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		cancelled, err := tracker.Cancel()
+		require.NoError(t, err)
+		return cancelled == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTracker_CancelInterruptsStatement(t *testing.T) {
+	tracker := NewTracker(openTestDB(t), "", nil)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- tracker.Run(func(ctx context.Context, conn *sql.Conn) error {
+			var count int
+			// Never finishes on its own
+			return conn.QueryRowContext(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c").Scan(&count)
+		})
+	}()
+	cancelWhenRunning(t, tracker)
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("statement was not interrupted")
+	}
+
+	cancelled, err := tracker.Cancel()
+	require.NoError(t, err)
+	assert.Zero(t, cancelled, "finished statements are no longer tracked")
+}
+
+func TestTracker_CancelsSessionFromServer(t *testing.T) {
+	cancelledIDs := make(chan int64, 1)
+	tracker := NewTracker(openTestDB(t), "SELECT 42", func(ctx context.Context, db *sql.DB, id int64) error {
+		cancelledIDs <- id
+		return nil
+	})
+
+	release, err := tracker.Pin(func(ctx context.Context, conn *sql.Conn) error { return nil })
+	require.NoError(t, err)
+	cancelWhenRunning(t, tracker)
+	assert.Equal(t, int64(42), <-cancelledIDs)
+
+	release()
+	release() // Releasing twice is harmless
+	cancelled, err := tracker.Cancel()
+	require.NoError(t, err)
+	assert.Zero(t, cancelled)
+}
@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"dbsage/pkg/database/drivertest"
+	"dbsage/pkg/dbinterfaces"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conformance.db")
+	drivertest.Run(t, func(t *testing.T) dbinterfaces.DatabaseInterface {
+		db, err := NewSQLiteDatabase(path)
+		require.NoError(t, err)
+		return db
+	}, drivertest.Options{Dialect: "sqlite"})
+}
@@ -39,6 +39,20 @@ func NewSQLiteDatabase(connectionURL string) (*SQLiteDatabase, error) {
 	}, nil
 }
 
+// NewSQLiteDatabaseFromDB wraps an already opened SQLite handle, such as an
+// in-memory database that was populated by the caller
+func NewSQLiteDatabaseFromDB(db *sql.DB) *SQLiteDatabase {
+	return &SQLiteDatabase{
+		db:            db,
+		queryExecutor: queries.NewSQLiteExecutor(db),
+	}
+}
+
+// DatabaseType returns the database type name
+func (s *SQLiteDatabase) DatabaseType() string {
+	return "sqlite"
+}
+
 // Close closes the database connection
 func (s *SQLiteDatabase) Close() error {
 	if s.db != nil {
@@ -68,11 +82,36 @@ func (s *SQLiteDatabase) ExecuteSQL(query string) (*models.QueryResult, error) {
 	return s.queryExecutor.ExecuteSQL(query)
 }
 
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters
+func (s *SQLiteDatabase) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
+	return s.queryExecutor.ExecuteSQLWithArgs(query, args...)
+}
+
+// ExecuteExpectingRows runs a write statement that is rolled back unless it affects the expected rows
+func (s *SQLiteDatabase) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	return s.queryExecutor.ExecuteExpectingRows(query, expected, args...)
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (s *SQLiteDatabase) ExplainQuery(query string) (*models.QueryResult, error) {
 	return s.queryExecutor.ExplainQuery(query)
 }
 
+// OpenCursor runs a query and returns a cursor reading its rows in batches
+func (s *SQLiteDatabase) OpenCursor(query string) (dbinterfaces.RowCursor, error) {
+	return s.queryExecutor.OpenCursor(query)
+}
+
+// BeginTx starts a transaction on one connection of the pool
+func (s *SQLiteDatabase) BeginTx() (*sql.Tx, error) {
+	return s.db.Begin()
+}
+
+// CancelRunning cancels the statements the connection is running
+func (s *SQLiteDatabase) CancelRunning() (int, error) {
+	return s.queryExecutor.CancelRunning()
+}
+
 // GetAllTables returns a list of all tables
 func (s *SQLiteDatabase) GetAllTables() ([]models.TableInfo, error) {
 	query := `
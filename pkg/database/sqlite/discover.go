@@ -0,0 +1,165 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxDiscoveredFiles is the most database files Discover reports
+const MaxDiscoveredFiles = 200
+
+// sqliteHeader starts every SQLite 3 database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// discoverExtensions are the file extensions Discover looks at
+var discoverExtensions = map[string]bool{
+	".db":      true,
+	".sqlite":  true,
+	".sqlite3": true,
+	".db3":     true,
+}
+
+// skippedDirs are never descended into
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+// DiscoveredFile is a SQLite database found by Discover
+type DiscoveredFile struct {
+	Path     string
+	Size     int64
+	Modified time.Time
+	Tables   int    // -1 when the file could not be opened
+	Err      string // Why the tables could not be counted
+}
+
+// Discover walks a directory tree for SQLite database files, checking the
+// file header so other .db files are skipped, and counts their tables
+// read-only. Unreadable directories are skipped. It reports whether more than
+// MaxDiscoveredFiles were found.
+func Discover(root string) ([]DiscoveredFile, bool, error) {
+	// Connections store the path, so make it usable from any directory
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return nil, false, fmt.Errorf("%s is not a directory", root)
+	}
+
+	var found []DiscoveredFile
+	truncated := false
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skippedDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !discoverExtensions[strings.ToLower(filepath.Ext(path))] || !hasSQLiteHeader(path) {
+			return nil
+		}
+		if len(found) == MaxDiscoveredFiles {
+			truncated = true
+			return fs.SkipAll
+		}
+
+		file := DiscoveredFile{Path: path, Tables: -1}
+		if info, err := d.Info(); err == nil {
+			file.Size = info.Size()
+			file.Modified = info.ModTime()
+		}
+		if tables, err := countTables(path); err != nil {
+			file.Err = err.Error()
+		} else {
+			file.Tables = tables
+		}
+		found = append(found, file)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Most recently used files first, they are usually the interesting ones
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Modified.After(found[j].Modified)
+	})
+	return found, truncated, nil
+}
+
+// hasSQLiteHeader reports whether a file starts with the SQLite 3 header
+func hasSQLiteHeader(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, sqliteHeader)
+}
+
+// countTables counts the user tables of a database opened read-only
+func countTables(path string) (int, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro&_timeout=2000", url.QueryEscape(filepath.Clean(path))))
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read tables: %w", err)
+	}
+	return count, nil
+}
+
+// ConnectionName derives a connection name from a database file name, adding
+// a numeric suffix when the name is already taken
+func ConnectionName(path string, taken func(string) bool) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var b strings.Builder
+	for _, r := range strings.ToLower(base) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := strings.Trim(b.String(), "_")
+	if name == "" {
+		name = "sqlite"
+	}
+
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	return candidate
+}
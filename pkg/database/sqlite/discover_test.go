@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createDatabase(t *testing.T, path string, tables ...string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, table := range tables {
+		_, err := db.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)")
+		require.NoError(t, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	createDatabase(t, filepath.Join(root, "app", "data.db"), "users", "orders")
+	createDatabase(t, filepath.Join(root, "cache.sqlite3"), "entries")
+	createDatabase(t, filepath.Join(root, "node_modules", "pkg", "fixture.db"), "ignored")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.db"), []byte("not a database"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "readme.txt"), []byte("hello"), 0o644))
+
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "cache.sqlite3"), old, old))
+
+	found, truncated, err := Discover(root)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, found, 2)
+
+	assert.Equal(t, filepath.Join(root, "app", "data.db"), found[0].Path)
+	assert.Equal(t, 2, found[0].Tables)
+	assert.Positive(t, found[0].Size)
+	assert.Equal(t, filepath.Join(root, "cache.sqlite3"), found[1].Path)
+	assert.Equal(t, 1, found[1].Tables)
+}
+
+func TestDiscover_NotADirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.db")
+	createDatabase(t, path, "t")
+
+	_, _, err := Discover(path)
+	assert.ErrorContains(t, err, "is not a directory")
+}
+
+func TestConnectionName(t *testing.T) {
+	taken := map[string]bool{"data": true, "data_2": true}
+	isTaken := func(name string) bool { return taken[name] }
+
+	assert.Equal(t, "data_3", ConnectionName("/tmp/data.db", isTaken))
+	assert.Equal(t, "my_app_cache", ConnectionName("/tmp/My App.cache.sqlite", isTaken))
+	assert.Equal(t, "sqlite", ConnectionName("/tmp/.db", isTaken))
+}
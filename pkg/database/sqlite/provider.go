@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"dbsage/pkg/dbinterfaces"
 )
@@ -31,6 +33,7 @@ func (p *SQLiteProvider) CreateConnection(config *dbinterfaces.ConnectionConfig)
 		Timeout:     "30s",    // Default timeout
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	}
 
 	if err := p.validateLocalConfig(localConfig); err != nil {
@@ -56,6 +59,7 @@ func (p *SQLiteProvider) ValidateConfig(config *dbinterfaces.ConnectionConfig) e
 		Timeout:     "30s",
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
@@ -114,6 +118,7 @@ func (p *SQLiteProvider) BuildConnectionURL(config *dbinterfaces.ConnectionConfi
 		Timeout:     "30s",
 		Description: config.Description,
 		LastUsed:    config.LastUsed,
+		ReadOnly:    config.ReadOnly,
 	})
 }
 
@@ -134,10 +139,13 @@ func (p *SQLiteProvider) buildLocalConnectionURL(config *ConnectionConfig) strin
 	params := make(map[string]string)
 	params["mode"] = config.Mode
 	params["cache"] = config.Cache
-	params["_timeout"] = config.Timeout
+	params["_timeout"] = busyTimeoutMillis(config.Timeout)
 	params["_journal_mode"] = "WAL"   // Use WAL mode for better concurrency
 	params["_synchronous"] = "NORMAL" // Balance between safety and performance
 	params["_foreign_keys"] = "on"    // Enable foreign key constraints
+	if config.ReadOnly {
+		params["_query_only"] = "on" // SQLite itself refuses writes
+	}
 
 	// Build query string
 	var paramPairs []string
@@ -151,3 +159,16 @@ func (p *SQLiteProvider) buildLocalConnectionURL(config *ConnectionConfig) strin
 
 	return dsn
 }
+
+// busyTimeoutMillis converts a timeout such as "30s" to the milliseconds the
+// sqlite3 driver expects. Plain numbers are already milliseconds.
+func busyTimeoutMillis(timeout string) string {
+	if _, err := strconv.Atoi(timeout); err == nil {
+		return timeout
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d <= 0 {
+		return "30000"
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
@@ -1,37 +1,71 @@
 package queries
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"dbsage/internal/models"
+	"dbsage/pkg/database/sessions"
 	"dbsage/pkg/dbinterfaces"
 )
 
 // SQLiteExecutor handles SQL query execution for SQLite
 type SQLiteExecutor struct {
-	db *sql.DB
+	db       *sql.DB
+	sessions *sessions.Tracker // Running statements, for CancelRunning
 }
 
 // NewSQLiteExecutor creates a new SQLite query executor
 func NewSQLiteExecutor(db *sql.DB) *SQLiteExecutor {
-	return &SQLiteExecutor{db: db}
+	return &SQLiteExecutor{
+		db:       db,
+		sessions: sessions.NewTracker(db, "", nil),
+	}
 }
 
 // Ensure SQLiteExecutor implements QueryExecutorInterface
 var _ dbinterfaces.QueryExecutorInterface = (*SQLiteExecutor)(nil)
+var _ dbinterfaces.ParameterizedExecutor = (*SQLiteExecutor)(nil)
+var _ dbinterfaces.GuardedExecutor = (*SQLiteExecutor)(nil)
+var _ dbinterfaces.Canceller = (*SQLiteExecutor)(nil)
+
+// CancelRunning interrupts the running statements, which SQLite runs in
+// process, and returns how many were interrupted
+func (e *SQLiteExecutor) CancelRunning() (int, error) {
+	return e.sessions.Cancel()
+}
 
 // ExecuteSQL executes a SQL query and returns structured results
 func (e *SQLiteExecutor) ExecuteSQL(query string) (*models.QueryResult, error) {
+	return e.ExecuteSQLWithArgs(query)
+}
+
+// ExecuteSQLWithArgs executes a SQL statement with bound parameters and returns structured results
+func (e *SQLiteExecutor) ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error) {
 	start := time.Now()
 
-	rows, err := e.db.Query(query)
+	var result *models.QueryResult
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query execution failed: %w", err)
+		}
+		defer rows.Close()
+		result, err = readResult(rows)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
+	result.Duration = time.Since(start).String()
+	return result, nil
+}
+
+// readResult reads the columns and rows of a query
+func readResult(rows *sql.Rows) (*models.QueryResult, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
@@ -76,16 +110,50 @@ func (e *SQLiteExecutor) ExecuteSQL(query string) (*models.QueryResult, error) {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	duration := time.Since(start)
-
 	return &models.QueryResult{
 		Columns:  columns,
 		Rows:     resultRows,
 		RowCount: len(resultRows),
-		Duration: duration.String(),
 	}, nil
 }
 
+// ExecuteExpectingRows runs a write statement in a transaction and commits it
+// only if it affected exactly the expected number of rows
+func (e *SQLiteExecutor) ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error) {
+	var affected int64
+	err := e.sessions.Run(func(ctx context.Context, conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("statement execution failed: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rowsAffected != expected {
+			affected = rowsAffected
+			if err := tx.Rollback(); err != nil {
+				return fmt.Errorf("failed to roll back transaction: %w", err)
+			}
+			return &dbinterfaces.RowCountMismatchError{Expected: expected, Actual: rowsAffected}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		affected = rowsAffected
+		return nil
+	})
+	return affected, err
+}
+
 // ExplainQuery analyzes a query's execution plan
 func (e *SQLiteExecutor) ExplainQuery(query string) (*models.QueryResult, error) {
 	// SQLite uses EXPLAIN QUERY PLAN for execution plan analysis
@@ -9,4 +9,5 @@ type ConnectionConfig struct {
 	Timeout     string `json:"timeout"`  // Connection timeout
 	Description string `json:"description"`
 	LastUsed    string `json:"last_used,omitempty"` // ISO 8601 timestamp
+	ReadOnly    bool   `json:"read_only,omitempty"` // Refuse changes to the database file
 }
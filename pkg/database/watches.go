@@ -0,0 +1,40 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"dbsage/internal/watches"
+	"dbsage/pkg/dbinterfaces"
+)
+
+// SweepAbandonedWatches removes the helper triggers and log tables that
+// watches of a connection left behind when dbsage stopped while watching.
+// Watches whose objects cannot be removed stay recorded for the next sweep.
+// It returns the number of watches cleaned up.
+func SweepAbandonedWatches(db dbinterfaces.DatabaseInterface, connection string, now time.Time) int {
+	abandoned, err := watches.Abandoned(connection, now)
+	if err != nil {
+		log.Printf("Failed to read the watches of %s: %v", connection, err)
+		return 0
+	}
+	swept := 0
+	for _, w := range abandoned {
+		var failed error
+		for _, stmt := range w.Teardown {
+			if _, err := db.ExecuteSQL(stmt); err != nil && failed == nil {
+				failed = err
+			}
+		}
+		if failed != nil {
+			log.Printf("Failed to remove the objects of an interrupted watch of %s on %s: %v", w.Table, connection, failed)
+			continue
+		}
+		if err := watches.Forget(connection, w.LogTable); err != nil {
+			log.Printf("Failed to forget the watch of %s on %s: %v", w.Table, connection, err)
+			continue
+		}
+		swept++
+	}
+	return swept
+}
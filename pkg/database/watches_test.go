@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"dbsage/internal/watches"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepAbandonedWatches(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db := openScratchTestDB(t)
+	now := time.Now()
+
+	_, err := db.ExecuteSQL("CREATE TABLE dbsage_watch_old (seq INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, watches.Record(watches.Watch{Connection: "shop", Table: "orders", LogTable: "dbsage_watch_old", Teardown: []string{"DROP TABLE IF EXISTS dbsage_watch_old"}, Until: now.Add(-time.Minute)}))
+	require.NoError(t, watches.Record(watches.Watch{Connection: "shop", Table: "orders", LogTable: "dbsage_watch_live", Teardown: []string{"DROP TABLE IF EXISTS dbsage_watch_live"}, Until: now.Add(time.Minute)}))
+	require.NoError(t, watches.Record(watches.Watch{Connection: "shop", Table: "orders", LogTable: "dbsage_watch_bad", Teardown: []string{"DROP TABLE dbsage_watch_bad"}, Until: now.Add(-time.Minute)}))
+
+	assert.Equal(t, 1, SweepAbandonedWatches(db, "shop", now))
+	tables, err := db.GetAllTables()
+	require.NoError(t, err)
+	for _, table := range tables {
+		assert.NotEqual(t, "dbsage_watch_old", table.TableName)
+	}
+
+	left, err := watches.Abandoned("shop", now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, left, 2, "running watches and failed teardowns stay recorded")
+	assert.Equal(t, "dbsage_watch_live", left[0].LogTable)
+	assert.Equal(t, "dbsage_watch_bad", left[1].LogTable)
+}
@@ -1,10 +1,16 @@
 package dbinterfaces
 
 import (
+	"database/sql"
+	"fmt"
+	"time"
+
 	"dbsage/internal/models"
 )
 
 // DatabaseInterface defines the core interface that all database implementations must satisfy
+// Further capabilities are optional interfaces, checked by the helpers of this
+// file, so that mocks need not implement them.
 type DatabaseInterface interface {
 	// Connection management
 	Close() error
@@ -24,10 +30,298 @@ type DatabaseInterface interface {
 	FindDuplicateData(tableName string, columns []string) (*models.QueryResult, error)
 }
 
+// DatabaseTypeProvider is implemented by databases that can report their type
+// (postgresql, mysql, sqlite).
+type DatabaseTypeProvider interface {
+	DatabaseType() string
+}
+
+// GetDatabaseType returns the type of a database, or an empty string if unknown
+func GetDatabaseType(db DatabaseInterface) string {
+	if provider, ok := db.(DatabaseTypeProvider); ok {
+		return provider.DatabaseType()
+	}
+	return ""
+}
+
+// ParameterizedExecutor is implemented by databases that can run statements
+// with bound parameters.
+type ParameterizedExecutor interface {
+	ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error)
+}
+
+// ExecuteSQLWithArgs runs a statement with bound parameters, or returns an
+// error if the database cannot bind parameters
+func ExecuteSQLWithArgs(db DatabaseInterface, query string, args ...interface{}) (*models.QueryResult, error) {
+	executor, ok := db.(ParameterizedExecutor)
+	if !ok {
+		return nil, fmt.Errorf("this connection does not support parameterized statements")
+	}
+	return executor.ExecuteSQLWithArgs(query, args...)
+}
+
+// PrimaryExecutor is implemented by databases that route reads to replicas
+// and can run one on the primary instead, for reads that must see the latest
+// writes.
+type PrimaryExecutor interface {
+	ExecuteSQLOnPrimary(query string, args ...interface{}) (*models.QueryResult, error)
+}
+
+// ExecuteSQLOnPrimary runs a statement with bound parameters on the primary
+// of a database with replicas, or like ExecuteSQLWithArgs on any other
+func ExecuteSQLOnPrimary(db DatabaseInterface, query string, args ...interface{}) (*models.QueryResult, error) {
+	if executor, ok := db.(PrimaryExecutor); ok {
+		return executor.ExecuteSQLOnPrimary(query, args...)
+	}
+	return ExecuteSQLWithArgs(db, query, args...)
+}
+
+// GuardedExecutor is implemented by databases that can run a write statement in
+// a transaction that is rolled back unless it affects the expected number of
+// rows.
+type GuardedExecutor interface {
+	ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error)
+}
+
+// RowCountMismatchError reports a guarded statement that was rolled back
+// because it affected a different number of rows than expected
+type RowCountMismatchError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *RowCountMismatchError) Error() string {
+	return fmt.Sprintf("statement would affect %d rows but %d were expected; it was rolled back", e.Actual, e.Expected)
+}
+
+// ExecuteExpectingRows runs a write statement that is rolled back unless it
+// affects exactly expected rows, or returns an error if the database cannot
+// guard statements
+func ExecuteExpectingRows(db DatabaseInterface, query string, expected int64, args ...interface{}) (int64, error) {
+	executor, ok := db.(GuardedExecutor)
+	if !ok {
+		return 0, fmt.Errorf("this connection does not support guarded statements")
+	}
+	return executor.ExecuteExpectingRows(query, expected, args...)
+}
+
+// RowCursor reads the rows of a query a batch at a time, so large results are
+// never held in memory at once. It must be closed when no longer needed.
+type RowCursor interface {
+	Columns() []string
+	// Next returns up to n more rows, fewer only at the end of the result
+	Next(n int) ([][]interface{}, error)
+	Close() error
+}
+
+// StreamingExecutor is implemented by databases that can read query results in
+// batches.
+type StreamingExecutor interface {
+	OpenCursor(query string) (RowCursor, error)
+}
+
+// OpenCursor opens a cursor over the rows of a query. Databases that cannot
+// stream run the query in full and hand out its rows in batches.
+func OpenCursor(db DatabaseInterface, query string) (RowCursor, error) {
+	if executor, ok := db.(StreamingExecutor); ok {
+		return executor.OpenCursor(query)
+	}
+	result, err := db.ExecuteSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	return NewResultCursor(result), nil
+}
+
+// NewResultCursor returns a cursor handing out the rows of a result that was
+// read in full
+func NewResultCursor(result *models.QueryResult) RowCursor {
+	return &resultCursor{result: result}
+}
+
+// resultCursor hands out the rows of a result that was read in full
+type resultCursor struct {
+	result *models.QueryResult
+	next   int
+}
+
+func (c *resultCursor) Columns() []string {
+	return c.result.Columns
+}
+
+func (c *resultCursor) Next(n int) ([][]interface{}, error) {
+	end := c.next + n
+	if end > len(c.result.Rows) {
+		end = len(c.result.Rows)
+	}
+	rows := c.result.Rows[c.next:end]
+	c.next = end
+	return rows, nil
+}
+
+func (c *resultCursor) Close() error {
+	return nil
+}
+
+// TransactionStarter is implemented by databases that can run statements in a
+// transaction on one connection.
+type TransactionStarter interface {
+	BeginTx() (*sql.Tx, error)
+}
+
+// BeginTx starts a transaction, or returns an error if the database cannot
+func BeginTx(db DatabaseInterface) (*sql.Tx, error) {
+	starter, ok := db.(TransactionStarter)
+	if !ok {
+		return nil, fmt.Errorf("this connection does not support transactions")
+	}
+	return starter.BeginTx()
+}
+
+// ScratchpadController is implemented by connections that can run their
+// statements in a scratchpad, a transaction that is rolled back when it ends or
+// times out and never committed.
+type ScratchpadController interface {
+	StartScratchpad(timeout time.Duration) error
+	EndScratchpad() error
+	ScratchpadExpiry() time.Time // Zero when no scratchpad is open
+}
+
+// StartScratchpad opens a scratchpad that is rolled back after timeout, or
+// returns an error if the connection cannot
+func StartScratchpad(db DatabaseInterface, timeout time.Duration) error {
+	controller, ok := db.(ScratchpadController)
+	if !ok {
+		return fmt.Errorf("this connection does not support scratchpads")
+	}
+	return controller.StartScratchpad(timeout)
+}
+
+// EndScratchpad rolls back the open scratchpad of a connection
+func EndScratchpad(db DatabaseInterface) error {
+	controller, ok := db.(ScratchpadController)
+	if !ok {
+		return fmt.Errorf("this connection does not support scratchpads")
+	}
+	return controller.EndScratchpad()
+}
+
+// ScratchpadExpiry returns when the open scratchpad of a connection is rolled
+// back, or the zero time when none is open
+func ScratchpadExpiry(db DatabaseInterface) time.Time {
+	if controller, ok := db.(ScratchpadController); ok {
+		return controller.ScratchpadExpiry()
+	}
+	return time.Time{}
+}
+
+// SessionQuery runs a statement in the session of RunInSession
+type SessionQuery func(query string, args ...interface{}) (*models.QueryResult, error)
+
+// SessionRunner is implemented by connections that can run a series of reads
+// on one database session, for session state such as hypothetical indexes
+// that other connections of the pool cannot see.
+type SessionRunner interface {
+	RunInSession(fn func(query SessionQuery) error) error
+}
+
+// RunInSession runs fn with a function querying one session of the database,
+// or returns an error if the connection cannot
+func RunInSession(db DatabaseInterface, fn func(query SessionQuery) error) error {
+	runner, ok := db.(SessionRunner)
+	if !ok {
+		return fmt.Errorf("this connection does not support sessions")
+	}
+	return runner.RunInSession(fn)
+}
+
+// Canceller is implemented by connections that can cancel the statements they
+// are running on the server, rather than only abandoning the wait for them.
+type Canceller interface {
+	CancelRunning() (int, error) // Returns how many statements were cancelled
+}
+
+// CancelRunning cancels the statements running on a connection and returns
+// how many were cancelled, or 0 when the connection cannot cancel them
+func CancelRunning(db DatabaseInterface) (int, error) {
+	if canceller, ok := db.(Canceller); ok {
+		return canceller.CancelRunning()
+	}
+	return 0, nil
+}
+
+// RLSPolicyProvider is implemented by databases with row-level security
+// policies.
+type RLSPolicyProvider interface {
+	GetRLSPolicies(tableName string) (*models.RLSReport, error)
+}
+
+// GetRLSPolicies returns the row-level security policies of a table, or of
+// every table using them when tableName is empty
+func GetRLSPolicies(db DatabaseInterface, tableName string) (*models.RLSReport, error) {
+	provider, ok := db.(RLSPolicyProvider)
+	if !ok {
+		return nil, fmt.Errorf("row-level security policies are only available for PostgreSQL connections")
+	}
+	return provider.GetRLSPolicies(tableName)
+}
+
+// SchemaCatalogProvider is implemented by databases that can list their tables
+// one schema at a time, so large catalogs can be loaded lazily.
+type SchemaCatalogProvider interface {
+	ListSchemas() ([]string, error)
+	GetTablesInSchema(schema string) ([]models.TableInfo, error)
+}
+
+// RemoteReporter is implemented by databases that know whether they are far
+// away, so chatty work such as indexing can be batched.
+type RemoteReporter interface {
+	IsRemote() bool
+}
+
+// IsRemote reports whether a database was detected as a high-latency connection
+func IsRemote(db DatabaseInterface) bool {
+	if reporter, ok := db.(RemoteReporter); ok {
+		return reporter.IsRemote()
+	}
+	return false
+}
+
+// ReadOnlyReporter is implemented by connections that can refuse writes.
+type ReadOnlyReporter interface {
+	IsReadOnly() bool
+}
+
+// IsReadOnly reports whether a database refuses statements that write
+func IsReadOnly(db DatabaseInterface) bool {
+	if reporter, ok := db.(ReadOnlyReporter); ok {
+		return reporter.IsReadOnly()
+	}
+	return false
+}
+
+// NamedConnection is implemented by connections that know the name they were
+// configured under.
+type NamedConnection interface {
+	ConnectionName() string
+}
+
+// ConnectionName returns the configured name of a database, or "" when unknown
+func ConnectionName(db DatabaseInterface) string {
+	if named, ok := db.(NamedConnection); ok {
+		return named.ConnectionName()
+	}
+	return ""
+}
+
 // QueryExecutorInterface defines the interface for query execution
 type QueryExecutorInterface interface {
 	ExecuteSQL(query string) (*models.QueryResult, error)
+	ExecuteSQLWithArgs(query string, args ...interface{}) (*models.QueryResult, error)
+	ExecuteExpectingRows(query string, expected int64, args ...interface{}) (int64, error)
 	ExplainQuery(query string) (*models.QueryResult, error)
+	OpenCursor(query string) (RowCursor, error)
+	CancelRunning() (int, error)
 }
 
 // ConnectionConfig represents a database connection configuration
@@ -39,9 +333,16 @@ type ConnectionConfig struct {
 	Database    string `json:"database"`
 	Username    string `json:"username"`
 	Password    string `json:"password"`
+	PasswordRef string `json:"password_ref,omitempty"` // Secret reference used instead of Password, e.g. vault:kv/data/db#password or op://vault/item/field
 	SSLMode     string `json:"ssl_mode"`
 	Description string `json:"description"`
 	LastUsed    string `json:"last_used,omitempty"` // ISO 8601 timestamp
+	AliasOf     string `json:"alias_of,omitempty"`  // Name of the connection this alias refers to
+
+	MaxConcurrency int  `json:"max_concurrency,omitempty"` // Statements run at once, 0 for the environment default
+	ReadOnly       bool `json:"read_only,omitempty"`       // Refuse statements that change data, schema or settings
+
+	Replicas []string `json:"replicas,omitempty"` // Read replicas as host or host:port, connected with the other settings of the connection
 }
 
 // DatabaseProviderInterface defines the interface for database providers
@@ -62,6 +363,8 @@ type DatabaseProviderInterface interface {
 // ConnectionManagerInterface defines the interface for connection management
 type ConnectionManagerInterface interface {
 	AddConnection(config *ConnectionConfig) error
+	UpdateConnection(config *ConnectionConfig) error
+	UndoConnectionEdit(name string) error
 	RemoveConnection(name string) error
 	ListConnections() map[string]*ConnectionConfig
 	GetCurrentConnection() (DatabaseInterface, string, error)
@@ -75,8 +378,11 @@ type ConnectionManagerInterface interface {
 // ConnectionServiceInterface defines the interface for connection service
 type ConnectionServiceInterface interface {
 	GetCurrentTools() DatabaseInterface
+	CurrentConnection() (DatabaseInterface, string)
 	GetConnectionManager() ConnectionManagerInterface
 	AddConnection(config *ConnectionConfig) error
+	UpdateConnection(config *ConnectionConfig) error
+	UndoConnectionEdit(name string) error
 	SwitchConnection(name string) error
 	RemoveConnection(name string) error
 	GetConnectionInfo() (map[string]*ConnectionConfig, map[string]string, string)
@@ -86,4 +392,29 @@ type ConnectionServiceInterface interface {
 	IsConnectionHealthy() bool
 	EnsureHealthyConnection() error
 	GetConnectionStats() map[string]interface{}
+	GetHealthStatus() HealthStatus
+	ConnectionsHealth() map[string]HealthStatus
+	StartHealthMonitor(interval time.Duration, onChange func())
+	StopHealthMonitor()
+}
+
+// Connection health states
+const (
+	HealthUnknown  = "unknown"  // Not checked yet
+	HealthHealthy  = "healthy"  // Last check succeeded
+	HealthDegraded = "degraded" // Recent checks failed, reconnects are still attempted
+	HealthDown     = "down"     // Too many failures, reconnects back off
+)
+
+// HealthStatus describes the health of the current connection
+type HealthStatus struct {
+	State               string
+	CheckTTL            time.Duration
+	LastCheck           time.Time
+	LastChange          time.Time // When State last changed
+	LastError           string
+	ConsecutiveFailures int
+	NextAttempt         time.Time     // Earliest time of the next reconnect attempt while down
+	Latency             time.Duration // Round trip measured on connect, 0 when not measured
+	Remote              bool          // Latency is above the remote threshold, checks and timeouts are relaxed
 }
@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	key, err := NewKey()
+	require.NoError(t, err)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt("s3cret")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "s3cret")
+	again, _ := c.Encrypt("s3cret")
+	assert.NotEqual(t, encrypted, again, "each value gets its own nonce")
+
+	plaintext, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", plaintext)
+
+	// Another key or an altered value fail to decrypt
+	other, _ := NewKey()
+	otherCipher, _ := NewCipher(other)
+	_, err = otherCipher.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = c.Decrypt(encrypted[:len(encrypted)-4] + "AAAA")
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = NewCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestDeriveKey(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	key, err := DeriveKey("correct horse", salt)
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	same, _ := DeriveKey("correct horse", salt)
+	assert.Equal(t, key, same)
+	other, _ := DeriveKey("wrong horse", salt)
+	assert.NotEqual(t, key, other)
+}
@@ -0,0 +1,167 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// keyringService is the service dbsage stores its keyring entries under
+const keyringService = "dbsage"
+
+// Keyring stores small secrets, such as an encryption key, in the keyring of
+// the operating system
+type Keyring interface {
+	// Name describes the keyring in messages
+	Name() string
+	// Available reports whether the keyring can be used on this system
+	Available() bool
+	// Get returns the secret of an account
+	Get(ctx context.Context, account string) (string, error)
+	// Set stores the secret of an account, replacing any previous one
+	Set(ctx context.Context, account, secret string) error
+}
+
+// SystemKeyring returns the keyring of the operating system: the login
+// keychain on macOS, the Secret Service (GNOME Keyring, KWallet) through
+// secret-tool on Linux, and a file protected with DPAPI for the current user
+// on Windows
+func SystemKeyring() Keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		return &commandKeyring{
+			name:    "macOS keychain",
+			command: "security",
+			getArgs: func(account string) []string {
+				return []string{"find-generic-password", "-s", keyringService, "-a", account, "-w"}
+			},
+			// -w as the last argument makes security prompt for the secret,
+			// twice, so it never shows up in the process list
+			setArgs: func(account, _ string) []string {
+				return []string{"add-generic-password", "-U", "-s", keyringService, "-a", account, "-w"}
+			},
+			setStdin: func(secret string) string { return secret + "\n" + secret + "\n" },
+		}
+	case "windows":
+		dir, _ := os.UserConfigDir()
+		return &dpapiKeyring{dir: filepath.Join(dir, keyringService)}
+	default:
+		return &commandKeyring{
+			name:    "Secret Service keyring",
+			command: "secret-tool",
+			getArgs: func(account string) []string {
+				return []string{"lookup", "service", keyringService, "account", account}
+			},
+			setArgs: func(account, _ string) []string {
+				return []string{"store", "--label", "dbsage " + account, "service", keyringService, "account", account}
+			},
+			setStdin: func(secret string) string { return secret },
+		}
+	}
+}
+
+// commandKeyring uses the keyring CLI of the system
+type commandKeyring struct {
+	name     string
+	command  string
+	getArgs  func(account string) []string
+	setArgs  func(account, secret string) []string
+	setStdin func(secret string) string // Input of Set, which then keeps the secret out of setArgs
+}
+
+func (k *commandKeyring) Name() string { return k.name }
+
+func (k *commandKeyring) Available() bool {
+	_, err := exec.LookPath(k.command)
+	return err == nil
+}
+
+func (k *commandKeyring) Get(ctx context.Context, account string) (string, error) {
+	secret, err := runKeyringCommand(ctx, k.command, "", k.getArgs(account)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the %s: %w", account, k.name, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%s is not in the %s", account, k.name)
+	}
+	return secret, nil
+}
+
+func (k *commandKeyring) Set(ctx context.Context, account, secret string) error {
+	stdin := ""
+	if k.setStdin != nil {
+		stdin = k.setStdin(secret)
+	}
+	if _, err := runKeyringCommand(ctx, k.command, stdin, k.setArgs(account, secret)...); err != nil {
+		return fmt.Errorf("failed to store %s in the %s: %w", account, k.name, err)
+	}
+	return nil
+}
+
+// dpapiKeyring keeps each secret in a file encrypted with the Windows Data
+// Protection API, which only the current Windows user can decrypt
+type dpapiKeyring struct {
+	dir string
+}
+
+func (k *dpapiKeyring) Name() string { return "Windows Data Protection API" }
+
+func (k *dpapiKeyring) Available() bool {
+	_, err := exec.LookPath("powershell")
+	return err == nil && k.dir != ""
+}
+
+// path returns the file of an account
+func (k *dpapiKeyring) path(account string) string {
+	return filepath.Join(k.dir, strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(account)+".dpapi")
+}
+
+func (k *dpapiKeyring) Get(ctx context.Context, account string) (string, error) {
+	protected, err := os.ReadFile(k.path(account))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", account, err)
+	}
+	secret, err := runKeyringCommand(ctx, "powershell", string(protected), "-NoProfile", "-NonInteractive", "-Command",
+		"$s = $input | ConvertTo-SecureString; [Runtime.InteropServices.Marshal]::PtrToStringBSTR([Runtime.InteropServices.Marshal]::SecureStringToBSTR($s))")
+	if err != nil {
+		return "", fmt.Errorf("failed to unprotect %s: %w", account, err)
+	}
+	return secret, nil
+}
+
+func (k *dpapiKeyring) Set(ctx context.Context, account, secret string) error {
+	protected, err := runKeyringCommand(ctx, "powershell", secret, "-NoProfile", "-NonInteractive", "-Command",
+		"$input | ConvertTo-SecureString -AsPlainText -Force | ConvertFrom-SecureString")
+	if err != nil {
+		return fmt.Errorf("failed to protect %s: %w", account, err)
+	}
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(k.path(account), []byte(protected), 0600)
+}
+
+// runKeyringCommand runs a keyring CLI and returns its trimmed output
+func runKeyringCommand(ctx context.Context, command, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("%s was not found in PATH", command)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s", message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// OnePasswordBackend reads op://vault/item/field references with the
+// 1Password CLI, which handles sign-in, desktop app unlock and service
+// account tokens (OP_SERVICE_ACCOUNT_TOKEN)
+type OnePasswordBackend struct {
+	command string
+}
+
+// NewOnePasswordBackend creates a 1Password backend using the op command
+func NewOnePasswordBackend() *OnePasswordBackend {
+	return &OnePasswordBackend{command: "op"}
+}
+
+// Fetch reads a secret with `op read`
+func (b *OnePasswordBackend) Fetch(ctx context.Context, ref string) (Secret, error) {
+	if !strings.HasPrefix(ref, "op://") {
+		return Secret{}, fmt.Errorf("invalid 1Password reference %q: expected op://vault/item/field", ref)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.command, "read", "--no-newline", ref)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return Secret{}, fmt.Errorf("the 1Password CLI (op) is needed to resolve %s but was not found in PATH", ref)
+		}
+		message := strings.TrimSpace(stderr.String())
+		lower := strings.ToLower(message)
+		if strings.Contains(lower, "signed in") || strings.Contains(lower, "session") || strings.Contains(lower, "authoriz") {
+			return Secret{}, fmt.Errorf("1Password CLI is not signed in (%s): run `eval $(op signin)` or unlock the desktop app, then reconnect", message)
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return Secret{}, fmt.Errorf("failed to read %s from 1Password: %s", ref, message)
+	}
+	return Secret{Value: stdout.String()}, nil
+}
@@ -0,0 +1,119 @@
+// Package secrets fetches connection secrets from external stores such as
+// HashiCorp Vault or 1Password, so they never have to be written to disk.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a fetched secret is reused when its backend
+// gives no lease
+const DefaultCacheTTL = 5 * time.Minute
+
+// fetchTimeout bounds one fetch, long enough for a 1Password unlock prompt
+const fetchTimeout = time.Minute
+
+// Secret is a value fetched from a backend
+type Secret struct {
+	Value string
+	TTL   time.Duration // Lease of the value, 0 for the default cache TTL
+}
+
+// Backend fetches the secrets of one reference scheme, such as "vault" for
+// vault:kv/data/db#password. It receives the whole reference.
+type Backend interface {
+	Fetch(ctx context.Context, ref string) (Secret, error)
+}
+
+var (
+	mu       sync.Mutex
+	backends = map[string]Backend{
+		"vault": NewVaultBackend(),
+		"op":    NewOnePasswordBackend(),
+	}
+	cache = make(map[string]cacheEntry)
+)
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Register adds or replaces the backend of a reference scheme
+func Register(scheme string, backend Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	backends[scheme] = backend
+}
+
+// Schemes returns the registered reference schemes
+func Schemes() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	schemes := make([]string, 0, len(backends))
+	for scheme := range backends {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// cacheTTL returns how long secrets are cached, from DBSAGE_SECRET_TTL
+func cacheTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DBSAGE_SECRET_TTL")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultCacheTTL
+}
+
+// Resolve returns the secret a reference points to, from the cache while it
+// is fresh. Values are kept in memory only.
+func Resolve(ref string) (string, error) {
+	scheme, _, ok := strings.Cut(ref, ":")
+	if !ok || scheme == "" {
+		return "", fmt.Errorf("invalid secret reference %q: expected scheme:path, e.g. vault:kv/data/db#password", ref)
+	}
+
+	mu.Lock()
+	backend, exists := backends[scheme]
+	entry, cached := cache[ref]
+	mu.Unlock()
+	if !exists {
+		return "", fmt.Errorf("unknown secret backend %q in %q (supported: %s)", scheme, ref, strings.Join(Schemes(), ", "))
+	}
+	if cached && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	secret, err := backend.Fetch(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := cacheTTL()
+	if secret.TTL > 0 && secret.TTL < ttl {
+		ttl = secret.TTL
+	}
+	if ttl > 0 {
+		mu.Lock()
+		cache[ref] = cacheEntry{value: secret.Value, expires: time.Now().Add(ttl)}
+		mu.Unlock()
+	}
+	return secret.Value, nil
+}
+
+// Invalidate drops a cached secret, so the next Resolve fetches it again, for
+// example after the database rejected a rotated password
+func Invalidate(ref string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(cache, ref)
+}
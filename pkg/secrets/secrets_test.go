@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBackend returns a new value on every fetch
+type countingBackend struct {
+	fetches int
+	ttl     time.Duration
+}
+
+func (b *countingBackend) Fetch(ctx context.Context, ref string) (Secret, error) {
+	b.fetches++
+	return Secret{Value: fmt.Sprintf("%s-%d", ref, b.fetches), TTL: b.ttl}, nil
+}
+
+func TestResolve_Caches(t *testing.T) {
+	backend := &countingBackend{}
+	Register("test", backend)
+	t.Cleanup(func() { Invalidate("test:db") })
+
+	value, err := Resolve("test:db")
+	require.NoError(t, err)
+	assert.Equal(t, "test:db-1", value)
+	value, _ = Resolve("test:db")
+	assert.Equal(t, "test:db-1", value, "cached")
+
+	Invalidate("test:db")
+	value, _ = Resolve("test:db")
+	assert.Equal(t, "test:db-2", value)
+
+	t.Setenv("DBSAGE_SECRET_TTL", "0")
+	Invalidate("test:db")
+	Resolve("test:db")
+	value, _ = Resolve("test:db")
+	assert.Equal(t, "test:db-4", value, "caching disabled")
+
+	_, err = Resolve("nope:db")
+	assert.ErrorContains(t, err, "unknown secret backend")
+	_, err = Resolve("plain")
+	assert.ErrorContains(t, err, "invalid secret reference")
+}
+
+func TestVaultBackend_Fetch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_ROLE_ID", "role")
+	t.Setenv("VAULT_SECRET_ID", "secret")
+
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			logins++
+			fmt.Fprintf(w, `{"auth": {"client_token": "token-%d"}}`, logins)
+		case "/v1/kv/data/db":
+			// The first token has expired
+			if r.Header.Get("X-Vault-Token") != "token-2" {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"lease_duration": 0, "data": {"data": {"password": "s3cret"}, "metadata": {"version": 3}}}`)
+		case "/v1/secret/legacy":
+			fmt.Fprint(w, `{"lease_duration": 60, "data": {"password": "old"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+
+	backend := NewVaultBackend()
+	secret, err := backend.Fetch(context.Background(), "vault:kv/data/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret.Value)
+	assert.Equal(t, 2, logins, "logged in again after the token was rejected")
+
+	secret, err = backend.Fetch(context.Background(), "vault:secret/legacy#password")
+	require.NoError(t, err)
+	assert.Equal(t, "old", secret.Value)
+	assert.Equal(t, time.Minute, secret.TTL)
+
+	_, err = backend.Fetch(context.Background(), "vault:kv/data/db#user")
+	assert.ErrorContains(t, err, `no field "user"`)
+	_, err = backend.Fetch(context.Background(), "vault:kv/data/missing#password")
+	assert.ErrorContains(t, err, "not found")
+	_, err = backend.Fetch(context.Background(), "vault:kv/data/db")
+	assert.ErrorContains(t, err, "expected vault:<path>#<field>")
+
+	t.Setenv("VAULT_ROLE_ID", "")
+	t.Setenv("VAULT_TOKEN", "revoked")
+	_, err = backend.Fetch(context.Background(), "vault:kv/data/db#password")
+	assert.ErrorContains(t, err, "run `vault login`")
+}
+
+func TestOnePasswordBackend_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "op")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+case "$3" in
+  op://dev/db/password) printf 'pa$$word' ;;
+  op://locked/*) echo "[ERROR] You are not currently signed in." >&2; exit 1 ;;
+  *) echo "[ERROR] item not found" >&2; exit 1 ;;
+esac
+`), 0o755))
+
+	backend := &OnePasswordBackend{command: script}
+	secret, err := backend.Fetch(context.Background(), "op://dev/db/password")
+	require.NoError(t, err)
+	assert.Equal(t, "pa$$word", secret.Value)
+
+	_, err = backend.Fetch(context.Background(), "op://locked/db/password")
+	assert.ErrorContains(t, err, "op signin")
+	_, err = backend.Fetch(context.Background(), "op://dev/nope/password")
+	assert.ErrorContains(t, err, "item not found")
+	_, err = backend.Fetch(context.Background(), "vault:kv#x")
+	assert.ErrorContains(t, err, "expected op://")
+
+	backend = &OnePasswordBackend{command: filepath.Join(dir, "missing-op")}
+	_, err = backend.Fetch(context.Background(), "op://dev/db/password")
+	assert.Error(t, err)
+}
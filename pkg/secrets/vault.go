@@ -0,0 +1,148 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultBackend reads secrets from HashiCorp Vault over its HTTP API, with
+// references like vault:kv/data/db#password. KV version 1 and 2 engines are
+// supported. The server comes from VAULT_ADDR (and VAULT_NAMESPACE); the
+// token from VAULT_TOKEN, ~/.vault-token, or an AppRole login with
+// VAULT_ROLE_ID and VAULT_SECRET_ID.
+type VaultBackend struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	token string // Token from the last AppRole login
+}
+
+// NewVaultBackend creates a Vault backend
+func NewVaultBackend() *VaultBackend {
+	return &VaultBackend{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// vaultResponse is the part of a Vault read or login response used here
+type vaultResponse struct {
+	Data          map[string]interface{} `json:"data"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Errors        []string               `json:"errors"`
+	Auth          *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+}
+
+// Fetch reads one field of a Vault secret. When Vault rejects the token it
+// logs in again (AppRole) or re-reads the token sources, and retries once.
+func (b *VaultBackend) Fetch(ctx context.Context, ref string) (Secret, error) {
+	path, field, _ := strings.Cut(strings.TrimPrefix(ref, "vault:"), "#")
+	path = strings.Trim(path, "/")
+	if path == "" || field == "" {
+		return Secret{}, fmt.Errorf("invalid vault reference %q: expected vault:<path>#<field>", ref)
+	}
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return Secret{}, fmt.Errorf("VAULT_ADDR is not set; it is needed to resolve %s", ref)
+	}
+
+	token, err := b.currentToken(ctx, addr, false)
+	if err != nil {
+		return Secret{}, err
+	}
+	status, resp, err := b.request(ctx, http.MethodGet, addr+"/v1/"+path, token, nil)
+	if err == nil && (status == http.StatusForbidden || status == http.StatusUnauthorized) {
+		// The token expired or was revoked: get a fresh one and retry once
+		if fresh, loginErr := b.currentToken(ctx, addr, true); loginErr == nil && fresh != token {
+			status, resp, err = b.request(ctx, http.MethodGet, addr+"/v1/"+path, fresh, nil)
+		}
+	}
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read %s from vault: %w", path, err)
+	}
+	switch {
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		return Secret{}, fmt.Errorf("vault denied access to %s: the token is missing, expired or lacks a policy for it; run `vault login` and reconnect", path)
+	case status == http.StatusNotFound:
+		return Secret{}, fmt.Errorf("vault secret %s not found", path)
+	case status != http.StatusOK:
+		return Secret{}, fmt.Errorf("vault returned %d for %s: %s", status, path, strings.Join(resp.Errors, "; "))
+	}
+
+	fields := resp.Data
+	// KV version 2 nests the fields under data, next to the metadata
+	if inner, ok := resp.Data["data"].(map[string]interface{}); ok && resp.Data["metadata"] != nil {
+		fields = inner
+	}
+	value, ok := fields[field]
+	if !ok || value == nil {
+		return Secret{}, fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	return Secret{Value: fmt.Sprint(value), TTL: time.Duration(resp.LeaseDuration) * time.Second}, nil
+}
+
+// currentToken returns the token to use. With refresh, the token sources are
+// read again and an AppRole login is made instead of reusing the last one.
+func (b *VaultBackend) currentToken(ctx context.Context, addr string, refresh bool) (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil && strings.TrimSpace(string(data)) != "" {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	roleID, secretID := os.Getenv("VAULT_ROLE_ID"), os.Getenv("VAULT_SECRET_ID")
+	if roleID == "" {
+		return "", fmt.Errorf("no vault token: set VAULT_TOKEN, run `vault login`, or set VAULT_ROLE_ID and VAULT_SECRET_ID")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && !refresh {
+		return b.token, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"role_id": roleID, "secret_id": secretID})
+	status, resp, err := b.request(ctx, http.MethodPost, addr+"/v1/auth/approle/login", "", body)
+	if err != nil {
+		return "", fmt.Errorf("vault AppRole login failed: %w", err)
+	}
+	if status != http.StatusOK || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault AppRole login failed with %d: %s", status, strings.Join(resp.Errors, "; "))
+	}
+	b.token = resp.Auth.ClientToken
+	return b.token, nil
+}
+
+// request sends one Vault API request and decodes its JSON response
+func (b *VaultBackend) request(ctx context.Context, method, url, token string, body []byte) (int, *vaultResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resp := &vaultResponse{}
+	// Error responses may have an empty body
+	_ = json.NewDecoder(res.Body).Decode(resp)
+	return res.StatusCode, resp, nil
+}